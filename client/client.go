@@ -0,0 +1,516 @@
+// Package client talks to the control server of a running daemon, so that Go programs
+// can manage units without shelling out to systemctl
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"os"
+	"syscall"
+	"time"
+
+	"systemgo/system"
+	"systemgo/systemctl"
+	"systemgo/unit"
+)
+
+// Client talks to the control server of a running daemon.
+// It provides the methods of systemctl.Daemon, which report errors of the connection as well
+type Client struct {
+	*rpc.Client
+}
+
+// Dial connects to the control server listening on addr of network
+func Dial(network, addr string) (c *Client, err error) {
+	var rc *rpc.Client
+	if rc, err = rpc.DialHTTP(network, addr); err != nil {
+		return nil, err
+	}
+	return &Client{rc}, nil
+}
+
+// DialManager connects to the control socket of the system manager or,
+// if user is true, the user manager of the invoking user
+func DialManager(user bool) (c *Client, err error) {
+	addr := systemctl.SOCKET_PATH
+	if user {
+		addr = systemctl.UserSocketPath()
+	}
+	return Dial("unix", addr)
+}
+
+// IsNotRunning returns whether err returned by Dial means, that the daemon is not running
+func IsNotRunning(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT) || os.IsNotExist(err)
+}
+
+func (c *Client) call(method string, args []string) (yield interface{}, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server."+method, args, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Yield, nil
+}
+
+// jobs calls method with names and returns a system.JobError, if any of the jobs did not succeed
+func (c *Client) jobs(method string, names []string) (err error) {
+	var yield interface{}
+	if yield, err = c.call(method, names); err != nil {
+		return
+	}
+
+	if res, ok := yield.(system.Results); ok {
+		return res.Err()
+	}
+	return nil
+}
+
+func (c *Client) Start(names ...string) error {
+	return c.jobs("Start", names)
+}
+
+func (c *Client) Stop(names ...string) error {
+	return c.jobs("Stop", names)
+}
+
+func (c *Client) Isolate(names ...string) error {
+	return c.jobs("Isolate", names)
+}
+
+func (c *Client) Restart(names ...string) error {
+	return c.jobs("Restart", names)
+}
+
+func (c *Client) Reload(names ...string) error {
+	return c.jobs("Reload", names)
+}
+
+func (c *Client) TryRestart(names ...string) error {
+	return c.jobs("TryRestart", names)
+}
+
+func (c *Client) ReloadOrRestart(names ...string) error {
+	return c.jobs("ReloadOrRestart", names)
+}
+
+func (c *Client) ReloadOrTryRestart(names ...string) error {
+	return c.jobs("ReloadOrTryRestart", names)
+}
+
+func (c *Client) Enable(names ...string) (err error) {
+	_, err = c.call("Enable", names)
+	return
+}
+
+func (c *Client) Disable(names ...string) (err error) {
+	_, err = c.call("Disable", names)
+	return
+}
+
+func (c *Client) EnableRuntime(names ...string) (err error) {
+	_, err = c.call("EnableRuntime", names)
+	return
+}
+
+func (c *Client) DisableRuntime(names ...string) (err error) {
+	_, err = c.call("DisableRuntime", names)
+	return
+}
+
+func (c *Client) Mask(names ...string) (err error) {
+	_, err = c.call("Mask", names)
+	return
+}
+
+func (c *Client) Unmask(names ...string) (err error) {
+	_, err = c.call("Unmask", names)
+	return
+}
+
+func (c *Client) Preset(names ...string) (err error) {
+	_, err = c.call("Preset", names)
+	return
+}
+
+func (c *Client) PresetAll() (err error) {
+	_, err = c.call("PresetAll", nil)
+	return
+}
+
+func (c *Client) Link(path string) (err error) {
+	_, err = c.call("Link", []string{path})
+	return
+}
+
+func (c *Client) Revert(names ...string) (err error) {
+	_, err = c.call("Revert", names)
+	return
+}
+
+// ResetFailed resets the failed state of units specified by names, all failed units if none are specified
+func (c *Client) ResetFailed(names ...string) (err error) {
+	_, err = c.call("ResetFailed", names)
+	return
+}
+
+// Freeze suspends all processes of units specified by names
+func (c *Client) Freeze(names ...string) (err error) {
+	_, err = c.call("Freeze", names)
+	return
+}
+
+// Thaw resumes all processes of units specified by names
+func (c *Client) Thaw(names ...string) (err error) {
+	_, err = c.call("Thaw", names)
+	return
+}
+
+// ShutdownSystem makes the manager shut the system down, killing the processes of units
+// instead of stopping them, if force is true. It returns, once the shutdown is initiated
+func (c *Client) ShutdownSystem(kind system.ShutdownKind, force bool) (err error) {
+	var resp systemctl.Response
+	return c.Call("Server.ShutdownSystem", systemctl.ShutdownArgs{Kind: kind, Force: force}, &resp)
+}
+
+// ScheduleShutdown makes the manager shut the system down at when
+func (c *Client) ScheduleShutdown(kind system.ShutdownKind, when time.Time) (err error) {
+	var resp systemctl.Response
+	return c.Call("Server.ShutdownSystem", systemctl.ShutdownArgs{Kind: kind, When: when}, &resp)
+}
+
+// CancelShutdown cancels the scheduled shutdown
+func (c *Client) CancelShutdown() (err error) {
+	_, err = c.call("CancelShutdown", nil)
+	return
+}
+
+// ScheduledShutdown returns the scheduled shutdown, ok is false if none is scheduled
+func (c *Client) ScheduledShutdown() (s system.ScheduledShutdown, ok bool, err error) {
+	var yield interface{}
+	if yield, err = c.call("ScheduledShutdown", nil); err != nil {
+		return
+	}
+	s, _ = yield.(system.ScheduledShutdown)
+	return s, !s.When.IsZero(), nil
+}
+
+func (c *Client) PlanStart(names ...string) (p system.Plan, err error) {
+	var yield interface{}
+	if yield, err = c.call("PlanStart", names); err != nil {
+		return
+	}
+	p, _ = yield.(system.Plan)
+	return
+}
+
+func (c *Client) ReloadDaemon() (err error) {
+	_, err = c.call("ReloadDaemon", nil)
+	return
+}
+
+// Reexec asks the daemon to re-execute itself. The connection gets dropped in the process,
+// so the client is not usable afterwards
+func (c *Client) Reexec() (err error) {
+	_, err = c.call("Reexec", nil)
+	return
+}
+
+func (c *Client) DefaultTarget() (name string, err error) {
+	var yield interface{}
+	if yield, err = c.call("DefaultTarget", nil); err != nil {
+		return
+	}
+	name, _ = yield.(string)
+	return
+}
+
+func (c *Client) SetDefaultTarget(name string) (err error) {
+	_, err = c.call("SetDefaultTarget", []string{name})
+	return
+}
+
+func (c *Client) SetEnvironment(assignments ...string) (err error) {
+	_, err = c.call("SetEnvironment", assignments)
+	return
+}
+
+func (c *Client) UnsetEnvironment(names ...string) (err error) {
+	_, err = c.call("UnsetEnvironment", names)
+	return
+}
+
+func (c *Client) ShowEnvironment() (env []string, err error) {
+	var yield interface{}
+	if yield, err = c.call("ShowEnvironment", nil); err != nil {
+		return
+	}
+	env, _ = yield.([]string)
+	return
+}
+
+// Units returns names of the units held in-memory by the daemon
+func (c *Client) Units() (names []string, err error) {
+	var yield interface{}
+	if yield, err = c.call("ListUnits", nil); err != nil {
+		return
+	}
+	names, _ = yield.([]string)
+	return
+}
+
+// ListUnits returns the units held in-memory by the daemon selected by filter
+func (c *Client) ListUnits(filter system.UnitFilter) (infos []system.UnitInfo, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.ListUnitsFiltered", filter, &resp); err != nil {
+		return
+	}
+	infos, _ = resp.Yield.([]system.UnitInfo)
+	return
+}
+
+// ListUnitFiles returns the unit files found in the unit paths of the daemon
+func (c *Client) ListUnitFiles() (files []system.UnitFile, err error) {
+	var yield interface{}
+	if yield, err = c.call("ListUnitFiles", nil); err != nil {
+		return
+	}
+	files, _ = yield.([]system.UnitFile)
+	return
+}
+
+// ListTimers returns the active timer units held in-memory by the daemon, or all of them, if all is true
+func (c *Client) ListTimers(all bool) (infos []system.TimerInfo, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.ListTimers", all, &resp); err != nil {
+		return
+	}
+	infos, _ = resp.Yield.([]system.TimerInfo)
+	return
+}
+
+// ListSockets returns the sockets of active socket units held in-memory by the daemon, or of all of them, if all is true
+func (c *Client) ListSockets(all bool) (infos []system.SocketInfo, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.ListSockets", all, &resp); err != nil {
+		return
+	}
+	infos, _ = resp.Yield.([]system.SocketInfo)
+	return
+}
+
+// Blame returns the units held in-memory by the daemon, which finished activating,
+// along with the times they spent activating, slowest first
+func (c *Client) Blame() (timings []system.UnitTiming, err error) {
+	var yield interface{}
+	if yield, err = c.call("Blame", nil); err != nil {
+		return
+	}
+	timings, _ = yield.([]system.UnitTiming)
+	return
+}
+
+// BootTiming returns the time spent booting by the kernel and by the daemon
+func (c *Client) BootTiming() (bt system.BootTiming, err error) {
+	var yield interface{}
+	if yield, err = c.call("BootTiming", nil); err != nil {
+		return
+	}
+	bt, _ = yield.(system.BootTiming)
+	return
+}
+
+// Timeline returns the time spent booting along with the intervals the units were activating in
+func (c *Client) Timeline() (tl system.Timeline, err error) {
+	var yield interface{}
+	if yield, err = c.call("Timeline", nil); err != nil {
+		return
+	}
+	tl, _ = yield.(system.Timeline)
+	return
+}
+
+// CriticalChain returns the chain of units, which delayed the activation of the unit with name specified
+func (c *Client) CriticalChain(name string) (root system.ChainNode, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.CriticalChain", name, &resp); err != nil {
+		return
+	}
+	root, _ = resp.Yield.(system.ChainNode)
+	return
+}
+
+// Transitions returns the most recent activation state transitions of the unit with name specified, oldest first
+func (c *Client) Transitions(name string) (transitions []system.Transition, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.Transitions", name, &resp); err != nil {
+		return
+	}
+	transitions, _ = resp.Yield.([]system.Transition)
+	return
+}
+
+// ListDependencies returns the dependency tree of the unit with name specified
+func (c *Client) ListDependencies(name string, opts system.DependencyOptions) (root system.DependencyNode, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.ListDependencies", systemctl.ListDependenciesArgs{Name: name, Options: opts}, &resp); err != nil {
+		return
+	}
+	root, _ = resp.Yield.(system.DependencyNode)
+	return
+}
+
+// DependencyGraph returns the Requires=, Wants=, Conflicts= and After= dependencies of the loaded units held in-memory by the daemon
+func (c *Client) DependencyGraph() (edges []system.DependencyEdge, err error) {
+	var yield interface{}
+	if yield, err = c.call("DependencyGraph", nil); err != nil {
+		return
+	}
+	edges, _ = yield.([]system.DependencyEdge)
+	return
+}
+
+// LogEntries returns the log entries matching q
+func (c *Client) LogEntries(q system.LogQuery) (entries []system.LogEntry, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.Logs", q, &resp); err != nil {
+		return
+	}
+	entries, _ = resp.Yield.([]system.LogEntry)
+	return
+}
+
+// Boots returns the boots, which the log entries were logged during, the current boot is the last one
+func (c *Client) Boots() (boots []system.BootRecord, err error) {
+	var yield interface{}
+	if yield, err = c.call("Boots", nil); err != nil {
+		return
+	}
+	boots, _ = yield.([]system.BootRecord)
+	return
+}
+
+// EditContents returns the override drop-in of the unit with name specified or,
+// if full is true, its definition to be edited and passed to Edit
+func (c *Client) EditContents(name string, full bool) (b []byte, err error) {
+	var resp systemctl.Response
+	if err = c.Call("Server.EditContents", systemctl.EditArgs{Name: name, Full: full}, &resp); err != nil {
+		return
+	}
+	b, _ = resp.Yield.([]byte)
+	return
+}
+
+// Edit writes contents to the override drop-in of the unit with name specified or,
+// if full is true, replaces its definition
+func (c *Client) Edit(name string, contents []byte, full bool) (err error) {
+	var resp systemctl.Response
+	return c.Call("Server.Edit", systemctl.EditArgs{Name: name, Contents: contents, Full: full}, &resp)
+}
+
+// SetProperty sets the properties of the unit with name specified given as KEY=VALUE assignments.
+// If runtime is true, the properties are not persisted
+func (c *Client) SetProperty(name string, assignments []string, runtime bool) (err error) {
+	var resp systemctl.Response
+	return c.Call("Server.SetProperty", systemctl.SetPropertyArgs{Name: name, Assignments: assignments, Runtime: runtime}, &resp)
+}
+
+// Properties returns the effective properties of the unit with name specified
+func (c *Client) Properties(name string) (props map[string]string, err error) {
+	var yield interface{}
+	if yield, err = c.call("Properties", []string{name}); err != nil {
+		return
+	}
+	props, _ = yield.(map[string]string)
+	return
+}
+
+func (c *Client) State() (st system.State, err error) {
+	var yield interface{}
+	if yield, err = c.call("IsSystemRunning", nil); err != nil {
+		return
+	}
+
+	for st = system.Initializing; st <= system.Stopping; st++ {
+		if fmt.Sprint(st) == yield {
+			return st, nil
+		}
+	}
+	return -1, fmt.Errorf("Unknown state: %v", yield)
+}
+
+func (c *Client) Status() (st system.Status, err error) {
+	var yield interface{}
+	if yield, err = c.call("SystemStatus", nil); err != nil {
+		return
+	}
+	st, _ = yield.(system.Status)
+	return
+}
+
+func (c *Client) StatusOf(name string) (st unit.Status, err error) {
+	var yield interface{}
+	if yield, err = c.call("Status", []string{name}); err != nil {
+		return
+	}
+	st = yield.(map[string]unit.Status)[name]
+	return
+}
+
+// Statuses returns the statuses of units specified by names mapped by name in a single call,
+// or of all units held in-memory by the daemon, if none are specified.
+// Names of units, which cannot be found, are mapped to the errors in errs, nil if all are found
+func (c *Client) Statuses(names ...string) (statuses map[string]unit.Status, errs map[string]error, err error) {
+	var yield interface{}
+	if yield, err = c.call("Statuses", names); err != nil {
+		return
+	}
+
+	batch, _ := yield.(system.StatusBatch)
+	for name, msg := range batch.Errors {
+		if errs == nil {
+			errs = map[string]error{}
+		}
+		errs[name] = errors.New(msg)
+	}
+	return batch.Statuses, errs, nil
+}
+
+func (c *Client) IsEnabled(name string) (st unit.Enable, err error) {
+	var yield interface{}
+	if yield, err = c.call("IsEnabled", []string{name}); err != nil {
+		return
+	}
+	st = yield.(map[string]unit.Enable)[name]
+	return
+}
+
+func (c *Client) IsActive(name string) (st unit.Activation, err error) {
+	var yield interface{}
+	if yield, err = c.call("IsActive", []string{name}); err != nil {
+		return
+	}
+	st = yield.(map[string]unit.Activation)[name]
+	return
+}
+
+// Logs returns the log of the manager, if no names are specified,
+// otherwise the logs of units specified by names in order
+func (c *Client) Logs(names ...string) (logs []byte, err error) {
+	if len(names) == 0 {
+		var st system.Status
+		if st, err = c.Status(); err != nil {
+			return
+		}
+		return st.Log, nil
+	}
+
+	for _, name := range names {
+		var st unit.Status
+		if st, err = c.StatusOf(name); err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		logs = append(logs, st.Log...)
+	}
+	return
+}
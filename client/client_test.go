@@ -0,0 +1,214 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/system"
+	"systemgo/systemctl"
+	"systemgo/unit"
+)
+
+func TestClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "client-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	units := filepath.Join(dir, "units")
+	require.NoError(t, os.Mkdir(units, 0755))
+	for name, contents := range map[string]string{
+		"foo.service": "[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true",
+		"bar.service": "[Service]\nType=oneshot\nExecStart=/bin/false",
+		"baz.timer":   "[Timer]\nOnCalendar=daily\nUnit=foo.service",
+		"baz.socket":  "[Socket]\nListenStream=" + filepath.Join(dir, "baz.sock") + "\nService=foo.service",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(units, name), []byte(contents), 0644))
+	}
+
+	sys := system.New()
+	sys.SetPaths(units)
+
+	srv := rpc.NewServer()
+	require.NoError(t, srv.Register(systemctl.NewServer(sys)))
+
+	addr := filepath.Join(dir, "control")
+	l, err := net.Listen("unix", addr)
+	require.NoError(t, err)
+	defer l.Close()
+	go http.Serve(l, srv)
+
+	_, err = Dial("unix", filepath.Join(dir, "missing"))
+	assert.True(t, IsNotRunning(err), "dialing missing socket")
+
+	c, err := Dial("unix", addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Start("f*.service"))
+
+	st, err := c.IsActive("foo.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Active, st)
+
+	err = c.Start("bar.service")
+	if jerr, ok := err.(system.JobError); assert.True(t, ok, "error is JobError") {
+		assert.Equal(t, []string{"bar.service"}, jerr.Results.Failed())
+	}
+
+	transitions, err := c.Transitions("bar.service")
+	require.NoError(t, err)
+	if assert.Len(t, transitions, 2) {
+		assert.Equal(t, unit.Failed, transitions[1].To)
+		assert.Equal(t, "start", transitions[1].Job)
+	}
+
+	names, err := c.Units()
+	require.NoError(t, err)
+	assert.Contains(t, names, "foo.service")
+	assert.Contains(t, names, "bar.service")
+
+	infos, err := c.ListUnits(system.UnitFilter{States: []string{"failed"}})
+	require.NoError(t, err)
+	if assert.Len(t, infos, 1) {
+		assert.Equal(t, "bar.service", infos[0].Name)
+		assert.Equal(t, unit.Failed, infos[0].Active)
+	}
+
+	infos, err = c.ListUnits(system.UnitFilter{States: []string{"failed"}, Status: true})
+	require.NoError(t, err)
+	if assert.Len(t, infos, 1) && assert.NotNil(t, infos[0].Status) {
+		assert.Equal(t, unit.Failed, infos[0].Status.Activation.State)
+	}
+
+	statuses, errs, err := c.Statuses("foo.service", "bar.service", "missing.service")
+	require.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, unit.Failed, statuses["bar.service"].Activation.State)
+	if assert.Len(t, errs, 1) {
+		assert.Error(t, errs["missing.service"])
+	}
+
+	statuses, errs, err = c.Statuses("missing*")
+	require.NoError(t, err)
+	assert.Empty(t, statuses, "patterns matching nothing select no units")
+	assert.Nil(t, errs)
+
+	require.NoError(t, c.Start("baz.timer", "baz.socket"))
+	defer c.Stop("baz.timer", "baz.socket")
+
+	timers, err := c.ListTimers(false)
+	require.NoError(t, err)
+	if assert.Len(t, timers, 1) {
+		assert.Equal(t, "baz.timer", timers[0].Name)
+		assert.Equal(t, "foo.service", timers[0].Activates)
+	}
+
+	sockets, err := c.ListSockets(false)
+	require.NoError(t, err)
+	if assert.Len(t, sockets, 1) {
+		assert.Equal(t, "baz.socket", sockets[0].Name)
+		assert.Equal(t, unit.Listen{Type: "Stream", Address: filepath.Join(dir, "baz.sock")}, sockets[0].Listen)
+		assert.Equal(t, "foo.service", sockets[0].Activates)
+	}
+
+	root, err := c.ListDependencies("foo.service", system.DependencyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "foo.service", root.Name)
+	assert.Equal(t, unit.Active, root.Active)
+
+	contents, err := c.EditContents("foo.service", false)
+	require.NoError(t, err)
+	assert.Empty(t, contents)
+	require.NoError(t, c.Edit("foo.service", []byte("[Unit]\nDescription=Edited"), false))
+	contents, err = c.EditContents("foo.service", false)
+	require.NoError(t, err)
+	assert.Equal(t, "[Unit]\nDescription=Edited", string(contents))
+
+	props, err := c.Properties("foo.service")
+	require.NoError(t, err)
+	assert.Equal(t, "oneshot", props["Type"])
+	assert.Equal(t, "active", props["ActiveState"])
+
+	ust, err := c.StatusOf("foo.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Active, ust.Activation.State)
+
+	require.NoError(t, c.SetEnvironment("FOO=bar"))
+	env, err := c.ShowEnvironment()
+	require.NoError(t, err)
+	assert.Contains(t, env, "FOO=bar")
+
+	state, err := c.State()
+	require.NoError(t, err)
+	assert.Equal(t, system.Initializing, state)
+
+	_, err = c.Status()
+	require.NoError(t, err)
+
+	_, err = c.IsEnabled("missing.service")
+	assert.Error(t, err)
+
+	_, err = c.Logs()
+	require.NoError(t, err)
+
+	logs, err := c.Logs("bar.service")
+	require.NoError(t, err)
+	assert.NotEmpty(t, logs)
+
+	_, err = c.Logs("foo.service", "missing.service")
+	assert.Error(t, err)
+
+	entries, err := c.LogEntries(system.LogQuery{Units: []string{"bar"}, Lines: 1})
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "bar.service", entries[0].Unit)
+	}
+
+	boots, err := c.Boots()
+	require.NoError(t, err)
+	if assert.Len(t, boots, 1) {
+		assert.Equal(t, entries[0].Boot, boots[0].ID)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "client-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.service"),
+		[]byte("[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true"), 0644))
+
+	sys := system.New()
+	sys.SetPaths(dir)
+
+	addr := filepath.Join(dir, "control")
+	l, err := net.Listen("unix", addr)
+	require.NoError(t, err)
+	defer l.Close()
+	go http.Serve(l, systemctl.NewServer(sys))
+
+	c, err := Dial("unix", addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.NoError(t, c.Start("foo.service"))
+	assert.Error(t, c.Stop("missing.service"))
+
+	entries, err := c.LogEntries(system.LogQuery{Audit: true})
+	require.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, fmt.Sprintf("start foo.service requested via control by uid=%d pid=%d: succeeded",
+			os.Getuid(), os.Getpid()), entries[0].Message)
+		assert.Contains(t, entries[1].Message, "stop missing.service requested via control")
+		assert.Equal(t, system.LOG_WARNING, entries[1].Priority)
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/rpc"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Name of the executable bridging its standard input and output to the control socket.
+// It is run on the remote host by DialSSH
+const STDIO_BRIDGE = "systemgo-stdio-bridge"
+
+// Command used to reach remote hosts
+var SSH_COMMAND = "ssh"
+
+// Status line sent by the server in reply to the CONNECT request of an RPC client
+const connected = "200 Connected to Go RPC"
+
+// DialSSH connects to the control server of the daemon running on host, specified as [USER@]HOST[:PORT].
+// The control protocol is tunneled over SSH using STDIO_BRIDGE on the remote host,
+// which talks to the user manager of the remote user, if user is true
+func DialSSH(host string, user bool) (c *Client, err error) {
+	args := []string{"-xT"}
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && strings.Count(host, ":") == 1 {
+		if _, err = strconv.Atoi(host[i+1:]); err != nil {
+			return nil, fmt.Errorf("invalid port in %q", host)
+		}
+		args = append(args, "-p", host[i+1:])
+		host = host[:i]
+	}
+	args = append(args, "--", host, STDIO_BRIDGE)
+	if user {
+		args = append(args, "--user")
+	}
+
+	return DialCommand(exec.Command(SSH_COMMAND, args...))
+}
+
+// DialCommand starts cmd and talks to the control server over its standard input and output
+func DialCommand(cmd *exec.Cmd) (c *Client, err error) {
+	var pc pipeConn
+	if pc.WriteCloser, err = cmd.StdinPipe(); err != nil {
+		return
+	}
+	if pc.ReadCloser, err = cmd.StdoutPipe(); err != nil {
+		return
+	}
+	pc.cmd = cmd
+
+	if err = cmd.Start(); err != nil {
+		return
+	}
+
+	if c, err = NewClient(&pc); err != nil {
+		pc.Close()
+		return nil, err
+	}
+	return
+}
+
+// NewClient returns a client talking to the control server on the other side of conn,
+// which is expected to be served over HTTP, like the control socket is
+func NewClient(conn io.ReadWriteCloser) (c *Client, err error) {
+	if _, err = io.WriteString(conn, "CONNECT "+rpc.DefaultRPCPath+" HTTP/1.0\n\n"); err != nil {
+		return
+	}
+
+	r := bufio.NewReader(conn)
+
+	var resp *http.Response
+	if resp, err = http.ReadResponse(r, &http.Request{Method: "CONNECT"}); err != nil {
+		return
+	}
+	if resp.Status != connected {
+		return nil, fmt.Errorf("unexpected HTTP response: %s", resp.Status)
+	}
+
+	return &Client{rpc.NewClient(&bufferedConn{r, conn})}, nil
+}
+
+// pipeConn is a connection to the standard input and output of a command
+type pipeConn struct {
+	io.ReadCloser
+	io.WriteCloser
+
+	cmd  *exec.Cmd
+	once sync.Once
+}
+
+// Close closes the standard input of the command and waits for it to exit
+func (pc *pipeConn) Close() (err error) {
+	pc.once.Do(func() {
+		pc.WriteCloser.Close()
+		err = pc.cmd.Wait()
+	})
+	return
+}
+
+// bufferedConn reads from the reader, which may have buffered data read from the connection
+type bufferedConn struct {
+	*bufio.Reader
+	io.ReadWriteCloser
+}
+
+func (bc *bufferedConn) Read(b []byte) (int, error) {
+	return bc.Reader.Read(b)
+}
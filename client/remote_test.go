@@ -0,0 +1,70 @@
+package client
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/system"
+	"systemgo/systemctl"
+	"systemgo/unit"
+)
+
+// Environment variable making the test binary act as STDIO_BRIDGE connecting to the socket specified
+const bridgeEnv = "SYSTEMCTL_TEST_BRIDGE"
+
+func TestMain(m *testing.M) {
+	if addr := os.Getenv(bridgeEnv); addr != "" {
+		if err := systemctl.Bridge(os.Stdin, os.Stdout, "unix", addr); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestDialSSH(t *testing.T) {
+	dir, err := ioutil.TempDir("", "remote-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.service"),
+		[]byte("[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true"), 0644))
+
+	sys := system.New()
+	sys.SetPaths(dir)
+
+	srv := rpc.NewServer()
+	require.NoError(t, srv.Register(systemctl.NewServer(sys)))
+
+	addr := filepath.Join(dir, "control")
+	l, err := net.Listen("unix", addr)
+	require.NoError(t, err)
+	defer l.Close()
+	go http.Serve(l, srv)
+
+	defer func(cmd string) { SSH_COMMAND = cmd }(SSH_COMMAND)
+	SSH_COMMAND = os.Args[0]
+	os.Setenv(bridgeEnv, addr)
+	defer os.Unsetenv(bridgeEnv)
+
+	_, err = DialSSH("root@example.com:port", false)
+	assert.Error(t, err, "invalid port")
+
+	c, err := DialSSH("root@example.com:2222", true)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Start("foo.service"))
+
+	st, err := c.IsActive("foo.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Active, st)
+
+	assert.NoError(t, c.Close())
+}
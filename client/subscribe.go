@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"systemgo/system"
+)
+
+// Subscribe streams events about units specified by names, all units if none are specified,
+// and manager state changes. The channel is closed, when cancel is called or the connection fails
+func (c *Client) Subscribe(names ...string) (events <-chan system.Event, cancel func(), err error) {
+	var yield interface{}
+	if yield, err = c.call("Subscribe", names); err != nil {
+		return
+	}
+
+	id, ok := yield.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("Unexpected reply: %v", yield)
+	}
+
+	ch := make(chan system.Event, system.EVENT_BUFFER_SIZE)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(ch)
+
+		for {
+			yield, err := c.call("Events", []string{id})
+			if err != nil {
+				return
+			}
+
+			evs, _ := yield.([]system.Event)
+			for _, e := range evs {
+				select {
+				case ch <- e:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(done)
+			c.call("Unsubscribe", []string{id})
+		})
+	}, nil
+}
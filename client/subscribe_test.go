@@ -0,0 +1,78 @@
+package client
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/system"
+	"systemgo/systemctl"
+	"systemgo/unit"
+)
+
+func TestSubscribe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscribe-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"foo.service", "bar.service"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name),
+			[]byte("[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true"), 0644))
+	}
+
+	sys := system.New()
+	sys.SetPaths(dir)
+
+	srv := rpc.NewServer()
+	require.NoError(t, srv.Register(systemctl.NewServer(sys)))
+
+	addr := filepath.Join(dir, "control")
+	l, err := net.Listen("unix", addr)
+	require.NoError(t, err)
+	defer l.Close()
+	go http.Serve(l, srv)
+
+	c, err := Dial("unix", addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	events, cancel, err := c.Subscribe("foo.service")
+	require.NoError(t, err)
+
+	require.NoError(t, c.Start("bar.service"))
+	require.NoError(t, c.Start("foo.service"))
+
+	var active bool
+	for !active {
+		select {
+		case e, ok := <-events:
+			require.True(t, ok, "channel open")
+			assert.Equal(t, "foo.service", e.Unit)
+			active = e.Type == system.UnitStateChanged && e.To == unit.Active
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for foo.service to become active")
+		}
+	}
+
+	cancel()
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		for ok {
+			_, ok = <-events
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the channel to be closed")
+	}
+
+	var resp systemctl.Response
+	assert.Error(t, c.Call("Server.Events", []string{"1"}, &resp), "subscription dropped")
+}
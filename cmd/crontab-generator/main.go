@@ -0,0 +1,48 @@
+// Command crontab-generator is a generator converting the system crontab and the crontabs in /etc/cron.d
+// to timer and service units. It is run by systemgo with the output directories passed as arguments
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"systemgo/generator/crontab"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s NORMAL_DIR [EARLY_DIR LATE_DIR]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	path, dir := crontab.CRONTAB, crontab.CRON_D
+	if env := os.Getenv("SYSTEMGO_CRONTAB"); env != "" {
+		path = env
+	}
+	if env := os.Getenv("SYSTEMGO_CRON_D"); env != "" {
+		dir = env
+	}
+
+	failed := false
+
+	entries, err := crontab.ParseFile(path, "crontab")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		failed = true
+	}
+
+	more, errs := crontab.ParseDir(dir)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+		failed = true
+	}
+	entries = append(entries, more...)
+
+	if err = crontab.Generate(entries, os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,43 @@
+// Command fstab-generator is a generator converting the file system table to mount and swap units.
+// It is run by systemgo with the output directories passed as arguments
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"systemgo/generator/fstab"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "Usage: %s NORMAL_DIR [EARLY_DIR LATE_DIR]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	path := fstab.FSTAB
+	if env := os.Getenv("SYSTEMGO_FSTAB"); env != "" {
+		path = env
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	entries, err := fstab.Parse(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		os.Exit(1)
+	}
+
+	if err = fstab.Generate(entries, os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
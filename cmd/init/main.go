@@ -1,36 +1,184 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"net/rpc"
 	"os"
 	"os/signal"
-	"sync"
+	"path/filepath"
 	"time"
 
+	godbus "github.com/godbus/dbus/v5"
 	log "github.com/sirupsen/logrus"
 
 	"systemgo/config"
+	"systemgo/dbus"
+	"systemgo/rest"
 	"systemgo/system"
 	"systemgo/systemctl"
+	"systemgo/varlink"
 )
 
-// Initializes the system, sets the default paths, as specified in configuration and attempts to start the default target, falls back to "rescue.target", if it fails
+// Initializes the system, sets the default paths, as specified in configuration and boots into the default target, falls back to "rescue.target", if it fails
 func main() {
-	go Serve()
+	flag.BoolVar(&config.User, "user", config.User, "Run as the user manager of the invoking user")
+	flag.Parse()
+
+	if os.Getpid() == 1 {
+		if err := sys.EarlyBoot(); err != nil {
+			log.Errorf("Error during early boot: %s", err)
+		}
+
+		if _, err := sys.CaptureKmsg(); err != nil {
+			log.Errorf("Error capturing kernel log: %s", err)
+		}
+		if config.ForwardToKmsg {
+			if err := sys.ForwardToKmsg(); err != nil {
+				log.Errorf("Error forwarding to kernel log: %s", err)
+			}
+		}
+	}
 
 	// Initialize system
-	log.Info("Systemgo starting...")
+	if config.User {
+		log.Info("Systemgo starting in user mode...")
+		sys = system.NewUser()
+	} else {
+		log.Info("Systemgo starting...")
+		sys.SetPaths(config.Paths...)
+		sys.SetGeneratorPaths(config.Generators...)
+	}
+	sys.SetMaxJobs(config.Jobs)
+	sys.SetCgroup(config.Cgroup)
+
+	if !config.User || config.TimerStamps != system.DEFAULT_TIMER_STAMP_PATH {
+		sys.SetTimerStampPath(config.TimerStamps)
+	}
+
+	if config.Journal != "" {
+		dir := config.Journal
+		if config.User && dir == system.DEFAULT_JOURNAL_DIR {
+			dir = system.UserJournalDir()
+		}
+
+		compress := config.JournalCompress
+		if compress == "none" {
+			compress = system.COMPRESS_NONE
+		}
+
+		if err := sys.SetJournal(system.JournalConfig{
+			Dir:          dir,
+			MaxFileSize:  config.JournalMaxFileSize,
+			MaxFiles:     config.JournalMaxFiles,
+			MaxRetention: config.JournalMaxRetention,
+			Compress:     compress,
+			MaxUse:       config.JournalMaxUse,
+			KeepFree:     config.JournalKeepFree,
+		}); err != nil {
+			log.Errorf("Error opening journal in %s: %s", dir, err)
+		}
+	}
+
+	if config.ForwardToSyslog {
+		if err := sys.ForwardToSyslog(config.Syslog); err != nil {
+			log.Errorf("Error forwarding to syslog: %s", err)
+		}
+	}
+
+	if config.ForwardToConsole {
+		if level, err := system.ParsePriority(config.MaxLevelConsole); err != nil {
+			log.Errorf("Error parsing max_level_console: %s", err)
+		} else if err = sys.ForwardToConsole(config.TTYPath, level); err != nil {
+			log.Errorf("Error forwarding to %s: %s", config.TTYPath, err)
+		}
+	}
+
+	if config.LogShip != "" {
+		if err := sys.ShipLogs(system.LogShipConfig{
+			URL:    config.LogShip,
+			CAFile: config.LogShipCA,
+		}); err != nil {
+			log.Errorf("Error shipping logs to %s: %s", config.LogShip, err)
+		}
+	}
+
+	if config.LogFile != "" {
+		if s, err := system.NewFileLogSink(config.LogFile); err != nil {
+			log.Errorf("Error opening %s: %s", config.LogFile, err)
+		} else {
+			sys.AddLogSink("file", s)
+		}
+	}
+
+	if config.ForwardToJournald {
+		if s, err := system.NewJournaldLogSink(""); err != nil {
+			log.Errorf("Error connecting to journald: %s", err)
+		} else {
+			sys.AddLogSink("journald", s)
+		}
+	}
+
+	if config.OTLPEndpoint != "" {
+		if err := sys.Trace(system.TracingConfig{Endpoint: config.OTLPEndpoint}); err != nil {
+			log.Errorf("Error exporting traces to %s: %s", config.OTLPEndpoint, err)
+		}
+	}
+
+	conf := system.SYSTEM_CONF
+	if config.User {
+		conf = system.USER_CONF
+	}
+	if err := sys.LoadConf(conf); err != nil {
+		log.Errorf("Error loading %s: %s", conf, err)
+	}
+
+	if err := sys.RunGenerators(); err != nil {
+		log.Errorf("Error running generators: %s", err)
+	}
+
+	if config.Preload > 0 {
+		if err := sys.Preload(config.Preload); err != nil {
+			log.Errorf("Error preloading units: %s", err)
+		}
+	}
+
+	if config.Watch {
+		if _, err := sys.Watch(); err != nil {
+			log.Errorf("Error watching unit paths: %s", err)
+		}
+	}
+
+	Serve()
+
+	if config.REST != "" {
+		go serve("tcp", config.REST, rest.NewHandler(sys))
+	}
+
+	if config.Varlink {
+		go serveVarlink()
+	}
 
-	sys.SetPaths(config.Paths...)
+	if config.DBus {
+		if err := serveDBus(); err != nil {
+			log.Errorf("Error serving D-Bus API: %s", err)
+		}
+	}
+
+	// Restore the state, if re-executed by daemon-reexec
+	restored, err := sys.Restore()
+	if err != nil {
+		log.Errorf("Error restoring state: %s", err)
+	}
 
-	// Start the default target
-	if err := sys.Start(config.Target); err != nil {
-		log.Errorf("Error starting default target %s: %s", config.Target, err)
-		if err = sys.Start(config.RESCUE_TARGET); err != nil {
-			log.Errorf("Error starting rescue target %s: %s", config.RESCUE_TARGET, err)
+	// Boot into the default target, falls back to rescue target, if it fails
+	if !restored {
+		sys.SetBootTarget(config.Target)
+		if err := sys.Boot(); err != nil {
+			log.Errorf("Error booting: %s", err)
 		}
 	}
 
@@ -38,65 +186,119 @@ func main() {
 		go printUnits()
 	}
 
-	exit := make(chan os.Signal)
+	if os.Getpid() == 1 {
+		// Signals are handled by the daemon in init mode
+		sys.InitMode()
+		select {}
+	}
+
+	exit := make(chan os.Signal, 1)
 	signal.Notify(exit, os.Interrupt, os.Kill)
 	<-exit
 
 	log.Infoln("Shutting down...")
-	if err := sys.Isolate("shutdown.target"); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), sys.Defaults().TimeoutStopSec)
+	defer cancel()
+
+	if err := sys.Shutdown(ctx); err != nil {
 		log.Fatalf("Error shutting down: %s", err)
 	}
+}
 
-	wg := &sync.WaitGroup{}
-	for _, u := range sys.Units() {
-		if u.IsActive() {
-			log.Infof("Waiting for %s to stop", u.Name())
-			wg.Add(1)
+// Instance of a system
+var sys = system.New()
 
-			go func(u *system.Unit) {
-				defer wg.Done()
+// Listen for systemctl requests on the control socket and, if running as the system manager, the TCP port
+func Serve() {
+	// Requests are served by a server bound to each connection, which audits them with the credentials of the client
+	http.Handle(rpc.DefaultRPCPath, systemctl.NewServer(sys))
 
-				var t time.Duration
-				for range time.Tick(time.Second) {
-					t += time.Second
-					if !u.IsActive() || t == time.Minute {
-						return
-					}
-				}
-			}(u)
-		}
+	if config.User {
+		go serve("unix", systemctl.UserSocketPath(), nil)
+		return
 	}
+	go serve("unix", systemctl.SOCKET_PATH, nil)
+	go serve("tcp", config.Port.String(), nil)
 }
 
-// Instance of a system
-var sys = system.New()
+// Export the systemd1 D-Bus API on the system bus or the session bus, if running as the user manager
+func serveDBus() (err error) {
+	connect := godbus.ConnectSystemBus
+	if config.User {
+		connect = godbus.ConnectSessionBus
+	}
 
-// Listen for systemctl requests
-func Serve() {
+	var conn *godbus.Conn
+	if conn, err = connect(); err != nil {
+		return
+	}
+
+	if _, err = dbus.Serve(sys, conn); err != nil {
+		conn.Close()
+	}
+	return
+}
+
+// Serve HTTP on addr using h(http.DefaultServeMux, if nil), retrying on failure
+func serve(network, addr string, h http.Handler) {
 	for {
-		if err := listenHTTP(config.Port.String()); err != nil {
-			log.Errorf("Error listening on %v: %s", config.Port, err)
+		if err := listenHTTP(network, addr, h); err != nil {
+			log.Errorf("Error listening on %v: %s", addr, err)
 		}
 		log.Infof("Retrying in %v seconds", config.Retry)
 		time.Sleep(config.Retry)
 	}
 }
 
-// Handle systemctl requests using HTTP
-func listenHTTP(addr string) (err error) {
-	daemonRPC := systemctl.NewServer(sys)
-	rpc.Register(daemonRPC)
-	rpc.HandleHTTP()
+// Handle requests using HTTP
+func listenHTTP(network, addr string, h http.Handler) (err error) {
+	l, err := listen(network, addr)
+	if err != nil {
+		return
+	}
+	return http.Serve(l, h)
+}
 
-	e := log.WithField("port", config.Port)
+// Serve the Varlink interfaces on the socket in the runtime directory of the manager, retrying on failure
+func serveVarlink() {
+	addr := varlink.SOCKET_PATH
+	if config.User {
+		addr = filepath.Join(system.UserRuntimeDir(), "systemgo", varlink.MANAGER_INTERFACE)
+	}
 
-	l, err := net.Listen("tcp", config.Port.String())
-	if err != nil {
-		e.Fatalf("Listen error: %s", err)
+	srv := varlink.NewService(sys)
+	for {
+		l, err := listen("unix", addr)
+		if err == nil {
+			err = srv.Serve(l)
+		}
+		log.Errorf("Error serving Varlink on %v: %s", addr, err)
+
+		log.Infof("Retrying in %v seconds", config.Retry)
+		time.Sleep(config.Retry)
+	}
+}
+
+// Listen on addr of network, creating the directory of unix sockets and removing the stale ones
+func listen(network, addr string) (l net.Listener, err error) {
+	if network == "unix" {
+		perm := os.FileMode(0755)
+		if config.User {
+			perm = 0700
+		}
+		if err = os.MkdirAll(filepath.Dir(addr), perm); err != nil {
+			return
+		}
+		// Remove the stale socket, if any
+		os.Remove(addr)
+	}
+
+	if l, err = net.Listen(network, addr); err != nil {
+		return
 	}
 
-	log.Infof("Listening on http://localhost%s", config.Port)
-	return http.Serve(l, nil)
+	log.Infof("Listening on %s %s", network, addr)
+	return
 }
 
 func printUnits() {
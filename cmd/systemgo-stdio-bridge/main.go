@@ -0,0 +1,27 @@
+// Command systemgo-stdio-bridge connects its standard input and output to the control socket
+// of the system manager or, with --user, the user manager of the invoking user.
+// It is run on the remote host by systemctl -H over SSH
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"systemgo/systemctl"
+)
+
+func main() {
+	user := flag.Bool("user", false, "Connect to the user manager of the invoking user")
+	flag.Parse()
+
+	addr := systemctl.SOCKET_PATH
+	if *user {
+		addr = systemctl.UserSocketPath()
+	}
+
+	if err := systemctl.Bridge(os.Stdin, os.Stdout, "unix", addr); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", addr, err)
+		os.Exit(1)
+	}
+}
@@ -12,8 +12,8 @@ import (
 
 const (
 	DEFAULT_PORT   = 8008
-	DEFAULT_TARGET = "default.target"
-	RESCUE_TARGET  = "rescue.target"
+	DEFAULT_TARGET = system.DEFAULT_TARGET
+	RESCUE_TARGET  = system.RESCUE_TARGET
 )
 
 var (
@@ -23,13 +23,97 @@ var (
 	// Paths to search for unit files
 	Paths []string
 
+	// Paths to search for generator executables
+	Generators []string
+
 	// Port for system daemon to listen on
 	Port port
 
+	// Maximum number of jobs run concurrently(0 means no limit)
+	Jobs int
+
+	// Number of workers unit files are loaded by concurrently on startup(0 means units are loaded on demand only)
+	Preload int
+
 	// Retry specifies the period(in seconds) to wait before
 	// restarting the http service if it fails
 	Retry time.Duration
 
+	// Whether to run as the user manager of the invoking user
+	User bool
+
+	// Whether to watch unit paths for changes of definitions
+	Watch bool
+
+	// Address to serve the HTTP JSON API on(empty means disabled)
+	REST string
+
+	// Whether to serve the Varlink interfaces on the socket in the runtime directory
+	Varlink bool
+
+	// cgroup v2 directory to create cgroups of units in(empty means disabled)
+	Cgroup string
+
+	// Directory to store the times units were last activated at by persistent timers in
+	TimerStamps string
+
+	// Directory to keep the persistent journal of unit logs in(empty means disabled)
+	Journal string
+
+	// Size in bytes, after which journal files get rotated
+	JournalMaxFileSize int64
+
+	// Number of rotated journal files kept per unit
+	JournalMaxFiles int
+
+	// Age, after which rotated journal files get vacuumed(0 means no limit)
+	JournalMaxRetention time.Duration
+
+	// Algorithm rotated journal files get compressed with, "gzip" or "none"
+	JournalCompress string
+
+	// Size in bytes, which journal files of all units may use at most(0 means no limit)
+	JournalMaxUse int64
+
+	// Size in bytes, which is kept free on the file system holding the journal(0 means no limit)
+	JournalKeepFree int64
+
+	// Whether to forward log entries of units to syslog
+	ForwardToSyslog bool
+
+	// Syslog endpoint to forward log entries to, e.g. "udp://HOST:514"(empty means /dev/log)
+	Syslog string
+
+	// Whether to forward log entries to the kernel log buffer, until the journal is opened, when running as init
+	ForwardToKmsg bool
+
+	// Whether to mirror log entries of high priority to the console
+	ForwardToConsole bool
+
+	// TTY log entries are mirrored to
+	TTYPath string
+
+	// Lowest priority of entries mirrored to the console, e.g. "info" or "6"
+	MaxLevelConsole string
+
+	// Remote endpoint log entries are shipped to, "tls://HOST:PORT" or "https://HOST/PATH"(empty means disabled)
+	LogShip string
+
+	// Certificates of the authorities verifying the remote log endpoint(empty means the system ones)
+	LogShipCA string
+
+	// File log entries are appended to as JSON lines(empty means disabled)
+	LogFile string
+
+	// Whether to pass log entries through to systemd-journald
+	ForwardToJournald bool
+
+	// OTLP/HTTP traces endpoint spans of transactions and jobs are exported to(empty means disabled)
+	OTLPEndpoint string
+
+	// Whether to serve the systemd1 D-Bus API on the system(or session, in user mode) bus
+	DBus bool
+
 	// Wheter to show debugging statements
 	Debug bool
 )
@@ -44,7 +128,35 @@ func init() {
 	viper.SetDefault("port", DEFAULT_PORT)
 	viper.SetDefault("target", DEFAULT_TARGET)
 	viper.SetDefault("paths", system.DEFAULT_PATHS)
+	viper.SetDefault("generators", system.DEFAULT_GENERATOR_PATHS)
 	viper.SetDefault("retry", 1)
+	viper.SetDefault("jobs", system.DEFAULT_MAX_JOBS)
+	viper.SetDefault("preload", system.DEFAULT_PRELOAD_WORKERS)
+	viper.SetDefault("user", false)
+	viper.SetDefault("watch", false)
+	viper.SetDefault("rest", "")
+	viper.SetDefault("varlink", false)
+	viper.SetDefault("dbus", false)
+	viper.SetDefault("cgroup", "")
+	viper.SetDefault("timer_stamps", system.DEFAULT_TIMER_STAMP_PATH)
+	viper.SetDefault("journal", system.DEFAULT_JOURNAL_DIR)
+	viper.SetDefault("journal_max_file_size", system.DEFAULT_JOURNAL_MAX_FILE_SIZE)
+	viper.SetDefault("journal_max_files", system.DEFAULT_JOURNAL_MAX_FILES)
+	viper.SetDefault("journal_max_retention", "0")
+	viper.SetDefault("journal_compress", system.COMPRESS_GZIP)
+	viper.SetDefault("journal_max_use", 0)
+	viper.SetDefault("journal_keep_free", 0)
+	viper.SetDefault("forward_to_syslog", false)
+	viper.SetDefault("syslog", "")
+	viper.SetDefault("forward_to_kmsg", false)
+	viper.SetDefault("forward_to_console", false)
+	viper.SetDefault("tty_path", system.CONSOLE_PATH)
+	viper.SetDefault("max_level_console", system.PriorityName(system.DEFAULT_MAX_LEVEL_CONSOLE))
+	viper.SetDefault("log_ship", "")
+	viper.SetDefault("log_ship_ca", "")
+	viper.SetDefault("log_file", "")
+	viper.SetDefault("forward_to_journald", false)
+	viper.SetDefault("otlp_endpoint", "")
 	viper.SetDefault("debug", false)
 
 	viper.SetEnvPrefix("systemgo")
@@ -76,8 +188,36 @@ func init() {
 
 	Target = viper.GetString("target")
 	Paths = viper.GetStringSlice("paths")
+	Generators = viper.GetStringSlice("generators")
 	Port = port(viper.GetInt("port"))
 	Retry = viper.GetDuration("retry") * time.Second
+	Jobs = viper.GetInt("jobs")
+	Preload = viper.GetInt("preload")
+	User = viper.GetBool("user")
+	Watch = viper.GetBool("watch")
+	REST = viper.GetString("rest")
+	Varlink = viper.GetBool("varlink")
+	DBus = viper.GetBool("dbus")
+	Cgroup = viper.GetString("cgroup")
+	TimerStamps = viper.GetString("timer_stamps")
+	Journal = viper.GetString("journal")
+	JournalMaxFileSize = viper.GetInt64("journal_max_file_size")
+	JournalMaxFiles = viper.GetInt("journal_max_files")
+	JournalMaxRetention = viper.GetDuration("journal_max_retention")
+	JournalCompress = viper.GetString("journal_compress")
+	JournalMaxUse = viper.GetInt64("journal_max_use")
+	JournalKeepFree = viper.GetInt64("journal_keep_free")
+	ForwardToSyslog = viper.GetBool("forward_to_syslog")
+	Syslog = viper.GetString("syslog")
+	ForwardToKmsg = viper.GetBool("forward_to_kmsg")
+	ForwardToConsole = viper.GetBool("forward_to_console")
+	TTYPath = viper.GetString("tty_path")
+	MaxLevelConsole = viper.GetString("max_level_console")
+	LogShip = viper.GetString("log_ship")
+	LogShipCA = viper.GetString("log_ship_ca")
+	LogFile = viper.GetString("log_file")
+	ForwardToJournald = viper.GetBool("forward_to_journald")
+	OTLPEndpoint = viper.GetString("otlp_endpoint")
 	Debug = viper.GetBool("debug")
 
 	if Debug {
@@ -0,0 +1,484 @@
+// Package dbus exposes a system.Daemon over D-Bus mimicking the org.freedesktop.systemd1 API,
+// so that existing tooling talking to systemd can be used with systemgo unmodified
+package dbus
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"systemgo/system"
+	"systemgo/unit"
+
+	godbus "github.com/godbus/dbus/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Well-known name requested on the bus
+	BUS_NAME = "org.freedesktop.systemd1"
+
+	// Path of the manager object
+	OBJECT_PATH = godbus.ObjectPath("/org/freedesktop/systemd1")
+
+	// Interfaces implemented by the manager and unit objects
+	MANAGER_INTERFACE    = "org.freedesktop.systemd1.Manager"
+	UNIT_INTERFACE       = "org.freedesktop.systemd1.Unit"
+	PROPERTIES_INTERFACE = "org.freedesktop.DBus.Properties"
+
+	// Paths, under which unit and job objects are found
+	UNIT_PATH = OBJECT_PATH + "/unit"
+	JOB_PATH  = OBJECT_PATH + "/job"
+)
+
+// Errors returned to callers, named as in systemd
+const (
+	ERR_NO_SUCH_UNIT    = "org.freedesktop.systemd1.NoSuchUnit"
+	ERR_UNKNOWN_PROP    = "org.freedesktop.DBus.Error.UnknownProperty"
+	ERR_UNKNOWN_IFACE   = "org.freedesktop.DBus.Error.UnknownInterface"
+	ERR_NOT_IMPLEMENTED = "org.freedesktop.DBus.Error.NotSupported"
+)
+
+// Server serves the Manager and Unit interfaces of systemd1 backed by a Daemon
+type Server struct {
+	sys  *system.Daemon
+	conn *godbus.Conn
+
+	// Unique names of the clients subscribed to signals
+	subscribers map[string]bool
+
+	// ID of the last job created
+	lastJob uint32
+
+	mutex sync.Mutex
+}
+
+// unitInfo is an entry of the reply to ListUnits, signature (ssssssouso)
+type unitInfo struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	Following   string
+	Path        godbus.ObjectPath
+	JobID       uint32
+	JobType     string
+	JobPath     godbus.ObjectPath
+}
+
+// Serve exports the manager and unit objects backed by sys on conn and requests BUS_NAME.
+// Returns a function, which stops the delivery of signals
+func Serve(sys *system.Daemon, conn *godbus.Conn) (stop func(), err error) {
+	log.Debugf("dbus.Serve")
+
+	srv := &Server{
+		sys:         sys,
+		conn:        conn,
+		subscribers: map[string]bool{},
+	}
+
+	if err = conn.Export(srv, OBJECT_PATH, MANAGER_INTERFACE); err != nil {
+		return
+	}
+
+	units := &units{srv}
+	if err = conn.ExportSubtree(units, UNIT_PATH, UNIT_INTERFACE); err != nil {
+		return
+	}
+	if err = conn.ExportSubtree(units, UNIT_PATH, PROPERTIES_INTERFACE); err != nil {
+		return
+	}
+
+	reply, err := conn.RequestName(BUS_NAME, godbus.NameFlagDoNotQueue)
+	if err != nil {
+		return
+	}
+	if reply != godbus.RequestNameReplyPrimaryOwner {
+		return nil, fmt.Errorf("name %s is already taken", BUS_NAME)
+	}
+
+	events := sys.Subscribe()
+	go srv.forward(events)
+
+	return func() { sys.Unsubscribe(events) }, nil
+}
+
+// forward emits signals corresponding to events to subscribers
+func (srv *Server) forward(events <-chan system.Event) {
+	for e := range events {
+		if e.Type != system.UnitStateChanged || !srv.subscribed() {
+			continue
+		}
+
+		u, err := srv.sys.Unit(e.Unit)
+		if err != nil {
+			continue
+		}
+
+		changed := map[string]godbus.Variant{
+			"ActiveState": godbus.MakeVariant(activeState(e.To)),
+			"SubState":    godbus.MakeVariant(u.Sub()),
+		}
+		srv.emit(UnitPath(e.Unit), PROPERTIES_INTERFACE+".PropertiesChanged", UNIT_INTERFACE, changed, []string{})
+	}
+}
+
+// subscribed returns whether any client is subscribed to signals
+func (srv *Server) subscribed() bool {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	return len(srv.subscribers) > 0
+}
+
+// emit emits signal on path, if any client is subscribed
+func (srv *Server) emit(path godbus.ObjectPath, name string, values ...interface{}) {
+	if !srv.subscribed() {
+		return
+	}
+
+	if err := srv.conn.Emit(path, name, values...); err != nil {
+		log.WithField("signal", name).Errorf("Error emitting signal: %s", err)
+	}
+}
+
+// Subscribe enables the emission of signals to sender
+func (srv *Server) Subscribe(sender godbus.Sender) *godbus.Error {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	srv.subscribers[string(sender)] = true
+	return nil
+}
+
+// Unsubscribe disables the emission of signals to sender
+func (srv *Server) Unsubscribe(sender godbus.Sender) *godbus.Error {
+	srv.mutex.Lock()
+	defer srv.mutex.Unlock()
+
+	delete(srv.subscribers, string(sender))
+	return nil
+}
+
+// GetUnit returns the object path of the loaded unit name
+func (srv *Server) GetUnit(name string) (godbus.ObjectPath, *godbus.Error) {
+	u, err := srv.sys.Unit(name)
+	if err != nil {
+		return "", noSuchUnit(name)
+	}
+	return UnitPath(u.Name()), nil
+}
+
+// LoadUnit returns the object path of the unit name, loading it, if necessary
+func (srv *Server) LoadUnit(name string) (godbus.ObjectPath, *godbus.Error) {
+	_, missing := srv.sys.Unit(name)
+
+	u, err := srv.sys.Get(name)
+	if err != nil {
+		return "", noSuchUnit(name)
+	}
+
+	path := UnitPath(u.Name())
+	if missing != nil {
+		srv.emit(OBJECT_PATH, MANAGER_INTERFACE+".UnitNew", u.Name(), path)
+	}
+	return path, nil
+}
+
+// ListUnits returns the status of all loaded units
+func (srv *Server) ListUnits() (infos []unitInfo, _ *godbus.Error) {
+	infos = []unitInfo{}
+	for _, u := range srv.sys.Units() {
+		infos = append(infos, unitInfo{
+			Name:        u.Name(),
+			Description: u.Description(),
+			LoadState:   loadState(u.Loaded()),
+			ActiveState: activeState(u.Active()),
+			SubState:    u.Sub(),
+			Path:        UnitPath(u.Name()),
+			JobPath:     "/",
+		})
+	}
+	return infos, nil
+}
+
+// StartUnit queues a start job for the unit name and returns its object path.
+// Mode "isolate" isolates the unit, other modes are accepted, but have no effect yet
+func (srv *Server) StartUnit(name, mode string) (godbus.ObjectPath, *godbus.Error) {
+	if mode == "isolate" {
+		return srv.job(name, "isolate", srv.sys.Isolate)
+	}
+	return srv.job(name, "start", srv.sys.Start)
+}
+
+// StopUnit queues a stop job for the unit name and returns its object path
+func (srv *Server) StopUnit(name, mode string) (godbus.ObjectPath, *godbus.Error) {
+	return srv.job(name, "stop", srv.sys.Stop)
+}
+
+// RestartUnit queues a restart job for the unit name and returns its object path
+func (srv *Server) RestartUnit(name, mode string) (godbus.ObjectPath, *godbus.Error) {
+	return srv.job(name, "restart", srv.sys.Restart)
+}
+
+// TryRestartUnit queues a try-restart job for the unit name and returns its object path
+func (srv *Server) TryRestartUnit(name, mode string) (godbus.ObjectPath, *godbus.Error) {
+	return srv.job(name, "try-restart", srv.sys.TryRestart)
+}
+
+// ReloadUnit queues a reload job for the unit name and returns its object path
+func (srv *Server) ReloadUnit(name, mode string) (godbus.ObjectPath, *godbus.Error) {
+	return srv.job(name, "reload", srv.sys.Reload)
+}
+
+// ReloadOrRestartUnit queues a reload-or-restart job for the unit name and returns its object path
+func (srv *Server) ReloadOrRestartUnit(name, mode string) (godbus.ObjectPath, *godbus.Error) {
+	return srv.job(name, "reload-or-restart", srv.sys.ReloadOrRestart)
+}
+
+// Reload reloads the unit definitions
+func (srv *Server) Reload() *godbus.Error {
+	if err := srv.sys.ReloadDaemon(); err != nil {
+		return godbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// job runs fn on name in the background and returns the object path of the job.
+// JobNew and JobRemoved signals are emitted when the job is queued and finished
+func (srv *Server) job(name, typ string, fn func(...string) error) (godbus.ObjectPath, *godbus.Error) {
+	if _, err := srv.sys.Get(name); err != nil {
+		return "", noSuchUnit(name)
+	}
+
+	srv.mutex.Lock()
+	srv.lastJob++
+	id := srv.lastJob
+	srv.mutex.Unlock()
+
+	path := JobPath(id)
+	srv.emit(OBJECT_PATH, MANAGER_INTERFACE+".JobNew", id, path, name)
+
+	go func() {
+		result := "done"
+		err := fn(name)
+		srv.sys.Audit(system.AuditRecord{
+			Operation: typ,
+			Units:     []string{name},
+			Via:       system.AUDIT_VIA_DBUS,
+			UID:       -1,
+			PID:       -1,
+			Err:       err,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"unit": name,
+				"job":  typ,
+			}).Errorf("Job failed: %s", err)
+			result = "failed"
+		}
+		srv.emit(OBJECT_PATH, MANAGER_INTERFACE+".JobRemoved", id, path, name, result)
+	}()
+	return path, nil
+}
+
+// units serves the unit objects
+type units struct {
+	srv *Server
+}
+
+// unit returns the unit the object at path of msg corresponds to
+func (us *units) unit(msg godbus.Message) (u *system.Unit, derr *godbus.Error) {
+	path, _ := msg.Headers[godbus.FieldPath].Value().(godbus.ObjectPath)
+
+	name, ok := UnitName(path)
+	if !ok {
+		return nil, noSuchUnit(string(path))
+	}
+
+	u, err := us.srv.sys.Unit(name)
+	if err != nil {
+		return nil, noSuchUnit(name)
+	}
+	return u, nil
+}
+
+// Start starts the unit
+func (us *units) Start(msg godbus.Message, mode string) (godbus.ObjectPath, *godbus.Error) {
+	u, err := us.unit(msg)
+	if err != nil {
+		return "", err
+	}
+	return us.srv.StartUnit(u.Name(), mode)
+}
+
+// Stop stops the unit
+func (us *units) Stop(msg godbus.Message, mode string) (godbus.ObjectPath, *godbus.Error) {
+	u, err := us.unit(msg)
+	if err != nil {
+		return "", err
+	}
+	return us.srv.StopUnit(u.Name(), mode)
+}
+
+// Restart restarts the unit
+func (us *units) Restart(msg godbus.Message, mode string) (godbus.ObjectPath, *godbus.Error) {
+	u, err := us.unit(msg)
+	if err != nil {
+		return "", err
+	}
+	return us.srv.RestartUnit(u.Name(), mode)
+}
+
+// Reload reloads the unit
+func (us *units) Reload(msg godbus.Message, mode string) (godbus.ObjectPath, *godbus.Error) {
+	u, err := us.unit(msg)
+	if err != nil {
+		return "", err
+	}
+	return us.srv.ReloadUnit(u.Name(), mode)
+}
+
+// Get returns the property prop of the unit
+func (us *units) Get(msg godbus.Message, iface, prop string) (godbus.Variant, *godbus.Error) {
+	props, err := us.GetAll(msg, iface)
+	if err != nil {
+		return godbus.Variant{}, err
+	}
+
+	v, ok := props[prop]
+	if !ok {
+		return godbus.Variant{}, godbus.NewError(ERR_UNKNOWN_PROP, []interface{}{prop})
+	}
+	return v, nil
+}
+
+// GetAll returns all properties of the unit
+func (us *units) GetAll(msg godbus.Message, iface string) (map[string]godbus.Variant, *godbus.Error) {
+	if iface != UNIT_INTERFACE && iface != "" {
+		return nil, godbus.NewError(ERR_UNKNOWN_IFACE, []interface{}{iface})
+	}
+
+	u, err := us.unit(msg)
+	if err != nil {
+		return nil, err
+	}
+	return properties(u), nil
+}
+
+// Set always fails, properties of units are read-only
+func (us *units) Set(msg godbus.Message, iface, prop string, v godbus.Variant) *godbus.Error {
+	return godbus.NewError(ERR_NOT_IMPLEMENTED, []interface{}{"properties are read-only"})
+}
+
+// properties returns the properties of the Unit interface of u
+func properties(u *system.Unit) map[string]godbus.Variant {
+	return map[string]godbus.Variant{
+		"Id":            godbus.MakeVariant(u.Name()),
+		"Names":         godbus.MakeVariant([]string{u.Name()}),
+		"Description":   godbus.MakeVariant(u.Description()),
+		"LoadState":     godbus.MakeVariant(loadState(u.Loaded())),
+		"ActiveState":   godbus.MakeVariant(activeState(u.Active())),
+		"SubState":      godbus.MakeVariant(u.Sub()),
+		"FragmentPath":  godbus.MakeVariant(u.Path()),
+		"UnitFileState": godbus.MakeVariant(enableState(u.EnableState())),
+		"Requires":      godbus.MakeVariant(nonNil(u.Requires())),
+		"Wants":         godbus.MakeVariant(nonNil(u.Wants())),
+	}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// UnitPath returns the object path of the unit name.
+// Characters other than ASCII letters and digits are escaped as "_xx", where xx is the hex value of the byte
+func UnitPath(name string) godbus.ObjectPath {
+	return UNIT_PATH + "/" + godbus.ObjectPath(escape(name))
+}
+
+// UnitName returns the name of the unit, object path of which is path
+func UnitName(path godbus.ObjectPath) (name string, ok bool) {
+	prefix := string(UNIT_PATH) + "/"
+	if !strings.HasPrefix(string(path), prefix) {
+		return "", false
+	}
+	return unescape(strings.TrimPrefix(string(path), prefix))
+}
+
+// JobPath returns the object path of the job with id
+func JobPath(id uint32) godbus.ObjectPath {
+	return JOB_PATH + godbus.ObjectPath(fmt.Sprintf("/%d", id))
+}
+
+func escape(s string) string {
+	if s == "" {
+		return "_"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "_%02x", c)
+	}
+	return b.String()
+}
+
+func unescape(s string) (string, bool) {
+	if s == "_" {
+		return "", true
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		var c byte
+		if i+2 >= len(s) {
+			return "", false
+		}
+		if _, err := fmt.Sscanf(s[i+1:i+3], "%02x", &c); err != nil {
+			return "", false
+		}
+		b.WriteByte(c)
+		i += 2
+	}
+	return b.String(), true
+}
+
+// loadState returns the name of st as used by systemd
+func loadState(st unit.Load) string {
+	if st == unit.NotFound {
+		return "not-found"
+	}
+	return strings.ToLower(fmt.Sprint(st))
+}
+
+// activeState returns the name of st as used by systemd
+func activeState(st unit.Activation) string {
+	return strings.ToLower(fmt.Sprint(st))
+}
+
+// enableState returns the name of st as used by systemd
+func enableState(st unit.Enable) string {
+	if st == unit.EnabledRuntime {
+		return "enabled-runtime"
+	}
+	return strings.ToLower(fmt.Sprint(st))
+}
+
+// noSuchUnit returns the error returned, when the unit name is not found
+func noSuchUnit(name string) *godbus.Error {
+	return godbus.NewError(ERR_NO_SUCH_UNIT, []interface{}{fmt.Sprintf("Unit %s not found.", name)})
+}
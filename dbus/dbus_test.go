@@ -0,0 +1,123 @@
+package dbus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/system"
+	"systemgo/unit"
+)
+
+func TestUnitPath(t *testing.T) {
+	for name, path := range map[string]godbus.ObjectPath{
+		"foo.service":        "/org/freedesktop/systemd1/unit/foo_2eservice",
+		"dev-sda1.swap":      "/org/freedesktop/systemd1/unit/dev_2dsda1_2eswap",
+		"getty@tty1.service": "/org/freedesktop/systemd1/unit/getty_40tty1_2eservice",
+		"1foo_bar.target":    "/org/freedesktop/systemd1/unit/_31foo_5fbar_2etarget",
+		"":                   "/org/freedesktop/systemd1/unit/_",
+	} {
+		assert.Equal(t, path, UnitPath(name), name)
+		assert.True(t, path.IsValid(), name)
+
+		got, ok := UnitName(path)
+		assert.True(t, ok, name)
+		assert.Equal(t, name, got)
+	}
+
+	for _, path := range []godbus.ObjectPath{
+		"/org/freedesktop/systemd1",
+		"/org/freedesktop/systemd1/unit/foo_2",
+		"/org/freedesktop/systemd1/unit/foo_zz",
+	} {
+		_, ok := UnitName(path)
+		assert.False(t, ok, path)
+	}
+
+	assert.Equal(t, godbus.ObjectPath("/org/freedesktop/systemd1/job/42"), JobPath(42))
+}
+
+func TestStates(t *testing.T) {
+	assert.Equal(t, "not-found", loadState(unit.NotFound))
+	assert.Equal(t, "loaded", loadState(unit.Loaded))
+	assert.Equal(t, "deactivating", activeState(unit.Deactivating))
+	assert.Equal(t, "enabled-runtime", enableState(unit.EnabledRuntime))
+	assert.Equal(t, "static", enableState(unit.Static))
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbus-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.service"),
+		[]byte("[Unit]\nDescription=Foo\n[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true"), 0644))
+
+	sys := system.New()
+	sys.SetPaths(dir)
+
+	srv := &Server{sys: sys, subscribers: map[string]bool{}}
+
+	_, derr := srv.GetUnit("foo.service")
+	require.NotNil(t, derr)
+	assert.Equal(t, ERR_NO_SUCH_UNIT, derr.Name)
+
+	path, derr := srv.LoadUnit("foo.service")
+	require.Nil(t, derr)
+	assert.Equal(t, UnitPath("foo.service"), path)
+
+	path, derr = srv.GetUnit("foo.service")
+	require.Nil(t, derr)
+	assert.Equal(t, UnitPath("foo.service"), path)
+
+	_, derr = srv.StartUnit("bar.service", "replace")
+	require.NotNil(t, derr)
+
+	job, derr := srv.StartUnit("foo.service", "replace")
+	require.Nil(t, derr)
+	assert.Equal(t, JobPath(1), job)
+
+	assert.Eventually(t, func() bool {
+		st, err := sys.IsActive("foo.service")
+		return err == nil && st == unit.Active
+	}, time.Second, 10*time.Millisecond)
+
+	infos, derr := srv.ListUnits()
+	require.Nil(t, derr)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "foo.service", infos[0].Name)
+	assert.Equal(t, "Foo", infos[0].Description)
+	assert.Equal(t, "loaded", infos[0].LoadState)
+	assert.Equal(t, "active", infos[0].ActiveState)
+
+	msg := godbus.Message{Headers: map[godbus.HeaderField]godbus.Variant{
+		godbus.FieldPath: godbus.MakeVariant(path),
+	}}
+	us := &units{srv}
+
+	v, derr := us.Get(msg, UNIT_INTERFACE, "ActiveState")
+	require.Nil(t, derr)
+	assert.Equal(t, "active", v.Value())
+
+	v, derr = us.Get(msg, UNIT_INTERFACE, "Id")
+	require.Nil(t, derr)
+	assert.Equal(t, "foo.service", v.Value())
+
+	_, derr = us.Get(msg, UNIT_INTERFACE, "Nonexistent")
+	require.NotNil(t, derr)
+	assert.Equal(t, ERR_UNKNOWN_PROP, derr.Name)
+
+	_, derr = us.GetAll(msg, MANAGER_INTERFACE)
+	require.NotNil(t, derr)
+	assert.Equal(t, ERR_UNKNOWN_IFACE, derr.Name)
+
+	require.Nil(t, srv.Subscribe("sender"))
+	assert.True(t, srv.subscribed())
+	require.Nil(t, srv.Unsubscribe("sender"))
+	assert.False(t, srv.subscribed())
+}
@@ -0,0 +1,517 @@
+// Package crontab converts crontab(5) entries to timer and service units
+package crontab
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"systemgo/unit"
+)
+
+// Default paths to the system crontab and the directory of crontabs of packages
+const (
+	CRONTAB = "/etc/crontab"
+	CRON_D  = "/etc/cron.d"
+)
+
+// Target pulling in the timers generated
+const TIMERS_TARGET = "timers.target"
+
+// Delay after boot @reboot entries are run at
+const REBOOT_DELAY = "1min"
+
+// Shell commands are run by, unless SHELL is set in the crontab
+const DEFAULT_SHELL = "/bin/sh"
+
+// Calendar events of the special schedules
+var keywords = map[string]string{
+	"@yearly":   "yearly",
+	"@annually": "yearly",
+	"@monthly":  "monthly",
+	"@weekly":   "weekly",
+	"@daily":    "daily",
+	"@midnight": "daily",
+	"@hourly":   "hourly",
+}
+
+// Names of months and weekdays accepted in schedules
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// Entry of a system crontab
+type Entry struct {
+	// Five time and date fields or one of the special schedules, e.g. "@daily"
+	Schedule string
+
+	User    string
+	Command string
+
+	// Variables assigned in the crontab before the entry, as "NAME=value"
+	Environment []string
+
+	// Name of the crontab, "crontab" for /etc/crontab or the name of the file in /etc/cron.d
+	Source string
+
+	// Line of the crontab the entry is found at
+	Line int
+}
+
+// Parse parses the system crontab named source read from r, entries of which specify the user
+// commands are run as. Comments and blank lines are skipped
+func Parse(r io.Reader, source string) (entries []Entry, err error) {
+	var env []string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if name, value, ok := parseAssignment(line); ok {
+			env = append(env, name+"="+value)
+			continue
+		}
+
+		nfields := 6
+		if line[0] == '@' {
+			nfields = 2
+		}
+
+		fields, command := splitFields(line, nfields)
+		if len(fields) < nfields || command == "" {
+			return nil, fmt.Errorf("line %d: expected schedule, user and command", n)
+		}
+
+		e := Entry{
+			Schedule:    strings.Join(fields[:nfields-1], " "),
+			User:        fields[nfields-1],
+			Command:     command,
+			Environment: append([]string(nil), env...),
+			Source:      source,
+			Line:        n,
+		}
+		if _, err = e.Calendars(); err != nil {
+			return nil, fmt.Errorf("line %d: %s", n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// parseAssignment parses line as an environment variable assignment, "NAME = value".
+// Values may be quoted
+func parseAssignment(line string) (name, value string, ok bool) {
+	i := strings.Index(line, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+
+	name = strings.TrimSpace(line[:i])
+	for j, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || j > 0 && unicode.IsDigit(r)) {
+			return "", "", false
+		}
+	}
+
+	value = strings.TrimSpace(line[i+1:])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return name, value, true
+}
+
+// splitFields splits the first n whitespace separated fields off line and returns them along with the rest of line
+func splitFields(line string, n int) (fields []string, rest string) {
+	rest = line
+	for len(fields) < n {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		i := strings.IndexFunc(rest, unicode.IsSpace)
+		if i < 0 {
+			i = len(rest)
+		}
+		fields = append(fields, rest[:i])
+		rest = rest[i:]
+	}
+	return fields, strings.TrimSpace(rest)
+}
+
+// IsReboot returns whether e is run once after boot
+func (e Entry) IsReboot() bool {
+	return e.Schedule == "@reboot"
+}
+
+// Calendars returns the calendar events as accepted by OnCalendar= equivalent to the schedule of e,
+// none for @reboot entries. If both the day of month and the day of week are restricted,
+// commands are run, when either matches, so an event is returned for each
+func (e Entry) Calendars() (calendars []string, err error) {
+	if e.IsReboot() {
+		return nil, nil
+	}
+	if c, ok := keywords[e.Schedule]; ok {
+		return []string{c}, nil
+	}
+
+	fields := strings.Fields(e.Schedule)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule: %q", e.Schedule)
+	}
+
+	var minute, hour, dom, month, dow string
+	if minute, err = convertField(fields[0], 0, nil); err != nil {
+		return
+	}
+	if hour, err = convertField(fields[1], 0, nil); err != nil {
+		return
+	}
+	if dom, err = convertField(fields[2], 1, nil); err != nil {
+		return
+	}
+	if month, err = convertField(fields[3], 1, monthNames); err != nil {
+		return
+	}
+	if dow, err = convertWeekdays(fields[4]); err != nil {
+		return
+	}
+
+	clock := fmt.Sprintf("%s:%s:00", hour, minute)
+	switch {
+	case dow == "":
+		calendars = []string{fmt.Sprintf("*-%s-%s %s", month, dom, clock)}
+	case dom == "*":
+		calendars = []string{fmt.Sprintf("%s *-%s-* %s", dow, month, clock)}
+	default:
+		calendars = []string{
+			fmt.Sprintf("%s *-%s-* %s", dow, month, clock),
+			fmt.Sprintf("*-%s-%s %s", month, dom, clock),
+		}
+	}
+
+	for _, c := range calendars {
+		if _, err = unit.ParseCalendar(c); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %s", e.Schedule, err)
+		}
+	}
+	return calendars, nil
+}
+
+// convertField converts a time or date field of a schedule to a component of a calendar event.
+// Ranges "a-b" become "a..b", steps of "*" start at min. names are the names of values starting at min, if any
+func convertField(field string, min int, names []string) (string, error) {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		step := ""
+		if j := strings.Index(item, "/"); j >= 0 {
+			item, step = item[:j], item[j:]
+		}
+
+		switch {
+		case item == "*" && step != "":
+			item = strconv.Itoa(min)
+		case item == "*":
+		default:
+			bounds := strings.SplitN(item, "-", 2)
+			for k, b := range bounds {
+				v, err := fieldValue(b, min, names)
+				if err != nil {
+					return "", err
+				}
+				bounds[k] = strconv.Itoa(v)
+			}
+			item = strings.Join(bounds, "..")
+		}
+		items[i] = item + step
+	}
+	return strings.Join(items, ","), nil
+}
+
+// fieldValue parses s as a number or one of names, which are the names of values starting at min
+func fieldValue(s string, min int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.ToLower(s) == name {
+			return min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %q", s)
+	}
+	return v, nil
+}
+
+// convertWeekdays converts the day of week field of a schedule to a list of weekdays of a calendar event,
+// empty, if any day matches. Both 0 and 7 are Sunday
+func convertWeekdays(field string) (string, error) {
+	if field == "*" {
+		return "", nil
+	}
+
+	var days [7]bool
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if j := strings.Index(item, "/"); j >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[j+1:]); err != nil || step <= 0 {
+				return "", fmt.Errorf("invalid step: %q", item)
+			}
+			item = item[:j]
+		}
+
+		from, to := 0, 7
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if from, err = fieldValue(bounds[0], 0, weekdayNames); err != nil {
+				return "", err
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = fieldValue(bounds[1], 0, weekdayNames); err != nil {
+					return "", err
+				}
+			}
+		}
+		if from < 0 || to > 7 || from > to {
+			return "", fmt.Errorf("invalid day of week: %q", item)
+		}
+
+		for d := from; d <= to; d += step {
+			days[d%7] = true
+		}
+	}
+
+	var names []string
+	for d, ok := range days {
+		if ok {
+			name := weekdayNames[d]
+			names = append(names, strings.ToUpper(name[:1])+name[1:])
+		}
+	}
+	return strings.Join(names, ","), nil
+}
+
+// Name returns the name of the units generated for e without the suffix
+func (e Entry) Name() string {
+	return fmt.Sprintf("cron-%s-%s-%d", sanitize(e.Source), sanitize(e.User), e.Line)
+}
+
+// sanitize replaces the characters of s, which are not allowed in unit names, by underscores
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// shell returns the shell the command of e is run by
+func (e Entry) shell() string {
+	for _, kv := range e.Environment {
+		if strings.HasPrefix(kv, "SHELL=") {
+			return strings.TrimPrefix(kv, "SHELL=")
+		}
+	}
+	return DEFAULT_SHELL
+}
+
+// Timer returns the contents of the timer unit generated for e
+func (e Entry) Timer() []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintln(buf, "# Automatically generated by crontab-generator")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "[Unit]")
+	fmt.Fprintf(buf, "Description=[Cron] %s\n", e.Command)
+	fmt.Fprintln(buf, "Documentation=man:crontab(5)")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "[Timer]")
+
+	if e.IsReboot() {
+		fmt.Fprintf(buf, "OnBootSec=%s\n", REBOOT_DELAY)
+	}
+	calendars, _ := e.Calendars()
+	for _, c := range calendars {
+		fmt.Fprintf(buf, "OnCalendar=%s\n", c)
+	}
+
+	// Cron runs commands at the start of the minute
+	fmt.Fprintln(buf, "AccuracySec=1s")
+	return buf.Bytes()
+}
+
+// Service returns the contents of the service unit generated for e, which runs the script at path
+func (e Entry) Service(script string) []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintln(buf, "# Automatically generated by crontab-generator")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "[Unit]")
+	fmt.Fprintf(buf, "Description=[Cron] %s\n", e.Command)
+	fmt.Fprintln(buf, "Documentation=man:crontab(5)")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "[Service]")
+	fmt.Fprintln(buf, "Type=oneshot")
+
+	if e.User == "root" {
+		fmt.Fprintf(buf, "ExecStart=%s %s\n", e.shell(), script)
+	} else {
+		fmt.Fprintf(buf, "ExecStart=/bin/su -s %s %s %s\n", e.shell(), e.User, script)
+	}
+	return buf.Bytes()
+}
+
+// Script returns the contents of the shell script running the command of e.
+// Text of the command after the first unescaped "%" is passed as standard input, further ones are newlines
+func (e Entry) Script() []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintln(buf, "#!"+e.shell())
+	fmt.Fprintf(buf, "# Automatically generated by crontab-generator from line %d of %s\n", e.Line, e.Source)
+	for _, kv := range e.Environment {
+		i := strings.Index(kv, "=")
+		fmt.Fprintf(buf, "%s=%s; export %s\n", kv[:i], quote(kv[i+1:]), kv[:i])
+	}
+
+	command, input, hasInput := splitInput(e.Command)
+	if !hasInput {
+		fmt.Fprintln(buf, command)
+		return buf.Bytes()
+	}
+
+	fmt.Fprintf(buf, "%s <<'CRONTAB_INPUT'\n", command)
+	fmt.Fprintln(buf, input)
+	fmt.Fprintln(buf, "CRONTAB_INPUT")
+	return buf.Bytes()
+}
+
+// splitInput splits command at the first "%", which is not escaped by a backslash, into the command and
+// the standard input, in which further "%" are replaced by newlines
+func splitInput(command string) (cmd, input string, ok bool) {
+	var parts []string
+
+	cur := &strings.Builder{}
+	for i := 0; i < len(command); i++ {
+		switch {
+		case command[i] == '\\' && i+1 < len(command) && command[i+1] == '%':
+			cur.WriteByte('%')
+			i++
+		case command[i] == '%':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(command[i])
+		}
+	}
+	parts = append(parts, cur.String())
+
+	if len(parts) == 1 {
+		return parts[0], "", false
+	}
+	return parts[0], strings.Join(parts[1:], "\n"), true
+}
+
+// quote quotes s for the shell
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// Generate writes the timer and service units generated for entries to dir along with the scripts
+// running the commands and the symlinks in the '.wants' directory of TIMERS_TARGET pulling the timers in
+func Generate(entries []Entry, dir string) (err error) {
+	for _, e := range entries {
+		name := e.Name()
+
+		script := filepath.Join(dir, name+".sh")
+		if err = ioutil.WriteFile(script, e.Script(), 0755); err != nil {
+			return
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, name+".service"), e.Service(script), 0644); err != nil {
+			return
+		}
+
+		timer := filepath.Join(dir, name+".timer")
+		if err = ioutil.WriteFile(timer, e.Timer(), 0644); err != nil {
+			return
+		}
+
+		link := filepath.Join(dir, TIMERS_TARGET+".wants", name+".timer")
+		if err = os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			return
+		}
+		if err = os.Symlink(timer, link); err != nil && !os.IsExist(err) {
+			return
+		}
+	}
+	return nil
+}
+
+// ParseFile parses the system crontab at path named source, see Parse. Missing file is not an error
+func ParseFile(path, source string) (entries []Entry, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	if entries, err = Parse(f, source); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return entries, nil
+}
+
+// ParseDir parses the crontabs in dir named after the files, see Parse. As cron does, files are skipped,
+// names of which contain characters other than letters, digits, underscores and hyphens, e.g. backups
+// left by package managers. Entries of crontabs, which can be parsed, are returned along with the errors
+// encountered parsing the others
+func ParseDir(dir string) (entries []Entry, errs []error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || !validName(info.Name()) {
+			continue
+		}
+
+		parsed, err := ParseFile(filepath.Join(dir, info.Name()), info.Name())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		entries = append(entries, parsed...)
+	}
+	return entries, errs
+}
+
+// validName returns whether name is a name of a crontab in CRON_D, which is not skipped
+func validName(name string) bool {
+	for _, r := range name {
+		if !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,136 @@
+package crontab
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const table = `# /etc/crontab
+SHELL=/bin/sh
+PATH="/usr/local/bin:/usr/bin:/bin"
+
+17 *	* * *	root	cd / && run-parts --report /etc/cron.hourly
+*/15 9-17 * * mon-fri	nobody	/usr/bin/check   --quiet
+0 4 1 jan,jul 0	root	/usr/bin/rotate
+@daily	root	/usr/bin/backup
+@reboot	root	/usr/bin/warmup
+`
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(strings.NewReader(table), "crontab")
+	require.NoError(t, err, "Parse")
+	require.Len(t, entries, 5)
+
+	assert.Equal(t, "17 * * * *", entries[0].Schedule)
+	assert.Equal(t, "root", entries[0].User)
+	assert.Equal(t, "cd / && run-parts --report /etc/cron.hourly", entries[0].Command)
+	assert.Equal(t, []string{"SHELL=/bin/sh", "PATH=/usr/local/bin:/usr/bin:/bin"}, entries[0].Environment)
+	assert.Equal(t, "cron-crontab-root-5", entries[0].Name())
+
+	assert.Equal(t, "nobody", entries[1].User)
+	assert.Equal(t, "/usr/bin/check   --quiet", entries[1].Command)
+
+	assert.True(t, entries[4].IsReboot())
+
+	for i, expected := range [][]string{
+		{"*-*-* *:17:00"},
+		{"Mon,Tue,Wed,Thu,Fri *-*-* 9..17:0/15:00"},
+		{"Sun *-1,7-* 4:0:00", "*-1,7-1 4:0:00"},
+		{"daily"},
+		nil,
+	} {
+		calendars, err := entries[i].Calendars()
+		if assert.NoError(t, err, entries[i].Schedule) {
+			assert.Equal(t, expected, calendars, entries[i].Schedule)
+		}
+	}
+
+	for _, line := range []string{
+		"* * * * root",
+		"@daily root",
+		"61 * * * * root /bin/true",
+		"* * * foo * root /bin/true",
+		"* * * * 8 root /bin/true",
+		"@fortnightly root /bin/true",
+	} {
+		_, err = Parse(strings.NewReader(line), "crontab")
+		assert.Error(t, err, line)
+	}
+}
+
+func TestScript(t *testing.T) {
+	e := Entry{
+		Command:     `printf "%s\n" foo\%bar%baz%qux | sort`,
+		Environment: []string{"FOO=it's"},
+		Source:      "crontab",
+		Line:        1,
+	}
+	cmd, input, ok := splitInput(e.Command)
+	require.True(t, ok)
+	assert.Equal(t, `printf "`, cmd)
+	assert.Equal(t, "s\\n\" foo%bar\nbaz\nqux | sort", input)
+
+	e.Command = `echo "$FOO" 100\% && cat%bar%baz`
+	out, err := exec.Command("/bin/sh", "-c", string(e.Script())).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "it's 100%\nbar\nbaz\n", string(out), "environment and standard input are passed")
+}
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crontab-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	crond := filepath.Join(dir, "cron.d")
+	require.NoError(t, os.Mkdir(crond, 0755))
+	for name, contents := range map[string]string{
+		"php":          "09,39 * * * * root /usr/lib/php/sessionclean",
+		"php.dpkg-old": "* * * * * root /bin/false",
+		"broken":       "* * * * *",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(crond, name), []byte(contents), 0644))
+	}
+
+	entries, errs := ParseDir(crond)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "php", entries[0].Source)
+	assert.Len(t, errs, 1, "broken crontab is reported")
+
+	more, err := Parse(strings.NewReader(table), "crontab")
+	require.NoError(t, err, "Parse")
+	entries = append(entries, more...)
+
+	out := filepath.Join(dir, "out")
+	require.NoError(t, os.Mkdir(out, 0755))
+	require.NoError(t, Generate(entries, out), "Generate")
+
+	for _, path := range []string{
+		"cron-php-root-1.timer",
+		"cron-php-root-1.service",
+		"cron-php-root-1.sh",
+		"timers.target.wants/cron-php-root-1.timer",
+		"timers.target.wants/cron-crontab-root-9.timer",
+	} {
+		_, err := os.Stat(filepath.Join(out, path))
+		assert.NoError(t, err, path)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(out, "cron-php-root-1.timer"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "OnCalendar=*-*-* *:9,39:00\n")
+
+	b, err = ioutil.ReadFile(filepath.Join(out, "cron-crontab-root-9.timer"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "OnBootSec=1min\n")
+
+	b, err = ioutil.ReadFile(filepath.Join(out, "cron-crontab-nobody-6.service"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "Type=oneshot\nExecStart=/bin/su -s /bin/sh nobody "+filepath.Join(out, "cron-crontab-nobody-6.sh")+"\n")
+}
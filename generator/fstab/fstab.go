@@ -0,0 +1,271 @@
+// Package fstab converts fstab(5) entries to mount and swap units
+package fstab
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"systemgo/unit/mount"
+
+	"github.com/coreos/go-systemd/unit"
+)
+
+// Default path to the file system table
+const FSTAB = "/etc/fstab"
+
+const (
+	LOCAL_FS_TARGET  = "local-fs.target"
+	REMOTE_FS_TARGET = "remote-fs.target"
+	SWAP_TARGET      = "swap.target"
+	UMOUNT_TARGET    = "umount.target"
+)
+
+// File system types, which require network
+var networkTypes = map[string]bool{
+	"nfs":       true,
+	"nfs4":      true,
+	"cifs":      true,
+	"smb3":      true,
+	"sshfs":     true,
+	"ncpfs":     true,
+	"glusterfs": true,
+	"ceph":      true,
+	"9p":        true,
+}
+
+// Prefixes of mount points of API file systems, which are not managed by units
+var apiMounts = []string{"/proc", "/sys", "/dev", "/run"}
+
+// Entry of the file system table
+type Entry struct {
+	Spec    string
+	File    string
+	VfsType string
+	Options []string
+}
+
+// Parse parses the file system table read from r.
+// Comments and blank lines are skipped, missing options default to "defaults"
+func Parse(r io.Reader) (entries []Entry, err error) {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 fields, got %d", n, len(fields))
+		}
+
+		e := Entry{
+			Spec:    mount.Unescape(fields[0]),
+			File:    mount.Unescape(fields[1]),
+			VfsType: fields[2],
+			Options: []string{"defaults"},
+		}
+		if len(fields) > 3 {
+			e.Options = strings.Split(fields[3], ",")
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// What returns the path to the device or file specified by e.Spec,
+// resolving UUID=, LABEL=, PARTUUID= and PARTLABEL= tags
+func (e Entry) What() string {
+	for tag, dir := range map[string]string{
+		"UUID=":      "by-uuid",
+		"LABEL=":     "by-label",
+		"PARTUUID=":  "by-partuuid",
+		"PARTLABEL=": "by-partlabel",
+	} {
+		if strings.HasPrefix(e.Spec, tag) {
+			return filepath.Join("/dev/disk", dir, strings.TrimPrefix(e.Spec, tag))
+		}
+	}
+	return e.Spec
+}
+
+// IsSwap returns whether e specifies a swap area
+func (e Entry) IsSwap() bool {
+	return e.VfsType == "swap"
+}
+
+// IsNetwork returns whether the file system specified by e requires network
+func (e Entry) IsNetwork() bool {
+	return networkTypes[e.VfsType] || e.HasOption("_netdev")
+}
+
+// HasOption returns whether opt is one of the options of e
+func (e Entry) HasOption(opt string) bool {
+	for _, o := range e.Options {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns values of all options of e named name(as in 'name=value')
+func (e Entry) Values(name string) (values []string) {
+	for _, o := range e.Options {
+		if strings.HasPrefix(o, name+"=") {
+			values = append(values, strings.TrimPrefix(o, name+"="))
+		}
+	}
+	return
+}
+
+// mountOptions returns options of e, which are passed to mount(8) or swapon(8)
+func (e Entry) mountOptions() string {
+	opts := make([]string, 0, len(e.Options))
+	for _, o := range e.Options {
+		if !strings.HasPrefix(o, "x-systemd.") {
+			opts = append(opts, o)
+		}
+	}
+	return strings.Join(opts, ",")
+}
+
+// Name returns the name of the unit generated for e
+func (e Entry) Name() string {
+	if e.IsSwap() {
+		return unit.UnitNamePathEscape(e.What()) + ".swap"
+	}
+	return unit.UnitNamePathEscape(filepath.Clean(e.File)) + ".mount"
+}
+
+// Target returns the name of the target, which pulls in the unit generated for e
+func (e Entry) Target() string {
+	switch {
+	case e.IsSwap():
+		return SWAP_TARGET
+	case e.IsNetwork():
+		return REMOTE_FS_TARGET
+	default:
+		return LOCAL_FS_TARGET
+	}
+}
+
+// isManaged returns whether a unit should be generated for e
+func (e Entry) isManaged() bool {
+	if e.IsSwap() {
+		return true
+	}
+
+	if !filepath.IsAbs(e.File) {
+		return false
+	}
+
+	file := filepath.Clean(e.File)
+	for _, prefix := range apiMounts {
+		if file == prefix || strings.HasPrefix(file, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// Unit returns the contents of the unit file generated for e
+func (e Entry) Unit() []byte {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintln(buf, "# Automatically generated by fstab-generator")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "[Unit]")
+	fmt.Fprintln(buf, "Documentation=man:fstab(5)")
+
+	if !e.HasOption("nofail") {
+		fmt.Fprintf(buf, "Before=%s\n", e.Target())
+	}
+	if !e.IsSwap() {
+		fmt.Fprintf(buf, "Before=%s\n", UMOUNT_TARGET)
+	}
+
+	for _, name := range e.Values("x-systemd.requires") {
+		fmt.Fprintf(buf, "Requires=%s\nAfter=%s\n", name, name)
+	}
+	for _, name := range e.Values("x-systemd.wants") {
+		fmt.Fprintf(buf, "Wants=%s\n", name)
+	}
+	for _, name := range e.Values("x-systemd.after") {
+		fmt.Fprintf(buf, "After=%s\n", name)
+	}
+	for _, name := range e.Values("x-systemd.before") {
+		fmt.Fprintf(buf, "Before=%s\n", name)
+	}
+
+	fmt.Fprintln(buf)
+	if e.IsSwap() {
+		fmt.Fprintln(buf, "[Swap]")
+		fmt.Fprintf(buf, "What=%s\n", e.What())
+		for _, prio := range e.Values("pri") {
+			fmt.Fprintf(buf, "Priority=%s\n", prio)
+		}
+	} else {
+		fmt.Fprintln(buf, "[Mount]")
+		fmt.Fprintf(buf, "What=%s\n", e.What())
+		fmt.Fprintf(buf, "Where=%s\n", filepath.Clean(e.File))
+		if e.VfsType != "" && e.VfsType != "auto" {
+			fmt.Fprintf(buf, "Type=%s\n", e.VfsType)
+		}
+	}
+	if opts := e.mountOptions(); opts != "" {
+		fmt.Fprintf(buf, "Options=%s\n", opts)
+	}
+	return buf.Bytes()
+}
+
+// links returns paths relative to the output directory of the symlinks to the unit generated for e
+func (e Entry) links() (links []string) {
+	if !e.HasOption("noauto") {
+		dir := ".requires"
+		if e.HasOption("nofail") {
+			dir = ".wants"
+		}
+		links = append(links, filepath.Join(e.Target()+dir, e.Name()))
+	}
+
+	for _, target := range e.Values("x-systemd.wanted-by") {
+		links = append(links, filepath.Join(target+".wants", e.Name()))
+	}
+	for _, target := range e.Values("x-systemd.required-by") {
+		links = append(links, filepath.Join(target+".requires", e.Name()))
+	}
+	return
+}
+
+// Generate writes units generated for entries to dir along with the symlinks in '.wants'
+// and '.requires' directories of the targets pulling them in
+func Generate(entries []Entry, dir string) (err error) {
+	for _, e := range entries {
+		if !e.isManaged() {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+		if err = ioutil.WriteFile(path, e.Unit(), 0644); err != nil {
+			return
+		}
+
+		for _, link := range e.links() {
+			link = filepath.Join(dir, link)
+			if err = os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+				return
+			}
+			if err = os.Symlink(path, link); err != nil && !os.IsExist(err) {
+				return
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package fstab
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const table = `# /etc/fstab
+UUID=1234	/		ext4	errors=remount-ro	0 1
+/dev/sdb1	/mnt/data	auto	nofail,x-systemd.requires=foo.service	0 2
+server:/srv	/mnt/nfs	nfs	noauto
+/dev/sda2	none		swap	sw,pri=5	0 0
+proc		/proc		proc	defaults	0 0
+`
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(strings.NewReader(table))
+	require.NoError(t, err, "Parse")
+	require.Len(t, entries, 5)
+
+	assert.Equal(t, "/dev/disk/by-uuid/1234", entries[0].What())
+	assert.Equal(t, "-.mount", entries[0].Name())
+	assert.Equal(t, LOCAL_FS_TARGET, entries[0].Target())
+
+	assert.Equal(t, "mnt-data.mount", entries[1].Name())
+	assert.Equal(t, []string{"foo.service"}, entries[1].Values("x-systemd.requires"))
+	assert.Equal(t, "nofail", entries[1].mountOptions())
+
+	assert.True(t, entries[2].IsNetwork())
+	assert.Equal(t, REMOTE_FS_TARGET, entries[2].Target())
+
+	assert.True(t, entries[3].IsSwap())
+	assert.Equal(t, "dev-sda2.swap", entries[3].Name())
+	assert.Equal(t, SWAP_TARGET, entries[3].Target())
+
+	assert.False(t, entries[4].isManaged())
+
+	_, err = Parse(strings.NewReader("/dev/sda1 /"))
+	assert.Error(t, err, "Parse with missing fields")
+}
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fstab-test")
+	require.NoError(t, err, "ioutil.TempDir")
+	defer os.RemoveAll(dir)
+
+	entries, err := Parse(strings.NewReader(table))
+	require.NoError(t, err, "Parse")
+	require.NoError(t, Generate(entries, dir), "Generate")
+
+	for _, path := range []string{
+		"-.mount",
+		"mnt-data.mount",
+		"mnt-nfs.mount",
+		"dev-sda2.swap",
+		"local-fs.target.requires/-.mount",
+		"local-fs.target.wants/mnt-data.mount",
+		"swap.target.requires/dev-sda2.swap",
+	} {
+		_, err := os.Stat(filepath.Join(dir, path))
+		assert.NoError(t, err, path)
+	}
+
+	for _, path := range []string{
+		"proc.mount",
+		"remote-fs.target.requires",
+		"remote-fs.target.wants",
+	} {
+		_, err := os.Stat(filepath.Join(dir, path))
+		assert.True(t, os.IsNotExist(err), path)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "mnt-data.mount"))
+	require.NoError(t, err, "ioutil.ReadFile")
+	assert.Contains(t, string(b), "Requires=foo.service\nAfter=foo.service\n")
+	assert.NotContains(t, string(b), "Before=local-fs.target")
+	assert.NotContains(t, string(b), "Type=")
+}
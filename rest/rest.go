@@ -0,0 +1,319 @@
+// Package rest serves a JSON API of a system.Daemon over HTTP
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"systemgo/system"
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var errBadRequest = errors.New("Malformed request")
+var errMethodNotAllowed = errors.New("Method not allowed")
+
+// Handler serves the following endpoints:
+//
+//	GET  /status                 status of the system
+//	GET  /log                    system log
+//	GET  /metrics                resource usage of units in the Prometheus text format
+//	GET  /units                  statuses of all loaded units
+//	POST /units                  link the unit file at "Path" of the body
+//	GET  /units/{name}           status of the unit name
+//	GET  /units/{name}/status    status of the unit name
+//	POST /units/{name}/{action}  perform action on the unit name, see actions
+//
+// Errors are reported as {"Error": "..."} with status code corresponding to the error
+type Handler struct {
+	sys *system.Daemon
+}
+
+// NewHandler returns a Handler serving the API of sys
+func NewHandler(sys *system.Daemon) *Handler {
+	return &Handler{sys}
+}
+
+// Unit status as reported by the API
+type unitStatus struct {
+	Name string `json:"Name"`
+	unit.Status
+
+	// Log as text, instead of base64
+	Log string `json:"Log,omitempty"`
+}
+
+// System status as reported by the API
+type systemStatus struct {
+	system.Status
+
+	// Log is served by /log
+	Log string `json:"Log,omitempty"`
+}
+
+// Outcome of an action performed on a unit
+type actionReply struct {
+	Error   string         `json:"Error,omitempty"`
+	Results system.Results `json:"Results,omitempty"`
+}
+
+// Body of a link request
+type linkRequest struct {
+	Path string `json:"Path"`
+}
+
+// Actions performed on POST /units/{name}/{action}
+var actions = map[string]func(*system.Daemon, string) error{
+	"start":                 func(sys *system.Daemon, name string) error { return sys.Start(name) },
+	"stop":                  func(sys *system.Daemon, name string) error { return sys.Stop(name) },
+	"restart":               func(sys *system.Daemon, name string) error { return sys.Restart(name) },
+	"try-restart":           func(sys *system.Daemon, name string) error { return sys.TryRestart(name) },
+	"reload":                func(sys *system.Daemon, name string) error { return sys.Reload(name) },
+	"reload-or-restart":     func(sys *system.Daemon, name string) error { return sys.ReloadOrRestart(name) },
+	"try-reload-or-restart": func(sys *system.Daemon, name string) error { return sys.ReloadOrTryRestart(name) },
+	"isolate":               func(sys *system.Daemon, name string) error { return sys.Isolate(name) },
+	"enable":                func(sys *system.Daemon, name string) error { return sys.Enable(name) },
+	"disable":               func(sys *system.Daemon, name string) error { return sys.Disable(name) },
+	"mask":                  func(sys *system.Daemon, name string) error { return sys.Mask(name) },
+	"unmask":                func(sys *system.Daemon, name string) error { return sys.Unmask(name) },
+	"preset":                func(sys *system.Daemon, name string) error { return sys.Preset(name) },
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.WithFields(log.Fields{
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}).Debugf("rest.ServeHTTP")
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "status":
+		h.status(w, r)
+	case len(parts) == 1 && parts[0] == "log":
+		h.logs(w, r)
+	case len(parts) == 1 && parts[0] == "metrics":
+		h.metrics(w, r)
+	case len(parts) == 1 && parts[0] == "units":
+		h.units(w, r)
+	case len(parts) == 2 && parts[0] == "units",
+		len(parts) == 3 && parts[0] == "units" && parts[2] == "status":
+		h.unitStatus(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "units":
+		h.action(w, r, parts[1], parts[2])
+	default:
+		writeError(w, http.StatusNotFound, system.ErrNotFound)
+	}
+}
+
+func (h *Handler) status(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	st, err := h.sys.Status()
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	st.Log = nil
+
+	writeJSON(w, http.StatusOK, systemStatus{Status: st})
+}
+
+func (h *Handler) logs(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	b, err := ioutil.ReadAll(h.sys.Log.Cursor())
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"Log": string(b)})
+}
+
+// Metrics served by /metrics
+var metrics = []struct {
+	name, typ, help string
+	value           func(unit.ResourceUsage) float64
+}{
+	{"systemgo_unit_cpu_usage_seconds_total", "counter", "CPU time consumed by the processes of the unit.",
+		func(u unit.ResourceUsage) float64 { return u.CPU.Seconds() }},
+	{"systemgo_unit_memory_bytes", "gauge", "Memory currently used by the processes of the unit.",
+		func(u unit.ResourceUsage) float64 { return float64(u.Memory) }},
+	{"systemgo_unit_memory_peak_bytes", "gauge", "Peak memory used by the processes of the unit.",
+		func(u unit.ResourceUsage) float64 { return float64(u.MemoryPeak) }},
+	{"systemgo_unit_io_read_bytes_total", "counter", "Bytes read by the processes of the unit.",
+		func(u unit.ResourceUsage) float64 { return float64(u.IORead) }},
+	{"systemgo_unit_io_write_bytes_total", "counter", "Bytes written by the processes of the unit.",
+		func(u unit.ResourceUsage) float64 { return float64(u.IOWrite) }},
+	{"systemgo_unit_tasks", "gauge", "Number of tasks of the unit.",
+		func(u unit.ResourceUsage) float64 { return float64(u.Tasks) }},
+}
+
+func (h *Handler) metrics(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	units := h.sys.Units()
+	sort.Slice(units, func(i, j int) bool { return units[i].Name() < units[j].Name() })
+
+	usages := make([]unit.ResourceUsage, len(units))
+	for i, u := range units {
+		usages[i] = u.ResourceUsage()
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.typ)
+		for i, u := range units {
+			fmt.Fprintf(w, "%s{unit=%q} %s\n", m.name, u.Name(), strconv.FormatFloat(m.value(usages[i]), 'g', -1, 64))
+		}
+	}
+}
+
+func (h *Handler) units(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		units := h.sys.Units()
+		sort.Slice(units, func(i, j int) bool { return units[i].Name() < units[j].Name() })
+
+		sts := make([]unitStatus, 0, len(units))
+		for _, u := range units {
+			st := u.Status()
+			st.Log = nil
+			sts = append(sts, unitStatus{Name: u.Name(), Status: st})
+		}
+		writeJSON(w, http.StatusOK, sts)
+
+	case http.MethodPost:
+		var req linkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+			writeError(w, http.StatusBadRequest, errBadRequest)
+			return
+		}
+
+		if err := h.sys.Link(req.Path); err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, actionReply{})
+
+	default:
+		allow(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (h *Handler) unitStatus(w http.ResponseWriter, r *http.Request, name string) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	st, err := h.sys.StatusOf(name)
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, unitStatus{
+		Name:   name,
+		Status: st,
+		Log:    string(st.Log),
+	})
+}
+
+func (h *Handler) action(w http.ResponseWriter, r *http.Request, name, action string) {
+	fn, ok := actions[action]
+	if !ok {
+		writeError(w, http.StatusNotFound, system.ErrNotFound)
+		return
+	}
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+
+	err := fn(h.sys, name)
+	h.sys.Audit(system.AuditRecord{
+		Operation: action,
+		Units:     []string{name},
+		Via:       system.AUDIT_VIA_REST,
+		Remote:    r.RemoteAddr,
+		UID:       -1,
+		PID:       -1,
+		Err:       err,
+	})
+	if err != nil {
+		reply := actionReply{Error: err.Error()}
+		if jerr, ok := err.(system.JobError); ok {
+			reply.Results = jerr.Results
+		}
+		writeJSON(w, statusCode(err), reply)
+		return
+	}
+	writeJSON(w, http.StatusOK, actionReply{})
+}
+
+// allow writes a 405 response and returns false, if method of r is not one of methods
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errMethodNotAllowed)
+	return false
+}
+
+// Status codes corresponding to errors, matched using errors.Is in order
+var statusCodes = []struct {
+	code    int
+	targets []error
+}{
+	{http.StatusNotFound, []error{system.ErrNotFound, system.ErrNotLoaded, os.ErrNotExist}},
+	{http.StatusConflict, []error{system.ErrExists, system.ErrMasked, system.ErrIrreversible, system.ErrUnmergeable}},
+	{http.StatusBadRequest, []error{system.ErrUnknownType, system.ErrNoReload, system.ErrIsDir, system.ErrNotDir}},
+	{http.StatusFailedDependency, []error{system.ErrDepFail, system.ErrDepConflict}},
+	{http.StatusNotImplemented, []error{system.ErrNotImplemented}},
+	{http.StatusGatewayTimeout, []error{system.ErrTimeout}},
+	{http.StatusTooManyRequests, []error{system.ErrStartLimit}},
+}
+
+// statusCode returns the HTTP status code corresponding to err
+func statusCode(err error) int {
+	for _, sc := range statusCodes {
+		for _, target := range sc.targets {
+			if errors.Is(err, target) {
+				return sc.code
+			}
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, actionReply{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("Error writing response: %s", err)
+	}
+}
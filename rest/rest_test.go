@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/system"
+)
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rest-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	units := filepath.Join(dir, "units")
+	require.NoError(t, os.Mkdir(units, 0755))
+	for name, contents := range map[string]string{
+		"foo.service": "[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true",
+		"bar.service": "[Service]\nType=oneshot\nExecStart=/bin/false",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(units, name), []byte(contents), 0644))
+	}
+
+	linked := filepath.Join(dir, "baz.service")
+	require.NoError(t, ioutil.WriteFile(linked, []byte("[Service]\nExecStart=/bin/true"), 0644))
+
+	other := filepath.Join(dir, "other", "baz.service")
+	require.NoError(t, os.Mkdir(filepath.Dir(other), 0755))
+	require.NoError(t, ioutil.WriteFile(other, []byte("[Service]\nExecStart=/bin/true"), 0644))
+
+	sys := system.New()
+	sys.SetPaths(units)
+
+	srv := httptest.NewServer(NewHandler(sys))
+	defer srv.Close()
+
+	do := func(method, path, body string, v interface{}) int {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		if v != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	var reply actionReply
+	assert.Equal(t, http.StatusOK, do("POST", "/units/foo.service/start", "", &reply))
+	assert.Empty(t, reply.Error)
+
+	var st map[string]interface{}
+	assert.Equal(t, http.StatusOK, do("GET", "/units/foo.service/status", "", &st))
+	assert.Equal(t, "foo.service", st["Name"])
+	assert.Equal(t, filepath.Join(units, "foo.service"), st["Load"].(map[string]interface{})["Path"])
+
+	assert.Equal(t, http.StatusOK, do("GET", "/units/foo.service", "", nil))
+
+	reply = actionReply{}
+	assert.Equal(t, http.StatusInternalServerError, do("POST", "/units/bar.service/start", "", &reply))
+	assert.NotEmpty(t, reply.Error)
+	assert.Contains(t, reply.Results, "bar.service")
+
+	var sts []map[string]interface{}
+	assert.Equal(t, http.StatusOK, do("GET", "/units", "", &sts))
+	require.Len(t, sts, 2)
+	assert.Equal(t, "bar.service", sts[0]["Name"])
+	assert.Equal(t, "foo.service", sts[1]["Name"])
+
+	reply = actionReply{}
+	assert.Equal(t, http.StatusNotFound, do("GET", "/units/nonexistent.service/status", "", &reply))
+	assert.Equal(t, (&system.LoadError{Name: "nonexistent.service", Err: system.ErrNotFound}).Error(), reply.Error)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/units/foo.bar/start", "", nil))
+	assert.Equal(t, http.StatusNotFound, do("POST", "/units/foo.service/explode", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, do("GET", "/units/foo.service/start", "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, do("DELETE", "/units", "", nil))
+	assert.Equal(t, http.StatusNotFound, do("GET", "/nonexistent", "", nil))
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/units", "{}", nil))
+	assert.Equal(t, http.StatusCreated, do("POST", "/units", `{"Path": "`+linked+`"}`, nil))
+	assert.Equal(t, http.StatusConflict, do("POST", "/units", `{"Path": "`+other+`"}`, nil))
+	assert.Equal(t, http.StatusNotFound, do("POST", "/units", `{"Path": "`+filepath.Join(dir, "nonexistent.service")+`"}`, nil))
+
+	var status map[string]interface{}
+	assert.Equal(t, http.StatusOK, do("GET", "/status", "", &status))
+	assert.Contains(t, status, "State")
+	assert.EqualValues(t, 1, status["Failed"])
+
+	var logs map[string]string
+	assert.Equal(t, http.StatusOK, do("GET", "/log", "", &logs))
+	assert.Contains(t, logs, "Log")
+}
+
+func TestMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rest-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sys := system.New()
+	sys.SetPaths()
+	sys.SetCgroup(dir)
+
+	_, err = sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true\nCPUAccounting=yes"))
+	require.NoError(t, err)
+
+	cgroup := filepath.Join(dir, "foo.service")
+	require.NoError(t, os.Mkdir(cgroup, 0755))
+	for name, contents := range map[string]string{
+		"cpu.stat":       "usage_usec 1500000\nuser_usec 1000000\n",
+		"memory.current": "4096\n",
+		"pids.current":   "2\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(cgroup, name), []byte(contents), 0644))
+	}
+
+	srv := httptest.NewServer(NewHandler(sys))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	body := string(b)
+	assert.Contains(t, body, "# TYPE systemgo_unit_cpu_usage_seconds_total counter\n")
+	assert.Contains(t, body, `systemgo_unit_cpu_usage_seconds_total{unit="foo.service"} 1.5`+"\n")
+	assert.Contains(t, body, `systemgo_unit_memory_bytes{unit="foo.service"} 4096`+"\n")
+	assert.Contains(t, body, `systemgo_unit_tasks{unit="foo.service"} 2`+"\n")
+	assert.Contains(t, body, `systemgo_unit_io_read_bytes_total{unit="foo.service"} 0`+"\n")
+}
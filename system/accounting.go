@@ -0,0 +1,164 @@
+package system
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"systemgo/unit"
+)
+
+// Controllers applying the resource control properties by property
+var resourceControllers = map[string]string{
+	"MemoryMax": "memory",
+	"CPUQuota":  "cpu",
+	"TasksMax":  "pids",
+}
+
+// accounting returns the resource accounting options of u by name, the manager defaults
+// apply to the ones u does not specify
+func (sys *Daemon) accounting(u *Unit) (acc map[string]bool) {
+	sys.mutex.RLock()
+	acc = map[string]bool{
+		"CPUAccounting":    sys.defaults.CPUAccounting,
+		"MemoryAccounting": sys.defaults.MemoryAccounting,
+		"IOAccounting":     sys.defaults.IOAccounting,
+		"TasksAccounting":  sys.defaults.TasksAccounting,
+	}
+	sys.mutex.RUnlock()
+
+	if a, ok := u.Interface.(unit.Accounter); ok {
+		for key, value := range a.Accounting() {
+			acc[key] = value
+		}
+	}
+	return
+}
+
+// cgroupControllers returns the cgroup v2 controllers, which account the resource usage of u
+// and apply its resource control properties, sorted by name
+func (sys *Daemon) cgroupControllers(u *Unit) (controllers []string) {
+	seen := map[string]bool{}
+	for key, enabled := range sys.accounting(u) {
+		if enabled {
+			seen[unit.AccountingControllers[key]] = true
+		}
+	}
+	if rc, ok := u.Interface.(unit.ResourceController); ok {
+		for key := range rc.Resources() {
+			seen[resourceControllers[key]] = true
+		}
+	}
+
+	for c := range seen {
+		controllers = append(controllers, c)
+	}
+	sort.Strings(controllers)
+	return
+}
+
+// enableControllers enables controllers in the cgroups created in the cgroup at dir
+func enableControllers(dir string, controllers []string) (err error) {
+	for _, c := range controllers {
+		if werr := ioutil.WriteFile(filepath.Join(dir, CGROUP_SUBTREE_CONTROL), []byte("+"+c), 0644); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return
+}
+
+// ResourceUsage returns the resource usage of the processes of u accounted in its cgroup.
+// Usage is zero, if processes of units are not put into cgroups
+func (u *Unit) ResourceUsage() (usage unit.ResourceUsage) {
+	if u.System == nil {
+		return
+	}
+
+	dir := u.System.cgroupOf(u)
+	if dir == "" {
+		return
+	}
+
+	acc := u.System.accounting(u)
+	if acc["CPUAccounting"] {
+		if usec, ok := readCgroupKey(filepath.Join(dir, CGROUP_CPU_STAT), "usage_usec"); ok {
+			usage.CPU = time.Duration(usec) * time.Microsecond
+		}
+	}
+	if acc["MemoryAccounting"] {
+		usage.Memory, _ = readCgroupValue(filepath.Join(dir, CGROUP_MEMORY_CURRENT))
+		usage.MemoryPeak, _ = readCgroupValue(filepath.Join(dir, CGROUP_MEMORY_PEAK))
+	}
+	if acc["IOAccounting"] {
+		usage.IORead, usage.IOWrite = readIOStat(filepath.Join(dir, CGROUP_IO_STAT))
+	}
+	if acc["TasksAccounting"] {
+		usage.Tasks, _ = readCgroupValue(filepath.Join(dir, CGROUP_PIDS_CURRENT))
+	}
+	return
+}
+
+// readCgroupValue reads the single value of the cgroup file at path
+func readCgroupValue(path string) (v uint64, ok bool) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	v, err = strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	return v, err == nil
+}
+
+// readCgroupKey reads the value of key in the flat keyed cgroup file at path, e.g. cpu.stat
+func readCgroupKey(path, key string) (v uint64, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if fields := strings.Fields(s.Text()); len(fields) == 2 && fields[0] == key {
+			v, err = strconv.ParseUint(fields[1], 10, 64)
+			return v, err == nil
+		}
+	}
+	return 0, false
+}
+
+// readIOStat returns the bytes read and written summed over the devices listed in the io.stat file at path
+func readIOStat(path string) (read, written uint64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// e.g. "8:0 rbytes=4096 wbytes=0 rios=1 wios=0 dbytes=0 dios=0"
+		for _, field := range strings.Fields(s.Text())[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			n, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			switch kv[0] {
+			case "rbytes":
+				read += n
+			case "wbytes":
+				written += n
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,74 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestResourceUsage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accounting-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader(`[Service]
+ExecStart=/bin/sleep 1000
+IOAccounting=yes
+MemoryAccounting=no
+TasksMax=10`))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{
+		"CPUAccounting":    false,
+		"MemoryAccounting": false,
+		"IOAccounting":     true,
+		"TasksAccounting":  true,
+	}, sys.accounting(u))
+	assert.Equal(t, []string{"io", "pids"}, sys.cgroupControllers(u))
+	assert.Equal(t, unit.ResourceUsage{}, u.ResourceUsage(), "cgroups are disabled")
+
+	sys.SetCgroup(dir)
+
+	cgroup := filepath.Join(dir, "foo.service")
+	require.NoError(t, os.Mkdir(cgroup, 0755))
+	for name, contents := range map[string]string{
+		CGROUP_CPU_STAT:       "usage_usec 2000000\n",
+		CGROUP_MEMORY_CURRENT: "4096\n",
+		CGROUP_IO_STAT:        "8:0 rbytes=1024 wbytes=512 rios=1 wios=1\n8:16 rbytes=1024 wbytes=0 rios=1 wios=0\n",
+		CGROUP_PIDS_CURRENT:   "3\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(cgroup, name), []byte(contents), 0644))
+	}
+
+	expected := unit.ResourceUsage{IORead: 2048, IOWrite: 512, Tasks: 3}
+	assert.Equal(t, expected, u.ResourceUsage())
+	assert.Equal(t, expected, u.Status().Usage)
+
+	props, err := sys.Properties("foo.service")
+	require.NoError(t, err)
+	for name, value := range map[string]string{
+		"CPUAccounting":    "no",
+		"MemoryAccounting": "no",
+		"IOAccounting":     "yes",
+		"IOReadBytes":      "2048",
+		"IOWriteBytes":     "512",
+		"TasksCurrent":     "3",
+	} {
+		assert.Equal(t, value, props[name], name)
+	}
+	assert.NotContains(t, props, "MemoryCurrent")
+	assert.NotContains(t, props, "CPUUsageNSec")
+
+	sys.defaults.CPUAccounting = true
+	assert.Equal(t, 2*time.Second, u.ResourceUsage().CPU)
+}
@@ -0,0 +1,208 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Default start rate limit of units, which do not specify their own
+const (
+	DEFAULT_START_LIMIT_INTERVAL = 10 * time.Second
+	DEFAULT_START_LIMIT_BURST    = 5
+)
+
+// Function used to exit the process on 'exit' actions
+var exit = os.Exit
+
+// action is a parsed FailureAction=, SuccessAction= or StartLimitAction= value
+type action struct {
+	kind ShutdownKind
+
+	// Whether the manager exits instead of shutting the system down
+	exit bool
+
+	// Whether units are not stopped cleanly
+	force bool
+
+	// Whether reboot(2) is invoked right away without unmounting file systems
+	immediate bool
+}
+
+// parseAction parses s, which is one of: none, reboot, reboot-force, reboot-immediate,
+// poweroff, poweroff-force, poweroff-immediate, halt, halt-force, halt-immediate, exit, exit-force
+func parseAction(s string) (a action, ok bool, err error) {
+	if s == "" || s == "none" {
+		return action{}, false, nil
+	}
+
+	name := s
+	switch {
+	case strings.HasSuffix(s, "-force"):
+		name, a.force = strings.TrimSuffix(s, "-force"), true
+	case strings.HasSuffix(s, "-immediate"):
+		name, a.immediate = strings.TrimSuffix(s, "-immediate"), true
+	}
+
+	switch name {
+	case "reboot":
+		a.kind = Reboot
+	case "poweroff":
+		a.kind = Poweroff
+	case "halt":
+		a.kind = Halt
+	case "exit":
+		if a.immediate {
+			return action{}, false, fmt.Errorf("unknown action: %q", s)
+		}
+		a.exit = true
+	default:
+		return action{}, false, fmt.Errorf("unknown action: %q", s)
+	}
+	return a, true, nil
+}
+
+// runAction executes action specified by option of u in background
+func (sys *Daemon) runAction(u *Unit, option, value string) {
+	a, ok, err := parseAction(value)
+	if err != nil {
+		u.Log.Errorf("%s: %s", option, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	u.Log.Printf("Executing %s=%s", option, value)
+	log.WithFields(log.Fields{
+		"unit":   u.Name(),
+		"action": value,
+	}).Infof("Executing %s", option)
+
+	go func() {
+		var err error
+		switch {
+		case a.exit && a.force:
+			exit(0)
+
+		case a.exit:
+			sys.setState(Stopping)
+
+			if err = sys.stopAll(); err != nil {
+				log.Errorf("Error stopping units: %s", err)
+			}
+			exit(0)
+
+		case a.force:
+			err = sys.reboot(a.kind, true)
+
+		case a.immediate:
+			err = sys.reboot(a.kind, false)
+
+		default:
+			err = sys.ShutdownSystem(a.kind)
+		}
+
+		if err != nil {
+			log.Errorf("Error executing %s=%s of %s: %s", option, value, u.Name(), err)
+		}
+	}()
+}
+
+// onTransition executes FailureAction= of u, if it failed, or SuccessAction=, if it
+// got deactivated successfully
+func (sys *Daemon) onTransition(u *Unit, prev, cur unit.Activation) {
+	spec, ok := u.Interface.(unit.ActionSpecifier)
+	if !ok {
+		return
+	}
+
+	switch {
+	case cur == unit.Failed:
+		sys.runAction(u, "FailureAction", spec.FailureAction())
+	case cur == unit.Inactive && (prev == unit.Active || prev == unit.Deactivating):
+		sys.runAction(u, "SuccessAction", spec.SuccessAction())
+	}
+}
+
+// startLimit returns the start rate limit of u
+func (sys *Daemon) startLimit(u *Unit) (interval time.Duration, burst int) {
+	sys.mutex.RLock()
+	interval, burst = sys.defaults.StartLimitIntervalSec, sys.defaults.StartLimitBurst
+	sys.mutex.RUnlock()
+
+	if limiter, ok := u.Interface.(unit.StartLimiter); ok {
+		if v, ok := limiter.StartLimitIntervalSec(); ok {
+			interval = v
+		}
+		if v, ok := limiter.StartLimitBurst(); ok {
+			burst = v
+		}
+	}
+	return
+}
+
+// checkStartLimit records a start attempt of u and returns ErrStartLimit, executing StartLimitAction=
+// of u, if there were more attempts than the burst of the start rate limit within its interval.
+// Rate limiting is disabled, if either of those is 0
+func (u *Unit) checkStartLimit() (err error) {
+	if u.System == nil {
+		return nil
+	}
+
+	interval, burst := u.System.startLimit(u)
+	if interval <= 0 || burst <= 0 {
+		return nil
+	}
+
+	u.mutex.Lock()
+	now := u.System.clock.Now()
+
+	starts := u.starts[:0]
+	for _, t := range u.starts {
+		if now.Sub(t) < interval {
+			starts = append(starts, t)
+		}
+	}
+
+	if len(starts) >= burst {
+		u.starts = starts
+		u.mutex.Unlock()
+
+		u.Log.Error("Start request repeated too quickly")
+		if spec, ok := u.Interface.(unit.ActionSpecifier); ok {
+			u.System.runAction(u, "StartLimitAction", spec.StartLimitAction())
+		}
+		return ErrStartLimit
+	}
+
+	u.starts = append(starts, now)
+	u.mutex.Unlock()
+	return nil
+}
+
+// reboot syncs file systems and invokes reboot(2) with the flag corresponding to kind,
+// if running as PID 1. File systems get unmounted first, if unmount is set
+func (sys *Daemon) reboot(kind ShutdownKind, unmount bool) (err error) {
+	e := log.WithField("kind", kind)
+
+	if os.Getpid() != 1 {
+		e.Info("Not running as PID 1, not invoking reboot")
+		return nil
+	}
+
+	if unmount {
+		unmountAll()
+	}
+	syscall.Sync()
+	sys.rebootWatchdog()
+
+	e.Info("Invoking reboot")
+	return syscall.Reboot(kind.rebootCmd())
+}
@@ -0,0 +1,83 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAction(t *testing.T) {
+	for s, expected := range map[string]action{
+		"reboot":             {kind: Reboot},
+		"reboot-force":       {kind: Reboot, force: true},
+		"poweroff-immediate": {kind: Poweroff, immediate: true},
+		"halt":               {kind: Halt},
+		"exit":               {exit: true},
+		"exit-force":         {exit: true, force: true},
+	} {
+		a, ok, err := parseAction(s)
+		if assert.NoError(t, err, s) {
+			assert.True(t, ok, s)
+			assert.Equal(t, expected, a, s)
+		}
+	}
+
+	for _, s := range []string{"", "none"} {
+		_, ok, err := parseAction(s)
+		assert.NoError(t, err, s)
+		assert.False(t, ok, s)
+	}
+
+	for _, s := range []string{"foo", "exit-immediate", "reboot-now"} {
+		_, _, err := parseAction(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestActions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "action-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"limited.service": "[Unit]\nStartLimitBurst=2\nStartLimitIntervalSec=1min\n[Service]\nType=oneshot\nExecStart=/bin/true",
+		"fail.service":    "[Unit]\nFailureAction=exit-force\n[Service]\nType=oneshot\nExecStart=/bin/false",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	exited := make(chan int, 1)
+	exit = func(code int) { exited <- code }
+	defer func() { exit = os.Exit }()
+
+	clock := NewFakeClock(time.Now())
+
+	sys := New()
+	sys.SetPaths(dir)
+	sys.SetClock(clock)
+
+	for i := 0; i < 2; i++ {
+		u, err := sys.Get("limited.service")
+		require.NoError(t, err)
+		require.NoError(t, u.start(), "start %d", i)
+	}
+	u, err := sys.Get("limited.service")
+	require.NoError(t, err)
+	assert.Equal(t, ErrStartLimit, u.start())
+
+	clock.Advance(time.Minute)
+	assert.NoError(t, u.start(), "start after StartLimitIntervalSec")
+
+	assert.Error(t, sys.Start("fail.service"))
+	select {
+	case code := <-exited:
+		assert.Equal(t, 0, code)
+	case <-time.After(time.Second):
+		t.Error("FailureAction not executed")
+	}
+}
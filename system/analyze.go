@@ -0,0 +1,257 @@
+package system
+
+import (
+	"sort"
+	"time"
+)
+
+// UnitTiming is an entry of the list returned by Blame
+type UnitTiming struct {
+	Name string
+
+	// Times the unit last started activating at and finished activating at
+	Activating, Activated time.Time
+}
+
+// Time returns the time the unit spent activating
+func (t UnitTiming) Time() time.Duration {
+	return t.Activated.Sub(t.Activating)
+}
+
+// finished reports whether the last activation of the unit finished
+func (t UnitTiming) finished() bool {
+	return !t.Activating.IsZero() && !t.Activated.Before(t.Activating)
+}
+
+// BootTiming is the time spent booting returned by BootTiming
+type BootTiming struct {
+	// Time spent by the kernel until sys started, zero if unknown
+	Kernel time.Duration
+
+	// Time spent by sys until Boot finished
+	Userspace time.Duration
+
+	// Name of the target reached on Boot, empty if none was
+	Target string
+
+	// Time elapsed since sys started until Target became active, zero if unknown
+	TargetReached time.Duration
+}
+
+// setActivating records the current time as the time u started activating at
+func (u *Unit) setActivating() {
+	if u.System == nil {
+		return
+	}
+	now := u.System.clock.Now()
+
+	u.mutex.Lock()
+	u.activating = now
+	u.mutex.Unlock()
+}
+
+// setActivated records the current time as the time u finished activating at
+func (u *Unit) setActivated() {
+	if u.System == nil {
+		return
+	}
+	now := u.System.clock.Now()
+
+	u.mutex.Lock()
+	u.activated = now
+	u.mutex.Unlock()
+}
+
+// timing returns the times u last started and finished activating at
+func (u *Unit) timing() UnitTiming {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return UnitTiming{
+		Name:       u.name,
+		Activating: u.activating,
+		Activated:  u.activated,
+	}
+}
+
+// Blame returns the units held in-memory, which finished activating, along with the times they spent activating,
+// sorted by the time spent, slowest first
+func (sys *Daemon) Blame() (timings []UnitTiming) {
+	timings = []UnitTiming{}
+	for _, u := range sys.Units() {
+		if t := u.timing(); t.finished() {
+			timings = append(timings, t)
+		}
+	}
+
+	sort.SliceStable(timings, func(i, j int) bool {
+		if ti, tj := timings[i].Time(), timings[j].Time(); ti != tj {
+			return ti > tj
+		}
+		return timings[i].Name < timings[j].Name
+	})
+	return
+}
+
+// BootTiming returns the time spent booting by the kernel and by sys.
+// ErrBootNotFinished is returned, if Boot has not finished yet
+func (sys *Daemon) BootTiming() (bt BootTiming, err error) {
+	sys.mutex.RLock()
+	finished, reached := sys.bootFinished, sys.bootReached
+	sys.mutex.RUnlock()
+
+	if finished.IsZero() {
+		return bt, ErrBootNotFinished
+	}
+
+	since := sys.Since()
+	bt.Userspace = finished.Sub(since)
+	if !sys.IsUser() {
+		if d := since.Sub(sys.bootTime()); d > 0 {
+			bt.Kernel = d
+		}
+	}
+
+	if reached == "" {
+		return bt, nil
+	}
+	bt.Target = reached
+	if reached == DEFAULT_TARGET {
+		if name, err := sys.DefaultTarget(); err == nil {
+			bt.Target = name
+		}
+	}
+
+	if u, err := sys.Unit(reached); err == nil {
+		if t := u.timing(); t.finished() {
+			bt.TargetReached = t.Activated.Sub(since)
+		}
+	}
+	return bt, nil
+}
+
+// Timeline is the timeline of the boot returned by Timeline
+type Timeline struct {
+	BootTiming
+
+	// Units, which finished activating, sorted by the time they started activating at
+	Units []TimelineEntry
+}
+
+// TimelineEntry is the activation interval of a unit on a Timeline
+type TimelineEntry struct {
+	Name string
+
+	// Time elapsed since sys started until the unit started activating and until it finished activating
+	Activating, Activated time.Duration
+}
+
+// Timeline returns the time spent booting along with the intervals the units held in-memory, which finished activating,
+// were activating in relative to the start of sys. ErrBootNotFinished is returned, if Boot has not finished yet
+func (sys *Daemon) Timeline() (tl Timeline, err error) {
+	if tl.BootTiming, err = sys.BootTiming(); err != nil {
+		return
+	}
+
+	since := sys.Since()
+	tl.Units = []TimelineEntry{}
+	for _, t := range sys.Blame() {
+		tl.Units = append(tl.Units, TimelineEntry{
+			Name:       t.Name,
+			Activating: t.Activating.Sub(since),
+			Activated:  t.Activated.Sub(since),
+		})
+	}
+
+	sort.SliceStable(tl.Units, func(i, j int) bool {
+		a, b := tl.Units[i], tl.Units[j]
+		if a.Activating != b.Activating {
+			return a.Activating < b.Activating
+		}
+		return a.Name < b.Name
+	})
+	return tl, nil
+}
+
+// ChainNode is a node of the critical chain returned by CriticalChain
+type ChainNode struct {
+	Name string
+
+	// Time elapsed since sys started until the unit finished activating and the time the unit spent activating,
+	// zero if the unit did not finish activating
+	At, Time time.Duration
+
+	// Units the unit is ordered after, which finished activating last, sorted by name
+	Deps []ChainNode `json:",omitempty"`
+}
+
+// CriticalChain returns the chain of units, which delayed the activation of the unit with name specified.
+// Of the units held in-memory, which the unit is ordered after and which finished activating before
+// it started activating, the ones, which finished last, are expanded recursively
+func (sys *Daemon) CriticalChain(name string) (root ChainNode, err error) {
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return
+	}
+
+	return sys.chainNode(u, map[*Unit]bool{}), nil
+}
+
+// chainNode returns the node of u, expanding it, unless it is on the path already
+func (sys *Daemon) chainNode(u *Unit, path map[*Unit]bool) (node ChainNode) {
+	t := u.timing()
+
+	node = ChainNode{Name: u.Name()}
+	if t.finished() {
+		node.At, node.Time = t.Activated.Sub(sys.Since()), t.Time()
+	}
+
+	if path[u] {
+		return
+	}
+	path[u] = true
+	defer delete(path, u)
+
+	var last time.Time
+	var deps []*Unit
+	for _, dep := range sys.predecessors(u) {
+		dt := dep.timing()
+		if !dt.finished() || t.finished() && dt.Activated.After(t.Activating) {
+			continue
+		}
+
+		switch {
+		case dt.Activated.After(last):
+			last, deps = dt.Activated, []*Unit{dep}
+		case dt.Activated.Equal(last):
+			deps = append(deps, dep)
+		}
+	}
+
+	for _, dep := range deps {
+		node.Deps = append(node.Deps, sys.chainNode(dep, path))
+	}
+	sort.Slice(node.Deps, func(i, j int) bool { return node.Deps[i].Name < node.Deps[j].Name })
+	return
+}
+
+// predecessors returns the units held in-memory, which u is ordered after
+func (sys *Daemon) predecessors(u *Unit) (units []*Unit) {
+	seen := map[*Unit]bool{u: true}
+	add := func(dep *Unit) {
+		if !seen[dep] {
+			seen[dep] = true
+			units = append(units, dep)
+		}
+	}
+
+	for _, name := range sys.deps.get(u, depAfter) {
+		if dep, err := sys.Unit(name); err == nil {
+			add(dep)
+		}
+	}
+	for _, other := range sys.deps.dependents(depBefore, unitKeys(u.Name())...) {
+		add(other)
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestTiming(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	clock := NewFakeClock(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+
+	sys := New()
+	sys.SetPaths()
+	sys.SetClock(clock)
+	sys.SetBootTarget("foo.target")
+
+	_, err := sys.BootTiming()
+	assert.Equal(t, ErrBootNotFinished, err)
+	_, err = sys.Timeline()
+	assert.Equal(t, ErrBootNotFinished, err)
+
+	// slow.service takes 2 seconds to start, fast.service, which is ordered after it, 1 second
+	for _, c := range []struct {
+		name            string
+		d               time.Duration
+		requires, after []string
+	}{
+		{"slow.service", 2 * time.Second, []string{}, []string{}},
+		{"fast.service", time.Second, []string{}, []string{"slow.service"}},
+		{"foo.target", 0, []string{"slow.service", "fast.service"}, []string{"slow.service", "fast.service"}},
+	} {
+		c := c
+		m := newMock(ctrl)
+		for _, method := range []string{"wants", "conflicts", "before"} {
+			emptyOne(m, method).AnyTimes()
+		}
+		m.MockInterface.EXPECT().Requires().Return(c.requires).AnyTimes()
+		m.MockInterface.EXPECT().After().Return(c.after).AnyTimes()
+
+		active := unit.Inactive
+		m.MockInterface.EXPECT().Active().DoAndReturn(func() unit.Activation { return active }).AnyTimes()
+		m.MockStarter.EXPECT().Start().DoAndReturn(func() error {
+			clock.Advance(c.d)
+			active = unit.Active
+			return nil
+		})
+
+		u, err := sys.Supervise(c.name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	require.NoError(t, sys.Boot())
+
+	timings := sys.Blame()
+	if assert.Len(t, timings, 3) {
+		assert.Equal(t, "slow.service", timings[0].Name)
+		assert.Equal(t, 2*time.Second, timings[0].Time())
+		assert.Equal(t, "fast.service", timings[1].Name)
+		assert.Equal(t, time.Second, timings[1].Time(), "waiting for slow.service is not blamed")
+		assert.Equal(t, "foo.target", timings[2].Name)
+		assert.Zero(t, timings[2].Time())
+	}
+
+	bt, err := sys.BootTiming()
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Second, bt.Userspace)
+	assert.Equal(t, "foo.target", bt.Target)
+	assert.Equal(t, 3*time.Second, bt.TargetReached)
+	assert.True(t, bt.Kernel >= 0)
+
+	tl, err := sys.Timeline()
+	require.NoError(t, err)
+	assert.Equal(t, bt, tl.BootTiming)
+	assert.Equal(t, []TimelineEntry{
+		{Name: "slow.service", Activating: 0, Activated: 2 * time.Second},
+		{Name: "fast.service", Activating: 2 * time.Second, Activated: 3 * time.Second},
+		{Name: "foo.target", Activating: 3 * time.Second, Activated: 3 * time.Second},
+	}, tl.Units)
+
+	root, err := sys.CriticalChain("foo.target")
+	require.NoError(t, err)
+	assert.Equal(t, ChainNode{
+		Name: "foo.target",
+		At:   3 * time.Second,
+		Deps: []ChainNode{{
+			Name: "fast.service",
+			At:   3 * time.Second,
+			Time: time.Second,
+			Deps: []ChainNode{{
+				Name: "slow.service",
+				At:   2 * time.Second,
+				Time: 2 * time.Second,
+			}},
+		}},
+	}, root, "only the unit, which finished last, is on the chain")
+}
@@ -0,0 +1,76 @@
+package system
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Identifier of the entries of the audit stream
+const AUDIT_IDENTIFIER = "audit"
+
+// Interfaces management operations are requested over
+const (
+	AUDIT_VIA_CONTROL = "control"
+	AUDIT_VIA_DBUS    = "dbus"
+	AUDIT_VIA_VARLINK = "varlink"
+	AUDIT_VIA_REST    = "rest"
+)
+
+// AuditRecord describes a management operation requested by a client
+type AuditRecord struct {
+	// Operation requested, e.g. "start" or "enable"
+	Operation string
+
+	// Names of the units the operation was requested on
+	Units []string
+
+	// Interface the request came over, one of AUDIT_VIA_*
+	Via string
+
+	// Address of the remote client, empty for local ones
+	Remote string
+
+	// Credentials of the requesting process, -1 if unknown
+	UID, PID int
+
+	// Error the operation failed with, nil if it succeeded
+	Err error
+}
+
+// String returns the message the record is logged with
+func (r AuditRecord) String() string {
+	msg := fmt.Sprintf("%s %s requested via %s", r.Operation, strings.Join(r.Units, " "), r.Via)
+	if r.Remote != "" {
+		msg += " from " + r.Remote
+	}
+	if r.UID >= 0 {
+		msg += fmt.Sprintf(" by uid=%d", r.UID)
+		if r.PID >= 0 {
+			msg += fmt.Sprintf(" pid=%d", r.PID)
+		}
+	}
+
+	if r.Err != nil {
+		return msg + ": failed: " + r.Err.Error()
+	}
+	return msg + ": succeeded"
+}
+
+// Audit records the management operation requested by a client in the audit stream of the log of sys,
+// which is returned by Logs, if LogQuery.Audit is set. Failed operations are recorded with higher priority
+func (sys *Daemon) Audit(r AuditRecord) {
+	log.WithField("record", r).Debugf("sys.Audit")
+
+	e := LogEntry{
+		Time:     sys.clock.Now(),
+		Priority: LOG_NOTICE,
+		Message:  r.String(),
+		Audit:    true,
+	}
+	if r.Err != nil {
+		e.Priority = LOG_WARNING
+	}
+	sys.Log.add(e)
+}
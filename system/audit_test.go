@@ -0,0 +1,63 @@
+package system
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRecord(t *testing.T) {
+	assert.Equal(t, "start foo.service bar.service requested via control by uid=1000 pid=42: succeeded", AuditRecord{
+		Operation: "start",
+		Units:     []string{"foo.service", "bar.service"},
+		Via:       AUDIT_VIA_CONTROL,
+		UID:       1000,
+		PID:       42,
+	}.String())
+
+	assert.Equal(t, "stop foo.service requested via rest from 192.0.2.1:4242: failed: timeout", AuditRecord{
+		Operation: "stop",
+		Units:     []string{"foo.service"},
+		Via:       AUDIT_VIA_REST,
+		Remote:    "192.0.2.1:4242",
+		UID:       -1,
+		PID:       -1,
+		Err:       errors.New("timeout"),
+	}.String())
+}
+
+func TestAudit(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	_, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	sys.Log.Println("manager")
+	sys.Audit(AuditRecord{Operation: "enable", Units: []string{"foo.service"}, Via: AUDIT_VIA_DBUS, UID: -1, PID: -1})
+	sys.Audit(AuditRecord{Operation: "stop", Units: []string{"foo.service"}, Via: AUDIT_VIA_VARLINK, UID: 0, PID: -1,
+		Err: errors.New("failed")})
+
+	entries, err := sys.Logs(LogQuery{Audit: true})
+	require.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, AUDIT_IDENTIFIER, entries[0].Identifier())
+		assert.Equal(t, LOG_NOTICE, entries[0].Priority)
+		assert.Equal(t, "enable foo.service requested via dbus: succeeded", entries[0].Message)
+		assert.Equal(t, LOG_WARNING, entries[1].Priority)
+		assert.Equal(t, "stop foo.service requested via varlink by uid=0: failed: failed", entries[1].Message)
+	}
+
+	entries, err = sys.Logs(LogQuery{Units: []string{"foo"}})
+	require.NoError(t, err)
+	assert.Empty(t, entries, "records are not entries of the units")
+
+	var b bytes.Buffer
+	require.NoError(t, WriteExport(&b, sys.Log.Entries()[1]))
+	assert.Contains(t, b.String(), "_TRANSPORT=audit\n")
+	assert.Contains(t, b.String(), "SYSLOG_IDENTIFIER=audit\n")
+}
@@ -0,0 +1,123 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Name of the symlink pointing to the target booted into by default
+	DEFAULT_TARGET = "default.target"
+
+	// Target booted into, if booting into the default target fails
+	RESCUE_TARGET = "rescue.target"
+)
+
+// DefaultTarget returns the name of the target default.target links to.
+// If default.target is not a symlink, DEFAULT_TARGET is returned
+func (sys *Daemon) DefaultTarget() (name string, err error) {
+	log.Debugf("sys.DefaultTarget")
+
+	for _, dir := range sys.Paths() {
+		path := filepath.Join(dir, DEFAULT_TARGET)
+
+		var info os.FileInfo
+		if info, err = os.Lstat(path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			return DEFAULT_TARGET, nil
+		}
+
+		var target string
+		if target, err = os.Readlink(path); err != nil {
+			return "", err
+		}
+		return filepath.Base(target), nil
+	}
+	return "", ErrNotFound
+}
+
+// SetDefaultTarget points default.target in the first of the unit paths to the target specified by name
+func (sys *Daemon) SetDefaultTarget(name string) (err error) {
+	log.WithField("name", name).Debugf("sys.SetDefaultTarget")
+
+	if filepath.Ext(name) != ".target" || name == DEFAULT_TARGET {
+		return ErrUnknownType
+	}
+
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return
+	}
+	if !u.IsLoaded() || u.Path() == "" {
+		return ErrNotLoaded
+	}
+
+	var dir string
+	if dir, err = sys.configDir(); err != nil {
+		return
+	}
+
+	path := filepath.Join(dir, DEFAULT_TARGET)
+	if isSymlink(path) {
+		if err = os.Remove(path); err != nil {
+			return
+		}
+	}
+	if err = os.Symlink(u.Path(), path); err != nil {
+		return
+	}
+
+	return sys.reread(DEFAULT_TARGET)
+}
+
+// Boot isolates the boot target of sys(DEFAULT_TARGET, unless specified otherwise by SetBootTarget).
+// If that fails, RESCUE_TARGET is isolated instead
+func (sys *Daemon) Boot() (err error) {
+	sys.mutex.RLock()
+	target := sys.bootTarget
+	sys.mutex.RUnlock()
+
+	sys.setState(Starting)
+
+	e := log.WithField("target", target)
+	e.Debugf("sys.Boot")
+
+	state, reached := Running, target
+	if err = sys.Isolate(target); err != nil {
+		e.Errorf("Error booting into target: %s", err)
+
+		state, reached = Maintenance, RESCUE_TARGET
+		if err = sys.Isolate(RESCUE_TARGET); err != nil {
+			log.WithField("target", RESCUE_TARGET).Errorf("Error booting into rescue target: %s", err)
+			reached = ""
+		}
+	}
+
+	sys.mutex.Lock()
+	sys.bootReached, sys.bootFinished = reached, sys.clock.Now()
+	sys.mutex.Unlock()
+
+	sys.setState(state)
+	return
+}
+
+// BootTarget returns the name of the target sys isolates on Boot
+func (sys *Daemon) BootTarget() (name string) {
+	return sys.bootTarget
+}
+
+// SetBootTarget sets the name of the target sys isolates on Boot
+func (sys *Daemon) SetBootTarget(name string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.bootTarget = name
+}
@@ -0,0 +1,63 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTarget(t *testing.T) {
+	admin, err := ioutil.TempDir("", "boot-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "boot-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	for _, name := range []string{"multi-user.target", "graphical.target", RESCUE_TARGET} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, name), []byte{}, 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "test.service"), []byte("[Service]\nExecStart=/bin/true"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(vendor, "graphical.target"), filepath.Join(vendor, DEFAULT_TARGET)))
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	name, err := sys.DefaultTarget()
+	require.NoError(t, err)
+	assert.Equal(t, "graphical.target", name)
+
+	assert.Equal(t, ErrUnknownType, sys.SetDefaultTarget("test.service"))
+	assert.ErrorIs(t, sys.SetDefaultTarget("missing.target"), ErrNotFound)
+
+	require.NoError(t, sys.SetDefaultTarget("multi-user.target"))
+	require.NoError(t, sys.SetDefaultTarget("multi-user.target"), "setting twice")
+
+	name, err = sys.DefaultTarget()
+	require.NoError(t, err)
+	assert.Equal(t, "multi-user.target", name)
+
+	require.NoError(t, sys.Boot())
+	assert.Equal(t, Running, sys.state)
+
+	u, err := sys.Get(DEFAULT_TARGET)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(admin, DEFAULT_TARGET), u.Path())
+	assert.True(t, u.IsActive())
+
+	sys = New()
+	sys.SetPaths(admin, vendor)
+	sys.SetBootTarget("missing.target")
+
+	require.NoError(t, sys.Boot())
+	assert.Equal(t, Maintenance, sys.state)
+
+	rescue, err := sys.Get(RESCUE_TARGET)
+	require.NoError(t, err)
+	assert.True(t, rescue.IsActive())
+}
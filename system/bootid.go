@@ -0,0 +1,137 @@
+package system
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Path to the file holding the ID of the current boot generated by the kernel
+const BOOT_ID_PATH = "/proc/sys/kernel/random/boot_id"
+
+// BootRecord describes a boot, which entries of the logs were logged during
+type BootRecord struct {
+	// ID of the boot, 32 hexadecimal digits
+	ID string
+
+	// Times of the oldest and the newest entries of the boot
+	First, Last time.Time
+}
+
+// newBootID returns the ID of the current boot read from BOOT_ID_PATH or, if it can not be read, a random one
+func newBootID() string {
+	if b, err := ioutil.ReadFile(BOOT_ID_PATH); err == nil {
+		if id := strings.Replace(strings.TrimSpace(string(b)), "-", "", -1); len(id) == 32 {
+			return id
+		}
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Error generating boot ID: %s", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// BootID returns the ID of the current boot, which all log entries, events and snapshots are stamped with
+func (sys *Daemon) BootID() string {
+	sys.logMutex.Lock()
+	defer sys.logMutex.Unlock()
+
+	return sys.bootID
+}
+
+// setBootID sets the ID of the current boot to id, e.g. when restoring the state after re-execution
+func (sys *Daemon) setBootID(id string) {
+	sys.logMutex.Lock()
+	sys.bootID = id
+	sys.logMutex.Unlock()
+
+	sys.Log.setBoot(id)
+	for _, u := range sys.Units() {
+		u.Log.setBoot(id)
+	}
+}
+
+// Boots returns the boots, which entries of the logs of the manager and units were logged during,
+// ordered by the time of their oldest entries. The current boot is always the last one
+func (sys *Daemon) Boots() (boots []BootRecord, err error) {
+	log.Debugf("sys.Boots")
+
+	var entries []LogEntry
+	if entries, err = sys.Logs(LogQuery{}); err != nil {
+		return nil, err
+	}
+
+	current := sys.BootID()
+
+	byID := map[string]*BootRecord{}
+	for _, e := range entries {
+		if e.Boot == "" || e.Boot == current {
+			continue
+		}
+
+		b, ok := byID[e.Boot]
+		if !ok {
+			b = &BootRecord{ID: e.Boot, First: e.Time, Last: e.Time}
+			byID[e.Boot] = b
+		}
+		if e.Time.Before(b.First) {
+			b.First = e.Time
+		}
+		if e.Time.After(b.Last) {
+			b.Last = e.Time
+		}
+	}
+
+	for _, b := range byID {
+		boots = append(boots, *b)
+	}
+	sort.Slice(boots, func(i, j int) bool {
+		return boots[i].First.Before(boots[j].First)
+	})
+
+	b := BootRecord{ID: current, First: sys.since, Last: sys.clock.Now()}
+	for _, e := range entries {
+		if e.Boot == current && e.Time.Before(b.First) {
+			b.First = e.Time
+		}
+	}
+	return append(boots, b), nil
+}
+
+// resolveBoot returns the ID of the boot specified by s: either an ID or an offset, where 0 is
+// the current boot, -1 the previous one, etc. and 1 is the first boot, 2 the second one, etc.
+func (sys *Daemon) resolveBoot(s string) (id string, err error) {
+	if len(s) == 32 {
+		if _, err = hex.DecodeString(s); err == nil {
+			return strings.ToLower(s), nil
+		}
+	}
+
+	var offset int
+	if offset, err = strconv.Atoi(s); err != nil {
+		return "", fmt.Errorf("invalid boot: %q", s)
+	}
+
+	var boots []BootRecord
+	if boots, err = sys.Boots(); err != nil {
+		return "", err
+	}
+
+	i := len(boots) - 1 + offset
+	if offset > 0 {
+		i = offset - 1
+	}
+	if i < 0 || i >= len(boots) {
+		return "", fmt.Errorf("no boot with offset %d", offset)
+	}
+	return boots[i].ID, nil
+}
@@ -0,0 +1,100 @@
+package system
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBootID(t *testing.T) {
+	id := newBootID()
+	assert.Len(t, id, 32)
+	assert.NotContains(t, id, "-")
+}
+
+func TestBoots(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	const (
+		first  = "11111111111111111111111111111111"
+		second = "22222222222222222222222222222222"
+	)
+	t0 := time.Now().Add(-time.Hour)
+	sys.Log.add(LogEntry{Time: t0, Priority: LOG_INFO, Message: "first boot", Boot: first})
+	u.Log.add(LogEntry{Time: t0.Add(time.Minute), Priority: LOG_INFO, Message: "second boot", Boot: second})
+	sys.Log.add(LogEntry{Time: t0.Add(2 * time.Minute), Priority: LOG_INFO, Message: "second boot", Boot: second})
+	u.Log.Println("current boot")
+
+	boots, err := sys.Boots()
+	require.NoError(t, err)
+	if assert.Len(t, boots, 3) {
+		assert.Equal(t, first, boots[0].ID)
+		assert.Equal(t, second, boots[1].ID)
+		assert.Equal(t, t0.Add(time.Minute).Unix(), boots[1].First.Unix())
+		assert.Equal(t, t0.Add(2*time.Minute).Unix(), boots[1].Last.Unix())
+		assert.Equal(t, sys.BootID(), boots[2].ID)
+	}
+
+	for boot, expected := range map[string]string{
+		"0":    sys.BootID(),
+		"-1":   second,
+		"-2":   first,
+		"1":    first,
+		"3":    sys.BootID(),
+		second: second,
+	} {
+		id, err := sys.resolveBoot(boot)
+		if assert.NoError(t, err, boot) {
+			assert.Equal(t, expected, id, boot)
+		}
+	}
+
+	for _, boot := range []string{"-3", "4", "foo"} {
+		_, err := sys.resolveBoot(boot)
+		assert.Error(t, err, boot)
+	}
+
+	entries, err := sys.Logs(LogQuery{Boot: "-1"})
+	require.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "foo.service", entries[0].Unit)
+		assert.Equal(t, MANAGER_IDENTIFIER, entries[1].Identifier())
+	}
+
+	entries, err = sys.Logs(LogQuery{Units: []string{"foo"}, Boot: "0"})
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "current boot", entries[0].Message)
+		assert.Equal(t, sys.BootID(), entries[0].Boot)
+	}
+
+	_, err = sys.Logs(LogQuery{Boot: "-5"})
+	assert.Error(t, err)
+}
+
+func TestSetBootID(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	const id = "33333333333333333333333333333333"
+	sys.setBootID(id)
+	u.Log.Println("restored")
+
+	entries, err := sys.Logs(LogQuery{Units: []string{"foo"}, Boot: id})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	snapshot, err := sys.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, id, snapshot.Boot)
+}
@@ -0,0 +1,79 @@
+package system
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// stamp identifies a version of a file on disk
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// cachedDefinition is the contents of a definition file together with its drop-ins
+// and the stamps of the files at the time they were read
+type cachedDefinition struct {
+	contents []byte
+	stamps   map[string]stamp
+}
+
+// definitionCache holds the contents of definition files keyed by path, so that files,
+// which did not change on disk, do not get re-read
+type definitionCache struct {
+	entries map[string]*cachedDefinition
+	mutex   sync.Mutex
+}
+
+func newDefinitionCache() *definitionCache {
+	return &definitionCache{entries: map[string]*cachedDefinition{}}
+}
+
+// stamps returns the stamps of files in fsys specified by paths
+func stamps(fsys fileSystem, paths []string) (stamps map[string]stamp, err error) {
+	stamps = make(map[string]stamp, len(paths))
+	for _, path := range paths {
+		var info os.FileInfo
+		if info, err = fsys.Stat(path); err != nil {
+			return nil, err
+		}
+		stamps[path] = stamp{info.ModTime(), info.Size()}
+	}
+	return
+}
+
+// get returns the cached contents of the files specified by paths, if none of them changed
+// since they were put in the cache under key
+func (c *definitionCache) get(key string, current map[string]stamp) (b []byte, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || len(entry.stamps) != len(current) {
+		return nil, false
+	}
+
+	for path, st := range current {
+		if cached, ok := entry.stamps[path]; !ok || cached.size != st.size || !cached.modTime.Equal(st.modTime) {
+			return nil, false
+		}
+	}
+	return entry.contents, true
+}
+
+// put stores b read from files with the stamps specified under key
+func (c *definitionCache) put(key string, b []byte, stamps map[string]stamp) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[key] = &cachedDefinition{b, stamps}
+}
+
+// forget removes the entry stored under key
+func (c *definitionCache) forget(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, key)
+}
@@ -0,0 +1,58 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinitionCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	path := filepath.Join(dir, "test.service")
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	write := func(contents string) {
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	write("[Unit]\nDescription=foo")
+	b, err := sys.readDefinitionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[Unit]\nDescription=foo", string(b))
+
+	// Same size and modification time - cached contents are returned
+	write("[Unit]\nDescription=bar")
+	b, err = sys.readDefinitionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[Unit]\nDescription=foo", string(b))
+
+	mtime = mtime.Add(time.Second)
+	write("[Unit]\nDescription=bar")
+	b, err = sys.readDefinitionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[Unit]\nDescription=bar", string(b))
+
+	// Adding a drop-in invalidates the cached contents
+	require.NoError(t, os.Mkdir(path+".d", 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path+".d", "override.conf"), []byte("[Unit]\nDescription=baz"), 0644))
+	b, err = sys.readDefinitionFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "[Unit]\nDescription=bar\n[Unit]\nDescription=baz", string(b))
+
+	require.NoError(t, os.Remove(filepath.Join(path+".d", "override.conf")))
+	require.NoError(t, os.Remove(path))
+	_, err = sys.readDefinitionFile(path)
+	assert.True(t, os.IsNotExist(err))
+}
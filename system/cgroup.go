@@ -0,0 +1,205 @@
+package system
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Files of cgroup v2 directories used by the manager
+const (
+	CGROUP_PROCS           = "cgroup.procs"
+	CGROUP_FREEZE          = "cgroup.freeze"
+	CGROUP_SUBTREE_CONTROL = "cgroup.subtree_control"
+	CGROUP_CPU_STAT        = "cpu.stat"
+	CGROUP_MEMORY_CURRENT  = "memory.current"
+	CGROUP_MEMORY_PEAK     = "memory.peak"
+	CGROUP_IO_STAT         = "io.stat"
+	CGROUP_PIDS_CURRENT    = "pids.current"
+)
+
+var ErrNoCgroup = errors.New("Unit has no cgroup")
+
+// Cgroup returns the cgroup v2 directory, in which cgroups of units are created,
+// empty if processes of units are not put into cgroups
+func (sys *Daemon) Cgroup() string {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.cgroup
+}
+
+// SetCgroup sets the cgroup v2 directory, in which a cgroup named after the unit is created
+// for processes of each unit, e.g. /sys/fs/cgroup/systemgo. If dir is empty, which is the default,
+// processes of units are not put into cgroups and units can not be frozen
+func (sys *Daemon) SetCgroup(dir string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.cgroup = dir
+}
+
+// cgroupOf returns the cgroup directory of u, empty if processes of units are not put into cgroups
+func (sys *Daemon) cgroupOf(u *Unit) string {
+	dir := sys.Cgroup()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, u.Name())
+}
+
+// cgroupAttrs returns the contents of cgroup files by name, which apply the resource control properties of u
+func cgroupAttrs(u *Unit) (attrs map[string]string) {
+	attrs = map[string]string{}
+
+	rc, ok := u.Interface.(unit.ResourceController)
+	if !ok {
+		return
+	}
+
+	for key, value := range rc.Resources() {
+		file, contents, err := unit.CgroupAttribute(key, value)
+		if err != nil {
+			u.Log.Errorf("Error applying %s=%s: %s", key, value, err)
+			continue
+		}
+		attrs[file] = contents
+	}
+	return
+}
+
+// writeCgroupAttrs writes attrs to the files in the cgroup at dir
+func writeCgroupAttrs(dir string, attrs map[string]string) (err error) {
+	for file, contents := range attrs {
+		if werr := ioutil.WriteFile(filepath.Join(dir, file), []byte(contents), 0644); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return
+}
+
+// cgroupExecutor puts processes started by Executor into the cgroup at dir,
+// which gets created with controllers enabled and attrs written to its files
+type cgroupExecutor struct {
+	unit.Executor
+	dir         string
+	attrs       map[string]string
+	controllers []string
+}
+
+func (e cgroupExecutor) Start(cmd *exec.Cmd) (p unit.Process, err error) {
+	if err = os.MkdirAll(filepath.Dir(e.dir), 0755); err != nil {
+		return nil, err
+	}
+
+	if err = enableControllers(filepath.Dir(e.dir), e.controllers); err != nil {
+		log.WithField("cgroup", e.dir).Warnf("Error enabling controllers: %s", err)
+	}
+
+	if err = os.MkdirAll(e.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	if err = writeCgroupAttrs(e.dir, e.attrs); err != nil {
+		// The controllers may not be enabled in the parent cgroup
+		log.WithField("cgroup", e.dir).Warnf("Error applying resource control properties: %s", err)
+	}
+
+	if p, err = e.Executor.Start(cmd); err != nil || p.Pid() <= 0 {
+		return
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(e.dir, CGROUP_PROCS), []byte(strconv.Itoa(p.Pid())), 0644); err != nil {
+		if kerr := p.Signal(os.Kill); kerr == nil {
+			p.Wait()
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// Freeze suspends all processes of units specified by names using the cgroup v2 freezer.
+// If error is returned, it is going to be a *LoadError or a *UnitError
+func (sys *Daemon) Freeze(names ...string) (err error) {
+	return sys.setFrozen(true, names)
+}
+
+// Thaw resumes all processes of units specified by names, which were suspended by Freeze.
+// If error is returned, it is going to be a *LoadError or a *UnitError
+func (sys *Daemon) Thaw(names ...string) (err error) {
+	return sys.setFrozen(false, names)
+}
+
+func (sys *Daemon) setFrozen(frozen bool, names []string) (err error) {
+	op := "thaw"
+	if frozen {
+		op = "freeze"
+	}
+
+	for _, name := range names {
+		var u *Unit
+		if u, err = sys.Get(name); err != nil {
+			return
+		}
+
+		if frozen && !u.IsActive() {
+			return &UnitError{Name: name, Op: op, Err: ErrNotActive}
+		}
+
+		if err = u.setFrozen(frozen); err != nil {
+			return &UnitError{Name: name, Op: op, Err: err}
+		}
+	}
+	return nil
+}
+
+// setFrozen writes the desired freezer state of the cgroup of u
+func (u *Unit) setFrozen(frozen bool) (err error) {
+	dir := ""
+	if u.System != nil {
+		dir = u.System.cgroupOf(u)
+	}
+	if dir == "" {
+		return ErrNoCgroup
+	}
+
+	v, msg := "0", "Thawing..."
+	if frozen {
+		v, msg = "1", "Freezing..."
+	}
+	u.Log.Println(msg)
+
+	if err = ioutil.WriteFile(filepath.Join(dir, CGROUP_FREEZE), []byte(v), 0644); os.IsNotExist(err) {
+		// The cgroup is only created, once u spawns a process
+		return ErrNoCgroup
+	}
+	return
+}
+
+// frozen returns whether the cgroup of u is frozen
+func (u *Unit) frozen() bool {
+	if u.System == nil {
+		return false
+	}
+
+	dir := u.System.cgroupOf(u)
+	if dir == "" {
+		return false
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, CGROUP_FREEZE))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.WithField("unit", u.Name()).Debugf("Error reading freezer state: %s", err)
+		}
+		return false
+	}
+	return strings.TrimSpace(string(b)) == "1"
+}
@@ -0,0 +1,59 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestFreeze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sys := New()
+	sys.SetPaths()
+
+	sleep, err := sys.Load("sleep.service", strings.NewReader("[Service]\nExecStart=/bin/sleep 1000"))
+	require.NoError(t, err)
+	_, err = sys.Load("idle.service", strings.NewReader("[Service]\nExecStart=/bin/sleep 1000"))
+	require.NoError(t, err)
+
+	require.NoError(t, sleep.start())
+	assert.ErrorIs(t, sys.Freeze("sleep.service"), ErrNoCgroup, "cgroups are disabled")
+	require.NoError(t, sleep.stop())
+
+	sys.SetCgroup(dir)
+	require.NoError(t, sleep.start())
+	defer sleep.stop()
+
+	cgroup := filepath.Join(dir, "sleep.service")
+	b, err := ioutil.ReadFile(filepath.Join(cgroup, CGROUP_PROCS))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(sleep.Interface.(unit.MainPIDer).MainPID()), string(b))
+
+	require.NoError(t, sys.Freeze("sleep.service"))
+	b, err = ioutil.ReadFile(filepath.Join(cgroup, CGROUP_FREEZE))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(b))
+
+	props, err := sys.Properties("sleep.service")
+	require.NoError(t, err)
+	assert.Equal(t, "frozen", props["FreezerState"])
+
+	require.NoError(t, sys.Thaw("sleep.service"))
+	b, err = ioutil.ReadFile(filepath.Join(cgroup, CGROUP_FREEZE))
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(b))
+	assert.False(t, sleep.frozen())
+
+	assert.ErrorIs(t, sys.Freeze("idle.service"), ErrNotActive)
+	assert.ErrorIs(t, sys.Thaw("idle.service"), ErrNoCgroup, "cgroup is created, once a process is spawned")
+}
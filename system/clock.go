@@ -0,0 +1,151 @@
+package system
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and measures durations for the Daemon, so that the passage of time
+// can be controlled by tests of timing behavior
+type Clock interface {
+	Now() time.Time
+
+	// After waits for d to elapse and sends the current time on the channel returned
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer returns a Timer, which sends the current time on its channel after d
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event timer obtained from a Clock, see time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// RealClock is the Clock backed by the time package used, unless set otherwise
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// FakeClock is a Clock, time of which only passes, when Advance is called
+type FakeClock struct {
+	now    time.Time
+	timers map[*fakeTimer]struct{}
+	mutex  sync.Mutex
+}
+
+// NewFakeClock returns a FakeClock, which is set to now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:    now,
+		timers: map[*fakeTimer]struct{}{},
+	}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{
+		clock: c,
+		ch:    make(chan time.Time, 1),
+	}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the time of c forward by d and fires the timers, which expire in the meantime
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if !t.deadline.After(c.now) {
+			c.fire(t)
+		}
+	}
+}
+
+// Timers returns the number of timers waiting to fire.
+// Tests may poll it to make sure the code under test started waiting, before calling Advance
+func (c *FakeClock) Timers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.timers)
+}
+
+// fire sends the current time on the channel of t and stops it.
+// c.mutex must be held
+func (c *FakeClock) fire(t *fakeTimer) {
+	delete(c.timers, t)
+	select {
+	case t.ch <- c.now:
+	default:
+	}
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() (active bool) {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	_, active = t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return
+}
+
+func (t *fakeTimer) Reset(d time.Duration) (active bool) {
+	c := t.clock
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, active = c.timers[t]
+	t.deadline = c.now.Add(d)
+	c.timers[t] = struct{}{}
+	if d <= 0 {
+		c.fire(t)
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package system
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+
+	timer := c.NewTimer(time.Minute)
+	after := c.After(2 * time.Minute)
+	assert.Equal(t, 2, c.Timers())
+
+	c.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(30*time.Second), c.Now())
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	c.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-timer.C())
+	assert.False(t, timer.Stop(), "Stop of fired timer")
+
+	assert.False(t, timer.Reset(time.Minute), "Reset of fired timer")
+	assert.True(t, timer.Stop(), "Stop of reset timer")
+	assert.Equal(t, 1, c.Timers())
+
+	c.Advance(time.Minute)
+	assert.Equal(t, start.Add(2*time.Minute), <-after)
+	assert.Equal(t, 0, c.Timers())
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	c := NewFakeClock(time.Now())
+
+	block := make(chan struct{})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runWithTimeout(c, time.Minute, func() error {
+			<-block
+			return nil
+		}, func() { close(block) })
+	}()
+
+	for c.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Advance(time.Minute)
+	assert.Equal(t, ErrTimeout, <-done)
+
+	errTest := errors.New("test")
+	assert.Equal(t, errTest, runWithTimeout(c, time.Minute, func() error { return errTest }, nil))
+	assert.Equal(t, 0, c.Timers())
+}
@@ -0,0 +1,217 @@
+package system
+
+import (
+	"os"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Paths to the configuration files of system and user managers
+const (
+	SYSTEM_CONF = "/etc/systemgo/system.conf"
+	USER_CONF   = "/etc/systemgo/user.conf"
+)
+
+// Timeouts of start and stop operations of units, which do not specify their own
+const (
+	DEFAULT_TIMEOUT_START = 90 * time.Second
+	DEFAULT_TIMEOUT_STOP  = 90 * time.Second
+	DEFAULT_RESTART_SEC   = 100 * time.Millisecond
+)
+
+// Time activations of units by timers, which do not specify their own accuracy, may be delayed by
+// to coalesce them with other timers
+const DEFAULT_TIMER_ACCURACY = time.Minute
+
+// Defaults are the manager defaults applied to units, which do not specify their own values
+type Defaults struct {
+	TimeoutStartSec time.Duration
+	TimeoutStopSec  time.Duration
+
+	// Time to sleep before restarting a unit
+	RestartSec time.Duration
+
+	StartLimitIntervalSec time.Duration
+	StartLimitBurst       int
+
+	// Time activations of units by timers may be delayed by to coalesce them
+	TimerAccuracySec time.Duration
+
+	// Environment block passed to all spawned processes
+	Environment []string
+
+	// Whether the resource usage of units is accounted, unless the units specify otherwise
+	CPUAccounting, MemoryAccounting, IOAccounting, TasksAccounting bool
+}
+
+// DefaultDefaults returns the defaults used, unless configured otherwise
+func DefaultDefaults() Defaults {
+	return Defaults{
+		TimeoutStartSec:       DEFAULT_TIMEOUT_START,
+		TimeoutStopSec:        DEFAULT_TIMEOUT_STOP,
+		RestartSec:            DEFAULT_RESTART_SEC,
+		StartLimitIntervalSec: DEFAULT_START_LIMIT_INTERVAL,
+		StartLimitBurst:       DEFAULT_START_LIMIT_BURST,
+		TimerAccuracySec:      DEFAULT_TIMER_ACCURACY,
+		MemoryAccounting:      true,
+		TasksAccounting:       true,
+	}
+}
+
+// Contents of a manager configuration file
+type conf struct {
+	Manager struct {
+		LogLevel string
+		UnitPath []string
+
+		DefaultTimeoutStartSec, DefaultTimeoutStopSec, DefaultRestartSec *time.Duration
+		DefaultStartLimitIntervalSec, DefaultTimerAccuracySec            *time.Duration
+		DefaultStartLimitBurst                                           *int
+		DefaultEnvironment                                               []string
+
+		DefaultCPUAccounting, DefaultMemoryAccounting *bool
+		DefaultIOAccounting, DefaultTasksAccounting   *bool
+
+		RuntimeWatchdogSec, RebootWatchdogSec *time.Duration
+		WatchdogDevice                        string
+	}
+}
+
+// Defaults returns the effective manager defaults of sys
+func (sys *Daemon) Defaults() (d Defaults) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	d = sys.defaults
+	d.Environment = append([]string(nil), d.Environment...)
+	return
+}
+
+// LoadConf loads the manager configuration file found at path, which has a single [Manager] section.
+// Options not specified in the file are left untouched. Missing file is not an error
+func (sys *Daemon) LoadConf(path string) (err error) {
+	log.WithField("path", path).Debugf("sys.LoadConf")
+
+	var file *os.File
+	if file, err = os.Open(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return
+	}
+	defer file.Close()
+
+	var c conf
+	if err = unit.ParseDefinition(file, &c); err != nil {
+		return
+	}
+	m := c.Manager
+
+	if m.LogLevel != "" {
+		var lvl log.Level
+		if lvl, err = log.ParseLevel(m.LogLevel); err != nil {
+			return
+		}
+		log.SetLevel(lvl)
+	}
+
+	if err = sys.SetEnvironment(m.DefaultEnvironment...); err != nil {
+		return
+	}
+
+	if len(m.UnitPath) > 0 {
+		sys.SetPaths(m.UnitPath...)
+	}
+
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	for _, opt := range []struct {
+		value *time.Duration
+		dst   *time.Duration
+	}{
+		{m.DefaultTimeoutStartSec, &sys.defaults.TimeoutStartSec},
+		{m.DefaultTimeoutStopSec, &sys.defaults.TimeoutStopSec},
+		{m.DefaultRestartSec, &sys.defaults.RestartSec},
+		{m.DefaultStartLimitIntervalSec, &sys.defaults.StartLimitIntervalSec},
+		{m.DefaultTimerAccuracySec, &sys.defaults.TimerAccuracySec},
+	} {
+		if opt.value != nil {
+			*opt.dst = *opt.value
+		}
+	}
+	if m.DefaultStartLimitBurst != nil {
+		sys.defaults.StartLimitBurst = *m.DefaultStartLimitBurst
+	}
+	for _, opt := range []struct {
+		value *bool
+		dst   *bool
+	}{
+		{m.DefaultCPUAccounting, &sys.defaults.CPUAccounting},
+		{m.DefaultMemoryAccounting, &sys.defaults.MemoryAccounting},
+		{m.DefaultIOAccounting, &sys.defaults.IOAccounting},
+		{m.DefaultTasksAccounting, &sys.defaults.TasksAccounting},
+	} {
+		if opt.value != nil {
+			*opt.dst = *opt.value
+		}
+	}
+	sys.defaults.Environment = append(sys.defaults.Environment, m.DefaultEnvironment...)
+
+	if m.RuntimeWatchdogSec != nil {
+		sys.watchdogConf.RuntimeSec = *m.RuntimeWatchdogSec
+	}
+	if m.RebootWatchdogSec != nil {
+		sys.watchdogConf.RebootSec = *m.RebootWatchdogSec
+	}
+	if m.WatchdogDevice != "" {
+		sys.watchdogConf.Device = m.WatchdogDevice
+	}
+	return nil
+}
+
+// timeouts returns the timeouts of start and stop operations of u
+func (sys *Daemon) timeouts(u *Unit) (start, stop time.Duration) {
+	sys.mutex.RLock()
+	start, stop = sys.defaults.TimeoutStartSec, sys.defaults.TimeoutStopSec
+	sys.mutex.RUnlock()
+
+	if t, ok := u.Interface.(unit.Timeouter); ok {
+		if v, ok := t.TimeoutStartSec(); ok {
+			start = v
+		}
+		if v, ok := t.TimeoutStopSec(); ok {
+			stop = v
+		}
+	}
+	return
+}
+
+// runWithTimeout runs fn and returns ErrTimeout, if it does not return within timeout measured by clock.
+// On timeout, cancel is called to make fn return and fn is waited for, so that it does not run concurrently
+// with whatever follows. fn is not limited in time, if timeout is 0
+func runWithTimeout(clock Clock, timeout time.Duration, fn func() error, cancel func()) error {
+	if timeout <= 0 {
+		return fn()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C():
+		cancel()
+		<-done
+		return ErrTimeout
+	}
+}
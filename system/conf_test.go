@@ -0,0 +1,77 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestLoadConf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conf-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	units := filepath.Join(dir, "units")
+	require.NoError(t, os.Mkdir(units, 0755))
+
+	path := filepath.Join(dir, "system.conf")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[Manager]
+UnitPath=`+units+`
+DefaultTimeoutStartSec=100ms
+DefaultStartLimitBurst=3
+DefaultTimerAccuracySec=1s
+DefaultCPUAccounting=yes
+DefaultTasksAccounting=no
+DefaultEnvironment=FOO=bar BAZ=qux`), 0644))
+
+	sys := New()
+	assert.Equal(t, DefaultDefaults(), sys.Defaults())
+
+	require.NoError(t, sys.LoadConf(filepath.Join(dir, "missing.conf")), "missing file")
+	require.NoError(t, sys.LoadConf(path))
+
+	expected := DefaultDefaults()
+	expected.TimeoutStartSec = 100 * time.Millisecond
+	expected.StartLimitBurst = 3
+	expected.TimerAccuracySec = time.Second
+	expected.CPUAccounting = true
+	expected.TasksAccounting = false
+	expected.Environment = []string{"FOO=bar", "BAZ=qux"}
+	assert.Equal(t, expected, sys.Defaults())
+
+	assert.Equal(t, []string{units}, sys.Paths())
+	assert.Contains(t, sys.ShowEnvironment(), "FOO=bar")
+
+	for name, contents := range map[string]string{
+		"slow.service":     "[Service]\nType=oneshot\nExecStart=/bin/sleep 5",
+		"patient.service":  "[Service]\nType=oneshot\nExecStart=/bin/sleep 0.2\nTimeoutStartSec=5s",
+		"stubborn.service": "[Service]\nExecStart=/bin/sleep 5\nExecStop=/bin/sleep 5\nTimeoutStopSec=100ms",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(units, name), []byte(contents), 0644))
+	}
+
+	u, err := sys.Get("slow.service")
+	require.NoError(t, err)
+	assert.Equal(t, ErrTimeout, u.start())
+
+	u, err = sys.Get("patient.service")
+	require.NoError(t, err)
+	assert.NoError(t, u.start())
+
+	u, err = sys.Get("stubborn.service")
+	require.NoError(t, err)
+	require.NoError(t, u.start())
+	begin := time.Now()
+	assert.Equal(t, ErrTimeout, u.stop())
+	assert.True(t, time.Since(begin) < 2*time.Second, "stop is cancelled, once timed out")
+	assert.Equal(t, 0, u.Interface.(unit.MainPIDer).MainPID(), "processes are killed")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("[Manager]\nUnknown=yes"), 0644))
+	assert.Error(t, sys.LoadConf(path))
+}
@@ -0,0 +1,156 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Console log entries are forwarded to by default
+const CONSOLE_PATH = "/dev/console"
+
+// Lowest priority of entries forwarded to the console by default
+const DEFAULT_MAX_LEVEL_CONSOLE = LOG_INFO
+
+// Number of entries queued for writing to the console, further entries are dropped,
+// until the queue is drained
+const CONSOLE_QUEUE_SIZE = 256
+
+// consoleForwarder writes log entries of high priority to a console or another TTY
+type consoleForwarder struct {
+	file *os.File
+
+	// Lowest priority of entries written
+	maxLevel int
+
+	queue chan []byte
+	done  chan struct{}
+
+	// Closed, when the queue is drained after done is closed
+	stopped chan struct{}
+
+	closeOnce sync.Once
+}
+
+// ForwardToConsole makes the entries of priority maxLevel and higher logged by the manager and units
+// be mirrored to the TTY at path, CONSOLE_PATH if empty, which is essential when debugging failures
+// of machines without other means of reading the logs. Entries of the kernel are not mirrored,
+// as the kernel writes them to the console itself. The TTY forwarded to before, if any, is replaced
+func (sys *Daemon) ForwardToConsole(path string, maxLevel int) (err error) {
+	log.WithFields(log.Fields{
+		"path":     path,
+		"maxLevel": maxLevel,
+	}).Debugf("sys.ForwardToConsole")
+
+	if path == "" {
+		path = CONSOLE_PATH
+	}
+
+	c := &consoleForwarder{
+		maxLevel: maxLevel,
+		queue:    make(chan []byte, CONSOLE_QUEUE_SIZE),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if c.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|syscall.O_NOCTTY, 0); err != nil {
+		return
+	}
+	go c.run()
+
+	sys.logMutex.Lock()
+	prev := sys.console
+	sys.console = c
+	sys.logMutex.Unlock()
+
+	if prev != nil {
+		prev.close()
+	}
+	return nil
+}
+
+// closeConsole stops forwarding entries to the console, once the entries queued are written
+func (sys *Daemon) closeConsole() {
+	sys.logMutex.Lock()
+	c := sys.console
+	sys.console = nil
+	sys.logMutex.Unlock()
+
+	if c != nil {
+		c.close()
+	}
+}
+
+// forwardToConsole queues e for writing to the console, if enabled and e is of high enough priority
+func (sys *Daemon) forwardToConsole(e LogEntry) {
+	if e.Kernel {
+		return
+	}
+
+	sys.logMutex.Lock()
+	c := sys.console
+	sys.logMutex.Unlock()
+
+	if c == nil || e.Priority > c.maxLevel {
+		return
+	}
+	c.send(formatConsole(e))
+}
+
+// formatConsole formats e as a line written to the console
+func formatConsole(e LogEntry) []byte {
+	identifier := e.Identifier()
+	if e.PID != 0 {
+		identifier += "[" + strconv.Itoa(e.PID) + "]"
+	}
+	return []byte(fmt.Sprintf("%s %s: %s\n", e.Time.Local().Format(time.Stamp), identifier, e.Message))
+}
+
+// send queues line, line is dropped, if the queue is full
+func (c *consoleForwarder) send(line []byte) {
+	select {
+	case <-c.done:
+	case c.queue <- line:
+	default:
+	}
+}
+
+// run writes the lines queued to the console
+func (c *consoleForwarder) run() {
+	defer close(c.stopped)
+	defer c.file.Close()
+
+	write := func(line []byte) {
+		if _, err := c.file.Write(line); err != nil {
+			log.WithField("path", c.file.Name()).Debugf("Error writing to console: %s", err)
+		}
+	}
+
+	for {
+		select {
+		case line := <-c.queue:
+			write(line)
+		case <-c.done:
+			for {
+				select {
+				case line := <-c.queue:
+					write(line)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// close stops c, once the lines queued are written
+func (c *consoleForwarder) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	<-c.stopped
+}
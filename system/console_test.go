@@ -0,0 +1,57 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatConsole(t *testing.T) {
+	now := time.Now()
+	stamp := now.Local().Format(time.Stamp)
+
+	assert.Equal(t, stamp+" foo.service[42]: failed\n",
+		string(formatConsole(LogEntry{Unit: "foo.service", Time: now, PID: 42, Message: "failed"})))
+	assert.Equal(t, stamp+" systemgo: Starting...\n",
+		string(formatConsole(LogEntry{Time: now, Message: "Starting..."})))
+}
+
+func TestForwardToConsole(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "tty")
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+
+	sys := New()
+	sys.SetPaths()
+	require.NoError(t, sys.ForwardToConsole(path, LOG_WARNING))
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	u.Log.Println("not mirrored")
+	u.Log.Error("failed")
+	sys.Log.Warn("degraded")
+	sys.Log.add(LogEntry{Time: time.Now(), Priority: LOG_ERR, Message: "kernel", Kernel: true})
+
+	sys.closeConsole()
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if assert.Len(t, lines, 2, "entries of low priority and of the kernel are not mirrored") {
+		assert.True(t, strings.HasSuffix(lines[0], " foo.service: failed"), lines[0])
+		assert.True(t, strings.HasSuffix(lines[1], " systemgo: degraded"), lines[1])
+	}
+
+	assert.Error(t, sys.ForwardToConsole(filepath.Join(dir, "missing", "tty"), LOG_INFO))
+}
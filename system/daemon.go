@@ -1,6 +1,9 @@
 package system
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -8,7 +11,11 @@ import (
 	"time"
 
 	"systemgo/unit"
+	"systemgo/unit/mount"
 	"systemgo/unit/service"
+	"systemgo/unit/socket"
+	"systemgo/unit/swap"
+	"systemgo/unit/timer"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -16,11 +23,19 @@ import (
 // Default paths to search for unit paths - Daemon uses those, if none are specified
 var DEFAULT_PATHS = []string{"/etc/systemd/system/", "/run/systemd/system", "/lib/systemd/system"}
 
+// Default path to create symlinks enabling units until reboot in
+const DEFAULT_RUNTIME_PATH = "/run/systemd/system"
+
+// Maximum number of jobs run concurrently by default
+const DEFAULT_MAX_JOBS = 16
+
 var supported = map[string]bool{
 	".service": true,
 	".target":  true,
-	".mount":   false,
-	".socket":  false,
+	".mount":   true,
+	".swap":    true,
+	".socket":  true,
+	".timer":   true,
 }
 
 // SupportedSuffix returns a bool indicating if suffix represents a unit type,
@@ -40,43 +55,316 @@ type Daemon struct {
 	// System log
 	Log *Log
 
-	// Map of created units (name -> *Unit)
-	units map[string]*Unit
+	// Created units by name and path
+	units *registry
+
+	// Dependency graph of the units created
+	deps *depIndex
+
+	// Serializes loading of units, so that a unit is only created once per name
+	loadMutex sync.Mutex
 
 	// Paths, where the unit file specifications get searched for
 	paths []string
 
+	// File system unit definitions get read from
+	fsys fileSystem
+
+	// Path, where symlinks enabling units until reboot get created
+	runtimePath string
+
+	// Paths, where the generator executables get searched for
+	generatorPaths []string
+
+	// Directory generators write the unit files to
+	generatorDir string
+
+	// Whether the generators have been run
+	generated bool
+
+	// Paths, where the preset files get searched for
+	presetPaths []string
+
 	// System state
 	state State
 
+	// Whether the daemon is a user manager
+	user bool
+
+	// Name of the target isolated on Boot
+	bootTarget string
+
+	// Name of the target reached on Boot and the time Boot finished at, zero if not finished yet
+	bootReached  string
+	bootFinished time.Time
+
 	// System starting time
 	since time.Time
 
-	mutex sync.Mutex
+	// Clock used for all time-dependent behavior
+	clock Clock
+
+	// Manager environment block passed to all spawned processes
+	environment map[string]string
+
+	// Executor spawning processes of units(nil means unit.OSExecutor)
+	executor unit.Executor
+
+	// cgroup v2 directory, in which cgroups of units are created(empty means disabled)
+	cgroup string
+
+	// Persistent journal of unit logs(nil means logs are only kept in-memory)
+	journal *Journal
+
+	// Forwarder of unit log entries to syslog(nil means entries are not forwarded)
+	syslog *syslogForwarder
+
+	// Kernel log buffer entries are forwarded to until the journal is set(nil means entries are not forwarded)
+	kmsg *os.File
+
+	// Forwarder of log entries to the console(nil means entries are not forwarded)
+	console *consoleForwarder
+
+	// Shipper of the unified log to a remote endpoint(nil means entries are not shipped)
+	shipper *logShipper
+
+	// ID of the current boot
+	bootID string
+
+	// Iterators following the logs, see QueryLogs
+	followers map[*LogIterator]bool
+
+	// Sinks added by embedders by name, see AddLogSink
+	sinks map[string]*logSinkForwarder
+
+	// Guards journal, syslog, kmsg, console, shipper, bootID, followers and sinks
+	logMutex sync.Mutex
+
+	// Shutdown scheduled by ScheduleShutdown, if any
+	scheduled *schedule
+
+	// Defaults applied to units, which do not specify their own values
+	defaults Defaults
+
+	// Contents of definition files read from disk
+	cache *definitionCache
+
+	// Names of units masked in-memory
+	masked map[string]bool
+
+	// Files kept open across re-executions by name
+	files map[string]*os.File
+
+	// Watcher of the unit paths started by Watch, nil if not watching
+	watcher    *pathWatcher
+	watchMutex sync.Mutex
+
+	// Watchers starting services on incoming traffic on the sockets of socket units
+	sockets     map[*Unit]*socketWatcher
+	socketMutex sync.Mutex
+
+	// Watchers activating units of timer units, whenever they elapse
+	timers     map[*Unit]*timerWatcher
+	timerMutex sync.Mutex
+
+	// Directory the times units were last activated at by persistent timers are stored in
+	timerStampPath string
+
+	// Tracer recording spans of transactions and jobs(nil means disabled)
+	tracer *tracer
+
+	// Configuration of the hardware watchdog and the device, if armed
+	watchdogConf  WatchdogConfig
+	watchdog      *hardwareWatchdog
+	watchdogMutex sync.Mutex
+
+	// Watchers probing the health of active units, which specify a health check
+	healths     map[*Unit]*healthWatcher
+	healthMutex sync.Mutex
+
+	// Semaphore limiting the number of jobs run concurrently(nil means no limit)
+	jobSlots chan struct{}
+
+	// Serializes building and dispatching of transactions, so that jobs of concurrent transactions
+	// see each other when checked for redundancy
+	jobMutex sync.Mutex
+
+	// Channels of event subscribers
+	subscribers map[chan Event]struct{}
+	eventMutex  sync.Mutex
+
+	// Guards the configuration and the state of sys, read-only queries only take the read lock.
+	// Units and jobs are guarded by the registry, the job queue and the units themselves
+	mutex sync.RWMutex
 }
 
 // New returns an instance of a Daemon ready to use
 func New() (sys *Daemon) {
-	return &Daemon{
-		units: make(map[string]*Unit),
-
-		since: time.Now(),
-		Log:   NewLog(),
-		paths: DEFAULT_PATHS,
+	sys = &Daemon{
+		units:       newRegistry(),
+		deps:        newDepIndex(),
+		subscribers: make(map[chan Event]struct{}),
+		masked:      make(map[string]bool),
+		files:       make(map[string]*os.File),
+		environment: environmentMap(DEFAULT_ENVIRONMENT),
+		cache:       newDefinitionCache(),
+		defaults:    DefaultDefaults(),
+
+		since:       RealClock.Now(),
+		clock:       RealClock,
+		bootTarget:  DEFAULT_TARGET,
+		Log:         NewLog(),
+		paths:       DEFAULT_PATHS,
+		fsys:        osFS{},
+		runtimePath: DEFAULT_RUNTIME_PATH,
+		presetPaths: DEFAULT_PRESET_PATHS,
+
+		timerStampPath: DEFAULT_TIMER_STAMP_PATH,
+		watchdogConf:   DefaultWatchdogConfig(),
+
+		generatorPaths: DEFAULT_GENERATOR_PATHS,
+		generatorDir:   DEFAULT_GENERATOR_DIR,
+
+		jobSlots: make(chan struct{}, DEFAULT_MAX_JOBS),
+
+		bootID: newBootID(),
 	}
+	sys.Log.boot = sys.bootID
+	sys.Log.clock = sys.clock
+	sys.Log.forward = func(e LogEntry) {
+		sys.forward(nil, e)
+	}
+	return sys
 }
 
 // Paths returns paths, which get searched for unit files by sys(first path gets searched first)
 func (sys *Daemon) Paths() (paths []string) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
 	return sys.paths
 }
 
 // SetPaths sets paths, which get searched for unit files by sys(first path gets searched first)
 func (sys *Daemon) SetPaths(paths ...string) {
+	sys.mutex.Lock()
+	sys.paths = paths
+	sys.deps.reset()
+	sys.mutex.Unlock()
+
+	sys.rewatch()
+}
+
+// RuntimePath returns the path, where sys creates symlinks enabling units until reboot
+func (sys *Daemon) RuntimePath() (path string) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.runtimePath
+}
+
+// SetRuntimePath sets the path, where sys creates symlinks enabling units until reboot.
+// The path should be one of the unit paths for the symlinks to take effect
+func (sys *Daemon) SetRuntimePath(path string) {
 	sys.mutex.Lock()
 	defer sys.mutex.Unlock()
 
-	sys.paths = paths
+	sys.runtimePath = path
+}
+
+// MaxJobs returns the maximum number of jobs sys runs concurrently(0 means no limit)
+func (sys *Daemon) MaxJobs() (n int) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return cap(sys.jobSlots)
+}
+
+// Clock returns the clock used by sys
+func (sys *Daemon) Clock() Clock {
+	return sys.clock
+}
+
+// SetClock sets the clock used by sys for timeouts, start rate limiting and timestamps,
+// and resets the starting time of sys to the current time of c.
+// It must be called before sys is used
+func (sys *Daemon) SetClock(c Clock) {
+	sys.clock = c
+	sys.since = c.Now()
+	sys.Log.clock = c
+}
+
+// Executor returns the Executor spawning processes of units, nil if the default one is used
+func (sys *Daemon) Executor() unit.Executor {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.executor
+}
+
+// SetExecutor sets the Executor spawning processes of units, which do not specify their own.
+// If e is nil, unit.OSExecutor is used
+func (sys *Daemon) SetExecutor(e unit.Executor) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.executor = e
+}
+
+// setExecutor passes the Executor of the manager to u, if it spawns processes.
+// Output of the processes is logged to the log of u and the processes are put into the cgroup of u,
+// if cgroups are enabled
+func (sys *Daemon) setExecutor(u *Unit) {
+	setter, ok := u.Interface.(unit.ExecutorSetter)
+	if !ok {
+		return
+	}
+
+	e := sys.Executor()
+	if e == nil {
+		e = unit.OSExecutor
+	}
+	if dir := sys.cgroupOf(u); dir != "" {
+		e = cgroupExecutor{Executor: e, dir: dir, attrs: cgroupAttrs(u), controllers: sys.cgroupControllers(u)}
+	}
+	setter.SetExecutor(stdioExecutor{Executor: e, log: u.Log})
+}
+
+// SetMaxJobs sets the maximum number of jobs sys runs concurrently.
+// If n <= 0, the number of jobs is not limited
+func (sys *Daemon) SetMaxJobs(n int) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	if n <= 0 {
+		sys.jobSlots = nil
+	} else {
+		sys.jobSlots = make(chan struct{}, n)
+	}
+}
+
+// acquireJob blocks until a job slot is available and returns a function releasing it
+func (sys *Daemon) acquireJob() (release func()) {
+	sys.mutex.RLock()
+	slots := sys.jobSlots
+	sys.mutex.RUnlock()
+
+	if slots == nil {
+		return func() {}
+	}
+
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// lockJobs locks the job queue of sys, if sys is not nil, and returns a function unlocking it
+func (sys *Daemon) lockJobs() (unlock func()) {
+	if sys == nil {
+		return func() {}
+	}
+
+	sys.jobMutex.Lock()
+	return sys.jobMutex.Unlock
 }
 
 // Since returns time, when sys was created
@@ -85,19 +373,17 @@ func (sys *Daemon) Since() (t time.Time) {
 }
 
 // IsEnabled returns enable state of the unit held in-memory under specified name.
-// If error is returned, it is going to be ErrNotFound
-//
-// TODO
+// If error is returned, it is going to be a *LoadError
 func (sys *Daemon) IsEnabled(name string) (st unit.Enable, err error) {
-	//var u *Unit
-	//if u, err = sys.Unit(name); err == nil && sys.Enabled[u] {
-	//st = unit.Enabled
-	//}
-	return -1, ErrNotImplemented
+	var u *Unit
+	if u, err = sys.Get(name); err == nil {
+		st = u.EnableState()
+	}
+	return
 }
 
 // IsActive returns activation state of the unit held in-memory under specified name.
-// If error is returned, it is going to be ErrNotFound
+// If error is returned, it is going to be a *LoadError
 func (sys *Daemon) IsActive(name string) (st unit.Activation, err error) {
 	var u *Unit
 	if u, err = sys.Get(name); err == nil {
@@ -107,7 +393,7 @@ func (sys *Daemon) IsActive(name string) (st unit.Activation, err error) {
 }
 
 // StatusOf returns status of the unit held in-memory under specified name.
-// If error is returned, it is going to be ErrNotFound
+// If error is returned, it is going to be a *LoadError
 func (sys *Daemon) StatusOf(name string) (st unit.Status, err error) {
 	var u *Unit
 	if u, err = sys.Get(name); err != nil {
@@ -117,6 +403,31 @@ func (sys *Daemon) StatusOf(name string) (st unit.Status, err error) {
 	return u.Status(), nil
 }
 
+// Statuses returns the statuses of units specified by names mapped by name, or of all units held in-memory,
+// if none are specified. Names of units, which cannot be found, are mapped to the errors in errs, nil if all are found
+func (sys *Daemon) Statuses(names ...string) (statuses map[string]unit.Status, errs map[string]error) {
+	statuses = map[string]unit.Status{}
+	if len(names) == 0 {
+		for _, u := range sys.Units() {
+			statuses[u.Name()] = u.Status()
+		}
+		return
+	}
+
+	for _, name := range names {
+		st, err := sys.StatusOf(name)
+		if err != nil {
+			if errs == nil {
+				errs = map[string]error{}
+			}
+			errs[name] = err
+			continue
+		}
+		statuses[name] = st
+	}
+	return
+}
+
 // Start gets names from internal hashmap, creates a new start transaction and runs it
 func (sys *Daemon) Start(names ...string) (err error) {
 	log.WithField("names", names).Debugf("sys.Start")
@@ -125,7 +436,7 @@ func (sys *Daemon) Start(names ...string) (err error) {
 	if tr, err = sys.newTransaction(start, names); err != nil {
 		return
 	}
-	return tr.Run()
+	return tr.run()
 }
 
 // Stop gets names from internal hashmap, creates a new stop transaction and runs it
@@ -136,11 +447,12 @@ func (sys *Daemon) Stop(names ...string) (err error) {
 	if tr, err = sys.newTransaction(stop, names); err != nil {
 		return
 	}
-	return tr.Run()
+	return tr.run()
 }
 
 // Isolate gets names from internal hashmap, creates a new start transaction, adds a stop job
-// for each unit currently active, but not in the transaction already and runs the transaction
+// for each unit currently active, but not in the transaction already and runs the transaction.
+// Units with IgnoreOnIsolate set are left untouched
 func (sys *Daemon) Isolate(names ...string) (err error) {
 	log.WithField("names", names).Debugf("sys.Isolate")
 
@@ -150,7 +462,7 @@ func (sys *Daemon) Isolate(names ...string) (err error) {
 	}
 
 	for _, u := range sys.Units() {
-		if _, ok := tr.unmerged[u]; ok {
+		if _, ok := tr.unmerged[u]; ok || u.IgnoresIsolate() {
 			continue
 		}
 
@@ -158,7 +470,7 @@ func (sys *Daemon) Isolate(names ...string) (err error) {
 			return
 		}
 	}
-	return tr.Run()
+	return tr.run()
 }
 
 // Restart gets names from internal hashmap, creates a new restart transaction and runs it
@@ -169,7 +481,42 @@ func (sys *Daemon) Restart(names ...string) (err error) {
 	if tr, err = sys.newTransaction(restart, names); err != nil {
 		return
 	}
-	return tr.Run()
+	return tr.run()
+}
+
+// TryRestart gets names from internal hashmap, creates a new transaction restarting the units,
+// which are active, and runs it
+func (sys *Daemon) TryRestart(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.TryRestart")
+
+	var tr *transaction
+	if tr, err = sys.newTransaction(tryRestart, names); err != nil {
+		return
+	}
+	return tr.run()
+}
+
+// ReloadOrRestart gets names from internal hashmap, creates a new transaction reloading the units,
+// which support reloading and are active, restarting the rest, and runs it
+func (sys *Daemon) ReloadOrRestart(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.ReloadOrRestart")
+
+	var tr *transaction
+	if tr, err = sys.newTransaction(reloadOrRestart, names); err != nil {
+		return
+	}
+	return tr.run()
+}
+
+// ReloadOrTryRestart is similar to ReloadOrRestart, but leaves inactive units untouched
+func (sys *Daemon) ReloadOrTryRestart(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.ReloadOrTryRestart")
+
+	var tr *transaction
+	if tr, err = sys.newTransaction(tryReloadOrRestart, names); err != nil {
+		return
+	}
+	return tr.run()
 }
 
 // Reload gets names from internal hashmap, creates a new reload transaction and runs it
@@ -180,14 +527,19 @@ func (sys *Daemon) Reload(names ...string) (err error) {
 	if tr, err = sys.newTransaction(reload, names); err != nil {
 		return
 	}
-	return tr.Run()
+	return tr.run()
 }
 
 func (sys *Daemon) newTransaction(typ jobType, names []string) (tr *transaction, err error) {
-	sys.mutex.Lock()
-	defer sys.mutex.Unlock()
+	defer sys.lockJobs()()
 
-	tr = newTransaction()
+	tr = newTransaction(sys)
+
+	for _, name := range names {
+		if irreversibleTargets[name] {
+			tr.irreversible = true
+		}
+	}
 
 	for _, name := range names {
 		var dep *Unit
@@ -196,7 +548,7 @@ func (sys *Daemon) newTransaction(typ jobType, names []string) (tr *transaction,
 		}
 
 		if err = tr.add(typ, dep, nil, true, true); err != nil {
-			return nil, err
+			return nil, &UnitError{name, fmt.Sprint(typ), err}
 		}
 	}
 	return
@@ -228,6 +580,32 @@ func (sys *Daemon) Disable(names ...string) (err error) {
 	})
 }
 
+// EnableRuntime gets names from internal hasmap and calls EnableRuntime() on each unit returned
+func (sys *Daemon) EnableRuntime(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.EnableRuntime")
+
+	return sys.getAndExecute(names, func(u *Unit, gerr error) error {
+		if gerr != nil {
+			return gerr
+		}
+
+		return u.EnableRuntime()
+	})
+}
+
+// DisableRuntime gets names from internal hasmap and calls DisableRuntime() on each unit returned
+func (sys *Daemon) DisableRuntime(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.DisableRuntime")
+
+	return sys.getAndExecute(names, func(u *Unit, gerr error) error {
+		if gerr != nil {
+			return gerr
+		}
+
+		return u.DisableRuntime()
+	})
+}
+
 func (sys *Daemon) getAndExecute(names []string, fn func(*Unit, error) error) (err error) {
 	for _, name := range names {
 		if err = fn(sys.Get(name)); err != nil {
@@ -241,16 +619,7 @@ func (sys *Daemon) getAndExecute(names []string, fn func(*Unit, error) error) (e
 func (sys *Daemon) Units() (units []*Unit) {
 	log.Debugf("sys.Units")
 
-	unitSet := map[*Unit]struct{}{}
-	for _, u := range sys.units {
-		unitSet[u] = struct{}{}
-	}
-
-	units = make([]*Unit, 0, len(unitSet))
-	for u := range unitSet {
-		units = append(units, u)
-	}
-	return
+	return sys.units.all()
 }
 
 // Unit looks up unit name in the internal hasmap and returns the unit created associated with it
@@ -259,7 +628,7 @@ func (sys *Daemon) Unit(name string) (u *Unit, err error) {
 	log.WithField("name", name).Debug("sys.Unit")
 
 	var ok bool
-	if u, ok = sys.units[name]; !ok {
+	if u, ok = sys.units.get(name); !ok {
 		return nil, ErrNotFound
 	}
 	return
@@ -267,29 +636,74 @@ func (sys *Daemon) Unit(name string) (u *Unit, err error) {
 
 // Get looks up the unit name in the internal hasmap of loaded units and calls
 // sys.Load(name) if it can not be found.
-// If error is returned, it will be error from sys.Load(name)
+// If error is returned, it will be the *LoadError from sys.Load(name)
 func (sys *Daemon) Get(name string) (u *Unit, err error) {
 	log.WithField("name", name).Debug("sys.Get")
 
-	if u, err = sys.Unit(name); err != nil || !u.IsLoaded() {
-		return sys.load(name)
+	if u, err = sys.Unit(name); err == nil && u.IsLoaded() {
+		return
 	}
-	return
+
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
+	// The unit might have been loaded while waiting for the lock
+	if u, err = sys.Unit(name); err == nil && u.IsLoaded() {
+		return
+	}
+	return sys.load(name)
 }
 
 // Supervise creates a *Unit wrapping v and stores it in internal hashmap.
+// hooks are notified of activation state transitions of the unit.
 // If a unit with name specified already exists - nil and ErrExists are returned
-func (sys *Daemon) Supervise(name string, v unit.Interface) (u *Unit, err error) {
+func (sys *Daemon) Supervise(name string, v unit.Interface, hooks ...Hooks) (u *Unit, err error) {
 	log.WithFields(log.Fields{
 		"name":      name,
 		"interface": v,
 	}).Debugf("sys.Supervise")
 
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
 	if u, err = sys.Unit(name); err == nil {
 		return nil, ErrExists
 	}
 
-	return sys.newUnit(name, v), nil
+	u = sys.newUnit(name, v)
+	u.hooks = hooks
+	return u, nil
+}
+
+// Load defines the unit with name specified by the definition read from r, parsed and validated
+// the same way, as definitions of units loaded from disk. Drop-ins do not apply to the unit.
+// If the unit already exists, it gets redefined.
+// Errors returned are of type *LoadError, u is nil only if name is not supported or r can not be read
+func (sys *Daemon) Load(name string, r io.Reader) (u *Unit, err error) {
+	log.WithField("name", name).Debugf("sys.Load")
+
+	if !Supported(name) {
+		return nil, &LoadError{Name: name, Err: ErrUnknownType}
+	}
+
+	var b []byte
+	if b, err = ioutil.ReadAll(r); err != nil {
+		return nil, &LoadError{Name: name, Err: err}
+	}
+
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
+	if u, err = sys.Unit(name); err != nil {
+		u = sys.newUnit(name, sys.newInterface(name))
+	}
+	// Not loaded from disk, hence not re-read on daemon reload
+	u.setPath("")
+
+	if err = u.define(b); err != nil {
+		return u, &LoadError{Name: name, Err: err}
+	}
+	return u, nil
 }
 
 func (sys *Daemon) newUnit(name string, v unit.Interface) (u *Unit) {
@@ -300,98 +714,144 @@ func (sys *Daemon) newUnit(name string, v unit.Interface) (u *Unit) {
 
 	u = NewUnit(v)
 	u.name = name
+	u.Log.unit = name
+	u.Log.boot = sys.BootID()
+	u.Log.clock = sys.clock
+	u.Log.forward = func(e LogEntry) {
+		sys.forward(u, e)
+	}
 
 	u.System = sys
 
-	sys.units[name] = u
-	if strings.HasSuffix(name, ".service") {
-		sys.units[strings.TrimSuffix(name, ".service")] = u
+	if notifier, ok := v.(unit.StateNotifier); ok {
+		notifier.SetStateNotify(u.stateChanged)
 	}
 
+	if j := sys.Journal(); j != nil {
+		j.attach(u)
+	}
+
+	sys.units.set(u, unitKeys(name)...)
+	sys.deps.add(u)
+
 	return
 }
 
+// unitKeys returns the names a unit named name is registered under, services are registered without the suffix as well
+func unitKeys(name string) (keys []string) {
+	keys = []string{name}
+	if strings.HasSuffix(name, ".service") {
+		keys = append(keys, strings.TrimSuffix(name, ".service"))
+	}
+	return
+}
+
+// forward forwards e logged by u, nil for the manager, to the destinations enabled.
+// Of the entries of the manager only the ones of the audit stream are forwarded to syslog
+func (sys *Daemon) forward(u *Unit, e LogEntry) {
+	if u != nil || e.Audit {
+		sys.forwardToSyslog(u, e)
+	}
+	sys.forwardToKmsg(e)
+	sys.forwardToConsole(e)
+	sys.shipLog(u, e)
+	sys.writeLogSinks(e)
+	sys.followLog(e)
+}
+
+// newInterface returns a new unit.Interface of type corresponding to the suffix of name
+func (sys *Daemon) newInterface(name string) (v unit.Interface) {
+	switch filepath.Ext(name) {
+	case ".target":
+		return &Target{System: sys}
+	case ".service":
+		return &service.Unit{}
+	case ".mount":
+		return &mount.Unit{}
+	case ".swap":
+		return &swap.Unit{}
+	case ".socket":
+		return &socket.Unit{}
+	case ".timer":
+		return &timer.Unit{}
+	default:
+		panic("Trying to load an unsupported unit type")
+	}
+}
+
 // load searches for name in configured paths, parses it, and either overwrites the definition of already
-// created Unit or creates a new one
+// created Unit or creates a new one. Errors are returned as *LoadError.
+// sys.loadMutex must be held
 func (sys *Daemon) load(name string) (u *Unit, err error) {
 	log.WithField("name", name).Debugln("sys.Load")
 
 	if !Supported(name) {
-		return nil, ErrUnknownType
+		return nil, &LoadError{Name: name, Err: ErrUnknownType}
+	}
+
+	if sys.isMasked(name) {
+		if u, err = sys.Unit(name); err != nil {
+			u = sys.newUnit(name, sys.newInterface(name))
+		}
+		u.setLoad(unit.Masked)
+		return u, nil
 	}
 
 	var paths []string
 	if filepath.IsAbs(name) {
 		paths = []string{name}
 	} else {
-		paths = make([]string, len(sys.paths))
-		for i, path := range sys.paths {
+		dirs := sys.Paths()
+		paths = make([]string, len(dirs))
+		for i, path := range dirs {
 			paths[i] = filepath.Join(path, name)
 		}
 	}
 
 	for _, path := range paths {
-		var file *os.File
-		if file, err = os.Open(path); err != nil {
+		var info os.FileInfo
+		if info, err = sys.fsys.Stat(path); err != nil {
 			if os.IsNotExist(err) {
 				continue
 			}
-			return nil, err
+			return nil, &LoadError{name, path, err}
 		}
-		// Commented out because of gopherjs bug,
-		// which breaks systemgo on Browsix
-		// See https://goo.gl/AycBTv
-		//
-		//defer file.Close()
 
 		// Check if a unit for name had already been created
 		if u, err = sys.Unit(name); err != nil {
 			// If not - create a new one
-			var v unit.Interface
-			switch filepath.Ext(name) {
-			case ".target":
-				v = &Target{System: sys}
-			case ".service":
-				v = &service.Unit{}
-			default:
-				panic("Trying to load an unsupported unit type")
-			}
-
-			u = sys.newUnit(name, v)
+			u = sys.newUnit(name, sys.newInterface(name))
 		}
 
-		u.path = path
-		sys.units[path] = u
+		u.setPath(path)
+		sys.units.set(u, path)
 
-		var info os.FileInfo
-		if info, err = file.Stat(); err == nil && info.IsDir() {
-			err = ErrIsDir
+		if isMaskLinkIn(sys.fsys, path) {
+			u.setLoad(unit.Masked)
+			return u, nil
 		}
-		if err != nil {
-			u.Log.Errorf("%s", err)
-			file.Close()
-			return u, err
+
+		if info.IsDir() {
+			u.Log.Errorf("%s", ErrIsDir)
+			return u, &LoadError{name, path, ErrIsDir}
 		}
 
-		if err = u.Interface.Define(file); err != nil {
-			if me, ok := err.(unit.MultiError); ok {
-				u.Log.Error("Definition is invalid:")
-				for _, errmsg := range me.Errors() {
-					u.Log.Error(errmsg)
-				}
-			} else {
-				u.Log.Errorf("Error parsing definition: %s", err)
-			}
-			u.load = unit.Error
-			file.Close()
-			return u, err
+		var b []byte
+		if b, err = sys.readDefinitionFile(path); err != nil {
+			u.Log.Errorf("Error reading definition: %s", err)
+			return u, &LoadError{name, path, err}
 		}
 
-		u.load = unit.Loaded
-		return u, file.Close()
+		if err = u.define(b); err != nil {
+			return u, &LoadError{name, path, err}
+		}
+		return u, nil
 	}
 
-	return nil, ErrNotFound
+	if template, ok := templateOf(name); ok {
+		return sys.loadInstance(name, template)
+	}
+	return nil, &LoadError{Name: name, Err: ErrNotFound}
 }
 
 // pathset returns a slice of paths to definitions of supported unit types found in path specified
@@ -4,6 +4,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -52,7 +54,7 @@ func TestGet(t *testing.T) {
 	u, err := sys.Supervise(name, m)
 	require.NoError(t, err)
 
-	sys.units[fpath] = u
+	sys.units.set(u, fpath)
 
 	for _, name := range []string{name, fpath} {
 		ptr, err := sys.Get(name)
@@ -61,6 +63,45 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestLoad(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.target", strings.NewReader(`[Unit]
+Description=Foo`))
+	require.NoError(t, err, "sys.Load")
+	assert.True(t, u.IsLoaded(), "u.IsLoaded")
+	assert.Equal(t, "Foo", u.Description())
+
+	got, err := sys.Get("foo.target")
+	require.NoError(t, err, "sys.Get")
+	assert.Equal(t, u, got)
+
+	require.NoError(t, sys.Start("foo.target"), "sys.Start")
+	assert.Equal(t, unit.Active, u.Active())
+
+	u, err = sys.Load("foo.target", strings.NewReader(`[Unit]
+Description=Bar`))
+	require.NoError(t, err, "sys.Load redefining")
+	assert.Equal(t, got, u)
+	assert.Equal(t, "Bar", u.Description())
+
+	u, err = sys.Load("bar.service", strings.NewReader(`[Service]`))
+	var lerr *LoadError
+	if assert.ErrorAs(t, err, &lerr) {
+		assert.Equal(t, "bar.service", lerr.Name)
+	}
+	var merr unit.MultiError
+	assert.ErrorAs(t, err, &merr)
+	if assert.NotNil(t, u) {
+		assert.Equal(t, unit.Error, u.Loaded())
+	}
+
+	u, err = sys.Load("foo.bar", strings.NewReader(``))
+	assert.ErrorIs(t, err, ErrUnknownType)
+	assert.Nil(t, u)
+}
+
 func TestSuported(t *testing.T) {
 	for suffix, is := range supported {
 		assert.Equal(t, is, Supported("foo"+suffix))
@@ -154,6 +195,7 @@ func TestStop(t *testing.T) {
 	m := newMock(ctrl)
 	m.MockStopper.EXPECT().Stop().Return(nil).Times(1)
 	m.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+	empty(m, "after", "before")
 
 	sys := New()
 
@@ -180,6 +222,8 @@ func TestIsolate(t *testing.T) {
 	mocks["a"].MockStopper.EXPECT().Stop().Return(nil).Times(1)
 	mocks["b"].MockStopper.EXPECT().Stop().Return(nil).Times(1)
 
+	empty(mocks["a"], "after", "before")
+	empty(mocks["b"], "after", "before")
 	empty(mocks["c"], "wants", "before", "conflicts", "after", "requires")
 
 	for name, mock := range mocks {
@@ -191,6 +235,14 @@ func TestIsolate(t *testing.T) {
 		u.load = unit.Loaded
 	}
 
+	ignoring := newMock(ctrl)
+	ignoring.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+	ignoring.MockStopper.EXPECT().Stop().Times(0)
+
+	u, err := sys.Supervise("d", isolateIgnorer{ignoring})
+	require.NoError(t, err)
+	u.load = unit.Loaded
+
 	require.NoError(t, sys.Isolate("c"), "sys.Isolate")
 
 	names := make([]string, 0, len(mocks))
@@ -200,6 +252,64 @@ func TestIsolate(t *testing.T) {
 	waitForJobs(t, sys, "a", "b")
 }
 
+func TestIrreversible(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+
+	m := newMock(ctrl)
+	for _, method := range []string{"wants", "conflicts", "requires", "after", "before"} {
+		emptyOne(m, method).AnyTimes()
+	}
+	m.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+
+	stopping, release := make(chan struct{}), make(chan struct{})
+	m.MockStopper.EXPECT().Stop().DoAndReturn(func() error {
+		close(stopping)
+		<-release
+		return nil
+	}).Times(1)
+
+	u, err := sys.Supervise("shutdown.target", m)
+	require.NoError(t, err)
+	u.load = unit.Loaded
+
+	tr, err := sys.newTransaction(stop, []string{"shutdown.target"})
+	if assert.NoError(t, err) {
+		assert.True(t, tr.irreversible, "tr.irreversible")
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- sys.stopAll()
+	}()
+
+	select {
+	case <-stopping:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the stop job")
+	}
+	if j := u.runningJob(); assert.NotNil(t, j, "running job") {
+		assert.True(t, j.irreversible, "job.irreversible")
+	}
+
+	// Jobs conflicting with the stop job run on shutdown are refused
+	assert.ErrorIs(t, sys.Start("shutdown.target"), ErrIrreversible, "sys.Start")
+	assert.ErrorIs(t, sys.Restart("shutdown.target"), ErrIrreversible, "sys.Restart")
+
+	close(release)
+	assert.NoError(t, <-stopped, "sys.stopAll")
+}
+
+type isolateIgnorer struct {
+	*mockUnit
+}
+
+func (m isolateIgnorer) IgnoreOnIsolate() bool {
+	return true
+}
+
 func waitForJobs(t *testing.T, sys *Daemon, names ...string) {
 	wg := &sync.WaitGroup{}
 	for _, name := range names {
@@ -210,75 +320,197 @@ func waitForJobs(t *testing.T, sys *Daemon, names ...string) {
 		go func(name string, u *Unit) {
 			defer wg.Done()
 
-			for u.job == nil {
+			for u.currentJob() == nil {
 				log.Warnf("%s job still nil", name)
 				time.Sleep(100 * time.Millisecond)
 			}
 
 			log.Warnf("Waiting for %s job to finish", name)
-			u.job.Wait()
+			u.currentJob().Wait()
 
-			assert.True(t, u.job.Success())
+			assert.True(t, u.currentJob().Success())
 		}(name, u)
 	}
 	wg.Wait()
 }
 
 func TestEnable(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
+	admin, err := ioutil.TempDir("", "enable-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "enable-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	for name, contents := range map[string]string{
+		"test.service": `[Service]
+ExecStart=/bin/true
+
+[Install]
+WantedBy=test.target
+RequiredBy=test.target
+Alias=alias.service
+Also=other.service`,
+		"other.service": `[Service]
+ExecStart=/bin/true
+
+[Install]
+WantedBy=test.target`,
+		"static.service": `[Service]
+ExecStart=/bin/true`,
+		"test.target": ``,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, name), []byte(contents), 0644))
+	}
 
 	sys := New()
+	sys.SetPaths(admin, vendor)
 
-	m := mock_unit.NewMockInterface(ctrl)
-	m.EXPECT().WantedBy().Return([]string{"test.target"}).Times(2)
-	m.EXPECT().RequiredBy().Return([]string{"test.target"}).Times(2)
+	for name, expected := range map[string]unit.Enable{
+		"test.service":   unit.Disabled,
+		"other.service":  unit.Disabled,
+		"static.service": unit.Static,
+	} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err, "sys.IsEnabled")
+		assert.Equal(t, expected, st, name)
+	}
 
-	var err error
+	require.NoError(t, sys.Enable("test.service"), "sys.Enable")
 
-	for name, iface := range map[string]unit.Interface{
-		"test.target":  nil,
-		"test.service": m,
+	for _, link := range []string{
+		filepath.Join("test.target.wants", "test.service"),
+		filepath.Join("test.target.requires", "test.service"),
+		filepath.Join("test.target.wants", "other.service"),
+		"alias.service",
 	} {
-		e := log.WithField("name", name)
+		path, err := os.Readlink(filepath.Join(admin, link))
+		require.NoError(t, err, "os.Readlink")
+		assert.Equal(t, vendor, filepath.Dir(path), "link path")
+	}
 
-		var u *Unit
-		if u, err = sys.Supervise(name, iface); err != nil {
-			e.WithField("err", err).Fatal("sys.Supervise")
-		}
+	target, err := sys.Get("test.target")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other.service", "test.service"}, target.Wants())
+	assert.Equal(t, []string{"test.service"}, target.Requires())
 
-		var f *os.File
-		if f, err = ioutil.TempFile("", name); err != nil {
-			e.WithField("err", err).Fatal("ioutil.TempDir")
-		}
+	// Enabling twice is not an error
+	require.NoError(t, sys.Enable("test.service"), "sys.Enable")
 
-		u.path = f.Name()
-		u.load = unit.Loaded
+	for _, name := range []string{"test.service", "other.service"} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err, "sys.IsEnabled")
+		assert.Equal(t, unit.Enabled, st, name)
 	}
 
-	require.NoError(t, sys.Enable("test.service"), "sys.Enable")
-
-	for _, suffix := range []string{"wants", "requires"} {
-		path, err := os.Readlink(filepath.Join(sys.units["test.target"].path+"."+suffix, "test.service"))
-		require.NoError(t, err, "os.Readlink")
-		assert.Equal(t, path, sys.units["test.service"].path, "link path")
+	require.NoError(t, sys.Disable("test.service"), "sys.Disable")
+	for _, link := range []string{
+		filepath.Join("test.target.wants", "test.service"),
+		filepath.Join("test.target.requires", "test.service"),
+		filepath.Join("test.target.wants", "other.service"),
+		"alias.service",
+	} {
+		_, err := os.Lstat(filepath.Join(admin, link))
+		assert.True(t, os.IsNotExist(err), "os.Lstat")
 	}
 
-	// TODO implement
-	//st, err := sys.IsEnabled("test.service")
-	//assert.NoError(t, err, "sys.IsEnabled")
-	//assert.Equal(t, unit.Enabled, st, "sys.IsEnabled")
+	st, err := sys.IsEnabled("test.service")
+	assert.NoError(t, err, "sys.IsEnabled")
+	assert.Equal(t, unit.Disabled, st, "sys.IsEnabled")
 
-	require.NoError(t, sys.Disable("test.service"), "sys.Disable")
-	for _, suffix := range []string{"wants", "requires"} {
-		_, err := os.Open(filepath.Join(sys.units["test.target"].path+"."+suffix, "test.service"))
-		assert.True(t, os.IsNotExist(err), "os.Open")
+	require.NoError(t, sys.Mask("test.service"))
+	assert.Equal(t, ErrMasked, sys.Enable("test.service"))
+
+	st, err = sys.IsEnabled("test.service")
+	assert.NoError(t, err, "sys.IsEnabled")
+	assert.Equal(t, unit.EnableMasked, st, "sys.IsEnabled")
+}
+
+func TestEnableRuntime(t *testing.T) {
+	admin, err := ioutil.TempDir("", "enable-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	runtime, err := ioutil.TempDir("", "enable-runtime")
+	require.NoError(t, err)
+	defer os.RemoveAll(runtime)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(admin, "test.service"), []byte(`[Service]
+ExecStart=/bin/true
+
+[Install]
+WantedBy=test.target`), 0644))
+
+	sys := New()
+	sys.SetPaths(admin, runtime)
+	sys.SetRuntimePath(runtime)
+
+	require.NoError(t, sys.EnableRuntime("test.service"))
+
+	_, err = os.Readlink(filepath.Join(runtime, "test.target.wants", "test.service"))
+	assert.NoError(t, err, "os.Readlink")
+	_, err = os.Lstat(filepath.Join(admin, "test.target.wants", "test.service"))
+	assert.True(t, os.IsNotExist(err), "os.Lstat")
+
+	st, err := sys.IsEnabled("test.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.EnabledRuntime, st)
+
+	require.NoError(t, sys.Enable("test.service"))
+	st, err = sys.IsEnabled("test.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Enabled, st)
+
+	require.NoError(t, sys.Disable("test.service"))
+	st, err = sys.IsEnabled("test.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.EnabledRuntime, st)
+
+	require.NoError(t, sys.DisableRuntime("test.service"))
+	st, err = sys.IsEnabled("test.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Disabled, st)
+}
+
+func TestLinkedDeps(t *testing.T) {
+	admin, err := ioutil.TempDir("", "deps-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "deps-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "test.target"), []byte(`[Unit]
+Wants=a.service`), 0644))
+
+	for dir, links := range map[string]map[string]string{
+		admin: {
+			"test.target.wants/b.service":    filepath.Join(vendor, "b.service"),
+			"test.target.wants/c.service":    MASK_TARGET,
+			"test.target.requires/d.service": filepath.Join(vendor, "d.service"),
+		},
+		vendor: {
+			"test.target.wants/b.service": filepath.Join(vendor, "b.service"),
+			"test.target.wants/e.service": filepath.Join(vendor, "e.service"),
+		},
+	} {
+		for link, target := range links {
+			path := filepath.Join(dir, link)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+			require.NoError(t, os.Symlink(target, path))
+		}
 	}
 
-	// TODO implement
-	//st, err = sys.IsEnabled("test.service")
-	//assert.NoError(t, err, "sys.IsEnabled")
-	//assert.Equal(t, unit.Disabled, st, "sys.IsEnabled")
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	u, err := sys.Get("test.target")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a.service", "b.service", "e.service"}, u.Wants())
+	assert.Equal(t, []string{"d.service"}, u.Requires())
 }
 
 func empty(m *mockUnit, methods ...string) {
@@ -307,3 +539,129 @@ func emptyOne(m *mockUnit, method string) (c *gomock.Call) {
 	}
 	return c.Return([]string{})
 }
+
+func TestConcurrentStartStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "concurrent-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	names := []string{"a.service", "b.service", "c.service", "d.service"}
+	for _, name := range names {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name),
+			[]byte("[Unit]\nWants=a.service b.service\n[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true"), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for _, name := range names {
+			wg.Add(2)
+			go func(name string) {
+				defer wg.Done()
+				assert.NoError(t, sys.Start(name), name)
+			}(name)
+			go func(name string) {
+				defer wg.Done()
+				_, err := sys.StatusOf(name)
+				assert.NoError(t, err, name)
+				sys.Units()
+			}(name)
+		}
+	}
+	wg.Wait()
+
+	assert.Len(t, sys.Units(), len(names))
+	for _, name := range names {
+		u, err := sys.Get(name)
+		require.NoError(t, err, name)
+		// Jobs of wanted units are not waited for, when they get started by another transaction
+		assert.Eventually(t, u.IsActive, time.Second, 10*time.Millisecond, name)
+
+		ptr, err := sys.Unit(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		assert.Equal(t, u, ptr, name)
+	}
+}
+
+// newBenchmarkDaemon returns a Daemon holding n targets, each of which wants the previous one
+func newBenchmarkDaemon(b *testing.B, n int) (sys *Daemon, names []string) {
+	log.SetLevel(log.WarnLevel)
+
+	sys = New()
+	sys.SetPaths()
+
+	for i := 0; i < n; i++ {
+		name := "bench" + strconv.Itoa(i) + ".target"
+		def := "[Unit]\nDescription=Benchmark"
+		if i > 0 {
+			def += "\nWants=" + names[i-1] + "\nAfter=" + names[i-1]
+		}
+		_, err := sys.Load(name, strings.NewReader(def))
+		require.NoError(b, err)
+		names = append(names, name)
+	}
+	return
+}
+
+// BenchmarkParallelQueries measures the throughput of read-only queries issued concurrently,
+// while the configuration of the daemon is changed every now and then
+func BenchmarkParallelQueries(b *testing.B) {
+	sys, names := newBenchmarkDaemon(b, 100)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				sys.SetEnvironment("BENCHMARK=1")
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			u, _ := sys.Unit(names[i%len(names)])
+			u.Loaded()
+			u.Path()
+			sys.Defaults()
+			sys.MaxJobs()
+			sys.Cgroup()
+			i++
+		}
+	})
+}
+
+// BenchmarkParallelStatus measures the throughput of status queries issued concurrently, while transactions are run
+func BenchmarkParallelStatus(b *testing.B) {
+	sys, names := newBenchmarkDaemon(b, 100)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				sys.Start(names[i%len(names)])
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			sys.StatusOf(names[i%len(names)])
+			sys.State()
+			i++
+		}
+	})
+}
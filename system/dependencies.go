@@ -0,0 +1,153 @@
+package system
+
+import (
+	"path/filepath"
+	"sort"
+
+	"systemgo/unit"
+)
+
+// DependencyOptions specify the dependency tree returned by ListDependencies
+type DependencyOptions struct {
+	// Whether to list units depending on the unit instead of its dependencies
+	Reverse bool
+
+	// Whether to expand all units recursively, only targets are expanded otherwise
+	All bool
+}
+
+// DependencyNode is a node of the dependency tree returned by ListDependencies
+type DependencyNode struct {
+	Name   string
+	Load   unit.Load
+	Active unit.Activation
+
+	// Dependencies(or dependent units, if the tree is reversed) sorted by name
+	Deps []DependencyNode `json:",omitempty"`
+}
+
+// ListDependencies returns the tree of units required or wanted by the unit with name specified,
+// annotated with their states. Units already listed on the path from the root are not expanded again
+func (sys *Daemon) ListDependencies(name string, opts DependencyOptions) (root DependencyNode, err error) {
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return
+	}
+
+	return sys.dependencyNode(u, opts, map[*Unit]bool{}), nil
+}
+
+// dependencyNode returns the node of u, expanding it, unless it is on the path already
+func (sys *Daemon) dependencyNode(u *Unit, opts DependencyOptions, path map[*Unit]bool) (node DependencyNode) {
+	node = DependencyNode{
+		Name:   u.Name(),
+		Load:   u.Loaded(),
+		Active: u.Active(),
+	}
+
+	if path[u] || !opts.All && len(path) > 0 && filepath.Ext(u.Name()) != ".target" {
+		return
+	}
+	path[u] = true
+	defer delete(path, u)
+
+	var names []string
+	if opts.Reverse {
+		names = sys.dependents(u)
+	} else {
+		names = sys.dependencies(u)
+	}
+
+	for _, name := range names {
+		dep, _ := sys.Get(name)
+		if dep == nil {
+			node.Deps = append(node.Deps, DependencyNode{Name: name, Load: unit.NotFound})
+			continue
+		}
+		node.Deps = append(node.Deps, sys.dependencyNode(dep, opts, path))
+	}
+	sort.Slice(node.Deps, func(i, j int) bool { return node.Deps[i].Name < node.Deps[j].Name })
+	return
+}
+
+// dependencies returns names of the units required or wanted by u
+func (sys *Daemon) dependencies(u *Unit) (names []string) {
+	seen := map[string]bool{}
+	for _, name := range append(sys.deps.get(u, depRequires), sys.deps.get(u, depWants)...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// dependents returns names of the units held in-memory, which require or want u
+func (sys *Daemon) dependents(u *Unit) (names []string) {
+	seen := map[*Unit]bool{}
+	for _, typ := range []depType{depRequires, depWants} {
+		for _, other := range sys.deps.dependents(typ, unitKeys(u.Name())...) {
+			if !seen[other] {
+				seen[other] = true
+				names = append(names, other.Name())
+			}
+		}
+	}
+	return
+}
+
+// DependencyEdge is an edge of the dependency graph returned by DependencyGraph
+type DependencyEdge struct {
+	From, To string
+
+	// Type of the dependency, one of "Requires", "Wants", "Conflicts" and "After"
+	Type string
+}
+
+// DependencyGraph returns the Requires=, Wants=, Conflicts= and After= dependencies of the loaded units held in-memory,
+// sorted by the units they originate from. Before= dependencies are listed as After= dependencies of the unit ordered after
+func (sys *Daemon) DependencyGraph() (edges []DependencyEdge) {
+	seen := map[DependencyEdge]bool{}
+	add := func(e DependencyEdge) {
+		if !seen[e] {
+			seen[e] = true
+			edges = append(edges, e)
+		}
+	}
+
+	edges = []DependencyEdge{}
+	for _, u := range sys.Units() {
+		if !u.IsLoaded() {
+			continue
+		}
+
+		for _, deps := range []struct {
+			typ   string
+			names []string
+		}{
+			{"Requires", sys.deps.get(u, depRequires)},
+			{"Wants", sys.deps.get(u, depWants)},
+			{"Conflicts", sys.deps.get(u, depConflicts)},
+			{"After", sys.deps.get(u, depAfter)},
+		} {
+			for _, name := range deps.names {
+				add(DependencyEdge{From: u.Name(), To: name, Type: deps.typ})
+			}
+		}
+		for _, name := range sys.deps.get(u, depBefore) {
+			add(DependencyEdge{From: name, To: u.Name(), Type: "After"})
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.To < b.To
+	})
+	return
+}
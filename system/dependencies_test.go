@@ -0,0 +1,80 @@
+package system
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestListDependencies(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	for name, contents := range map[string]string{
+		"a.target":  "[Unit]\nRequires=b.service\nWants=c.target",
+		"b.service": "[Unit]\nWants=e.service\n[Service]\nExecStart=/bin/true",
+		"c.target":  "[Unit]\nWants=a.target d.service",
+		"e.service": "[Service]\nExecStart=/bin/true",
+	} {
+		_, err := sys.Load(name, strings.NewReader(contents))
+		require.NoError(t, err, name)
+	}
+
+	root, err := sys.ListDependencies("a.target", DependencyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, DependencyNode{
+		Name: "a.target", Load: unit.Loaded, Active: root.Active,
+		Deps: []DependencyNode{
+			{Name: "b.service", Load: unit.Loaded, Active: unit.Inactive},
+			{Name: "c.target", Load: unit.Loaded, Active: unit.Active, Deps: []DependencyNode{
+				{Name: "a.target", Load: unit.Loaded, Active: root.Active},
+				{Name: "d.service", Load: unit.NotFound},
+			}},
+		},
+	}, root)
+
+	root, err = sys.ListDependencies("a.target", DependencyOptions{All: true})
+	require.NoError(t, err)
+	if assert.Len(t, root.Deps, 2) {
+		assert.Equal(t, []DependencyNode{
+			{Name: "e.service", Load: unit.Loaded, Active: unit.Inactive},
+		}, root.Deps[0].Deps)
+	}
+
+	root, err = sys.ListDependencies("e.service", DependencyOptions{Reverse: true})
+	require.NoError(t, err)
+	assert.Equal(t, DependencyNode{
+		Name: "e.service", Load: unit.Loaded, Active: unit.Inactive,
+		Deps: []DependencyNode{
+			{Name: "b.service", Load: unit.Loaded, Active: unit.Inactive},
+		},
+	}, root)
+
+	_, err = sys.ListDependencies("missing.service", DependencyOptions{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDependencyGraph(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	for name, contents := range map[string]string{
+		"a.target":  "[Unit]\nRequires=b.service\nWants=c.service\nAfter=b.service",
+		"b.service": "[Unit]\nConflicts=c.service\nBefore=c.service\n[Service]\nExecStart=/bin/true",
+		"c.service": "[Unit]\nAfter=b.service\n[Service]\nExecStart=/bin/true",
+	} {
+		_, err := sys.Load(name, strings.NewReader(contents))
+		require.NoError(t, err, name)
+	}
+
+	assert.Equal(t, []DependencyEdge{
+		{From: "a.target", To: "b.service", Type: "After"},
+		{From: "a.target", To: "b.service", Type: "Requires"},
+		{From: "a.target", To: "c.service", Type: "Wants"},
+		{From: "b.service", To: "c.service", Type: "Conflicts"},
+		{From: "c.service", To: "b.service", Type: "After"},
+	}, sys.DependencyGraph(), "Before= is listed as After= of the other unit once")
+}
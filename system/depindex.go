@@ -0,0 +1,197 @@
+package system
+
+import "sync"
+
+// depType is a type of dependencies indexed by depIndex
+type depType int
+
+const (
+	depRequires depType = iota
+	depWants
+	depConflicts
+	depAfter
+	depBefore
+
+	dep_type_count
+)
+
+// names returns the names of the dependencies of u of type typ as found in its definition
+// and, for Requires= and Wants=, its '.requires' and '.wants' directories
+func (typ depType) names(u *Unit) []string {
+	switch typ {
+	case depRequires:
+		return u.Requires()
+	case depWants:
+		return u.Wants()
+	case depConflicts:
+		return u.Conflicts()
+	case depAfter:
+		return u.After()
+	case depBefore:
+		return u.Before()
+	default:
+		panic("Unknown dependency type")
+	}
+}
+
+// depIndex is the dependency graph of the units held in-memory, it is safe for concurrent use.
+// Forward adjacency maps units to the names of their dependencies, reverse adjacency maps names to the units
+// depending on them. Dependencies of a unit are indexed the first time they are looked up and re-indexed,
+// after the unit is redefined or units get linked into its '.wants' and '.requires' directories,
+// so that building transactions does not query the definitions and the unit paths repeatedly.
+// Each type of dependencies is indexed separately
+type depIndex struct {
+	forward [dep_type_count]map[*Unit][]string
+	reverse [dep_type_count]map[string]map[*Unit]struct{}
+
+	// Units added or forgotten, which are not indexed yet, they are indexed before reverse lookups
+	pending [dep_type_count]map[*Unit]struct{}
+
+	// Incremented whenever units are forgotten, so that dependencies looked up concurrently are not indexed stale
+	version uint64
+
+	mutex sync.RWMutex
+}
+
+func newDepIndex() (idx *depIndex) {
+	idx = &depIndex{}
+	for typ := range idx.pending {
+		idx.pending[typ] = map[*Unit]struct{}{}
+	}
+	idx.reset()
+	return
+}
+
+// reset drops all units indexed, they are re-indexed on demand
+func (idx *depIndex) reset() {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.version++
+	for typ := range idx.forward {
+		for u := range idx.forward[typ] {
+			idx.pending[typ][u] = struct{}{}
+		}
+		idx.forward[typ] = map[*Unit][]string{}
+		idx.reverse[typ] = map[string]map[*Unit]struct{}{}
+	}
+}
+
+// get returns the names of the dependencies of u of type specified, indexing them, if they are not indexed yet
+func (idx *depIndex) get(u *Unit, typ depType) (names []string) {
+	idx.mutex.RLock()
+	names, ok := idx.forward[typ][u]
+	version := idx.version
+	idx.mutex.RUnlock()
+	if ok {
+		return names
+	}
+
+	// Definitions and directories of u are read without holding the lock
+	names = typ.names(u)
+
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	if indexed, ok := idx.forward[typ][u]; ok {
+		// Indexed concurrently
+		return indexed
+	}
+	if idx.version != version {
+		// Forgotten concurrently, the names read might be stale already
+		return names
+	}
+
+	idx.forward[typ][u] = names
+	delete(idx.pending[typ], u)
+	for _, name := range names {
+		if idx.reverse[typ][name] == nil {
+			idx.reverse[typ][name] = map[*Unit]struct{}{}
+		}
+		idx.reverse[typ][name][u] = struct{}{}
+	}
+	return names
+}
+
+// add adds u to the units indexed on demand
+func (idx *depIndex) add(u *Unit) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	for typ := range idx.pending {
+		if _, ok := idx.forward[typ][u]; !ok {
+			idx.pending[typ][u] = struct{}{}
+		}
+	}
+}
+
+// dependents returns the units added, which depend on any of names with the dependency of type specified
+func (idx *depIndex) dependents(typ depType, names ...string) (units []*Unit) {
+	idx.mutex.RLock()
+	pending := make([]*Unit, 0, len(idx.pending[typ]))
+	for u := range idx.pending[typ] {
+		pending = append(pending, u)
+	}
+	idx.mutex.RUnlock()
+
+	for _, u := range pending {
+		idx.get(u, typ)
+	}
+
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	seen := map[*Unit]bool{}
+	for _, name := range names {
+		for u := range idx.reverse[typ][name] {
+			if !seen[u] {
+				seen[u] = true
+				units = append(units, u)
+			}
+		}
+	}
+	return
+}
+
+// forget drops the dependencies of u indexed, they are re-indexed on demand
+func (idx *depIndex) forget(u *Unit) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.version++
+	for typ := range idx.forward {
+		if idx.unindex(u, depType(typ)) {
+			idx.pending[typ][u] = struct{}{}
+		}
+	}
+}
+
+// remove drops u from the index
+func (idx *depIndex) remove(u *Unit) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.version++
+	for typ := range idx.forward {
+		idx.unindex(u, depType(typ))
+		delete(idx.pending[typ], u)
+	}
+}
+
+// unindex drops the dependencies of u of type typ from the forward and reverse adjacency
+// and reports whether they were indexed. idx.mutex must be held
+func (idx *depIndex) unindex(u *Unit, typ depType) bool {
+	names, ok := idx.forward[typ][u]
+	if !ok {
+		return false
+	}
+	delete(idx.forward[typ], u)
+
+	for _, name := range names {
+		delete(idx.reverse[typ][name], u)
+		if len(idx.reverse[typ][name]) == 0 {
+			delete(idx.reverse[typ], name)
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "depindex")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"foo.target": "[Unit]\nRequires=bar.service\nAfter=bar.service",
+		"bar.service": `[Service]
+ExecStart=/bin/true
+
+[Install]
+WantedBy=foo.target`,
+		"baz.service": "[Unit]\nBefore=foo.target\n[Service]\nExecStart=/bin/true",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	foo, err := sys.Get("foo.target")
+	require.NoError(t, err)
+	_, err = sys.Get("bar.service")
+	require.NoError(t, err)
+	baz, err := sys.Get("baz.service")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"bar.service"}, sys.deps.get(foo, depRequires))
+	assert.Empty(t, sys.deps.get(foo, depWants))
+	assert.Equal(t, []*Unit{foo}, sys.deps.dependents(depRequires, "bar.service"))
+	assert.Equal(t, []*Unit{foo}, sys.deps.dependents(depAfter, unitKeys("bar.service")...))
+	assert.Equal(t, []*Unit{baz}, sys.deps.dependents(depBefore, "foo.target"), "units not looked up yet are indexed")
+
+	require.NoError(t, sys.Enable("bar.service"))
+	assert.Equal(t, []string{"bar.service"}, sys.deps.get(foo, depWants), "enabling re-indexes the units linked into")
+	assert.Equal(t, []*Unit{foo}, sys.deps.dependents(depWants, "bar.service"))
+
+	require.NoError(t, sys.Disable("bar.service"))
+	assert.Empty(t, sys.deps.get(foo, depWants))
+	assert.Empty(t, sys.deps.dependents(depWants, "bar.service"))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "foo.target.wants"), 0755))
+	require.NoError(t, os.Symlink(baz.Path(), filepath.Join(dir, "foo.target.wants", "baz.service")))
+	assert.Empty(t, sys.deps.get(foo, depWants), "links created behind the back of the daemon are not indexed")
+
+	require.NoError(t, sys.ReloadDaemon())
+	assert.Equal(t, []string{"baz.service"}, sys.deps.get(foo, depWants))
+
+	_, err = sys.Load("foo.target", strings.NewReader("[Unit]\nRequires=baz.service"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"baz.service"}, sys.deps.get(foo, depRequires), "redefining re-indexes the unit")
+	assert.Empty(t, sys.deps.dependents(depRequires, "bar.service"))
+	assert.Equal(t, []*Unit{foo}, sys.deps.dependents(depRequires, "baz.service"))
+
+	sys.deps.remove(foo)
+	assert.Empty(t, sys.deps.dependents(depRequires, "baz.service"), "removed units are not indexed again")
+}
@@ -0,0 +1,169 @@
+package system
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+
+	"systemgo/unit/mount"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Paths to the files configuring the hostname and holding the machine ID
+const (
+	HOSTNAME_PATH   = "/etc/hostname"
+	MACHINE_ID_PATH = "/etc/machine-id"
+)
+
+// Path the machine ID gets written to, if MACHINE_ID_PATH is not writable
+const RUNTIME_MACHINE_ID_PATH = "/run/machine-id"
+
+// apiFileSystem is a file system mounted on early boot
+type apiFileSystem struct {
+	source, target, fstype string
+	flags                  uintptr
+	data                   string
+}
+
+// API file systems in the order of mounting
+var apiFileSystems = []apiFileSystem{
+	{"proc", "/proc", "proc", syscall.MS_NOSUID | syscall.MS_NOEXEC | syscall.MS_NODEV, ""},
+	{"sysfs", "/sys", "sysfs", syscall.MS_NOSUID | syscall.MS_NOEXEC | syscall.MS_NODEV, ""},
+	{"devtmpfs", "/dev", "devtmpfs", syscall.MS_NOSUID | syscall.MS_STRICTATIME, "mode=755"},
+	{"devpts", "/dev/pts", "devpts", syscall.MS_NOSUID | syscall.MS_NOEXEC, "mode=620,gid=5"},
+	{"tmpfs", "/dev/shm", "tmpfs", syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_STRICTATIME, "mode=1777"},
+	{"tmpfs", "/run", "tmpfs", syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_STRICTATIME, "mode=755"},
+	{"cgroup2", "/sys/fs/cgroup", "cgroup2", syscall.MS_NOSUID | syscall.MS_NOEXEC | syscall.MS_NODEV, ""},
+}
+
+// EarlyBoot prepares the system for running as init: mounts the API file systems, sets the hostname
+// as found in HOSTNAME_PATH and initializes the machine ID. Steps failing get logged and do not prevent
+// the following ones from being performed, the first error encountered is returned
+func (sys *Daemon) EarlyBoot() (err error) {
+	log.Debugf("sys.EarlyBoot")
+
+	fail := func(msg string, ferr error) {
+		log.Errorf("%s: %s", msg, ferr)
+		if err == nil {
+			err = ferr
+		}
+	}
+
+	for _, fs := range apiFileSystems {
+		if ferr := mountAPIFileSystem(fs); ferr != nil {
+			fail("Error mounting "+fs.target, ferr)
+		}
+	}
+
+	if name, ferr := readHostname(HOSTNAME_PATH); ferr != nil {
+		if !os.IsNotExist(ferr) {
+			fail("Error reading hostname", ferr)
+		}
+	} else if name != "" {
+		if ferr = syscall.Sethostname([]byte(name)); ferr != nil {
+			fail("Error setting hostname", ferr)
+		} else {
+			log.Infof("Hostname set to %s", name)
+		}
+	}
+
+	if id, ferr := setupMachineID(MACHINE_ID_PATH, RUNTIME_MACHINE_ID_PATH); ferr != nil {
+		fail("Error initializing machine ID", ferr)
+	} else {
+		log.Infof("Machine ID: %s", id)
+	}
+	return
+}
+
+// mountAPIFileSystem mounts fs, unless its target is already a mount point
+func mountAPIFileSystem(fs apiFileSystem) (err error) {
+	if mount.IsMounted(fs.target) {
+		return nil
+	}
+
+	if err = os.MkdirAll(fs.target, 0755); err != nil {
+		return
+	}
+	return syscall.Mount(fs.source, fs.target, fs.fstype, fs.flags, fs.data)
+}
+
+// readHostname returns the hostname configured in the file found at path.
+// Empty lines and comments are skipped
+func readHostname(path string) (name string, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && line[0] != '#' {
+			return line, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// isMachineID returns whether id is a valid machine ID - 32 lowercase hexadecimal characters
+func isMachineID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// newMachineID returns a new random machine ID
+func newMachineID() (id string, err error) {
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+
+	// Format as a version 4 UUID, like Systemd does
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return hex.EncodeToString(b), nil
+}
+
+// setupMachineID returns the machine ID found at path. If none is found, a new one is generated
+// and written to path. If path is not writable, the ID is written to runtime and bind-mounted over path
+func setupMachineID(path, runtime string) (id string, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(path); err == nil {
+		if id = strings.TrimSpace(string(b)); isMachineID(id) {
+			return id, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if id, err = newMachineID(); err != nil {
+		return "", err
+	}
+
+	if err = ioutil.WriteFile(path, []byte(id+"\n"), 0444); err == nil {
+		return id, nil
+	}
+
+	// Most likely, the root file system is read-only
+	if err = ioutil.WriteFile(runtime, []byte(id+"\n"), 0444); err != nil {
+		return "", err
+	}
+	if err = syscall.Mount(runtime, path, "", syscall.MS_BIND, ""); err != nil {
+		return "", fmt.Errorf("bind-mounting %s: %s", runtime, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,50 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHostname(t *testing.T) {
+	dir, err := ioutil.TempDir("", "earlyboot-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hostname")
+	require.NoError(t, ioutil.WriteFile(path, []byte("# comment\n\n  foo  \nbar\n"), 0644))
+
+	name, err := readHostname(path)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", name)
+
+	_, err = readHostname(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSetupMachineID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "earlyboot-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "machine-id")
+
+	id, err := setupMachineID(path, filepath.Join(dir, "runtime-machine-id"))
+	require.NoError(t, err)
+	assert.True(t, isMachineID(id), id)
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, id+"\n", string(b))
+
+	again, err := setupMachineID(path, filepath.Join(dir, "runtime-machine-id"))
+	require.NoError(t, err)
+	assert.Equal(t, id, again, "existing ID is kept")
+
+	assert.False(t, isMachineID("uninitialized"))
+	assert.False(t, isMachineID("0123456789ABCDEF0123456789ABCDEF"))
+}
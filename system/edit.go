@@ -0,0 +1,104 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Name of the drop-in written by Edit
+const OVERRIDE_DROPIN = "override" + DROPIN_SUFFIX
+
+// editPath returns the path of the file written by Edit for the unit with name specified
+func (sys *Daemon) editPath(name string, full bool) (path string, err error) {
+	if !Supported(name) {
+		return "", ErrUnknownType
+	}
+
+	var dir string
+	if dir, err = sys.configDir(); err != nil {
+		return
+	}
+
+	if full {
+		return filepath.Join(dir, name), nil
+	}
+	return filepath.Join(dir, name+".d", OVERRIDE_DROPIN), nil
+}
+
+// EditContents returns the contents of the file written by Edit for the unit with name specified.
+// If the file does not exist yet, the definition of the unit is returned, if full is true, nothing otherwise
+func (sys *Daemon) EditContents(name string, full bool) (b []byte, err error) {
+	log.WithField("name", name).Debugf("sys.EditContents")
+
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return
+	}
+
+	var path string
+	if path, err = sys.editPath(name, full); err != nil {
+		return
+	}
+
+	if b, err = ioutil.ReadFile(path); !os.IsNotExist(err) {
+		return
+	}
+
+	if !full {
+		return nil, nil
+	}
+	return ioutil.ReadFile(u.Path())
+}
+
+// Edit writes contents to the override drop-in of the unit with name specified located in the first
+// of the unit paths or, if full is true, replaces the definition of the unit there.
+// The file is replaced atomically and the definition of the unit is reloaded, if it has been loaded
+func (sys *Daemon) Edit(name string, contents []byte, full bool) (err error) {
+	log.WithField("name", name).Debugf("sys.Edit")
+
+	var path string
+	if path, err = sys.editPath(name, full); err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	if err = writeFileAtomic(path, contents, 0644); err != nil {
+		return
+	}
+
+	return sys.reread(name)
+}
+
+// writeFileAtomic writes b to the file at path by renaming a temporary file written in the same directory,
+// so that the file is never observed partially written
+func writeFileAtomic(path string, b []byte, perm os.FileMode) (err error) {
+	var f *os.File
+	if f, err = ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"."); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+
+	if err = os.Chmod(f.Name(), perm); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), path)
+}
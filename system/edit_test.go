@@ -0,0 +1,63 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdit(t *testing.T) {
+	admin, err := ioutil.TempDir("", "edit-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "edit-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	definition := []byte("[Unit]\nDescription=Vendor\n[Service]\nExecStart=/bin/true")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "foo.service"), definition, 0644))
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err)
+	assert.Equal(t, "Vendor", u.Description())
+
+	b, err := sys.EditContents("foo.service", false)
+	require.NoError(t, err)
+	assert.Empty(t, b, "override contents")
+
+	b, err = sys.EditContents("foo.service", true)
+	require.NoError(t, err)
+	assert.Equal(t, definition, b, "full contents")
+
+	override := []byte("[Unit]\nDescription=Override")
+	require.NoError(t, sys.Edit("foo.service", override, false))
+	assert.Equal(t, "Override", u.Description())
+
+	b, err = ioutil.ReadFile(filepath.Join(admin, "foo.service.d", OVERRIDE_DROPIN))
+	require.NoError(t, err)
+	assert.Equal(t, override, b)
+
+	b, err = sys.EditContents("foo.service", false)
+	require.NoError(t, err)
+	assert.Equal(t, override, b, "override contents")
+
+	require.NoError(t, sys.Edit("foo.service", []byte("[Unit]\nDescription=Full\n[Service]\nExecStart=/bin/true"), true))
+	assert.Equal(t, filepath.Join(admin, "foo.service"), u.Path())
+	assert.Equal(t, "Override", u.Description(), "drop-in applies to the full replacement")
+
+	infos, err := ioutil.ReadDir(admin)
+	require.NoError(t, err)
+	assert.Len(t, infos, 2, "temporary files left")
+
+	assert.ErrorIs(t, sys.Edit("foo.bar", nil, false), ErrUnknownType)
+	_, err = sys.EditContents("missing.service", false)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
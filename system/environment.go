@@ -0,0 +1,153 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Environment block system managers start with
+var DEFAULT_ENVIRONMENT = []string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"}
+
+// parseAssignment splits a 'NAME=VALUE' assignment
+func parseAssignment(s string) (name, value string, err error) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return "", "", fmt.Errorf("%q is not a valid assignment", s)
+	}
+
+	name, value = s[:i], s[i+1:]
+	if !isEnvName(name) {
+		return "", "", fmt.Errorf("%q is not a valid environment variable name", name)
+	}
+	return
+}
+
+// isEnvName returns whether name is a valid environment variable name
+func isEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+
+	for _, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SetEnvironment sets the variables specified by 'NAME=VALUE' assignments in the
+// manager environment block, which is passed to all processes spawned by sys
+func (sys *Daemon) SetEnvironment(assignments ...string) (err error) {
+	log.WithField("assignments", assignments).Debugf("sys.SetEnvironment")
+
+	env := make(map[string]string, len(assignments))
+	for _, s := range assignments {
+		name, value, err := parseAssignment(s)
+		if err != nil {
+			return err
+		}
+		env[name] = value
+	}
+
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	for name, value := range env {
+		sys.environment[name] = value
+	}
+	return nil
+}
+
+// UnsetEnvironment removes the variables specified by names from the manager environment block.
+// If a 'NAME=VALUE' assignment is specified, the variable only gets removed if it is set to VALUE
+func (sys *Daemon) UnsetEnvironment(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.UnsetEnvironment")
+
+	for _, name := range names {
+		if strings.ContainsRune(name, '=') {
+			if _, _, err = parseAssignment(name); err != nil {
+				return
+			}
+		} else if !isEnvName(name) {
+			return fmt.Errorf("%q is not a valid environment variable name", name)
+		}
+	}
+
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	for _, name := range names {
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			if v, ok := sys.environment[name[:i]]; !ok || v != name[i+1:] {
+				continue
+			}
+			name = name[:i]
+		}
+		delete(sys.environment, name)
+	}
+	return nil
+}
+
+// ImportEnvironment copies the variables specified by names from the environment of the
+// calling process to the manager environment block. All the variables get imported, if none are specified
+func (sys *Daemon) ImportEnvironment(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.ImportEnvironment")
+
+	if len(names) == 0 {
+		return sys.SetEnvironment(os.Environ()...)
+	}
+
+	assignments := make([]string, 0, len(names))
+	for _, name := range names {
+		if !isEnvName(name) {
+			return fmt.Errorf("%q is not a valid environment variable name", name)
+		}
+
+		if value, ok := os.LookupEnv(name); ok {
+			assignments = append(assignments, name+"="+value)
+		} else {
+			log.WithField("name", name).Warn("Variable is not set, not importing")
+		}
+	}
+	return sys.SetEnvironment(assignments...)
+}
+
+// ShowEnvironment returns the manager environment block as 'NAME=VALUE' assignments sorted by name
+func (sys *Daemon) ShowEnvironment() (env []string) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	env = make([]string, 0, len(sys.environment))
+	for name, value := range sys.environment {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return
+}
+
+// setEnvironment passes the manager environment block to u, if it spawns processes
+func (sys *Daemon) setEnvironment(u *Unit) {
+	if setter, ok := u.Interface.(unit.EnvironmentSetter); ok {
+		setter.SetEnvironment(sys.ShowEnvironment())
+	}
+}
+
+// environmentMap returns a map of variables specified by 'NAME=VALUE' assignments, invalid ones are skipped
+func environmentMap(assignments []string) (env map[string]string) {
+	env = make(map[string]string, len(assignments))
+	for _, s := range assignments {
+		if name, value, err := parseAssignment(s); err == nil {
+			env[name] = value
+		}
+	}
+	return
+}
@@ -0,0 +1,32 @@
+package system
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironment(t *testing.T) {
+	sys := New()
+	assert.Equal(t, DEFAULT_ENVIRONMENT, sys.ShowEnvironment())
+
+	require.NoError(t, sys.SetEnvironment("FOO=bar", "BAZ=a=b"))
+	assert.Equal(t, []string{"BAZ=a=b", "FOO=bar", DEFAULT_ENVIRONMENT[0]}, sys.ShowEnvironment())
+
+	assert.Error(t, sys.SetEnvironment("FOO"), "SetEnvironment without value")
+	assert.Error(t, sys.SetEnvironment("1FOO=bar"), "SetEnvironment with invalid name")
+
+	require.NoError(t, sys.UnsetEnvironment("FOO=wrong", "PATH"))
+	assert.Equal(t, []string{"BAZ=a=b", "FOO=bar"}, sys.ShowEnvironment())
+
+	require.NoError(t, sys.UnsetEnvironment("FOO=bar", "BAZ"))
+	assert.Empty(t, sys.ShowEnvironment())
+
+	require.NoError(t, os.Setenv("SYSTEMGO_TEST_IMPORT", "value"))
+	defer os.Unsetenv("SYSTEMGO_TEST_IMPORT")
+
+	require.NoError(t, sys.ImportEnvironment("SYSTEMGO_TEST_IMPORT", "SYSTEMGO_TEST_UNSET"))
+	assert.Equal(t, []string{"SYSTEMGO_TEST_IMPORT=value"}, sys.ShowEnvironment())
+}
@@ -1,6 +1,9 @@
 package system
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var ErrIsDir = errors.New("Is a directory")
 var ErrNotDir = errors.New("Is not a directory")
@@ -14,3 +17,80 @@ var ErrNotActive = errors.New("Unit is not active")
 var ErrExists = errors.New("Unit already exists")
 var ErrNotImplemented = errors.New("Not implemented yet")
 var ErrUnmergeable = errors.New("Unmergeable job types")
+var ErrMasked = errors.New("Unit is masked")
+var ErrTimeout = errors.New("Operation timed out")
+var ErrStartLimit = errors.New("Start request repeated too quickly")
+var ErrIrreversible = errors.New("Unit has an irreversible job running")
+var ErrNotScheduled = errors.New("No shutdown scheduled")
+var ErrFollowReverse = errors.New("Logs can not be followed in reverse")
+var ErrBootNotFinished = errors.New("Bootup is not yet finished")
+var ErrOrderingCycle = errors.New("Ordering cycle found")
+
+// LoadError is returned, when the unit Name could not be loaded.
+// Err is ErrNotFound, if no definition exists in the unit paths, ErrUnknownType,
+// if the type of the unit is not supported, unit.ParseError or unit.MultiError,
+// if the definition at Path is invalid, or the error reading the definition otherwise
+type LoadError struct {
+	Name string
+	Path string
+	Err  error
+}
+
+func (err *LoadError) Error() string {
+	if err.Path != "" && err.Path != err.Name {
+		return fmt.Sprintf("%s (%s): %s", err.Name, err.Path, err.Err)
+	}
+	return fmt.Sprintf("%s: %s", err.Name, err.Err)
+}
+
+func (err *LoadError) Unwrap() error {
+	return err.Err
+}
+
+// UnitError is returned, when operation Op could not be performed on the unit Name
+type UnitError struct {
+	Name string
+	Op   string
+	Err  error
+}
+
+func (err *UnitError) Error() string {
+	return fmt.Sprintf("Failed to %s %s: %s", err.Op, err.Name, err.Err)
+}
+
+func (err *UnitError) Unwrap() error {
+	return err.Err
+}
+
+// DependencyError is the error of a job of the unit Name, which failed,
+// because Job of the required unit Dep did not succeed.
+// Err is ErrDepConflict, if Dep failed to stop, ErrDepFail otherwise
+type DependencyError struct {
+	Name string
+	Dep  string
+	Job  string
+	Err  error
+}
+
+func (err *DependencyError) Error() string {
+	return fmt.Sprintf("%s job of %s failed: %s", err.Job, err.Dep, err.Err)
+}
+
+func (err *DependencyError) Unwrap() error {
+	return err.Err
+}
+
+// VerifyError is a problem with the unit Name found by Verify.
+// Err is a unit.ParseError naming the option, if the problem is with a specific option
+type VerifyError struct {
+	Name string
+	Err  error
+}
+
+func (err *VerifyError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Name, err.Err)
+}
+
+func (err *VerifyError) Unwrap() error {
+	return err.Err
+}
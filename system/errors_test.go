@@ -0,0 +1,53 @@
+package system
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errors-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.service"), []byte("[Service]\nType=invalid"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "dir.service"), 0755))
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	var lerr *LoadError
+
+	_, err = sys.Get("missing.service")
+	if assert.True(t, errors.As(err, &lerr), "missing.service") {
+		assert.Equal(t, "missing.service", lerr.Name)
+		assert.Empty(t, lerr.Path)
+	}
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = sys.Get("dir.service")
+	if assert.True(t, errors.As(err, &lerr), "dir.service") {
+		assert.Equal(t, filepath.Join(dir, "dir.service"), lerr.Path)
+	}
+	assert.ErrorIs(t, err, ErrIsDir)
+
+	_, err = sys.Get("invalid.service")
+	if assert.True(t, errors.As(err, &lerr), "invalid.service") {
+		assert.Equal(t, filepath.Join(dir, "invalid.service"), lerr.Path)
+		assert.NotErrorIs(t, err, ErrNotFound)
+	}
+
+	_, err = sys.Get("foo.bar")
+	assert.ErrorIs(t, err, ErrUnknownType)
+
+	err = sys.Start("missing.service")
+	if assert.True(t, errors.As(err, &lerr), "sys.Start") {
+		assert.Equal(t, "missing.service", lerr.Name)
+	}
+}
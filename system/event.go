@@ -0,0 +1,184 @@
+package system
+
+import (
+	"fmt"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Maximum number of events buffered per subscriber
+const EVENT_BUFFER_SIZE = 64
+
+// Type of an event
+type EventType int
+
+//go:generate stringer -type=EventType event.go
+const (
+	JobQueued EventType = iota
+	JobFinished
+	UnitStateChanged
+	ManagerStateChanged
+)
+
+// Event describes a change of job or unit state
+type Event struct {
+	Type EventType `json:"Type"`
+
+	// Name of the unit the event is about(empty for ManagerStateChanged)
+	Unit string `json:"Unit,omitempty"`
+
+	// Type of the job(JobQueued and JobFinished only)
+	Job string `json:"Job,omitempty"`
+
+	// Error the job failed with(JobFinished only)
+	Err string `json:"Err,omitempty"`
+
+	// Activation state transition(UnitStateChanged only)
+	From unit.Activation `json:"From"`
+	To   unit.Activation `json:"To"`
+
+	// State the manager entered(ManagerStateChanged only)
+	State State `json:"State,omitempty"`
+
+	// ID of the boot the event occurred during
+	Boot string `json:"Boot,omitempty"`
+
+	Time time.Time `json:"Time"`
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case JobQueued:
+		return fmt.Sprintf("%s: %s job queued", e.Unit, e.Job)
+	case JobFinished:
+		if e.Err != "" {
+			return fmt.Sprintf("%s: %s job failed: %s", e.Unit, e.Job, e.Err)
+		}
+		return fmt.Sprintf("%s: %s job finished", e.Unit, e.Job)
+	case UnitStateChanged:
+		return fmt.Sprintf("%s: %s -> %s", e.Unit, e.From, e.To)
+	case ManagerStateChanged:
+		return fmt.Sprintf("manager: %s", e.State)
+	}
+	return fmt.Sprint(e.Type)
+}
+
+// Subscribe returns a channel, on which events emitted by sys get delivered.
+// Events are dropped, if the subscriber does not keep up with them.
+func (sys *Daemon) Subscribe() <-chan Event {
+	sys.eventMutex.Lock()
+	defer sys.eventMutex.Unlock()
+
+	ch := make(chan Event, EVENT_BUFFER_SIZE)
+	sys.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe stops delivery of events to ch and closes it
+func (sys *Daemon) Unsubscribe(ch <-chan Event) {
+	sys.eventMutex.Lock()
+	defer sys.eventMutex.Unlock()
+
+	for sub := range sys.subscribers {
+		if sub == ch {
+			delete(sys.subscribers, sub)
+			close(sub)
+			return
+		}
+	}
+}
+
+func (sys *Daemon) emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = sys.clock.Now()
+	}
+	if e.Boot == "" {
+		e.Boot = sys.BootID()
+	}
+
+	sys.eventMutex.Lock()
+	defer sys.eventMutex.Unlock()
+
+	for sub := range sys.subscribers {
+		select {
+		case sub <- e:
+		default:
+			log.WithField("event", e.Type).Debug("subscriber is full, dropping event")
+		}
+	}
+}
+
+func (j *job) emit(typ EventType) {
+	if j.unit == nil || j.unit.System == nil {
+		return
+	}
+
+	e := Event{
+		Type: typ,
+		Unit: j.unit.Name(),
+		Job:  fmt.Sprint(j.typ),
+	}
+	if err := j.Err(); typ == JobFinished && err != nil {
+		e.Err = err.Error()
+	}
+	j.unit.System.emit(e)
+}
+
+// transition emits a UnitStateChanged event, notifies hooks of u and records the transition
+// in the history of u, if activation state of u differs from prev and the state has not been recorded
+// as reached already. The transition is caused by reason and triggered by j, if not nil
+func (u *Unit) transition(prev unit.Activation, j *job, reason string) (cur unit.Activation) {
+	if cur = u.Active(); cur == prev {
+		return
+	}
+
+	t := Transition{
+		From:   prev,
+		To:     cur,
+		Reason: reason,
+	}
+	if j != nil {
+		t.Job = fmt.Sprint(j.typ)
+	}
+	if u.System != nil {
+		t.Time = u.System.clock.Now()
+	} else {
+		t.Time = time.Now()
+	}
+	if !u.recordChange(t) {
+		// Already observed by a concurrent transition
+		return
+	}
+
+	u.notify(cur)
+	if u.System == nil {
+		return
+	}
+
+	u.System.emit(Event{
+		Type: UnitStateChanged,
+		Unit: u.Name(),
+		From: prev,
+		To:   cur,
+	})
+	u.System.onTransition(u, prev, cur)
+	return
+}
+
+// stateChanged records the transition of u, which changed its activation state on its own for reason,
+// e.g. when its main process exited. Jobs running for u record the transitions during them themselves
+func (u *Unit) stateChanged(reason string) {
+	if u.runningJob() != nil {
+		return
+	}
+
+	// The state is only reported to have changed by units, which were active
+	prev, ok := u.lastState()
+	if !ok {
+		prev = unit.Active
+	}
+	u.transition(prev, nil, reason)
+}
@@ -0,0 +1,135 @@
+package system
+
+import (
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestSubscribe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+
+	m := newMock(ctrl)
+	empty(m, "wants", "conflicts", "requires", "after", "before")
+
+	mutex := sync.Mutex{}
+	active := unit.Inactive
+	m.MockInterface.EXPECT().Active().DoAndReturn(func() unit.Activation {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return active
+	}).AnyTimes()
+	m.MockStarter.EXPECT().Start().DoAndReturn(func() error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		active = unit.Active
+		return nil
+	}).Times(1)
+
+	u, err := sys.Supervise("foo", m)
+	require.NoError(t, err)
+	u.load = unit.Loaded
+
+	events := sys.Subscribe()
+	defer sys.Unsubscribe(events)
+
+	require.NoError(t, sys.Start("foo"))
+
+	expected := []Event{
+		{Type: JobQueued, Unit: "foo"},
+		{Type: UnitStateChanged, Unit: "foo", From: unit.Inactive, To: unit.Activating},
+		{Type: JobFinished, Unit: "foo"},
+		{Type: UnitStateChanged, Unit: "foo", From: unit.Activating, To: unit.Active},
+	}
+
+	for _, exp := range expected {
+		select {
+		case e := <-events:
+			assert.Equal(t, exp.Type, e.Type, "Type")
+			assert.Equal(t, exp.Unit, e.Unit, "Unit")
+			assert.Equal(t, exp.From, e.From, "From")
+			assert.Equal(t, exp.To, e.To, "To")
+			assert.False(t, e.Time.IsZero(), "Time")
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %v event", exp.Type)
+		}
+	}
+
+	sys.Unsubscribe(events)
+	_, ok := <-events
+	assert.False(t, ok, "channel closed")
+}
+
+func TestUnitCrashed(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/sleep 60"))
+	require.NoError(t, err)
+	require.NoError(t, sys.Start("foo.service"))
+
+	events := sys.Subscribe()
+	defer sys.Unsubscribe(events)
+
+	// The main process is killed outside of any job
+	pid := u.Interface.(unit.MainPIDer).MainPID()
+	require.NotZero(t, pid)
+	require.NoError(t, syscall.Kill(pid, syscall.SIGKILL))
+
+	select {
+	case e := <-events:
+		assert.Equal(t, UnitStateChanged, e.Type, "Type")
+		assert.Equal(t, "foo.service", e.Unit, "Unit")
+		assert.Equal(t, unit.Active, e.From, "From")
+		assert.Equal(t, unit.Failed, e.To, "To")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for UnitStateChanged event")
+	}
+
+	transitions, err := sys.Transitions("foo.service")
+	require.NoError(t, err)
+	if assert.NotEmpty(t, transitions) {
+		last := transitions[len(transitions)-1]
+		assert.Equal(t, unit.Failed, last.To)
+		assert.Equal(t, "main process exited: signal: killed", last.Reason)
+		assert.Empty(t, last.Job)
+	}
+}
+
+func TestManagerStateChanged(t *testing.T) {
+	sys := New()
+
+	events := sys.Subscribe()
+	defer sys.Unsubscribe(events)
+
+	sys.setState(Starting)
+	sys.setState(Starting)
+	sys.setState(Running)
+
+	for _, st := range []State{Starting, Running} {
+		select {
+		case e := <-events:
+			assert.Equal(t, ManagerStateChanged, e.Type)
+			assert.Equal(t, st, e.State)
+			assert.Equal(t, "manager: "+st.String(), e.String())
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %s", st)
+		}
+	}
+
+	select {
+	case e := <-events:
+		t.Errorf("Unexpected event: %s", e)
+	default:
+	}
+}
@@ -0,0 +1,104 @@
+package system
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Identifier of the entries logged by the manager itself
+const MANAGER_IDENTIFIER = "systemgo"
+
+// WriteExport writes e to w in the Journal Export Format, as produced by journalctl -o export,
+// so that it can be ingested by collectors of the journal, e.g. systemd-journal-remote.
+// Additional fields of e are written with their names converted to valid journal field names
+func WriteExport(w io.Writer, e LogEntry) (err error) {
+	var buf bytes.Buffer
+
+	identifier := strings.TrimSuffix(e.Identifier(), filepath.Ext(e.Unit))
+
+	writeExportField(&buf, "__REALTIME_TIMESTAMP", strconv.FormatInt(e.Time.UnixNano()/1000, 10))
+	writeExportField(&buf, "__MONOTONIC_TIMESTAMP", strconv.FormatInt(int64(e.Monotonic)/1000, 10))
+	writeExportField(&buf, "PRIORITY", strconv.Itoa(e.Priority))
+	writeExportField(&buf, "SYSLOG_IDENTIFIER", identifier)
+	switch {
+	case e.Kernel:
+		writeExportField(&buf, "_TRANSPORT", "kernel")
+	case e.Audit:
+		writeExportField(&buf, "_TRANSPORT", "audit")
+	}
+	if e.Boot != "" {
+		writeExportField(&buf, "_BOOT_ID", e.Boot)
+	}
+	if e.Unit != "" {
+		writeExportField(&buf, "_SYSTEMD_UNIT", e.Unit)
+	}
+	if e.PID != 0 {
+		writeExportField(&buf, "_PID", strconv.Itoa(e.PID))
+	}
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if name := exportFieldName(k); name != "" {
+			writeExportField(&buf, name, e.Fields[k])
+		}
+	}
+	writeExportField(&buf, "MESSAGE", e.Message)
+
+	// Entries are separated by an empty line
+	buf.WriteByte('\n')
+
+	_, err = w.Write(buf.Bytes())
+	return
+}
+
+// writeExportField writes the field name set to value to buf. Values, which are not printable text
+// on a single line, are written in the binary form prefixed by their length
+func writeExportField(buf *bytes.Buffer, name, value string) {
+	if !strings.ContainsAny(value, "\n") && isPrintable(value) {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// isPrintable returns whether s contains no control characters, except tabs
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if r < ' ' && r != '\t' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// exportFieldName returns name converted to a valid journal field name, which consists of uppercase
+// letters, digits and underscores and does not start with an underscore or a digit. Empty string is
+// returned, if name can not be converted
+func exportFieldName(name string) string {
+	b := []byte(strings.ToUpper(name))
+	for i, c := range b {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+
+	s := strings.TrimLeft(string(b), "_0123456789")
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
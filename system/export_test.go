@@ -0,0 +1,57 @@
+package system
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteExport(t *testing.T) {
+	var buf bytes.Buffer
+
+	require.NoError(t, WriteExport(&buf, LogEntry{
+		Unit:      "foo.service",
+		Time:      time.Unix(1760000000, 123456789),
+		Monotonic: 5 * time.Second,
+		PID:       42,
+		Priority:  LOG_ERR,
+		Message:   "Main process exited",
+		Fields:    map[string]string{"exit-code": "1", "_trusted": "no", "9": "dropped"},
+	}))
+	require.NoError(t, WriteExport(&buf, LogEntry{
+		Time:    time.Unix(1760000001, 0),
+		Message: "two\nlines",
+	}))
+
+	assert.Equal(t, "__REALTIME_TIMESTAMP=1760000000123456\n"+
+		"__MONOTONIC_TIMESTAMP=5000000\n"+
+		"PRIORITY=3\n"+
+		"SYSLOG_IDENTIFIER=foo\n"+
+		"_SYSTEMD_UNIT=foo.service\n"+
+		"_PID=42\n"+
+		"TRUSTED=no\n"+
+		"EXIT_CODE=1\n"+
+		"MESSAGE=Main process exited\n"+
+		"\n"+
+		"__REALTIME_TIMESTAMP=1760000001000000\n"+
+		"__MONOTONIC_TIMESTAMP=0\n"+
+		"PRIORITY=0\n"+
+		"SYSLOG_IDENTIFIER=systemgo\n"+
+		"MESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\n"+
+		"\n", buf.String())
+}
+
+func TestExportFieldName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"code":      "CODE",
+		"exit-code": "EXIT_CODE",
+		"_PID":      "PID",
+		"1st":       "ST",
+		"é":         "",
+	} {
+		assert.Equal(t, expected, exportFieldName(name), name)
+	}
+}
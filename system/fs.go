@@ -0,0 +1,148 @@
+package system
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fileSystem is the file system unit definitions and their drop-ins get read from
+type fileSystem interface {
+	Stat(path string) (os.FileInfo, error)
+	Open(path string) (fs.File, error)
+	ReadFile(path string) ([]byte, error)
+	ReadDir(path string) ([]os.FileInfo, error)
+	Readlink(path string) (string, error)
+}
+
+// osFS is the file system of the operating system
+type osFS struct{}
+
+func (osFS) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (osFS) Open(path string) (fs.File, error) {
+	return os.Open(path)
+}
+
+func (osFS) ReadFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
+
+func (osFS) ReadDir(path string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(path)
+}
+
+func (osFS) Readlink(path string) (string, error) {
+	return os.Readlink(path)
+}
+
+// ioFS adapts an fs.FS, paths are resolved relative to its root
+type ioFS struct {
+	fsys fs.FS
+}
+
+// name returns the name of the file at path in f.fsys
+func (f ioFS) name(path string) string {
+	name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+func (f ioFS) Stat(path string) (os.FileInfo, error) {
+	return fs.Stat(f.fsys, f.name(path))
+}
+
+func (f ioFS) Open(path string) (fs.File, error) {
+	return f.fsys.Open(f.name(path))
+}
+
+func (f ioFS) ReadFile(path string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, f.name(path))
+}
+
+func (f ioFS) ReadDir(path string) (infos []os.FileInfo, err error) {
+	var entries []fs.DirEntry
+	if entries, err = fs.ReadDir(f.fsys, f.name(path)); err != nil {
+		return nil, err
+	}
+
+	infos = make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		var info os.FileInfo
+		if info, err = entry.Info(); err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return
+}
+
+// Readlink always fails, as fs.FS does not expose symlinks
+func (f ioFS) Readlink(path string) (string, error) {
+	return "", &fs.PathError{Op: "readlink", Path: path, Err: ErrNotImplemented}
+}
+
+// findFiles returns paths to files found in dirs of fsys, ordered by filename.
+// match is called for the first file found with each name and returns whether the file
+// is to be returned and whether it masks the files with the same name in later directories
+func findFiles(fsys fileSystem, dirs []string, match func(path string, info os.FileInfo) (ok, masks bool)) (files []string) {
+	found := map[string]string{}
+	for _, dir := range dirs {
+		infos, err := fsys.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, info := range infos {
+			name := info.Name()
+			if _, ok := found[name]; ok {
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			switch ok, masks := match(path, info); {
+			case ok:
+				found[name] = path
+			case masks:
+				found[name] = ""
+			}
+		}
+	}
+
+	names := make([]string, 0, len(found))
+	for name, path := range found {
+		if path != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	files = make([]string, len(names))
+	for i, name := range names {
+		files[i] = found[name]
+	}
+	return
+}
+
+// SetFS sets the file system unit definitions and their drop-ins get loaded from, e.g. an embed.FS
+// or fstest.MapFS. Unit paths and absolute unit names are resolved relative to the root of fsys.
+// Units in fsys can not be masked by symlinks and their changes are not watched for.
+// If fsys is nil, definitions get loaded from the file system of the operating system
+func (sys *Daemon) SetFS(fsys fs.FS) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	if fsys == nil {
+		sys.fsys = osFS{}
+	} else {
+		sys.fsys = ioFS{fsys}
+	}
+	sys.cache = newDefinitionCache()
+}
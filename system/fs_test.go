@@ -0,0 +1,50 @@
+package system
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/systemd/system/foo.service": {
+			Data: []byte("[Unit]\nDescription=Foo\n[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true"),
+		},
+		"etc/systemd/system/foo.service.d/override.conf": {
+			Data: []byte("[Unit]\nDescription=Overridden"),
+		},
+		"lib/systemd/system/bar.service": {
+			Data: []byte("[Unit]\nRequires=foo.service\n[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true"),
+		},
+		"lib/systemd/system/dir.service/foo": {},
+	}
+
+	sys := New()
+	sys.SetFS(fsys)
+	sys.SetPaths("/etc/systemd/system", "/lib/systemd/system")
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err)
+	assert.True(t, u.IsLoaded())
+	assert.Equal(t, "/etc/systemd/system/foo.service", u.Path())
+	assert.Equal(t, "Overridden", u.Description())
+
+	require.NoError(t, sys.Start("bar.service"))
+	assert.True(t, u.IsActive())
+
+	_, err = sys.Get("/lib/systemd/system/bar.service")
+	assert.NoError(t, err, "absolute name")
+
+	_, err = sys.Get("dir.service")
+	assert.ErrorIs(t, err, ErrIsDir)
+
+	_, err = sys.Get("missing.service")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	sys.SetFS(nil)
+	_, err = sys.Get("/lib/systemd/system/missing.service")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
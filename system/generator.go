@@ -0,0 +1,168 @@
+package system
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Default paths to search for generator executables - Daemon uses those, if none are specified
+var DEFAULT_GENERATOR_PATHS = []string{
+	"/run/systemd/system-generators",
+	"/etc/systemd/system-generators",
+	"/usr/local/lib/systemd/system-generators",
+	"/lib/systemd/system-generators",
+	"/lib/systemgo/generators",
+}
+
+// Paths to search for generator executables of user managers
+var DEFAULT_USER_GENERATOR_PATHS = []string{
+	"/run/systemd/user-generators",
+	"/etc/systemd/user-generators",
+	"/usr/local/lib/systemd/user-generators",
+	"/lib/systemd/user-generators",
+}
+
+// Default directory generators write the unit files to, removed and created again on every run.
+// Directories with ".early" and ".late" suffixes are used as well.
+// The output of generators run by systemd, if any, in /run/systemd/generator is left intact
+const DEFAULT_GENERATOR_DIR = "/run/systemgo/generator"
+
+// GeneratorPaths returns paths, which get searched for generator executables by sys
+func (sys *Daemon) GeneratorPaths() (paths []string) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.generatorPaths
+}
+
+// SetGeneratorPaths sets paths, which get searched for generator executables by sys
+func (sys *Daemon) SetGeneratorPaths(paths ...string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.generatorPaths = paths
+}
+
+// SetGeneratorDir sets the directory generators write the unit files to.
+// If dir is empty, RunGenerators does not run generators
+func (sys *Daemon) SetGeneratorDir(dir string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.generatorDir = dir
+}
+
+// generatorOutputs returns the normal, early and late output directories of generators, sys.mutex must be held
+func (sys *Daemon) generatorOutputs() (normal, early, late string) {
+	return sys.generatorDir, sys.generatorDir + ".early", sys.generatorDir + ".late"
+}
+
+// isGenerated returns whether dir is one of the generator output directories, sys.mutex must be held
+func (sys *Daemon) isGenerated(dir string) bool {
+	if sys.generatorDir == "" {
+		return false
+	}
+
+	normal, early, late := sys.generatorOutputs()
+	switch filepath.Clean(dir) {
+	case normal, early, late:
+		return true
+	}
+	return false
+}
+
+// generators returns paths to generator executables found in paths, ordered by filename.
+// A file in a directory coming earlier masks the files with the same name in later ones
+func generators(paths []string) (files []string) {
+	return findFiles(osFS{}, paths, func(path string, info os.FileInfo) (ok, masks bool) {
+		if info.Mode()&os.ModeSymlink != 0 {
+			var err error
+			if info, err = os.Stat(path); err != nil {
+				return false, false
+			}
+		}
+
+		// Symlinks to /dev/null mask generators as well
+		return info.Mode().IsRegular() && info.Mode()&0111 != 0, true
+	})
+}
+
+// RunGenerators runs the generator executables found in generator paths, which write unit files
+// to the output directories passed to them as arguments. The output directories get merged into
+// the unit paths: the early one is searched first, the normal one after the configuration
+// and runtime paths and the late one last.
+// Failing generators get logged, but do not make RunGenerators fail.
+// If no output directory is set, RunGenerators does nothing
+func (sys *Daemon) RunGenerators() (err error) {
+	log.Debugf("sys.RunGenerators")
+
+	sys.mutex.RLock()
+	normal, early, late := sys.generatorOutputs()
+	sys.mutex.RUnlock()
+
+	if normal == "" {
+		// The outputs would be relative to the working directory
+		log.Debugf("No generator output directory set, not running generators")
+		return nil
+	}
+
+	for _, dir := range []string{normal, early, late} {
+		if err = os.RemoveAll(dir); err != nil {
+			return
+		}
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+	}
+
+	scope := "system"
+	if sys.user {
+		scope = "user"
+	}
+
+	for _, path := range generators(sys.GeneratorPaths()) {
+		e := log.WithField("generator", path)
+		e.Debugf("Running generator")
+
+		cmd := exec.Command(path, normal, early, late)
+		cmd.Env = append(os.Environ(), "SYSTEMD_SCOPE="+scope)
+
+		if out, err := cmd.CombinedOutput(); err != nil {
+			e.Errorf("Generator failed: %s\n%s", err, out)
+		}
+	}
+
+	// The output directories were created again, they are watched, once the unit paths are set
+	defer sys.rewatch()
+
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	paths := make([]string, 0, len(sys.paths)+3)
+	paths = append(paths, early)
+
+	inserted := false
+	for i, path := range sys.paths {
+		if sys.isGenerated(path) {
+			continue
+		}
+		paths = append(paths, path)
+
+		// Normal output goes after the configuration and runtime paths
+		if !inserted && (path == sys.runtimePath || i == len(sys.paths)-1) {
+			paths = append(paths, normal)
+			inserted = true
+		}
+	}
+	if !inserted {
+		paths = append(paths, normal)
+	}
+	paths = append(paths, late)
+
+	sys.paths = paths
+	sys.generated = true
+	return nil
+}
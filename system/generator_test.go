@@ -0,0 +1,95 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunGenerators(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "generator-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	admin := filepath.Join(tmp, "admin")
+	vendor := filepath.Join(tmp, "vendor")
+	local := filepath.Join(tmp, "generators-local")
+	global := filepath.Join(tmp, "generators-global")
+	out := filepath.Join(tmp, "generator")
+
+	for _, dir := range []string{admin, vendor, local, global} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+
+	for path, contents := range map[string]string{
+		filepath.Join(global, "10-test"): `#!/bin/sh
+printf '[Service]\nExecStart=/bin/true\n' > "$1/generated.service"
+printf '[Service]\nExecStart=/bin/true\n' > "$3/late.service"
+[ "$SYSTEMD_SCOPE" = system ] || exit 1
+`,
+		filepath.Join(global, "20-masked"):  "#!/bin/sh\ntouch \"$1/masked.service\"",
+		filepath.Join(global, "30-failing"): "#!/bin/sh\nexit 1",
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(global, "40-not-executable"), []byte("#!/bin/sh\ntouch \"$1/ignored.service\""), 0644))
+	require.NoError(t, os.Symlink(MASK_TARGET, filepath.Join(local, "20-masked")))
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+	sys.SetRuntimePath(admin)
+	sys.SetGeneratorPaths(local, global)
+	sys.SetGeneratorDir(out)
+
+	require.NoError(t, sys.RunGenerators())
+	assert.Equal(t, []string{out + ".early", admin, out, vendor, out + ".late"}, sys.Paths())
+
+	// Running again does not duplicate the paths
+	require.NoError(t, sys.RunGenerators())
+	assert.Equal(t, []string{out + ".early", admin, out, vendor, out + ".late"}, sys.Paths())
+
+	for _, name := range []string{"generated.service", "late.service"} {
+		u, err := sys.Get(name)
+		require.NoError(t, err, name)
+		assert.True(t, u.IsLoaded(), name)
+	}
+
+	for _, name := range []string{"masked.service", "ignored.service"} {
+		_, err := sys.Get(name)
+		assert.ErrorIs(t, err, ErrNotFound, name)
+	}
+
+	dir, err := sys.configDir()
+	require.NoError(t, err)
+	assert.Equal(t, admin, dir)
+}
+
+func TestRunGeneratorsNoDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "generator-nodir")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmp))
+	defer os.Chdir(wd)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmp, "10-test"), []byte("#!/bin/sh\ntouch \"$1/generated.service\""), 0755))
+
+	sys := New()
+	sys.SetPaths(tmp)
+	sys.SetGeneratorPaths(tmp)
+	sys.SetGeneratorDir("")
+
+	require.NoError(t, sys.RunGenerators())
+	assert.Equal(t, []string{tmp}, sys.Paths())
+
+	for _, name := range []string{".early", ".late", "generated.service"} {
+		_, err := os.Stat(filepath.Join(tmp, name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+}
@@ -0,0 +1,188 @@
+package system
+
+import (
+	"sync"
+
+	"systemgo/unit"
+)
+
+// Health states of units as reported by HealthState
+const (
+	HEALTH_UNKNOWN   = "unknown"
+	HEALTH_HEALTHY   = "healthy"
+	HEALTH_UNHEALTHY = "unhealthy"
+)
+
+// Restart policies, which restart units found unhealthy, see systemd.service(5)
+var healthRestartPolicies = map[string]bool{
+	"always":      true,
+	"on-failure":  true,
+	"on-abnormal": true,
+	"on-watchdog": true,
+}
+
+// healthWatcher probes the health of an active unit periodically
+type healthWatcher struct {
+	u  *Unit
+	hc unit.HealthCheck
+
+	done chan struct{}
+
+	// Health state of the unit and the number of consecutive failed probes
+	state    string
+	failures int
+
+	mutex sync.Mutex
+}
+
+// watchHealth starts probing the health of u, if u specifies a health check
+func (sys *Daemon) watchHealth(u *Unit) {
+	checker, ok := u.Interface.(unit.HealthChecker)
+	if !ok {
+		return
+	}
+	hc, ok := checker.HealthCheck()
+	if !ok {
+		return
+	}
+
+	w := &healthWatcher{
+		u:     u,
+		hc:    hc,
+		done:  make(chan struct{}),
+		state: HEALTH_UNKNOWN,
+	}
+
+	sys.healthMutex.Lock()
+	if sys.healths == nil {
+		sys.healths = map[*Unit]*healthWatcher{}
+	}
+	prev := sys.healths[u]
+	sys.healths[u] = w
+	sys.healthMutex.Unlock()
+
+	if prev != nil {
+		prev.stop()
+	}
+	go w.run(sys)
+}
+
+// unwatchHealth stops the watcher of u started by watchHealth, if any
+func (sys *Daemon) unwatchHealth(u *Unit) {
+	sys.healthMutex.Lock()
+	w, ok := sys.healths[u]
+	delete(sys.healths, u)
+	sys.healthMutex.Unlock()
+
+	if ok {
+		w.stop()
+	}
+}
+
+// HealthState returns the health state of u: HEALTH_HEALTHY, HEALTH_UNHEALTHY, HEALTH_UNKNOWN
+// before the first probe finished, and empty, if the health of u is not probed
+func (sys *Daemon) HealthState(u *Unit) string {
+	sys.healthMutex.Lock()
+	w, ok := sys.healths[u]
+	sys.healthMutex.Unlock()
+
+	if !ok {
+		return ""
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.state
+}
+
+// run probes the health of the unit every Interval, until the watcher is stopped or the unit is found unhealthy
+func (w *healthWatcher) run(sys *Daemon) {
+	for {
+		timer := sys.clock.NewTimer(w.hc.Interval)
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		err := w.hc.Probe()
+
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		if w.update(err) {
+			w.unhealthy(sys)
+			return
+		}
+	}
+}
+
+// update records the outcome of a probe and returns whether the unit became unhealthy
+func (w *healthWatcher) update(err error) (unhealthy bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err == nil {
+		if w.state == HEALTH_UNHEALTHY || w.failures > 0 {
+			w.u.Log.Println("Health check passed")
+		}
+		w.state, w.failures = HEALTH_HEALTHY, 0
+		return false
+	}
+
+	w.failures++
+	w.u.Log.Warnf("Health check failed(%d/%d): %s", w.failures, w.hc.Retries, err)
+	if w.failures < w.hc.Retries {
+		return false
+	}
+
+	w.state = HEALTH_UNHEALTHY
+	return true
+}
+
+// unhealthy kills the processes of the unit found unhealthy, which puts it into the failed state,
+// and restarts the unit after RestartSec, if its restart policy applies to failed health checks
+func (w *healthWatcher) unhealthy(sys *Daemon) {
+	u := w.u
+	u.Log.Errorf("Unhealthy after %d failed health checks", w.hc.Retries)
+
+	prev := u.Active()
+	if killer, ok := u.Interface.(unit.Killer); ok {
+		if err := killer.Kill(); err != nil {
+			u.Log.Errorf("Error killing: %s", err)
+		}
+	}
+	u.transition(prev, nil, "health check failed")
+
+	r, ok := u.Interface.(unit.Restarter)
+	if !ok || !healthRestartPolicies[r.RestartPolicy()] {
+		return
+	}
+
+	sys.mutex.RLock()
+	delay := sys.defaults.RestartSec
+	sys.mutex.RUnlock()
+
+	timer := sys.clock.NewTimer(delay)
+	select {
+	case <-w.done:
+		timer.Stop()
+		return
+	case <-timer.C():
+	}
+
+	u.Log.Println("Restarting unhealthy unit...")
+	if err := sys.Restart(u.Name()); err != nil {
+		u.Log.Errorf("Error restarting: %s", err)
+	}
+}
+
+// stop stops the watcher
+func (w *healthWatcher) stop() {
+	close(w.done)
+}
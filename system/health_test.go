@@ -0,0 +1,69 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestHealthCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	healthy := filepath.Join(dir, "healthy")
+	require.NoError(t, ioutil.WriteFile(healthy, nil, 0644))
+
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader(`[Service]
+ExecStart=/bin/sleep 1000
+ExecHealthCheck=/bin/test -e `+healthy+`
+HealthCheckIntervalSec=10ms
+HealthCheckRetries=2
+Restart=on-failure`))
+	require.NoError(t, err)
+
+	_, err = sys.Load("bar.service", strings.NewReader(`[Service]
+ExecStart=/bin/sleep 1000
+ExecHealthCheck=/bin/false
+HealthCheckIntervalSec=10ms
+HealthCheckRetries=1`))
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Start("foo.service", "bar.service"))
+	defer sys.Stop("foo.service", "bar.service")
+
+	assert.Eventually(t, func() bool {
+		return sys.HealthState(u) == HEALTH_HEALTHY
+	}, time.Second, 5*time.Millisecond, "foo.service is healthy")
+	assert.Equal(t, HEALTH_HEALTHY, u.Status().Activation.Health)
+
+	bar, err := sys.Get("bar.service")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return bar.Active() == unit.Failed
+	}, time.Second, 5*time.Millisecond, "bar.service is killed, once unhealthy")
+
+	props, err := sys.Properties("bar.service")
+	require.NoError(t, err)
+	assert.Equal(t, HEALTH_UNHEALTHY, props["HealthState"])
+
+	pid := u.Interface.(unit.MainPIDer).MainPID()
+	require.NoError(t, os.Remove(healthy))
+	assert.Eventually(t, func() bool {
+		p := u.Interface.(unit.MainPIDer).MainPID()
+		return p != 0 && p != pid && u.IsActive()
+	}, 2*time.Second, 5*time.Millisecond, "foo.service is restarted, once unhealthy")
+
+	require.NoError(t, sys.Stop("bar.service"))
+	assert.Empty(t, sys.HealthState(bar), "health is not probed, once stopped")
+}
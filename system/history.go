@@ -0,0 +1,86 @@
+package system
+
+import (
+	"fmt"
+	"time"
+
+	"systemgo/unit"
+)
+
+// Number of the most recent activation state transitions kept per unit
+const TRANSITION_HISTORY_SIZE = 64
+
+// Transition is an activation state transition of a unit
+type Transition struct {
+	Time     time.Time
+	From, To unit.Activation
+
+	// What caused the transition, e.g. "start job done"
+	Reason string
+
+	// Type of the job, which triggered the transition, empty if not triggered by a job
+	Job string
+}
+
+func (t Transition) String() string {
+	return fmt.Sprintf("%s -> %s (%s)", t.From, t.To, t.Reason)
+}
+
+// recordTransition appends t to the history of u, dropping the oldest transition,
+// once TRANSITION_HISTORY_SIZE transitions are kept
+func (u *Unit) recordTransition(t Transition) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.appendTransition(t)
+}
+
+// recordChange records t like recordTransition, unless the transition recorded last already led to t.To,
+// i.e. the change has been recorded by another goroutine observing it. Returns whether t was recorded
+func (u *Unit) recordChange(t Transition) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if n := len(u.transitions); n > 0 && u.transitions[n-1].To == t.To {
+		return false
+	}
+	u.appendTransition(t)
+	return true
+}
+
+// lastState returns the activation state u transitioned to last, ok is false if no transitions are recorded
+func (u *Unit) lastState() (st unit.Activation, ok bool) {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	if n := len(u.transitions); n > 0 {
+		return u.transitions[n-1].To, true
+	}
+	return st, false
+}
+
+// appendTransition appends t to the history of u, u.mutex must be held
+func (u *Unit) appendTransition(t Transition) {
+	if len(u.transitions) == TRANSITION_HISTORY_SIZE {
+		u.transitions = append(u.transitions[:0], u.transitions[1:]...)
+	}
+	u.transitions = append(u.transitions, t)
+}
+
+// Transitions returns the most recent activation state transitions of u, oldest first
+func (u *Unit) Transitions() []Transition {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return append([]Transition{}, u.transitions...)
+}
+
+// Transitions returns the most recent activation state transitions of the unit with name specified, oldest first.
+// If error is returned, it is going to be a *LoadError
+func (sys *Daemon) Transitions(name string) (transitions []Transition, err error) {
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return
+	}
+	return u.Transitions(), nil
+}
@@ -0,0 +1,49 @@
+package system
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestTransitions(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nType=oneshot\nExecStart=/bin/false"))
+	require.NoError(t, err)
+
+	assert.Error(t, sys.Start("foo.service"))
+	sys.ResetFailed("foo.service")
+
+	transitions, err := sys.Transitions("foo.service")
+	require.NoError(t, err)
+	if assert.Len(t, transitions, 3) {
+		assert.Equal(t, unit.Inactive, transitions[0].From)
+		assert.Equal(t, unit.Activating, transitions[0].To)
+		assert.Equal(t, "start job started", transitions[0].Reason)
+		assert.Equal(t, "start", transitions[0].Job)
+
+		assert.Equal(t, unit.Activating, transitions[1].From)
+		assert.Equal(t, unit.Failed, transitions[1].To)
+		assert.True(t, strings.HasPrefix(transitions[1].Reason, "start job Failed: "), transitions[1].Reason)
+
+		assert.Equal(t, unit.Failed, transitions[2].From)
+		assert.Equal(t, unit.Inactive, transitions[2].To)
+		assert.Empty(t, transitions[2].Job)
+		assert.False(t, transitions[2].Time.Before(transitions[1].Time))
+	}
+
+	for i := 0; i < TRANSITION_HISTORY_SIZE; i++ {
+		u.recordTransition(Transition{Reason: "flapping"})
+	}
+	transitions = u.Transitions()
+	assert.Len(t, transitions, TRANSITION_HISTORY_SIZE, "history is bounded")
+	assert.Equal(t, "flapping", transitions[0].Reason, "oldest transitions are dropped")
+
+	_, err = sys.Transitions("missing.service")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,44 @@
+package system
+
+import "systemgo/unit"
+
+// Hooks are notified of activation state transitions of a unit supervised with them, see Supervise.
+// Hooks are called synchronously by the goroutine running the job of the unit, hence must not block.
+// Nil hooks are ignored
+type Hooks struct {
+	// Called, when the unit starts activating
+	OnStarting func(u *Unit)
+
+	// Called, when the unit becomes active
+	OnActive func(u *Unit)
+
+	// Called, when the unit fails
+	OnFailed func(u *Unit)
+
+	// Called, when the unit becomes inactive
+	OnStopped func(u *Unit)
+}
+
+// hook returns the hook of h corresponding to activation state st, if any
+func (h Hooks) hook(st unit.Activation) func(u *Unit) {
+	switch st {
+	case unit.Activating:
+		return h.OnStarting
+	case unit.Active:
+		return h.OnActive
+	case unit.Failed:
+		return h.OnFailed
+	case unit.Inactive:
+		return h.OnStopped
+	}
+	return nil
+}
+
+// notify calls hooks of u corresponding to activation state st
+func (u *Unit) notify(st unit.Activation) {
+	for _, h := range u.hooks {
+		if fn := h.hook(st); fn != nil {
+			fn(u)
+		}
+	}
+}
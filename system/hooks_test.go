@@ -0,0 +1,92 @@
+package system
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestSuperviseHooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+
+	mutex := sync.Mutex{}
+	called := []string{}
+	record := func(hook string) func(*Unit) {
+		return func(u *Unit) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			called = append(called, u.Name()+" "+hook)
+		}
+	}
+	hooks := Hooks{
+		OnStarting: record("starting"),
+		OnActive:   record("active"),
+		OnFailed:   record("failed"),
+		OnStopped:  record("stopped"),
+	}
+
+	supervise := func(name string, startErr error) {
+		m := newMock(ctrl)
+		for _, method := range []string{"wants", "conflicts", "requires", "after", "before"} {
+			emptyOne(m, method).AnyTimes()
+		}
+
+		active := unit.Inactive
+		m.MockInterface.EXPECT().Active().DoAndReturn(func() unit.Activation {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return active
+		}).AnyTimes()
+		m.MockStarter.EXPECT().Start().DoAndReturn(func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if active = unit.Active; startErr != nil {
+				active = unit.Failed
+			}
+			return startErr
+		}).AnyTimes()
+		m.MockStopper.EXPECT().Stop().DoAndReturn(func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			active = unit.Inactive
+			return nil
+		}).AnyTimes()
+
+		u, err := sys.Supervise(name, m, hooks)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	expect := func(expected ...string) {
+		assert.Eventually(t, func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return assert.ObjectsAreEqual(expected, called)
+		}, time.Second, 10*time.Millisecond, "hooks called")
+
+		mutex.Lock()
+		called = []string{}
+		mutex.Unlock()
+	}
+
+	supervise("foo", nil)
+	supervise("bar", errors.New("bar"))
+
+	require.NoError(t, sys.Start("foo"))
+	expect("foo starting", "foo active")
+
+	require.NoError(t, sys.Stop("foo"))
+	expect("foo stopped")
+
+	sys.Start("bar")
+	expect("bar starting", "bar failed")
+}
@@ -0,0 +1,204 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// configDir returns the directory symlinks enabling units get created in
+// (first of the unit paths, which is not a generator output directory)
+func (sys *Daemon) configDir() (dir string, err error) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	for _, path := range sys.paths {
+		if !sys.isGenerated(path) {
+			return path, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// aliases returns a slice of unit names as found in Alias of u definition
+func (u *Unit) aliases() []string {
+	if inst, ok := u.Interface.(unit.Installer); ok {
+		return inst.Alias()
+	}
+	return nil
+}
+
+// also returns a slice of unit names as found in Also of u definition
+func (u *Unit) also() []string {
+	if inst, ok := u.Interface.(unit.Installer); ok {
+		return inst.Also()
+	}
+	return nil
+}
+
+// installLinks returns paths to symlinks, which enable u, located in dir
+func (u *Unit) installLinks(dir string) (links []string) {
+	name := filepath.Base(u.Name())
+
+	for _, dep := range u.WantedBy() {
+		links = append(links, filepath.Join(dir, dep+".wants", name))
+	}
+	for _, dep := range u.RequiredBy() {
+		links = append(links, filepath.Join(dir, dep+".requires", name))
+	}
+	for _, alias := range u.aliases() {
+		links = append(links, filepath.Join(dir, alias))
+	}
+	return
+}
+
+// Enable creates symlinks to u definition in '.wants' and '.requires' directories
+// of units found in WantedBy and RequiredBy and symlinks named after aliases of u.
+// Units found in Also get enabled as well
+func (u *Unit) Enable() (err error) {
+	log.WithField("unit", u.Name()).Debugf("u.Enable")
+
+	var dir string
+	if dir, err = u.System.configDir(); err != nil {
+		return
+	}
+	return u.install(dir, true, map[*Unit]bool{})
+}
+
+// Disable removes symlinks(if they exist) created by Enable
+func (u *Unit) Disable() (err error) {
+	log.WithField("unit", u.Name()).Debugf("u.Disable")
+
+	var dir string
+	if dir, err = u.System.configDir(); err != nil {
+		return
+	}
+	return u.install(dir, false, map[*Unit]bool{})
+}
+
+// EnableRuntime is like Enable, but creates the symlinks in the runtime path,
+// so that u is only enabled until reboot
+func (u *Unit) EnableRuntime() (err error) {
+	log.WithField("unit", u.Name()).Debugf("u.EnableRuntime")
+
+	return u.install(u.System.runtimePath, true, map[*Unit]bool{})
+}
+
+// DisableRuntime removes symlinks(if they exist) created by EnableRuntime
+func (u *Unit) DisableRuntime() (err error) {
+	log.WithField("unit", u.Name()).Debugf("u.DisableRuntime")
+
+	return u.install(u.System.runtimePath, false, map[*Unit]bool{})
+}
+
+func (u *Unit) install(dir string, enable bool, visited map[*Unit]bool) (err error) {
+	if visited[u] {
+		return nil
+	}
+	visited[u] = true
+
+	if enable && u.IsMasked() {
+		return ErrMasked
+	}
+
+	for _, link := range u.installLinks(dir) {
+		if enable {
+			err = linkUnit(link, u.Path())
+		} else {
+			err = unlinkUnit(link)
+		}
+		if err != nil {
+			return
+		}
+	}
+	for _, name := range append(u.WantedBy(), u.RequiredBy()...) {
+		if dep, err := u.System.Unit(name); err == nil {
+			u.System.deps.forget(dep)
+		}
+	}
+
+	for _, name := range u.also() {
+		var other *Unit
+		if other, err = u.System.Get(name); err != nil {
+			return
+		}
+
+		if err = other.install(dir, enable, visited); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// linkUnit creates a symlink to target at path, creating the parent directory if needed
+func linkUnit(path, target string) (err error) {
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+
+	if err = os.Symlink(target, path); err != nil && os.IsExist(err) {
+		if existing, lerr := os.Readlink(path); lerr == nil && existing == target {
+			return nil
+		}
+	}
+	return
+}
+
+// unlinkUnit removes the symlink at path, if it exists
+func unlinkUnit(path string) (err error) {
+	var info os.FileInfo
+	if info, err = os.Lstat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		// Never remove actual definitions
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// isSymlink returns whether path is a symlink
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// EnableState returns the enablement state of u
+func (u *Unit) EnableState() unit.Enable {
+	if u.IsMasked() {
+		return unit.EnableMasked
+	}
+
+	if u.System != nil {
+		for _, dir := range u.System.paths {
+			for _, link := range u.installLinks(dir) {
+				if !isSymlink(link) {
+					continue
+				}
+
+				if dir == u.System.runtimePath {
+					return unit.EnabledRuntime
+				}
+				return unit.Enabled
+			}
+		}
+	}
+
+	switch {
+	case u.Path() != "" && isSymlink(u.Path()):
+		return unit.Linked
+	case len(u.WantedBy()) > 0 || len(u.RequiredBy()) > 0 || len(u.aliases()) > 0:
+		return unit.Disabled
+	case len(u.also()) > 0:
+		return unit.Indirect
+	default:
+		return unit.Static
+	}
+}
@@ -1,12 +1,15 @@
 package system
 
 import (
+	"fmt"
 	"sync"
 
+	"systemgo/unit"
+
 	log "github.com/sirupsen/logrus"
 )
 
-const job_type_count = 4
+const job_type_count = 7
 
 type job struct {
 	typ  jobType
@@ -18,9 +21,17 @@ type job struct {
 
 	executed bool
 
+	// Whether later transactions are forbidden to override the job
+	irreversible bool
+
+	result JobResult
+
 	waitch chan struct{}
 	err    error
 
+	// Span recording the execution of j, nil if not traced
+	span *span
+
 	mutex sync.Mutex
 }
 
@@ -49,9 +60,9 @@ func newJob(typ jobType, u *Unit) (j *job) {
 	}
 }
 
-//func (j *job) String() string {
-//return fmt.Sprintf("%s job for %s", j.typ, j.unit.Name())
-//}
+func (j *job) String() string {
+	return fmt.Sprintf("%v job for %s", j.typ, j.unit.Name())
+}
 
 func (j *job) IsRedundant() bool {
 	switch j.typ {
@@ -61,12 +72,17 @@ func (j *job) IsRedundant() bool {
 		return j.unit.IsActivating() || j.unit.IsActive()
 	case reload:
 		return j.unit.IsReloading()
+	case tryRestart, tryReloadOrRestart:
+		return !j.unit.IsActive()
 	default:
 		return false
 	}
 }
 
 func (j *job) IsRunning() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
 	return !j.executed
 }
 
@@ -78,6 +94,22 @@ func (j *job) Failed() bool {
 	return j.State() == failed
 }
 
+// Result returns the result of j. Only meaningful, once j is not running anymore
+func (j *job) Result() JobResult {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.result
+}
+
+// Err returns the error j failed with, if any. Only meaningful, once j is not running anymore
+func (j *job) Err() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.err
+}
+
 func (j *job) Wait() (finished bool) {
 	<-j.waitch
 	return true
@@ -88,8 +120,11 @@ func (j *job) isOrphan() bool {
 }
 
 func (j *job) State() (st jobState) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
 	switch {
-	case j.IsRunning():
+	case !j.executed:
 		return running
 	case j.err == nil:
 		return success
@@ -98,63 +133,105 @@ func (j *job) State() (st jobState) {
 	}
 }
 
-func (j *job) Run() (err error) {
+// Run runs j, which has been set as the job of its unit, when the unit was in prev activation state
+func (j *job) Run(prev unit.Activation) (err error) {
 	e := log.WithFields(log.Fields{
 		"unit": j.unit.Name(),
 		"job":  j.typ,
 	})
 	e.Debugf("j.Run()")
 
-	j.unit.job = j
+	result := ResultDone
+
+	prev = j.unit.transition(prev, j, fmt.Sprintf("%s job started", j.typ))
 	defer func() {
-		j.err = err
-		j.finish()
+		if err != nil && result != ResultDependency {
+			result = ResultFailed
+		}
+		j.finish(result, err)
+		j.emit(JobFinished)
+		j.unit.transition(prev, j, j.Status().String())
 	}()
 
-	wg := &sync.WaitGroup{}
+	wait := j.span.child("job.wait")
+
+	// Jobs ordered before j must finish first, regardless of their outcome
+	for dep := range j.after {
+		dep.Wait()
+	}
+
+	// Failure of a required job fails j, failures of wanted jobs are tolerated
 	for dep := range j.requires {
-		wg.Add(1)
-		go func(dep *job) {
-			e := e.WithField("dep", dep.unit.Name())
-
-			e.Debug("dep.Wait")
-			dep.Wait()
-			e.Debug("dep.Wait returned")
-
-			if !dep.Success() {
-				e.Debugf("->!dep.Success: %s", dep.State())
-				j.unit.Log.Errorf("%s failed to %s", dep.unit.Name(), dep.typ)
-				err = ErrDepFail
+		e := e.WithField("dep", dep.unit.Name())
+
+		e.Debug("dep.Wait")
+		dep.Wait()
+		e.Debug("dep.Wait returned")
+
+		if !dep.Success() {
+			e.Debugf("->!dep.Success: %s", dep.State())
+			j.unit.Log.Errorf("%s failed to %s", dep.unit.Name(), dep.typ)
+
+			result = ResultDependency
+			derr := &DependencyError{
+				Name: j.unit.Name(),
+				Dep:  dep.unit.Name(),
+				Job:  fmt.Sprint(dep.typ),
+				Err:  ErrDepFail,
 			}
-			wg.Done()
-		}(dep)
+			if dep.typ == stop && j.typ != stop {
+				derr.Err = ErrDepConflict
+			}
+			err = derr
+		}
 	}
-	wg.Wait()
+
+	wait.end(err)
 
 	if err != nil {
 		e.Debugf("failed: %s", err)
 		return
 	}
 
+	if sys := j.unit.System; sys != nil {
+		queue := j.span.child("job.queue")
+		release := sys.acquireJob()
+		queue.end(nil)
+		defer release()
+	}
+
+	exec := j.span.child("job.exec")
+	defer func() { exec.end(err) }()
+
 	switch j.typ {
 	case start:
 		return j.unit.start()
 	case stop:
 		return j.unit.stop()
-	case restart:
-		if err = j.unit.stop(); err != nil {
-			return err
-		}
-		return j.unit.start()
+	case restart, tryRestart:
+		// tryRestart jobs for inactive units are redundant and never get run
+		return j.unit.restart()
 	case reload:
 		return j.unit.reload()
+	case reloadOrRestart, tryReloadOrRestart:
+		if j.unit.IsReloader() && unit.IsActive(j.unit.Interface) {
+			return j.unit.reload()
+		}
+		return j.unit.restart()
 	default:
 		panic(ErrUnknownType)
 	}
 }
 
-func (j *job) finish() {
+// finish records the outcome of j and wakes up the waiters
+func (j *job) finish(result JobResult, err error) {
+	j.mutex.Lock()
+	j.result, j.err = result, err
 	j.executed = true
+	j.mutex.Unlock()
+
+	j.span.set("systemgo.job.result", fmt.Sprint(result))
+	j.span.end(err)
 	close(j.waitch)
 }
 
@@ -162,18 +239,51 @@ var mergeTable = map[jobType]map[jobType]jobType{
 	start: {
 		start: start,
 		//verify_active: start,
-		reload:  reload, //reload_or_start
-		restart: restart,
+		reload:             reload, //reload_or_start
+		restart:            restart,
+		tryRestart:         restart,
+		reloadOrRestart:    reloadOrRestart,
+		tryReloadOrRestart: reloadOrRestart,
 	},
 	reload: {
 		start: reload, //reload_or_start
 		//verify_active: reload,
-		restart: restart,
+		restart:            restart,
+		tryRestart:         tryRestart,
+		reloadOrRestart:    reloadOrRestart,
+		tryReloadOrRestart: tryReloadOrRestart,
 	},
 	restart: {
 		start: restart,
 		//verify_active: restart,
-		reload: restart,
+		reload:             restart,
+		tryRestart:         restart,
+		reloadOrRestart:    restart,
+		tryReloadOrRestart: restart,
+	},
+	tryRestart: {
+		start:              restart,
+		reload:             tryRestart,
+		restart:            restart,
+		tryRestart:         tryRestart,
+		reloadOrRestart:    restart,
+		tryReloadOrRestart: tryRestart,
+	},
+	reloadOrRestart: {
+		start:              reloadOrRestart,
+		reload:             reloadOrRestart,
+		restart:            restart,
+		tryRestart:         restart,
+		reloadOrRestart:    reloadOrRestart,
+		tryReloadOrRestart: reloadOrRestart,
+	},
+	tryReloadOrRestart: {
+		start:              reloadOrRestart,
+		reload:             tryReloadOrRestart,
+		restart:            restart,
+		tryRestart:         tryRestart,
+		reloadOrRestart:    reloadOrRestart,
+		tryReloadOrRestart: tryReloadOrRestart,
 	},
 }
 
@@ -18,4 +18,25 @@ const (
 	stop
 	reload
 	restart
+	tryRestart
+	reloadOrRestart
+	tryReloadOrRestart
+)
+
+// Result of a finished job
+type JobResult int
+
+//go:generate stringer -type=JobResult -trimprefix=Result job_generate.go
+const (
+	// Job was executed successfully
+	ResultDone JobResult = iota
+
+	// Job was executed, but failed
+	ResultFailed
+
+	// Job was not executed, as the unit was in the desired state already
+	ResultSkipped
+
+	// Job was not executed, as a job it required failed
+	ResultDependency
 )
@@ -2,19 +2,165 @@ package system
 
 import (
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
 )
 
 func TestJobState(t *testing.T) {
 	j := newJob(-1, nil)
 	assert.Equal(t, running, j.State())
 
-	j.finish()
+	j.finish(ResultDone, nil)
 	assert.Equal(t, success, j.State())
 	assert.True(t, j.Success())
 
 	j.err = errors.New("")
 	assert.Equal(t, failed, j.State())
 }
+
+func TestMaxJobs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+	sys.SetMaxJobs(2)
+	assert.Equal(t, 2, sys.MaxJobs())
+
+	mutex := sync.Mutex{}
+	var running, max int
+
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		m := newMock(ctrl)
+		empty(m, "wants", "before", "conflicts", "after", "requires")
+		m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+		m.MockStarter.EXPECT().Start().DoAndReturn(func() error {
+			mutex.Lock()
+			if running++; running > max {
+				max = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return nil
+		}).Times(1)
+
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	require.NoError(t, sys.Start(names...))
+	waitForJobs(t, sys, names...)
+
+	assert.Equal(t, 2, max, "maximum number of concurrent jobs")
+
+	sys.SetMaxJobs(0)
+	assert.Equal(t, 0, sys.MaxJobs())
+}
+
+func TestFailurePropagation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+
+	mocks := map[string]*mockUnit{
+		"requirer": newMock(ctrl),
+		"wanter":   newMock(ctrl),
+		"required": newMock(ctrl),
+		"wanted":   newMock(ctrl),
+	}
+
+	empty(mocks["requirer"], "wants", "before", "conflicts", "after")
+	mocks["requirer"].MockInterface.EXPECT().Requires().Return([]string{"required"}).Times(1)
+	mocks["requirer"].MockStarter.EXPECT().Start().Times(0)
+
+	empty(mocks["wanter"], "requires", "before", "conflicts", "after")
+	mocks["wanter"].MockInterface.EXPECT().Wants().Return([]string{"wanted"}).Times(1)
+	mocks["wanter"].MockStarter.EXPECT().Start().Return(nil).Times(1)
+
+	for _, name := range []string{"required", "wanted"} {
+		empty(mocks[name], "wants", "before", "conflicts", "after", "requires")
+		mocks[name].MockStarter.EXPECT().Start().Return(errors.New("test")).Times(1)
+	}
+
+	for name, m := range mocks {
+		m.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	err := sys.Start("requirer", "wanter")
+	if jerr, ok := err.(JobError); assert.True(t, ok, "error is JobError") {
+		assert.Equal(t, []string{"requirer"}, jerr.Results.Failed())
+		assert.Equal(t, ResultDone, jerr.Results["wanter"].Result)
+		assert.Equal(t, ResultDependency, jerr.Results["requirer"].Result)
+		assert.Equal(t, (&DependencyError{"requirer", "required", "start", ErrDepFail}).Error(), jerr.Results["requirer"].Err)
+	}
+
+	for name, expected := range map[string]JobResult{
+		"requirer": ResultDependency,
+		"required": ResultFailed,
+		"wanter":   ResultDone,
+		"wanted":   ResultFailed,
+	} {
+		u, err := sys.Unit(name)
+		require.NoError(t, err)
+
+		for u.currentJob() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		u.currentJob().Wait()
+
+		assert.Equal(t, expected, u.currentJob().Result(), name)
+	}
+}
+
+func TestTryRestart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+
+	active, inactive := newMock(ctrl), newMock(ctrl)
+	for name, m := range map[string]*mockUnit{
+		"active":   active,
+		"inactive": inactive,
+	} {
+		empty(m, "wants", "before", "conflicts", "after", "requires")
+
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	active.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+	gomock.InOrder(
+		active.MockStopper.EXPECT().Stop().Return(nil).Times(1),
+		active.MockStarter.EXPECT().Start().Return(nil).Times(1),
+	)
+
+	inactive.MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+	inactive.MockStopper.EXPECT().Stop().Times(0)
+	inactive.MockStarter.EXPECT().Start().Times(0)
+
+	require.NoError(t, sys.TryRestart("active", "inactive"))
+	waitForJobs(t, sys, "active")
+
+	u, err := sys.Unit("inactive")
+	require.NoError(t, err)
+	assert.Nil(t, u.job, "job of inactive unit")
+}
@@ -0,0 +1,578 @@
+package system
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Default location and limits of the persistent journal
+const (
+	DEFAULT_JOURNAL_DIR           = "/var/log/systemgo"
+	DEFAULT_JOURNAL_MAX_FILE_SIZE = 1 << 20
+	DEFAULT_JOURNAL_MAX_FILES     = 5
+)
+
+// Suffix of journal files, rotated files get a ".N" appended, N being 1 for the most recent one
+const JOURNAL_SUFFIX = ".log"
+
+// Suffix appended to rotated journal files compressed with gzip
+const GZIP_SUFFIX = ".gz"
+
+// Compression algorithms of rotated journal files
+const (
+	COMPRESS_NONE = ""
+	COMPRESS_GZIP = "gzip"
+)
+
+// Interval, at which the journal is vacuumed in the background, if any limits are set
+const JOURNAL_VACUUM_INTERVAL = time.Minute
+
+// JournalConfig specifies where and for how long unit logs are kept on disk
+type JournalConfig struct {
+	// Directory, which holds the journal files of all units
+	Dir string
+
+	// Size in bytes, after which the journal file of a unit gets rotated(0 means no rotation)
+	MaxFileSize int64
+
+	// Number of rotated files kept per unit, older ones get vacuumed
+	MaxFiles int
+
+	// Age, after which the rotated files get vacuumed(0 means rotated files are kept regardless of age)
+	MaxRetention time.Duration
+
+	// Algorithm rotated files are compressed with, COMPRESS_NONE or COMPRESS_GZIP
+	Compress string
+
+	// Size in bytes, which the files of all units may use at most, oldest rotated files
+	// get vacuumed first(0 means no limit)
+	MaxUse int64
+
+	// Size in bytes, which is kept free on the file system holding the journal, oldest rotated files
+	// get vacuumed first(0 means no limit)
+	KeepFree int64
+}
+
+// DefaultJournalConfig returns the journal configuration used, unless configured otherwise
+func DefaultJournalConfig() JournalConfig {
+	return JournalConfig{
+		Dir:         DEFAULT_JOURNAL_DIR,
+		MaxFileSize: DEFAULT_JOURNAL_MAX_FILE_SIZE,
+		MaxFiles:    DEFAULT_JOURNAL_MAX_FILES,
+		Compress:    COMPRESS_GZIP,
+	}
+}
+
+// Journal keeps logs of units in per-unit files on disk, so that they survive restarts of the manager
+type Journal struct {
+	config JournalConfig
+
+	// Open journal files by unit name
+	files map[string]*journalFile
+
+	// Closed to stop vacuuming in the background
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mutex sync.Mutex
+}
+
+// OpenJournal creates the journal directory specified by config, if it does not exist,
+// and vacuums the files exceeding the retention limits. If any limits besides the number of files are set,
+// the journal is vacuumed every JOURNAL_VACUUM_INTERVAL, until closed
+func OpenJournal(config JournalConfig) (j *Journal, err error) {
+	log.WithField("config", config).Debugf("OpenJournal")
+
+	if config.Dir == "" {
+		return nil, fmt.Errorf("journal directory not specified")
+	}
+	switch config.Compress {
+	case COMPRESS_NONE, COMPRESS_GZIP:
+	default:
+		return nil, fmt.Errorf("unsupported journal compression: %q", config.Compress)
+	}
+	if err = os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	j = &Journal{
+		config: config,
+		files:  make(map[string]*journalFile),
+		done:   make(chan struct{}),
+	}
+	if config.MaxRetention > 0 || config.MaxUse > 0 || config.KeepFree > 0 {
+		go j.vacuumEvery(JOURNAL_VACUUM_INTERVAL)
+	}
+	return j, j.Vacuum()
+}
+
+// vacuumEvery vacuums j every interval, until j is closed
+func (j *Journal) vacuumEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			if err := j.Vacuum(); err != nil {
+				log.Errorf("Error vacuuming journal: %s", err)
+			}
+		}
+	}
+}
+
+// Config returns the configuration of j
+func (j *Journal) Config() JournalConfig {
+	return j.config
+}
+
+// path returns the path of the active journal file of unit with name specified
+func (j *Journal) path(name string) string {
+	return filepath.Join(j.config.Dir, name+JOURNAL_SUFFIX)
+}
+
+// Writer returns the writer appending to the journal file of the unit with name specified
+func (j *Journal) Writer(name string) io.Writer {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	f, ok := j.files[name]
+	if !ok {
+		f = &journalFile{journal: j, path: j.path(name)}
+		j.files[name] = f
+	}
+	return f
+}
+
+// Read returns the complete journal of the unit with name specified, oldest entries first.
+// Missing journal is not an error
+func (j *Journal) Read(name string) (b []byte, err error) {
+	j.mutex.Lock()
+	f := j.files[name]
+	j.mutex.Unlock()
+
+	if f != nil {
+		// Rotation must not happen in between reading the files
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+	}
+
+	paths := j.rotated(name)
+	for i := len(paths) - 1; i >= 0; i-- {
+		var part []byte
+		if part, err = readJournalFile(paths[i]); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		b = append(b, part...)
+	}
+
+	var part []byte
+	if part, err = ioutil.ReadFile(j.path(name)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return append(b, part...), nil
+}
+
+// Entries returns the entries of the journal of the unit with name specified, oldest first.
+// Missing journal is not an error
+func (j *Journal) Entries(name string) (entries []LogEntry, err error) {
+	var b []byte
+	if b, err = j.Read(name); err != nil {
+		return nil, err
+	}
+	return decodeEntries(name, b), nil
+}
+
+// Tail returns up to n last bytes of the journal of the unit with name specified,
+// starting at a beginning of a line
+func (j *Journal) Tail(name string, n int) (b []byte, err error) {
+	if b, err = j.Read(name); err != nil || len(b) <= n {
+		return
+	}
+
+	b = b[len(b)-n:]
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[i+1:]
+	} else {
+		b = nil
+	}
+	return
+}
+
+// readJournalFile reads the journal file at path, decompressing it, if compressed
+func readJournalFile(path string) (b []byte, err error) {
+	if !strings.HasSuffix(path, GZIP_SUFFIX) {
+		return ioutil.ReadFile(path)
+	}
+
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r *gzip.Reader
+	if r, err = gzip.NewReader(f); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// compressJournalFile compresses the file at path into path with GZIP_SUFFIX appended and removes it
+func compressJournalFile(path string) (err error) {
+	var in *os.File
+	if in, err = os.Open(path); err != nil {
+		return
+	}
+	defer in.Close()
+
+	tmp := path + GZIP_SUFFIX + ".tmp"
+	var out *os.File
+	if out, err = os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640); err != nil {
+		return
+	}
+	defer os.Remove(tmp)
+
+	w := gzip.NewWriter(out)
+	if _, err = io.Copy(w, in); err == nil {
+		err = w.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+
+	if err = os.Rename(tmp, path+GZIP_SUFFIX); err != nil {
+		return
+	}
+	return os.Remove(path)
+}
+
+// Vacuum removes the rotated files exceeding the number of files kept per unit or
+// older than the retention age and then the oldest rotated files of all units,
+// until the disk usage limits are satisfied
+func (j *Journal) Vacuum() (err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(j.config.Dir); err != nil {
+		return
+	}
+
+	for _, fi := range infos {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, JOURNAL_SUFFIX) {
+			continue
+		}
+		if verr := j.vacuum(strings.TrimSuffix(name, JOURNAL_SUFFIX)); verr != nil && err == nil {
+			err = verr
+		}
+	}
+
+	if verr := j.vacuumUsage(); verr != nil && err == nil {
+		err = verr
+	}
+	return
+}
+
+// Returns the number of bytes available on the file system holding dir, replaced in tests
+var diskFree = func(dir string) (free int64, err error) {
+	var st syscall.Statfs_t
+	if err = syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// vacuumUsage removes the oldest rotated files of all units, until the files of j use at most MaxUse
+// bytes and KeepFree bytes are free on the file system. Active files are never removed
+func (j *Journal) vacuumUsage() (err error) {
+	if j.config.MaxUse <= 0 && j.config.KeepFree <= 0 {
+		return nil
+	}
+
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(j.config.Dir); err != nil {
+		return
+	}
+
+	var used int64
+	var rotated []os.FileInfo
+	for _, fi := range infos {
+		if fi.IsDir() {
+			continue
+		}
+		used += fi.Size()
+
+		if isRotated(fi.Name()) {
+			rotated = append(rotated, fi)
+		}
+	}
+
+	sort.SliceStable(rotated, func(i, k int) bool {
+		return rotated[i].ModTime().Before(rotated[k].ModTime())
+	})
+
+	var free int64 = -1
+	if j.config.KeepFree > 0 {
+		if free, err = diskFree(j.config.Dir); err != nil {
+			return
+		}
+	}
+
+	exceeded := func() bool {
+		return j.config.MaxUse > 0 && used > j.config.MaxUse ||
+			j.config.KeepFree > 0 && free < j.config.KeepFree
+	}
+
+	for _, fi := range rotated {
+		if !exceeded() {
+			break
+		}
+
+		path := filepath.Join(j.config.Dir, fi.Name())
+		log.WithField("path", path).Debugf("Vacuuming journal file exceeding disk usage limits")
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return
+		}
+
+		used -= fi.Size()
+		if free >= 0 {
+			free += fi.Size()
+		}
+	}
+	return nil
+}
+
+// isRotated returns whether the file with name specified is a rotated journal file, i.e.
+// it is named "<unit>.log.N", optionally with GZIP_SUFFIX appended
+func isRotated(name string) bool {
+	name = strings.TrimSuffix(name, GZIP_SUFFIX)
+
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 || !strings.HasSuffix(name[:i], JOURNAL_SUFFIX) {
+		return false
+	}
+	n, err := strconv.Atoi(name[i+1:])
+	return err == nil && n > 0
+}
+
+// vacuum removes the rotated files of unit with name specified exceeding the retention limits
+func (j *Journal) vacuum(name string) (err error) {
+	for i, path := range j.rotated(name) {
+		remove := i >= j.config.MaxFiles
+		if !remove && j.config.MaxRetention > 0 {
+			if fi, serr := os.Stat(path); serr == nil {
+				remove = time.Since(fi.ModTime()) > j.config.MaxRetention
+			}
+		}
+
+		if remove {
+			log.WithField("path", path).Debugf("Vacuuming journal file")
+			if rerr := os.Remove(path); rerr != nil && !os.IsNotExist(rerr) && err == nil {
+				err = rerr
+			}
+		}
+	}
+	return
+}
+
+// rotated returns paths of the rotated files of unit with name specified, most recent first
+func (j *Journal) rotated(name string) (paths []string) {
+	prefix := j.path(name) + "."
+
+	matches, _ := filepath.Glob(prefix + "*")
+
+	nums := map[string]int{}
+	for _, path := range matches {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, prefix), GZIP_SUFFIX))
+		if err != nil || n <= 0 {
+			continue
+		}
+		nums[path] = n
+		paths = append(paths, path)
+	}
+
+	sort.Slice(paths, func(i, k int) bool {
+		return nums[paths[i]] < nums[paths[k]]
+	})
+	return
+}
+
+// Close closes all journal files and stops vacuuming in the background
+func (j *Journal) Close() (err error) {
+	j.closeOnce.Do(func() {
+		close(j.done)
+	})
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for name, f := range j.files {
+		f.mutex.Lock()
+		cerr := f.close()
+		f.mutex.Unlock()
+
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(j.files, name)
+	}
+	return
+}
+
+// journalFile appends to the active journal file of a unit and rotates it, as it grows too large
+type journalFile struct {
+	journal *Journal
+	path    string
+
+	file *os.File
+	size int64
+
+	mutex sync.Mutex
+}
+
+func (f *journalFile) Write(b []byte) (n int, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	max := f.journal.config.MaxFileSize
+	if f.file != nil && max > 0 && f.size > 0 && f.size+int64(len(b)) > max {
+		if err = f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.file == nil {
+		if f.file, err = os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640); err != nil {
+			return 0, err
+		}
+
+		var fi os.FileInfo
+		if fi, err = f.file.Stat(); err != nil {
+			return 0, err
+		}
+		f.size = fi.Size()
+	}
+
+	n, err = f.file.Write(b)
+	f.size += int64(n)
+	return
+}
+
+// rotate shifts the rotated files by one, renames the active file to "<path>.1", compresses it,
+// if configured, and vacuums the files exceeding the retention limits. The active file gets created on next write
+func (f *journalFile) rotate() (err error) {
+	name := strings.TrimSuffix(filepath.Base(f.path), JOURNAL_SUFFIX)
+
+	if err = f.close(); err != nil {
+		return
+	}
+
+	paths := f.journal.rotated(name)
+	for i := len(paths) - 1; i >= 0; i-- {
+		suffix := ""
+		if strings.HasSuffix(paths[i], GZIP_SUFFIX) {
+			suffix = GZIP_SUFFIX
+		}
+		if err = os.Rename(paths[i], fmt.Sprintf("%s.%d%s", f.path, i+2, suffix)); err != nil {
+			return
+		}
+	}
+
+	if err = os.Rename(f.path, f.path+".1"); err != nil {
+		return
+	}
+
+	if f.journal.config.Compress == COMPRESS_GZIP {
+		if err = compressJournalFile(f.path + ".1"); err != nil {
+			return
+		}
+	}
+	return f.journal.vacuum(name)
+}
+
+func (f *journalFile) close() (err error) {
+	if f.file == nil {
+		return nil
+	}
+
+	err = f.file.Close()
+	f.file = nil
+	f.size = 0
+	return
+}
+
+// Journal returns the persistent journal of sys, nil if logs of units are only kept in-memory
+func (sys *Daemon) Journal() *Journal {
+	sys.logMutex.Lock()
+	defer sys.logMutex.Unlock()
+
+	return sys.journal
+}
+
+// SetJournal opens the journal specified by config and makes units keep their logs in it,
+// so that the logs survive restarts of sys. Logs kept in the journal from previous runs
+// get restored into the in-memory logs of the units
+func (sys *Daemon) SetJournal(config JournalConfig) (err error) {
+	var j *Journal
+	if j, err = OpenJournal(config); err != nil {
+		return
+	}
+
+	sys.logMutex.Lock()
+	prev := sys.journal
+	sys.journal = j
+	sys.logMutex.Unlock()
+
+	if prev != nil {
+		if err = prev.Close(); err != nil {
+			log.Errorf("Error closing journal: %s", err)
+		}
+	}
+
+	for _, u := range sys.Units() {
+		j.attach(u)
+	}
+
+	// Entries are not needed in the kernel log buffer, once journaled
+	sys.closeKmsg()
+	return nil
+}
+
+// closeJournal closes the journal files held open by sys, if any
+func (sys *Daemon) closeJournal() {
+	if j := sys.Journal(); j != nil {
+		if err := j.Close(); err != nil {
+			log.Errorf("Error closing journal: %s", err)
+		}
+	}
+}
+
+// attach restores the most recent entries of the journal of u into its in-memory log
+// and makes the log of u write to the journal
+func (j *Journal) attach(u *Unit) {
+	// Journal lines are larger, than the messages kept in-memory
+	b, err := j.Tail(u.Name(), 4*BUFFER_SIZE)
+	if err != nil {
+		log.WithField("unit", u.Name()).Errorf("Error reading journal: %s", err)
+	}
+	u.Log.restore(decodeEntries(u.Name(), b), j.Writer(u.Name()))
+}
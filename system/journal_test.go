@@ -0,0 +1,202 @@
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, err := OpenJournal(JournalConfig{Dir: dir, MaxFileSize: 100, MaxFiles: 2})
+	require.NoError(t, err)
+	defer j.Close()
+
+	w := j.Writer("foo.service")
+
+	var all []string
+	for i := 0; i < 20; i++ {
+		line := fmt.Sprintf("line %02d %s\n", i, strings.Repeat("x", 20))
+		all = append(all, line)
+
+		_, err = w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "foo.service.log*"))
+	require.NoError(t, err)
+	assert.Len(t, matches, 3, "active file and 2 rotated ones")
+
+	for _, path := range matches {
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.True(t, fi.Size() <= 100, path)
+	}
+
+	b, err := j.Read("foo.service")
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(strings.Join(all, ""), string(b)), "oldest entries come first")
+	assert.True(t, strings.HasPrefix(string(b), "line 12"), "oldest files are vacuumed")
+
+	b, err = j.Tail("foo.service", 40)
+	require.NoError(t, err)
+	assert.Equal(t, all[19], string(b))
+
+	b, err = j.Read("missing.service")
+	assert.NoError(t, err)
+	assert.Empty(t, b)
+}
+
+func TestJournalVacuum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"foo.service.log", "foo.service.log.1", "foo.service.log.2", "bar.service.log.1"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte(name+"\n"), 0640))
+		require.NoError(t, os.Chtimes(path, old, old))
+	}
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "foo.service.log.1"), time.Now(), time.Now()))
+
+	_, err = OpenJournal(JournalConfig{Dir: dir, MaxFiles: 5, MaxRetention: 24 * time.Hour})
+	require.NoError(t, err)
+
+	for name, exists := range map[string]bool{
+		"foo.service.log":   true,
+		"foo.service.log.1": true,
+		"foo.service.log.2": false,
+		// Rotated files are only vacuumed along with the active file of the unit
+		"bar.service.log.1": true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.Equal(t, exists, err == nil, name)
+	}
+}
+
+func TestJournalCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = OpenJournal(JournalConfig{Dir: dir, Compress: "zstd"})
+	assert.Error(t, err, "unsupported compression")
+
+	// Rotated files kept uncompressed before are read as well
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.service.log.1"), []byte("legacy\n"), 0640))
+
+	j, err := OpenJournal(JournalConfig{Dir: dir, MaxFileSize: 100, MaxFiles: 3, Compress: COMPRESS_GZIP})
+	require.NoError(t, err)
+	defer j.Close()
+
+	w := j.Writer("foo.service")
+	for i := 0; i < 8; i++ {
+		_, err = w.Write([]byte(fmt.Sprintf("line %02d %s\n", i, strings.Repeat("x", 20))))
+		require.NoError(t, err)
+	}
+
+	for name, exists := range map[string]bool{
+		"foo.service.log":      true,
+		"foo.service.log.1.gz": true,
+		"foo.service.log.1":    false,
+		"foo.service.log.2.gz": true,
+		"foo.service.log.3":    true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.Equal(t, exists, err == nil, name)
+	}
+
+	b, err := j.Read("foo.service")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(b), "legacy\nline 00"), "compressed files are decompressed")
+	assert.True(t, strings.HasSuffix(string(b), "line 07 "+strings.Repeat("x", 20)+"\n"))
+}
+
+func TestJournalMaxUse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := []byte(strings.Repeat("x", 99) + "\n")
+	for i, name := range []string{"foo.service.log.2.gz", "bar.service.log.1", "foo.service.log.1.gz", "foo.service.log", "bar.service.log"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, content, 0640))
+
+		mtime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	j, err := OpenJournal(JournalConfig{Dir: dir, MaxFiles: 5, MaxUse: 350})
+	require.NoError(t, err)
+	j.Close()
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	assert.False(t, exists("foo.service.log.2.gz"), "oldest rotated files are vacuumed first")
+	assert.False(t, exists("bar.service.log.1"))
+	assert.True(t, exists("foo.service.log.1.gz"))
+
+	// Active files are never vacuumed
+	j, err = OpenJournal(JournalConfig{Dir: dir, MaxFiles: 5, MaxUse: 1})
+	require.NoError(t, err)
+	j.Close()
+	assert.False(t, exists("foo.service.log.1.gz"))
+	assert.True(t, exists("foo.service.log"))
+	assert.True(t, exists("bar.service.log"))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.service.log.1"), content, 0640))
+	defer func(f func(string) (int64, error)) { diskFree = f }(diskFree)
+	diskFree = func(string) (int64, error) { return 50, nil }
+
+	j, err = OpenJournal(JournalConfig{Dir: dir, MaxFiles: 5, KeepFree: 100})
+	require.NoError(t, err)
+	j.Close()
+	assert.False(t, exists("foo.service.log.1"), "files are vacuumed, until enough space is free")
+}
+
+func TestSetJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	u.Log.Println("before journal")
+
+	require.NoError(t, sys.SetJournal(JournalConfig{Dir: dir, MaxFiles: 1}))
+	u.Log.Println("after journal")
+
+	b, err := sys.Journal().Read("foo.service")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "before journal")
+	assert.Contains(t, string(b), "after journal")
+
+	// Logs survive restarts of the manager
+	sys = New()
+	sys.SetPaths()
+	require.NoError(t, sys.SetJournal(JournalConfig{Dir: dir, MaxFiles: 1}))
+	defer sys.Journal().Close()
+
+	u, err = sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	b, err = ioutil.ReadAll(u.Log.Cursor())
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "after journal")
+}
@@ -0,0 +1,213 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Device of the kernel log buffer
+const KMSG_PATH = "/dev/kmsg"
+
+// Size of the buffer records of the kernel log buffer are read into, records do not exceed it
+const KMSG_RECORD_MAX = 8192
+
+// Identifier of the entries of the kernel
+const KERNEL_IDENTIFIER = "kernel"
+
+// CaptureKmsg merges the messages of the kernel read from KMSG_PATH, starting with the oldest ones kept
+// in the kernel log buffer, into the log of sys, so that diagnostics of early boot are captured.
+// Messages written to the buffer by userspace are ignored. Returns a function, which stops capturing
+func (sys *Daemon) CaptureKmsg() (stop func(), err error) {
+	return sys.captureKmsg(KMSG_PATH)
+}
+
+func (sys *Daemon) captureKmsg(path string) (stop func(), err error) {
+	log.WithField("path", path).Debugf("sys.CaptureKmsg")
+
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readKmsg(f, func(e LogEntry) {
+			sys.Log.add(e)
+		})
+	}()
+
+	return func() {
+		f.Close()
+		<-done
+	}, nil
+}
+
+// readKmsg calls add with the entries of the kernel read from r, until r is closed or drained.
+// Each read of the kernel log buffer returns a single record
+func readKmsg(r io.Reader, add func(LogEntry)) {
+	buf := make([]byte, KMSG_RECORD_MAX)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			record := string(buf[:n])
+			if i := strings.IndexByte(record, '\n'); i >= 0 {
+				// Continuation lines hold the dictionary of the record
+				record = record[:i]
+			}
+
+			if e, ok := parseKmsg(record); ok {
+				add(e)
+			}
+		}
+
+		switch {
+		case err == nil:
+		case errors.Is(err, syscall.EPIPE):
+			// Records were overwritten before being read, reading continues with the oldest one kept
+			log.Debugf("Kernel log records were overwritten before being read")
+		case err == io.EOF || errors.Is(err, os.ErrClosed):
+			return
+		default:
+			log.Errorf("Error reading kernel log: %s", err)
+			return
+		}
+	}
+}
+
+// parseKmsg parses a record of the kernel log buffer in the form "PRIORITY,SEQUENCE,TIMESTAMP,FLAGS;MESSAGE",
+// see dev-kmsg in the kernel documentation. Records of userspace and malformed records
+// are not entries of the kernel and ok is false for them
+func parseKmsg(line string) (e LogEntry, ok bool) {
+	i := strings.IndexByte(line, ';')
+	if i < 0 {
+		return e, false
+	}
+
+	prefix := strings.Split(line[:i], ",")
+	if len(prefix) < 3 {
+		return e, false
+	}
+
+	pri, err := strconv.Atoi(prefix[0])
+	if err != nil || pri>>3 != 0 {
+		return e, false
+	}
+
+	usec, err := strconv.ParseInt(prefix[2], 10, 64)
+	if err != nil {
+		return e, false
+	}
+
+	e.Monotonic = time.Duration(usec) * time.Microsecond
+	e.Time = realtime(e.Monotonic)
+	e.Priority = pri & 7
+	e.Message = unescapeKmsg(line[i+1:])
+	e.Kernel = true
+	return e, true
+}
+
+// unescapeKmsg replaces the "\xNN" escape sequences of non-printable characters in s by the characters
+func unescapeKmsg(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// realtime returns the time, when the time elapsed since the system booted, measured by the monotonic clock, was d
+func realtime(d time.Duration) time.Time {
+	monotonic(time.Now())
+	return bootTime.Add(d)
+}
+
+// ForwardToKmsg makes the entries logged by the manager and units be written to the kernel log buffer
+// at KMSG_PATH, until a journal is set, so that they are captured before the journal is available.
+// Entries are written with the facility "daemon"
+func (sys *Daemon) ForwardToKmsg() (err error) {
+	return sys.forwardToKmsgAt(KMSG_PATH)
+}
+
+func (sys *Daemon) forwardToKmsgAt(path string) (err error) {
+	log.WithField("path", path).Debugf("sys.ForwardToKmsg")
+
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_WRONLY, 0); err != nil {
+		return
+	}
+
+	sys.logMutex.Lock()
+	prev := sys.kmsg
+	sys.kmsg = f
+	sys.logMutex.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
+	return nil
+}
+
+// closeKmsg stops forwarding entries to the kernel log buffer
+func (sys *Daemon) closeKmsg() {
+	sys.logMutex.Lock()
+	f := sys.kmsg
+	sys.kmsg = nil
+	sys.logMutex.Unlock()
+
+	if f != nil {
+		f.Close()
+	}
+}
+
+// forwardToKmsg writes e to the kernel log buffer, if enabled. Entries of the kernel are not written back
+func (sys *Daemon) forwardToKmsg(e LogEntry) {
+	if e.Kernel {
+		return
+	}
+
+	sys.logMutex.Lock()
+	defer sys.logMutex.Unlock()
+
+	if sys.kmsg == nil {
+		return
+	}
+
+	if _, err := sys.kmsg.Write(formatKmsg(e)); err != nil {
+		log.Debugf("Error writing to kernel log: %s", err)
+	}
+}
+
+// formatKmsg formats e as a record written to the kernel log buffer, the buffer takes a record per write
+func formatKmsg(e LogEntry) []byte {
+	identifier := MANAGER_IDENTIFIER
+	if e.Unit != "" {
+		identifier = strings.TrimSuffix(e.Unit, filepath.Ext(e.Unit))
+	}
+	if e.PID != 0 {
+		identifier += "[" + strconv.Itoa(e.PID) + "]"
+	}
+	facility, _ := unit.ParseSyslogFacility(unit.DEFAULT_SYSLOG_FACILITY)
+	return []byte(fmt.Sprintf("<%d>%s: %s\n", facility*8+e.Priority, identifier, e.Message))
+}
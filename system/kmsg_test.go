@@ -0,0 +1,134 @@
+package system
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKmsg(t *testing.T) {
+	e, ok := parseKmsg(`6,339,5140900,-;NET: Registered protocol family 10`)
+	require.True(t, ok)
+	assert.Equal(t, LOG_INFO, e.Priority)
+	assert.Equal(t, 5140900*time.Microsecond, e.Monotonic)
+	assert.Equal(t, "NET: Registered protocol family 10", e.Message)
+	assert.True(t, e.Kernel)
+	assert.Equal(t, KERNEL_IDENTIFIER, e.Identifier())
+
+	e, ok = parseKmsg(`3,340,5141000,-,caller=T1;tab\x09and\x5c`)
+	require.True(t, ok)
+	assert.Equal(t, LOG_ERR, e.Priority)
+	assert.Equal(t, "tab\tand\\", e.Message)
+
+	for _, line := range []string{
+		"30,341,5141100,-;foo: forwarded by userspace",
+		"6,342;no timestamp",
+		"no prefix",
+	} {
+		_, ok = parseKmsg(line)
+		assert.False(t, ok, line)
+	}
+}
+
+// recordReader returns a record per read, like the kernel log buffer does
+type recordReader struct {
+	records []string
+	errs    []error
+}
+
+func (r *recordReader) Read(b []byte) (n int, err error) {
+	if len(r.errs) > 0 {
+		err, r.errs = r.errs[0], r.errs[1:]
+		if err != nil {
+			return 0, err
+		}
+	}
+	if len(r.records) == 0 {
+		return 0, io.EOF
+	}
+	n = copy(b, r.records[0])
+	r.records = r.records[1:]
+	return n, nil
+}
+
+func TestReadKmsg(t *testing.T) {
+	r := &recordReader{
+		records: []string{
+			"6,1,100,-;first\n SUBSYSTEM=net\n",
+			"14,2,200,-;userspace\n",
+			"4,3,300,-;second\n",
+		},
+		errs: []error{nil, &os.PathError{Op: "read", Path: KMSG_PATH, Err: syscall.EPIPE}},
+	}
+
+	var messages []string
+	readKmsg(r, func(e LogEntry) {
+		messages = append(messages, e.Message)
+	})
+	assert.Equal(t, []string{"first", "second"}, messages, "reading continues after EPIPE")
+}
+
+func TestCaptureKmsg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kmsg-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "kmsg")
+	require.NoError(t, ioutil.WriteFile(path, []byte("3,1,100,-;Out of memory\n"), 0644))
+
+	sys := New()
+	stop, err := sys.captureKmsg(path)
+	require.NoError(t, err)
+	defer stop()
+
+	require.Eventually(t, func() bool {
+		return sys.Log.Len() > 0
+	}, time.Second, time.Millisecond)
+
+	entries, err := sys.Logs(LogQuery{Kernel: true})
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "Out of memory", entries[0].Message)
+		assert.Equal(t, LOG_ERR, entries[0].Priority)
+	}
+
+	_, err = sys.captureKmsg(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestForwardToKmsg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kmsg-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "kmsg")
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+
+	sys := New()
+	sys.SetPaths()
+	require.NoError(t, sys.forwardToKmsgAt(path))
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	u.Log.WithField("pid", 42).Error("failed")
+	sys.Log.Println("manager")
+	sys.Log.add(LogEntry{Time: time.Now(), Priority: LOG_INFO, Message: "kernel", Kernel: true})
+
+	require.NoError(t, sys.SetJournal(JournalConfig{Dir: filepath.Join(dir, "journal")}))
+	defer sys.Journal().Close()
+	sys.Log.Println("journaled")
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "<27>foo[42]: failed\n<30>systemgo: manager\n", string(b),
+		"entries of the kernel are not written back and forwarding stops, once journaled")
+}
@@ -0,0 +1,124 @@
+package system
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Link makes the unit defined in the file located at path outside of the unit paths
+// available to sys by symlinking it into the first of the unit paths
+func (sys *Daemon) Link(path string) (err error) {
+	log.WithField("path", path).Debugf("sys.Link")
+
+	if !filepath.IsAbs(path) {
+		if path, err = filepath.Abs(path); err != nil {
+			return
+		}
+	}
+
+	name := filepath.Base(path)
+	if !Supported(name) {
+		return ErrUnknownType
+	}
+
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		return
+	} else if info.IsDir() {
+		return ErrIsDir
+	}
+
+	var dir string
+	if dir, err = sys.configDir(); err != nil {
+		return
+	}
+
+	link := filepath.Join(dir, name)
+	if err = os.Symlink(path, link); err != nil {
+		if !os.IsExist(err) {
+			return
+		}
+		if target, lerr := os.Readlink(link); lerr != nil || target != path {
+			return ErrExists
+		}
+	}
+
+	return sys.reread(name)
+}
+
+// Revert drops the drop-ins, overrides and masks of units specified by names found in the first
+// of the unit paths and in the runtime path, so that the vendor definitions of those get used again
+func (sys *Daemon) Revert(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Revert")
+
+	var dir string
+	if dir, err = sys.configDir(); err != nil {
+		return
+	}
+
+	for _, name := range names {
+		if !Supported(name) {
+			return ErrUnknownType
+		}
+
+		runtime := sys.RuntimePath()
+
+		vendor := false
+		for _, path := range sys.Paths() {
+			if path == dir || path == runtime {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(path, name)); err == nil {
+				vendor = true
+				break
+			}
+		}
+
+		for _, path := range []string{dir, runtime} {
+			if err = os.RemoveAll(filepath.Join(path, name+".d")); err != nil {
+				return
+			}
+
+			definition := filepath.Join(path, name)
+			if !vendor && !isMaskLink(definition) {
+				// Do not remove the only definition there is
+				continue
+			}
+			if err = os.Remove(definition); err != nil && !os.IsNotExist(err) {
+				return
+			}
+		}
+
+		sys.mutex.Lock()
+		delete(sys.masked, name)
+		sys.mutex.Unlock()
+
+		if err = sys.reread(name); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// reread reloads the definition of the unit held in-memory under specified name, if there is one
+func (sys *Daemon) reread(name string) (err error) {
+	u, err := sys.Unit(name)
+	if err != nil {
+		return nil
+	}
+
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
+	u.setLoad(unit.Stub)
+	if _, err = sys.load(name); errors.Is(err, ErrNotFound) {
+		u.setLoad(unit.NotFound)
+		return nil
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestLink(t *testing.T) {
+	admin, err := ioutil.TempDir("", "link-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	external, err := ioutil.TempDir("", "link-external")
+	require.NoError(t, err)
+	defer os.RemoveAll(external)
+
+	path := filepath.Join(external, "test.service")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[Unit]
+Description=External
+
+[Service]
+ExecStart=/bin/true`), 0644))
+
+	sys := New()
+	sys.SetPaths(admin)
+
+	require.NoError(t, sys.Link(path))
+	require.NoError(t, sys.Link(path), "linking twice")
+
+	u, err := sys.Get("test.service")
+	require.NoError(t, err)
+	assert.True(t, u.IsLoaded())
+	assert.Equal(t, "External", u.Description())
+	assert.Equal(t, unit.Linked, u.EnableState())
+
+	assert.Equal(t, ErrUnknownType, sys.Link(filepath.Join(external, "test.txt")))
+	assert.True(t, os.IsNotExist(sys.Link(filepath.Join(external, "missing.service"))))
+}
+
+func TestRevert(t *testing.T) {
+	admin, err := ioutil.TempDir("", "revert-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	runtime, err := ioutil.TempDir("", "revert-runtime")
+	require.NoError(t, err)
+	defer os.RemoveAll(runtime)
+
+	vendor, err := ioutil.TempDir("", "revert-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	for path, contents := range map[string]string{
+		filepath.Join(vendor, "test.service"):                   "[Unit]\nDescription=Vendor\n[Service]\nExecStart=/bin/true",
+		filepath.Join(admin, "test.service"):                    "[Unit]\nDescription=Admin\n[Service]\nExecStart=/bin/true",
+		filepath.Join(runtime, "test.service.d", "a.conf"):      "[Unit]\nDescription=Runtime",
+		filepath.Join(admin, "only.service"):                    "[Unit]\nDescription=Only\n[Service]\nExecStart=/bin/true",
+		filepath.Join(admin, "only.service.d", "override.conf"): "[Unit]\nDescription=Override",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(admin, runtime, vendor)
+	sys.SetRuntimePath(runtime)
+
+	u, err := sys.Get("test.service")
+	require.NoError(t, err)
+	assert.Equal(t, "Runtime", u.Description())
+
+	only, err := sys.Get("only.service")
+	require.NoError(t, err)
+	assert.Equal(t, "Override", only.Description())
+
+	require.NoError(t, sys.Revert("test.service", "only.service"))
+
+	assert.Equal(t, "Vendor", u.Description())
+	assert.Equal(t, filepath.Join(vendor, "test.service"), u.Path())
+
+	// Definitions without vendor versions are kept
+	assert.Equal(t, "Only", only.Description())
+	assert.True(t, only.IsLoaded())
+}
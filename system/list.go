@@ -0,0 +1,189 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"systemgo/unit"
+)
+
+// UnitFilter selects units listed by ListUnits
+type UnitFilter struct {
+	// Unit types to list, e.g. "service", all if empty
+	Types []string
+
+	// Load, active or sub states to list, e.g. "failed" or "running", all if empty.
+	// Matching is case-insensitive and ignores dashes, so that "not-found" matches unit.NotFound
+	States []string
+
+	// Whether to list inactive units without jobs as well.
+	// Implied, if States are specified
+	All bool
+
+	// Whether to embed the status of each unit listed, so that it needs not be requested separately
+	Status bool
+}
+
+// UnitInfo is an entry of the list returned by ListUnits
+type UnitInfo struct {
+	Name        string
+	Description string
+
+	Load   unit.Load
+	Active unit.Activation
+	Sub    string
+
+	// Status of the unit without the log, nil unless requested by UnitFilter.Status
+	Status *unit.Status
+}
+
+// StatusBatch holds the statuses of units requested at once
+type StatusBatch struct {
+	// Statuses of the units found mapped by name
+	Statuses map[string]unit.Status
+
+	// Errors looking up the units, which were not found, mapped by name
+	Errors map[string]string
+}
+
+// matches returns whether u is selected by f
+func (f UnitFilter) matches(u *Unit) bool {
+	if len(f.Types) > 0 && !matchesAny(strings.TrimPrefix(filepath.Ext(u.Name()), "."), f.Types) {
+		return false
+	}
+
+	if len(f.States) > 0 {
+		return matchesAny(fmt.Sprint(u.Loaded()), f.States) ||
+			matchesAny(fmt.Sprint(u.Active()), f.States) ||
+			matchesAny(u.Sub(), f.States)
+	}
+
+	return f.All || u.Active() != unit.Inactive || u.currentJob() != nil
+}
+
+// matchesAny returns whether s matches any of patterns case-insensitively, ignoring dashes
+func matchesAny(s string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.EqualFold(strings.Replace(pattern, "-", "", -1), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListUnits returns the units held in-memory selected by filter sorted by name
+func (sys *Daemon) ListUnits(filter UnitFilter) (infos []UnitInfo) {
+	infos = []UnitInfo{}
+	for _, u := range sys.Units() {
+		if !filter.matches(u) {
+			continue
+		}
+
+		info := UnitInfo{
+			Name:        u.Name(),
+			Description: u.Description(),
+			Load:        u.Loaded(),
+			Active:      u.Active(),
+			Sub:         u.Sub(),
+		}
+		if filter.Status {
+			st := u.Status()
+			st.Log = nil
+			info.Status = &st
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return
+}
+
+// UnitFile is an entry of the list returned by ListUnitFiles
+type UnitFile struct {
+	Name  string
+	Path  string
+	State unit.Enable
+}
+
+// ListUnitFiles returns the definitions of supported unit types found in the unit paths sorted by name,
+// whether the units are loaded or not. Only the definition taking precedence is listed for each name
+func (sys *Daemon) ListUnitFiles() (files []UnitFile, err error) {
+	var paths map[string]string
+	if paths, err = sys.unitFiles(); err != nil {
+		return
+	}
+
+	files = make([]UnitFile, 0, len(paths))
+	for name, path := range paths {
+		files = append(files, UnitFile{
+			Name:  name,
+			Path:  path,
+			State: sys.unitFileState(name, path),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// unitFiles returns paths to the definitions of supported unit types found in the unit paths by name.
+// Only the definition taking precedence is returned for each name. The unit paths are scanned concurrently
+func (sys *Daemon) unitFiles() (files map[string]string, err error) {
+	dirs := sys.Paths()
+
+	found := make([][]string, len(dirs))
+	errs := make([]error, len(dirs))
+
+	var wg sync.WaitGroup
+	for i, dir := range dirs {
+		wg.Add(1)
+		go func(i int, dir string) {
+			defer wg.Done()
+			found[i], errs[i] = pathset(dir)
+		}(i, dir)
+	}
+	wg.Wait()
+
+	files = map[string]string{}
+	for i := range dirs {
+		if err = errs[i]; err != nil {
+			if os.IsNotExist(err) || err == ErrNotDir {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, path := range found[i] {
+			if name := filepath.Base(path); files[name] == "" {
+				files[name] = path
+			}
+		}
+	}
+	return files, nil
+}
+
+// unitFileState returns the enablement state of the unit with name defined at path.
+// Units, which are not loaded, are not created in-memory
+func (sys *Daemon) unitFileState(name, path string) unit.Enable {
+	if u, err := sys.Unit(name); err == nil && u.Path() == path {
+		return u.EnableState()
+	}
+
+	if sys.isMasked(name) || isMaskLinkIn(sys.fsys, path) {
+		return unit.EnableMasked
+	}
+
+	u := NewUnit(sys.newInterface(name))
+	u.name = name
+	u.System = sys
+	u.setPath(path)
+
+	if b, err := sys.readDefinitionFile(path); err == nil {
+		u.define(b)
+	}
+	return u.EnableState()
+}
@@ -0,0 +1,112 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestListUnits(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	for name, contents := range map[string]string{
+		"active.target":    "[Unit]\nDescription=Active",
+		"inactive.service": "[Service]\nType=oneshot\nExecStart=/bin/true",
+		"failed.service":   "[Service]\nType=oneshot\nExecStart=/bin/false",
+	} {
+		_, err := sys.Load(name, strings.NewReader(contents))
+		require.NoError(t, err, name)
+	}
+
+	require.NoError(t, sys.Start("active.target"))
+	require.Error(t, sys.Start("failed.service"))
+
+	names := func(infos []UnitInfo) (names []string) {
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+		return
+	}
+
+	infos := sys.ListUnits(UnitFilter{})
+	assert.Equal(t, []string{"active.target", "failed.service"}, names(infos))
+	assert.Equal(t, UnitInfo{
+		Name:        "active.target",
+		Description: "Active",
+		Load:        unit.Loaded,
+		Active:      unit.Active,
+		Sub:         infos[0].Sub,
+	}, infos[0])
+
+	assert.Equal(t, []string{"active.target", "failed.service", "inactive.service"}, names(sys.ListUnits(UnitFilter{All: true})))
+	assert.Equal(t, []string{"failed.service", "inactive.service"}, names(sys.ListUnits(UnitFilter{Types: []string{"service"}, All: true})))
+	assert.Equal(t, []string{"failed.service"}, names(sys.ListUnits(UnitFilter{States: []string{"FAILED"}})))
+	assert.Equal(t, []string{"inactive.service"}, names(sys.ListUnits(UnitFilter{States: []string{"dead"}})))
+	assert.Empty(t, sys.ListUnits(UnitFilter{States: []string{"not-found"}}))
+
+	infos = sys.ListUnits(UnitFilter{States: []string{"failed"}, Status: true})
+	if assert.Len(t, infos, 1) && assert.NotNil(t, infos[0].Status) {
+		assert.Equal(t, unit.Failed, infos[0].Status.Activation.State)
+		assert.Nil(t, infos[0].Status.Log, "log is not embedded")
+	}
+
+	statuses, errs := sys.Statuses("active.target", "failed.service", "missing.service")
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, unit.Active, statuses["active.target"].Activation.State)
+	assert.Equal(t, unit.Failed, statuses["failed.service"].Activation.State)
+	if assert.Len(t, errs, 1) {
+		assert.ErrorIs(t, errs["missing.service"], ErrNotFound)
+	}
+
+	statuses, errs = sys.Statuses()
+	assert.Len(t, statuses, 3)
+	assert.Nil(t, errs)
+}
+
+func TestListUnitFiles(t *testing.T) {
+	admin, err := ioutil.TempDir("", "list-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "list-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	for name, contents := range map[string]string{
+		"enabled.service":  "[Service]\nExecStart=/bin/true\n[Install]\nWantedBy=test.target",
+		"disabled.service": "[Service]\nExecStart=/bin/true\n[Install]\nWantedBy=test.target",
+		"masked.service":   "[Service]\nExecStart=/bin/true",
+		"static.service":   "[Service]\nExecStart=/bin/true",
+		"test.target":      "",
+		"unsupported.foo":  "",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, name), []byte(contents), 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(admin, "static.service"), []byte("[Service]\nExecStart=/bin/false"), 0644))
+
+	other := New()
+	other.SetPaths(admin, vendor)
+	require.NoError(t, other.Enable("enabled.service"))
+	require.NoError(t, other.Mask("masked.service"))
+
+	sys := New()
+	sys.SetPaths(admin, filepath.Join(admin, "missing"), vendor)
+
+	files, err := sys.ListUnitFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []UnitFile{
+		{"disabled.service", filepath.Join(vendor, "disabled.service"), unit.Disabled},
+		{"enabled.service", filepath.Join(vendor, "enabled.service"), unit.Enabled},
+		{"masked.service", filepath.Join(admin, "masked.service"), unit.EnableMasked},
+		{"static.service", filepath.Join(admin, "static.service"), unit.Static},
+		{"test.target", filepath.Join(vendor, "test.target"), unit.Static},
+	}, files)
+	assert.Empty(t, sys.Units(), "units loaded")
+}
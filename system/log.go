@@ -1,15 +1,30 @@
 package system
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// Maximum number of bytes kept if log buffer
+// Maximum number of bytes of messages kept in log
 const BUFFER_SIZE = 10000
 
+// Maximum number of entries kept in log, which bounds the ring, when the messages are short or empty
+const BUFFER_ENTRIES = 1000
+
+// Path to the file holding the uptime of the system
+const UPTIME_PATH = "/proc/uptime"
+
 type debugHook struct{}
 
 func (h *debugHook) Levels() []log.Level {
@@ -21,73 +36,456 @@ func (h *debugHook) Fire(e *log.Entry) error {
 	return nil
 }
 
-// Log uses log.Logger to write data to embedded bytes.Buffer
-// Keeps up to 10000 bytes of data in-memory
+// entryHook adds the entries logged by the Logger of a Log to the Log
+type entryHook struct {
+	log *Log
+}
+
+func (h *entryHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *entryHook) Fire(e *log.Entry) error {
+	entry := LogEntry{
+		Time:     e.Time,
+		Priority: levelPriorities[e.Level.String()],
+		Message:  e.Message,
+	}
+
+	for k, v := range e.Data {
+		if k == "pid" {
+			if pid, err := strconv.Atoi(fmt.Sprint(v)); err == nil {
+				entry.PID = pid
+				continue
+			}
+		}
+
+		if entry.Fields == nil {
+			entry.Fields = map[string]string{}
+		}
+		entry.Fields[k] = fmt.Sprint(v)
+	}
+
+	h.log.add(entry)
+	return nil
+}
+
+// Formatter rendering entries as text read from a Log
+var textFormatter = &log.TextFormatter{
+	FullTimestamp: true,
+	DisableColors: true,
+}
+
+// Log keeps the structured entries logged by the embedded log.Logger or written to it as text in-memory.
+// Keeps up to 10000 bytes of messages in a ring, all entries are also written to journal as JSON lines, if set.
+// Entries are read by any number of readers concurrently using a LogCursor each
 type Log struct {
 	*log.Logger
-	*bytes.Reader
-	buffer *bytes.Buffer
+
+	// Name of the unit, empty for the log of the manager
+	unit string
+
+	// ID of the boot entries are stamped with
+	boot string
+
+	// Ring of entries, the oldest one is at head
+	ring        []LogEntry
+	head, count int
+
+	// Sequence number of the oldest entry kept, entries are numbered in the order added
+	first int64
+
+	// Number of bytes of messages of entries
+	size int
+
+	// Closed and replaced, whenever an entry is added
+	added chan struct{}
+
+	// Persistent journal of the unit(nil means the log is only kept in-memory)
+	journal io.Writer
+
+	// Called with each entry added, must not block(nil means entries are not forwarded)
+	forward func(LogEntry)
+
+	// Lowest priority of entries kept, entries of lower priorities are discarded
+	maxPriority int
+
+	// Clock entries without a time of their own are stamped by
+	clock Clock
+
+	mutex sync.Mutex
 }
 
 // NewLog returns a new log
 func NewLog() (l *Log) {
-	defer func() {
-		l.Logger = &log.Logger{
-			Out: l,
-			Formatter: &log.TextFormatter{
-				FullTimestamp: true,
-			},
-			Level: log.InfoLevel,
-			Hooks: log.LevelHooks{},
-		}
-		l.Hooks.Add(&debugHook{})
-	}()
-	return &Log{
-		buffer: bytes.NewBuffer(make([]byte, 0, BUFFER_SIZE)),
+	l = &Log{
+		maxPriority: LOG_DEBUG,
+		added:       make(chan struct{}),
+		clock:       RealClock,
 	}
+	l.Logger = &log.Logger{
+		Out:       ioutil.Discard,
+		Formatter: textFormatter,
+		Level:     log.InfoLevel,
+		Hooks:     log.LevelHooks{},
+	}
+	l.Hooks.Add(&entryHook{l})
+	l.Hooks.Add(&debugHook{})
+	return l
 }
 
+// Len returns the number of bytes of messages kept in-memory
 func (l *Log) Len() (n int) {
-	return l.buffer.Len()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.size
 }
 
-func (l *Log) Cap() (n int) {
-	return l.buffer.Cap()
+// Entries returns the entries kept in-memory, oldest first
+func (l *Log) Entries() (entries []LogEntry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries = make([]LogEntry, l.count)
+	for i := range entries {
+		entries[i] = l.at(i)
+	}
+	return
+}
+
+// at returns the i-th oldest entry kept
+func (l *Log) at(i int) LogEntry {
+	return l.ring[(l.head+i)%len(l.ring)]
+}
+
+// setBoot makes l stamp the entries added with the ID of the boot specified
+func (l *Log) setBoot(id string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.boot = id
+}
+
+// MaxPriority returns the lowest priority of entries kept
+func (l *Log) MaxPriority() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.maxPriority
+}
+
+// SetMaxPriority makes l discard the entries of priority lower than p, i.e. greater numerically
+func (l *Log) SetMaxPriority(p int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.maxPriority = p
+}
+
+// Priority returns the lowest priority of the entries logged by the embedded log.Logger
+func (l *Log) Priority() int {
+	return levelPriorities[l.Logger.GetLevel().String()]
+}
+
+// SetPriority makes the embedded log.Logger log the entries of priority p and higher
+func (l *Log) SetPriority(p int) {
+	l.Logger.SetLevel(priorityLevel(p))
 }
 
-func (l *Log) Read(b []byte) (n int, err error) {
-	if l.Reader == nil {
-		l.Reader = bytes.NewReader(l.buffer.Bytes())
+// Write adds the lines of b as entries. Lines written by the logrus text formatter are parsed,
+// other lines are logged at the current time with the informational priority
+func (l *Log) Write(b []byte) (n int, err error) {
+	now := l.clock.Now()
+
+	prev := LogEntry{Time: now, Priority: LOG_INFO}
+	for _, e := range parseLog("", b) {
+		if e.Time.IsZero() {
+			e.Time, e.Priority = prev.Time, prev.Priority
+		}
+		l.add(e)
+		prev = e
+	}
+	return len(b), nil
+}
+
+// add adds e to the entries, discarding the oldest ones, if the capacity is exceeded,
+// and writes it to the journal. Entries of priority lower than the maximum priority of l are discarded
+func (l *Log) add(e LogEntry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if e.Priority > l.maxPriority {
+		return
+	}
+
+	e.Unit = l.unit
+	if e.Boot == "" {
+		e.Boot = l.boot
+	}
+	if e.Monotonic == 0 {
+		e.Monotonic = monotonic(e.Time)
 	}
-	defer func() {
-		if err == nil && l.Reader.Len() == 0 {
-			err = io.EOF
-			l.Reader = nil
+
+	if l.journal != nil {
+		if err := writeEntry(l.journal, e); err != nil {
+			log.Debugf("Error writing to journal: %s", err)
 		}
-	}()
-	return l.Reader.Read(b)
+	}
+	if l.forward != nil {
+		l.forward(e)
+	}
+	l.push(e)
+
+	close(l.added)
+	l.added = make(chan struct{})
 }
 
-func (l *Log) Write(b []byte) (n int, err error) {
-	if l.Len()+len(b) <= l.Cap() {
-		return l.buffer.Write(b)
+// push adds e to the entries, discarding the oldest ones, if the size of the messages
+// or the number of entries exceeds the capacity. The ring grows, when it is full
+func (l *Log) push(e LogEntry) {
+	if l.count == len(l.ring) {
+		ring := make([]LogEntry, 2*len(l.ring)+16)
+		for i := 0; i < l.count; i++ {
+			ring[i] = l.at(i)
+		}
+		l.ring, l.head = ring, 0
 	}
 
-	// Make sure that no 'partial' strings are left in buffer, as the buffer capacity is exceeded
-	defer func() {
-		if err == nil {
-			_, err = l.buffer.ReadString('\n')
+	l.ring[(l.head+l.count)%len(l.ring)] = e
+	l.count++
+	l.size += len(e.Message)
+
+	for l.count > 1 && (l.size > BUFFER_SIZE || l.count > BUFFER_ENTRIES) {
+		l.size -= len(l.ring[l.head].Message)
+		l.ring[l.head] = LogEntry{}
+		l.head = (l.head + 1) % len(l.ring)
+		l.count--
+		l.first++
+	}
+}
+
+// restore puts entries restored from the journal w in front of the entries kept in-memory and makes l write to w
+func (l *Log) restore(entries []LogEntry, w io.Writer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	cur := make([]LogEntry, l.count)
+	for i := range cur {
+		cur[i] = l.at(i)
+	}
+
+	// Keep the entries kept numbered as before, so that cursors are not moved
+	next := l.first + int64(l.count)
+	l.ring, l.head, l.count, l.size = nil, 0, 0, 0
+
+	for _, e := range entries {
+		l.push(e)
+	}
+	for _, e := range cur {
+		l.push(e)
+
+		if err := writeEntry(w, e); err != nil {
+			log.Debugf("Error writing to journal: %s", err)
+		}
+	}
+	l.first = next - int64(l.count)
+	l.journal = w
+}
+
+// LogCursor is the position of a reader of a Log. Each reader uses its own cursor,
+// so that readers do not interfere with each other
+type LogCursor struct {
+	log *Log
+
+	// Sequence number of the next entry read
+	seq int64
+
+	// Rest of the text of the entry being read by Read
+	buf []byte
+}
+
+// Cursor returns a new cursor positioned at the oldest entry kept in l
+func (l *Log) Cursor() *LogCursor {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return &LogCursor{log: l, seq: l.first}
+}
+
+// SeekEnd positions c after the newest entry, so that only entries added afterwards are read
+func (c *LogCursor) SeekEnd() {
+	c.log.mutex.Lock()
+	defer c.log.mutex.Unlock()
+
+	c.seq = c.log.first + int64(c.log.count)
+	c.buf = nil
+}
+
+// Next returns the entry at c and advances c, ok is false if c is at the end.
+// Entries discarded before being read are skipped
+func (c *LogCursor) Next() (e LogEntry, ok bool) {
+	c.log.mutex.Lock()
+	defer c.log.mutex.Unlock()
+
+	return c.next()
+}
+
+func (c *LogCursor) next() (e LogEntry, ok bool) {
+	l := c.log
+	if c.seq < l.first {
+		c.seq = l.first
+	}
+	if c.seq >= l.first+int64(l.count) {
+		return LogEntry{}, false
+	}
+
+	e = l.at(int(c.seq - l.first))
+	c.seq++
+	return e, true
+}
+
+// Follow returns the entry at c and advances c. If c is at the end, Follow blocks until an entry
+// is added or ctx is done, in which case the error of ctx is returned
+func (c *LogCursor) Follow(ctx context.Context) (e LogEntry, err error) {
+	for {
+		c.log.mutex.Lock()
+		e, ok := c.next()
+		added := c.log.added
+		c.log.mutex.Unlock()
+
+		if ok {
+			return e, nil
 		}
-	}()
 
-	if len(b) >= l.Cap() {
-		l.buffer.Reset()
-		return l.buffer.Write(b[len(b)-l.Cap():])
+		select {
+		case <-added:
+		case <-ctx.Done():
+			return LogEntry{}, ctx.Err()
+		}
 	}
+}
+
+// Read reads the entries rendered as text starting at c, io.EOF is returned once c is at the end.
+// Reading may be resumed after io.EOF, once new entries are added
+func (c *LogCursor) Read(b []byte) (n int, err error) {
+	for n < len(b) {
+		if len(c.buf) == 0 {
+			e, ok := c.Next()
+			if !ok {
+				break
+			}
+			c.buf = formatEntry(e)
+		}
+
+		m := copy(b[n:], c.buf)
+		c.buf = c.buf[m:]
+		n += m
+	}
+
+	if n == 0 && len(b) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
 
-	if _, err = l.buffer.Read(make([]byte, len(b)-l.Cap()+l.Len())); err != nil {
-		return 0, err
+// writeEntry writes e to w as a line of JSON
+func writeEntry(w io.Writer, e LogEntry) (err error) {
+	var b []byte
+	if b, err = json.Marshal(e); err != nil {
+		return
 	}
+	_, err = w.Write(append(b, '\n'))
+	return
+}
+
+// decodeEntries decodes the entries of the journal of unit with name specified written by writeEntry.
+// Lines written by the logrus text formatter, as the journal was kept in before, are parsed as well
+func decodeEntries(name string, b []byte) (entries []LogEntry) {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+
+	for s.Scan() {
+		line := s.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var e LogEntry
+		if line[0] != '{' || json.Unmarshal(line, &e) != nil {
+			entries = append(entries, parseLog(name, []byte(s.Text()+"\n"))...)
+			continue
+		}
+		e.Unit = name
+		entries = append(entries, e)
+	}
+	return
+}
 
-	return l.buffer.Write(b)
+// formatEntry renders e as a line written by the logrus text formatter
+func formatEntry(e LogEntry) []byte {
+	data := log.Fields{}
+	for k, v := range e.Fields {
+		data[k] = v
+	}
+	if e.PID != 0 {
+		data["pid"] = e.PID
+	}
+
+	b, err := textFormatter.Format(&log.Entry{
+		Time:    e.Time,
+		Level:   priorityLevel(e.Priority),
+		Message: e.Message,
+		Data:    data,
+	})
+	if err != nil {
+		return []byte(e.Message + "\n")
+	}
+	return b
+}
+
+// priorityLevel returns the logrus level entries of priority p are logged with
+func priorityLevel(p int) log.Level {
+	switch {
+	case p <= LOG_ALERT:
+		return log.PanicLevel
+	case p == LOG_CRIT:
+		return log.FatalLevel
+	case p == LOG_ERR:
+		return log.ErrorLevel
+	case p <= LOG_NOTICE:
+		return log.WarnLevel
+	case p == LOG_INFO:
+		return log.InfoLevel
+	default:
+		return log.DebugLevel
+	}
+}
+
+var (
+	bootTime     time.Time
+	bootTimeOnce sync.Once
+)
+
+// monotonic returns the time elapsed since the system booted until t, measured by the monotonic clock
+func monotonic(t time.Time) time.Duration {
+	bootTimeOnce.Do(func() {
+		now := time.Now()
+		bootTime = now
+
+		b, err := ioutil.ReadFile(UPTIME_PATH)
+		if err != nil {
+			log.Debugf("Error reading uptime: %s", err)
+			return
+		}
+
+		fields := strings.Fields(string(b))
+		if len(fields) == 0 {
+			return
+		}
+		if uptime, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			bootTime = now.Add(-time.Duration(uptime * float64(time.Second)))
+		}
+	})
+	return t.Sub(bootTime)
 }
@@ -2,9 +2,14 @@ package system
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -22,54 +27,222 @@ var lorem = []byte(`
 func TestWrite(t *testing.T) {
 	l := NewLog()
 
-	l.Write(lorem)
-	assert.Equal(t, len(lorem), l.Len(), "l.Len()")
-
-	l.buffer.Reset()
+	n, err := l.Write(lorem)
+	assert.NoError(t, err, "l.Write(lorem)")
+	assert.Equal(t, len(lorem), n, "l.Write(lorem)")
 
-	// []byte to write into buffer
-	var b []byte
+	lines := strings.Split(strings.TrimSpace(string(lorem)), "\n")
+	entries := l.Entries()
+	if assert.Len(t, entries, len(lines)) {
+		assert.Equal(t, strings.TrimSpace(lines[0]), strings.TrimSpace(entries[0].Message))
+		assert.Equal(t, LOG_INFO, entries[0].Priority)
+		assert.False(t, entries[0].Time.IsZero())
+	}
 
 	// Times lorem fits in buffer
-	var n int = BUFFER_SIZE / len(lorem)
-	for i := 0; i <= n; i++ {
-		b = append(b, lorem...)
+	for i := 0; i <= BUFFER_SIZE/len(lorem); i++ {
+		l.Write(lorem)
 	}
+	assert.True(t, l.Len() <= BUFFER_SIZE, "l.Len()")
+	assert.True(t, l.Len() > BUFFER_SIZE-len(lorem), "l.Len()")
+
+	entries = l.Entries()
+	assert.Equal(t, strings.TrimSpace(lines[len(lines)-1]), strings.TrimSpace(entries[len(entries)-1].Message),
+		"oldest entries are discarded")
+}
+
+func TestWriteText(t *testing.T) {
+	l := NewLog()
+
+	l.Write([]byte(`time="2026-10-16T12:00:00Z" level=warning msg="Low memory" pid=42 free=10M` + "\n"))
+
+	entries := l.Entries()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "Low memory", entries[0].Message)
+		assert.Equal(t, LOG_WARNING, entries[0].Priority)
+		assert.Equal(t, 42, entries[0].PID)
+		assert.Equal(t, map[string]string{"free": "10M"}, entries[0].Fields)
+	}
+}
+
+func TestWriteClock(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	sys := New()
+	sys.SetClock(NewFakeClock(now))
+	sys.SetPaths()
 
-	n, err := l.Write(b)
-	assert.NoError(t, err, "l.Write(b)")
-	assert.Equal(t, n, BUFFER_SIZE, "l.Write(b)")
-	assert.Equal(t, l.Cap(), BUFFER_SIZE, "l.Cap()")
-
-	var char byte
-	if (BUFFER_SIZE-len(b))%len(b) == 0 {
-		char = b[0]
-	} else {
-		r := bytes.NewReader(b)
-		for {
-			// Search for first \n and char to next one after that
-			c, err := r.ReadByte()
-			require.NoError(t, err, "r.ReadByte()")
-
-			if c == '\n' {
-				char, _ = r.ReadByte()
-				break
-			}
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	for _, l := range []*Log{sys.Log, u.Log} {
+		l.Write([]byte("Lorem ipsum\n"))
+
+		entries := l.Entries()
+		if assert.NotEmpty(t, entries) {
+			assert.Equal(t, now, entries[len(entries)-1].Time)
 		}
 	}
+}
 
-	assert.Equal(t, char, l.buffer.Bytes()[0])
+func TestLogger(t *testing.T) {
+	l := NewLog()
+	l.unit = "foo.service"
+
+	l.WithFields(log.Fields{"pid": 42, "code": "exited"}).Error("Main process exited")
+
+	entries := l.Entries()
+	require.Len(t, entries, 1)
+
+	e := entries[0]
+	assert.Equal(t, "foo.service", e.Unit)
+	assert.Equal(t, "Main process exited", e.Message)
+	assert.Equal(t, LOG_ERR, e.Priority)
+	assert.Equal(t, 42, e.PID)
+	assert.Equal(t, map[string]string{"code": "exited"}, e.Fields)
+	assert.True(t, e.Monotonic > 0, "monotonic time is set")
+
+	var buf bytes.Buffer
+	require.NoError(t, writeEntry(&buf, e))
+	decoded := decodeEntries("foo.service", buf.Bytes())
+	if assert.Len(t, decoded, 1) {
+		assert.True(t, e.Time.Equal(decoded[0].Time))
+		decoded[0].Time = e.Time
+		assert.Equal(t, e, decoded[0], "entries survive the journal")
+	}
 }
 
 func TestRead(t *testing.T) {
 	l := NewLog()
-	l.buffer = bytes.NewBuffer(lorem)
+	l.Write(lorem)
+	l.Error("failure")
+
+	b, err := ioutil.ReadAll(l.Cursor())
+	assert.NoError(t, err, "first ioutil.ReadAll(l.Cursor())")
+
+	bTest, err := ioutil.ReadAll(l.Cursor())
+	assert.NoError(t, err, "second ioutil.ReadAll(l.Cursor())")
+
+	assert.Equal(t, b, bTest, "ioutil.ReadAll(l.Cursor()) bytes read")
+
+	entries := parseLog("", b)
+	if assert.Len(t, entries, len(l.Entries())) {
+		last := entries[len(entries)-1]
+		assert.Equal(t, "failure", last.Message, "text read is parsed back")
+		assert.Equal(t, LOG_ERR, last.Priority, "text read is parsed back")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	l := NewLog()
+	l.Info("first")
+
+	c1, c2 := l.Cursor(), l.Cursor()
+	l.Info("second")
+
+	e, ok := c1.Next()
+	require.True(t, ok)
+	assert.Equal(t, "first", e.Message)
+
+	// Readers interleaved do not interfere with each other
+	buf := make([]byte, 8)
+	n, err := c2.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 8, n)
+
+	e, ok = c1.Next()
+	require.True(t, ok)
+	assert.Equal(t, "second", e.Message)
+	_, ok = c1.Next()
+	assert.False(t, ok, "c1 is at the end")
+
+	rest, err := ioutil.ReadAll(c2)
+	require.NoError(t, err)
+	entries := parseLog("", append(buf[:n], rest...))
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "second", entries[1].Message)
+	}
+
+	l.Info("third")
+	rest, err = ioutil.ReadAll(c2)
+	require.NoError(t, err)
+	assert.Contains(t, string(rest), "third", "reading is resumed after io.EOF")
+
+	end := l.Cursor()
+	end.SeekEnd()
+	_, ok = end.Next()
+	assert.False(t, ok)
+}
+
+func TestRing(t *testing.T) {
+	l := NewLog()
+	c := l.Cursor()
+
+	msg := strings.Repeat("x", 100)
+	for i := 0; i < 3*BUFFER_SIZE/len(msg); i++ {
+		l.Info(msg + strconv.Itoa(i))
+	}
+	assert.True(t, l.Len() <= BUFFER_SIZE, "l.Len()")
+
+	entries := l.Entries()
+	last := 3*BUFFER_SIZE/len(msg) - 1
+	assert.Equal(t, msg+strconv.Itoa(last), entries[len(entries)-1].Message)
+
+	e, ok := c.Next()
+	require.True(t, ok)
+	assert.Equal(t, entries[0], e, "entries discarded before being read are skipped")
+
+	// Entries with empty messages are discarded as well
+	for i := 0; i < 3*BUFFER_ENTRIES; i++ {
+		l.add(LogEntry{Time: time.Now(), Priority: LOG_INFO})
+	}
+	assert.Len(t, l.Entries(), BUFFER_ENTRIES)
+	assert.Equal(t, 0, l.Len())
+}
+
+func TestFollow(t *testing.T) {
+	l := NewLog()
+	c := l.Cursor()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.Info("followed")
+	}()
 
-	b, err := ioutil.ReadAll(l)
-	assert.NoError(t, err, "first ioutil.ReadAll(l)")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
 
-	bTest, err := ioutil.ReadAll(l)
-	assert.NoError(t, err, "second ioutil.ReadAll(l)")
+	e, err := c.Follow(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "followed", e.Message)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = c.Follow(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
 
-	assert.Equal(t, b, bTest, "ioutil.ReadAll(l) bytes read")
+func TestMaxPriority(t *testing.T) {
+	l := NewLog()
+	assert.Equal(t, LOG_DEBUG, l.MaxPriority())
+	assert.Equal(t, LOG_INFO, l.Priority())
+
+	l.Debug("hidden")
+	l.Info("info")
+	assert.Len(t, l.Entries(), 1, "debug messages are not logged by default")
+
+	l.SetPriority(LOG_DEBUG)
+	l.Debug("debug")
+	assert.Len(t, l.Entries(), 2)
+
+	l.SetMaxPriority(LOG_WARNING)
+	l.Info("dropped")
+	l.Write([]byte("<6>dropped\n"))
+	l.Warn("warning")
+
+	entries := l.Entries()
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "warning", entries[2].Message)
+	}
 }
@@ -0,0 +1,308 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Priorities of log entries as defined by syslog(3), lower is more important
+const (
+	LOG_EMERG = iota
+	LOG_ALERT
+	LOG_CRIT
+	LOG_ERR
+	LOG_WARNING
+	LOG_NOTICE
+	LOG_INFO
+	LOG_DEBUG
+)
+
+// Priorities of entries logged with the logrus levels
+var levelPriorities = map[string]int{
+	log.PanicLevel.String(): LOG_EMERG,
+	log.FatalLevel.String(): LOG_CRIT,
+	log.ErrorLevel.String(): LOG_ERR,
+	log.WarnLevel.String():  LOG_WARNING,
+	log.InfoLevel.String():  LOG_INFO,
+	log.DebugLevel.String(): LOG_DEBUG,
+	log.TraceLevel.String(): LOG_DEBUG,
+}
+
+// ParsePriority parses a priority specified either by its name, e.g. "err", or number, e.g. "3"
+func ParsePriority(s string) (p int, err error) {
+	return unit.ParsePriority(s)
+}
+
+// PriorityName returns the name of priority p, e.g. "err"
+func PriorityName(p int) string {
+	return unit.PriorityName(p)
+}
+
+// LogQuery specifies the log entries returned by Logs
+type LogQuery struct {
+	// Names or shell patterns of units to return the entries of,
+	// entries of all units and the manager itself are returned if empty
+	Units []string
+
+	// Entries logged before Since or after Until are omitted, zero values mean no limit
+	Since, Until time.Time
+
+	// Name or number of the lowest priority returned, e.g. "err" or "3", all entries are returned if empty
+	Priority string
+
+	// Number of most recent entries returned(0 means all)
+	Lines int
+
+	// Whether to return the newest entries first
+	Reverse bool
+
+	// Whether to return the entries of the kernel only, Units are ignored if set
+	Kernel bool
+
+	// Whether to return the entries of the audit stream only, Units are ignored if set
+	Audit bool
+
+	// ID of the boot or its offset to return the entries of: 0 is the current boot, -1 the previous one, etc.
+	// and 1 is the first boot, 2 the second one, etc. Entries of all boots are returned if empty
+	Boot string
+}
+
+// LogEntry is an entry of the log of a unit or the manager
+type LogEntry struct {
+	// Name of the unit, empty for entries of the manager
+	Unit string `json:",omitempty"`
+
+	// Realtime of the entry
+	Time time.Time
+
+	// Time elapsed since the system booted, measured by the monotonic clock
+	Monotonic time.Duration `json:",omitempty"`
+
+	// PID of the process the entry originates from, 0 if unknown
+	PID int `json:",omitempty"`
+
+	Priority int
+	Message  string
+
+	// Additional fields of the entry
+	Fields map[string]string `json:",omitempty"`
+
+	// Whether the entry originates from the kernel log buffer
+	Kernel bool `json:",omitempty"`
+
+	// Whether the entry is a record of the audit stream, see Daemon.Audit
+	Audit bool `json:",omitempty"`
+
+	// ID of the boot the entry was logged during, empty for entries logged before boots were tracked
+	Boot string `json:",omitempty"`
+}
+
+// Identifier returns the name of the unit, KERNEL_IDENTIFIER, AUDIT_IDENTIFIER or MANAGER_IDENTIFIER,
+// depending on where e originates from
+func (e LogEntry) Identifier() string {
+	switch {
+	case e.Kernel:
+		return KERNEL_IDENTIFIER
+	case e.Audit:
+		return AUDIT_IDENTIFIER
+	case e.Unit != "":
+		return e.Unit
+	default:
+		return MANAGER_IDENTIFIER
+	}
+}
+
+// Logs returns the entries of the logs of units matching q ordered by time, oldest first, unless reversed.
+// Logs of units are read from the journal, if sys has one, or from memory otherwise
+func (sys *Daemon) Logs(q LogQuery) (entries []LogEntry, err error) {
+	log.WithField("query", q).Debugf("sys.Logs")
+
+	var match func(LogEntry) bool
+	if match, err = sys.logMatcher(q); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if q.Kernel || q.Audit {
+		for _, e := range sys.Log.Entries() {
+			if q.Kernel && e.Kernel || q.Audit && e.Audit {
+				entries = append(entries, e)
+			}
+		}
+	} else if len(q.Units) == 0 {
+		entries = append(entries, sys.Log.Entries()...)
+
+		for _, u := range sys.Units() {
+			names = append(names, u.Name())
+		}
+	} else if names, err = sys.Expand(logUnitPatterns(q.Units)...); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		var unitEntries []LogEntry
+		if unitEntries, err = sys.readLog(name); err != nil {
+			return nil, err
+		}
+		entries = append(entries, unitEntries...)
+	}
+
+	filtered := entries[:0]
+	for _, e := range entries {
+		if match(e) {
+			filtered = append(filtered, e)
+		}
+	}
+	entries = filtered
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	if q.Lines > 0 && len(entries) > q.Lines {
+		entries = entries[len(entries)-q.Lines:]
+	}
+
+	if q.Reverse {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	return entries, nil
+}
+
+// logUnitPatterns returns the names and patterns of units specified in a LogQuery,
+// names without a suffix are names of services
+func logUnitPatterns(units []string) []string {
+	patterns := make([]string, len(units))
+	for i, name := range units {
+		if filepath.Ext(name) == "" && !isPattern(name) {
+			name += ".service"
+		}
+		patterns[i] = name
+	}
+	return patterns
+}
+
+// logMatcher returns a function, which returns whether an entry is of the priority, boot and time range
+// specified by q. Units, Kernel and Audit of q are not matched
+func (sys *Daemon) logMatcher(q LogQuery) (match func(LogEntry) bool, err error) {
+	max := LOG_DEBUG
+	if q.Priority != "" {
+		if max, err = ParsePriority(q.Priority); err != nil {
+			return nil, err
+		}
+	}
+
+	var boot string
+	if q.Boot != "" {
+		if boot, err = sys.resolveBoot(q.Boot); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(e LogEntry) bool {
+		return e.Priority <= max && (boot == "" || e.Boot == boot) &&
+			(q.Since.IsZero() || !e.Time.Before(q.Since)) &&
+			(q.Until.IsZero() || !e.Time.After(q.Until))
+	}, nil
+}
+
+// readLog returns the entries of the log of the unit with name specified from the journal or,
+// if sys has no journal, from memory. Missing log is not an error
+func (sys *Daemon) readLog(name string) (entries []LogEntry, err error) {
+	if j := sys.Journal(); j != nil {
+		return j.Entries(name)
+	}
+
+	u, err := sys.Unit(name)
+	if err != nil {
+		return nil, nil
+	}
+	return u.Log.Entries(), nil
+}
+
+// parseLog parses the entries of the log of unit with name specified written by the logrus text formatter.
+// Lines, which are not entries, are returned as entries with the time and priority of the preceding one
+func parseLog(name string, b []byte) (entries []LogEntry) {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+
+	prev := LogEntry{Unit: name, Priority: LOG_INFO}
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+
+		e := prev
+		e.Message = line
+
+		e.Fields = nil
+
+		fields := parseFields(line)
+		if t, err := time.Parse(time.RFC3339, fields["time"]); err == nil {
+			e.Time = t
+			e.Message = fields["msg"]
+			if p, ok := levelPriorities[fields["level"]]; ok {
+				e.Priority = p
+			}
+			e.PID = 0
+			if pid, err := strconv.Atoi(fields["pid"]); err == nil {
+				e.PID = pid
+			}
+
+			for _, k := range []string{"time", "level", "msg", "pid"} {
+				delete(fields, k)
+			}
+			if len(fields) > 0 {
+				e.Fields = fields
+			}
+		}
+
+		entries = append(entries, e)
+		prev = e
+	}
+	return
+}
+
+// parseFields parses the key=value pairs of a line written by the logrus text formatter,
+// values may be quoted
+func parseFields(line string) (fields map[string]string) {
+	fields = map[string]string{}
+
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		i := strings.IndexByte(line, '=')
+		if i <= 0 || strings.ContainsAny(line[:i], " \"") {
+			return
+		}
+		key := line[:i]
+		line = line[i+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return
+			}
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return
+			}
+			line = line[len(quoted):]
+		} else if i = strings.IndexByte(line, ' '); i >= 0 {
+			value, line = line[:i], line[i:]
+		} else {
+			value, line = line, ""
+		}
+		fields[key] = value
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package system
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriority(t *testing.T) {
+	for s, expected := range map[string]int{
+		"emerg":   LOG_EMERG,
+		"err":     LOG_ERR,
+		"warning": LOG_WARNING,
+		"6":       LOG_INFO,
+		"7":       LOG_DEBUG,
+	} {
+		p, err := ParsePriority(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, p, s)
+			assert.Equal(t, expected, must(ParsePriority(PriorityName(p))), s)
+		}
+	}
+
+	for _, s := range []string{"", "error", "8", "-1"} {
+		_, err := ParsePriority(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func must(p int, err error) int {
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func TestParseLog(t *testing.T) {
+	entries := parseLog("foo.service", []byte(
+		`time="2026-10-16T12:00:00Z" level=info msg="Starting..."`+"\n"+
+			`time="2026-10-16T12:00:01Z" level=error msg="Error starting: \"exit status 1\"" pid=42`+"\n"+
+			"continued\n"))
+
+	t0 := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, []LogEntry{
+		{Unit: "foo.service", Time: t0, Priority: LOG_INFO, Message: "Starting..."},
+		{Unit: "foo.service", Time: t0.Add(time.Second), PID: 42, Priority: LOG_ERR, Message: `Error starting: "exit status 1"`},
+		{Unit: "foo.service", Time: t0.Add(time.Second), PID: 42, Priority: LOG_ERR, Message: "continued"},
+	}, entries)
+}
+
+func TestLogs(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	foo, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	bar, err := sys.Load("bar.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i, l := range []*Log{foo.Log, bar.Log, foo.Log, bar.Log} {
+		l.WithTime(t0.Add(time.Duration(i)*time.Minute)).Infof("entry %d", i)
+	}
+	bar.Log.WithTime(t0.Add(10 * time.Minute)).Error("failure")
+
+	messages := func(entries []LogEntry) (msgs []string) {
+		for _, e := range entries {
+			msgs = append(msgs, e.Unit+": "+e.Message)
+		}
+		return
+	}
+
+	entries, err := sys.Logs(LogQuery{Units: []string{"foo", "bar.service"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"foo.service: entry 0",
+		"bar.service: entry 1",
+		"foo.service: entry 2",
+		"bar.service: entry 3",
+		"bar.service: failure",
+	}, messages(entries), "entries are ordered by time")
+
+	entries, err = sys.Logs(LogQuery{Units: []string{"*.service"}, Lines: 2, Reverse: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar.service: failure", "bar.service: entry 3"}, messages(entries))
+
+	entries, err = sys.Logs(LogQuery{Units: []string{"foo.service"}, Since: t0.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo.service: entry 2"}, messages(entries))
+
+	entries, err = sys.Logs(LogQuery{Units: []string{"bar.service"}, Until: t0.Add(2 * time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar.service: entry 1"}, messages(entries))
+
+	entries, err = sys.Logs(LogQuery{Priority: "err"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar.service: failure"}, messages(entries))
+
+	entries, err = sys.Logs(LogQuery{Units: []string{"missing.service"}})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = sys.Logs(LogQuery{Priority: "loud"})
+	assert.Error(t, err)
+}
+
+func TestLogLevels(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("quiet.service", strings.NewReader("[Service]\nExecStart=/bin/echo quiet\nLogLevelMax=err\nLogLevel=debug"))
+	require.NoError(t, err)
+	assert.Equal(t, LOG_ERR, u.Log.MaxPriority())
+	assert.Equal(t, LOG_DEBUG, u.Log.Priority())
+
+	u.Log.Println("dropped")
+	u.Log.Error("failed")
+	assert.Len(t, u.Log.Entries(), 1)
+
+	require.NoError(t, sys.SetProperty("quiet.service", "LogLevelMax", "info", false))
+	assert.Equal(t, LOG_INFO, u.Log.MaxPriority())
+	u.Log.Debug("dropped")
+	assert.Len(t, u.Log.Entries(), 2, "the Set message is kept")
+
+	require.NoError(t, sys.SetProperty("quiet.service", "LogLevel", "err", false))
+	assert.Equal(t, LOG_ERR, u.Log.Priority())
+
+	u, err = sys.Load("default.service", strings.NewReader("[Service]\nExecStart=/bin/echo default"))
+	require.NoError(t, err)
+	assert.Equal(t, LOG_DEBUG, u.Log.MaxPriority())
+	assert.Equal(t, LOG_INFO, u.Log.Priority())
+}
@@ -0,0 +1,93 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Path unit definitions get symlinked to in order to mask them
+const MASK_TARGET = "/dev/null"
+
+// isMaskLink returns whether path is a symlink to MASK_TARGET
+func isMaskLink(path string) bool {
+	return isMaskLinkIn(osFS{}, path)
+}
+
+// isMaskLinkIn returns whether path in fsys is a symlink to MASK_TARGET
+func isMaskLinkIn(fsys fileSystem, path string) bool {
+	target, err := fsys.Readlink(path)
+	return err == nil && target == MASK_TARGET
+}
+
+// isMasked returns whether the unit named name has been masked by Mask
+func (sys *Daemon) isMasked(name string) bool {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.masked[name]
+}
+
+// Mask masks units specified by names by symlinking their definitions in the first
+// of the unit paths, which is not a generator output directory, to MASK_TARGET.
+// Masked units can not be started.
+func (sys *Daemon) Mask(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Mask")
+
+	for _, name := range names {
+		if !Supported(name) {
+			return ErrUnknownType
+		}
+
+		if dir, err := sys.configDir(); err == nil {
+			path := filepath.Join(dir, name)
+			if err = os.Symlink(MASK_TARGET, path); err != nil {
+				if !os.IsExist(err) || !isMaskLink(path) {
+					return err
+				}
+			}
+		}
+
+		sys.mutex.Lock()
+		sys.masked[name] = true
+		sys.mutex.Unlock()
+
+		if u, err := sys.Unit(name); err == nil {
+			u.setLoad(unit.Masked)
+		}
+	}
+	return nil
+}
+
+// Unmask removes the masks of units specified by names created by Mask or otherwise
+// and reloads the definitions of units, which have been loaded
+func (sys *Daemon) Unmask(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Unmask")
+
+	for _, name := range names {
+		for _, dir := range sys.Paths() {
+			path := filepath.Join(dir, name)
+			if !isMaskLink(path) {
+				continue
+			}
+
+			if err = os.Remove(path); err != nil {
+				return
+			}
+		}
+
+		sys.mutex.Lock()
+		delete(sys.masked, name)
+		sys.mutex.Unlock()
+
+		if u, err := sys.Unit(name); err == nil && u.IsMasked() {
+			if err = sys.reread(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestMask(t *testing.T) {
+	admin, err := ioutil.TempDir("", "mask-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "mask-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "test.service"), []byte(`[Service]
+ExecStart=/bin/true`), 0644))
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	u, err := sys.Get("test.service")
+	require.NoError(t, err)
+	assert.True(t, u.IsLoaded())
+
+	require.NoError(t, sys.Mask("test.service"))
+	assert.True(t, u.IsMasked())
+	assert.True(t, isMaskLink(filepath.Join(admin, "test.service")))
+	assert.ErrorIs(t, sys.Start("test.service"), ErrMasked)
+
+	// Mask symlinks persist
+	other := New()
+	other.SetPaths(admin, vendor)
+
+	ou, err := other.Get("test.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Masked, ou.Loaded())
+
+	require.NoError(t, sys.Unmask("test.service"))
+	assert.True(t, u.IsLoaded())
+	assert.Equal(t, filepath.Join(vendor, "test.service"), u.Path())
+
+	_, err = os.Lstat(filepath.Join(admin, "test.service"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMaskGenerated(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "mask-generated")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	admin := filepath.Join(tmp, "admin")
+	vendor := filepath.Join(tmp, "vendor")
+	generators := filepath.Join(tmp, "generators")
+	out := filepath.Join(tmp, "generator")
+
+	for _, dir := range []string{admin, vendor, generators} {
+		require.NoError(t, os.Mkdir(dir, 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "test.service"), []byte(`[Service]
+ExecStart=/bin/true`), 0644))
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+	sys.SetGeneratorPaths(generators)
+	sys.SetGeneratorDir(out)
+	require.NoError(t, sys.RunGenerators())
+
+	require.NoError(t, sys.Mask("test.service"))
+	assert.True(t, isMaskLink(filepath.Join(admin, "test.service")))
+
+	// Generator output directories get recreated on reload
+	require.NoError(t, sys.ReloadDaemon())
+	assert.True(t, isMaskLink(filepath.Join(admin, "test.service")))
+
+	u, err := sys.Get("test.service")
+	require.NoError(t, err)
+	assert.True(t, u.IsMasked())
+}
+
+func TestMaskConcurrent(t *testing.T) {
+	admin, err := ioutil.TempDir("", "mask-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "mask-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	for _, name := range []string{"foo.service", "bar.service"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, name), []byte("[Service]\nExecStart=/bin/true"), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, sys.Mask("foo.service"))
+			assert.NoError(t, sys.Unmask("foo.service"))
+			sys.SetPaths(admin, vendor)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, err := sys.Get("bar.service")
+		assert.NoError(t, err)
+		sys.ListUnitFiles()
+		sys.Paths()
+	}
+	<-done
+}
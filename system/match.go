@@ -0,0 +1,70 @@
+package system
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// isPattern returns whether name is a shell pattern
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, `*?[\`)
+}
+
+// Match returns names of the units held in-memory and the unit files found in the unit paths,
+// which match the shell pattern specified, sorted by name. The pattern syntax is the one of path.Match
+func (sys *Daemon) Match(pattern string) (names []string, err error) {
+	log.WithField("pattern", pattern).Debugf("sys.Match")
+
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+
+	var files map[string]string
+	if files, err = sys.unitFiles(); err != nil {
+		return
+	}
+
+	candidates := make(map[string]bool, len(files))
+	for name := range files {
+		candidates[name] = true
+	}
+	for _, u := range sys.Units() {
+		candidates[u.Name()] = true
+	}
+
+	names = []string{}
+	for name := range candidates {
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Expand returns names with the shell patterns among them replaced by the names of units they match,
+// see Match. Duplicates are dropped
+func (sys *Daemon) Expand(names ...string) (expanded []string, err error) {
+	seen := map[string]bool{}
+	expanded = make([]string, 0, len(names))
+
+	for _, name := range names {
+		matches := []string{name}
+		if isPattern(name) {
+			if matches, err = sys.Match(name); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				expanded = append(expanded, match)
+			}
+		}
+	}
+	return expanded, nil
+}
@@ -0,0 +1,47 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "match-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"web-1.service", "web-2.service", "db.service", "web.target"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("[Service]\nExecStart=/bin/true"), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	_, err = sys.Load("web-3.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	names, err := sys.Match("web-*.service")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-1.service", "web-2.service", "web-3.service"}, names)
+
+	names, err = sys.Match("*.target")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web.target"}, names)
+
+	names, err = sys.Match("*.timer")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	_, err = sys.Match("web-[.service")
+	assert.Error(t, err)
+
+	names, err = sys.Expand("db.service", "web-?.service", "web-1.service", "missing.service")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db.service", "web-1.service", "web-2.service", "web-3.service", "missing.service"}, names)
+}
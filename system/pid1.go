@@ -0,0 +1,240 @@
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Interval between passes of the zombie reaper in init mode
+const REAP_INTERVAL = time.Second
+
+// Path procfs is mounted at
+const PROC_PATH = "/proc"
+
+// First real-time signal as seen by programs linked against glibc
+const SIGRTMIN = syscall.Signal(34)
+
+// Targets isolated on SIGRTMIN+n in init mode
+var rtTargets = []string{
+	DEFAULT_TARGET,
+	RESCUE_TARGET,
+	"emergency.target",
+}
+
+// Shutdowns performed on SIGRTMIN+3..6 in init mode
+var rtShutdowns = []ShutdownKind{Halt, Poweroff, Reboot, Kexec}
+
+// Arguments to reboot(2) used on SIGRTMIN+13..16 in init mode
+var rtReboots = []int{
+	syscall.LINUX_REBOOT_CMD_HALT,
+	syscall.LINUX_REBOOT_CMD_POWER_OFF,
+	syscall.LINUX_REBOOT_CMD_RESTART,
+	syscall.LINUX_REBOOT_CMD_KEXEC,
+}
+
+// zombies returns PIDs of zombie processes found in proc, parent of which is ppid
+func zombies(proc string, ppid int) (pids map[int]bool) {
+	pids = map[int]bool{}
+
+	dirs, err := ioutil.ReadDir(proc)
+	if err != nil {
+		return
+	}
+
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(filepath.Join(proc, dir.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+
+		// Format is 'pid (comm) state ppid ...', comm may contain anything
+		i := bytes.LastIndexByte(b, ')')
+		if i < 0 {
+			continue
+		}
+
+		fields := bytes.Fields(b[i+1:])
+		if len(fields) < 2 || string(fields[0]) != "Z" {
+			continue
+		}
+
+		if parent, err := strconv.Atoi(string(fields[1])); err == nil && parent == ppid {
+			pids[pid] = true
+		}
+	}
+	return
+}
+
+// reaper reaps orphaned processes reparented to the daemon.
+// Zombies only get reaped if they were found by the previous pass as well,
+// so that processes waited for by the daemon itself(e.g. main processes of services)
+// get reaped by their waiters
+type reaper struct {
+	proc  string
+	seen  map[int]bool
+	mutex sync.Mutex
+}
+
+// reap reaps the zombies found by the previous pass and returns the PIDs of those reaped
+func (r *reaper) reap() (reaped []int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	found := zombies(r.proc, os.Getpid())
+	for pid := range found {
+		if !r.seen[pid] {
+			continue
+		}
+
+		var status syscall.WaitStatus
+		if wpid, err := syscall.Wait4(pid, &status, syscall.WNOHANG, nil); err == nil && wpid == pid {
+			log.WithFields(log.Fields{
+				"pid":    pid,
+				"status": status.ExitStatus(),
+			}).Debugf("Reaped orphaned process")
+
+			reaped = append(reaped, pid)
+			delete(found, pid)
+		}
+	}
+	r.seen = found
+	return
+}
+
+// InitMode makes sys act as the init process: orphaned processes get reaped and signals
+// get handled like systemd handles them. SIGPIPE is ignored.
+// Returns a function, which restores the default signal handling
+func (sys *Daemon) InitMode() (stop func()) {
+	log.Debugf("sys.InitMode")
+
+	signal.Ignore(syscall.SIGPIPE)
+
+	sigs := []os.Signal{
+		syscall.SIGCHLD,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+		syscall.SIGHUP,
+		syscall.SIGUSR1,
+		syscall.SIGUSR2,
+	}
+	for i := 0; i <= 23; i++ {
+		sigs = append(sigs, SIGRTMIN+syscall.Signal(i))
+	}
+
+	ch := make(chan os.Signal, 32)
+	signal.Notify(ch, sigs...)
+
+	r := &reaper{proc: PROC_PATH}
+	ticker := time.NewTicker(REAP_INTERVAL)
+	done := make(chan struct{})
+
+	// The watchdog is pinged by the main loop, so that the system gets rebooted, if it hangs
+	var (
+		pinger *time.Ticker
+		pings  <-chan time.Time
+	)
+	if interval := sys.armWatchdog(); interval > 0 {
+		pinger = time.NewTicker(interval)
+		pings = pinger.C
+	}
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.reap()
+			case <-pings:
+				sys.pingWatchdog()
+			case sig := <-ch:
+				if sig == syscall.SIGCHLD {
+					r.reap()
+					continue
+				}
+				go sys.handleSignal(sig.(syscall.Signal))
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		if pinger != nil {
+			pinger.Stop()
+		}
+		sys.disarmWatchdog()
+		signal.Stop(ch)
+		signal.Reset(syscall.SIGPIPE)
+		close(done)
+	}
+}
+
+// handleSignal performs the action associated with sig in init mode
+func (sys *Daemon) handleSignal(sig syscall.Signal) {
+	e := log.WithField("signal", sig)
+	e.Debugf("sys.handleSignal")
+
+	var err error
+	switch n := int(sig - SIGRTMIN); {
+	case sig == syscall.SIGINT, sig == syscall.SIGTERM:
+		// ctrl-alt-del.target is an alias of reboot.target
+		err = sys.ShutdownSystem(Reboot)
+
+	case sig == syscall.SIGHUP:
+		err = sys.ReloadDaemon()
+
+	case sig == syscall.SIGUSR1:
+		e.Info("No bus to reconnect to, ignoring")
+
+	case sig == syscall.SIGUSR2:
+		buf := &bytes.Buffer{}
+		if err = sys.Serialize(buf); err == nil {
+			e.Infof("State dump:\n%s", buf)
+		}
+
+	case n >= 0 && n <= 2:
+		target := rtTargets[n]
+		if n == 0 {
+			target = sys.BootTarget()
+		}
+		err = sys.Isolate(target)
+
+	case n >= 3 && n <= 6:
+		err = sys.ShutdownSystem(rtShutdowns[n-3])
+
+	case n >= 13 && n <= 16:
+		if os.Getpid() != 1 {
+			e.Warn("Not running as PID 1, refusing to reboot immediately")
+			return
+		}
+		syscall.Sync()
+		err = syscall.Reboot(rtReboots[n-13])
+
+	case n == 22:
+		log.SetLevel(log.DebugLevel)
+
+	case n == 23:
+		log.SetLevel(log.InfoLevel)
+
+	default:
+		e.Debug("Signal ignored")
+	}
+
+	if err != nil {
+		e.Errorf("Error handling signal: %s", err)
+	}
+}
@@ -0,0 +1,34 @@
+package system
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaper(t *testing.T) {
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Start())
+	pid := cmd.Process.Pid
+
+	// Wait for the process to become a zombie
+	for i := 0; i < 100 && !zombies(PROC_PATH, os.Getpid())[pid]; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, zombies(PROC_PATH, os.Getpid())[pid], "process did not become a zombie")
+
+	r := &reaper{proc: PROC_PATH}
+
+	// Zombies get reaped on the second pass only
+	assert.NotContains(t, r.reap(), pid)
+	assert.Contains(t, r.reap(), pid)
+
+	_, err := os.Stat(filepath.Join(PROC_PATH, strconv.Itoa(pid)))
+	assert.True(t, os.IsNotExist(err))
+}
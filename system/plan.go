@@ -0,0 +1,108 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Plan describes jobs a transaction would execute, in the order they would get dispatched
+type Plan []PlannedJob
+
+// PlannedJob describes a single job of a Plan
+type PlannedJob struct {
+	// Name of the unit the job is for
+	Unit string `json:"Unit"`
+
+	// Type of the job
+	Job string `json:"Job"`
+
+	// Whether the unit was explicitly requested
+	Requested bool `json:"Requested"`
+
+	// Whether the job would be skipped, as the unit is in the desired state already
+	Redundant bool `json:"Redundant"`
+
+	// Names of units, jobs of which the job requires or wants
+	Requires []string `json:"Requires,omitempty"`
+	Wants    []string `json:"Wants,omitempty"`
+
+	// Names of units, jobs of which must finish before the job gets run
+	After []string `json:"After,omitempty"`
+}
+
+func (p Plan) String() string {
+	lines := make([]string, 0, len(p))
+	for _, j := range p {
+		line := fmt.Sprintf("%s %s", j.Job, j.Unit)
+		if j.Requested {
+			line += " (requested)"
+		}
+		if j.Redundant {
+			line += " (redundant)"
+		}
+
+		for _, deps := range []struct {
+			name  string
+			units []string
+		}{
+			{"requires", j.Requires},
+			{"wants", j.Wants},
+			{"after", j.After},
+		} {
+			if len(deps.units) > 0 {
+				line += fmt.Sprintf("\n\t%s: %s", deps.name, strings.Join(deps.units, ", "))
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PlanStart builds and validates a start transaction for units specified by names
+// and returns a Plan of it without running anything
+func (sys *Daemon) PlanStart(names ...string) (p Plan, err error) {
+	log.WithField("names", names).Debugf("sys.PlanStart")
+
+	var tr *transaction
+	if tr, err = sys.newTransaction(start, names); err != nil {
+		return
+	}
+
+	var ordering []*job
+	if ordering, err = tr.prepare(); err != nil {
+		return
+	}
+
+	requested := map[*Unit]bool{}
+	for _, name := range names {
+		if u, err := sys.Unit(name); err == nil {
+			requested[u] = true
+		}
+	}
+
+	p = make(Plan, 0, len(ordering))
+	for _, j := range ordering {
+		p = append(p, PlannedJob{
+			Unit:      j.unit.Name(),
+			Job:       fmt.Sprint(j.typ),
+			Requested: requested[j.unit],
+			Redundant: j.IsRedundant(),
+			Requires:  j.requires.names(),
+			Wants:     j.wants.names(),
+			After:     j.after.names(),
+		})
+	}
+	return
+}
+
+// names returns sorted names of units of jobs in s
+func (s set) names() (names []string) {
+	for j := range s {
+		names = append(names, j.unit.Name())
+	}
+	sort.Strings(names)
+	return
+}
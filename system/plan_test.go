@@ -0,0 +1,56 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestPlanStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mocks := map[string]*mockUnit{
+		"a": newMock(ctrl),
+		"b": newMock(ctrl),
+	}
+
+	empty(mocks["a"], "wants", "before", "conflicts", "after", "requires")
+	empty(mocks["b"], "wants", "before", "conflicts")
+
+	mocks["b"].MockInterface.EXPECT().After().Return([]string{"a"}).Times(1)
+	mocks["b"].MockInterface.EXPECT().Requires().Return([]string{"a"}).Times(1)
+
+	mocks["a"].MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+	mocks["b"].MockInterface.EXPECT().Active().Return(unit.Inactive).AnyTimes()
+
+	sys := New()
+	for name, mock := range mocks {
+		u, err := sys.Supervise(name, mock)
+		require.NoError(t, err)
+
+		u.load = unit.Loaded
+	}
+
+	p, err := sys.PlanStart("b")
+	require.NoError(t, err)
+	require.Len(t, p, 2)
+
+	assert.Equal(t, "a", p[0].Unit)
+	assert.False(t, p[0].Requested, "a requested")
+	assert.True(t, p[0].Redundant, "a redundant")
+
+	assert.Equal(t, "b", p[1].Unit)
+	assert.True(t, p[1].Requested, "b requested")
+	assert.False(t, p[1].Redundant, "b redundant")
+	assert.Equal(t, []string{"a"}, p[1].Requires)
+	assert.Equal(t, []string{"a"}, p[1].After)
+
+	for name := range mocks {
+		u, _ := sys.Unit(name)
+		assert.Nil(t, u.job, "job of %s", name)
+	}
+}
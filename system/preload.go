@@ -0,0 +1,117 @@
+package system
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Default number of workers definitions are read and parsed by on Preload
+const DEFAULT_PRELOAD_WORKERS = 8
+
+// preloaded is a definition read and parsed by a worker of Preload
+type preloaded struct {
+	name, path string
+
+	// Definition read, specifiers expanded
+	b []byte
+
+	// Interface defined by b and the error returned by parsing b, v is nil, if the definition could not be read
+	v   unit.Interface
+	err error
+}
+
+// isTemplate reports whether name is a name of a template unit, e.g. foo@.service
+func isTemplate(name string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "@")
+}
+
+// Preload loads the units found in the unit paths, which are neither loaded, nor masked, nor templates.
+// Definitions are read and parsed concurrently by the number of workers specified, the units are created
+// as they are parsed. Errors in definitions are logged by the units, the error returned is non-nil
+// only if the unit paths could not be read
+func (sys *Daemon) Preload(workers int) (err error) {
+	log.WithField("workers", workers).Debug("sys.Preload")
+
+	var files map[string]string
+	if files, err = sys.unitFiles(); err != nil {
+		return
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+
+	sys.loadMutex.Lock()
+	defer sys.loadMutex.Unlock()
+
+	queue := make(chan preloaded, len(files))
+	for name, path := range files {
+		if isTemplate(name) || sys.isMasked(name) {
+			continue
+		}
+		if u, err := sys.Unit(name); err == nil && u.IsLoaded() {
+			continue
+		}
+		queue <- preloaded{name: name, path: path}
+	}
+	close(queue)
+
+	results := make(chan preloaded)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range queue {
+				results <- sys.preload(p)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for p := range results {
+		sys.register(p)
+	}
+	return nil
+}
+
+// preload reads and parses the definition of p. Definitions, which are masked or can not be read,
+// are left to be loaded by load
+func (sys *Daemon) preload(p preloaded) preloaded {
+	if info, err := sys.fsys.Stat(p.path); err != nil || info.IsDir() || isMaskLinkIn(sys.fsys, p.path) {
+		return p
+	}
+
+	b, err := sys.readDefinitionFile(p.path)
+	if err != nil {
+		return p
+	}
+
+	p.b = expandSpecifiers(b, p.name)
+	p.v = sys.newInterface(p.name)
+	p.err = p.v.Define(bytes.NewReader(p.b))
+	return p
+}
+
+// register creates the unit defined by p. If p was not parsed or the unit already exists, it is loaded by load instead.
+// sys.loadMutex must be held
+func (sys *Daemon) register(p preloaded) {
+	if _, err := sys.Unit(p.name); err == nil || p.v == nil {
+		sys.load(p.name)
+		return
+	}
+
+	u := sys.newUnit(p.name, p.v)
+	u.setPath(p.path)
+	sys.units.set(u, p.path)
+	u.defined(p.b, p.err)
+}
@@ -0,0 +1,86 @@
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestPreload(t *testing.T) {
+	admin, err := ioutil.TempDir("", "preload-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "preload-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	for i := 0; i < 32; i++ {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, fmt.Sprintf("foo%d.service", i)),
+			[]byte("[Service]\nExecStart=/bin/sleep "+fmt.Sprint(i)), 0644))
+	}
+	for name, contents := range map[string]string{
+		"foo0.service":   "[Unit]\nDescription=Overridden\n[Service]\nExecStart=/bin/true",
+		"bad.service":    "[Service]\nType=unknown",
+		"bar@.service":   "[Service]\nExecStart=/bin/echo %i",
+		"multi.target":   "[Unit]\nDescription=Multi\nWants=foo1.service",
+		"loaded.service": "[Service]\nExecStart=/bin/false",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(admin, name), []byte(contents), 0644))
+	}
+	require.NoError(t, os.Symlink(MASK_TARGET, filepath.Join(admin, "masked.service")))
+	require.NoError(t, os.Mkdir(filepath.Join(admin, "dir.service"), 0755))
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	loaded, err := sys.Load("loaded.service", strings.NewReader("[Unit]\nDescription=In-memory\n[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Preload(4))
+
+	for i := 0; i < 32; i++ {
+		name := fmt.Sprintf("foo%d.service", i)
+		if u, err := sys.Unit(name); assert.NoError(t, err, name) {
+			assert.Equal(t, unit.Loaded, u.Loaded(), name)
+		}
+	}
+
+	u, err := sys.Unit("foo0.service")
+	require.NoError(t, err)
+	assert.Equal(t, "Overridden", u.Description(), "definitions in the first path take precedence")
+	assert.Equal(t, filepath.Join(admin, "foo0.service"), u.Path())
+
+	u, err = sys.Unit(filepath.Join(vendor, "foo1.service"))
+	require.NoError(t, err, "units are looked up by path")
+	assert.Equal(t, "foo1.service", u.Name())
+
+	for name, load := range map[string]unit.Load{
+		"bad.service":    unit.Error,
+		"multi.target":   unit.Loaded,
+		"masked.service": unit.Masked,
+	} {
+		if u, err := sys.Unit(name); assert.NoError(t, err, name) {
+			assert.Equal(t, load, u.Loaded(), name)
+		}
+	}
+
+	u, err = sys.Unit("dir.service")
+	require.NoError(t, err)
+	assert.False(t, u.IsLoaded())
+
+	_, err = sys.Unit("bar@.service")
+	assert.Equal(t, ErrNotFound, err, "templates are not preloaded")
+
+	u, err = sys.Unit("loaded.service")
+	require.NoError(t, err)
+	assert.Equal(t, loaded, u)
+	assert.Equal(t, "In-memory", u.Description(), "loaded units are not redefined")
+}
@@ -0,0 +1,160 @@
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Default paths to search for preset files - Daemon uses those, if none are specified
+var DEFAULT_PRESET_PATHS = []string{"/etc/systemd/system-preset", "/run/systemd/system-preset", "/usr/lib/systemd/system-preset", "/lib/systemd/system-preset"}
+
+// Suffix of preset policy files
+const PRESET_SUFFIX = ".preset"
+
+// presetRule is a single 'enable' or 'disable' directive found in a preset file
+type presetRule struct {
+	pattern string
+	enable  bool
+}
+
+// presetFiles returns paths to preset files found in paths, ordered by filename.
+// A file in a directory coming earlier masks the files with the same name in later ones
+func presetFiles(paths []string) (files []string) {
+	return findFiles(osFS{}, paths, func(_ string, info os.FileInfo) (ok, masks bool) {
+		ok = !info.IsDir() && strings.HasSuffix(info.Name(), PRESET_SUFFIX)
+		return ok, ok
+	})
+}
+
+// readPresets parses preset files found in paths and returns the rules in the order
+// they should be applied
+func readPresets(paths []string) (rules []presetRule, err error) {
+	for _, path := range presetFiles(paths) {
+		var file *os.File
+		if file, err = os.Open(path); err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				file.Close()
+				return nil, fmt.Errorf("%s:%d: malformed preset line: %q", path, n, line)
+			}
+
+			var rule presetRule
+			switch fields[0] {
+			case "enable":
+				rule.enable = true
+			case "disable":
+			default:
+				file.Close()
+				return nil, fmt.Errorf("%s:%d: unknown preset directive: %q", path, n, fields[0])
+			}
+			rule.pattern = fields[1]
+
+			rules = append(rules, rule)
+		}
+		file.Close()
+
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// presetEnables returns whether the first of rules matching name enables it.
+// Units not matched by any rule get enabled
+func presetEnables(rules []presetRule, name string) bool {
+	for _, rule := range rules {
+		if ok, _ := filepath.Match(rule.pattern, name); ok {
+			return rule.enable
+		}
+	}
+	return true
+}
+
+// PresetPaths returns paths, which get searched for preset files by sys
+func (sys *Daemon) PresetPaths() (paths []string) {
+	return sys.presetPaths
+}
+
+// SetPresetPaths sets paths, which get searched for preset files by sys
+func (sys *Daemon) SetPresetPaths(paths ...string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.presetPaths = paths
+}
+
+// Preset enables or disables units specified by names according to the preset policy
+func (sys *Daemon) Preset(names ...string) (err error) {
+	log.WithField("names", names).Debugf("sys.Preset")
+
+	var rules []presetRule
+	if rules, err = readPresets(sys.presetPaths); err != nil {
+		return
+	}
+	return sys.preset(rules, names)
+}
+
+// PresetAll enables or disables all units found in unit paths according to the preset policy
+func (sys *Daemon) PresetAll() (err error) {
+	log.Debugf("sys.PresetAll")
+
+	var rules []presetRule
+	if rules, err = readPresets(sys.presetPaths); err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+	for _, dir := range sys.Paths() {
+		paths, err := pathset(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return sys.preset(rules, names)
+}
+
+func (sys *Daemon) preset(rules []presetRule, names []string) (err error) {
+	return sys.getAndExecute(names, func(u *Unit, gerr error) error {
+		if gerr != nil {
+			return gerr
+		}
+
+		if u.IsMasked() {
+			u.Log.Println("Unit is masked, preset not applied")
+			return nil
+		}
+
+		if presetEnables(rules, u.Name()) {
+			return u.Enable()
+		}
+		return u.Disable()
+	})
+}
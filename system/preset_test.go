@@ -0,0 +1,75 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestPreset(t *testing.T) {
+	units, err := ioutil.TempDir("", "preset-units")
+	require.NoError(t, err)
+	defer os.RemoveAll(units)
+
+	admin, err := ioutil.TempDir("", "preset-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "preset-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	for _, name := range []string{"a.service", "b.service", "c.service", "other.service"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(units, name), []byte(`[Service]
+ExecStart=/bin/true
+
+[Install]
+WantedBy=test.target`), 0644))
+	}
+
+	for path, contents := range map[string]string{
+		filepath.Join(vendor, "90-default.preset"): "# vendor defaults\nenable a.service\ndisable *",
+		filepath.Join(vendor, "50-local.preset"):   "enable b.service",
+		filepath.Join(admin, "50-local.preset"):    "; overrides vendor\ndisable b.service\nenable c.*",
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(units)
+	sys.SetPresetPaths(admin, vendor)
+
+	require.NoError(t, sys.Enable("b.service"))
+	require.NoError(t, sys.Preset("a.service", "b.service"))
+
+	for name, expected := range map[string]unit.Enable{
+		"a.service": unit.Enabled,
+		"b.service": unit.Disabled,
+		"c.service": unit.Disabled,
+	} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, st, name)
+	}
+
+	require.NoError(t, sys.PresetAll())
+
+	for name, expected := range map[string]unit.Enable{
+		"a.service":     unit.Enabled,
+		"b.service":     unit.Disabled,
+		"c.service":     unit.Enabled,
+		"other.service": unit.Disabled,
+	} {
+		st, err := sys.IsEnabled(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, st, name)
+	}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(admin, "10-broken.preset"), []byte("start a.service"), 0644))
+	assert.Error(t, sys.PresetAll())
+}
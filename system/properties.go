@@ -0,0 +1,99 @@
+package system
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"systemgo/unit"
+)
+
+// Properties returns the effective properties of the unit with name specified - the options of its
+// definition(including drop-ins) with manager defaults applied and its runtime state,
+// formatted the way Systemd does.
+// If error is returned, it is going to be a *LoadError
+func (sys *Daemon) Properties(name string) (props map[string]string, err error) {
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return
+	}
+
+	props = unit.Options(u.Interface)
+
+	start, stop := sys.timeouts(u)
+	props["TimeoutStartSec"] = unit.FormatTimespan(start)
+	props["TimeoutStopSec"] = unit.FormatTimespan(stop)
+
+	interval, burst := sys.startLimit(u)
+	props["StartLimitIntervalSec"] = unit.FormatTimespan(interval)
+	props["StartLimitBurst"] = strconv.Itoa(burst)
+
+	for key, enabled := range sys.accounting(u) {
+		props[key] = "no"
+		if enabled {
+			props[key] = "yes"
+		}
+	}
+
+	props["Id"] = u.Name()
+	props["Requires"] = strings.Join(u.Requires(), " ")
+	props["Wants"] = strings.Join(u.Wants(), " ")
+	props["FragmentPath"] = u.Path()
+	props["LoadState"] = loadStateName(u.Loaded())
+	props["ActiveState"] = strings.ToLower(fmt.Sprint(u.Active()))
+	props["SubState"] = u.Sub()
+	props["UnitFileState"] = enableStateName(u.EnableState())
+	props["FreezerState"] = "running"
+	if u.frozen() {
+		props["FreezerState"] = "frozen"
+	}
+	props["NeedDaemonReload"] = "no"
+	if u.NeedsReload() {
+		props["NeedDaemonReload"] = "yes"
+	}
+
+	if st := sys.HealthState(u); st != "" {
+		props["HealthState"] = st
+	}
+
+	if pider, ok := u.Interface.(unit.MainPIDer); ok {
+		props["MainPID"] = strconv.Itoa(pider.MainPID())
+	}
+
+	if sys.cgroupOf(u) != "" {
+		usage := u.ResourceUsage()
+		for _, p := range []struct {
+			key     string
+			value   uint64
+			enabled bool
+		}{
+			{"CPUUsageNSec", uint64(usage.CPU), props["CPUAccounting"] == "yes"},
+			{"MemoryCurrent", usage.Memory, props["MemoryAccounting"] == "yes"},
+			{"MemoryPeak", usage.MemoryPeak, props["MemoryAccounting"] == "yes"},
+			{"IOReadBytes", usage.IORead, props["IOAccounting"] == "yes"},
+			{"IOWriteBytes", usage.IOWrite, props["IOAccounting"] == "yes"},
+			{"TasksCurrent", usage.Tasks, props["TasksAccounting"] == "yes"},
+		} {
+			if p.enabled {
+				props[p.key] = strconv.FormatUint(p.value, 10)
+			}
+		}
+	}
+	return props, nil
+}
+
+// loadStateName returns the name of st as used by Systemd
+func loadStateName(st unit.Load) string {
+	if st == unit.NotFound {
+		return "not-found"
+	}
+	return strings.ToLower(fmt.Sprint(st))
+}
+
+// enableStateName returns the name of st as used by Systemd
+func enableStateName(st unit.Enable) string {
+	if st == unit.EnabledRuntime {
+		return "enabled-runtime"
+	}
+	return strings.ToLower(fmt.Sprint(st))
+}
@@ -0,0 +1,53 @@
+package system
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProperties(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	sys.defaults.TimeoutStopSec = 10 * time.Second
+
+	_, err := sys.Load("foo.service", strings.NewReader(`[Unit]
+Description=Foo
+Wants=bar.service
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/true
+TimeoutStartSec=90`))
+	require.NoError(t, err)
+	require.NoError(t, sys.Start("foo.service"))
+
+	props, err := sys.Properties("foo.service")
+	require.NoError(t, err)
+	for name, expected := range map[string]string{
+		"Id":               "foo.service",
+		"Description":      "Foo",
+		"Wants":            "bar.service",
+		"Type":             "oneshot",
+		"RemainAfterExit":  "yes",
+		"ExecStart":        "/bin/true",
+		"TimeoutStartSec":  "1min 30s",
+		"TimeoutStopSec":   "10s",
+		"LoadState":        "loaded",
+		"ActiveState":      "active",
+		"SubState":         "exited",
+		"UnitFileState":    "static",
+		"NeedDaemonReload": "no",
+		"MainPID":          "0",
+	} {
+		assert.Equal(t, expected, props[name], name)
+	}
+
+	_, err = sys.Properties("missing.service")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,96 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// SetProperty sets the property key of the unit with name specified to value at runtime, supported are
+// Restart= and the resource control properties MemoryMax=, CPUQuota= and TasksMax=. Resource control
+// properties are applied to the cgroup of the unit immediately, if it exists.
+// If persist is true, the property is also written to a drop-in located in the first of the unit paths,
+// so that it is kept across reloads and restarts of the manager.
+// If error is returned, it is going to be a *LoadError or a *UnitError
+func (sys *Daemon) SetProperty(name, key, value string, persist bool) (err error) {
+	log.WithFields(log.Fields{
+		"name":  name,
+		"key":   key,
+		"value": value,
+	}).Debugf("sys.SetProperty")
+
+	var u *Unit
+	if u, err = sys.Get(name); err != nil {
+		return
+	}
+
+	fail := func(err error) error {
+		return &UnitError{Name: name, Op: "set property of", Err: err}
+	}
+
+	setter, ok := u.Interface.(unit.PropertySetter)
+	if !ok {
+		return fail(unit.ParseErr(key, unit.ErrNotSupported))
+	}
+
+	if err = setter.SetProperty(key, value); err != nil {
+		return fail(err)
+	}
+	if key == "LogLevelMax" || key == "LogLevel" {
+		u.setLogLevels()
+	}
+	u.Log.Printf("Set %s=%s", key, value)
+
+	if file, contents, aerr := unit.CgroupAttribute(key, value); aerr == nil {
+		if dir := sys.cgroupOf(u); dir != "" {
+			if _, serr := os.Stat(dir); serr == nil {
+				if err = writeCgroupAttrs(dir, map[string]string{file: contents}); err != nil {
+					return fail(err)
+				}
+			}
+		}
+	}
+
+	if !persist {
+		return nil
+	}
+
+	if u.Path() == "" {
+		// Drop-ins do not apply to units not loaded from disk
+		return fail(ErrNotFound)
+	}
+
+	var dir string
+	if dir, err = sys.configDir(); err != nil {
+		return fail(err)
+	}
+	path := filepath.Join(dir, name+".d", propertyDropin(key))
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fail(err)
+	}
+	if err = writeFileAtomic(path, []byte(fmt.Sprintf("[%s]\n%s=%s\n", section(name), key, value)), 0644); err != nil {
+		return fail(err)
+	}
+
+	return sys.reread(name)
+}
+
+// propertyDropin returns the name of the drop-in SetProperty persists the property key in
+func propertyDropin(key string) string {
+	return "50-" + key + DROPIN_SUFFIX
+}
+
+// section returns the name of the type specific section of the definition of the unit with name specified,
+// e.g. Service for foo.service
+func section(name string) string {
+	typ := strings.TrimPrefix(filepath.Ext(name), ".")
+	if typ == "" {
+		return ""
+	}
+	return strings.ToUpper(typ[:1]) + typ[1:]
+}
@@ -0,0 +1,68 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetProperty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "property-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	paths, cgroup := filepath.Join(dir, "units"), filepath.Join(dir, "cgroup")
+	require.NoError(t, os.Mkdir(paths, 0755))
+	require.NoError(t, os.Mkdir(cgroup, 0755))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(paths, "sleep.service"), []byte("[Service]\nExecStart=/bin/sleep 1000\nTasksMax=16"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(paths, "foo.target"), nil, 0644))
+
+	sys := New()
+	sys.SetPaths(paths)
+	sys.SetCgroup(cgroup)
+
+	u, err := sys.Get("sleep.service")
+	require.NoError(t, err)
+	require.NoError(t, u.start())
+	defer u.stop()
+
+	read := func(file string) string {
+		b, err := ioutil.ReadFile(filepath.Join(cgroup, "sleep.service", file))
+		require.NoError(t, err)
+		return string(b)
+	}
+	assert.Equal(t, "16", read("pids.max"), "limits are applied on start")
+
+	require.NoError(t, sys.SetProperty("sleep.service", "MemoryMax", "512M", false))
+	assert.Equal(t, "536870912", read("memory.max"))
+	_, err = os.Stat(filepath.Join(paths, "sleep.service.d"))
+	assert.True(t, os.IsNotExist(err), "runtime properties are not persisted")
+
+	require.NoError(t, sys.SetProperty("sleep.service", "CPUQuota", "20%", true))
+	assert.Equal(t, "20000 100000", read("cpu.max"))
+
+	b, err := ioutil.ReadFile(filepath.Join(paths, "sleep.service.d", "50-CPUQuota.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "[Service]\nCPUQuota=20%\n", string(b))
+
+	props, err := sys.Properties("sleep.service")
+	require.NoError(t, err)
+	assert.Equal(t, "20%", props["CPUQuota"])
+	assert.Equal(t, "16", props["TasksMax"])
+	assert.Equal(t, "active", props["ActiveState"])
+
+	require.NoError(t, sys.SetProperty("sleep.service", "Restart", "always", false))
+	props, err = sys.Properties("sleep.service")
+	require.NoError(t, err)
+	assert.Equal(t, "always", props["Restart"])
+
+	var uerr *UnitError
+	assert.ErrorAs(t, sys.SetProperty("sleep.service", "MemoryMax", "lots", false), &uerr)
+	assert.ErrorAs(t, sys.SetProperty("foo.target", "MemoryMax", "1G", false), &uerr)
+	assert.Error(t, sys.SetProperty("missing.service", "MemoryMax", "1G", false))
+}
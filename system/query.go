@@ -0,0 +1,180 @@
+package system
+
+import (
+	"context"
+	"io"
+	"path"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Number of new entries queued for a LogIterator following the logs, further entries are dropped,
+// until the queue is drained
+const LOG_FOLLOW_QUEUE_SIZE = 4096
+
+// LogFilter specifies the entries of the logs iterated over by a LogIterator
+type LogFilter struct {
+	LogQuery
+
+	// Whether to iterate over new entries matching the query as they are logged,
+	// once the ones logged before are exhausted
+	Follow bool
+}
+
+// LogIterator iterates over the entries of the logs matching a LogFilter, see Daemon.QueryLogs
+type LogIterator struct {
+	sys    *Daemon
+	filter LogFilter
+
+	// Entries logged before the iterator was created, which are not returned yet
+	entries []LogEntry
+
+	// Returns whether an entry matches the filter, except for the units
+	match func(LogEntry) bool
+
+	// Patterns of the units entries are followed of
+	patterns []string
+
+	// New entries, nil unless following
+	follow chan LogEntry
+
+	// Entries logged since following started, which are among the entries logged before,
+	// so that they are not returned twice
+	seen []LogEntry
+
+	closeOnce sync.Once
+}
+
+// QueryLogs returns an iterator over the entries of the logs of units, the manager, the kernel
+// and the audit stream matching filter ordered by time, as Logs returns them. If filter.Follow is set,
+// the iterator then returns the new entries matching filter as they are logged, until it is closed
+func (sys *Daemon) QueryLogs(filter LogFilter) (it *LogIterator, err error) {
+	log.WithField("filter", filter).Debugf("sys.QueryLogs")
+
+	if filter.Follow && filter.Reverse {
+		return nil, ErrFollowReverse
+	}
+
+	it = &LogIterator{
+		sys:      sys,
+		filter:   filter,
+		patterns: logUnitPatterns(filter.Units),
+	}
+	if it.match, err = sys.logMatcher(filter.LogQuery); err != nil {
+		return nil, err
+	}
+
+	if !filter.Follow {
+		if it.entries, err = sys.Logs(filter.LogQuery); err != nil {
+			return nil, err
+		}
+		return it, nil
+	}
+
+	// Following starts before the entries logged before are read, so that none are missed in between
+	since := sys.clock.Now()
+	it.follow = make(chan LogEntry, LOG_FOLLOW_QUEUE_SIZE)
+
+	sys.logMutex.Lock()
+	if sys.followers == nil {
+		sys.followers = map[*LogIterator]bool{}
+	}
+	sys.followers[it] = true
+	sys.logMutex.Unlock()
+
+	if it.entries, err = sys.Logs(filter.LogQuery); err != nil {
+		it.Close()
+		return nil, err
+	}
+	for _, e := range it.entries {
+		if !e.Time.Before(since) {
+			it.seen = append(it.seen, e)
+		}
+	}
+	return it, nil
+}
+
+// Next returns the next entry. If the entries are exhausted, Next returns io.EOF or, if following,
+// blocks until a new entry is logged or ctx is done, in which case ctx.Err() is returned
+func (it *LogIterator) Next(ctx context.Context) (e LogEntry, err error) {
+	if len(it.entries) > 0 {
+		e, it.entries = it.entries[0], it.entries[1:]
+		return e, nil
+	}
+
+	if it.follow == nil {
+		return e, io.EOF
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return e, ctx.Err()
+		case e = <-it.follow:
+			if !it.wasSeen(e) {
+				return e, nil
+			}
+		}
+	}
+}
+
+// Close stops following the logs, if following
+func (it *LogIterator) Close() {
+	it.closeOnce.Do(func() {
+		it.sys.logMutex.Lock()
+		delete(it.sys.followers, it)
+		it.sys.logMutex.Unlock()
+	})
+}
+
+// wasSeen returns whether e is among the entries returned before, e is forgotten then
+func (it *LogIterator) wasSeen(e LogEntry) bool {
+	for i, s := range it.seen {
+		if s.Unit == e.Unit && s.Time.Equal(e.Time) && s.Monotonic == e.Monotonic && s.Message == e.Message {
+			it.seen = append(it.seen[:i], it.seen[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns whether e, which is being logged, matches the filter of it
+func (it *LogIterator) matches(e LogEntry) bool {
+	switch {
+	case it.filter.Kernel || it.filter.Audit:
+		if !(it.filter.Kernel && e.Kernel || it.filter.Audit && e.Audit) {
+			return false
+		}
+	case len(it.patterns) > 0:
+		matched := false
+		for _, pattern := range it.patterns {
+			if ok, _ := path.Match(pattern, e.Unit); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return it.match(e)
+}
+
+// followLog queues e for the iterators following the logs, which e matches the filters of
+func (sys *Daemon) followLog(e LogEntry) {
+	sys.logMutex.Lock()
+	defer sys.logMutex.Unlock()
+
+	for it := range sys.followers {
+		if !it.matches(e) {
+			continue
+		}
+
+		select {
+		case it.follow <- e:
+		default:
+			log.Debugf("Log follow queue is full, dropping entry")
+		}
+	}
+}
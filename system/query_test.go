@@ -0,0 +1,85 @@
+package system
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLogs(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	foo, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	bar, err := sys.Load("bar.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	foo.Log.Println("foo info")
+	foo.Log.Error("foo error")
+	bar.Log.Error("bar error")
+
+	ctx := context.Background()
+
+	it, err := sys.QueryLogs(LogFilter{LogQuery: LogQuery{Units: []string{"foo"}, Priority: "err"}})
+	require.NoError(t, err)
+	defer it.Close()
+
+	e, err := it.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "foo error", e.Message)
+
+	_, err = it.Next(ctx)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = sys.QueryLogs(LogFilter{LogQuery: LogQuery{Reverse: true}, Follow: true})
+	assert.Equal(t, ErrFollowReverse, err)
+
+	_, err = sys.QueryLogs(LogFilter{LogQuery: LogQuery{Priority: "foo"}})
+	assert.Error(t, err)
+}
+
+func TestQueryLogsFollow(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	foo, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	foo.Log.Println("before")
+
+	it, err := sys.QueryLogs(LogFilter{LogQuery: LogQuery{Units: []string{"f*.service"}}, Follow: true})
+	require.NoError(t, err)
+	defer it.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	e, err := it.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "before", e.Message)
+
+	sys.Log.Println("manager")
+	fab, err := sys.Load("fab.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	fab.Log.Println("after")
+
+	e, err = it.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "after", e.Message, "entries of units loaded later, which match, are followed")
+	assert.Equal(t, "fab.service", e.Unit)
+
+	short, cancelShort := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelShort()
+	_, err = it.Next(short)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	it.Close()
+	foo.Log.Println("closed")
+	assert.Empty(t, it.follow, "entries are not queued, once closed")
+}
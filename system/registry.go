@@ -0,0 +1,60 @@
+package system
+
+import "sync"
+
+// registry maps names and paths of units to the units, it is safe for concurrent use
+type registry struct {
+	units map[string]*Unit
+	mutex sync.RWMutex
+}
+
+func newRegistry() *registry {
+	return &registry{units: make(map[string]*Unit)}
+}
+
+// get returns the unit registered under key, if any
+func (r *registry) get(key string) (u *Unit, ok bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	u, ok = r.units[key]
+	return
+}
+
+// set registers u under keys
+func (r *registry) set(u *Unit, keys ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, key := range keys {
+		r.units[key] = u
+	}
+}
+
+// remove unregisters u under all of its keys
+func (r *registry) remove(u *Unit) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, registered := range r.units {
+		if registered == u {
+			delete(r.units, key)
+		}
+	}
+}
+
+// all returns the units registered, each unit is returned once regardless of the number of its keys
+func (r *registry) all() (units []*Unit) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	unitSet := make(map[*Unit]struct{}, len(r.units))
+	units = make([]*Unit, 0, len(r.units))
+	for _, u := range r.units {
+		if _, ok := unitSet[u]; !ok {
+			unitSet[u] = struct{}{}
+			units = append(units, u)
+		}
+	}
+	return
+}
@@ -0,0 +1,166 @@
+package system
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Suffix of drop-in configuration files
+const DROPIN_SUFFIX = ".conf"
+
+// dropins returns paths to drop-in files of unit defined in path, ordered by filename.
+// Drop-in files are searched for in '<name>.d' directories found in paths and the directory of the definition.
+// A file in a directory coming earlier masks the files with the same name in later ones.
+// Directories are read from fsys
+func dropins(fsys fileSystem, path string, paths []string) (files []string) {
+	dirname := filepath.Base(path) + ".d"
+
+	dirs := make([]string, 0, len(paths)+1)
+	for _, p := range paths {
+		dirs = append(dirs, filepath.Join(p, dirname))
+	}
+	dirs = append(dirs, filepath.Join(filepath.Dir(path), dirname))
+
+	return findFiles(fsys, dirs, func(_ string, info os.FileInfo) (ok, masks bool) {
+		ok = !info.IsDir() && strings.HasSuffix(info.Name(), DROPIN_SUFFIX)
+		return ok, ok
+	})
+}
+
+// readDefinition reads the definition from r and appends the contents of drop-ins
+// of unit defined in path to it
+func (sys *Daemon) readDefinition(r io.Reader, path string) (b []byte, err error) {
+	buf := &bytes.Buffer{}
+	if _, err = buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	for _, dropin := range dropins(sys.fsys, path, sys.Paths()) {
+		var contents []byte
+		if contents, err = sys.fsys.ReadFile(dropin); err != nil {
+			return nil, err
+		}
+
+		buf.WriteByte('\n')
+		buf.Write(contents)
+	}
+	return buf.Bytes(), nil
+}
+
+// ReloadDaemon re-runs the generators, if they have been run before, and re-reads definitions
+// of all units loaded from disk together with their drop-ins.
+// Units, which are not running, get redefined straight away.
+// Running units, definitions of which changed, are marked as such and get redefined
+// the next time they are started
+func (sys *Daemon) ReloadDaemon() (err error) {
+	log.Debugf("sys.ReloadDaemon")
+
+	if sys.generated {
+		if err = sys.RunGenerators(); err != nil {
+			return
+		}
+	}
+
+	// Units might have been linked into '.wants' and '.requires' directories
+	sys.deps.reset()
+
+	for _, u := range sys.Units() {
+		if u.Path() == "" {
+			// Not loaded from disk
+			continue
+		}
+
+		var b []byte
+		if b, err = sys.readDefinitionFile(u.Path()); err != nil {
+			if !os.IsNotExist(err) {
+				u.Log.Errorf("Error reading definition: %s", err)
+				return
+			}
+			err = nil
+
+			if u.isRunning() {
+				u.setChanged(true)
+			} else {
+				u.setLoad(unit.NotFound)
+			}
+			continue
+		}
+
+		if u.IsLoaded() && u.definedWith(b) {
+			u.setChanged(false)
+			continue
+		}
+
+		if u.isRunning() {
+			u.Log.Println("Definition changed on disk, restart to apply")
+			u.setChanged(true)
+			continue
+		}
+
+		u.define(b)
+	}
+	return nil
+}
+
+// readDefinitionFile returns the definition found at path together with its drop-ins.
+// Files are only read if any of them changed on disk since they were last read
+func (sys *Daemon) readDefinitionFile(path string) (b []byte, err error) {
+	files := append([]string{path}, dropins(sys.fsys, path, sys.Paths())...)
+
+	var current map[string]stamp
+	if current, err = stamps(sys.fsys, files); err != nil {
+		sys.cache.forget(path)
+		return
+	}
+
+	if b, ok := sys.cache.get(path, current); ok {
+		return b, nil
+	}
+
+	var file fs.File
+	if file, err = sys.fsys.Open(path); err != nil {
+		return
+	}
+	defer file.Close()
+
+	if b, err = sys.readDefinition(file, path); err != nil {
+		return
+	}
+	sys.cache.put(path, b, current)
+	return
+}
+
+// redefine re-reads the definition of u from disk and redefines u
+func (u *Unit) redefine() (err error) {
+	var b []byte
+	if b, err = u.System.readDefinitionFile(u.Path()); err != nil {
+		return
+	}
+	return u.define(b)
+}
+
+// isRunning returns whether u is neither dead, nor failed
+func (u *Unit) isRunning() bool {
+	switch u.Interface.Active() {
+	case unit.Inactive, unit.Failed:
+		return false
+	default:
+		return true
+	}
+}
+
+// NeedsReload returns whether the definition of u changed on disk since it was last defined
+func (u *Unit) NeedsReload() bool {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return u.changed
+}
@@ -0,0 +1,72 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropins(t *testing.T) {
+	vendor, err := ioutil.TempDir("", "dropins-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	admin, err := ioutil.TempDir("", "dropins-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	for dir, names := range map[string][]string{
+		vendor: {"10-foo.conf", "20-bar.conf", "ignored.txt"},
+		admin:  {"20-bar.conf", "30-baz.conf"},
+	} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "test.service.d"), 0755))
+		for _, name := range names {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "test.service.d", name), []byte{}, 0644))
+		}
+	}
+
+	assert.Equal(t, []string{
+		filepath.Join(vendor, "test.service.d", "10-foo.conf"),
+		filepath.Join(admin, "test.service.d", "20-bar.conf"),
+		filepath.Join(admin, "test.service.d", "30-baz.conf"),
+	}, dropins(osFS{}, filepath.Join(vendor, "test.service"), []string{admin, vendor}))
+}
+
+func TestReloadDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.service")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`[Unit]
+Description=Original
+After=a.target
+
+[Service]
+ExecStart=/bin/true`), 0644))
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	u, err := sys.Get("test.service")
+	require.NoError(t, err)
+	assert.Equal(t, "Original", u.Description())
+
+	require.NoError(t, os.Mkdir(path+".d", 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(path+".d", "override.conf"), []byte(`[Unit]
+Description=Overridden
+After=b.target`), 0644))
+
+	require.NoError(t, sys.ReloadDaemon())
+	assert.Equal(t, "Overridden", u.Description())
+	assert.Equal(t, []string{"a.target", "b.target"}, u.After())
+	assert.False(t, u.NeedsReload())
+
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, sys.ReloadDaemon())
+	assert.False(t, u.IsLoaded())
+}
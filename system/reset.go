@@ -0,0 +1,48 @@
+package system
+
+import "systemgo/unit"
+
+// ResetFailed resets the failed state and the start rate limit counters of units specified by names,
+// or of all units, which have failed, if none are specified.
+// If error is returned, it is going to be a *LoadError
+func (sys *Daemon) ResetFailed(names ...string) (err error) {
+	var units []*Unit
+	if len(names) == 0 {
+		for _, u := range sys.Units() {
+			if u.Active() == unit.Failed {
+				units = append(units, u)
+			}
+		}
+	} else {
+		units = make([]*Unit, 0, len(names))
+		for _, name := range names {
+			var u *Unit
+			if u, err = sys.Get(name); err != nil {
+				return
+			}
+			units = append(units, u)
+		}
+	}
+
+	for _, u := range units {
+		u.resetFailed()
+	}
+	return nil
+}
+
+// resetFailed clears the recorded start attempts of u and puts it into the inactive state, if it has failed
+func (u *Unit) resetFailed() {
+	u.mutex.Lock()
+	u.starts = nil
+	u.mutex.Unlock()
+
+	prev := u.Active()
+	if prev != unit.Failed {
+		return
+	}
+
+	if r, ok := u.Interface.(unit.FailedResetter); ok {
+		r.ResetFailed()
+		u.transition(prev, nil, "failed state reset")
+	}
+}
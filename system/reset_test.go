@@ -0,0 +1,37 @@
+package system
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestResetFailed(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+	sys.SetClock(NewFakeClock(time.Now()))
+
+	fail, err := sys.Load("fail.service", strings.NewReader("[Service]\nType=oneshot\nExecStart=/bin/false"))
+	require.NoError(t, err)
+	limited, err := sys.Load("limited.service", strings.NewReader("[Unit]\nStartLimitBurst=1\nStartLimitIntervalSec=1min\n[Service]\nType=oneshot\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	assert.Error(t, fail.start())
+	assert.Equal(t, unit.Failed, fail.Active())
+
+	require.NoError(t, limited.start())
+	assert.Equal(t, ErrStartLimit, limited.start())
+
+	require.NoError(t, sys.ResetFailed())
+	assert.Equal(t, unit.Inactive, fail.Active())
+	assert.Equal(t, ErrStartLimit, limited.start(), "only failed units are reset, if none are specified")
+
+	require.NoError(t, sys.ResetFailed("limited.service"))
+	assert.NoError(t, limited.start())
+
+	assert.Error(t, sys.ResetFailed("missing.service"))
+}
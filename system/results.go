@@ -0,0 +1,79 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JobStatus describes the outcome of a job
+type JobStatus struct {
+	// Type of the job
+	Job string `json:"Job"`
+
+	Result JobResult `json:"Result"`
+
+	// Error the job failed with, if any
+	Err string `json:"Err,omitempty"`
+}
+
+// Succeeded returns whether the job was either executed successfully or did not need to be
+func (st JobStatus) Succeeded() bool {
+	return st.Result == ResultDone || st.Result == ResultSkipped
+}
+
+func (st JobStatus) String() string {
+	if st.Err != "" {
+		return fmt.Sprintf("%s job %s: %s", st.Job, st.Result, st.Err)
+	}
+	return fmt.Sprintf("%s job %s", st.Job, st.Result)
+}
+
+// Results maps names of requested units to outcomes of their jobs
+type Results map[string]JobStatus
+
+// Failed returns sorted names of units, jobs for which did not succeed
+func (res Results) Failed() (names []string) {
+	for name, st := range res {
+		if !st.Succeeded() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// Err returns a JobError, if any of the jobs in res did not succeed, nil otherwise
+func (res Results) Err() error {
+	if len(res.Failed()) == 0 {
+		return nil
+	}
+	return JobError{res}
+}
+
+// JobError is returned, when some of the requested jobs did not succeed
+type JobError struct {
+	Results Results
+}
+
+func (err JobError) Error() string {
+	failed := err.Results.Failed()
+
+	msgs := make([]string, len(failed))
+	for i, name := range failed {
+		msgs[i] = fmt.Sprintf("%s: %s", name, err.Results[name])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Status returns the outcome of j
+func (j *job) Status() (st JobStatus) {
+	st = JobStatus{
+		Job:    fmt.Sprint(j.typ),
+		Result: j.Result(),
+	}
+	if err := j.Err(); err != nil {
+		st.Err = err.Error()
+	}
+	return
+}
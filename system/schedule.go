@@ -0,0 +1,202 @@
+package system
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Format of times in messages to users
+const TIME_FORMAT = "Mon 2006-01-02 15:04:05 MST"
+
+// Times left before a scheduled shutdown, at which logged-in users are warned in addition
+// to the warning broadcast, when the shutdown is scheduled
+var wallMarks = []time.Duration{
+	24 * time.Hour,
+	12 * time.Hour,
+	6 * time.Hour,
+	3 * time.Hour,
+	time.Hour,
+	30 * time.Minute,
+	10 * time.Minute,
+	5 * time.Minute,
+	time.Minute,
+}
+
+// ScheduledShutdown is a shutdown of the system scheduled to happen at When
+type ScheduledShutdown struct {
+	Kind ShutdownKind
+	When time.Time
+}
+
+// schedule is a ScheduledShutdown waited for by the scheduler
+type schedule struct {
+	ScheduledShutdown
+
+	// Closed, when the shutdown is cancelled
+	cancel chan struct{}
+}
+
+// ScheduleShutdown schedules the shutdown of kind to happen at when, replacing the shutdown already
+// scheduled, if any. Logged-in users are warned about the upcoming shutdown right away and then
+// in decreasing intervals. Once when passes, ShutdownSystem is called
+func (sys *Daemon) ScheduleShutdown(kind ShutdownKind, when time.Time) (err error) {
+	log.WithFields(log.Fields{
+		"kind": kind,
+		"when": when,
+	}).Debugf("sys.ScheduleShutdown")
+
+	s := &schedule{
+		ScheduledShutdown: ScheduledShutdown{Kind: kind, When: when},
+		cancel:            make(chan struct{}),
+	}
+
+	sys.mutex.Lock()
+	if sys.scheduled != nil {
+		close(sys.scheduled.cancel)
+	}
+	sys.scheduled = s
+	sys.mutex.Unlock()
+
+	sys.Log.Printf("Shutdown scheduled for %s, use 'systemctl %s --when=cancel' to cancel.", when.Format(TIME_FORMAT), kind)
+
+	go sys.runSchedule(s)
+	return nil
+}
+
+// CancelShutdown cancels the scheduled shutdown and notifies logged-in users.
+// ErrNotScheduled is returned, if no shutdown is scheduled
+func (sys *Daemon) CancelShutdown() (err error) {
+	log.Debugf("sys.CancelShutdown")
+
+	if !sys.cancelSchedule() {
+		return ErrNotScheduled
+	}
+
+	sys.Log.Println("Scheduled shutdown cancelled.")
+	wall("The system shutdown has been cancelled")
+	return nil
+}
+
+// cancelSchedule cancels the scheduled shutdown without notifying anyone, returns false if none is scheduled
+func (sys *Daemon) cancelSchedule() bool {
+	sys.mutex.Lock()
+	s := sys.scheduled
+	sys.scheduled = nil
+	sys.mutex.Unlock()
+
+	if s == nil {
+		return false
+	}
+	close(s.cancel)
+	return true
+}
+
+// ScheduledShutdown returns the scheduled shutdown, ok is false if none is scheduled
+func (sys *Daemon) ScheduledShutdown() (s ScheduledShutdown, ok bool) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	if sys.scheduled == nil {
+		return ScheduledShutdown{}, false
+	}
+	return sys.scheduled.ScheduledShutdown, true
+}
+
+// runSchedule warns logged-in users about s, until it is time to shut the system down or s gets cancelled
+func (sys *Daemon) runSchedule(s *schedule) {
+	for {
+		left := s.When.Sub(sys.clock.Now())
+		if left <= 0 {
+			break
+		}
+		wall(shutdownMessage(s.Kind, s.When))
+
+		timer := sys.clock.NewTimer(untilWall(left))
+		select {
+		case <-timer.C():
+		case <-s.cancel:
+			timer.Stop()
+			return
+		}
+	}
+
+	sys.mutex.Lock()
+	if sys.scheduled != s {
+		// Cancelled or replaced, while the timer fired
+		sys.mutex.Unlock()
+		return
+	}
+	sys.scheduled = nil
+	sys.mutex.Unlock()
+
+	wall(fmt.Sprintf("The system will %s now!", shutdownVerb(s.Kind)))
+	if err := sys.ShutdownSystem(s.Kind); err != nil {
+		log.WithField("kind", s.Kind).Errorf("Error shutting down: %s", err)
+	}
+}
+
+// untilWall returns the duration until the next warning, if left remains before the shutdown
+func untilWall(left time.Duration) time.Duration {
+	for _, mark := range wallMarks {
+		if left > mark {
+			return left - mark
+		}
+	}
+	return left
+}
+
+// shutdownMessage returns the warning broadcast to logged-in users about the shutdown of kind at when
+func shutdownMessage(kind ShutdownKind, when time.Time) string {
+	return fmt.Sprintf("The system will %s at %s!", shutdownVerb(kind), when.Format(TIME_FORMAT))
+}
+
+// shutdownVerb returns the verb describing the shutdown of kind in messages to users
+func shutdownVerb(kind ShutdownKind) string {
+	switch kind {
+	case Poweroff:
+		return "power off"
+	case Kexec:
+		return "reboot via kexec"
+	default:
+		return kind.String()
+	}
+}
+
+// ParseShutdownTime parses the time of a scheduled shutdown relative to now, specified as "now",
+// "+TIMESPAN", e.g. "+10min", "HH:MM" of the next occurrence of the time of day or "YYYY-MM-DD HH:MM[:SS]"
+// in the local time zone
+func ParseShutdownTime(s string, now time.Time) (t time.Time, err error) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case s == "now":
+		return now, nil
+
+	case strings.HasPrefix(s, "+"):
+		var d time.Duration
+		if d, err = unit.ParseTimespan(s[1:]); err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+
+	var clock time.Time
+	if clock, err = time.ParseInLocation("15:04", s, now.Location()); err == nil {
+		t = time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04"} {
+		if t, err = time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q", s)
+}
@@ -0,0 +1,121 @@
+package system
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShutdownTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	for s, expected := range map[string]time.Time{
+		"now":              now,
+		"+10min":           now.Add(10 * time.Minute),
+		"+1h 30min":        now.Add(90 * time.Minute),
+		"13:30":            time.Date(2026, 10, 16, 13, 30, 0, 0, time.UTC),
+		"08:00":            time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+		"2026-10-20 23:00": time.Date(2026, 10, 20, 23, 0, 0, 0, time.UTC),
+	} {
+		when, err := ParseShutdownTime(s, now)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, when, s)
+		}
+	}
+
+	for _, s := range []string{"", "soon", "+", "25:00"} {
+		_, err := ParseShutdownTime(s, now)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestUntilWall(t *testing.T) {
+	assert.Equal(t, 5*time.Minute, untilWall(10*time.Minute))
+	assert.Equal(t, time.Hour, untilWall(2*time.Hour))
+	assert.Equal(t, 30*time.Second, untilWall(30*time.Second))
+}
+
+func TestScheduleShutdown(t *testing.T) {
+	msgs := make(chan string, 10)
+	wall = func(msg string) { msgs <- msg }
+	defer func() { wall = broadcast }()
+
+	clock := NewFakeClock(time.Now())
+
+	sys := New()
+	sys.SetPaths()
+	sys.SetClock(clock)
+	state := sys.State()
+
+	// waitTimer waits for the scheduler to start waiting for the next warning
+	waitTimer := func() {
+		require.Eventually(t, func() bool { return clock.Timers() == 1 }, time.Second, time.Millisecond)
+	}
+
+	when := clock.Now().Add(10 * time.Minute)
+	require.NoError(t, sys.ScheduleShutdown(Reboot, when))
+
+	s, ok := sys.ScheduledShutdown()
+	assert.True(t, ok)
+	assert.Equal(t, ScheduledShutdown{Kind: Reboot, When: when}, s)
+
+	for _, d := range []time.Duration{5 * time.Minute, 4 * time.Minute} {
+		assert.Equal(t, shutdownMessage(Reboot, when), <-msgs)
+		waitTimer()
+		clock.Advance(d)
+	}
+	assert.Equal(t, shutdownMessage(Reboot, when), <-msgs)
+
+	waitTimer()
+	require.NoError(t, sys.CancelShutdown())
+	assert.Equal(t, "The system shutdown has been cancelled", <-msgs)
+	assert.Equal(t, ErrNotScheduled, sys.CancelShutdown())
+
+	_, ok = sys.ScheduledShutdown()
+	assert.False(t, ok)
+	assert.Equal(t, state, sys.State())
+
+	require.NoError(t, sys.ScheduleShutdown(Poweroff, clock.Now().Add(30*time.Second)))
+	<-msgs
+	waitTimer()
+	clock.Advance(30 * time.Second)
+
+	assert.Equal(t, "The system will power off now!", <-msgs)
+	assert.Eventually(t, func() bool { return sys.State() == Stopping }, time.Second, time.Millisecond)
+
+	_, ok = sys.ScheduledShutdown()
+	assert.False(t, ok)
+}
+
+func TestShutdownCancelsSchedule(t *testing.T) {
+	msgs := make(chan string, 10)
+	wall = func(msg string) { msgs <- msg }
+	defer func() { wall = broadcast }()
+
+	clock := NewFakeClock(time.Now())
+
+	sys := New()
+	sys.SetPaths()
+	sys.SetClock(clock)
+
+	when := clock.Now().Add(10 * time.Minute)
+	require.NoError(t, sys.ScheduleShutdown(Reboot, when))
+	assert.Equal(t, shutdownMessage(Reboot, when), <-msgs)
+	require.Eventually(t, func() bool { return clock.Timers() == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, sys.Shutdown(context.Background()))
+
+	_, ok := sys.ScheduledShutdown()
+	assert.False(t, ok)
+	assert.Eventually(t, func() bool { return clock.Timers() == 0 }, time.Second, time.Millisecond)
+
+	clock.Advance(10 * time.Minute)
+	select {
+	case msg := <-msgs:
+		t.Errorf("Unexpected message: %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,194 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Environment variable holding the number of the file descriptor, which the state
+// of the daemon serialized before re-execution can be read from
+const SERIALIZATION_FD_ENV = "SYSTEMGO_SERIALIZATION_FD"
+
+// Serialized state of a Daemon
+type serialization struct {
+	Snapshot
+
+	// Numbers of file descriptors held by the daemon by name
+	Files map[string]uintptr `json:",omitempty"`
+}
+
+// StoreFile stores f under name, so that it is kept open by sys across re-executions
+func (sys *Daemon) StoreFile(name string, f *os.File) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.files[name] = f
+}
+
+// File returns the file stored under name by StoreFile
+func (sys *Daemon) File(name string) (f *os.File, ok bool) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	f, ok = sys.files[name]
+	return
+}
+
+// Serialize writes the state of sys - states of units, queued jobs and held file descriptors to w
+func (sys *Daemon) Serialize(w io.Writer) (err error) {
+	log.Debugf("sys.Serialize")
+
+	sys.mutex.RLock()
+	fds := make(map[string]uintptr, len(sys.files))
+	for name, f := range sys.files {
+		fds[name] = f.Fd()
+	}
+	sys.mutex.RUnlock()
+
+	return sys.serialize(w, fds)
+}
+
+func (sys *Daemon) serialize(w io.Writer, fds map[string]uintptr) (err error) {
+	var snapshot *Snapshot
+	if snapshot, err = sys.Snapshot(); err != nil {
+		return
+	}
+
+	return json.NewEncoder(w).Encode(serialization{
+		Snapshot: *snapshot,
+		Files:    fds,
+	})
+}
+
+// Deserialize restores the state of sys written to r by Serialize, see RestoreSnapshot
+func (sys *Daemon) Deserialize(r io.Reader) (err error) {
+	log.Debugf("sys.Deserialize")
+
+	var s serialization
+	if err = json.NewDecoder(r).Decode(&s); err != nil {
+		return
+	}
+
+	sys.mutex.Lock()
+	for name, fd := range s.Files {
+		sys.files[name] = os.NewFile(fd, name)
+	}
+	sys.mutex.Unlock()
+
+	// The boot continues across re-executions
+	if s.Boot != "" {
+		sys.setBootID(s.Boot)
+	}
+	return sys.RestoreSnapshot(&s.Snapshot)
+}
+
+// isInPaths returns whether path is located in one of the unit paths
+func (sys *Daemon) isInPaths(path string) bool {
+	for _, dir := range sys.Paths() {
+		if filepath.Clean(filepath.Dir(path)) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseJobType returns the jobType named s
+func parseJobType(s string) (typ jobType, ok bool) {
+	for typ = 0; typ < job_type_count; typ++ {
+		if fmt.Sprint(typ) == s {
+			return typ, true
+		}
+	}
+	return -1, false
+}
+
+// Reexec serializes the state of sys and re-executes the running binary, which is expected
+// to call Restore on startup. Processes of running services and held file descriptors are inherited.
+// On success Reexec does not return
+func (sys *Daemon) Reexec() (err error) {
+	log.Debugf("sys.Reexec")
+
+	var exe string
+	if exe, err = os.Executable(); err != nil {
+		return
+	}
+
+	// Duplicated file descriptors are not closed on exec
+	fds := map[string]uintptr{}
+	dups := []int{}
+	defer func() {
+		for _, fd := range dups {
+			syscall.Close(fd)
+		}
+	}()
+
+	sys.mutex.RLock()
+	for name, f := range sys.files {
+		var fd int
+		if fd, err = syscall.Dup(int(f.Fd())); err != nil {
+			sys.mutex.RUnlock()
+			return
+		}
+		dups = append(dups, fd)
+		fds[name] = uintptr(fd)
+	}
+	sys.mutex.RUnlock()
+
+	var state *os.File
+	if state, err = ioutil.TempFile("", "systemgo-state"); err != nil {
+		return
+	}
+	defer state.Close()
+	os.Remove(state.Name())
+
+	if err = sys.serialize(state, fds); err != nil {
+		return
+	}
+	if _, err = state.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	var fd int
+	if fd, err = syscall.Dup(int(state.Fd())); err != nil {
+		return
+	}
+	dups = append(dups, fd)
+
+	env := append(os.Environ(), fmt.Sprintf("%s=%d", SERIALIZATION_FD_ENV, fd))
+
+	log.Infof("Re-executing %s", exe)
+	return syscall.Exec(exe, os.Args, env)
+}
+
+// Restore restores the state of sys serialized by Reexec, if the process was started by it.
+// Returns whether the state was restored
+func (sys *Daemon) Restore() (restored bool, err error) {
+	v := os.Getenv(SERIALIZATION_FD_ENV)
+	if v == "" {
+		return false, nil
+	}
+	os.Unsetenv(SERIALIZATION_FD_ENV)
+
+	log.Debugf("sys.Restore")
+
+	var fd int
+	if fd, err = strconv.Atoi(v); err != nil {
+		return false, err
+	}
+
+	f := os.NewFile(uintptr(fd), "state")
+	defer f.Close()
+
+	if err = sys.Deserialize(f); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,80 @@
+package system
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestSerialize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serialize-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"oneshot.service": "[Service]\nType=oneshot\nExecStart=/bin/true\nRemainAfterExit=yes",
+		"idle.service":    "[Service]\nExecStart=/bin/true",
+		"masked.service":  "[Service]\nExecStart=/bin/true",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	require.NoError(t, sys.Start("oneshot.service"))
+	_, err = sys.Get("idle.service")
+	require.NoError(t, err)
+	sys.masked["masked.service"] = true
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+	sys.StoreFile("pipe", r)
+
+	b := &bytes.Buffer{}
+	require.NoError(t, sys.Serialize(b))
+
+	restored := New()
+	restored.SetPaths(dir)
+	require.NoError(t, restored.Deserialize(b))
+
+	u, err := restored.Unit("oneshot.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Active, u.Active())
+	assert.Equal(t, "exited", u.Sub())
+
+	u, err = restored.Unit("idle.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Inactive, u.Active())
+
+	u, err = restored.Get("masked.service")
+	require.NoError(t, err)
+	assert.True(t, u.IsMasked())
+
+	// The restored file shares the descriptor with r, both are closed at once, so that neither closes
+	// the descriptor, once it is reused, when finalized
+	if f, ok := restored.File("pipe"); assert.True(t, ok) {
+		assert.Equal(t, r.Fd(), f.Fd())
+		f.Close()
+	}
+	r.Close()
+	assert.Equal(t, sys.Since().Unix(), restored.Since().Unix())
+}
+
+func TestParseJobType(t *testing.T) {
+	for typ := jobType(0); typ < job_type_count; typ++ {
+		parsed, ok := parseJobType(typ.String())
+		assert.True(t, ok)
+		assert.Equal(t, typ, parsed)
+	}
+
+	_, ok := parseJobType("unknown")
+	assert.False(t, ok)
+}
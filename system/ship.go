@@ -0,0 +1,367 @@
+package system
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Limits of buffering of the entries shipped to a remote endpoint
+const (
+	// Number of entries queued for shipping, further entries are dropped, until the queue is drained
+	SHIP_QUEUE_SIZE = 4096
+
+	// Number of entries buffered, while the endpoint is unavailable, oldest entries are dropped first
+	SHIP_BUFFER_SIZE = 65536
+
+	// Maximum number of entries sent at once
+	SHIP_BATCH_SIZE = 512
+)
+
+// Intervals of shipping the entries to a remote endpoint
+const (
+	// Interval, at which the entries buffered are sent
+	SHIP_FLUSH_INTERVAL = time.Second
+
+	// Delays before retrying to send the entries, after sending fails, doubled on each failure
+	SHIP_RETRY_MIN = time.Second
+	SHIP_RETRY_MAX = time.Minute
+
+	// Timeout of connecting and sending the entries
+	SHIP_TIMEOUT = 10 * time.Second
+)
+
+// Content type of the entries shipped to HTTP endpoints, as accepted by systemd-journal-remote
+const JOURNAL_EXPORT_CONTENT_TYPE = "application/vnd.fdo.journal"
+
+// LogShipConfig specifies the remote endpoint the entries of the unified log are shipped to
+type LogShipConfig struct {
+	// URL of the endpoint: "tls://HOST:PORT" for syslog over TLS(RFC 5425) or
+	// "http(s)://HOST[:PORT]/PATH" for an HTTP endpoint accepting entries in the Journal Export Format
+	URL string
+
+	// Path to the PEM encoded certificates of the authorities verifying the endpoint
+	// (empty means the authorities of the system)
+	CAFile string
+}
+
+// shippedEntry is an entry of the unified log queued for shipping
+type shippedEntry struct {
+	LogEntry
+
+	// Identifier and facility of the entry sent to syslog
+	identifier string
+	facility   int
+}
+
+// logTransport sends entries to a remote endpoint
+type logTransport interface {
+	// send sends the entries and returns the number of entries sent, the rest gets sent again later
+	send(entries []shippedEntry) (n int, err error)
+
+	close()
+}
+
+// logShipper streams the unified log to a remote endpoint, buffering the entries and retrying,
+// while the endpoint is unavailable
+type logShipper struct {
+	transport logTransport
+
+	flushInterval, retryMin, retryMax time.Duration
+
+	queue chan shippedEntry
+	done  chan struct{}
+
+	// Closed, when the entries queued are sent after done is closed
+	stopped chan struct{}
+
+	closeOnce sync.Once
+}
+
+// ShipLogs makes the entries of the manager, units and the kernel be streamed to the remote endpoint
+// specified by config. Entries are buffered, while the endpoint is unavailable, and sent again after
+// a delay growing up to SHIP_RETRY_MAX. The endpoint shipped to before, if any, is replaced
+func (sys *Daemon) ShipLogs(config LogShipConfig) (err error) {
+	log.WithField("config", config).Debugf("sys.ShipLogs")
+
+	var t logTransport
+	if t, err = newLogTransport(config); err != nil {
+		return
+	}
+	sys.setShipper(newLogShipper(t))
+	return nil
+}
+
+// newLogTransport returns the transport sending entries to the endpoint specified by config
+func newLogTransport(config LogShipConfig) (t logTransport, err error) {
+	var u *url.URL
+	if u, err = url.Parse(config.URL); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{}
+	if config.CAFile != "" {
+		var b []byte
+		if b, err = ioutil.ReadFile(config.CAFile); err != nil {
+			return nil, err
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CAFile)
+		}
+	}
+
+	switch u.Scheme {
+	case "tls":
+		if _, _, err = net.SplitHostPort(u.Host); err != nil {
+			return nil, err
+		}
+
+		t := &syslogTLSTransport{addr: u.Host, config: tlsConfig}
+		if t.hostname, err = os.Hostname(); err != nil {
+			t.hostname = "-"
+		}
+		return t, nil
+
+	case "http", "https":
+		return &httpTransport{
+			url: u.String(),
+			client: &http.Client{
+				Timeout:   SHIP_TIMEOUT,
+				Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported log shipping scheme: %q", u.Scheme)
+	}
+}
+
+func newLogShipper(t logTransport) *logShipper {
+	return &logShipper{
+		transport:     t,
+		flushInterval: SHIP_FLUSH_INTERVAL,
+		retryMin:      SHIP_RETRY_MIN,
+		retryMax:      SHIP_RETRY_MAX,
+		queue:         make(chan shippedEntry, SHIP_QUEUE_SIZE),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// setShipper starts s and makes sys ship the entries logged with it, replacing the previous shipper, if any
+func (sys *Daemon) setShipper(s *logShipper) {
+	go s.run()
+
+	sys.logMutex.Lock()
+	prev := sys.shipper
+	sys.shipper = s
+	sys.logMutex.Unlock()
+
+	if prev != nil {
+		prev.close()
+	}
+}
+
+// closeShipper stops shipping entries, once the entries queued are sent or sending them fails
+func (sys *Daemon) closeShipper() {
+	sys.logMutex.Lock()
+	s := sys.shipper
+	sys.shipper = nil
+	sys.logMutex.Unlock()
+
+	if s != nil {
+		s.close()
+	}
+}
+
+// shipLog queues e logged by u, nil for the manager, for shipping, if enabled
+func (sys *Daemon) shipLog(u *Unit, e LogEntry) {
+	sys.logMutex.Lock()
+	s := sys.shipper
+	sys.logMutex.Unlock()
+
+	if s == nil {
+		return
+	}
+
+	se := shippedEntry{LogEntry: e}
+	se.identifier, se.facility = syslogIdentity(u, e)
+	s.send(se)
+}
+
+// send queues e, e is dropped, if the queue is full
+func (s *logShipper) send(e shippedEntry) {
+	select {
+	case <-s.done:
+	case s.queue <- e:
+	default:
+		log.Debugf("Log shipping queue is full, dropping entry")
+	}
+}
+
+// run sends the entries queued in batches every flushInterval or, as soon as a batch is full.
+// If sending fails, the entries are kept in the buffer and sending is retried after a delay
+func (s *logShipper) run() {
+	defer close(s.stopped)
+	defer s.transport.close()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	var buf []shippedEntry
+	var retryAt time.Time
+	delay := s.retryMin
+
+	push := func(e shippedEntry) {
+		buf = append(buf, e)
+		if len(buf) > SHIP_BUFFER_SIZE {
+			log.Debugf("Log shipping buffer is full, dropping the oldest entry")
+			buf = buf[1:]
+		}
+	}
+
+	flush := func() error {
+		for len(buf) > 0 {
+			batch := buf
+			if len(batch) > SHIP_BATCH_SIZE {
+				batch = batch[:SHIP_BATCH_SIZE]
+			}
+
+			n, err := s.transport.send(batch)
+			buf = buf[n:]
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case e := <-s.queue:
+			push(e)
+			if len(buf) < SHIP_BATCH_SIZE || time.Now().Before(retryAt) {
+				continue
+			}
+		case <-ticker.C:
+			if time.Now().Before(retryAt) {
+				continue
+			}
+		case <-s.done:
+			for len(s.queue) > 0 {
+				push(<-s.queue)
+			}
+			if err := flush(); err != nil {
+				log.Errorf("Error shipping logs, %d entries are lost: %s", len(buf), err)
+			}
+			return
+		}
+
+		if err := flush(); err != nil {
+			log.Debugf("Error shipping logs, retrying in %s: %s", delay, err)
+			retryAt = time.Now().Add(delay)
+			if delay *= 2; delay > s.retryMax {
+				delay = s.retryMax
+			}
+			continue
+		}
+		retryAt, delay = time.Time{}, s.retryMin
+	}
+}
+
+// close stops s, once the entries queued are sent or sending them fails
+func (s *logShipper) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	<-s.stopped
+}
+
+// syslogTLSTransport sends entries as RFC 5424 messages to a syslog endpoint over TLS, see RFC 5425
+type syslogTLSTransport struct {
+	addr     string
+	config   *tls.Config
+	hostname string
+
+	// Connection to the endpoint, nil if not connected
+	conn net.Conn
+}
+
+func (t *syslogTLSTransport) send(entries []shippedEntry) (n int, err error) {
+	if t.conn == nil {
+		dialer := &net.Dialer{Timeout: SHIP_TIMEOUT}
+		if t.conn, err = tls.DialWithDialer(dialer, "tcp", t.addr, t.config); err != nil {
+			t.conn = nil
+			return 0, err
+		}
+	}
+
+	t.conn.SetWriteDeadline(time.Now().Add(SHIP_TIMEOUT))
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		msg := formatRFC5424(e.LogEntry, e.facility, t.hostname, e.identifier)
+
+		// Messages are framed by octet counting
+		buf.WriteString(strconv.Itoa(len(msg)) + " ")
+		buf.Write(msg)
+	}
+
+	if _, err = t.conn.Write(buf.Bytes()); err != nil {
+		t.close()
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func (t *syslogTLSTransport) close() {
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// httpTransport posts entries in the Journal Export Format to an HTTP endpoint,
+// e.g. the /upload endpoint of systemd-journal-remote
+type httpTransport struct {
+	url    string
+	client *http.Client
+}
+
+func (t *httpTransport) send(entries []shippedEntry) (n int, err error) {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		if err = WriteExport(&buf, e.LogEntry); err != nil {
+			return 0, err
+		}
+	}
+
+	var resp *http.Response
+	if resp, err = t.client.Post(t.url, JOURNAL_EXPORT_CONTENT_TYPE, &buf); err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("%s responded with %s", t.url, resp.Status)
+	}
+	return len(entries), nil
+}
+
+func (t *httpTransport) close() {
+	t.client.CloseIdleConnections()
+}
@@ -0,0 +1,156 @@
+package system
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogTransport(t *testing.T) {
+	for _, url := range []string{"tls://logs:6514", "http://logs/upload", "https://logs:19532/upload"} {
+		_, err := newLogTransport(LogShipConfig{URL: url})
+		assert.NoError(t, err, url)
+	}
+
+	for _, url := range []string{"tls://logs", "udp://logs:514", "logs"} {
+		_, err := newLogTransport(LogShipConfig{URL: url})
+		assert.Error(t, err, url)
+	}
+
+	_, err := newLogTransport(LogShipConfig{URL: "https://logs/upload", CAFile: "/nonexistent"})
+	assert.Error(t, err)
+}
+
+// startShipper makes sys ship logs over t retrying quickly
+func startShipper(sys *Daemon, t logTransport) {
+	s := newLogShipper(t)
+	s.flushInterval, s.retryMin, s.retryMax = 10*time.Millisecond, 10*time.Millisecond, 10*time.Millisecond
+	sys.setShipper(s)
+}
+
+func TestShipLogsHTTP(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		requests int
+		body     string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		requests++
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		assert.Equal(t, JOURNAL_EXPORT_CONTENT_TYPE, r.Header.Get("Content-Type"))
+		b, _ := ioutil.ReadAll(r.Body)
+		body += string(b)
+	}))
+	defer srv.Close()
+
+	tr, err := newLogTransport(LogShipConfig{URL: srv.URL + "/upload"})
+	require.NoError(t, err)
+
+	sys := New()
+	sys.SetPaths()
+	startShipper(sys, tr)
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	u.Log.Error("failed")
+	sys.Log.Println("manager")
+
+	require.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return strings.Contains(body, "MESSAGE=manager")
+	}, 5*time.Second, 10*time.Millisecond)
+	sys.closeShipper()
+
+	assert.True(t, requests > 1, "sending is retried")
+	assert.Equal(t, 1, strings.Count(body, "MESSAGE=failed\n"), "entries are kept until sent")
+	assert.Contains(t, body, "_SYSTEMD_UNIT=foo.service\n")
+}
+
+func TestShipLogsTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ship-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The certificate of the test server is used for the syslog endpoint
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	ca := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644))
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: srv.TLS.Certificates})
+	require.NoError(t, err)
+	defer l.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(length))
+			if err != nil {
+				return
+			}
+
+			b := make([]byte, n)
+			if _, err = io.ReadFull(r, b); err != nil {
+				return
+			}
+			messages <- string(b)
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	tr, err := newLogTransport(LogShipConfig{URL: "tls://127.0.0.1:" + port, CAFile: ca})
+	require.NoError(t, err)
+
+	sys := New()
+	sys.SetPaths()
+	startShipper(sys, tr)
+	defer sys.closeShipper()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true\nSyslogIdentifier=foo-daemon"))
+	require.NoError(t, err)
+	u.Log.WithField("pid", 42).Error("failed")
+
+	select {
+	case msg := <-messages:
+		assert.True(t, strings.HasPrefix(msg, "<27>1 "), msg)
+		assert.True(t, strings.HasSuffix(msg, " foo-daemon 42 - - failed"), msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
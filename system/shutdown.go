@@ -0,0 +1,264 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"systemgo/unit"
+	"systemgo/unit/mount"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Kind of shutdown
+type ShutdownKind int
+
+//go:generate stringer -type=ShutdownKind -linecomment shutdown.go
+const (
+	Poweroff ShutdownKind = iota // poweroff
+	Reboot                       // reboot
+	Halt                         // halt
+	Kexec                        // kexec
+)
+
+const (
+	// Target pulled in by all the targets shutting the system down
+	SHUTDOWN_TARGET = "shutdown.target"
+
+	// Target started to unmount file systems on shutdown
+	UMOUNT_TARGET = "umount.target"
+
+	// Target started as the last step of shutdown
+	FINAL_TARGET = "final.target"
+)
+
+// Prefixes of mount points of API file systems, which do not get unmounted on shutdown
+var apiMounts = []string{"/proc", "/sys", "/dev", "/run"}
+
+// Target returns the name of the target isolated on shutdown of kind k
+func (k ShutdownKind) Target() string {
+	return fmt.Sprintf("%s.target", k)
+}
+
+// rebootCmd returns the argument to reboot(2) corresponding to k
+func (k ShutdownKind) rebootCmd() int {
+	switch k {
+	case Reboot:
+		return syscall.LINUX_REBOOT_CMD_RESTART
+	case Halt:
+		return syscall.LINUX_REBOOT_CMD_HALT
+	case Kexec:
+		return syscall.LINUX_REBOOT_CMD_KEXEC
+	default:
+		return syscall.LINUX_REBOOT_CMD_POWER_OFF
+	}
+}
+
+// ShutdownSystem shuts the system down: the target corresponding to kind is isolated, stopping all
+// units in reverse order, shutdown.target, umount.target and final.target get started, if defined.
+// If running as PID 1, file systems get unmounted and synced and reboot(2) is invoked
+// with the flag corresponding to kind, in which case ShutdownSystem only returns on failure
+func (sys *Daemon) ShutdownSystem(kind ShutdownKind) (err error) {
+	e := log.WithField("kind", kind)
+	e.Debugf("sys.ShutdownSystem")
+
+	sys.setState(Stopping)
+
+	if err = sys.Isolate(kind.Target()); err != nil {
+		e.Errorf("Error isolating %s: %s", kind.Target(), err)
+		if err = sys.stopAll(); err != nil {
+			e.Errorf("Error stopping units: %s", err)
+		}
+	}
+
+	for _, target := range []string{SHUTDOWN_TARGET, UMOUNT_TARGET, FINAL_TARGET} {
+		if _, err := sys.Get(target); err != nil {
+			continue
+		}
+		if err := sys.Start(target); err != nil {
+			e.Errorf("Error starting %s: %s", target, err)
+		}
+	}
+
+	return sys.reboot(kind, true)
+}
+
+// ForceShutdown shuts the system down without stopping units: processes of all units get killed and,
+// if running as PID 1, file systems get unmounted and synced and reboot(2) is invoked
+// with the flag corresponding to kind, in which case ForceShutdown only returns on failure
+func (sys *Daemon) ForceShutdown(kind ShutdownKind) (err error) {
+	log.WithField("kind", kind).Debugf("sys.ForceShutdown")
+
+	sys.setState(Stopping)
+	sys.killAll()
+	return sys.reboot(kind, true)
+}
+
+// RebootNow syncs file systems and invokes reboot(2) with the flag corresponding to kind right away,
+// without stopping units or unmounting file systems. RebootNow only returns on failure
+func RebootNow(kind ShutdownKind) error {
+	syscall.Sync()
+	return syscall.Reboot(kind.rebootCmd())
+}
+
+// Shutdown stops all units in dependency order and releases the resources held by sys,
+// so that programs embedding the Daemon can terminate cleanly. Unlike ShutdownSystem,
+// the system itself is left running.
+// If ctx is done before the stop jobs finish, processes still running get killed
+// and ctx.Err() is returned once the stop jobs finish. The scheduled shutdown of the system, if any,
+// gets cancelled without notifying logged-in users. Watching of the unit paths stops,
+// subscriptions to events and files kept open across re-executions and the journal get closed,
+// forwarding to syslog stops
+func (sys *Daemon) Shutdown(ctx context.Context) (err error) {
+	log.Debugf("sys.Shutdown")
+
+	sys.cancelSchedule()
+	sys.setState(Stopping)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- sys.stopAll()
+	}()
+
+	var expired bool
+	select {
+	case err = <-stopped:
+		if err != nil {
+			log.Errorf("Error stopping units: %s", err)
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		expired = true
+		log.Warnf("Units did not stop in time, killing the remaining processes")
+	}
+
+	// Processes may outlive the stop jobs, which failed or did not finish in time
+	sys.killAll()
+
+	if expired {
+		// The stop jobs still running finish once the processes they wait for are killed
+		if err := <-stopped; err != nil {
+			log.Errorf("Error stopping units: %s", err)
+		}
+	}
+
+	sys.unwatch(nil)
+	sys.closeFiles()
+	sys.closeJournal()
+	sys.closeSyslog()
+	sys.closeKmsg()
+	sys.closeConsole()
+	sys.closeShipper()
+	sys.closeLogSinks()
+	sys.closeTracer()
+	sys.closeSubscriptions()
+	return
+}
+
+// killAll kills the processes still running of all units and waits for them to exit
+func (sys *Daemon) killAll() {
+	for _, u := range sys.Units() {
+		killer, ok := u.Interface.(unit.Killer)
+		if !ok {
+			continue
+		}
+
+		if err := killer.Kill(); err != nil {
+			u.Log.Errorf("Error killing: %s", err)
+		}
+	}
+}
+
+// closeFiles closes the files kept open across re-executions
+func (sys *Daemon) closeFiles() {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	for name, f := range sys.files {
+		if err := f.Close(); err != nil {
+			log.WithField("name", name).Errorf("Error closing: %s", err)
+		}
+		delete(sys.files, name)
+	}
+}
+
+// closeSubscriptions stops delivery of events to all subscribers and closes their channels
+func (sys *Daemon) closeSubscriptions() {
+	sys.eventMutex.Lock()
+	defer sys.eventMutex.Unlock()
+
+	for ch := range sys.subscribers {
+		delete(sys.subscribers, ch)
+		close(ch)
+	}
+}
+
+// stopAll stops all units in a single transaction
+func (sys *Daemon) stopAll() (err error) {
+	tr := newTransaction(sys)
+	tr.irreversible = true
+
+	for _, u := range sys.Units() {
+		if err = tr.add(stop, u, nil, true, true); err != nil {
+			return
+		}
+	}
+	return tr.run()
+}
+
+// mountPoints returns mount points found in /proc/self/mounts in reverse order of mounting
+func mountPoints() (points []string, err error) {
+	var f *os.File
+	if f, err = os.Open(PROC_PATH + "/self/mounts"); err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Spaces and other special characters are octal-escaped
+		points = append([]string{mount.Unescape(fields[1])}, points...)
+	}
+	return points, scanner.Err()
+}
+
+// isAPIMount returns whether point is a mount point of an API file system
+func isAPIMount(point string) bool {
+	for _, prefix := range apiMounts {
+		if point == prefix || strings.HasPrefix(point, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// unmountAll unmounts all file systems, but the API ones and remounts the root file system read-only
+func unmountAll() {
+	points, err := mountPoints()
+	if err != nil {
+		log.Errorf("Error reading mount points: %s", err)
+	}
+
+	for _, point := range points {
+		if point == "/" || isAPIMount(point) {
+			continue
+		}
+
+		if err := syscall.Unmount(point, 0); err != nil {
+			log.WithField("point", point).Errorf("Error unmounting: %s", err)
+		}
+	}
+
+	if err := syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+		log.Errorf("Error remounting / read-only: %s", err)
+	}
+}
@@ -0,0 +1,142 @@
+package system
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestShutdown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shutdown-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	// c is ordered after b, which is ordered after a
+	mocks := map[string]*mockUnit{
+		"a": newMock(ctrl),
+		"b": newMock(ctrl),
+		"c": newMock(ctrl),
+	}
+	after := map[string][]string{
+		"a": {},
+		"b": {"a"},
+		"c": {"b"},
+	}
+
+	for name, m := range mocks {
+		m.MockInterface.EXPECT().Active().Return(unit.Active).AnyTimes()
+		m.MockInterface.EXPECT().After().Return(after[name]).AnyTimes()
+		m.MockInterface.EXPECT().Before().Return([]string{}).AnyTimes()
+
+		u, err := sys.Supervise(name, m)
+		require.NoError(t, err)
+		u.load = unit.Loaded
+	}
+
+	// Units get stopped in reverse order
+	gomock.InOrder(
+		mocks["c"].MockStopper.EXPECT().Stop().Return(nil),
+		mocks["b"].MockStopper.EXPECT().Stop().Return(nil),
+		mocks["a"].MockStopper.EXPECT().Stop().Return(nil),
+	)
+
+	require.NoError(t, sys.ShutdownSystem(Poweroff))
+	assert.Equal(t, Stopping, sys.state)
+}
+
+func TestShutdownKind(t *testing.T) {
+	for kind, target := range map[ShutdownKind]string{
+		Poweroff: "poweroff.target",
+		Reboot:   "reboot.target",
+		Halt:     "halt.target",
+		Kexec:    "kexec.target",
+	} {
+		assert.Equal(t, target, kind.Target())
+	}
+
+	assert.True(t, isAPIMount("/proc"))
+	assert.True(t, isAPIMount("/sys/fs/cgroup"))
+	assert.False(t, isAPIMount("/"))
+	assert.False(t, isAPIMount("/home"))
+	assert.False(t, isAPIMount("/devices"))
+}
+
+func TestShutdownContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shutdown-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"foo.service": "[Service]\nExecStart=/bin/sleep 60",
+		"bar.service": "[Unit]\nAfter=foo.service\n[Service]\nExecStart=/bin/sleep 60\nExecStop=/bin/sleep 1",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(dir)
+	require.NoError(t, sys.Start("foo.service", "bar.service"))
+
+	stop, err := sys.Watch()
+	require.NoError(t, err)
+	defer stop()
+
+	events := sys.Subscribe()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The stop command of bar.service outlives ctx, so the processes get killed
+	assert.Equal(t, context.DeadlineExceeded, sys.Shutdown(ctx))
+	assert.Equal(t, Stopping, sys.State())
+
+	for _, name := range []string{"foo.service", "bar.service"} {
+		u, err := sys.Unit(name)
+		require.NoError(t, err)
+		assert.False(t, u.IsActive(), name)
+		assert.Nil(t, u.runningJob(), name)
+	}
+	assert.Nil(t, sys.watcher)
+
+	for range events {
+	}
+}
+
+func TestForceShutdown(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/sleep 60\nExecStop=/bin/sleep 60"))
+	require.NoError(t, err)
+	require.NoError(t, u.start())
+
+	// Units are not stopped, hence ExecStop= does not delay the shutdown
+	done := make(chan error, 1)
+	go func() {
+		done <- sys.ForceShutdown(Reboot)
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ForceShutdown did not return in time")
+	}
+	assert.Equal(t, Stopping, sys.State())
+	assert.False(t, u.IsActive())
+}
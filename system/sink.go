@@ -0,0 +1,255 @@
+package system
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Number of entries queued for writing to a sink, further entries are dropped, until the queue is drained
+const LOG_SINK_QUEUE_SIZE = 1024
+
+// Socket of systemd-journald accepting entries in the native protocol
+const JOURNALD_SOCKET_PATH = "/run/systemd/journal/socket"
+
+// LogSink receives the entries of the unified log, see Daemon.AddLogSink.
+// If the sink implements io.Closer, it is closed, once removed
+type LogSink interface {
+	// WriteEntry writes e, entries are written one at a time in the order they are logged
+	WriteEntry(e LogEntry) error
+}
+
+// logSinkForwarder writes the entries queued to a sink
+type logSinkForwarder struct {
+	name string
+	sink LogSink
+
+	queue chan LogEntry
+	done  chan struct{}
+
+	// Closed, when the queue is drained after done is closed
+	stopped chan struct{}
+
+	closeOnce sync.Once
+}
+
+// AddLogSink makes the entries logged by the manager, units and the kernel be written to sink in addition
+// to the built-in destinations. Entries are queued, so that a slow sink does not block logging.
+// The sink added with the same name before, if any, is replaced
+func (sys *Daemon) AddLogSink(name string, sink LogSink) {
+	log.WithField("name", name).Debugf("sys.AddLogSink")
+
+	f := &logSinkForwarder{
+		name:    name,
+		sink:    sink,
+		queue:   make(chan LogEntry, LOG_SINK_QUEUE_SIZE),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go f.run()
+
+	sys.logMutex.Lock()
+	if sys.sinks == nil {
+		sys.sinks = map[string]*logSinkForwarder{}
+	}
+	prev := sys.sinks[name]
+	sys.sinks[name] = f
+	sys.logMutex.Unlock()
+
+	if prev != nil {
+		prev.close()
+	}
+}
+
+// RemoveLogSink stops writing entries to the sink added with name specified, once the entries queued are written.
+// Returns ErrNotFound, if no such sink was added
+func (sys *Daemon) RemoveLogSink(name string) (err error) {
+	log.WithField("name", name).Debugf("sys.RemoveLogSink")
+
+	sys.logMutex.Lock()
+	f, ok := sys.sinks[name]
+	delete(sys.sinks, name)
+	sys.logMutex.Unlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	f.close()
+	return nil
+}
+
+// LogSinks returns the names of the sinks added, sorted
+func (sys *Daemon) LogSinks() (names []string) {
+	sys.logMutex.Lock()
+	defer sys.logMutex.Unlock()
+
+	for name := range sys.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// closeLogSinks removes all sinks
+func (sys *Daemon) closeLogSinks() {
+	sys.logMutex.Lock()
+	sinks := sys.sinks
+	sys.sinks = nil
+	sys.logMutex.Unlock()
+
+	for _, f := range sinks {
+		f.close()
+	}
+}
+
+// writeLogSinks queues e for writing to the sinks added
+func (sys *Daemon) writeLogSinks(e LogEntry) {
+	sys.logMutex.Lock()
+	defer sys.logMutex.Unlock()
+
+	for _, f := range sys.sinks {
+		f.send(e)
+	}
+}
+
+// send queues e, e is dropped, if the queue is full
+func (f *logSinkForwarder) send(e LogEntry) {
+	select {
+	case <-f.done:
+	case f.queue <- e:
+	default:
+		log.WithField("sink", f.name).Debugf("Log sink queue is full, dropping entry")
+	}
+}
+
+// run writes the entries queued to the sink and closes it, once done
+func (f *logSinkForwarder) run() {
+	defer close(f.stopped)
+	defer func() {
+		if c, ok := f.sink.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.WithField("sink", f.name).Debugf("Error closing log sink: %s", err)
+			}
+		}
+	}()
+
+	write := func(e LogEntry) {
+		if err := f.sink.WriteEntry(e); err != nil {
+			log.WithField("sink", f.name).Debugf("Error writing to log sink: %s", err)
+		}
+	}
+
+	for {
+		select {
+		case e := <-f.queue:
+			write(e)
+		case <-f.done:
+			for {
+				select {
+				case e := <-f.queue:
+					write(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// close stops f, once the entries queued are written
+func (f *logSinkForwarder) close() {
+	f.closeOnce.Do(func() {
+		close(f.done)
+	})
+	<-f.stopped
+}
+
+// FileLogSink appends entries to a file as JSON lines
+type FileLogSink struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileLogSink returns a sink appending entries to the file at path, which is created, if it does not exist
+func NewFileLogSink(path string) (s *FileLogSink, err error) {
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640); err != nil {
+		return nil, err
+	}
+	return &FileLogSink{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (s *FileLogSink) WriteEntry(e LogEntry) error {
+	return s.enc.Encode(e)
+}
+
+func (s *FileLogSink) Close() error {
+	return s.file.Close()
+}
+
+// JournaldLogSink passes entries through to systemd-journald, e.g. on hybrid systems running both managers
+type JournaldLogSink struct {
+	conn *net.UnixConn
+}
+
+// NewJournaldLogSink returns a sink passing entries to the socket of systemd-journald at path,
+// JOURNALD_SOCKET_PATH if empty
+func NewJournaldLogSink(path string) (s *JournaldLogSink, err error) {
+	if path == "" {
+		path = JOURNALD_SOCKET_PATH
+	}
+
+	var conn *net.UnixConn
+	if conn, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"}); err != nil {
+		return nil, err
+	}
+	return &JournaldLogSink{conn: conn}, nil
+}
+
+func (s *JournaldLogSink) WriteEntry(e LogEntry) (err error) {
+	_, err = s.conn.Write(formatJournald(e))
+	return
+}
+
+func (s *JournaldLogSink) Close() error {
+	return s.conn.Close()
+}
+
+// formatJournald formats e as a datagram of the native protocol of systemd-journald
+func formatJournald(e LogEntry) []byte {
+	var buf bytes.Buffer
+
+	identifier, _ := syslogIdentity(nil, e)
+	writeJournaldField(&buf, "MESSAGE", e.Message)
+	writeJournaldField(&buf, "PRIORITY", strconv.Itoa(e.Priority))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", identifier)
+	if e.PID != 0 {
+		writeJournaldField(&buf, "SYSLOG_PID", strconv.Itoa(e.PID))
+	}
+	if e.Unit != "" {
+		writeJournaldField(&buf, "UNIT", e.Unit)
+	}
+	return buf.Bytes()
+}
+
+// writeJournaldField writes the field to buf, values containing newlines are written
+// prefixed by their length as a little-endian 64-bit integer
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
@@ -0,0 +1,129 @@
+package system
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink records entries written to it
+type memorySink struct {
+	entries []LogEntry
+	closed  bool
+	mutex   sync.Mutex
+}
+
+func (s *memorySink) WriteEntry(e LogEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *memorySink) messages() (messages []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, e := range s.entries {
+		messages = append(messages, e.Message)
+	}
+	return
+}
+
+func TestLogSinks(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	u, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+
+	first, second := &memorySink{}, &memorySink{}
+	sys.AddLogSink("first", first)
+	sys.AddLogSink("second", second)
+	assert.Equal(t, []string{"first", "second"}, sys.LogSinks())
+
+	u.Log.Println("unit")
+	sys.Log.Println("manager")
+
+	require.NoError(t, sys.RemoveLogSink("first"))
+	assert.True(t, first.closed, "sink is closed, once removed")
+	assert.Equal(t, []string{"unit", "manager"}, first.messages(), "queued entries are written before closing")
+	assert.Equal(t, ErrNotFound, sys.RemoveLogSink("first"))
+
+	replaced := &memorySink{}
+	sys.AddLogSink("second", replaced)
+	assert.True(t, second.closed, "replaced sink is closed")
+
+	sys.Log.Println("replaced")
+	sys.closeLogSinks()
+	assert.Equal(t, []string{"replaced"}, replaced.messages())
+	assert.Empty(t, sys.LogSinks())
+}
+
+func TestFileLogSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log.json")
+	s, err := NewFileLogSink(path)
+	require.NoError(t, err)
+
+	t0 := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.WriteEntry(LogEntry{Unit: "foo.service", Time: t0, Priority: LOG_ERR, Message: "failed"}))
+	require.NoError(t, s.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var e LogEntry
+	sc := bufio.NewScanner(f)
+	require.True(t, sc.Scan())
+	require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+	assert.Equal(t, "failed", e.Message)
+	assert.True(t, t0.Equal(e.Time))
+}
+
+func TestJournaldLogSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewJournaldLogSink(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.WriteEntry(LogEntry{Unit: "foo.service", PID: 42, Priority: LOG_WARNING, Message: "two\nlines"}))
+
+	b := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\n"+
+		"PRIORITY=4\nSYSLOG_IDENTIFIER=foo\nSYSLOG_PID=42\nUNIT=foo.service\n", string(b[:n]))
+}
@@ -0,0 +1,159 @@
+package system
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Snapshot is a serializable view of the state of a Daemon
+type Snapshot struct {
+	State      State
+	Since      time.Time
+	BootTarget string
+
+	// ID of the boot the snapshot was taken during
+	Boot string `json:",omitempty"`
+
+	Masked []string `json:",omitempty"`
+
+	// Units sorted by name
+	Units []UnitSnapshot
+
+	// Jobs, which have not finished at the moment the snapshot was taken
+	Jobs []JobSnapshot `json:",omitempty"`
+}
+
+// UnitSnapshot is the state of a unit in a Snapshot
+type UnitSnapshot struct {
+	Name string
+	Path string `json:",omitempty"`
+
+	Load   unit.Load       `json:",omitempty"`
+	Active unit.Activation `json:",omitempty"`
+	Sub    string          `json:",omitempty"`
+	Enable unit.Enable     `json:",omitempty"`
+
+	// PID of the main process, 0 if there is none
+	MainPID int `json:",omitempty"`
+
+	// Runtime state as returned by Serialize of unit.Serializer
+	State json.RawMessage `json:",omitempty"`
+}
+
+// JobSnapshot is a job in a Snapshot
+type JobSnapshot struct {
+	Unit string
+	Type string
+}
+
+// Snapshot returns the state of sys - states of units and jobs, which have not finished
+func (sys *Daemon) Snapshot() (s *Snapshot, err error) {
+	log.Debugf("sys.Snapshot")
+
+	sys.mutex.RLock()
+	s = &Snapshot{
+		State:      sys.state,
+		Since:      sys.since,
+		BootTarget: sys.bootTarget,
+		Boot:       sys.BootID(),
+	}
+	for name := range sys.masked {
+		s.Masked = append(s.Masked, name)
+	}
+	sys.mutex.RUnlock()
+	sort.Strings(s.Masked)
+
+	units := sys.Units()
+	sort.Slice(units, func(i, j int) bool { return units[i].Name() < units[j].Name() })
+
+	for _, u := range units {
+		us := UnitSnapshot{
+			Name:   u.Name(),
+			Path:   u.Path(),
+			Load:   u.Loaded(),
+			Active: u.Active(),
+			Sub:    u.Sub(),
+			Enable: u.EnableState(),
+		}
+
+		if pider, ok := u.Interface.(unit.MainPIDer); ok {
+			us.MainPID = pider.MainPID()
+		}
+
+		if serializer, ok := u.Interface.(unit.Serializer); ok && u.IsLoaded() {
+			if us.State, err = serializer.Serialize(); err != nil {
+				return nil, fmt.Errorf("%s: %s", u.Name(), err)
+			}
+		}
+		s.Units = append(s.Units, us)
+
+		if j := u.currentJob(); j != nil && (j.State() == waiting || j.IsRunning()) {
+			s.Jobs = append(s.Jobs, JobSnapshot{
+				Unit: u.Name(),
+				Type: fmt.Sprint(j.typ),
+			})
+		}
+	}
+	return s, nil
+}
+
+// RestoreSnapshot restores the state of sys from s as returned by Snapshot.
+// Units get loaded from disk and have their runtime states restored, jobs, which have not finished
+// at the moment the snapshot was taken, get queued again
+func (sys *Daemon) RestoreSnapshot(s *Snapshot) (err error) {
+	log.Debugf("sys.RestoreSnapshot")
+
+	sys.mutex.Lock()
+	sys.state = s.State
+	sys.since = s.Since
+	sys.bootTarget = s.BootTarget
+	for _, name := range s.Masked {
+		sys.masked[name] = true
+	}
+	sys.mutex.Unlock()
+
+	for _, us := range s.Units {
+		name := us.Name
+		if us.Path != "" && !sys.isInPaths(us.Path) {
+			name = us.Path
+		}
+
+		u, err := sys.Get(name)
+		if err != nil {
+			log.WithField("unit", us.Name).Errorf("Error restoring unit: %s", err)
+			continue
+		}
+
+		if len(us.State) == 0 {
+			continue
+		}
+
+		if serializer, ok := u.Interface.(unit.Serializer); ok {
+			if err = serializer.Deserialize(us.State); err != nil {
+				u.Log.Errorf("Error restoring state: %s", err)
+			}
+		}
+	}
+
+	for _, sj := range s.Jobs {
+		typ, ok := parseJobType(sj.Type)
+		if !ok {
+			log.WithField("unit", sj.Unit).Errorf("Unknown job type: %s", sj.Type)
+			continue
+		}
+
+		tr, err := sys.newTransaction(typ, []string{sj.Unit})
+		if err != nil {
+			log.WithField("unit", sj.Unit).Errorf("Error restoring %s job: %s", sj.Type, err)
+			continue
+		}
+		go tr.run()
+	}
+	return nil
+}
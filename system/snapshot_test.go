@@ -0,0 +1,73 @@
+package system
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"oneshot.service": "[Service]\nType=oneshot\nExecStart=/bin/true\nRemainAfterExit=yes",
+		"simple.service":  "[Service]\nExecStart=/bin/sleep 60",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	require.NoError(t, sys.Start("oneshot.service", "simple.service"))
+	defer sys.Stop("simple.service")
+	sys.masked["masked.service"] = true
+
+	s, err := sys.Snapshot()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"masked.service"}, s.Masked)
+	assert.Empty(t, s.Jobs)
+	if assert.Len(t, s.Units, 2) {
+		us := s.Units[0]
+		assert.Equal(t, "oneshot.service", us.Name)
+		assert.Equal(t, filepath.Join(dir, "oneshot.service"), us.Path)
+		assert.Equal(t, unit.Loaded, us.Load)
+		assert.Equal(t, unit.Active, us.Active)
+		assert.Equal(t, "exited", us.Sub)
+		assert.Zero(t, us.MainPID)
+
+		us = s.Units[1]
+		assert.Equal(t, "simple.service", us.Name)
+		assert.Equal(t, unit.Active, us.Active)
+		assert.Equal(t, "running", us.Sub)
+		assert.NotZero(t, us.MainPID)
+	}
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	var decoded Snapshot
+	require.NoError(t, json.Unmarshal(b, &decoded))
+
+	restored := New()
+	restored.SetPaths(dir)
+	require.NoError(t, restored.RestoreSnapshot(&decoded))
+
+	u, err := restored.Unit("oneshot.service")
+	require.NoError(t, err)
+	assert.Equal(t, unit.Active, u.Active())
+
+	u, err = restored.Get("masked.service")
+	require.NoError(t, err)
+	assert.True(t, u.IsMasked())
+	assert.Equal(t, sys.Since().Unix(), restored.Since().Unix())
+}
@@ -0,0 +1,487 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Interval, at which the sockets of units are checked for incoming traffic, while their service is active
+// and, at most, between checks for the watcher having been stopped
+const SOCKET_POLL_INTERVAL = 100 * time.Millisecond
+
+// socketWatcher starts the service of a socket unit on incoming traffic
+type socketWatcher struct {
+	u *Unit
+
+	done chan struct{}
+
+	// Held while the sockets are polled, so that they are not closed meanwhile
+	mutex sync.Mutex
+
+	// Number of connections accepted, if Accept= is set
+	accepted int
+
+	// Names of the instances spawned per connection accepted, which may still be running
+	instances []string
+
+	// Time the service was last seen busy, zero if the service is not active
+	lastBusy time.Time
+
+	// Whether the service was stopped being idle
+	idleStopped bool
+}
+
+// connection is a connection accepted on a socket
+type connection struct {
+	file *os.File
+
+	// Instance of the template service handling the connection, see connectionInstance
+	instance string
+}
+
+// accepts returns whether l spawns an instance of its service per connection accepted
+func accepts(l unit.Listener) bool {
+	a, ok := l.(unit.Acceptor)
+	return ok && a.Accept()
+}
+
+// listenerService returns the name of the service activated by u, the template of the instances,
+// if u spawns an instance per connection accepted
+func listenerService(u *Unit, l unit.Listener) string {
+	if name := l.Service(); name != "" {
+		return name
+	}
+	if accepts(l) {
+		return strings.TrimSuffix(u.Name(), ".socket") + "@.service"
+	}
+	return strings.TrimSuffix(u.Name(), ".socket") + ".service"
+}
+
+// setListenFiles passes the listening sockets of active socket units activating u to u, if it spawns processes.
+// Sockets are passed ordered by the name of the socket unit
+func (sys *Daemon) setListenFiles(u *Unit) {
+	setter, ok := u.Interface.(unit.ListenFilesSetter)
+	if !ok {
+		return
+	}
+
+	var sockets []*Unit
+	for _, su := range sys.Units() {
+		if l, ok := su.Interface.(unit.Listener); ok && listenerService(su, l) == u.Name() {
+			sockets = append(sockets, su)
+		}
+	}
+	sort.Slice(sockets, func(i, j int) bool {
+		return sockets[i].Name() < sockets[j].Name()
+	})
+
+	var (
+		files []*os.File
+		names []string
+	)
+	for _, su := range sockets {
+		l := su.Interface.(unit.Listener)
+
+		name := l.FileDescriptorName()
+		if name == "" {
+			name = su.Name()
+		}
+		for _, f := range l.Files() {
+			files = append(files, f)
+			names = append(names, name)
+		}
+	}
+	setter.SetListenFiles(files, names)
+}
+
+// watchSocket starts the service of u on incoming traffic on the sockets of u, if u holds listening sockets
+func (sys *Daemon) watchSocket(u *Unit) {
+	if _, ok := u.Interface.(unit.Listener); !ok {
+		return
+	}
+
+	w := &socketWatcher{
+		u:    u,
+		done: make(chan struct{}),
+	}
+
+	sys.socketMutex.Lock()
+	if sys.sockets == nil {
+		sys.sockets = map[*Unit]*socketWatcher{}
+	}
+	prev := sys.sockets[u]
+	sys.sockets[u] = w
+	sys.socketMutex.Unlock()
+
+	if prev != nil {
+		prev.stop()
+	}
+	go w.run(sys)
+}
+
+// unwatchSocket stops the watcher of u started by watchSocket, if any
+func (sys *Daemon) unwatchSocket(u *Unit) {
+	sys.socketMutex.Lock()
+	w, ok := sys.sockets[u]
+	delete(sys.sockets, u)
+	sys.socketMutex.Unlock()
+
+	if ok {
+		w.stop()
+	}
+}
+
+// run waits for incoming traffic on the sockets and starts the service, unless it is already active,
+// or, if Accept= is set, accepts the connections and spawns an instance of the service per connection
+func (w *socketWatcher) run(sys *Daemon) {
+	l := w.u.Interface.(unit.Listener)
+	name := listenerService(w.u, l)
+	accepting := accepts(l)
+
+	log.WithField("socket", w.u.Name()).Debugf("Watching for traffic activating %s", name)
+	for {
+		st, err := sys.IsActive(name)
+		if !accepting && err == nil && (st == unit.Active || st == unit.Activating) {
+			// The service handles the traffic itself
+			w.stopIdle(sys, name, l)
+			if !w.sleep() {
+				return
+			}
+			continue
+		}
+		w.lastBusy = time.Time{}
+
+		if w.idleStopped && st == unit.Failed {
+			// Killed by the manager, the service has not failed
+			sys.ResetFailed(name)
+		}
+		w.idleStopped = false
+
+		ready, conns, err := w.poll(l, accepting, SOCKET_POLL_INTERVAL)
+		switch {
+		case err == errWatcherStopped:
+			return
+		case err == unix.EINTR:
+			continue
+		case err != nil:
+			w.u.Log.Errorf("Error waiting for incoming traffic: %s", err)
+			return
+		case !ready:
+			continue
+		}
+
+		if accepting {
+			for _, conn := range conns {
+				w.spawn(sys, name, conn)
+			}
+			continue
+		}
+
+		w.u.Log.Printf("Incoming traffic, starting %s...", name)
+		if err = sys.Start(name); err != nil {
+			w.u.Log.Errorf("Error starting %s: %s", name, err)
+			// Do not spin on the traffic, which could not be handled
+			if !w.sleep() {
+				return
+			}
+		}
+	}
+}
+
+// errWatcherStopped is returned by poll, once the watcher is stopped
+var errWatcherStopped = errors.New("Watcher stopped")
+
+// poll waits for incoming traffic on the sockets for timeout at most and reports whether there is any.
+// If accepting is set, the connections pending are accepted
+func (w *socketWatcher) poll(l unit.Listener, accepting bool, timeout time.Duration) (ready bool, conns []connection, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	select {
+	case <-w.done:
+		return false, nil, errWatcherStopped
+	default:
+	}
+
+	files := l.Files()
+	if len(files) == 0 {
+		return false, nil, errWatcherStopped
+	}
+
+	fds := make([]unix.PollFd, len(files))
+	for i, f := range files {
+		fds[i] = unix.PollFd{Fd: int32(f.Fd()), Events: unix.POLLIN}
+	}
+
+	var n int
+	if n, err = unix.Poll(fds, int(timeout/time.Millisecond)); err != nil || n == 0 || !accepting {
+		return n > 0, nil, err
+	}
+
+	for _, fd := range fds {
+		if fd.Revents&unix.POLLIN == 0 {
+			continue
+		}
+
+		nfd, sa, err := unix.Accept4(int(fd.Fd), unix.SOCK_CLOEXEC)
+		if err != nil {
+			w.u.Log.Errorf("Error accepting connection: %s", err)
+			continue
+		}
+
+		conns = append(conns, connection{
+			file:     os.NewFile(uintptr(nfd), w.u.Name()),
+			instance: connectionInstance(w.accepted, nfd, sa),
+		})
+		w.accepted++
+	}
+	return true, conns, nil
+}
+
+// connectionInstance returns the name of the instance handling the connection accepted n-th on fd from sa:
+// n, the local and the remote address for IP sockets or n, the PID and the UID of the peer for unix sockets
+func connectionInstance(n, fd int, sa unix.Sockaddr) string {
+	switch remote := sa.(type) {
+	case *unix.SockaddrInet4, *unix.SockaddrInet6:
+		local, _ := unix.Getsockname(fd)
+		return fmt.Sprintf("%d-%s-%s", n, formatSockaddr(local), formatSockaddr(remote))
+	}
+
+	if cred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED); err == nil {
+		return fmt.Sprintf("%d-%d-%d", n, cred.Pid, cred.Uid)
+	}
+	return strconv.Itoa(n)
+}
+
+// formatSockaddr formats the IP address and the port of sa as they appear in instance names
+func formatSockaddr(sa unix.Sockaddr) string {
+	switch sa := sa.(type) {
+	case *unix.SockaddrInet4:
+		return fmt.Sprintf("%s:%d", net.IP(sa.Addr[:]), sa.Port)
+	case *unix.SockaddrInet6:
+		return fmt.Sprintf("%s:%d", net.IP(sa.Addr[:]), sa.Port)
+	default:
+		return "unknown"
+	}
+}
+
+// spawn starts the instance of template handling conn, unless MaxConnections= is reached.
+// Instances, which are not running anymore, are removed, failed ones are kept, until reset
+func (w *socketWatcher) spawn(sys *Daemon, template string, conn connection) {
+	defer conn.file.Close()
+
+	running := w.instances[:0]
+	for _, name := range w.instances {
+		u, err := sys.Unit(name)
+		switch {
+		case err != nil:
+		case u.isRunning():
+			running = append(running, name)
+		case u.Active() == unit.Inactive && u.runningJob() == nil:
+			sys.units.remove(u)
+			sys.deps.remove(u)
+		}
+	}
+	w.instances = running
+
+	if max := w.u.Interface.(unit.Acceptor).MaxConnections(); len(w.instances) >= max {
+		w.u.Log.Warningf("Too many incoming connections (%d), dropping connection", len(w.instances))
+		return
+	}
+
+	name := instanceOf(template, conn.instance)
+
+	u, err := sys.Get(name)
+	if err != nil {
+		w.u.Log.Errorf("Error loading %s: %s", name, err)
+		return
+	}
+
+	setter, ok := u.Interface.(unit.ConnectionSetter)
+	if !ok {
+		w.u.Log.Errorf("%s can not be passed connections", name)
+		return
+	}
+	setter.SetConnection(conn.file)
+
+	if err = sys.Start(name); err != nil {
+		w.u.Log.Errorf("Error starting %s: %s", name, err)
+	}
+	w.instances = append(w.instances, name)
+}
+
+// stopIdle stops the service, once it has not been busy for TimeoutIdleSec= of the socket
+func (w *socketWatcher) stopIdle(sys *Daemon, name string, l unit.Listener) {
+	is, ok := l.(unit.IdleStopper)
+	if !ok || is.TimeoutIdleSec() <= 0 {
+		return
+	}
+
+	now := sys.clock.Now()
+	if w.lastBusy.IsZero() || w.busy(sys, name, l) {
+		w.lastBusy = now
+		return
+	}
+
+	if idle := now.Sub(w.lastBusy); idle >= is.TimeoutIdleSec() {
+		w.u.Log.Printf("%s idle for %s, stopping...", name, unit.FormatTimespan(idle))
+		if err := sys.Stop(name); err != nil {
+			w.u.Log.Errorf("Error stopping %s: %s", name, err)
+		} else {
+			w.idleStopped = true
+		}
+		w.lastBusy = time.Time{}
+	}
+}
+
+// busy reports whether connections are pending on the sockets or any process of the service
+// holds a socket other than the listening ones, e.g. a connection accepted
+func (w *socketWatcher) busy(sys *Daemon, name string, l unit.Listener) bool {
+	if ready, _, err := w.poll(l, false, 0); err != nil || ready {
+		return true
+	}
+
+	u, err := sys.Unit(name)
+	if err != nil {
+		return false
+	}
+
+	listening := w.inodes(l)
+	for _, pid := range sys.pidsOf(u) {
+		for _, ino := range socketInodes(PROC_PATH, pid) {
+			if !listening[ino] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inodes returns the inodes of the sockets
+func (w *socketWatcher) inodes(l unit.Listener) (inodes map[uint64]bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	inodes = map[uint64]bool{}
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	for _, f := range l.Files() {
+		var st unix.Stat_t
+		if err := unix.Fstat(int(f.Fd()), &st); err == nil {
+			inodes[st.Ino] = true
+		}
+	}
+	return
+}
+
+// pidsOf returns the PIDs of the processes in the cgroup of u, if cgroups are enabled,
+// or the PID of the main process of u otherwise
+func (sys *Daemon) pidsOf(u *Unit) (pids []int) {
+	if dir := sys.cgroupOf(u); dir != "" {
+		if b, err := ioutil.ReadFile(filepath.Join(dir, CGROUP_PROCS)); err == nil {
+			for _, field := range strings.Fields(string(b)) {
+				if pid, err := strconv.Atoi(field); err == nil {
+					pids = append(pids, pid)
+				}
+			}
+			return
+		}
+	}
+
+	if m, ok := u.Interface.(unit.MainPIDer); ok && m.MainPID() > 0 {
+		pids = append(pids, m.MainPID())
+	}
+	return
+}
+
+// socketInodes returns the inodes of the sockets open by the process with pid found in proc
+func socketInodes(proc string, pid int) (inodes []uint64) {
+	dir := filepath.Join(proc, strconv.Itoa(pid), "fd")
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		if ino, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 64); err == nil {
+			inodes = append(inodes, ino)
+		}
+	}
+	return
+}
+
+// sleep waits for SOCKET_POLL_INTERVAL and reports whether w is still running
+func (w *socketWatcher) sleep() bool {
+	select {
+	case <-w.done:
+		return false
+	case <-time.After(SOCKET_POLL_INTERVAL):
+		return true
+	}
+}
+
+// stop stops w. Once stop returns, the sockets are not polled anymore,
+// a start of the service requested before is not waited for
+func (w *socketWatcher) stop() {
+	close(w.done)
+	w.mutex.Lock()
+	w.mutex.Unlock()
+}
+
+// SocketInfo is an entry of the list returned by ListSockets
+type SocketInfo struct {
+	// Name of the socket unit
+	Name string
+
+	// Socket listened on
+	Listen unit.Listen
+
+	// Name of the service activated, the template of the instances, if one is spawned per connection
+	Activates string
+}
+
+// ListSockets returns the sockets of active socket units held in-memory, or of all of them, if all is true,
+// sorted by the name of the unit
+func (sys *Daemon) ListSockets(all bool) (infos []SocketInfo) {
+	infos = []SocketInfo{}
+	for _, u := range sys.Units() {
+		l, ok := u.Interface.(unit.Listener)
+		if !ok || !all && u.Active() != unit.Active {
+			continue
+		}
+
+		for _, listen := range l.Listens() {
+			infos = append(infos, SocketInfo{
+				Name:      u.Name(),
+				Listen:    listen,
+				Activates: listenerService(u, l),
+			})
+		}
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return
+}
@@ -0,0 +1,204 @@
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"systemgo/unit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Environment variable making the test binary act as a socket-activated service echoing the lines received
+const echoEnv = "SYSTEMGO_TEST_ECHO"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(echoEnv) != "" {
+		ln, err := net.FileListener(os.NewFile(3, "listen"))
+		if err != nil {
+			os.Exit(1)
+		}
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				os.Exit(1)
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}
+	os.Exit(m.Run())
+}
+
+func TestSocketActivation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo.sock")
+	out := filepath.Join(dir, "out")
+
+	script := filepath.Join(dir, "foo.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte(fmt.Sprintf(
+		`echo "$$ $LISTEN_PID $LISTEN_FDS $LISTEN_FDNAMES" > %s.tmp && mv %s.tmp %s; exec sleep 10`, out, out, out,
+	)), 0644))
+
+	sys := New()
+	sys.SetPaths()
+
+	sv, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/sh "+script))
+	require.NoError(t, err)
+	sock, err := sys.Load("foo.socket", strings.NewReader("[Socket]\nListenStream="+path+"\nFileDescriptorName=foo"))
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Start("foo.socket"))
+	defer sys.Stop("foo.service", "foo.socket")
+	assert.Equal(t, unit.Active, sock.Active())
+	assert.Equal(t, unit.Inactive, sv.Active(), "service is not started before incoming traffic")
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var b []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if b, err = ioutil.ReadFile(out); err == nil {
+			break
+		}
+	}
+	require.NoError(t, err, "service is started on incoming traffic")
+
+	fields := strings.Fields(string(b))
+	require.Len(t, fields, 4)
+	assert.Equal(t, fields[0], fields[1], "LISTEN_PID is the PID of the service")
+	assert.Equal(t, strconv.Itoa(sv.Interface.(interface{ MainPID() int }).MainPID()), fields[1])
+	assert.Equal(t, []string{"1", "foo"}, fields[2:])
+	assert.Equal(t, unit.Active, sv.Active())
+
+	require.NoError(t, sys.Stop("foo.socket"))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "socket is removed, once stopped")
+}
+
+func TestSocketAccept(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo.sock")
+
+	script := filepath.Join(dir, "foo.sh")
+	require.NoError(t, ioutil.WriteFile(script, []byte(`read line && echo "$1 $line"`), 0644))
+
+	sys := New()
+	sys.SetPaths()
+
+	_, err = sys.Load("foo@.service", strings.NewReader("[Service]\nExecStart=/bin/sh "+script+" %i"))
+	require.NoError(t, err)
+	_, err = sys.Load("foo.socket", strings.NewReader("[Socket]\nListenStream="+path+"\nAccept=yes\nMaxConnections=1"))
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Start("foo.socket"))
+	defer sys.Stop("foo.socket")
+
+	first, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer first.Close()
+	first.SetDeadline(time.Now().Add(5 * time.Second))
+
+	instance := fmt.Sprintf("foo@0-%d-%d.service", os.Getpid(), os.Getuid())
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if st, err := sys.IsActive(instance); err == nil && st == unit.Active {
+			break
+		}
+	}
+	st, err := sys.IsActive(instance)
+	require.NoError(t, err)
+	require.Equal(t, unit.Active, st, "instance is spawned per connection")
+
+	second, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = second.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err, "connections exceeding MaxConnections are dropped")
+
+	_, err = first.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	line, err := bufio.NewReader(first).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("0-%d-%d hello\n", os.Getpid(), os.Getuid()), line,
+		"connection is passed as standard input and output")
+}
+
+func TestSocketIdle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo.sock")
+
+	require.NoError(t, os.Setenv(echoEnv, "1"))
+	defer os.Unsetenv(echoEnv)
+
+	sys := New()
+	sys.SetPaths()
+
+	sv, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart="+os.Args[0]+"\nPassEnvironment="+echoEnv))
+	require.NoError(t, err)
+	sock, err := sys.Load("foo.socket", strings.NewReader("[Socket]\nListenStream="+path+"\nTimeoutIdleSec=200ms"))
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Start("foo.socket"))
+	defer sys.Stop("foo.service", "foo.socket")
+
+	echo := func(conn net.Conn) {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		_, err := conn.Write([]byte("ping\n"))
+		require.NoError(t, err)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "ping\n", line)
+	}
+	waitInactive := func() bool {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if sv.Active() == unit.Inactive {
+				return true
+			}
+		}
+		return false
+	}
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	echo(conn)
+	assert.Equal(t, unit.Active, sv.Active(), "service is started on incoming traffic")
+
+	time.Sleep(2 * 200 * time.Millisecond)
+	echo(conn)
+	assert.Equal(t, unit.Active, sv.Active(), "service with connections open is not idle")
+
+	conn.Close()
+	require.True(t, waitInactive(), "idle service is stopped")
+	assert.Equal(t, unit.Active, sock.Active(), "socket keeps listening")
+
+	conn, err = net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	echo(conn)
+	assert.Equal(t, unit.Active, sv.Active(), "service is started again on incoming traffic")
+}
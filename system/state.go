@@ -1,15 +1,54 @@
 package system
 
+import "systemgo/unit"
+
 // System state
 type State int
 
-//go:generate stringer -type=State state.go
+//go:generate stringer -type=State -linecomment state.go
 
 const (
-	Initializing State = iota
-	Starting
-	Running
-	Degraded
-	Maintenance
-	Stopping
+	Initializing State = iota // initializing
+	Starting                  // starting
+	Running                   // running
+	Degraded                  // degraded
+	Maintenance               // maintenance
+	Stopping                  // stopping
 )
+
+// State returns the state of sys. A running system is degraded, if any of the units failed
+func (sys *Daemon) State() (st State) {
+	sys.mutex.RLock()
+	st = sys.state
+	sys.mutex.RUnlock()
+
+	if st == Running && sys.failed() > 0 {
+		return Degraded
+	}
+	return
+}
+
+// setState sets the state of sys to st and emits a ManagerStateChanged event, if it changed
+func (sys *Daemon) setState(st State) {
+	sys.mutex.Lock()
+	prev := sys.state
+	sys.state = st
+	sys.mutex.Unlock()
+
+	if prev != st {
+		sys.emit(Event{
+			Type:  ManagerStateChanged,
+			State: st,
+		})
+	}
+}
+
+// failed returns the number of units in failed state
+func (sys *Daemon) failed() (n int) {
+	for _, u := range sys.Units() {
+		if u.Active() == unit.Failed {
+			n++
+		}
+	}
+	return
+}
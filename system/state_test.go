@@ -0,0 +1,37 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fail.service"), []byte("[Service]\nType=oneshot\nExecStart=/bin/false"), 0644))
+
+	sys := New()
+	sys.SetPaths(dir)
+	assert.Equal(t, Initializing, sys.State())
+
+	sys.state = Running
+	assert.Equal(t, Running, sys.State())
+
+	assert.Error(t, sys.Start("fail.service"))
+	assert.Equal(t, Degraded, sys.State())
+
+	st, err := sys.Status()
+	require.NoError(t, err)
+	assert.Equal(t, Degraded, st.State)
+	assert.Equal(t, 1, st.Failed)
+
+	sys.state = Stopping
+	assert.Equal(t, Stopping, sys.State())
+}
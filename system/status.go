@@ -3,6 +3,7 @@ package system
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"time"
 )
 
@@ -20,8 +21,11 @@ type Status struct {
 	// Init time
 	Since time.Time `json:"Since"`
 
+	// PID of the manager
+	PID int `json:"PID"`
+
 	// Log
-	Log []byte `json:"Log, omitempty"`
+	Log []byte `json:"Log,omitempty"`
 }
 
 func (s Status) String() (out string) {
@@ -40,28 +44,22 @@ Since: %v`,
 
 // Status returns status of the system
 // If error is returned it is going to be an error,
-// returned by the call to ioutil.ReadAll(sys.Log)
+// returned by reading sys.Log
 func (sys *Daemon) Status() (st Status, err error) {
-	st = Status{Since: sys.since}
+	st = Status{
+		State:  sys.State(),
+		Failed: sys.failed(),
+		Since:  sys.since,
+		PID:    os.Getpid(),
+	}
 
 	for _, u := range sys.Units() {
-		switch {
-		case u.job == nil:
-			continue
-		case u.job.IsRunning():
+		if u.runningJob() != nil {
 			st.Jobs++
-		case u.job.Failed():
-			st.Failed++
 		}
 	}
 
-	if st.Failed > 0 {
-		st.State = Degraded
-	} else {
-		st.State = Running
-	}
-
-	st.Log, err = ioutil.ReadAll(sys.Log)
+	st.Log, err = ioutil.ReadAll(sys.Log.Cursor())
 
 	return
 }
@@ -0,0 +1,113 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Maximum length of a line of output of a process logged as a single entry, longer lines are split
+const LOG_LINE_MAX = 48 * 1024
+
+// stdioExecutor connects standard output and standard error of processes started by Executor,
+// which are not redirected elsewhere, to pipes, which are drained line by line into log
+type stdioExecutor struct {
+	unit.Executor
+	log *Log
+}
+
+func (e stdioExecutor) Start(cmd *exec.Cmd) (p unit.Process, err error) {
+	if cmd.Stdout != nil && cmd.Stderr != nil {
+		// Streams are redirected elsewhere
+		return e.Executor.Start(cmd)
+	}
+
+	var readers, writers []*os.File
+	for _, stream := range []*io.Writer{&cmd.Stdout, &cmd.Stderr} {
+		if *stream != nil {
+			continue
+		}
+
+		var r, w *os.File
+		if r, w, err = os.Pipe(); err != nil {
+			closeFiles(readers)
+			closeFiles(writers)
+			return nil, err
+		}
+		*stream = w
+		readers, writers = append(readers, r), append(writers, w)
+	}
+
+	p, err = e.Executor.Start(cmd)
+
+	// The write ends are held open by the child
+	closeFiles(writers)
+
+	if err != nil {
+		closeFiles(readers)
+		return nil, err
+	}
+
+	pid := 0
+	if p != nil {
+		pid = p.Pid()
+	}
+	for _, r := range readers {
+		go drainOutput(e.log, r, pid)
+	}
+	return p, nil
+}
+
+// closeFiles closes files
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// drainOutput logs the lines read from r to l as entries of the process with pid, until r is drained,
+// and closes r. Lines prefixed by "<N>" are logged with priority N, see sd-daemon(3)
+func drainOutput(l *Log, r io.ReadCloser, pid int) {
+	defer r.Close()
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, LOG_LINE_MAX)
+	s.Split(scanLogLines)
+
+	for s.Scan() {
+		p, msg := parsePriorityPrefix(s.Text())
+		l.add(LogEntry{
+			Time:     l.clock.Now(),
+			PID:      pid,
+			Priority: p,
+			Message:  msg,
+		})
+	}
+
+	if err := s.Err(); err != nil {
+		log.WithField("pid", pid).Debugf("Error reading output: %s", err)
+	}
+}
+
+// scanLogLines is a bufio.SplitFunc, which splits lines like bufio.ScanLines,
+// except that lines longer than LOG_LINE_MAX are split into multiple ones
+func scanLogLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i < 0 && len(data) >= LOG_LINE_MAX || i >= LOG_LINE_MAX {
+		return LOG_LINE_MAX, data[:LOG_LINE_MAX], nil
+	}
+	return bufio.ScanLines(data, atEOF)
+}
+
+// parsePriorityPrefix returns the priority specified by the "<N>" prefix of line and line without it.
+// If line has no valid prefix, LOG_INFO and line are returned
+func parsePriorityPrefix(line string) (p int, msg string) {
+	if len(line) >= 3 && line[0] == '<' && line[2] == '>' && line[1] >= '0' && line[1] <= '7' {
+		return int(line[1] - '0'), line[3:]
+	}
+	return LOG_INFO, line
+}
@@ -0,0 +1,77 @@
+package system
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriorityPrefix(t *testing.T) {
+	for line, expected := range map[string]struct {
+		p   int
+		msg string
+	}{
+		"<3>failure": {LOG_ERR, "failure"},
+		"<7>":        {LOG_DEBUG, ""},
+		"<8>line":    {LOG_INFO, "<8>line"},
+		"<3 line":    {LOG_INFO, "<3 line"},
+		"line":       {LOG_INFO, "line"},
+	} {
+		p, msg := parsePriorityPrefix(line)
+		assert.Equal(t, expected.p, p, line)
+		assert.Equal(t, expected.msg, msg, line)
+	}
+}
+
+func TestScanLogLines(t *testing.T) {
+	long := strings.Repeat("x", LOG_LINE_MAX+10)
+
+	s := bufio.NewScanner(strings.NewReader("first\r\n" + long + "\nlast"))
+	s.Buffer(nil, LOG_LINE_MAX)
+	s.Split(scanLogLines)
+
+	var lines []string
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	require.NoError(t, s.Err())
+	assert.Equal(t, []string{"first", long[:LOG_LINE_MAX], long[LOG_LINE_MAX:], "last"}, lines)
+}
+
+func TestCaptureOutput(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	for name, execStart := range map[string]string{
+		"stdout.service": "/bin/echo <4>hello",
+		"stderr.service": "/bin/ls /nonexistent",
+	} {
+		_, err := sys.Load(name, strings.NewReader("[Service]\nType=oneshot\nExecStart="+execStart))
+		require.NoError(t, err)
+	}
+	sys.Start("stdout.service", "stderr.service")
+
+	output := func(name string) (entries []LogEntry) {
+		u, err := sys.Unit(name)
+		require.NoError(t, err)
+
+		for _, e := range u.Log.Entries() {
+			if e.PID != 0 {
+				entries = append(entries, e)
+			}
+		}
+		return
+	}
+
+	require.Eventually(t, func() bool { return len(output("stdout.service")) > 0 }, time.Second, time.Millisecond)
+	e := output("stdout.service")[0]
+	assert.Equal(t, "hello", e.Message)
+	assert.Equal(t, LOG_WARNING, e.Priority)
+
+	require.Eventually(t, func() bool { return len(output("stderr.service")) > 0 }, time.Second, time.Millisecond)
+	assert.Contains(t, output("stderr.service")[0].Message, "/nonexistent")
+}
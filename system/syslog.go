@@ -0,0 +1,262 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Socket of the local syslog daemon
+const SYSLOG_SOCKET = "/dev/log"
+
+// Number of entries queued for forwarding to syslog, further entries are dropped,
+// until the queue is drained
+const SYSLOG_QUEUE_SIZE = 1024
+
+// Format of timestamps of RFC 5424 messages
+const RFC5424_TIME = "2006-01-02T15:04:05.000000Z07:00"
+
+// Maximum length of APP-NAME of RFC 5424 messages
+const rfc5424AppNameMax = 48
+
+// syslogForwarder sends log entries formatted as RFC 5424 messages to a syslog endpoint
+type syslogForwarder struct {
+	network, addr string
+
+	// Connection to the endpoint, nil if not connected
+	conn net.Conn
+
+	hostname string
+
+	queue chan []byte
+	done  chan struct{}
+
+	// Closed, when the queue is drained after done is closed
+	stopped chan struct{}
+
+	closeOnce sync.Once
+}
+
+// parseSyslogAddr parses addr of a syslog endpoint in the form "udp://HOST:PORT", "tcp://HOST:PORT"
+// or "unix://PATH". A path alone is a unix datagram socket, empty addr is the local syslog socket
+func parseSyslogAddr(addr string) (network, address string, err error) {
+	if addr == "" {
+		return "unixgram", SYSLOG_SOCKET, nil
+	}
+
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) == 1 {
+		return "unixgram", addr, nil
+	}
+
+	switch network, address = parts[0], parts[1]; network {
+	case "udp", "tcp":
+		if _, _, err = net.SplitHostPort(address); err != nil {
+			return "", "", err
+		}
+	case "unix":
+		network = "unixgram"
+	default:
+		return "", "", fmt.Errorf("unsupported syslog transport: %q", network)
+	}
+	return
+}
+
+// ForwardToSyslog makes all entries logged by units be forwarded to the syslog endpoint at addr,
+// see parseSyslogAddr, as RFC 5424 messages. SyslogIdentifier= and SyslogFacility= of units
+// are honored, entries are forwarded with the facility "daemon" and identifier derived from the name
+// of the unit by default. The endpoint forwarded to before, if any, is replaced
+func (sys *Daemon) ForwardToSyslog(addr string) (err error) {
+	log.WithField("addr", addr).Debugf("sys.ForwardToSyslog")
+
+	f := &syslogForwarder{
+		queue:   make(chan []byte, SYSLOG_QUEUE_SIZE),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if f.network, f.addr, err = parseSyslogAddr(addr); err != nil {
+		return
+	}
+
+	if f.hostname, err = os.Hostname(); err != nil {
+		f.hostname = "-"
+	}
+
+	if err = f.dial(); err != nil {
+		return
+	}
+	go f.run()
+
+	sys.logMutex.Lock()
+	prev := sys.syslog
+	sys.syslog = f
+	sys.logMutex.Unlock()
+
+	if prev != nil {
+		prev.close()
+	}
+	return nil
+}
+
+// closeSyslog stops forwarding entries to syslog, once the entries queued are sent
+func (sys *Daemon) closeSyslog() {
+	sys.logMutex.Lock()
+	f := sys.syslog
+	sys.syslog = nil
+	sys.logMutex.Unlock()
+
+	if f != nil {
+		f.close()
+	}
+}
+
+// forwardToSyslog queues e logged by u for forwarding to syslog, if enabled
+func (sys *Daemon) forwardToSyslog(u *Unit, e LogEntry) {
+	sys.logMutex.Lock()
+	f := sys.syslog
+	sys.logMutex.Unlock()
+
+	if f == nil {
+		return
+	}
+
+	identifier, facility := syslogIdentity(u, e)
+	f.send(formatRFC5424(e, facility, f.hostname, identifier))
+}
+
+// syslogIdentity returns the identifier and facility of e logged by u, nil for the manager, as sent to syslog.
+// Entries are sent with the facility "daemon" and identifier derived from the name of the unit,
+// unless SyslogIdentifier= or SyslogFacility= of u are specified. Entries of the kernel and the audit stream
+// are sent with the facilities "kern" and "authpriv"
+func syslogIdentity(u *Unit, e LogEntry) (identifier string, facility int) {
+	identifier = strings.TrimSuffix(e.Identifier(), filepath.Ext(e.Unit))
+	facility, _ = unit.ParseSyslogFacility(unit.DEFAULT_SYSLOG_FACILITY)
+	switch {
+	case e.Kernel:
+		facility, _ = unit.ParseSyslogFacility("kern")
+	case e.Audit:
+		facility, _ = unit.ParseSyslogFacility("authpriv")
+	}
+
+	if u == nil {
+		return
+	}
+	if s, ok := u.Interface.(unit.Syslogger); ok {
+		if s.SyslogIdentifier() != "" {
+			identifier = s.SyslogIdentifier()
+		}
+		if n, err := unit.ParseSyslogFacility(s.SyslogFacility()); err == nil {
+			facility = n
+		}
+	}
+	return
+}
+
+// formatRFC5424 formats e as a RFC 5424 message of facility with no structured data
+func formatRFC5424(e LogEntry, facility int, hostname, identifier string) []byte {
+	field := func(s string, max int) string {
+		s = strings.Map(func(r rune) rune {
+			if r <= ' ' || r > '~' {
+				return '_'
+			}
+			return r
+		}, s)
+		if s == "" {
+			return "-"
+		}
+		if len(s) > max {
+			return s[:max]
+		}
+		return s
+	}
+
+	procid := "-"
+	if e.PID != 0 {
+		procid = strconv.Itoa(e.PID)
+	}
+
+	return []byte(fmt.Sprintf("<%d>1 %s %s %s %s - - %s",
+		facility*8+e.Priority, e.Time.Format(RFC5424_TIME),
+		field(hostname, 255), field(identifier, rfc5424AppNameMax), procid, e.Message))
+}
+
+// send queues msg, msg is dropped, if the queue is full
+func (f *syslogForwarder) send(msg []byte) {
+	select {
+	case <-f.done:
+	case f.queue <- msg:
+	default:
+		log.WithField("addr", f.addr).Debugf("Syslog queue is full, dropping message")
+	}
+}
+
+func (f *syslogForwarder) dial() (err error) {
+	f.conn, err = net.Dial(f.network, f.addr)
+	return
+}
+
+// run writes the messages queued to the endpoint, reconnecting, if the connection fails
+func (f *syslogForwarder) run() {
+	defer close(f.stopped)
+	defer func() {
+		if f.conn != nil {
+			f.conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-f.queue:
+			f.write(msg)
+		case <-f.done:
+			for {
+				select {
+				case msg := <-f.queue:
+					f.write(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write writes msg to the endpoint, messages sent over TCP are framed by octet counting, see RFC 6587
+func (f *syslogForwarder) write(msg []byte) {
+	if f.network == "tcp" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if f.conn == nil {
+			if err := f.dial(); err != nil {
+				log.WithField("addr", f.addr).Debugf("Error connecting to syslog: %s", err)
+				return
+			}
+		}
+
+		_, err := f.conn.Write(msg)
+		if err == nil {
+			return
+		}
+
+		log.WithField("addr", f.addr).Debugf("Error writing to syslog: %s", err)
+		f.conn.Close()
+		f.conn = nil
+	}
+}
+
+// close stops f, once the messages queued are written
+func (f *syslogForwarder) close() {
+	f.closeOnce.Do(func() {
+		close(f.done)
+	})
+	<-f.stopped
+}
@@ -0,0 +1,131 @@
+package system
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSyslogAddr(t *testing.T) {
+	for addr, expected := range map[string][2]string{
+		"":                     {"unixgram", SYSLOG_SOCKET},
+		"/run/log.sock":        {"unixgram", "/run/log.sock"},
+		"unix:///run/log.sock": {"unixgram", "/run/log.sock"},
+		"udp://logs:514":       {"udp", "logs:514"},
+		"tcp://10.0.0.1:601":   {"tcp", "10.0.0.1:601"},
+	} {
+		network, address, err := parseSyslogAddr(addr)
+		if assert.NoError(t, err, addr) {
+			assert.Equal(t, expected, [2]string{network, address}, addr)
+		}
+	}
+
+	for _, addr := range []string{"udp://logs", "http://logs:80"} {
+		_, _, err := parseSyslogAddr(addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+func TestFormatRFC5424(t *testing.T) {
+	e := LogEntry{
+		Time:     time.Date(2026, 10, 16, 12, 0, 0, 1000, time.UTC),
+		PID:      42,
+		Priority: LOG_ERR,
+		Message:  "Main process exited",
+	}
+	assert.Equal(t, "<27>1 2026-10-16T12:00:00.000001Z host foo 42 - - Main process exited",
+		string(formatRFC5424(e, 3, "host", "foo")))
+
+	e.PID = 0
+	assert.Equal(t, "<131>1 2026-10-16T12:00:00.000001Z - my_app - - - Main process exited",
+		string(formatRFC5424(e, 16, "", "my app")))
+}
+
+func TestForwardToSyslog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sys := New()
+	sys.SetPaths()
+	require.Error(t, sys.ForwardToSyslog("unix://"+filepath.Join(dir, "missing")))
+	require.NoError(t, sys.ForwardToSyslog("unix://"+path))
+
+	foo, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	bar, err := sys.Load("bar.service", strings.NewReader("[Service]\nExecStart=/bin/true\nSyslogIdentifier=baz\nSyslogFacility=local0"))
+	require.NoError(t, err)
+
+	foo.Log.Println("hello")
+	bar.Log.Error("failure")
+
+	read := func() string {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		b := make([]byte, 1024)
+		n, err := conn.Read(b)
+		require.NoError(t, err)
+		return string(b[:n])
+	}
+
+	msg := read()
+	assert.True(t, strings.HasPrefix(msg, "<30>1 "), msg)
+	assert.True(t, strings.HasSuffix(msg, " foo - - - hello"), msg)
+
+	msg = read()
+	assert.True(t, strings.HasPrefix(msg, "<131>1 "), msg)
+	assert.True(t, strings.HasSuffix(msg, " baz - - - failure"), msg)
+
+	sys.closeSyslog()
+	foo.Log.Println("not forwarded")
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, err = conn.Read(make([]byte, 1024))
+	assert.Error(t, err, "entries are not forwarded, once forwarding stops")
+}
+
+func TestForwardToSyslogTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	sys := New()
+	sys.SetPaths()
+	require.NoError(t, sys.ForwardToSyslog("tcp://"+l.Addr().String()))
+	defer sys.closeSyslog()
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	foo, err := sys.Load("foo.service", strings.NewReader("[Service]\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	foo.Log.Println("hello")
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+
+	// Messages are framed by octet counting
+	prefix, err := r.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+	require.NoError(t, err)
+
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<30>1 .* foo - - - hello$`, string(msg))
+}
@@ -11,6 +11,17 @@ const (
 	dead   = "dead"
 )
 
+// Targets, transactions for which can not be cancelled or overridden by later ones
+var irreversibleTargets = map[string]bool{
+	"shutdown.target": true,
+	"poweroff.target": true,
+	"reboot.target":   true,
+	"halt.target":     true,
+	"kexec.target":    true,
+
+	"ctrl-alt-del.target": true,
+}
+
 // Target unit type is used for grouping units
 type Target struct {
 	unit.Definition
@@ -19,7 +30,13 @@ type Target struct {
 
 // Define attempts to fill the targ definition by parsing r
 func (targ *Target) Define(r io.Reader) (err error) {
-	return unit.ParseDefinition(r, &targ.Definition)
+	def := unit.Definition{}
+	if err = unit.ParseDefinition(r, &def); err != nil {
+		return
+	}
+
+	targ.Definition = def
+	return nil
 }
 
 // Active returns activation status of the unit
@@ -0,0 +1,90 @@
+package system
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// splitInstance splits the name of an instance of a template unit, e.g. foo@bar.service, into
+// the prefix, foo, and the instance, bar. ok is false, if name is not a name of an instance
+func splitInstance(name string) (prefix, instance string, ok bool) {
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	i := strings.Index(base, "@")
+	if i <= 0 || i == len(base)-1 {
+		return "", "", false
+	}
+	return base[:i], base[i+1:], true
+}
+
+// templateOf returns the name of the template unit name is an instance of, e.g. foo@.service for foo@bar.service.
+// ok is false, if name is not a name of an instance
+func templateOf(name string) (template string, ok bool) {
+	prefix, _, ok := splitInstance(name)
+	if !ok {
+		return "", false
+	}
+	return prefix + "@" + filepath.Ext(name), true
+}
+
+// instanceOf returns the name of the instance of template named instance, e.g. foo@bar.service for foo@.service and bar
+func instanceOf(template, instance string) string {
+	ext := filepath.Ext(template)
+	return strings.TrimSuffix(template, "@"+ext) + "@" + instance + ext
+}
+
+// expandSpecifiers replaces the specifiers %i, %I, %p, %n, %N and %% in the definition b of the instance name,
+// see systemd.unit(5)
+func expandSpecifiers(b []byte, name string) []byte {
+	prefix, instance, ok := splitInstance(name)
+	if !ok {
+		return b
+	}
+
+	return []byte(strings.NewReplacer(
+		"%%", "%",
+		"%i", instance,
+		"%I", instance,
+		"%p", prefix,
+		"%n", filepath.Base(name),
+		"%N", strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)),
+	).Replace(string(b)))
+}
+
+// loadInstance defines the instance name by the definition of template found in configured paths
+// or loaded in-memory. Errors are returned as *LoadError.
+// sys.loadMutex must be held
+func (sys *Daemon) loadInstance(name, template string) (u *Unit, err error) {
+	var (
+		b    []byte
+		path string
+	)
+	for _, dir := range sys.Paths() {
+		p := filepath.Join(dir, template)
+		if _, serr := sys.fsys.Stat(p); serr != nil {
+			continue
+		}
+		if b, err = sys.readDefinitionFile(p); err != nil {
+			return nil, &LoadError{name, p, err}
+		}
+		path = p
+		break
+	}
+
+	if path == "" {
+		t, terr := sys.Unit(template)
+		if terr != nil || !t.IsLoaded() {
+			return nil, &LoadError{Name: name, Err: ErrNotFound}
+		}
+		b = t.definition()
+	}
+
+	if u, err = sys.Unit(name); err != nil {
+		u = sys.newUnit(name, sys.newInterface(name))
+	}
+	u.setPath(path)
+
+	if err = u.define(b); err != nil {
+		return u, &LoadError{name, path, err}
+	}
+	return u, nil
+}
@@ -0,0 +1,46 @@
+package system
+
+import (
+	"strings"
+	"testing"
+
+	"systemgo/unit/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateOf(t *testing.T) {
+	template, ok := templateOf("foo@bar.service")
+	assert.True(t, ok)
+	assert.Equal(t, "foo@.service", template)
+
+	for _, name := range []string{"foo.service", "foo@.service", "@bar.service"} {
+		_, ok = templateOf(name)
+		assert.False(t, ok, name)
+	}
+
+	assert.Equal(t, "foo@0-1-2.service", instanceOf("foo@.service", "0-1-2"))
+}
+
+func TestExpandSpecifiers(t *testing.T) {
+	assert.Equal(t, "ExecStart=/bin/foo bar foo foo@bar.service foo@bar %i",
+		string(expandSpecifiers([]byte("ExecStart=/bin/foo %i %p %n %N %%i"), "foo@bar.service")))
+	assert.Equal(t, "%i", string(expandSpecifiers([]byte("%i"), "foo.service")), "only instances are expanded")
+}
+
+func TestLoadInstance(t *testing.T) {
+	sys := New()
+	sys.SetPaths()
+
+	_, err := sys.Load("foo@.service", strings.NewReader("[Service]\nExecStart=/bin/echo %i"))
+	require.NoError(t, err)
+
+	u, err := sys.Get("foo@bar.service")
+	require.NoError(t, err)
+	assert.True(t, u.IsLoaded())
+	assert.Equal(t, "/bin/echo bar", u.Interface.(*service.Unit).Definition.Service.ExecStart)
+
+	_, err = sys.Get("baz@bar.service")
+	assert.Error(t, err, "template is not found")
+}
@@ -0,0 +1,303 @@
+package system
+
+import (
+	"crypto/rand"
+	"hash/fnv"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Default directory the times units were last activated at by persistent timers are stored in
+const DEFAULT_TIMER_STAMP_PATH = "/var/lib/systemgo/timers"
+
+// timerWatcher activates the unit of a timer unit, whenever the timer elapses
+type timerWatcher struct {
+	u *Unit
+
+	done chan struct{}
+
+	// Time the unit was last activated at by the watcher and the time it is activated at next,
+	// zero if unknown
+	last, next time.Time
+
+	mutex sync.Mutex
+}
+
+// timerUnit returns the name of the unit activated by u
+func timerUnit(u *Unit, t unit.Timer) string {
+	if name := t.Unit(); name != "" {
+		return name
+	}
+	return strings.TrimSuffix(u.Name(), ".timer") + ".service"
+}
+
+// TimerStampPath returns the directory, in which sys stores the times units were last activated at by persistent timers
+func (sys *Daemon) TimerStampPath() (path string) {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.timerStampPath
+}
+
+// SetTimerStampPath sets the directory, in which sys stores the times units were last activated at by persistent timers
+func (sys *Daemon) SetTimerStampPath(path string) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.timerStampPath = path
+}
+
+// timerStamp returns the time the unit of the timer named name was last activated at as stored on disk,
+// zero if unknown
+func (sys *Daemon) timerStamp(name string) time.Time {
+	fi, err := os.Stat(filepath.Join(sys.TimerStampPath(), "stamp-"+name))
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// setTimerStamp stores t as the time the unit of the timer named name was last activated at on disk
+func (sys *Daemon) setTimerStamp(name string, t time.Time) (err error) {
+	dir := sys.TimerStampPath()
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	path := filepath.Join(dir, "stamp-"+name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	f.Close()
+	return os.Chtimes(path, t, t)
+}
+
+// bootTime returns the time the system was booted at, the starting time of sys, if unknown
+func (sys *Daemon) bootTime() time.Time {
+	var info unix.Sysinfo_t
+	if err := unix.Sysinfo(&info); err != nil {
+		return sys.Since()
+	}
+	return sys.clock.Now().Add(-time.Duration(info.Uptime) * time.Second)
+}
+
+// timerAccuracy returns the time activations by t may be delayed by to coalesce them with other timers
+func (sys *Daemon) timerAccuracy(t unit.Timer) time.Duration {
+	if accuracy, ok := t.AccuracySec(); ok {
+		return accuracy
+	}
+
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.defaults.TimerAccuracySec
+}
+
+// timerPerturbation returns the offset within a minute, which sys coalesces timers at. It is derived from the boot ID,
+// so that timers of different machines do not elapse at the same time
+func (sys *Daemon) timerPerturbation() time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(sys.BootID()))
+	return time.Duration(h.Sum64() % uint64(time.Minute))
+}
+
+// elapseAt returns the point in time the activation by t due at next happens at: delayed by a random time
+// up to RandomizedDelaySec= and then coalesced with other timers within AccuracySec=
+func (sys *Daemon) elapseAt(t unit.Timer, next time.Time) time.Time {
+	if d := t.RandomizedDelaySec(); d > 0 {
+		if n, err := rand.Int(rand.Reader, big.NewInt(int64(d))); err == nil {
+			next = next.Add(time.Duration(n.Int64()))
+		}
+	}
+	return coalesce(next, sys.timerAccuracy(t), sys.timerPerturbation())
+}
+
+// coalesce returns the first point in time not before t, which is a multiple of accuracy shifted by perturbation.
+// Timers due within the same window of accuracy elapse at the same time therefore
+func coalesce(t time.Time, accuracy, perturbation time.Duration) time.Time {
+	if accuracy <= 0 {
+		return t
+	}
+
+	offset := time.Duration((t.UnixNano() - int64(perturbation)) % int64(accuracy))
+	if offset < 0 {
+		offset += accuracy
+	}
+	if offset == 0 {
+		return t
+	}
+	return t.Add(accuracy - offset)
+}
+
+// watchTimer activates the unit of u, whenever u elapses, if u is a timer
+func (sys *Daemon) watchTimer(u *Unit) {
+	if _, ok := u.Interface.(unit.Timer); !ok {
+		return
+	}
+
+	w := &timerWatcher{
+		u:    u,
+		done: make(chan struct{}),
+	}
+
+	sys.timerMutex.Lock()
+	if sys.timers == nil {
+		sys.timers = map[*Unit]*timerWatcher{}
+	}
+	prev := sys.timers[u]
+	sys.timers[u] = w
+	sys.timerMutex.Unlock()
+
+	if prev != nil {
+		prev.stop()
+	}
+	go w.run(sys)
+}
+
+// unwatchTimer stops the watcher of u started by watchTimer, if any
+func (sys *Daemon) unwatchTimer(u *Unit) {
+	sys.timerMutex.Lock()
+	w, ok := sys.timers[u]
+	delete(sys.timers, u)
+	sys.timerMutex.Unlock()
+
+	if ok {
+		w.stop()
+	}
+}
+
+// run waits for the timer to elapse and activates its unit, until the timer never elapses again or the watcher is stopped.
+// If the timer is persistent, an activation missed, while sys was not running, happens right away
+func (w *timerWatcher) run(sys *Daemon) {
+	t := w.u.Interface.(unit.Timer)
+	name := timerUnit(w.u, t)
+
+	base := unit.TimerBase{
+		Activated: sys.clock.Now(),
+		Boot:      sys.bootTime(),
+		Startup:   sys.Since(),
+	}
+	if t.Persistent() {
+		base.Stamp = sys.timerStamp(w.u.Name())
+		w.mutex.Lock()
+		w.last = base.Stamp
+		w.mutex.Unlock()
+	}
+
+	e := log.WithField("timer", w.u.Name())
+	for {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+
+		next, ok := t.NextElapse(base)
+		t.SetElapsed(!ok)
+
+		if !ok {
+			e.Debugf("Timer elapsed, not activating %s anymore", name)
+			return
+		}
+		next = sys.elapseAt(t, next)
+
+		w.mutex.Lock()
+		w.next = next
+		w.mutex.Unlock()
+
+		e.Debugf("Activating %s at %s", name, next)
+		timer := sys.clock.NewTimer(next.Sub(sys.clock.Now()))
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+
+		now := sys.clock.Now()
+		base.LastTrigger = now
+
+		w.mutex.Lock()
+		w.last = now
+		w.mutex.Unlock()
+
+		if t.Persistent() {
+			if err := sys.setTimerStamp(w.u.Name(), now); err != nil {
+				w.u.Log.Errorf("Error storing the time of the activation: %s", err)
+			}
+		}
+
+		w.u.Log.Printf("Timer elapsed, starting %s...", name)
+		if err := sys.Start(name); err != nil {
+			w.u.Log.Errorf("Error starting %s: %s", name, err)
+		}
+	}
+}
+
+// stop stops the watcher
+func (w *timerWatcher) stop() {
+	close(w.done)
+}
+
+// TimerInfo is an entry of the list returned by ListTimers
+type TimerInfo struct {
+	Name string
+
+	// Name of the unit activated
+	Activates string
+
+	// Time the unit is activated at next and the time it was last activated at, zero if unknown
+	Next, Last time.Time
+}
+
+// ListTimers returns the active timer units held in-memory, or all of them, if all is true,
+// sorted by the time they elapse at next. Timers, which do not elapse again, are listed last
+func (sys *Daemon) ListTimers(all bool) (infos []TimerInfo) {
+	infos = []TimerInfo{}
+	for _, u := range sys.Units() {
+		t, ok := u.Interface.(unit.Timer)
+		if !ok || !all && u.Active() != unit.Active {
+			continue
+		}
+
+		info := TimerInfo{
+			Name:      u.Name(),
+			Activates: timerUnit(u, t),
+		}
+
+		sys.timerMutex.Lock()
+		w, ok := sys.timers[u]
+		sys.timerMutex.Unlock()
+		if ok {
+			w.mutex.Lock()
+			info.Next, info.Last = w.next, w.last
+			w.mutex.Unlock()
+		} else if t.Persistent() {
+			info.Last = sys.timerStamp(u.Name())
+		}
+		infos = append(infos, info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if a.Next.IsZero() != b.Next.IsZero() {
+			return b.Next.IsZero()
+		}
+		if !a.Next.Equal(b.Next) {
+			return a.Next.Before(b.Next)
+		}
+		return a.Name < b.Name
+	})
+	return
+}
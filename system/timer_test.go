@@ -0,0 +1,131 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestTimerPersistent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timer-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	clock := NewFakeClock(now)
+
+	sys := New()
+	sys.SetPaths()
+	sys.SetClock(clock)
+	sys.SetTimerStampPath(dir)
+
+	// Both timers last elapsed two days ago, while the system was down since
+	stamp := now.Add(-48 * time.Hour)
+	require.NoError(t, sys.setTimerStamp("foo.timer", stamp))
+	require.NoError(t, sys.setTimerStamp("bar.timer", stamp))
+
+	for _, name := range []string{"foo", "bar"} {
+		_, err = sys.Load(name+".service", strings.NewReader("[Service]\nType=oneshot\nExecStart=/bin/touch "+filepath.Join(dir, name+".ran")))
+		require.NoError(t, err)
+	}
+	_, err = sys.Load("foo.timer", strings.NewReader("[Timer]\nOnCalendar=daily\nPersistent=yes"))
+	require.NoError(t, err)
+	_, err = sys.Load("bar.timer", strings.NewReader("[Timer]\nOnCalendar=daily"))
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Start("foo.timer", "bar.timer"))
+	defer sys.Stop("foo.timer", "bar.timer")
+
+	ran := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name+".ran"))
+		return err == nil
+	}
+	waitRan := func(name string) bool {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if ran(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	require.True(t, waitRan("foo"), "missed run of persistent timer happens right away")
+	assert.True(t, now.Equal(sys.timerStamp("foo.timer")), "stamp is updated")
+
+	// The next activation is scheduled, once the unit activated is started
+	var infos []TimerInfo
+	assert.Eventually(t, func() bool {
+		infos = sys.ListTimers(false)
+		for _, info := range infos {
+			if !info.Next.After(now) {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond, "next activations are scheduled")
+	if assert.Len(t, infos, 2) {
+		assert.Equal(t, "bar.timer", infos[0].Name)
+		assert.Equal(t, "foo.timer", infos[1].Name)
+		assert.Equal(t, "foo.service", infos[1].Activates)
+		assert.True(t, now.Equal(infos[1].Last), "last activation is listed")
+		assert.True(t, infos[1].Next.After(now), "next activation is listed")
+		assert.True(t, infos[0].Last.IsZero(), "stamp of timer, which is not persistent, is not listed")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, ran("bar"), "missed run of timer, which is not persistent, is skipped")
+
+	clock.Advance(12*time.Hour + DEFAULT_TIMER_ACCURACY)
+	require.True(t, waitRan("bar"), "timer elapses at the next calendar event")
+	assert.True(t, stamp.Equal(sys.timerStamp("bar.timer")), "stamp of timer, which is not persistent, is kept")
+}
+
+func TestCoalesce(t *testing.T) {
+	base := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	for _, c := range []struct {
+		t                      time.Time
+		accuracy, perturbation time.Duration
+		expected               time.Time
+	}{
+		{base.Add(10 * time.Second), 0, 0, base.Add(10 * time.Second)},
+		{base.Add(10 * time.Second), time.Minute, 0, base.Add(time.Minute)},
+		{base.Add(50 * time.Second), time.Minute, 0, base.Add(time.Minute)},
+		{base, time.Minute, 0, base},
+		{base.Add(10 * time.Second), time.Minute, 20 * time.Second, base.Add(20 * time.Second)},
+		{base.Add(30 * time.Second), time.Minute, 20 * time.Second, base.Add(80 * time.Second)},
+		{base.Add(1500 * time.Millisecond), time.Second, 0, base.Add(2 * time.Second)},
+	} {
+		assert.Equal(t, c.expected, coalesce(c.t, c.accuracy, c.perturbation), "%s within %s shifted by %s", c.t, c.accuracy, c.perturbation)
+	}
+}
+
+func TestElapseAt(t *testing.T) {
+	sys := New()
+
+	next := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	perturbation := sys.timerPerturbation()
+
+	u, err := sys.Load("foo.timer", strings.NewReader("[Timer]\nOnCalendar=daily"))
+	require.NoError(t, err)
+	at := sys.elapseAt(u.Interface.(unit.Timer), next)
+	assert.Equal(t, coalesce(next, DEFAULT_TIMER_ACCURACY, perturbation), at, "coalesced within the default accuracy")
+
+	u, err = sys.Load("bar.timer", strings.NewReader("[Timer]\nOnCalendar=daily\nAccuracySec=1us\nRandomizedDelaySec=1h"))
+	require.NoError(t, err)
+
+	delays := map[time.Duration]bool{}
+	for i := 0; i < 10; i++ {
+		d := sys.elapseAt(u.Interface.(unit.Timer), next).Sub(next)
+		assert.True(t, d >= 0 && d <= time.Hour, "delay %s is within RandomizedDelaySec", d)
+		delays[d] = true
+	}
+	assert.True(t, len(delays) > 1, "delays are random")
+}
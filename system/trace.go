@@ -0,0 +1,410 @@
+package system
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Limits and intervals of exporting spans
+const (
+	// Number of spans queued for export, further spans are dropped, until the queue is drained
+	TRACE_QUEUE_SIZE = 2048
+
+	// Maximum number of spans sent at once
+	TRACE_BATCH_SIZE = 512
+
+	// Interval, at which the spans queued are sent
+	TRACE_FLUSH_INTERVAL = 5 * time.Second
+
+	// Timeout of sending the spans
+	TRACE_TIMEOUT = 10 * time.Second
+)
+
+// Name of the service the spans are attributed to, unless configured otherwise
+const DEFAULT_TRACE_SERVICE_NAME = "systemgo"
+
+// Content type of the spans sent to OTLP endpoints
+const OTLP_JSON_CONTENT_TYPE = "application/json"
+
+// TracingConfig specifies the OpenTelemetry collector the spans of transactions and jobs are exported to
+type TracingConfig struct {
+	// URL of the OTLP/HTTP traces endpoint, e.g. "http://localhost:4318/v1/traces"
+	Endpoint string
+
+	// Value of the service.name resource attribute(empty means DEFAULT_TRACE_SERVICE_NAME)
+	ServiceName string
+}
+
+// Span is a timed operation of the manager as exported to an OpenTelemetry collector
+type Span struct {
+	// Hex encoded identifiers of the trace, the span and the parent span(empty for root spans)
+	TraceID, SpanID, ParentID string
+
+	Name       string
+	Start, End time.Time
+
+	Attributes map[string]string
+
+	// Error the operation failed with, empty if it succeeded
+	Err string
+}
+
+// span is a Span being recorded. Methods of a nil span do nothing, so that operations
+// are instrumented the same way, whether tracing is enabled or not
+type span struct {
+	Span
+	tracer *tracer
+
+	// Clock the span and its children are timed by
+	clock Clock
+}
+
+// spanExporter sends spans to a collector
+type spanExporter interface {
+	export(spans []Span) error
+	close()
+}
+
+// tracer queues the spans ended and exports them in batches
+type tracer struct {
+	exporter      spanExporter
+	flushInterval time.Duration
+
+	queue chan Span
+	done  chan struct{}
+
+	// Closed, when the spans queued are sent after done is closed
+	stopped chan struct{}
+
+	closeOnce sync.Once
+}
+
+// Trace makes transactions and jobs of sys be recorded as OpenTelemetry spans and exported
+// to the collector specified by config. A transaction is the root span of a trace, the jobs
+// dispatched by it are its children, which are split into waiting for the jobs ordered before,
+// waiting for a free job slot and executing the operation. The collector exported to before,
+// if any, is replaced
+func (sys *Daemon) Trace(config TracingConfig) (err error) {
+	log.WithField("config", config).Debugf("sys.Trace")
+
+	var u *url.URL
+	if u, err = url.Parse(config.Endpoint); err != nil {
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported OTLP endpoint scheme: %q", u.Scheme)
+	}
+
+	name := config.ServiceName
+	if name == "" {
+		name = DEFAULT_TRACE_SERVICE_NAME
+	}
+
+	sys.setTracer(newTracer(&otlpExporter{
+		url:         u.String(),
+		serviceName: name,
+		client:      &http.Client{Timeout: TRACE_TIMEOUT},
+	}))
+	return nil
+}
+
+func newTracer(e spanExporter) *tracer {
+	return &tracer{
+		exporter:      e,
+		flushInterval: TRACE_FLUSH_INTERVAL,
+		queue:         make(chan Span, TRACE_QUEUE_SIZE),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// setTracer starts t and makes sys record spans with it, replacing the previous tracer, if any
+func (sys *Daemon) setTracer(t *tracer) {
+	go t.run()
+
+	sys.mutex.Lock()
+	prev := sys.tracer
+	sys.tracer = t
+	sys.mutex.Unlock()
+
+	if prev != nil {
+		prev.close()
+	}
+}
+
+// closeTracer stops recording spans, once the spans queued are sent or sending them fails
+func (sys *Daemon) closeTracer() {
+	sys.mutex.Lock()
+	t := sys.tracer
+	sys.tracer = nil
+	sys.mutex.Unlock()
+
+	if t != nil {
+		t.close()
+	}
+}
+
+// startSpan starts a root span named name at start with attributes given as key-value pairs,
+// nil if sys is nil or tracing is disabled
+func (sys *Daemon) startSpan(name string, start time.Time, attrs ...string) *span {
+	if sys == nil {
+		return nil
+	}
+
+	sys.mutex.RLock()
+	t := sys.tracer
+	sys.mutex.RUnlock()
+
+	if t == nil {
+		return nil
+	}
+
+	s := &span{
+		Span: Span{
+			TraceID: randomHex(16),
+			SpanID:  randomHex(8),
+			Name:    name,
+			Start:   start,
+		},
+		tracer: t,
+		clock:  sys.clock,
+	}
+	s.set(attrs...)
+	return s
+}
+
+// child starts a span named name with attributes given as key-value pairs, which is a child of s
+func (s *span) child(name string, attrs ...string) *span {
+	if s == nil {
+		return nil
+	}
+	return s.childAt(name, s.clock.Now(), attrs...)
+}
+
+// childAt starts a span named name at start with attributes given as key-value pairs, which is a child of s
+func (s *span) childAt(name string, start time.Time, attrs ...string) *span {
+	if s == nil {
+		return nil
+	}
+
+	c := &span{
+		Span: Span{
+			TraceID:  s.TraceID,
+			SpanID:   randomHex(8),
+			ParentID: s.SpanID,
+			Name:     name,
+			Start:    start,
+		},
+		tracer: s.tracer,
+		clock:  s.clock,
+	}
+	c.set(attrs...)
+	return c
+}
+
+// set sets the attributes of s given as key-value pairs
+func (s *span) set(attrs ...string) {
+	if s == nil {
+		return
+	}
+
+	if s.Attributes == nil {
+		s.Attributes = map[string]string{}
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.Attributes[attrs[i]] = attrs[i+1]
+	}
+}
+
+// end ends s, which failed, if err is not nil, and queues it for export
+func (s *span) end(err error) {
+	if s == nil {
+		return
+	}
+
+	s.End = s.clock.Now()
+	if err != nil {
+		s.Err = err.Error()
+	}
+	s.tracer.send(s.Span)
+}
+
+// randomHex returns n random bytes hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// send queues s, s is dropped, if the queue is full
+func (t *tracer) send(s Span) {
+	select {
+	case <-t.done:
+	case t.queue <- s:
+	default:
+		log.Debugf("Trace queue is full, dropping span")
+	}
+}
+
+// run sends the spans queued in batches every flushInterval or, as soon as a batch is full.
+// Spans, which fail to be sent, are dropped
+func (t *tracer) run() {
+	defer close(t.stopped)
+	defer t.exporter.close()
+
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+
+	var buf []Span
+	flush := func() {
+		if len(buf) == 0 {
+			return
+		}
+		if err := t.exporter.export(buf); err != nil {
+			log.Debugf("Error exporting %d spans: %s", len(buf), err)
+		}
+		buf = nil
+	}
+
+	for {
+		select {
+		case s := <-t.queue:
+			if buf = append(buf, s); len(buf) >= TRACE_BATCH_SIZE {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.done:
+			for len(t.queue) > 0 {
+				buf = append(buf, <-t.queue)
+			}
+			flush()
+			return
+		}
+	}
+}
+
+// close stops t, once the spans queued are sent or sending them fails
+func (t *tracer) close() {
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+	<-t.stopped
+}
+
+// otlpExporter posts spans encoded as OTLP/JSON to an OTLP/HTTP traces endpoint
+type otlpExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+}
+
+// OTLP/JSON encoding of the spans exported, see opentelemetry-proto
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// Span kind and status codes, see opentelemetry-proto
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+func (e *otlpExporter) export(spans []Span) (err error) {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: DEFAULT_TRACE_SERVICE_NAME}}
+	for _, s := range spans {
+		o := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		for key, value := range s.Attributes {
+			o.Attributes = append(o.Attributes, otlpAttribute{key, otlpValue{value}})
+		}
+		if s.Err != "" {
+			o.Status = otlpStatus{Code: otlpStatusError, Message: s.Err}
+		}
+		scope.Spans = append(scope.Spans, o)
+	}
+
+	b, err := json.Marshal(otlpTraces{[]otlpResourceSpans{{
+		Resource: otlpResource{[]otlpAttribute{
+			{"service.name", otlpValue{e.serviceName}},
+		}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+	if err != nil {
+		return
+	}
+
+	var resp *http.Response
+	if resp, err = e.client.Post(e.url, OTLP_JSON_CONTENT_TYPE, bytes.NewReader(b)); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+func (e *otlpExporter) close() {
+	e.client.CloseIdleConnections()
+}
@@ -0,0 +1,92 @@
+package system
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	var (
+		spans []otlpSpan
+		mutex sync.Mutex
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, OTLP_JSON_CONTENT_TYPE, r.Header.Get("Content-Type"))
+
+		var traces otlpTraces
+		if assert.NoError(t, json.NewDecoder(r.Body).Decode(&traces)) && assert.Len(t, traces.ResourceSpans, 1) {
+			rs := traces.ResourceSpans[0]
+			assert.Equal(t, []otlpAttribute{{"service.name", otlpValue{DEFAULT_TRACE_SERVICE_NAME}}}, rs.Resource.Attributes)
+
+			mutex.Lock()
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+			mutex.Unlock()
+		}
+	}))
+	defer srv.Close()
+
+	sys := New()
+	sys.SetPaths()
+
+	assert.Error(t, sys.Trace(TracingConfig{Endpoint: "udp://localhost:4318"}))
+	require.NoError(t, sys.Trace(TracingConfig{Endpoint: srv.URL + "/v1/traces"}))
+
+	_, err := sys.Load("foo.service", strings.NewReader("[Service]\nType=oneshot\nExecStart=/bin/true"))
+	require.NoError(t, err)
+	_, err = sys.Load("bar.service", strings.NewReader("[Service]\nType=oneshot\nExecStart=/bin/false"))
+	require.NoError(t, err)
+
+	require.NoError(t, sys.Start("foo.service"))
+	assert.Error(t, sys.Start("bar.service"))
+	sys.closeTracer()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	byName := map[string][]otlpSpan{}
+	byID := map[string]otlpSpan{}
+	for _, s := range spans {
+		byName[s.Name] = append(byName[s.Name], s)
+		byID[s.SpanID] = s
+	}
+	for _, name := range []string{"transaction", "transaction.build", "job", "job.wait", "job.queue", "job.exec"} {
+		assert.Len(t, byName[name], 2, name)
+	}
+
+	for _, s := range spans {
+		if s.Name == "transaction" {
+			assert.Empty(t, s.ParentSpanID)
+			continue
+		}
+		if parent, ok := byID[s.ParentSpanID]; assert.True(t, ok, "parent of %s is exported", s.Name) {
+			assert.Equal(t, parent.TraceID, s.TraceID)
+		}
+	}
+
+	for _, s := range byName["job"] {
+		attrs := map[string]string{}
+		for _, a := range s.Attributes {
+			attrs[a.Key] = a.Value.StringValue
+		}
+		assert.Equal(t, "start", attrs["systemgo.job.type"])
+
+		switch attrs["systemgo.unit"] {
+		case "foo.service":
+			assert.Equal(t, otlpStatusOK, s.Status.Code)
+		case "bar.service":
+			assert.Equal(t, otlpStatusError, s.Status.Code)
+			assert.NotEmpty(t, s.Status.Message)
+		default:
+			t.Errorf("unexpected unit %q", attrs["systemgo.unit"])
+		}
+	}
+}
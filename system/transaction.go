@@ -3,6 +3,10 @@ package system
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -10,44 +14,112 @@ import (
 type transaction struct {
 	unmerged map[*Unit]*prospectiveJobs
 	merged   map[*Unit]*job
+
+	// Units, jobs for which were explicitly requested
+	requested map[*Unit]struct{}
+
+	// Whether jobs of the transaction are irreversible
+	irreversible bool
+
+	// Time the transaction was created at, which is when building it starts
+	created time.Time
+
+	// Daemon the units of the transaction came from, nil if unknown
+	sys *Daemon
 }
 
 type prospectiveJobs struct {
 	anchored, optional [job_type_count]*job
 }
 
-func newTransaction() (tr *transaction) {
+func newTransaction(sys *Daemon) (tr *transaction) {
 	log.Debugf("newTransaction")
 
 	return &transaction{
-		unmerged: map[*Unit]*prospectiveJobs{},
-		merged:   map[*Unit]*job{},
+		sys:       sys,
+		unmerged:  map[*Unit]*prospectiveJobs{},
+		merged:    map[*Unit]*job{},
+		requested: map[*Unit]struct{}{},
+		created:   sys.clock.Now(),
 	}
 }
 
-func (tr *transaction) Run() (err error) {
+// Run dispatches the jobs in transaction, waits for the jobs of requested units to finish
+// and returns their outcomes
+func (tr *transaction) Run() (res Results, err error) {
 	log.WithField("transaction", tr).Debugf("tr.Run")
 
-	if err = tr.merge(); err != nil {
-		return
-	}
+	root := tr.sys.startSpan("transaction", tr.created, "systemgo.units", strings.Join(tr.requestedNames(), " "))
+	defer func() { root.end(err) }()
+
+	build := root.childAt("transaction.build", tr.created)
 
 	var ordering []*job
-	if ordering, err = tr.order(); err != nil {
+	ordering, err = tr.prepare()
+	build.end(err)
+	if err != nil {
 		return
 	}
+	root.set("systemgo.jobs", strconv.Itoa(len(ordering)))
 
+	unlock := tr.sys.lockJobs()
 	for _, j := range ordering {
 		if j.IsRedundant() {
+			// Nothing to do, but jobs depending on j must not wait for it
+			j.finish(ResultSkipped, nil)
 			continue
 		}
 
 		log.Debugf("dispatching job for %s", j.unit.Name())
-		go j.Run()
+		j.emit(JobQueued)
+
+		j.span = root.child("job", "systemgo.unit", j.unit.Name(), "systemgo.job.type", fmt.Sprint(j.typ))
+
+		prev := j.unit.Active()
+		j.unit.setJob(j)
+		go j.Run(prev)
 	}
+	unlock()
+
+	res = Results{}
+	for u := range tr.requested {
+		j, ok := tr.merged[u]
+		if !ok {
+			continue
+		}
+
+		j.Wait()
+		res[u.Name()] = j.Status()
+	}
+	return res, nil
+}
+
+// requestedNames returns the names of the units, jobs for which were explicitly requested, sorted
+func (tr *transaction) requestedNames() (names []string) {
+	for u := range tr.requested {
+		names = append(names, u.Name())
+	}
+	sort.Strings(names)
 	return
 }
 
+// run runs tr and returns a JobError, if any of the requested jobs did not succeed
+func (tr *transaction) run() (err error) {
+	var res Results
+	if res, err = tr.Run(); err != nil {
+		return
+	}
+	return res.Err()
+}
+
+// prepare merges the jobs in transaction and returns them ordered
+func (tr *transaction) prepare() (ordering []*job, err error) {
+	if err = tr.merge(); err != nil {
+		return
+	}
+	return tr.order()
+}
+
 // recursively adds jobs to transaction
 // tries to load dependencies not already present
 func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor bool) (err error) {
@@ -69,6 +141,14 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 	//case start:
 	//	if !u.CanStart() {}
 	//}
+	if cur := u.runningJob(); cur != nil && cur.irreversible && cur.typ != typ {
+		return ErrIrreversible
+	}
+
+	if typ != stop && u.IsMasked() {
+		return ErrMasked
+	}
+
 	var j *job
 	var isNew bool
 
@@ -96,6 +176,12 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 		}
 	}
 
+	j.irreversible = tr.irreversible
+
+	if parent == nil {
+		tr.requested[u] = struct{}{}
+	}
+
 	if parent != nil {
 		if required {
 			parent.requires.Put(j)
@@ -107,7 +193,7 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 	}
 
 	if isNew && typ != stop {
-		for _, name := range u.Conflicts() {
+		for _, name := range u.System.deps.get(u, depConflicts) {
 			dep, err := u.System.Get(name)
 			if err != nil {
 				return err
@@ -118,7 +204,7 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 			}
 		}
 
-		for _, name := range u.Requires() {
+		for _, name := range u.System.deps.get(u, depRequires) {
 			dep, err := u.System.Get(name)
 			if err != nil {
 				return err
@@ -129,7 +215,7 @@ func (tr *transaction) add(typ jobType, u *Unit, parent *job, required, anchor b
 			}
 		}
 
-		for _, name := range u.Wants() {
+		for _, name := range u.System.deps.get(u, depWants) {
 			dep, err := u.System.Get(name)
 			if err != nil {
 				continue
@@ -275,36 +361,27 @@ func (tr *transaction) order() (ordering []*job, err error) {
 	g := newGraph()
 
 	for u, j := range tr.merged {
-		if j.typ == stop {
-			// TODO Introduce stop job ordering(if needed)
-			continue
-		}
-
 		log.Debugf("Checking after of %s...", j.unit.Name())
-		for _, depname := range u.After() {
+		for _, depname := range u.System.deps.get(u, depAfter) {
 			var dep *Unit
 			if dep, err = u.System.Unit(depname); err != nil {
 				continue
 			}
 
-			depJob, ok := tr.merged[dep]
-			if ok {
-				j.after.Put(depJob)
-				depJob.before.Put(j)
+			if depJob, ok := tr.merged[dep]; ok {
+				orderAfter(j, depJob)
 			}
 		}
 
 		log.Debugf("Checking before of %s...", j.unit.Name())
-		for _, depname := range u.Before() {
+		for _, depname := range u.System.deps.get(u, depBefore) {
 			var dep *Unit
 			if dep, err = u.System.Unit(depname); err != nil {
 				continue
 			}
 
-			depJob, ok := tr.merged[dep]
-			if ok {
-				depJob.after.Put(j)
-				j.before.Put(depJob)
+			if depJob, ok := tr.merged[dep]; ok {
+				orderAfter(depJob, j)
 			}
 		}
 	}
@@ -312,13 +389,24 @@ func (tr *transaction) order() (ordering []*job, err error) {
 	g.ordering = make([]*job, 0, len(tr.merged))
 	for _, j := range tr.merged {
 		if err = g.order(j); err != nil {
-			return nil, fmt.Errorf("Dependency cycle determined:\njob for %s depends on %s", j.unit.Name(), err)
+			return nil, fmt.Errorf("%w:\njob for %s depends on %s", ErrOrderingCycle, j.unit.Name(), err)
 		}
 	}
 
 	return g.ordering, nil
 }
 
+// orderAfter orders j and dep, unit of j being ordered after the unit of dep.
+// Stop jobs get ordered in reverse and always run before start jobs
+func orderAfter(j, dep *job) {
+	if j.typ == stop {
+		j, dep = dep, j
+	}
+
+	j.after.Put(dep)
+	dep.before.Put(j)
+}
+
 type graph struct {
 	visited, ordered set
 	ordering         []*job
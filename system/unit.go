@@ -1,11 +1,14 @@
 package system
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"io/ioutil"
-	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"systemgo/unit"
@@ -26,9 +29,32 @@ type Unit struct {
 	path string
 	load unit.Load
 
+	// Checksum of the definition(including drop-ins) u was defined with
+	digest [sha256.Size]byte
+
+	// Definition u was defined with, instances of u are defined by it, if u is a template
+	source []byte
+
+	// Whether the definition on disk changed since u was defined
+	changed bool
+
 	job *job
 
-	mutex sync.Mutex
+	// Times of recent start attempts, used for start rate limiting
+	starts []time.Time
+
+	// Hooks notified of activation state transitions
+	hooks []Hooks
+
+	// Times the last start of u began and finished at, zero if unknown
+	activating, activated time.Time
+
+	// Most recent activation state transitions, oldest first
+	transitions []Transition
+
+	// Guards path, load, digest, source, changed, job, starts, activating, activated, transitions
+	// and the definition of Interface, read-only queries only take the read lock
+	mutex sync.RWMutex
 }
 
 // TODO introduce a better workaround
@@ -50,11 +76,109 @@ func NewUnit(v unit.Interface) (u *Unit) {
 //return u.Name()
 //}
 
+// define parses b as the definition of u and logs the errors encountered, if any.
+// Specifiers in b are expanded, if u is an instance of a template.
+// If u has already been defined with b, the definition is not parsed again
+func (u *Unit) define(b []byte) (err error) {
+	b = expandSpecifiers(b, u.Name())
+
+	if u.definedWith(b) {
+		u.setLoad(unit.Loaded)
+		u.setChanged(false)
+		return nil
+	}
+
+	u.mutex.Lock()
+	err = u.Interface.Define(bytes.NewReader(b))
+	u.mutex.Unlock()
+
+	return u.defined(b, err)
+}
+
+// defined records b as the definition of u, if err, returned by parsing b, is nil,
+// or logs the errors encountered otherwise
+func (u *Unit) defined(b []byte, err error) error {
+	if u.System != nil {
+		u.System.deps.forget(u)
+	}
+
+	if err != nil {
+		if me, ok := err.(unit.MultiError); ok {
+			u.Log.Error("Definition is invalid:")
+			for _, errmsg := range me.Errors() {
+				u.Log.Error(errmsg)
+			}
+		} else {
+			u.Log.Errorf("Error parsing definition: %s", err)
+		}
+		u.setLoad(unit.Error)
+		return err
+	}
+
+	u.mutex.Lock()
+	u.load = unit.Loaded
+	u.digest = sha256.Sum256(b)
+	u.source = b
+	u.changed = false
+	u.mutex.Unlock()
+
+	u.setLogLevels()
+	return nil
+}
+
+// definedWith returns whether u was last defined with b
+func (u *Unit) definedWith(b []byte) bool {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return sha256.Sum256(b) == u.digest
+}
+
+// definition returns the definition u was last defined with
+func (u *Unit) definition() []byte {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return u.source
+}
+
+// setChanged marks the definition of u as changed on disk since u was defined, if changed is true
+func (u *Unit) setChanged(changed bool) {
+	u.mutex.Lock()
+	u.changed = changed
+	u.mutex.Unlock()
+}
+
+// setLogLevels sets the priorities of the entries kept in the log of u and of the messages logged
+// about u to the ones specified by LogLevelMax= and LogLevel=, if any, or the defaults otherwise
+func (u *Unit) setLogLevels() {
+	max, level := LOG_DEBUG, LOG_INFO
+	if l, ok := u.Interface.(unit.LogLeveler); ok {
+		if p, err := ParsePriority(l.LogLevelMax()); err == nil {
+			max = p
+		}
+		if p, err := ParsePriority(l.LogLevel()); err == nil {
+			level = p
+		}
+	}
+	u.Log.SetMaxPriority(max)
+	u.Log.SetPriority(level)
+}
+
 // Path returns path to the defintion unit was loaded from
 func (u *Unit) Path() string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
 	return u.path
 }
 
+func (u *Unit) setPath(path string) {
+	u.mutex.Lock()
+	u.path = path
+	u.mutex.Unlock()
+}
+
 // Name returns the name of the unit(filename of the defintion)
 func (u *Unit) Name() string {
 	return u.name
@@ -62,9 +186,18 @@ func (u *Unit) Name() string {
 
 // Loaded returns load state of the unit
 func (u *Unit) Loaded() unit.Load {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
 	return u.load
 }
 
+func (u *Unit) setLoad(st unit.Load) {
+	u.mutex.Lock()
+	u.load = st
+	u.mutex.Unlock()
+}
+
 func (u *Unit) IsDead() bool {
 	return u.Active() == unit.Inactive
 }
@@ -85,6 +218,16 @@ func (u *Unit) IsLoaded() bool {
 	return u.Loaded() == unit.Loaded
 }
 
+func (u *Unit) IsMasked() bool {
+	return u.Loaded() == unit.Masked
+}
+
+// IgnoresIsolate returns whether u should be left running, when other units get isolated
+func (u *Unit) IgnoresIsolate() bool {
+	ii, ok := u.Interface.(unit.IsolateIgnorer)
+	return ok && ii.IgnoreOnIsolate()
+}
+
 // IsReloader returns whether u.Interface is capable of reloading
 func (u *Unit) IsReloader() (ok bool) {
 	_, ok = u.Interface.(unit.Reloader)
@@ -92,8 +235,8 @@ func (u *Unit) IsReloader() (ok bool) {
 }
 
 func (u *Unit) Active() (st unit.Activation) {
-	if u.jobRunning() {
-		switch u.job.typ {
+	if j := u.runningJob(); j != nil {
+		switch j.typ {
 		case start:
 			return unit.Activating
 		case stop:
@@ -107,8 +250,8 @@ func (u *Unit) Active() (st unit.Activation) {
 }
 
 func (u *Unit) Sub() string {
-	if u.jobRunning() {
-		switch u.job.typ {
+	if j := u.runningJob(); j != nil {
+		switch j.typ {
 		case start:
 			return starting
 		case stop:
@@ -121,26 +264,49 @@ func (u *Unit) Sub() string {
 	return u.Interface.Sub()
 }
 
-func (u *Unit) jobRunning() bool {
-	return u.job != nil && u.job.IsRunning()
+// currentJob returns the job last run for u, if any
+func (u *Unit) currentJob() *job {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return u.job
+}
+
+// runningJob returns the job running for u, if any
+func (u *Unit) runningJob() *job {
+	if j := u.currentJob(); j != nil && j.IsRunning() {
+		return j
+	}
+	return nil
+}
+
+func (u *Unit) setJob(j *job) {
+	u.mutex.Lock()
+	u.job = j
+	u.mutex.Unlock()
 }
 
 // Status returns status of the unit
 func (u *Unit) Status() unit.Status {
 	st := unit.Status{
 		Load: unit.LoadStatus{
-			Path:   u.Path(),
-			Loaded: u.Loaded(),
-			State:  -1, // TODO
+			Path:        u.Path(),
+			Loaded:      u.Loaded(),
+			State:       u.EnableState(),
+			NeedsReload: u.NeedsReload(),
 		},
 		Activation: unit.ActivationStatus{
 			State: u.Active(),
 			Sub:   u.Sub(),
 		},
+		Usage: u.ResourceUsage(),
+	}
+	if u.System != nil {
+		st.Activation.Health = u.System.HealthState(u)
 	}
 
 	var err error
-	if st.Log, err = ioutil.ReadAll(u.Log); err != nil {
+	if st.Log, err = ioutil.ReadAll(u.Log.Cursor()); err != nil {
 		u.Log.Errorf("Error reading log: %s", err)
 	}
 
@@ -154,126 +320,140 @@ func (u *Unit) Status() unit.Status {
 	}
 }
 
-// Requires returns a slice of unit names as found in definition and absolute paths
-// of units symlinked in units '.wants' directory
-func (u *Unit) Requires() (names []string) {
-	names = u.Interface.Requires()
-
-	if paths, err := readDepDir(u.requiresDir()); err == nil {
-		names = append(names, paths...)
-	}
+// Description returns the description of u as found in definition
+func (u *Unit) Description() string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
 
-	return
+	return u.Interface.Description()
 }
 
-// Wants returns a slice of unit names as found in definition and absolute paths
-// of units symlinked in units '.wants' directory
-func (u *Unit) Wants() (names []string) {
-	names = u.Interface.Wants()
+// Documentation returns the documentation of u as found in definition
+func (u *Unit) Documentation() string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
 
-	if paths, err := readDepDir(u.wantsDir()); err == nil {
-		names = append(names, paths...)
-	}
-
-	return
+	return u.Interface.Documentation()
 }
 
-func (u *Unit) wantsDir() (path string) {
-	return u.depDir("wants")
-}
+// Requires returns a slice of unit names as found in definition and names
+// of units symlinked in '.requires' directories of u
+func (u *Unit) Requires() (names []string) {
+	u.mutex.RLock()
+	// Copied, as the slice of the definition might have capacity to spare
+	names = append([]string(nil), u.Interface.Requires()...)
+	u.mutex.RUnlock()
 
-func (u *Unit) requiresDir() (path string) {
-	return u.depDir("requires")
+	return append(names, u.linkedDeps("requires")...)
 }
 
-func (u *Unit) depDir(suffix string) (path string) {
-	return u.Path() + "." + suffix
+// Wants returns a slice of unit names as found in definition and names
+// of units symlinked in '.wants' directories of u
+func (u *Unit) Wants() (names []string) {
+	u.mutex.RLock()
+	// Copied, as the slice of the definition might have capacity to spare
+	names = append([]string(nil), u.Interface.Wants()...)
+	u.mutex.RUnlock()
+
+	return append(names, u.linkedDeps("wants")...)
 }
 
-// Enable creates symlinks to u definition in dependency directories of each unit dependant on u
-func (u *Unit) Enable() (err error) {
-	err = u.System.getAndExecute(u.RequiredBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
-		}
+// Conflicts returns a slice of unit names as found in definition
+func (u *Unit) Conflicts() []string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
 
-		return dep.addRequiresDep(u)
-	})
-	if err != nil {
-		return
-	}
+	return u.Interface.Conflicts()
+}
 
-	return u.System.getAndExecute(u.WantedBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
-		}
+// RequiredBy returns a slice of unit names as found in definition
+func (u *Unit) RequiredBy() []string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
 
-		return dep.addWantsDep(u)
-	})
+	return u.Interface.RequiredBy()
 }
 
-func (u *Unit) addWantsDep(dep *Unit) (err error) {
-	return linkDep(u.wantsDir(), dep)
+// WantedBy returns a slice of unit names as found in definition
+func (u *Unit) WantedBy() []string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return u.Interface.WantedBy()
 }
 
-func (u *Unit) addRequiresDep(dep *Unit) (err error) {
-	return linkDep(u.requiresDir(), dep)
+// After returns a slice of unit names as found in definition
+func (u *Unit) After() []string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+
+	return u.Interface.After()
 }
 
-func linkDep(dir string, dep *Unit) (err error) {
-	if err = os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
-		return err
-	}
+// Before returns a slice of unit names as found in definition
+func (u *Unit) Before() []string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
 
-	return os.Symlink(dep.Path(), filepath.Join(dir, dep.Name()))
+	return u.Interface.Before()
 }
 
-// Disable removes symlinks(if they exist) created by Enable
-func (u *Unit) Disable() (err error) {
-	err = u.System.getAndExecute(u.RequiredBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
-		}
+// depDirs returns paths to '<name>.<suffix>' directories of u located in all unit paths
+// and the directory of the definition of u
+func (u *Unit) depDirs(suffix string) (dirs []string) {
+	name := filepath.Base(u.Name()) + "." + suffix
 
-		return dep.removeRequiresDep(u)
-	})
-	if err != nil {
-		return
+	if u.System != nil {
+		for _, path := range u.System.Paths() {
+			dirs = append(dirs, filepath.Join(path, name))
+		}
 	}
 
-	return u.System.getAndExecute(u.WantedBy(), func(dep *Unit, gerr error) error {
-		if gerr != nil {
-			return gerr
+	if path := u.Path(); path != "" {
+		dir := filepath.Join(filepath.Dir(path), name)
+		for _, d := range dirs {
+			if d == dir {
+				return
+			}
 		}
-
-		return dep.removeWantsDep(u)
-	})
-}
-
-func (u *Unit) removeWantsDep(dep *Unit) (err error) {
-	return unlinkDep(u.wantsDir(), dep)
+		dirs = append(dirs, dir)
+	}
+	return
 }
 
-func (u *Unit) removeRequiresDep(dep *Unit) (err error) {
-	return unlinkDep(u.requiresDir(), dep)
-}
+// linkedDeps returns names of units symlinked in '<name>.<suffix>' directories of u.
+// Links to MASK_TARGET are ignored
+func (u *Unit) linkedDeps(suffix string) (names []string) {
+	seen := map[string]bool{}
 
-func unlinkDep(dir string, dep *Unit) (err error) {
-	if err = os.Remove(filepath.Join(dir, dep.Name())); err != nil && !os.IsNotExist(err) {
-		return
+	for _, dir := range u.depDirs(suffix) {
+		links, err := pathset(dir)
+		if err != nil {
+			continue
+		}
+		sort.Strings(links)
+
+		for _, link := range links {
+			name := filepath.Base(link)
+			if seen[name] || isMaskLink(link) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
 	}
-	return nil
+	return
 }
 
 // Reload creates a new reload transaction and runs it
 func (u *Unit) Reload() (err error) {
 	log.WithField("u", u).Debugf("u.Reload")
 
-	tr := newTransaction()
+	tr := newTransaction(u.System)
 	if err = tr.add(reload, u, nil, true, true); err != nil {
 		return
 	}
-	return tr.Run()
+	return tr.run()
 }
 
 func (u *Unit) reload() (err error) {
@@ -290,43 +470,85 @@ func (u *Unit) reload() (err error) {
 func (u *Unit) Start() (err error) {
 	log.WithField("unit", u.Name()).Debugf("u.Start")
 
-	tr := newTransaction()
+	tr := newTransaction(u.System)
 	if err = tr.add(start, u, nil, true, true); err != nil {
 		return
 	}
-	return tr.Run()
+	return tr.run()
 }
 
 func (u *Unit) start() (err error) {
 	e := log.WithField("unit", u.Name())
 	e.Debugf("u.start")
 
+	if u.IsMasked() {
+		e.Debug("masked")
+		return ErrMasked
+	}
+
 	if !u.IsLoaded() {
 		e.Debug("not loaded")
 		return ErrNotLoaded
 	}
 
+	if u.NeedsReload() {
+		u.Log.Println("Applying changed definition...")
+		if err = u.redefine(); err != nil {
+			return err
+		}
+	}
+
+	if err = u.checkStartLimit(); err != nil {
+		return err
+	}
+
 	u.Log.Println("Starting...")
+	u.setActivating()
 
 	starter, ok := u.Interface.(unit.Starter)
 	if !ok {
 		e.Debugf("Interface is not unit.Starter")
+		u.setActivated()
 		return nil
 	}
 
 	e.Debugf("Interface.Start")
-	return starter.Start()
+	if u.System == nil {
+		return starter.Start()
+	}
+
+	u.System.setEnvironment(u)
+	u.System.setExecutor(u)
+	u.System.setListenFiles(u)
+
+	timeout, _ := u.System.timeouts(u)
+	u.Log.Debugf("Starting with timeout %s", timeout)
+	if err = runWithTimeout(u.System.clock, timeout, starter.Start, u.cancel); err == ErrTimeout {
+		u.Log.Errorf("Start operation timed out after %s", timeout)
+		if stopper, ok := u.Interface.(unit.Stopper); ok {
+			if serr := stopper.Stop(); serr != nil {
+				u.Log.Errorf("Error stopping: %s", serr)
+			}
+		}
+	}
+	if err == nil {
+		u.setActivated()
+		u.System.watchSocket(u)
+		u.System.watchTimer(u)
+		u.System.watchHealth(u)
+	}
+	return
 }
 
 // Stop creates a new stop transaction and runs it
 func (u *Unit) Stop() (err error) {
 	log.WithField("u", u).Debugf("u.Stop")
 
-	tr := newTransaction()
+	tr := newTransaction(u.System)
 	if err = tr.add(stop, u, nil, true, true); err != nil {
 		return
 	}
-	return tr.Run()
+	return tr.run()
 }
 
 func (u *Unit) stop() (err error) {
@@ -343,21 +565,47 @@ func (u *Unit) stop() (err error) {
 		return nil
 	}
 
-	return stopper.Stop()
-}
-
-func readDepDir(dir string) (paths []string, err error) {
-	var links []string
-	if links, err = pathset(dir); err != nil {
-		return
+	if u.System == nil {
+		return stopper.Stop()
 	}
 
-	paths = make([]string, 0, len(links))
-	for _, path := range links {
-		if path, err = filepath.EvalSymlinks(path); err != nil {
-			return
+	// Incoming traffic must not start the service anymore
+	u.System.unwatchSocket(u)
+	u.System.unwatchTimer(u)
+	u.System.unwatchHealth(u)
+
+	if u.frozen() {
+		// Frozen processes would not handle the stop signals
+		if err = u.setFrozen(false); err != nil {
+			u.Log.Errorf("Error thawing: %s", err)
 		}
-		paths = append(paths, path)
+	}
+
+	_, timeout := u.System.timeouts(u)
+	if err = runWithTimeout(u.System.clock, timeout, stopper.Stop, u.cancel); err == ErrTimeout {
+		u.Log.Errorf("Stop operation timed out after %s", timeout)
 	}
 	return
 }
+
+// cancel makes a start or stop operation of u, which timed out, return by killing the processes of u.
+// Units, which are not killable, are stopped instead
+func (u *Unit) cancel() {
+	var err error
+	switch v := u.Interface.(type) {
+	case unit.Killer:
+		err = v.Kill()
+	case unit.Stopper:
+		err = v.Stop()
+	}
+	if err != nil {
+		u.Log.Errorf("Error cancelling the operation: %s", err)
+	}
+}
+
+func (u *Unit) restart() (err error) {
+	if err = u.stop(); err != nil {
+		return
+	}
+	return u.start()
+}
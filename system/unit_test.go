@@ -1,8 +1,10 @@
 package system
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -58,3 +60,39 @@ func TestDeps(t *testing.T) {
 		}
 	}
 }
+
+func TestDepsConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deps-concurrent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.service"), []byte("[Unit]\nWants=a.service b.service\n[Service]\nExecStart=/bin/true"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "foo.service.d"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo.service.d", "10-wants.conf"), []byte("[Unit]\nWants=c.service"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "foo.service.wants"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "d.service"), []byte("[Service]\nExecStart=/bin/true"), 0644))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "d.service"), filepath.Join(dir, "foo.service.wants", "d.service")))
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	u, err := sys.Get("foo.service")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.service", "b.service", "c.service"}, u.Interface.Wants())
+
+	expected := []string{"a.service", "b.service", "c.service", "d.service"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.Equal(t, expected, u.Wants())
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"a.service", "b.service", "c.service"}, u.Interface.Wants())
+}
@@ -0,0 +1,85 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Paths to search for preset files of user managers
+var DEFAULT_USER_PRESET_PATHS = []string{"/etc/systemd/user-preset", "/usr/lib/systemd/user-preset", "/lib/systemd/user-preset"}
+
+// xdgDir returns the value of environment variable env, if set, filepath.Join(home, def) otherwise
+func xdgDir(env, def string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "/"
+	}
+	return filepath.Join(home, def)
+}
+
+// UserRuntimeDir returns the runtime directory of the invoking user($XDG_RUNTIME_DIR or /run/user/<uid>)
+func UserRuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return fmt.Sprintf("/run/user/%d", os.Getuid())
+}
+
+// UserRuntimePath returns the path, where the user manager of the invoking user creates symlinks
+// enabling units until reboot
+func UserRuntimePath() string {
+	return filepath.Join(UserRuntimeDir(), "systemd", "user")
+}
+
+// UserJournalDir returns the directory, in which the user manager of the invoking user keeps the journal
+// ($XDG_STATE_HOME/systemgo/journal or ~/.local/state/systemgo/journal)
+func UserJournalDir() string {
+	return filepath.Join(xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")), "systemgo", "journal")
+}
+
+// UserTimerStampPath returns the directory, in which the user manager of the invoking user stores the times
+// units were last activated at by persistent timers($XDG_STATE_HOME/systemgo/timers or ~/.local/state/systemgo/timers)
+func UserTimerStampPath() string {
+	return filepath.Join(xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")), "systemgo", "timers")
+}
+
+// UserPaths returns paths, which get searched for unit files by the user manager of the invoking user
+// as specified by the XDG base directory specification(first path gets searched first)
+func UserPaths() []string {
+	return []string{
+		filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "systemd", "user"),
+		"/etc/systemd/user",
+		UserRuntimePath(),
+		"/run/systemd/user",
+		filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), "systemd", "user"),
+		"/usr/lib/systemd/user",
+		"/lib/systemd/user",
+	}
+}
+
+// NewUser returns an instance of a Daemon ready to use as the user manager of the invoking user.
+// Services supervised by it run as the invoking user
+func NewUser() (sys *Daemon) {
+	sys = New()
+	sys.user = true
+	sys.paths = UserPaths()
+	sys.runtimePath = UserRuntimePath()
+	sys.timerStampPath = UserTimerStampPath()
+	sys.presetPaths = DEFAULT_USER_PRESET_PATHS
+	sys.generatorPaths = DEFAULT_USER_GENERATOR_PATHS
+	sys.generatorDir = filepath.Join(UserRuntimeDir(), "systemgo", "generator")
+
+	// User managers pass their own environment on
+	sys.environment = environmentMap(os.Environ())
+	return
+}
+
+// IsUser returns whether sys is a user manager
+func (sys *Daemon) IsUser() bool {
+	return sys.user
+}
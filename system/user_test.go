@@ -0,0 +1,28 @@
+package system
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserPaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/config")
+	t.Setenv("XDG_DATA_HOME", "/data")
+	t.Setenv("XDG_RUNTIME_DIR", "/runtime")
+
+	sys := NewUser()
+	assert.True(t, sys.IsUser())
+	assert.Equal(t, filepath.Join("/config", "systemd", "user"), sys.Paths()[0])
+	assert.Contains(t, sys.Paths(), filepath.Join("/data", "systemd", "user"))
+	assert.Contains(t, sys.Paths(), "/usr/lib/systemd/user")
+	assert.Equal(t, filepath.Join("/runtime", "systemd", "user"), sys.RuntimePath())
+	assert.Contains(t, sys.Paths(), sys.RuntimePath())
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/test")
+	assert.Equal(t, filepath.Join("/home/test", ".config", "systemd", "user"), UserPaths()[0])
+
+	assert.False(t, New().IsUser())
+}
@@ -0,0 +1,124 @@
+package system
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	gounit "github.com/coreos/go-systemd/unit"
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Verify checks the units specified without starting them and returns the problems found, as *VerifyError.
+// Units are specified by name, in which case they are loaded from the unit paths, or by path to the unit file.
+// Unknown options, bad values, invalid definitions, missing dependencies required or wanted by the units
+// and ordering cycles, which starting the units would run into, are reported
+func (sys *Daemon) Verify(names ...string) (errs []error) {
+	log.WithField("names", names).Debugf("sys.Verify")
+
+	report := func(name string, err error) {
+		errs = append(errs, &VerifyError{Name: name, Err: err})
+	}
+
+	for _, name := range names {
+		u, b, err := sys.verifyLoad(name)
+		if u != nil {
+			name = u.Name()
+		}
+		if err != nil {
+			if lerr, ok := err.(*LoadError); ok {
+				err = lerr.Err
+			}
+
+			// The definition is checked option by option, so that all problems are reported, not only the first one
+			var lint []error
+			if b != nil {
+				lint = sys.lint(name, b)
+			}
+			if len(lint) == 0 {
+				lint = []error{err}
+				if me, ok := err.(unit.MultiError); ok {
+					lint = me
+				}
+			}
+			for _, err := range lint {
+				report(name, err)
+			}
+			continue
+		}
+
+		for _, deps := range []struct {
+			option string
+			names  []string
+		}{
+			{"Requires", u.Requires()},
+			{"Wants", u.Wants()},
+		} {
+			for _, dep := range deps.names {
+				if _, err := sys.Get(dep); err != nil {
+					if lerr, ok := err.(*LoadError); ok {
+						err = lerr.Err
+					}
+					report(name, unit.ParseErr(deps.option, unit.ParseErr(dep, err)))
+				}
+			}
+		}
+
+		tr := newTransaction(sys)
+		if err = tr.add(start, u, nil, true, true); err == nil {
+			if err = tr.merge(); err == nil {
+				_, err = tr.order()
+			}
+		}
+		if errors.Is(err, ErrOrderingCycle) {
+			report(name, err)
+		}
+	}
+	return
+}
+
+// verifyLoad loads the unit specified by name or by path to the unit file and returns it along with its definition, if known
+func (sys *Daemon) verifyLoad(name string) (u *Unit, b []byte, err error) {
+	if !strings.Contains(name, "/") {
+		if u, err = sys.Get(name); err != nil {
+			if lerr, ok := err.(*LoadError); ok && lerr.Path != "" {
+				b, _ = ioutil.ReadFile(lerr.Path)
+			}
+		}
+		return
+	}
+
+	if b, err = ioutil.ReadFile(name); err != nil {
+		return
+	}
+	u, err = sys.Load(filepath.Base(name), bytes.NewReader(b))
+	return
+}
+
+// lint parses each option of the definition b of the unit named name on its own
+// and returns the errors encountered, i.e. unknown options and bad values
+func (sys *Daemon) lint(name string, b []byte) (errs []error) {
+	if !Supported(name) {
+		return nil
+	}
+
+	opts, err := gounit.Deserialize(bytes.NewReader(b))
+	if err != nil {
+		return []error{err}
+	}
+
+	for _, opt := range opts {
+		def := gounit.Serialize([]*gounit.UnitOption{opt})
+
+		// Errors of the definition as a whole, e.g. required options not being set, are reported as unit.MultiError
+		if err := sys.newInterface(name).Define(def); err != nil {
+			if _, ok := err.(unit.MultiError); !ok {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return
+}
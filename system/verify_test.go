@@ -0,0 +1,63 @@
+package system
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"good.service":     "[Unit]\nRequires=dep.service\nAfter=dep.service\n[Service]\nExecStart=/bin/true",
+		"dep.service":      "[Service]\nExecStart=/bin/true",
+		"bad.service":      "[Unit]\nFoo=bar\nRequires=missing.service\n[Service]\nExecStartt=/bin/true\nRemainAfterExit=maybe",
+		"noexec.service":   "[Service]\nType=oneshot",
+		"dangling.service": "[Unit]\nWants=missing.service\n[Service]\nExecStart=/bin/true",
+		"a.service":        "[Unit]\nRequires=b.service\nAfter=b.service\n[Service]\nExecStart=/bin/true",
+		"b.service":        "[Unit]\nAfter=a.service\n[Service]\nExecStart=/bin/true",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	sys := New()
+	sys.SetPaths(dir)
+
+	assert.Empty(t, sys.Verify("good.service", filepath.Join(dir, "dep.service")))
+
+	assert.Equal(t, []error{
+		&VerifyError{"bad.service", unit.ParseErr("Foo", unit.ErrNotExist)},
+		&VerifyError{"bad.service", unit.ParseErr("ExecStartt", unit.ErrNotExist)},
+	}, sys.Verify("bad.service")[:2], "all unknown options are reported")
+	if errs := sys.Verify("bad.service"); assert.Len(t, errs, 3) {
+		assert.Contains(t, errs[2].Error(), "RemainAfterExit", "bad values are reported")
+	}
+
+	assert.Equal(t, []error{
+		&VerifyError{"noexec.service", unit.ParseErr("ExecStart", unit.ErrNotSet)},
+	}, sys.Verify("noexec.service"))
+
+	assert.Equal(t, []error{
+		&VerifyError{"dangling.service", unit.ParseErr("Wants", unit.ParseErr("missing.service", ErrNotFound))},
+	}, sys.Verify("dangling.service"))
+
+	if errs := sys.Verify("a.service"); assert.Len(t, errs, 1) {
+		assert.True(t, errors.Is(errs[0], ErrOrderingCycle), "ordering cycle is reported")
+	}
+
+	if errs := sys.Verify("missing.service"); assert.Len(t, errs, 1) {
+		assert.True(t, errors.Is(errs[0], ErrNotFound))
+	}
+	for _, u := range sys.Units() {
+		assert.True(t, u.IsDead(), "%s is not started", u.Name())
+	}
+}
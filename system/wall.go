@@ -0,0 +1,85 @@
+package system
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Path to the database of logged-in users
+const UTMP_PATH = "/run/utmp"
+
+// Layout of utmp records, see utmp(5). Records are in native byte order, little endian is assumed
+const (
+	utmpSize        = 384
+	utmpUserProcess = 7
+	utmpLineOffset  = 8
+	utmpLineSize    = 32
+)
+
+// wall broadcasts messages to logged-in users, replaced in tests
+var wall = broadcast
+
+// broadcast writes msg headed by the sender and the current time to the terminals of logged-in users
+func broadcast(msg string) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	text := fmt.Sprintf("\r\nBroadcast message from systemgo@%s (%s):\r\n\r\n%s\r\n\r\n",
+		host, time.Now().Format(TIME_FORMAT), msg)
+
+	ttys, err := loggedInTerminals(UTMP_PATH)
+	if err != nil {
+		log.Errorf("Error reading logged-in users: %s", err)
+	}
+
+	for _, tty := range ttys {
+		f, err := os.OpenFile(tty, os.O_WRONLY|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			log.WithField("tty", tty).Debugf("Error opening: %s", err)
+			continue
+		}
+		if _, err = f.WriteString(text); err != nil {
+			log.WithField("tty", tty).Debugf("Error writing: %s", err)
+		}
+		f.Close()
+	}
+}
+
+// loggedInTerminals returns paths of the terminals of user processes recorded in the utmp database at path
+func loggedInTerminals(path string) (ttys []string, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for ; len(b) >= utmpSize; b = b[utmpSize:] {
+		if int16(binary.LittleEndian.Uint16(b)) != utmpUserProcess {
+			continue
+		}
+
+		line := b[utmpLineOffset : utmpLineOffset+utmpLineSize]
+		if i := bytes.IndexByte(line, 0); i >= 0 {
+			line = line[:i]
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		tty := filepath.Join("/dev", filepath.Clean("/"+string(line)))
+		if !seen[tty] {
+			seen[tty] = true
+			ttys = append(ttys, tty)
+		}
+	}
+	return ttys, nil
+}
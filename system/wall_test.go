@@ -0,0 +1,42 @@
+package system
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggedInTerminals(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wall-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	record := func(typ int16, line string) []byte {
+		b := make([]byte, utmpSize)
+		binary.LittleEndian.PutUint16(b, uint16(typ))
+		copy(b[utmpLineOffset:], line)
+		return b
+	}
+
+	var b []byte
+	b = append(b, record(2, "~")...)
+	b = append(b, record(utmpUserProcess, "pts/0")...)
+	b = append(b, record(utmpUserProcess, "tty1")...)
+	b = append(b, record(utmpUserProcess, "pts/0")...)
+	b = append(b, record(8, "pts/1")...)
+
+	path := filepath.Join(dir, "utmp")
+	require.NoError(t, ioutil.WriteFile(path, b, 0644))
+
+	ttys, err := loggedInTerminals(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dev/pts/0", "/dev/tty1"}, ttys)
+
+	_, err = loggedInTerminals(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// pathWatcher watches the unit paths of a Daemon, see Watch
+type pathWatcher struct {
+	*fsnotify.Watcher
+
+	// Directories watched
+	dirs map[string]bool
+}
+
+// Watch watches unit paths for definition files being added, changed or removed.
+// Units affected, which are not running, get redefined straight away, running ones
+// are marked as changed on disk and get redefined the next time they are started.
+// The unit paths set by SetPaths or RunGenerators later on are watched instead.
+// Returns a function, which stops watching
+func (sys *Daemon) Watch() (stop func(), err error) {
+	log.Debugf("sys.Watch")
+
+	w := &pathWatcher{dirs: map[string]bool{}}
+	if w.Watcher, err = fsnotify.NewWatcher(); err != nil {
+		return
+	}
+
+	sys.watchMutex.Lock()
+	prev := sys.watcher
+	sys.watcher = w
+	sys.watchMutex.Unlock()
+
+	if prev != nil {
+		prev.Close()
+	}
+	sys.rewatch()
+
+	go func() {
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) != 0 {
+					sys.invalidate(ev.Name)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("Error watching unit paths: %s", err)
+			}
+		}
+	}()
+
+	return func() { sys.unwatch(w) }, nil
+}
+
+// unwatch stops w, the watcher started by Watch, or the current one, if w is nil
+func (sys *Daemon) unwatch(w *pathWatcher) {
+	sys.watchMutex.Lock()
+	if w == nil {
+		w = sys.watcher
+	}
+	if sys.watcher == w {
+		sys.watcher = nil
+	}
+	sys.watchMutex.Unlock()
+
+	if w != nil {
+		w.Close()
+	}
+}
+
+// rewatch makes the watcher started by Watch, if any, watch the current unit paths.
+// Directories, which were created again since they were added, e.g. the generator output, are added again
+func (sys *Daemon) rewatch() {
+	sys.mutex.RLock()
+	paths := append([]string{}, sys.paths...)
+	sys.mutex.RUnlock()
+
+	sys.watchMutex.Lock()
+	defer sys.watchMutex.Unlock()
+
+	w := sys.watcher
+	if w == nil {
+		return
+	}
+
+	dirs := map[string]bool{}
+	for _, dir := range paths {
+		dirs[filepath.Clean(dir)] = true
+	}
+
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			w.Remove(dir)
+		}
+	}
+	for dir := range dirs {
+		if err := w.Add(dir); err != nil && !os.IsNotExist(err) {
+			log.WithField("path", dir).Warnf("Error watching: %s", err)
+		}
+	}
+	w.dirs = dirs
+}
+
+// resolve returns the path to the definition of unit named name, which would be loaded by sys
+func (sys *Daemon) resolve(name string) (path string, ok bool) {
+	for _, dir := range sys.Paths() {
+		path = filepath.Join(dir, name)
+		if _, err := os.Lstat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// invalidate handles the definition file at path being added, changed or removed
+func (sys *Daemon) invalidate(path string) {
+	name := filepath.Base(path)
+	if !Supported(name) {
+		return
+	}
+
+	e := log.WithField("path", path)
+	e.Debugf("sys.invalidate")
+
+	sys.cache.forget(path)
+
+	u, err := sys.Unit(name)
+	if err != nil {
+		// Not loaded yet, gets resolved on demand
+		return
+	}
+
+	resolved, found := sys.resolve(name)
+	if u.Path() != path && resolved != path {
+		// A file of lower priority changed
+		return
+	}
+
+	if !u.isRunning() {
+		if err := sys.reread(name); err != nil {
+			e.Errorf("Error rereading definition: %s", err)
+		}
+		return
+	}
+
+	if found && resolved == u.Path() {
+		if b, err := sys.readDefinitionFile(resolved); err == nil && u.definedWith(b) {
+			return
+		}
+	} else if found {
+		u.setPath(resolved)
+		sys.units.set(u, resolved)
+	}
+	u.Log.Println("Definition changed on disk, restart to apply")
+	u.setChanged(true)
+}
@@ -0,0 +1,65 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	admin, err := ioutil.TempDir("", "watch-admin")
+	require.NoError(t, err)
+	defer os.RemoveAll(admin)
+
+	vendor, err := ioutil.TempDir("", "watch-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(vendor)
+
+	sys := New()
+	sys.SetPaths(admin, vendor)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "foo.service"), []byte("[Service]\nExecStart=/bin/true"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "bar.target"), []byte("[Unit]\nDescription=bar"), 0644))
+
+	foo, err := sys.Get("foo.service")
+	require.NoError(t, err)
+	bar, err := sys.Get("bar.target")
+	require.NoError(t, err)
+	require.True(t, bar.IsActive(), "target without dependencies is active")
+
+	stop, err := sys.Watch()
+	require.NoError(t, err)
+	defer stop()
+
+	eventually := func(cond func() bool, msg string) {
+		for i := 0; i < 100 && !cond(); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.True(t, cond(), msg)
+	}
+
+	// Unit, which is not running, gets redefined
+	require.NoError(t, ioutil.WriteFile(filepath.Join(admin, "foo.service"), []byte("[Unit]\nDescription=foo\n[Service]\nExecStart=/bin/true"), 0644))
+	eventually(func() bool { return foo.Path() == filepath.Join(admin, "foo.service") }, "foo path resolved to admin")
+	eventually(func() bool { return foo.Description() == "foo" }, "foo redefined")
+	assert.False(t, foo.NeedsReload())
+
+	// Running unit gets marked as changed
+	require.NoError(t, ioutil.WriteFile(filepath.Join(vendor, "bar.target"), []byte("[Unit]\nDescription=changed"), 0644))
+	eventually(bar.NeedsReload, "bar marked as changed")
+	assert.Equal(t, "bar", bar.Description())
+
+	// Unit paths set later on are watched
+	other, err := ioutil.TempDir("", "watch-other")
+	require.NoError(t, err)
+	defer os.RemoveAll(other)
+
+	sys.SetPaths(other, admin, vendor)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(other, "foo.service"), []byte("[Unit]\nDescription=other\n[Service]\nExecStart=/bin/true"), 0644))
+	eventually(func() bool { return foo.Description() == "other" }, "foo redefined from path set later on")
+}
@@ -0,0 +1,171 @@
+package system
+
+import (
+	"os"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Default device of the hardware watchdog
+const DEFAULT_WATCHDOG_DEVICE = "/dev/watchdog"
+
+// Default timeout the hardware watchdog is armed with on reboot and shutdown
+const DEFAULT_REBOOT_WATCHDOG = 10 * time.Minute
+
+// Character disarming the hardware watchdog, when written right before the device is closed
+const WATCHDOG_MAGIC_CLOSE = "V"
+
+// WatchdogConfig configures the hardware watchdog, which reboots the system, unless pinged in time
+type WatchdogConfig struct {
+	// Device of the watchdog
+	Device string
+
+	// Timeout the watchdog is armed with, while running as init(0 means disabled)
+	RuntimeSec time.Duration
+
+	// Timeout the watchdog is armed with, right before reboot(2) is invoked(0 means the watchdog gets disarmed)
+	RebootSec time.Duration
+}
+
+// DefaultWatchdogConfig returns the configuration of the hardware watchdog used, unless configured otherwise
+func DefaultWatchdogConfig() WatchdogConfig {
+	return WatchdogConfig{
+		Device:    DEFAULT_WATCHDOG_DEVICE,
+		RebootSec: DEFAULT_REBOOT_WATCHDOG,
+	}
+}
+
+// hardwareWatchdog is an open watchdog device, see the Linux watchdog API
+type hardwareWatchdog struct {
+	f *os.File
+}
+
+// Watchdog returns the configuration of the hardware watchdog of sys
+func (sys *Daemon) Watchdog() WatchdogConfig {
+	sys.mutex.RLock()
+	defer sys.mutex.RUnlock()
+
+	return sys.watchdogConf
+}
+
+// SetWatchdog sets the configuration of the hardware watchdog of sys. It takes effect,
+// once sys enters init mode or the system is rebooted
+func (sys *Daemon) SetWatchdog(c WatchdogConfig) {
+	sys.mutex.Lock()
+	defer sys.mutex.Unlock()
+
+	sys.watchdogConf = c
+}
+
+// openWatchdog opens the watchdog device at path, which arms it
+func openWatchdog(path string) (w *hardwareWatchdog, err error) {
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_WRONLY|unix.O_CLOEXEC, 0); err != nil {
+		return nil, err
+	}
+	return &hardwareWatchdog{f}, nil
+}
+
+// setTimeout sets the time w reboots the system after, unless pinged, and pings it.
+// Devices not supporting the timeout keep their own one
+func (w *hardwareWatchdog) setTimeout(timeout time.Duration) {
+	secs := int((timeout + time.Second - 1) / time.Second)
+	if err := unix.IoctlSetPointerInt(int(w.f.Fd()), unix.WDIOC_SETTIMEOUT, secs); err != nil {
+		log.WithField("device", w.f.Name()).Warnf("Error setting the watchdog timeout to %ds: %s", secs, err)
+	}
+	w.ping()
+}
+
+// ping resets the timer of w
+func (w *hardwareWatchdog) ping() error {
+	_, err := w.f.Write([]byte{0})
+	return err
+}
+
+// disarm closes w, so that it does not reboot the system anymore
+func (w *hardwareWatchdog) disarm() error {
+	w.f.Write([]byte(WATCHDOG_MAGIC_CLOSE))
+	return w.f.Close()
+}
+
+// armWatchdog opens the hardware watchdog with RuntimeSec, if configured, and returns
+// the interval it has to be pinged at by pingWatchdog, 0 if there is no watchdog
+func (sys *Daemon) armWatchdog() (interval time.Duration) {
+	c := sys.Watchdog()
+	if c.RuntimeSec <= 0 {
+		return 0
+	}
+
+	sys.watchdogMutex.Lock()
+	defer sys.watchdogMutex.Unlock()
+
+	e := log.WithField("device", c.Device)
+	if sys.watchdog == nil {
+		w, err := openWatchdog(c.Device)
+		if err != nil {
+			e.Errorf("Error opening hardware watchdog: %s", err)
+			return 0
+		}
+		sys.watchdog = w
+	}
+	sys.watchdog.setTimeout(c.RuntimeSec)
+
+	e.Infof("Hardware watchdog armed with timeout %s", unit.FormatTimespan(c.RuntimeSec))
+	return c.RuntimeSec / 2
+}
+
+// pingWatchdog pings the hardware watchdog, if armed
+func (sys *Daemon) pingWatchdog() {
+	sys.watchdogMutex.Lock()
+	defer sys.watchdogMutex.Unlock()
+
+	if sys.watchdog == nil {
+		return
+	}
+	if err := sys.watchdog.ping(); err != nil {
+		log.Errorf("Error pinging hardware watchdog: %s", err)
+	}
+}
+
+// disarmWatchdog disarms and closes the hardware watchdog, if armed
+func (sys *Daemon) disarmWatchdog() {
+	sys.watchdogMutex.Lock()
+	defer sys.watchdogMutex.Unlock()
+
+	if sys.watchdog == nil {
+		return
+	}
+	if err := sys.watchdog.disarm(); err != nil {
+		log.Errorf("Error disarming hardware watchdog: %s", err)
+	}
+	sys.watchdog = nil
+}
+
+// rebootWatchdog arms the hardware watchdog with RebootSec, so that the system gets rebooted,
+// if reboot(2) hangs. The watchdog gets disarmed, if RebootSec is 0
+func (sys *Daemon) rebootWatchdog() {
+	c := sys.Watchdog()
+	if c.RebootSec <= 0 {
+		sys.disarmWatchdog()
+		return
+	}
+
+	sys.watchdogMutex.Lock()
+	defer sys.watchdogMutex.Unlock()
+
+	if sys.watchdog == nil {
+		w, err := openWatchdog(c.Device)
+		if err != nil {
+			// No hardware watchdog present
+			log.WithField("device", c.Device).Debugf("Error opening hardware watchdog: %s", err)
+			return
+		}
+		sys.watchdog = w
+	}
+	sys.watchdog.setTimeout(c.RebootSec)
+	log.WithField("device", c.Device).Infof("Hardware watchdog armed with timeout %s for reboot", unit.FormatTimespan(c.RebootSec))
+}
@@ -0,0 +1,61 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchdog-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A regular file stands in for the device, which does not support setting the timeout
+	device := filepath.Join(dir, "watchdog")
+	require.NoError(t, ioutil.WriteFile(device, nil, 0644))
+
+	sys := New()
+	assert.Equal(t, DefaultWatchdogConfig(), sys.Watchdog())
+	assert.Zero(t, sys.armWatchdog(), "disabled by default")
+
+	conf := filepath.Join(dir, "system.conf")
+	require.NoError(t, ioutil.WriteFile(conf, []byte(`[Manager]
+RuntimeWatchdogSec=30s
+RebootWatchdogSec=5min
+WatchdogDevice=`+device), 0644))
+	require.NoError(t, sys.LoadConf(conf))
+	assert.Equal(t, WatchdogConfig{Device: device, RuntimeSec: 30 * time.Second, RebootSec: 5 * time.Minute}, sys.Watchdog())
+
+	assert.Equal(t, 15*time.Second, sys.armWatchdog())
+	sys.pingWatchdog()
+
+	b, err := ioutil.ReadFile(device)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0}, b, "pinged when armed and by pingWatchdog")
+
+	sys.rebootWatchdog()
+	sys.watchdog.f.Close()
+	b, err = ioutil.ReadFile(device)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), WATCHDOG_MAGIC_CLOSE, "left armed for reboot")
+
+	sys.watchdog = nil
+	require.NoError(t, ioutil.WriteFile(device, nil, 0644))
+	sys.SetWatchdog(WatchdogConfig{Device: device, RuntimeSec: time.Second})
+	assert.Equal(t, 500*time.Millisecond, sys.armWatchdog())
+	sys.rebootWatchdog()
+	assert.Nil(t, sys.watchdog)
+
+	b, err = ioutil.ReadFile(device)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00"+WATCHDOG_MAGIC_CLOSE, string(b), "disarmed for reboot")
+
+	sys.SetWatchdog(WatchdogConfig{Device: filepath.Join(dir, "missing"), RuntimeSec: time.Second})
+	assert.Zero(t, sys.armWatchdog(), "missing device")
+}
@@ -0,0 +1,86 @@
+package systemctl
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/rpc"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/system"
+)
+
+// audited records the operation requested on the units with names specified, which failed with err,
+// if not nil, in the audit stream and returns err
+func (sv *Server) audited(op string, names []string, err error) error {
+	sv.sys.Audit(system.AuditRecord{
+		Operation: op,
+		Units:     names,
+		Via:       system.AUDIT_VIA_CONTROL,
+		Remote:    sv.remote,
+		UID:       sv.uid,
+		PID:       sv.pid,
+		Err:       err,
+	})
+	return err
+}
+
+// ServeHTTP serves RPC requests over the connection hijacked from the CONNECT request, like rpc.HandleHTTP does,
+// but by a server bound to the connection, so that operations requested are audited with the credentials
+// of the client connected to the control socket or the address of the remote one
+func (sv *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodConnect {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		log.Errorf("Error hijacking connection of %s: %s", req.RemoteAddr, err)
+		return
+	}
+	io.WriteString(conn, "HTTP/1.0 200 Connected to Go RPC\n\n")
+
+	bound := *sv
+	bound.uid, bound.pid, bound.remote = peerCredentials(conn)
+
+	srv := rpc.NewServer()
+	if err = srv.RegisterName("Server", &bound); err != nil {
+		log.Errorf("Error registering RPC server: %s", err)
+		conn.Close()
+		return
+	}
+	srv.ServeConn(conn)
+}
+
+// peerCredentials returns the credentials of the process connected to the unix socket conn, -1 if unknown,
+// or, if conn is not a unix socket, the address of the remote client
+func peerCredentials(conn net.Conn) (uid, pid int, remote string) {
+	uid, pid = -1, -1
+
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return uid, pid, conn.RemoteAddr().String()
+	}
+
+	rc, err := uc.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err = rc.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err == nil {
+		err = credErr
+	}
+	if err != nil {
+		log.Debugf("Error getting credentials of the client: %s", err)
+		return
+	}
+	return int(cred.Uid), int(cred.Pid), ""
+}
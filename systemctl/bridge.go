@@ -0,0 +1,32 @@
+package systemctl
+
+import (
+	"errors"
+	"io"
+	"net"
+)
+
+// Bridge copies data between r and w and the control server listening on addr of network,
+// until either side closes the connection
+func Bridge(r io.Reader, w io.Writer, network, addr string) (err error) {
+	var conn net.Conn
+	if conn, err = net.Dial(network, addr); err != nil {
+		return
+	}
+	defer conn.Close()
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(conn, r)
+		errs <- err
+	}()
+	go func() {
+		_, err := io.Copy(w, conn)
+		errs <- err
+	}()
+
+	if err = <-errs; errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return
+}
@@ -0,0 +1,52 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// analyzeBlameCmd represents the analyze blame command
+var analyzeBlameCmd = &cobra.Command{
+	Use:   "blame",
+	Short: "List units ordered by the time they spent activating",
+	Long:  `blame lists the units, which finished activating, ordered by the time they last spent activating, slowest first`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		timings, err := client.Blame()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer page()()
+
+		for _, t := range timings {
+			fmt.Printf("%16s %s\n", formatSpan(t.Time()), t.Name)
+		}
+	},
+}
+
+func init() {
+	analyzeCmd.AddCommand(analyzeBlameCmd)
+}
@@ -0,0 +1,108 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"systemgo/system"
+	"systemgo/unit"
+)
+
+// Number of next elapse times shown per calendar event by analyze calendar
+var calendarIterations int
+
+// analyzeCalendarCmd represents the analyze calendar command
+var analyzeCalendarCmd = &cobra.Command{
+	Use:   "calendar EXPRESSION...",
+	Short: "Show the normalized form and the next elapse times of calendar events",
+	Long: `calendar parses the calendar events specified as found in OnCalendar= of timer units, e.g. "Mon..Fri *-*-* 09:00" or "daily", and shows their normalized form and the next time they elapse at.
+With --iterations, the specified number of next elapse times is shown.
+Exits with non-zero exit code, if any of the events can not be parsed`,
+	Args: cobra.MinimumNArgs(1),
+
+	// Calendar events are analyzed without contacting the manager
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if calendarIterations < 1 {
+			fmt.Fprintln(os.Stderr, "--iterations must be positive")
+			os.Exit(1)
+		}
+
+		var shown, failed bool
+		now := time.Now()
+		for _, s := range args {
+			spec, err := unit.ParseCalendar(s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to parse calendar event %q: %s\n", s, err)
+				failed = true
+				continue
+			}
+
+			if shown {
+				fmt.Println()
+			}
+			showCalendar(s, spec, now)
+			shown = true
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// showCalendar prints the normalized form of spec parsed from s and the next times it elapses at after now
+func showCalendar(s string, spec *unit.CalendarSpec, now time.Time) {
+	fmt.Printf("  Original form: %s\n", s)
+	fmt.Printf("Normalized form: %s\n", spec)
+
+	next := now
+	for i := 1; i <= calendarIterations; i++ {
+		var ok bool
+		if next, ok = spec.Next(next); !ok {
+			if i == 1 {
+				fmt.Println("    Next elapse: never")
+			}
+			return
+		}
+
+		label := "Next elapse"
+		if i > 1 {
+			label = fmt.Sprintf("Iteration #%d", i)
+		}
+		fmt.Printf("%15s: %s\n", label, next.Format(system.TIME_FORMAT))
+		if utc := next.UTC().Format(system.TIME_FORMAT); utc != next.Format(system.TIME_FORMAT) {
+			fmt.Printf("%15s: %s\n", "(in UTC)", utc)
+		}
+		if i == 1 {
+			fmt.Printf("%15s: %s left\n", "From now", unit.FormatTimespan(next.Sub(now).Truncate(time.Second)))
+		}
+	}
+}
+
+func init() {
+	analyzeCalendarCmd.Flags().IntVar(&calendarIterations, "iterations", 1, "Number of next elapse times shown")
+	analyzeCmd.AddCommand(analyzeCalendarCmd)
+}
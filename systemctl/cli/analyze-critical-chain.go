@@ -0,0 +1,86 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"systemgo/system"
+)
+
+// analyzeCriticalChainCmd represents the analyze critical-chain command
+var analyzeCriticalChainCmd = &cobra.Command{
+	Use:   "critical-chain [UNIT]",
+	Short: "Show the chain of units, which delayed the activation of the unit",
+	Long: `critical-chain shows the tree of units, which delayed the activation of the unit specified, default.target, if none is.
+Of the units the unit is ordered after, the ones, which finished starting last, are expanded recursively`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := system.DEFAULT_TARGET
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		root, err := client.CriticalChain(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer page()()
+
+		fmt.Println(`The time when unit became active or started is printed after the "@" character.`)
+		fmt.Println(`The time the unit took to start is printed after the "+" character.`)
+		fmt.Println()
+		fmt.Println(formatChainNode(root))
+		printChain(root.Deps, "")
+	},
+}
+
+// formatChainNode formats the name of the unit of node followed by the times it finished starting at and spent starting
+func formatChainNode(node system.ChainNode) (s string) {
+	s = node.Name
+	if node.At > 0 {
+		s += " @" + formatSpan(node.At)
+	}
+	if node.Time > 0 {
+		s += " +" + formatSpan(node.Time)
+	}
+	return
+}
+
+// printChain prints nodes as branches of a tree, prefixing lines with prefix
+func printChain(nodes []system.ChainNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├─", "│ "
+		if i == len(nodes)-1 {
+			branch, indent = "└─", "  "
+		}
+
+		fmt.Printf("%s%s%s\n", prefix, branch, formatChainNode(node))
+		printChain(node.Deps, prefix+indent)
+	}
+}
+
+func init() {
+	analyzeCmd.AddCommand(analyzeCriticalChainCmd)
+}
@@ -0,0 +1,108 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"systemgo/system"
+)
+
+// Colors of the edges of the dependency graph by dependency type
+var dotColors = map[string]string{
+	"Requires":  "black",
+	"Wants":     "grey66",
+	"Conflicts": "red",
+	"After":     "green",
+}
+
+// Options of the dependency graph emitted by analyze dot
+var (
+	dotOrder, dotRequire           bool
+	dotFromPatterns, dotToPatterns []string
+)
+
+// analyzeDotCmd represents the analyze dot command
+var analyzeDotCmd = &cobra.Command{
+	Use:   "dot [PATTERN...]",
+	Short: "Emit the dependency graph of the loaded units in dot format",
+	Long: `dot emits the Requires=, Wants=, Conflicts= and After= dependencies of the loaded units in the format of Graphviz dot(1), e.g. "systemctl analyze dot | dot -Tsvg > deps.svg".
+If patterns are specified, only the dependencies of or on the units matching any of them are emitted.
+With --order, only After= dependencies are emitted, with --require, only the other ones`,
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, pattern := range append(append(append([]string{}, args...), dotFromPatterns...), dotToPatterns...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				log.Fatalf("Invalid pattern %q: %s", pattern, err)
+			}
+		}
+
+		edges, err := client.DependencyGraph()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println("digraph systemgo {")
+		for _, e := range edges {
+			if showEdge(e, args) {
+				fmt.Printf("\t%q->%q [color=%q];\n", e.From, e.To, dotColors[e.Type])
+			}
+		}
+		fmt.Println("}")
+
+		fmt.Fprintln(os.Stderr, `   Color legend: black     = Requires
+                 dark grey = Wants
+                 red       = Conflicts
+                 green     = After`)
+	},
+}
+
+// showEdge returns whether e is emitted, if the units matching patterns were requested
+func showEdge(e system.DependencyEdge, patterns []string) bool {
+	if dotOrder && !dotRequire && e.Type != "After" || dotRequire && !dotOrder && e.Type == "After" {
+		return false
+	}
+	if len(dotFromPatterns) > 0 && !matchAny(e.From, dotFromPatterns) || len(dotToPatterns) > 0 && !matchAny(e.To, dotToPatterns) {
+		return false
+	}
+	return len(patterns) == 0 || matchAny(e.From, patterns) || matchAny(e.To, patterns)
+}
+
+// matchAny returns whether name matches any of the shell patterns specified
+func matchAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	analyzeDotCmd.Flags().BoolVar(&dotOrder, "order", false, "Emit only After= dependencies")
+	analyzeDotCmd.Flags().BoolVar(&dotRequire, "require", false, "Emit only Requires=, Wants= and Conflicts= dependencies")
+	analyzeDotCmd.Flags().StringSliceVar(&dotFromPatterns, "from-pattern", nil, "Emit only dependencies of the units matching the pattern")
+	analyzeDotCmd.Flags().StringSliceVar(&dotToPatterns, "to-pattern", nil, "Emit only dependencies on the units matching the pattern")
+	analyzeCmd.AddCommand(analyzeDotCmd)
+}
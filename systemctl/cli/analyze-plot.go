@@ -0,0 +1,158 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"systemgo/system"
+)
+
+// Dimensions of the timeline emitted by analyze plot in pixels
+const (
+	plotSecond   = 100.0
+	plotRow      = 20
+	plotMargin   = 20
+	plotHeader   = 60
+	plotMinWidth = 800
+)
+
+// analyzePlotCmd represents the analyze plot command
+var analyzePlotCmd = &cobra.Command{
+	Use:   "plot",
+	Short: "Emit the timeline of the boot in SVG format",
+	Long: `plot emits the time spent booting by the kernel and by the manager along with the intervals the units were activating in as an SVG image, e.g. "systemctl analyze plot > boot.svg".
+Only the units, which finished activating, are shown`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tl, err := client.Timeline()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err = writePlot(os.Stdout, tl); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// writePlot writes tl to w as an SVG image. Units are placed after the time spent by the kernel
+func writePlot(w io.Writer, tl system.Timeline) (err error) {
+	x := func(d time.Duration) float64 {
+		return plotMargin + (tl.Kernel+d).Seconds()*plotSecond
+	}
+
+	end := tl.Userspace
+	for _, e := range tl.Units {
+		if e.Activated > end {
+			end = e.Activated
+		}
+	}
+
+	width := x(end) + plotMargin
+	if width < plotMinWidth {
+		width = plotMinWidth
+	}
+	height := plotHeader + (len(tl.Units)+2)*plotRow + plotMargin
+
+	p := &plotWriter{w: w}
+	p.printf(`<?xml version="1.0" standalone="no"?>
+<svg width="%.0f" height="%d" version="1.1" xmlns="http://www.w3.org/2000/svg">
+<style type="text/css">
+	rect { stroke-width: 1; stroke-opacity: 0; }
+	rect.kernel { fill: rgb(193,106,106); }
+	rect.userspace { fill: rgb(106,193,106); }
+	rect.activating { fill: rgb(255,0,0); fill-opacity: 0.7; }
+	rect.background { fill: rgb(255,255,255); }
+	line { stroke: rgb(64,64,64); stroke-width: 1; }
+	line.sec { stroke-opacity: 0.2; }
+	text { font-family: Verdana, Helvetica; font-size: 12px; }
+	text.title { font-size: 16px; }
+	text.sec { font-size: 10px; }
+</style>
+<rect class="background" width="100%%" height="100%%"/>
+`, width, height)
+
+	title := "Startup finished in " + formatSpan(tl.Userspace) + " (userspace)"
+	if tl.Kernel > 0 {
+		title = fmt.Sprintf("Startup finished in %s (kernel) + %s (userspace) = %s",
+			formatSpan(tl.Kernel), formatSpan(tl.Userspace), formatSpan(tl.Kernel+tl.Userspace))
+	}
+	p.printf("<text class=\"title\" x=\"%d\" y=\"%d\">%s</text>\n", plotMargin, plotMargin+10, html.EscapeString(title))
+	if tl.Target != "" && tl.TargetReached > 0 {
+		p.printf("<text x=\"%d\" y=\"%d\">%s</text>\n", plotMargin, plotMargin+30,
+			html.EscapeString(tl.Target+" reached after "+formatSpan(tl.TargetReached)+" in userspace"))
+	}
+
+	bottom := height - plotMargin
+	for s := time.Duration(0); s <= tl.Kernel+end; s += time.Second {
+		sx := x(s - tl.Kernel)
+		p.printf("<line class=\"sec\" x1=\"%.1f\" y1=\"%d\" x2=\"%.1f\" y2=\"%d\"/>\n", sx, plotHeader, sx, bottom)
+		p.printf("<text class=\"sec\" x=\"%.1f\" y=\"%d\">%s</text>\n", sx+2, plotHeader-2, formatSpan(s))
+	}
+
+	row := 0
+	bar := func(class, label string, from, to time.Duration) {
+		y := plotHeader + row*plotRow
+		row++
+
+		w := x(to) - x(from)
+		if w < 1 {
+			w = 1
+		}
+		p.printf("<rect class=\"%s\" x=\"%.1f\" y=\"%d\" width=\"%.1f\" height=\"%d\"/>\n", class, x(from), y+2, w, plotRow-4)
+		p.printf("<text x=\"%.1f\" y=\"%d\">%s</text>\n", x(from)+5, y+plotRow-6, html.EscapeString(label))
+	}
+
+	if tl.Kernel > 0 {
+		bar("kernel", "kernel", -tl.Kernel, 0)
+	}
+	bar("userspace", "systemgo", 0, tl.Userspace)
+	for _, e := range tl.Units {
+		bar("activating", fmt.Sprintf("%s (%s)", e.Name, formatSpan(e.Activated-e.Activating)), e.Activating, e.Activated)
+	}
+
+	p.printf("</svg>\n")
+	return p.err
+}
+
+// plotWriter writes formatted output, until an error occurs
+type plotWriter struct {
+	w   io.Writer
+	err error
+}
+
+// printf writes the output formatted according to format to the underlying writer, unless an error occurred before
+func (p *plotWriter) printf(format string, a ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, a...)
+	}
+}
+
+func init() {
+	analyzeCmd.AddCommand(analyzePlotCmd)
+}
@@ -0,0 +1,56 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// analyzeTimeCmd represents the analyze time command
+var analyzeTimeCmd = &cobra.Command{
+	Use:   "time",
+	Short: "Show the time spent booting",
+	Long:  `time shows the time spent booting by the kernel and by the manager and the time the boot target was reached after`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		bt, err := client.BootTiming()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if bt.Kernel > 0 {
+			fmt.Printf("Startup finished in %s (kernel) + %s (userspace) = %s\n",
+				formatSpan(bt.Kernel), formatSpan(bt.Userspace), formatSpan(bt.Kernel+bt.Userspace))
+		} else {
+			fmt.Printf("Startup finished in %s (userspace)\n", formatSpan(bt.Userspace))
+		}
+		if bt.Target != "" && bt.TargetReached > 0 {
+			fmt.Printf("%s reached after %s in userspace\n", bt.Target, formatSpan(bt.TargetReached))
+		}
+	},
+}
+
+func init() {
+	analyzeCmd.AddCommand(analyzeTimeCmd)
+}
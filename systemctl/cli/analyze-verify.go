@@ -0,0 +1,71 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"systemgo/system"
+)
+
+// analyzeVerifyCmd represents the analyze verify command
+var analyzeVerifyCmd = &cobra.Command{
+	Use:   "verify UNIT...",
+	Short: "Check unit definitions for problems",
+	Long: `verify loads the units specified by name from the unit paths or by path to the unit file and reports unknown options, bad values, invalid definitions, missing dependencies and ordering cycles without starting anything.
+Directories of the unit files specified by path are searched for dependencies first.
+Exits with non-zero exit code, if any problems are found`,
+	Args: cobra.MinimumNArgs(1),
+
+	// Units are verified without contacting the manager
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		sys, paths := system.New(), system.DEFAULT_PATHS
+		if user {
+			sys, paths = system.NewUser(), system.UserPaths()
+		}
+
+		var dirs []string
+		for _, name := range args {
+			if strings.Contains(name, "/") {
+				dirs = append(dirs, filepath.Dir(name))
+			}
+		}
+		sys.SetPaths(append(dirs, paths...)...)
+
+		errs := sys.Verify(args...)
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	analyzeCmd.AddCommand(analyzeVerifyCmd)
+}
@@ -0,0 +1,120 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"systemgo/system"
+)
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Generate the shell completion script",
+	Long: `completion prints the completion script for the shell specified, unit names get completed
+by querying the manager. To load the completions in the current bash session run:
+
+	source <(systemctl completion bash)`,
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+
+	// The script is generated without contacting the manager
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = RootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = RootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = RootCmd.GenFishCompletion(os.Stdout, true)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// completeUnits completes names of units loaded or found in the unit paths, which are not in args yet
+func completeUnits(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if client == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	seen := map[string]bool{}
+	for _, name := range args {
+		seen[name] = true
+	}
+
+	names := []string{}
+	add := func(name string) {
+		if !seen[name] && strings.HasPrefix(name, toComplete) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	if infos, err := client.ListUnits(system.UnitFilter{All: true}); err == nil {
+		for _, info := range infos {
+			add(info.Name)
+		}
+	}
+	if files, err := client.ListUnitFiles(); err == nil {
+		for _, f := range files {
+			add(f.Name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeUnit completes the name of a single unit
+func completeUnit(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeUnits(cmd, args, toComplete)
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+
+	for _, cmd := range []*cobra.Command{
+		startCmd, stopCmd, tryRestartCmd, reloadOrRestartCmd, tryReloadOrRestartCmd,
+		statusCmd, showCmd, resetFailedCmd, freezeCmd, thawCmd,
+		enableCmd, disableCmd, maskCmd, unmaskCmd, presetCmd, revertCmd, editCmd,
+		isActiveCmd, isFailedCmd, isEnabledCmd,
+	} {
+		cmd.ValidArgsFunction = completeUnits
+	}
+
+	for _, cmd := range []*cobra.Command{setPropertyCmd, listDependenciesCmd, setDefaultCmd} {
+		cmd.ValidArgsFunction = completeUnit
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// disableCmd represents the disable command
+var disableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable one or more unit files",
+	Long:  `TODO: add description`,
+	Run: func(cmd *cobra.Command, args []string) {
+		method := "Server.Disable"
+		if disableRuntime {
+			method = "Server.DisableRuntime"
+		}
+
+		if err := client.Call(method, args, nil); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// Whether to only disable units until reboot
+var disableRuntime bool
+
+func init() {
+	RootCmd.AddCommand(disableCmd)
+
+	disableCmd.Flags().BoolVar(&disableRuntime, "runtime", false, "Disable units enabled until the next reboot")
+}
@@ -0,0 +1,106 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Whether to edit the full definition instead of the override drop-in
+var editFull bool
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit UNIT...",
+	Short: "Edit one or more unit files",
+	Long:  `edit opens the override drop-in of each unit specified(or its full definition with --full) in an editor and reloads the unit, once the editor exits. The editor is taken from $SYSTEMD_EDITOR, $EDITOR or $VISUAL, vi is used, if none are set`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, name := range args {
+			if err := edit(name); err != nil {
+				log.Fatalf("Error editing %s: %s", name, err)
+			}
+		}
+	},
+}
+
+// edit lets the user edit the contents of the unit with name specified in an editor and writes them back,
+// if they were changed
+func edit(name string) (err error) {
+	var contents []byte
+	if contents, err = client.EditContents(name, editFull); err != nil {
+		return
+	}
+
+	var f *os.File
+	if f, err = ioutil.TempFile("", "systemctl-edit-*-"+filepath.Base(name)); err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(contents)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+
+	cmd := exec.Command("sh", "-c", editor()+` "$0"`, f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return
+	}
+
+	var edited []byte
+	if edited, err = ioutil.ReadFile(f.Name()); err != nil {
+		return
+	}
+
+	if bytes.Equal(edited, contents) {
+		fmt.Fprintf(os.Stderr, "%s not changed\n", name)
+		return nil
+	}
+	return client.Edit(name, edited, editFull)
+}
+
+// editor returns the command line of the editor to use
+func editor() string {
+	for _, env := range []string{"SYSTEMD_EDITOR", "EDITOR", "VISUAL"} {
+		if cmd := os.Getenv(env); cmd != "" {
+			return cmd
+		}
+	}
+	return "vi"
+}
+
+func init() {
+	RootCmd.AddCommand(editCmd)
+	editCmd.Flags().BoolVar(&editFull, "full", false, "Edit the full definition instead of the override drop-in")
+}
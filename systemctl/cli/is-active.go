@@ -0,0 +1,86 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"systemgo/systemctl"
+	"systemgo/unit"
+)
+
+// isActiveCmd represents the is-active command
+var isActiveCmd = &cobra.Command{
+	Use:   "is-active UNIT...",
+	Short: "Check whether units are active",
+	Long: `Print the activation state of each unit specified.
+Exit code is 0 if at least one of them is active, 3 otherwise`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !printActivations(args, unit.Active, unit.Reloading) {
+			os.Exit(3)
+		}
+	},
+}
+
+// printActivations prints the activation state of units specified by names, units, which are not loaded,
+// are reported as inactive. Returns whether any of them is in one of the states specified by match
+func printActivations(names []string, match ...unit.Activation) (matched bool) {
+	for _, name := range names {
+		var resp systemctl.Response
+		if err := client.Call("Server.IsActive", []string{name}, &resp); err != nil {
+			if !quiet {
+				fmt.Println(strings.ToLower(fmt.Sprint(unit.Inactive)))
+			}
+			continue
+		}
+
+		states, _ := resp.Yield.(map[string]unit.Activation)
+
+		matches := make([]string, 0, len(states))
+		for name := range states {
+			matches = append(matches, name)
+		}
+		sort.Strings(matches)
+
+		for _, name := range matches {
+			st := states[name]
+			if !quiet {
+				fmt.Println(strings.ToLower(fmt.Sprint(st)))
+			}
+			for _, m := range match {
+				if st == m {
+					matched = true
+				}
+			}
+		}
+	}
+	return
+}
+
+func init() {
+	isActiveCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Do not print the state")
+	RootCmd.AddCommand(isActiveCmd)
+}
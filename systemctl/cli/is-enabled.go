@@ -0,0 +1,88 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"systemgo/systemctl"
+	"systemgo/unit"
+)
+
+// isEnabledCmd represents the is-enabled command
+var isEnabledCmd = &cobra.Command{
+	Use:   "is-enabled UNIT...",
+	Short: "Check whether unit files are enabled",
+	Long: `Print the enablement state of each unit specified, one of:
+enabled, enabled-runtime, static, indirect, linked, masked, disabled.
+Exit code is 0 if at least one of them is enabled, static or indirect, 1 otherwise`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		enabled := false
+		for _, name := range args {
+			var resp systemctl.Response
+			if err := client.Call("Server.IsEnabled", []string{name}, &resp); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get unit file state for %s: %s\n", name, err)
+				continue
+			}
+
+			states, _ := resp.Yield.(map[string]unit.Enable)
+
+			matches := make([]string, 0, len(states))
+			for name := range states {
+				matches = append(matches, name)
+			}
+			sort.Strings(matches)
+
+			for _, name := range matches {
+				st := states[name]
+				if !quiet {
+					fmt.Println(enableName(st))
+				}
+				switch st {
+				case unit.Enabled, unit.EnabledRuntime, unit.Static, unit.Indirect:
+					enabled = true
+				}
+			}
+		}
+
+		if !enabled {
+			os.Exit(1)
+		}
+	},
+}
+
+// enableName returns the name of st as printed by Systemd
+func enableName(st unit.Enable) string {
+	if st == unit.EnabledRuntime {
+		return "enabled-runtime"
+	}
+	return strings.ToLower(fmt.Sprint(st))
+}
+
+func init() {
+	isEnabledCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Do not print the state")
+	RootCmd.AddCommand(isEnabledCmd)
+}
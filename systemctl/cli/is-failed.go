@@ -0,0 +1,47 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"systemgo/unit"
+)
+
+// isFailedCmd represents the is-failed command
+var isFailedCmd = &cobra.Command{
+	Use:   "is-failed UNIT...",
+	Short: "Check whether units have failed",
+	Long: `Print the activation state of each unit specified.
+Exit code is 0 if at least one of them has failed, 1 otherwise`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !printActivations(args, unit.Failed) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	isFailedCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Do not print the state")
+	RootCmd.AddCommand(isFailedCmd)
+}
@@ -0,0 +1,63 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+	"systemgo/system"
+	"systemgo/systemctl"
+)
+
+// Whether to suppress printing the state
+var quiet bool
+
+// isSystemRunningCmd represents the is-system-running command
+var isSystemRunningCmd = &cobra.Command{
+	Use:   "is-system-running",
+	Short: "Check whether the system is fully running",
+	Long: `Print the current state of the system, one of:
+initializing, starting, running, degraded, maintenance, stopping.
+Exit code is 0 only if the system is running`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var resp systemctl.Response
+		if err := client.Call("Server.IsSystemRunning", args, &resp); err != nil {
+			log.Fatal(err)
+		}
+
+		state, _ := resp.Yield.(string)
+		if !quiet {
+			fmt.Println(state)
+		}
+		if state != fmt.Sprint(system.Running) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	isSystemRunningCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Do not print the state")
+	RootCmd.AddCommand(isSystemRunningCmd)
+}
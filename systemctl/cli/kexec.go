@@ -0,0 +1,46 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"systemgo/system"
+)
+
+// kexecCmd represents the kexec command
+var kexecCmd = &cobra.Command{
+	Use:   "kexec",
+	Short: "Shut down and reboot the system with kexec",
+	Long: `kexec shuts the system down and reboots into the kernel loaded by kexec.
+If --force is specified, processes of units are killed instead of being stopped,
+if it is specified twice, reboot(2) is invoked right away without contacting the manager.
+With --when the shutdown is scheduled, logged-in users are warned about it in decreasing intervals`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		shutdownSystem(system.Kexec)
+	},
+}
+
+func init() {
+	kexecCmd.Flags().CountVarP(&force, "force", "f", "Kill processes of units instead of stopping them, specify twice to reboot immediately")
+	kexecCmd.Flags().StringVar(&when, "when", "", "Schedule the shutdown for \"+TIMESPAN\", \"HH:MM\" or \"YYYY-MM-DD HH:MM\", \"cancel\" or \"show\" it")
+	RootCmd.AddCommand(kexecCmd)
+}
@@ -0,0 +1,70 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"systemgo/system"
+)
+
+// Options of the dependency tree listed
+var dependencyOptions system.DependencyOptions
+
+// listDependenciesCmd represents the list-dependencies command
+var listDependenciesCmd = &cobra.Command{
+	Use:   "list-dependencies UNIT",
+	Short: "Recursively show units which are required or wanted by the unit",
+	Long:  `list-dependencies shows the tree of units required or wanted by the unit specified, or the units requiring or wanting it with --reverse. Only targets are expanded recursively, unless --all is specified`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		root, err := client.ListDependencies(args[0], dependencyOptions)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer page()()
+
+		fmt.Println(root.Name)
+		printDependencies(root.Deps, "")
+	},
+}
+
+// printDependencies prints deps as branches of a tree, prefixing lines with prefix
+func printDependencies(deps []system.DependencyNode, prefix string) {
+	for i, dep := range deps {
+		branch, indent := "├─", "│ "
+		if i == len(deps)-1 {
+			branch, indent = "└─", "  "
+		}
+
+		fmt.Printf("%s%s%s %s\n", prefix, branch, stateDot(dep.Load, dep.Active), dep.Name)
+		printDependencies(dep.Deps, prefix+indent)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(listDependenciesCmd)
+	listDependenciesCmd.Flags().BoolVar(&dependencyOptions.Reverse, "reverse", false, "Show units requiring or wanting the unit instead")
+	listDependenciesCmd.Flags().BoolVarP(&dependencyOptions.All, "all", "a", false, "Expand all units recursively, not only targets")
+}
@@ -0,0 +1,68 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Whether to list sockets of inactive socket units as well
+var listSocketsAll bool
+
+// listSocketsCmd represents the list-sockets command
+var listSocketsCmd = &cobra.Command{
+	Use:   "list-sockets",
+	Short: "List sockets of socket units",
+	Long:  `list-sockets lists the sockets active socket units listen on along with the services incoming traffic activates`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		infos, err := client.ListSockets(listSocketsAll)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer page()()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+		fmt.Fprintln(w, "LISTEN\tTYPE\tUNIT\tACTIVATES")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Listen.Address, info.Listen.Type, info.Name, info.Activates)
+		}
+		if err := w.Flush(); err != nil {
+			log.Error(err)
+		}
+
+		fmt.Printf("\n%d sockets listed.\n", len(infos))
+		if !listSocketsAll {
+			fmt.Println("Pass --all to see loaded but inactive sockets, too.")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(listSocketsCmd)
+	listSocketsCmd.Flags().BoolVarP(&listSocketsAll, "all", "a", false, "List sockets of inactive units as well")
+}
@@ -0,0 +1,97 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"systemgo/system"
+	"systemgo/unit"
+)
+
+// Whether to list inactive timers as well
+var listTimersAll bool
+
+// listTimersCmd represents the list-timers command
+var listTimersCmd = &cobra.Command{
+	Use:   "list-timers",
+	Short: "List timer units ordered by the time they elapse next",
+	Long:  `list-timers lists active timer units along with the time they elapse at next, the time they last elapsed at and the units they activate`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		infos, err := client.ListTimers(listTimersAll)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer page()()
+
+		now := time.Now()
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+		fmt.Fprintln(w, "NEXT\tLEFT\tLAST\tPASSED\tUNIT\tACTIVATES")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				formatTime(info.Next),
+				formatRelative(info.Next, info.Next.Sub(now), "left"),
+				formatTime(info.Last),
+				formatRelative(info.Last, now.Sub(info.Last), "ago"),
+				info.Name,
+				info.Activates)
+		}
+		if err := w.Flush(); err != nil {
+			log.Error(err)
+		}
+
+		fmt.Printf("\n%d timers listed.\n", len(infos))
+		if !listTimersAll {
+			fmt.Println("Pass --all to see loaded but inactive timers, too.")
+		}
+	},
+}
+
+// formatTime formats t for lists, "n/a" if t is zero
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "n/a"
+	}
+	return t.Format(system.TIME_FORMAT)
+}
+
+// formatRelative formats the time span d between t and now followed by suffix, "n/a" if t is zero
+func formatRelative(t time.Time, d time.Duration, suffix string) string {
+	if t.IsZero() {
+		return "n/a"
+	}
+	if d < 0 {
+		d = 0
+	}
+	return unit.FormatTimespan(d.Truncate(time.Second)) + " " + suffix
+}
+
+func init() {
+	RootCmd.AddCommand(listTimersCmd)
+	listTimersCmd.Flags().BoolVarP(&listTimersAll, "all", "a", false, "List inactive timers as well")
+}
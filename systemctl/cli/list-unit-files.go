@@ -0,0 +1,60 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// listUnitFilesCmd represents the list-unit-files command
+var listUnitFilesCmd = &cobra.Command{
+	Use:   "list-unit-files",
+	Short: "List unit files",
+	Long:  `list-unit-files lists unit files found in the unit paths along with their enablement states, whether the units are loaded or not`,
+	Run: func(cmd *cobra.Command, args []string) {
+		files, err := client.ListUnitFiles()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer page()()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+		fmt.Fprintln(w, "UNIT FILE\tSTATE")
+		for _, f := range files {
+			fmt.Fprintf(w, "%s\t%s\n", f.Name, enableName(f.State))
+		}
+		if err := w.Flush(); err != nil {
+			log.Error(err)
+		}
+
+		fmt.Printf("\n%d unit files listed.\n", len(files))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(listUnitFilesCmd)
+}
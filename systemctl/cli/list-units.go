@@ -21,42 +21,78 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
-	"os"
+	"strings"
 	"text/tabwriter"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"systemgo/systemctl"
-	"systemgo/unit"
+	"systemgo/system"
 )
 
+// Filter of the units listed
+var listFilter system.UnitFilter
+
 // list-unitsCmd represents the list-units command
 var listUnitsCmd = &cobra.Command{
 	Use:   "list-units",
 	Short: "list units",
-	Long:  `list units lists all units known to systemgo`,
+	Long:  `list units lists units known to systemgo, which are active, failed or have jobs queued`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var resp systemctl.Response
-		if err := client.Call("Server.StatusAll", args, &resp); err != nil {
+		filter := listFilter
+		if failed, _ := cmd.Flags().GetBool("failed"); failed {
+			filter.States = append(filter.States, "failed")
+		}
+
+		infos, err := client.ListUnits(filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		defer page()()
+
+		buf := &bytes.Buffer{}
+		w := tabwriter.NewWriter(buf, 0, 8, 1, ' ', 0)
+		fmt.Fprintln(w, "UNIT\tLOAD\tACTIVE\tSUB\tDESCRIPTION")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				info.Name,
+				strings.ToLower(fmt.Sprint(info.Load)),
+				strings.ToLower(fmt.Sprint(info.Active)),
+				info.Sub,
+				info.Description)
+		}
+		if err := w.Flush(); err != nil {
 			log.Error(err)
 		}
 
-		if resp.Yield != nil {
-			w := tabwriter.NewWriter(os.Stdout, 0, 8, 0, '\t', 0)
-			fmt.Fprintln(w, "unit\tload\tactive\tsub")
-			for name, st := range resp.Yield.(map[string]unit.Status) {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n",
-					name, st.Load.Loaded, st.Activation.State, st.Activation.Sub)
+		// Lines are colored once aligned, as escape sequences would be counted as cell contents
+		lines := strings.SplitAfter(buf.String(), "\n")
+		for i, info := range infos {
+			if color := stateColor(info.Load, info.Active); color == colorRed {
+				lines[i+1] = colored(strings.TrimSuffix(lines[i+1], "\n"), color) + "\n"
 			}
+		}
+		fmt.Print(strings.Join(lines, ""))
 
-			if err := w.Flush(); err != nil {
-				log.Error(err)
-			}
+		fmt.Printf(`
+LOAD   = Reflects whether the unit definition was properly loaded.
+ACTIVE = The high-level unit activation state, i.e. generalization of SUB.
+SUB    = The low-level unit activation state, values depend on unit type.
+
+%d loaded units listed.`, len(infos))
+		if !filter.All && len(filter.States) == 0 {
+			fmt.Print(" Pass --all to see loaded but inactive units, too.")
 		}
+		fmt.Println()
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(listUnitsCmd)
+	listUnitsCmd.Flags().StringSliceVarP(&listFilter.Types, "type", "t", nil, "List units of the types specified, e.g. service")
+	listUnitsCmd.Flags().StringSliceVar(&listFilter.States, "state", nil, "List units in the load, active or sub states specified")
+	listUnitsCmd.Flags().BoolVarP(&listFilter.All, "all", "a", false, "List inactive units as well")
+	listUnitsCmd.Flags().Bool("failed", false, "List failed units, same as --state=failed")
 }
@@ -0,0 +1,287 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"systemgo/system"
+	"systemgo/unit"
+)
+
+// Interval, at which new entries are queried, when following the logs
+const LOG_FOLLOW_INTERVAL = time.Second
+
+// Number of entries shown by default, when following the logs
+const LOG_FOLLOW_LINES = 10
+
+// Query of the entries shown
+var logQuery system.LogQuery
+
+// Options of logs, which are parsed by the command
+var (
+	logsFollow    bool
+	logsListBoots bool
+	logsSince     string
+	logsUntil     string
+	logsOutput    string
+)
+
+// Formats of entries supported by --output
+var logOutputs = map[string]bool{
+	"short":  true,
+	"export": true,
+	"json":   true,
+}
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show log entries of units and the manager",
+	Long: `logs shows entries of the logs of the units specified by -u, or of all units and the manager, if none are specified. With -k, entries of the kernel captured by the manager running as init are shown only, with --audit, records of the management operations requested by clients are shown only.
+With -b, entries logged during the boot specified are shown only: the current one, if none is specified, an offset relative to it, e.g. "-1" for the previous boot, an offset counted from the first boot, e.g. "1", or an ID listed by --list-boots.
+Entries can be restricted to a time range by --since and --until, which accept "now", "today", "yesterday", "-TIMESPAN", e.g. "-1h", "TIMESPAN ago", "HH:MM[:SS]" and "YYYY-MM-DD [HH:MM[:SS]]", and to the priority specified by -p or more important ones.
+With -f, new entries are shown as they are logged.
+Entries are shown in the format specified by -o: "short" lines, the Journal Export Format("export") ingested by collectors of the journal or JSON lines("json")`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !logOutputs[logsOutput] {
+			log.Fatalf("Unknown output format: %s", logsOutput)
+		}
+
+		if logsListBoots {
+			listBoots()
+			return
+		}
+
+		now := time.Now()
+
+		var err error
+		if logsSince != "" {
+			if logQuery.Since, err = parseLogTime(logsSince, now); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if logsUntil != "" {
+			if logQuery.Until, err = parseLogTime(logsUntil, now); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if logsFollow {
+			if logQuery.Reverse {
+				log.Fatal("--follow and --reverse can not be combined")
+			}
+			if !cmd.Flags().Changed("lines") {
+				logQuery.Lines = LOG_FOLLOW_LINES
+			}
+			followLogs(logQuery)
+			return
+		}
+
+		entries, err := client.LogEntries(logQuery)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if logsOutput == "short" {
+			defer page()()
+
+			if len(entries) == 0 {
+				fmt.Println("-- No entries --")
+				return
+			}
+		}
+		for _, e := range entries {
+			printLogEntry(e)
+		}
+	},
+}
+
+// listBoots prints the boots, which the log entries were logged during, with their offsets relative to the current boot
+func listBoots() {
+	boots, err := client.Boots()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 1, ' ', 0)
+	defer w.Flush()
+
+	for i, b := range boots {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i-len(boots)+1, b.ID,
+			b.First.Local().Format(time.Stamp), b.Last.Local().Format(time.Stamp))
+	}
+}
+
+// normalizeBootArgs joins "-b" and "--boot" with a negative offset following them, e.g. "-b -1",
+// which would be parsed as flags otherwise, as the offset is optional
+func normalizeBootArgs(args []string) []string {
+	normalized := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if (arg == "-b" || arg == "--boot") && i+1 < len(args) && isBootOffset(args[i+1]) {
+			arg += "=" + args[i+1]
+			i++
+		}
+		normalized = append(normalized, arg)
+	}
+	return normalized
+}
+
+// isBootOffset returns whether s is a negative boot offset
+func isBootOffset(s string) bool {
+	if len(s) < 2 || s[0] != '-' {
+		return false
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// followLogs prints the entries matching q and then the new ones as they are logged, until interrupted
+func followLogs(q system.LogQuery) {
+	// Entries printed, which were logged at the time of the last one
+	var printed []system.LogEntry
+
+	for {
+		entries, err := client.LogEntries(q)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, e := range entries {
+			if isPrinted(e, printed) {
+				continue
+			}
+			printLogEntry(e)
+
+			if len(printed) > 0 && !printed[0].Time.Equal(e.Time) {
+				printed = printed[:0]
+			}
+			printed = append(printed, e)
+		}
+
+		if len(printed) > 0 {
+			// Since is inclusive, hence the entries logged at the time of the last one are queried again
+			q.Since, q.Lines = printed[0].Time, 0
+		}
+		time.Sleep(LOG_FOLLOW_INTERVAL)
+	}
+}
+
+// isPrinted returns whether e is one of the entries printed
+func isPrinted(e system.LogEntry, printed []system.LogEntry) bool {
+	for _, p := range printed {
+		if p.Unit == e.Unit && p.Time.Equal(e.Time) && p.Monotonic == e.Monotonic && p.Message == e.Message {
+			return true
+		}
+	}
+	return false
+}
+
+// printLogEntry prints e in the format specified by --output, short lines highlight entries of high priority
+func printLogEntry(e system.LogEntry) {
+	switch logsOutput {
+	case "export":
+		if err := system.WriteExport(os.Stdout, e); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "json":
+		if err := json.NewEncoder(os.Stdout).Encode(e); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	msg := e.Message
+	switch {
+	case e.Priority <= system.LOG_ERR:
+		msg = colored(msg, colorRed)
+	case e.Priority <= system.LOG_NOTICE:
+		msg = colored(msg, colorYellow)
+	}
+
+	fmt.Printf("%s %s: %s\n", e.Time.Local().Format(time.Stamp), e.Identifier(), msg)
+}
+
+// parseLogTime parses the time specified to --since or --until relative to now
+func parseLogTime(s string, now time.Time) (t time.Time, err error) {
+	s = strings.TrimSpace(s)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch {
+	case s == "now":
+		return now, nil
+	case s == "today":
+		return today, nil
+	case s == "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case s == "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+
+	case strings.HasPrefix(s, "-") || strings.HasSuffix(s, " ago"):
+		var d time.Duration
+		if d, err = unit.ParseTimespan(strings.TrimSuffix(strings.TrimPrefix(s, "-"), " ago")); err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err = time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err = time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q", s)
+}
+
+func init() {
+	RootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().StringArrayVarP(&logQuery.Units, "unit", "u", nil, "Show entries of the unit, may be specified multiple times")
+	logsCmd.RegisterFlagCompletionFunc("unit", completeUnits)
+	logsCmd.Flags().BoolVarP(&logQuery.Kernel, "dmesg", "k", false, "Show entries of the kernel only")
+	logsCmd.Flags().BoolVar(&logQuery.Audit, "audit", false, "Show records of the management operations requested by clients only")
+	logsCmd.Flags().StringVarP(&logQuery.Boot, "boot", "b", "", "Show entries of the boot specified, the current one by default, e.g. \"-1\" for the previous one")
+	logsCmd.Flags().Lookup("boot").NoOptDefVal = "0"
+	logsCmd.Flags().BoolVar(&logsListBoots, "list-boots", false, "List the boots, which entries were logged during")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Show new entries as they are logged")
+	logsCmd.Flags().StringVarP(&logsSince, "since", "S", "", "Show entries logged not earlier than the time specified")
+	logsCmd.Flags().StringVarP(&logsUntil, "until", "U", "", "Show entries logged not later than the time specified")
+	logsCmd.Flags().StringVarP(&logQuery.Priority, "priority", "p", "", "Show entries of the priority specified or more important ones, e.g. \"err\" or \"3\"")
+	logsCmd.Flags().IntVarP(&logQuery.Lines, "lines", "n", 0, "Show the number of most recent entries specified")
+	logsCmd.Flags().BoolVarP(&logQuery.Reverse, "reverse", "r", false, "Show the newest entries first")
+	logsCmd.Flags().StringVarP(&logsOutput, "output", "o", "short", "Format of the entries shown: short, export or json")
+}
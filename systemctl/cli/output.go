@@ -0,0 +1,130 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"systemgo/unit"
+)
+
+// Escape sequences coloring output on terminals
+const (
+	colorRed    = "\x1b[0;1;31m"
+	colorGreen  = "\x1b[0;1;32m"
+	colorYellow = "\x1b[0;1;33m"
+	colorReset  = "\x1b[0m"
+)
+
+// Whether standard output is a terminal, output is only colored and paged if it is
+var isTerminal = func() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}()
+
+// Whether not to pipe output into a pager
+var noPager bool
+
+// page pipes standard output into the pager specified by $SYSTEMD_PAGER or $PAGER, less by default,
+// if it is a terminal. The function returned restores standard output and waits for the pager to exit,
+// it must be called once the output is written
+func page() (done func()) {
+	done = func() {}
+	if noPager || !isTerminal {
+		return
+	}
+
+	pager := os.Getenv("SYSTEMD_PAGER")
+	if pager == "" {
+		pager = os.Getenv("PAGER")
+	}
+	if pager == "" {
+		pager = "less"
+	}
+
+	// Output would be lost, if the pager could not be executed
+	if fields := strings.Fields(pager); len(fields) == 0 || fields[0] == "cat" {
+		return
+	} else if _, err := exec.LookPath(fields[0]); err != nil {
+		return
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		log.Debugf("Error creating pipe to the pager: %s", err)
+		return
+	}
+	defer r.Close()
+
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = r, os.Stdout, os.Stderr
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		// Quit, if the output fits on one screen, pass colors through and do not clear the screen
+		cmd.Env = append(os.Environ(), "LESS=FRXMK")
+	}
+
+	if err = cmd.Start(); err != nil {
+		log.Debugf("Error starting the pager: %s", err)
+		w.Close()
+		return
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	return func() {
+		os.Stdout = stdout
+		w.Close()
+		cmd.Wait()
+	}
+}
+
+// colored returns s wrapped in the escape sequences of color, if output is colored
+func colored(s, color string) string {
+	if !isTerminal || color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// stateColor returns the color units in state st are highlighted with, empty if none
+func stateColor(load unit.Load, st unit.Activation) string {
+	switch {
+	case load != unit.Loaded || st == unit.Failed:
+		return colorRed
+	case st == unit.Active:
+		return colorGreen
+	default:
+		return ""
+	}
+}
+
+// stateDot returns a dot, which is colored green if st is active and red if it failed or the unit
+// is not loaded, when output is colored
+func stateDot(load unit.Load, st unit.Activation) string {
+	return colored("●", stateColor(load, st))
+}
+
+func init() {
+	RootCmd.PersistentFlags().BoolVar(&noPager, "no-pager", false, "Do not pipe output into a pager")
+}
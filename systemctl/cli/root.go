@@ -21,17 +21,22 @@
 package cli
 
 import (
+	"bufio"
 	"fmt"
-	"net/rpc"
 	"os"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
+	ctl "systemgo/client"
 	"systemgo/config"
+	"systemgo/system"
+	"systemgo/systemctl"
 )
 
-var client *rpc.Client
+var client *ctl.Client
 
 var cfgFile string
 
@@ -46,20 +51,181 @@ var RootCmd = &cobra.Command{
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	RootCmd.SetArgs(normalizeBootArgs(os.Args[1:]))
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
 }
 
+// callJobs calls method with names and reports the outcome of each job, which did not succeed.
+// Exits with non-zero exit code, if any did not.
+func callJobs(method string, names []string) {
+	var resp systemctl.Response
+	if err := client.Call(method, names, &resp); err != nil {
+		log.Fatal(err)
+	}
+
+	res, ok := resp.Yield.(system.Results)
+	if !ok {
+		return
+	}
+
+	failed := res.Failed()
+	for _, name := range failed {
+		fmt.Fprintf(os.Stderr, "Job for %s failed: %s\n", name, res[name])
+	}
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+// Number of times --force is specified to halt, poweroff, reboot or kexec
+var force int
+
+// Time to schedule the shutdown for, "cancel" or "show" as passed to --when
+var when string
+
+// shutdownSystem makes the manager shut the system down as specified by kind.
+// If --force is specified, the processes of units get killed instead of being stopped,
+// if it is specified twice, reboot(2) is invoked right away without contacting the manager.
+// If --when is specified, the shutdown is scheduled, cancelled or shown instead.
+// Confirmation is asked for on a terminal, if the manager is not running as PID 1,
+// since then units are stopped, but the system is left running
+func shutdownSystem(kind system.ShutdownKind) {
+	if when != "" {
+		if force > 0 {
+			log.Fatal("--when can not be combined with --force")
+		}
+		scheduleShutdown(kind)
+		return
+	}
+
+	if force >= 2 {
+		if err := system.RebootNow(kind); err != nil {
+			log.Fatalf("Failed to %s: %s", kind, err)
+		}
+		return
+	}
+
+	st, err := client.Status()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if st.PID != 1 && stdinIsTerminal() {
+		fmt.Printf("The manager is not running as PID 1, %s will stop all units, but leave the system running. Continue? [y/N] ", kind)
+
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			os.Exit(1)
+		}
+	}
+
+	if err := client.ShutdownSystem(kind, force == 1); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// scheduleShutdown schedules the shutdown of kind for the time passed to --when, cancels it or shows it
+func scheduleShutdown(kind system.ShutdownKind) {
+	switch when {
+	case "cancel":
+		if err := client.CancelShutdown(); err != nil {
+			log.Fatal(err)
+		}
+
+	case "show":
+		s, ok, err := client.ScheduledShutdown()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !ok {
+			fmt.Println("No shutdown scheduled.")
+			return
+		}
+		fmt.Printf("%s scheduled for %s, use 'systemctl %s --when=cancel' to cancel.\n", s.Kind, s.When.Format(system.TIME_FORMAT), s.Kind)
+
+	default:
+		t, err := system.ParseShutdownTime(when, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = client.ScheduleShutdown(kind, t); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s scheduled for %s, use 'systemctl %s --when=cancel' to cancel.\n", kind, t.Format(system.TIME_FORMAT), kind)
+	}
+}
+
+// stdinIsTerminal returns whether standard input is a terminal
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Whether to talk to the user manager of the invoking user
+var user bool
+
+// Remote host to talk to the manager on, as [USER@]HOST[:PORT]
+var host string
+
 func init() {
-	addr := fmt.Sprintf("localhost%s", config.Port)
+	RootCmd.PersistentFlags().BoolVar(&user, "user", false, "Talk to the user manager of the invoking user")
+	RootCmd.PersistentFlags().StringVarP(&host, "host", "H", "", "Operate on the remote host [USER@]HOST[:PORT] over SSH")
+
+	// The completion command is provided by completion.go
+	RootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	RootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if cmd.Name() == cobra.ShellCompRequestCmd {
+			// Unit names are only completed, if the manager can be reached
+			client, _ = connect()
+			return
+		}
+		dial()
+	}
+}
 
-	e := log.WithField("addr", addr)
-	e.Debugf("Dialing...")
+// dial connects the client to the manager, exits on failure
+func dial() {
+	if force >= 2 {
+		// reboot(2) is invoked without contacting the manager
+		return
+	}
 
 	var err error
-	if client, err = rpc.DialHTTP("tcp", addr); err != nil {
-		e.Fatalf("Dial failed: %s", err)
+	if client, err = connect(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// connect connects to the control socket of the system manager or the user manager, if requested.
+// The system manager is reached over TCP, if the control socket does not exist.
+// If a remote host is specified, the control protocol is tunneled over SSH
+func connect() (c *ctl.Client, err error) {
+	if host != "" {
+		if c, err = ctl.DialSSH(host, user); err != nil {
+			return nil, fmt.Errorf("Failed to connect to the manager on %s: %s", host, err)
+		}
+		return c, nil
+	}
+
+	network, addr := "unix", systemctl.SOCKET_PATH
+	if user {
+		addr = systemctl.UserSocketPath()
+	} else if _, err := os.Stat(addr); os.IsNotExist(err) {
+		network, addr = "tcp", fmt.Sprintf("localhost%s", config.Port)
+	}
+
+	log.WithField("addr", addr).Debugf("Dialing...")
+
+	if c, err = ctl.Dial(network, addr); err != nil {
+		if ctl.IsNotRunning(err) {
+			return nil, fmt.Errorf("Failed to connect to the manager at %s: systemgo is not running", addr)
+		}
+		return nil, fmt.Errorf("Dial failed: %s", err)
 	}
+	return c, nil
 }
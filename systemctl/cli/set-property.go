@@ -0,0 +1,53 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// Whether to only change the properties at runtime
+var setPropertyRuntime bool
+
+// setPropertyCmd represents the set-property command
+var setPropertyCmd = &cobra.Command{
+	Use:   "set-property UNIT PROPERTY=VALUE...",
+	Short: "Set properties of a unit at runtime",
+	Long: `set-property changes the properties of a unit, which support it, while it is running.
+Supported are Restart=, MemoryMax=, CPUQuota= and TasksMax=, e.g.:
+
+	systemctl set-property foo.service CPUQuota=20% MemoryMax=512M
+
+The properties are persisted in a drop-in, unless --runtime is specified`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := client.SetProperty(args[0], args[1:], setPropertyRuntime); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(setPropertyCmd)
+	setPropertyCmd.Flags().BoolVar(&setPropertyRuntime, "runtime", false, "Do not persist the properties")
+}
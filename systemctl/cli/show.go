@@ -0,0 +1,71 @@
+// Copyright © 2016 Romans Volosatovs <rvolosatovs@riseup.net>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Names of the properties shown, all if empty
+var showProperties []string
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show UNIT...",
+	Short: "Show properties of one or more units",
+	Long:  `show prints the effective properties of each unit specified as NAME=VALUE lines, units are separated by an empty line`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		defer page()()
+
+		for i, name := range args {
+			props, err := client.Properties(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if i > 0 {
+				fmt.Println()
+			}
+
+			names := showProperties
+			if len(names) == 0 {
+				for name := range props {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+			}
+
+			for _, name := range names {
+				fmt.Printf("%s=%s\n", name, props[name])
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(showCmd)
+	showCmd.Flags().StringSliceVarP(&showProperties, "property", "p", nil, "Show only the properties specified, e.g. -p ActiveState,MainPID")
+}
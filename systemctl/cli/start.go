@@ -21,9 +21,13 @@
 package cli
 
 import (
+	"fmt"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
+	"systemgo/system"
+	"systemgo/systemctl"
 )
 
 // startCmd represents the start command
@@ -32,15 +36,30 @@ var startCmd = &cobra.Command{
 	Short: "Start (activate) one or more units",
 	Long:  `TODO: add description`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := client.Call("Server.Start", args, nil); err != nil {
-			log.Error(err)
+		if dryRun {
+			var resp systemctl.Response
+			if err := client.Call("Server.PlanStart", args, &resp); err != nil {
+				log.Error(err)
+			}
+
+			if plan, ok := resp.Yield.(system.Plan); ok {
+				fmt.Println(plan)
+			}
+			return
 		}
+
+		callJobs("Server.Start", args)
 	},
 }
 
+// Whether to only print the transaction, which would be run
+var dryRun bool
+
 func init() {
 	RootCmd.AddCommand(startCmd)
 
+	startCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the transaction plan instead of executing it")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -22,6 +22,9 @@ package cli
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"systemgo/systemctl"
@@ -41,16 +44,79 @@ var statusCmd = &cobra.Command{
 			log.Error(err)
 		}
 
-		if resp.Yield != nil {
-			for _, st := range resp.Yield.(map[string]unit.Status) {
-				fmt.Println(st)
+		follow, _ := cmd.Flags().GetBool("follow")
+		if !follow {
+			defer page()()
+		}
+
+		if statuses, ok := resp.Yield.(map[string]unit.Status); ok {
+			names := make([]string, 0, len(statuses))
+			for name := range statuses {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			n, _ := cmd.Flags().GetInt("n-transitions")
+			for i, name := range names {
+				if i > 0 {
+					fmt.Println()
+				}
+				printStatus(name, statuses[name])
+				if n > 0 {
+					printTransitions(name, n)
+				}
+			}
+		}
+
+		if follow {
+			events, _, err := client.Subscribe(args...)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for e := range events {
+				fmt.Printf("%s %s\n", e.Time.Format(time.Stamp), e)
 			}
 		}
 	},
 }
 
+// printStatus prints st of the unit name headed by a dot colored according to its state
+// and highlights the activation state
+func printStatus(name string, st unit.Status) {
+	color := stateColor(unit.Loaded, st.Activation.State)
+	fmt.Printf("%s %s\n", stateDot(st.Load.Loaded, st.Activation.State), name)
+
+	const active = "Active: "
+	for _, line := range strings.Split(st.String(), "\n") {
+		if strings.HasPrefix(line, active) {
+			line = active + colored(strings.TrimPrefix(line, active), color)
+		}
+		fmt.Println(line)
+	}
+}
+
+// printTransitions prints at most n most recent activation state transitions of the unit name
+func printTransitions(name string, n int) {
+	transitions, err := client.Transitions(name)
+	if err != nil {
+		log.Errorf("Error getting transitions of %s: %s", name, err)
+		return
+	}
+	if len(transitions) > n {
+		transitions = transitions[len(transitions)-n:]
+	}
+
+	fmt.Println("Transitions:")
+	for _, t := range transitions {
+		fmt.Printf("  %s %s\n", t.Time.Format(time.Stamp), t)
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().BoolP("follow", "f", false, "Follow state changes of the units and the manager")
+	statusCmd.Flags().Int("n-transitions", 0, "Number of the most recent state transitions shown")
 
 	// Here you will define your flags and configuration settings.
 
@@ -21,8 +21,6 @@
 package cli
 
 import (
-	"log"
-
 	"github.com/spf13/cobra"
 )
 
@@ -32,9 +30,7 @@ var stopCmd = &cobra.Command{
 	Short: "Stop (deactivate) one or more units",
 	Long:  `TODO: add description`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := client.Call("Server.Stop", args, nil); err != nil {
-			log.Fatalln(err.Error())
-		}
+		callJobs("Server.Stop", args)
 	},
 }
 
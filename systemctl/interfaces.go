@@ -1,6 +1,8 @@
 package systemctl
 
 import (
+	"time"
+
 	"systemgo/system"
 	"systemgo/unit"
 )
@@ -11,12 +13,65 @@ type Daemon interface {
 	Isolate(...string) error
 	Restart(...string) error
 	Reload(...string) error
+	TryRestart(...string) error
+	ReloadOrRestart(...string) error
+	ReloadOrTryRestart(...string) error
 	Enable(...string) error
 	Disable(...string) error
+	EnableRuntime(...string) error
+	DisableRuntime(...string) error
+	Mask(...string) error
+	Unmask(...string) error
+	Preset(...string) error
+	PresetAll() error
+	Link(string) error
+	Revert(...string) error
+	ResetFailed(...string) error
+	Freeze(...string) error
+	Thaw(...string) error
+	SetProperty(string, string, string, bool) error
+	ShutdownSystem(system.ShutdownKind) error
+	ForceShutdown(system.ShutdownKind) error
+	ScheduleShutdown(system.ShutdownKind, time.Time) error
+	CancelShutdown() error
+	ScheduledShutdown() (system.ScheduledShutdown, bool)
+	EditContents(string, bool) ([]byte, error)
+	Edit(string, []byte, bool) error
+
+	PlanStart(...string) (system.Plan, error)
+	ReloadDaemon() error
+	Reexec() error
+	DefaultTarget() (string, error)
+	SetDefaultTarget(string) error
+	SetEnvironment(...string) error
+	UnsetEnvironment(...string) error
+	ShowEnvironment() []string
 
 	Units() []*system.Unit
+	Match(string) ([]string, error)
+	Expand(...string) ([]string, error)
+	ListUnits(system.UnitFilter) []system.UnitInfo
+	ListUnitFiles() ([]system.UnitFile, error)
+	ListTimers(bool) []system.TimerInfo
+	ListSockets(bool) []system.SocketInfo
+	Blame() []system.UnitTiming
+	BootTiming() (system.BootTiming, error)
+	Timeline() (system.Timeline, error)
+	CriticalChain(string) (system.ChainNode, error)
+	Transitions(string) ([]system.Transition, error)
+	ListDependencies(string, system.DependencyOptions) (system.DependencyNode, error)
+	DependencyGraph() []system.DependencyEdge
+	State() system.State
 	Status() (system.Status, error)
 	StatusOf(string) (unit.Status, error)
+	Statuses(...string) (map[string]unit.Status, map[string]error)
 	IsEnabled(string) (unit.Enable, error)
 	IsActive(string) (unit.Activation, error)
+	Properties(string) (map[string]string, error)
+	Logs(system.LogQuery) ([]system.LogEntry, error)
+	Boots() ([]system.BootRecord, error)
+	Audit(system.AuditRecord)
+
+	Subscribe() <-chan system.Event
+	Unsubscribe(<-chan system.Event)
 }
@@ -3,7 +3,12 @@ package systemctl
 import (
 	"encoding/gob"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	log "github.com/sirupsen/logrus"
+	"systemgo/system"
 	"systemgo/unit"
 )
 
@@ -13,6 +18,28 @@ type Response struct {
 
 func init() {
 	gob.Register(map[string]unit.Status{})
+	gob.Register(system.Plan{})
+	gob.Register(system.StatusBatch{})
+	gob.Register(system.Results{})
+	gob.Register(system.Status{})
+	gob.Register(map[string]unit.Enable{})
+	gob.Register(map[string]unit.Activation{})
+	gob.Register([]system.Event{})
+	gob.Register([]system.UnitInfo{})
+	gob.Register([]system.UnitFile{})
+	gob.Register([]system.TimerInfo{})
+	gob.Register([]system.SocketInfo{})
+	gob.Register([]system.UnitTiming{})
+	gob.Register(system.BootTiming{})
+	gob.Register(system.Timeline{})
+	gob.Register(system.ChainNode{})
+	gob.Register([]system.Transition{})
+	gob.Register(system.DependencyNode{})
+	gob.Register([]system.DependencyEdge{})
+	gob.Register(map[string]string{})
+	gob.Register(system.ScheduledShutdown{})
+	gob.Register([]system.LogEntry{})
+	gob.Register([]system.BootRecord{})
 }
 
 func newResponse() (resp *Response) {
@@ -22,44 +49,246 @@ func newResponse() (resp *Response) {
 }
 
 func NewServer(sys Daemon) (sv *Server) {
-	return &Server{sys}
+	return &Server{
+		sys:  sys,
+		subs: &subscriptions{subs: map[string]*subscription{}},
+		uid:  -1,
+		pid:  -1,
+	}
 }
 
 type Server struct {
 	sys Daemon
+
+	// Event subscriptions of clients, shared by the servers of all connections
+	subs *subscriptions
+
+	// Credentials of the local client or the address of the remote one, see ServeHTTP
+	uid, pid int
+	remote   string
+}
+
+// results stores outcomes of jobs in resp, if err is a system.JobError,
+// so that the client can report them per-unit
+func (sv *Server) results(err error, resp *Response) error {
+	if jerr, ok := err.(system.JobError); ok {
+		*resp = Response{Yield: jerr.Results}
+		return nil
+	}
+	return err
+}
+
+// expanded calls fn with names, shell patterns among which are expanded, see system.Daemon.Expand
+func (sv *Server) expanded(names []string, fn func(...string) error) (err error) {
+	if names, err = sv.sys.Expand(names...); err != nil {
+		return
+	}
+	return fn(names...)
 }
 
 func (sv *Server) Start(names []string, resp *Response) (err error) {
-	return sv.sys.Start(names...)
+	return sv.results(sv.audited("start", names, sv.expanded(names, sv.sys.Start)), resp)
+}
+
+func (sv *Server) PlanStart(names []string, resp *Response) (err error) {
+	*resp = *newResponse()
+
+	if names, err = sv.sys.Expand(names...); err != nil {
+		return
+	}
+
+	var p system.Plan
+	if p, err = sv.sys.PlanStart(names...); err != nil {
+		return
+	}
+
+	resp.Yield = p
+	return
 }
 
 func (sv *Server) Stop(names []string, resp *Response) (err error) {
-	return sv.sys.Stop(names...)
+	return sv.results(sv.audited("stop", names, sv.expanded(names, sv.sys.Stop)), resp)
 }
 
 func (sv *Server) Restart(names []string, resp *Response) (err error) {
-	return sv.sys.Restart(names...)
+	return sv.results(sv.audited("restart", names, sv.expanded(names, sv.sys.Restart)), resp)
 }
 
 func (sv *Server) Isolate(names []string, resp *Response) (err error) {
-	return sv.sys.Isolate(names...)
+	return sv.results(sv.audited("isolate", names, sv.sys.Isolate(names...)), resp)
 }
 
 func (sv *Server) Reload(names []string, resp *Response) (err error) {
-	return sv.sys.Reload(names...)
+	return sv.results(sv.audited("reload", names, sv.expanded(names, sv.sys.Reload)), resp)
+}
+
+func (sv *Server) TryRestart(names []string, resp *Response) (err error) {
+	return sv.results(sv.audited("try-restart", names, sv.expanded(names, sv.sys.TryRestart)), resp)
+}
+
+func (sv *Server) ReloadOrRestart(names []string, resp *Response) (err error) {
+	return sv.results(sv.audited("reload-or-restart", names, sv.expanded(names, sv.sys.ReloadOrRestart)), resp)
+}
+
+func (sv *Server) ReloadOrTryRestart(names []string, resp *Response) (err error) {
+	return sv.results(sv.audited("reload-or-try-restart", names, sv.expanded(names, sv.sys.ReloadOrTryRestart)), resp)
 }
 
 func (sv *Server) Enable(names []string, resp *Response) (err error) {
-	return sv.sys.Enable(names...)
+	return sv.audited("enable", names, sv.expanded(names, sv.sys.Enable))
 }
 
 func (sv *Server) Disable(names []string, resp *Response) (err error) {
-	return sv.sys.Disable(names...)
+	return sv.audited("disable", names, sv.expanded(names, sv.sys.Disable))
+}
+
+func (sv *Server) EnableRuntime(names []string, resp *Response) (err error) {
+	return sv.audited("enable-runtime", names, sv.expanded(names, sv.sys.EnableRuntime))
+}
+
+func (sv *Server) DisableRuntime(names []string, resp *Response) (err error) {
+	return sv.audited("disable-runtime", names, sv.expanded(names, sv.sys.DisableRuntime))
+}
+
+func (sv *Server) ReloadDaemon(args []string, resp *Response) (err error) {
+	return sv.sys.ReloadDaemon()
+}
+
+func (sv *Server) Reexec(args []string, resp *Response) (err error) {
+	return sv.sys.Reexec()
+}
+
+// ShutdownArgs are the arguments of ShutdownSystem
+type ShutdownArgs struct {
+	Kind system.ShutdownKind
+
+	// Whether to kill the processes of units instead of stopping them
+	Force bool
+
+	// Time to schedule the shutdown for, the system is shut down right away if zero
+	When time.Time
+}
+
+// ShutdownSystem starts shutting the system down or schedules the shutdown and returns right away,
+// see system.Daemon.ShutdownSystem, system.Daemon.ForceShutdown and system.Daemon.ScheduleShutdown
+func (sv *Server) ShutdownSystem(args ShutdownArgs, resp *Response) (err error) {
+	if !args.When.IsZero() {
+		return sv.sys.ScheduleShutdown(args.Kind, args.When)
+	}
+
+	shutdown := sv.sys.ShutdownSystem
+	if args.Force {
+		shutdown = sv.sys.ForceShutdown
+	}
+
+	go func() {
+		if err := shutdown(args.Kind); err != nil {
+			log.WithField("kind", args.Kind).Errorf("Error shutting down: %s", err)
+		}
+	}()
+	return nil
+}
+
+// CancelShutdown cancels the scheduled shutdown
+func (sv *Server) CancelShutdown(args []string, resp *Response) (err error) {
+	return sv.sys.CancelShutdown()
+}
+
+// ScheduledShutdown yields the scheduled shutdown, which is zero if none is scheduled
+func (sv *Server) ScheduledShutdown(args []string, resp *Response) (err error) {
+	s, _ := sv.sys.ScheduledShutdown()
+	*resp = Response{Yield: s}
+	return nil
+}
+
+func (sv *Server) Mask(names []string, resp *Response) (err error) {
+	return sv.audited("mask", names, sv.expanded(names, sv.sys.Mask))
+}
+
+func (sv *Server) Unmask(names []string, resp *Response) (err error) {
+	return sv.audited("unmask", names, sv.expanded(names, sv.sys.Unmask))
+}
+
+func (sv *Server) Preset(names []string, resp *Response) (err error) {
+	return sv.expanded(names, sv.sys.Preset)
+}
+
+func (sv *Server) PresetAll(args []string, resp *Response) (err error) {
+	return sv.sys.PresetAll()
+}
+
+func (sv *Server) Link(paths []string, resp *Response) (err error) {
+	for _, path := range paths {
+		if err = sv.sys.Link(path); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (sv *Server) Revert(names []string, resp *Response) (err error) {
+	return sv.expanded(names, sv.sys.Revert)
+}
+
+// ResetFailed resets the failed state of units specified by names, all failed units if none are specified
+func (sv *Server) ResetFailed(names []string, resp *Response) (err error) {
+	return sv.expanded(names, sv.sys.ResetFailed)
+}
+
+// Freeze suspends all processes of units specified by names
+func (sv *Server) Freeze(names []string, resp *Response) (err error) {
+	return sv.expanded(names, sv.sys.Freeze)
+}
+
+// Thaw resumes all processes of units specified by names
+func (sv *Server) Thaw(names []string, resp *Response) (err error) {
+	return sv.expanded(names, sv.sys.Thaw)
+}
+
+func (sv *Server) DefaultTarget(args []string, resp *Response) (err error) {
+	*resp = *newResponse()
+
+	var name string
+	if name, err = sv.sys.DefaultTarget(); err != nil {
+		return
+	}
+
+	resp.Yield = name
+	return
+}
+
+func (sv *Server) SetDefaultTarget(names []string, resp *Response) (err error) {
+	if len(names) != 1 {
+		return fmt.Errorf("Expected exactly one target name, got %d", len(names))
+	}
+	return sv.sys.SetDefaultTarget(names[0])
+}
+
+func (sv *Server) SetEnvironment(assignments []string, resp *Response) (err error) {
+	return sv.sys.SetEnvironment(assignments...)
+}
+
+func (sv *Server) UnsetEnvironment(names []string, resp *Response) (err error) {
+	return sv.sys.UnsetEnvironment(names...)
+}
+
+func (sv *Server) ShowEnvironment(args []string, resp *Response) (err error) {
+	*resp = Response{Yield: sv.sys.ShowEnvironment()}
+	return nil
+}
+
+func (sv *Server) IsSystemRunning(args []string, resp *Response) (err error) {
+	*resp = Response{Yield: fmt.Sprint(sv.sys.State())}
+	return nil
 }
 
 func (sv *Server) Status(names []string, resp *Response) (err error) {
 	*resp = *newResponse()
 
+	if names, err = sv.sys.Expand(names...); err != nil {
+		return
+	}
+
 	statuses := map[string]unit.Status{}
 
 	for _, name := range names {
@@ -75,6 +304,276 @@ func (sv *Server) Status(names []string, resp *Response) (err error) {
 	return err
 }
 
+// Statuses yields the statuses of units specified by names and the errors looking up the ones,
+// which were not found, or the statuses of all units, if none are specified, see system.Daemon.Statuses
+func (sv *Server) Statuses(names []string, resp *Response) (err error) {
+	var expanded []string
+	if expanded, err = sv.sys.Expand(names...); err != nil {
+		return
+	}
+
+	// Patterns matching no units must not select all of them
+	if len(names) > 0 && len(expanded) == 0 {
+		*resp = Response{Yield: system.StatusBatch{Statuses: map[string]unit.Status{}}}
+		return nil
+	}
+
+	statuses, errs := sv.sys.Statuses(expanded...)
+
+	batch := system.StatusBatch{Statuses: statuses}
+	for name, err := range errs {
+		if batch.Errors == nil {
+			batch.Errors = map[string]string{}
+		}
+		batch.Errors[name] = err.Error()
+	}
+
+	*resp = Response{Yield: batch}
+	return nil
+}
+
+func (sv *Server) SystemStatus(args []string, resp *Response) (err error) {
+	var st system.Status
+	if st, err = sv.sys.Status(); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: st}
+	return
+}
+
+func (sv *Server) ListUnits(args []string, resp *Response) (err error) {
+	units := sv.sys.Units()
+
+	names := make([]string, 0, len(units))
+	for _, u := range units {
+		names = append(names, u.Name())
+	}
+	sort.Strings(names)
+
+	*resp = Response{Yield: names}
+	return nil
+}
+
+// ListUnitsFiltered yields the units selected by filter, see system.Daemon.ListUnits
+func (sv *Server) ListUnitsFiltered(filter system.UnitFilter, resp *Response) (err error) {
+	*resp = Response{Yield: sv.sys.ListUnits(filter)}
+	return nil
+}
+
+func (sv *Server) ListUnitFiles(args []string, resp *Response) (err error) {
+	var files []system.UnitFile
+	if files, err = sv.sys.ListUnitFiles(); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: files}
+	return nil
+}
+
+// ListTimers yields the active timer units, or all of them, if all is true, see system.Daemon.ListTimers
+func (sv *Server) ListTimers(all bool, resp *Response) (err error) {
+	*resp = Response{Yield: sv.sys.ListTimers(all)}
+	return nil
+}
+
+// ListSockets yields the sockets of active socket units, or of all of them, if all is true, see system.Daemon.ListSockets
+func (sv *Server) ListSockets(all bool, resp *Response) (err error) {
+	*resp = Response{Yield: sv.sys.ListSockets(all)}
+	return nil
+}
+
+// Blame yields the units, which finished activating, along with the times they spent activating, see system.Daemon.Blame
+func (sv *Server) Blame(args []string, resp *Response) (err error) {
+	*resp = Response{Yield: sv.sys.Blame()}
+	return nil
+}
+
+// BootTiming yields the time spent booting, see system.Daemon.BootTiming
+func (sv *Server) BootTiming(args []string, resp *Response) (err error) {
+	var bt system.BootTiming
+	if bt, err = sv.sys.BootTiming(); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: bt}
+	return nil
+}
+
+// Timeline yields the timeline of the boot, see system.Daemon.Timeline
+func (sv *Server) Timeline(args []string, resp *Response) (err error) {
+	var tl system.Timeline
+	if tl, err = sv.sys.Timeline(); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: tl}
+	return nil
+}
+
+// CriticalChain yields the chain of units, which delayed the activation of the unit, see system.Daemon.CriticalChain
+func (sv *Server) CriticalChain(name string, resp *Response) (err error) {
+	var root system.ChainNode
+	if root, err = sv.sys.CriticalChain(name); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: root}
+	return nil
+}
+
+// Transitions yields the most recent activation state transitions of the unit, see system.Daemon.Transitions
+func (sv *Server) Transitions(name string, resp *Response) (err error) {
+	var transitions []system.Transition
+	if transitions, err = sv.sys.Transitions(name); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: transitions}
+	return nil
+}
+
+// ListDependenciesArgs are the arguments of ListDependencies
+type ListDependenciesArgs struct {
+	Name    string
+	Options system.DependencyOptions
+}
+
+// ListDependencies yields the dependency tree of the unit, see system.Daemon.ListDependencies
+func (sv *Server) ListDependencies(args ListDependenciesArgs, resp *Response) (err error) {
+	var root system.DependencyNode
+	if root, err = sv.sys.ListDependencies(args.Name, args.Options); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: root}
+	return nil
+}
+
+// DependencyGraph yields the dependencies of the loaded units, see system.Daemon.DependencyGraph
+func (sv *Server) DependencyGraph(args []string, resp *Response) (err error) {
+	*resp = Response{Yield: sv.sys.DependencyGraph()}
+	return nil
+}
+
+// Logs yields the log entries matching the query, see system.Daemon.Logs
+func (sv *Server) Logs(q system.LogQuery, resp *Response) (err error) {
+	var entries []system.LogEntry
+	if entries, err = sv.sys.Logs(q); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: entries}
+	return nil
+}
+
+// Boots yields the boots, which the log entries were logged during, see system.Daemon.Boots
+func (sv *Server) Boots(args []string, resp *Response) (err error) {
+	var boots []system.BootRecord
+	if boots, err = sv.sys.Boots(); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: boots}
+	return nil
+}
+
+// EditArgs are the arguments of EditContents and Edit
+type EditArgs struct {
+	Name     string
+	Contents []byte
+	Full     bool
+}
+
+// EditContents yields the contents to be edited, see system.Daemon.EditContents
+func (sv *Server) EditContents(args EditArgs, resp *Response) (err error) {
+	var b []byte
+	if b, err = sv.sys.EditContents(args.Name, args.Full); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: b}
+	return nil
+}
+
+// Edit writes the contents edited, see system.Daemon.Edit
+func (sv *Server) Edit(args EditArgs, resp *Response) (err error) {
+	return sv.sys.Edit(args.Name, args.Contents, args.Full)
+}
+
+// SetPropertyArgs are the arguments of SetProperty
+type SetPropertyArgs struct {
+	Name string
+
+	// Properties to set as KEY=VALUE assignments
+	Assignments []string
+
+	// Whether the properties are only changed at runtime and not persisted
+	Runtime bool
+}
+
+// SetProperty sets the properties of a unit, see system.Daemon.SetProperty
+func (sv *Server) SetProperty(args SetPropertyArgs, resp *Response) (err error) {
+	for _, assignment := range args.Assignments {
+		kv := strings.SplitN(assignment, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("Not an assignment: %s", assignment)
+		}
+
+		if err = sv.sys.SetProperty(args.Name, kv[0], kv[1], !args.Runtime); err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// Properties yields the effective properties of the unit args[0], see system.Daemon.Properties
+func (sv *Server) Properties(args []string, resp *Response) (err error) {
+	if len(args) != 1 {
+		return fmt.Errorf("Expected exactly one unit name, got %d", len(args))
+	}
+
+	var props map[string]string
+	if props, err = sv.sys.Properties(args[0]); err != nil {
+		return
+	}
+
+	*resp = Response{Yield: props}
+	return nil
+}
+
+func (sv *Server) IsEnabled(names []string, resp *Response) (err error) {
+	if names, err = sv.sys.Expand(names...); err != nil {
+		return
+	}
+
+	states := map[string]unit.Enable{}
+	for _, name := range names {
+		if states[name], err = sv.sys.IsEnabled(name); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	*resp = Response{Yield: states}
+	return nil
+}
+
+func (sv *Server) IsActive(names []string, resp *Response) (err error) {
+	if names, err = sv.sys.Expand(names...); err != nil {
+		return
+	}
+
+	states := map[string]unit.Activation{}
+	for _, name := range names {
+		if states[name], err = sv.sys.IsActive(name); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
+	*resp = Response{Yield: states}
+	return nil
+}
+
 func (sv *Server) StatusAll(names []string, resp *Response) (err error) {
 	units := sv.sys.Units()
 
@@ -0,0 +1,15 @@
+package systemctl
+
+import (
+	"path/filepath"
+
+	"systemgo/system"
+)
+
+// Path to the control socket of the system manager
+const SOCKET_PATH = "/run/systemgo/control"
+
+// UserSocketPath returns path to the control socket of the user manager of the invoking user
+func UserSocketPath() string {
+	return filepath.Join(system.UserRuntimeDir(), "systemgo", "control")
+}
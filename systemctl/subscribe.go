@@ -0,0 +1,152 @@
+package systemctl
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"systemgo/system"
+)
+
+// Maximum duration an Events call blocks for, if no events are emitted
+const EVENTS_POLL_TIMEOUT = 30 * time.Second
+
+// Duration after which subscriptions not polled by clients get dropped
+const SUBSCRIPTION_TIMEOUT = 2 * EVENTS_POLL_TIMEOUT
+
+var ErrNoSubscription = errors.New("No such subscription")
+
+// subscription delivers events emitted by the daemon to a client polling for them
+type subscription struct {
+	events <-chan system.Event
+
+	// Names of units events are delivered for, all units if empty
+	units map[string]bool
+
+	// Time of the last poll
+	polled time.Time
+}
+
+// subscriptions holds subscriptions of clients by ID
+type subscriptions struct {
+	subs  map[string]*subscription
+	last  uint64
+	mutex sync.Mutex
+}
+
+// matches returns whether e should be delivered to sub
+func (sub *subscription) matches(e system.Event) bool {
+	return len(sub.units) == 0 || e.Unit == "" || sub.units[e.Unit]
+}
+
+// Subscribe subscribes to events about units specified by names, all units if none are specified.
+// Manager state changes are always delivered.
+// Yields the ID of the subscription to be passed to Events and Unsubscribe
+func (sv *Server) Subscribe(names []string, resp *Response) (err error) {
+	sv.subs.mutex.Lock()
+	defer sv.subs.mutex.Unlock()
+
+	sv.expire()
+
+	sub := &subscription{
+		events: sv.sys.Subscribe(),
+		units:  map[string]bool{},
+		polled: time.Now(),
+	}
+	for _, name := range names {
+		sub.units[name] = true
+	}
+
+	sv.subs.last++
+	id := fmt.Sprint(sv.subs.last)
+	sv.subs.subs[id] = sub
+
+	*resp = Response{Yield: id}
+	return nil
+}
+
+// Events yields events delivered to the subscription with ID args[0] since the previous call.
+// Blocks until an event is emitted or EVENTS_POLL_TIMEOUT passes
+func (sv *Server) Events(args []string, resp *Response) (err error) {
+	if len(args) != 1 {
+		return ErrNoSubscription
+	}
+
+	sv.subs.mutex.Lock()
+	sub, ok := sv.subs.subs[args[0]]
+	if ok {
+		sub.polled = time.Now()
+	}
+	sv.subs.mutex.Unlock()
+
+	if !ok {
+		return ErrNoSubscription
+	}
+
+	events := []system.Event{}
+	timeout := time.NewTimer(EVENTS_POLL_TIMEOUT)
+	defer timeout.Stop()
+
+	for len(events) == 0 {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				return ErrNoSubscription
+			}
+			if sub.matches(e) {
+				events = append(events, e)
+			}
+		case <-timeout.C:
+			*resp = Response{Yield: events}
+			return nil
+		}
+	}
+
+	// Drain the events already buffered
+	for drained := false; !drained; {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				drained = true
+			} else if sub.matches(e) {
+				events = append(events, e)
+			}
+		default:
+			drained = true
+		}
+	}
+
+	*resp = Response{Yield: events}
+	return nil
+}
+
+// Unsubscribe drops the subscription with ID args[0]
+func (sv *Server) Unsubscribe(args []string, resp *Response) (err error) {
+	if len(args) != 1 {
+		return ErrNoSubscription
+	}
+
+	sv.subs.mutex.Lock()
+	defer sv.subs.mutex.Unlock()
+
+	sub, ok := sv.subs.subs[args[0]]
+	if !ok {
+		return ErrNoSubscription
+	}
+
+	delete(sv.subs.subs, args[0])
+	sv.sys.Unsubscribe(sub.events)
+	return nil
+}
+
+// expire drops subscriptions, which have not been polled for SUBSCRIPTION_TIMEOUT.
+// sv.subs.mutex must be held
+func (sv *Server) expire() {
+	for id, sub := range sv.subs.subs {
+		if time.Since(sub.polled) > SUBSCRIPTION_TIMEOUT {
+			delete(sv.subs.subs, id)
+			sv.sys.Unsubscribe(sub.events)
+		}
+	}
+}
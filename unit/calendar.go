@@ -0,0 +1,369 @@
+package unit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Years calendar events are searched in
+const (
+	CALENDAR_MIN_YEAR = 1970
+	CALENDAR_MAX_YEAR = 2199
+)
+
+// Shorthands of calendar events and the expressions they stand for
+var calendarShorthands = map[string]string{
+	"minutely":     "*-*-* *:*:00",
+	"hourly":       "*-*-* *:00:00",
+	"daily":        "*-*-* 00:00:00",
+	"weekly":       "Mon *-*-* 00:00:00",
+	"monthly":      "*-*-01 00:00:00",
+	"quarterly":    "*-01,04,07,10-01 00:00:00",
+	"semiannually": "*-01,07-01 00:00:00",
+	"yearly":       "*-01-01 00:00:00",
+	"annually":     "*-01-01 00:00:00",
+}
+
+// Names of weekdays in calendar events, the short ones are used when formatting
+var weekdayNames = []struct {
+	short, long string
+}{
+	{"Sun", "sunday"},
+	{"Mon", "monday"},
+	{"Tue", "tuesday"},
+	{"Wed", "wednesday"},
+	{"Thu", "thursday"},
+	{"Fri", "friday"},
+	{"Sat", "saturday"},
+}
+
+// CalendarSpec is a calendar event expression as found in OnCalendar=, e.g. "Mon..Fri *-*-* 09:00:00",
+// see systemd.time(7)
+type CalendarSpec struct {
+	// Bit i is set, if time.Weekday(i) matches, 0 means any weekday
+	weekdays uint8
+
+	year, month, day, hour, minute, second calendarComponent
+
+	// Time zone the expression is evaluated in, nil means the local one
+	location *time.Location
+}
+
+// calendarComponent is a list of values matching a date or time component, nil means any value
+type calendarComponent []calendarRange
+
+// calendarRange matches values from start to end, which repeat every step, if step is not 0.
+// end is -1 for ranges, which have no end
+type calendarRange struct {
+	start, end, step int
+}
+
+// ParseCalendar parses s as a calendar event expression: an optional list of weekdays, a date in
+// the form [YEAR-]MONTH-DAY, a time in the form HOUR:MINUTE[:SECOND] and an optional time zone.
+// Date and time components are lists of values, ranges("a..b") and repetitions("a/step"), "*" matches any value.
+// An omitted date matches any day, an omitted time matches midnight. Shorthands like "daily" are recognized
+func ParseCalendar(s string) (spec *CalendarSpec, err error) {
+	s = strings.TrimSpace(s)
+	if expanded, ok := calendarShorthands[strings.ToLower(s)]; ok {
+		s = expanded
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty calendar event")
+	}
+
+	spec = &CalendarSpec{
+		hour:   calendarComponent{{0, 0, 0}},
+		minute: calendarComponent{{0, 0, 0}},
+		second: calendarComponent{{0, 0, 0}},
+	}
+
+	if last := fields[len(fields)-1]; len(fields) > 1 && !strings.ContainsAny(last, ":-*") {
+		if spec.location, err = time.LoadLocation(last); err != nil {
+			return nil, fmt.Errorf("unknown time zone: %q", last)
+		}
+		fields = fields[:len(fields)-1]
+	}
+
+	if isWeekdayList(fields[0]) {
+		if spec.weekdays, err = parseWeekdays(fields[0]); err != nil {
+			return nil, err
+		}
+		fields = fields[1:]
+	}
+
+	var date, clock bool
+	for _, field := range fields {
+		switch {
+		case strings.Contains(field, ":") && !clock:
+			if err = spec.parseTime(field); err != nil {
+				return nil, err
+			}
+			clock = true
+		case strings.Contains(field, "-") && !date:
+			if err = spec.parseDate(field); err != nil {
+				return nil, err
+			}
+			date = true
+		default:
+			return nil, fmt.Errorf("invalid calendar event component: %q", field)
+		}
+	}
+	return spec, nil
+}
+
+// isWeekdayList reports whether s starts like a list of weekdays
+func isWeekdayList(s string) bool {
+	return s != "" && (s[0] >= 'A' && s[0] <= 'Z' || s[0] >= 'a' && s[0] <= 'z') && !strings.Contains(s, "/")
+}
+
+// parseWeekday parses the full or abbreviated name of a weekday
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(s)
+	for i, name := range weekdayNames {
+		if len(s) >= 3 && strings.HasPrefix(name.long, s) {
+			return time.Weekday(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday: %q", s)
+}
+
+// parseWeekdays parses a list of weekdays and ranges of weekdays, e.g. "Mon..Fri,Sun"
+func parseWeekdays(s string) (mask uint8, err error) {
+	for _, item := range strings.Split(s, ",") {
+		bounds := strings.SplitN(item, "..", 2)
+
+		var from, to time.Weekday
+		if from, err = parseWeekday(bounds[0]); err != nil {
+			return 0, err
+		}
+		to = from
+		if len(bounds) == 2 {
+			if to, err = parseWeekday(bounds[1]); err != nil {
+				return 0, err
+			}
+		}
+
+		// Ranges may wrap around the end of the week, e.g. "Sat..Mon"
+		for d := from; ; d = (d + 1) % 7 {
+			mask |= 1 << uint(d)
+			if d == to {
+				break
+			}
+		}
+	}
+	return mask, nil
+}
+
+// parseDate parses the date part of a calendar event, [YEAR-]MONTH-DAY
+func (spec *CalendarSpec) parseDate(s string) (err error) {
+	parts := strings.Split(s, "-")
+	switch len(parts) {
+	case 2:
+		parts = append([]string{"*"}, parts...)
+	case 3:
+	default:
+		return fmt.Errorf("invalid date: %q", s)
+	}
+
+	if spec.year, err = parseCalendarComponent(parts[0], CALENDAR_MIN_YEAR, CALENDAR_MAX_YEAR); err != nil {
+		return
+	}
+	if spec.month, err = parseCalendarComponent(parts[1], 1, 12); err != nil {
+		return
+	}
+	spec.day, err = parseCalendarComponent(parts[2], 1, 31)
+	return
+}
+
+// parseTime parses the time part of a calendar event, HOUR:MINUTE[:SECOND]
+func (spec *CalendarSpec) parseTime(s string) (err error) {
+	parts := strings.Split(s, ":")
+	switch len(parts) {
+	case 2:
+		parts = append(parts, "00")
+	case 3:
+		// Fractions of seconds are not supported, they are dropped
+		if i := strings.Index(parts[2], "."); i > 0 {
+			parts[2] = parts[2][:i]
+		}
+	default:
+		return fmt.Errorf("invalid time: %q", s)
+	}
+
+	if spec.hour, err = parseCalendarComponent(parts[0], 0, 23); err != nil {
+		return
+	}
+	if spec.minute, err = parseCalendarComponent(parts[1], 0, 59); err != nil {
+		return
+	}
+	spec.second, err = parseCalendarComponent(parts[2], 0, 59)
+	return
+}
+
+// parseCalendarComponent parses a list of values between min and max, see ParseCalendar
+func parseCalendarComponent(s string, min, max int) (c calendarComponent, err error) {
+	if s == "*" {
+		return nil, nil
+	}
+
+	value := func(s string) (v int, err error) {
+		if v, err = strconv.Atoi(s); err != nil || v < min || v > max {
+			return 0, fmt.Errorf("invalid value %q, should be between %d and %d", s, min, max)
+		}
+		return v, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		r := calendarRange{}
+
+		if i := strings.Index(item, "/"); i >= 0 {
+			if r.step, err = strconv.Atoi(item[i+1:]); err != nil || r.step <= 0 {
+				return nil, fmt.Errorf("invalid repetition: %q", item)
+			}
+			item = item[:i]
+		}
+
+		switch bounds := strings.SplitN(item, "..", 2); {
+		case item == "*":
+			r.start, r.end = min, -1
+		case len(bounds) == 2:
+			if r.start, err = value(bounds[0]); err != nil {
+				return
+			}
+			if r.end, err = value(bounds[1]); err != nil {
+				return
+			}
+			if r.end < r.start {
+				return nil, fmt.Errorf("invalid range: %q", item)
+			}
+		default:
+			if r.start, err = value(item); err != nil {
+				return
+			}
+			r.end = r.start
+			if r.step > 0 {
+				r.end = -1
+			}
+		}
+		c = append(c, r)
+	}
+	return c, nil
+}
+
+// matches reports whether v matches c
+func (c calendarComponent) matches(v int) bool {
+	if c == nil {
+		return true
+	}
+	for _, r := range c {
+		if v < r.start || r.end >= 0 && v > r.end {
+			continue
+		}
+		if r.step == 0 || (v-r.start)%r.step == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the first time after t matching spec, false if there is none before CALENDAR_MAX_YEAR ends
+func (spec *CalendarSpec) Next(t time.Time) (next time.Time, ok bool) {
+	loc := spec.location
+	if loc == nil {
+		loc = t.Location()
+	}
+
+	t = t.In(loc).Truncate(time.Second).Add(time.Second)
+	for t.Year() <= CALENDAR_MAX_YEAR {
+		y, mon, d := t.Date()
+		h, min, sec := t.Clock()
+
+		switch {
+		case !spec.year.matches(y):
+			t = time.Date(y+1, 1, 1, 0, 0, 0, 0, loc)
+		case !spec.month.matches(int(mon)):
+			t = time.Date(y, mon+1, 1, 0, 0, 0, 0, loc)
+		case !spec.day.matches(d) || spec.weekdays != 0 && spec.weekdays&(1<<uint(t.Weekday())) == 0:
+			t = time.Date(y, mon, d+1, 0, 0, 0, 0, loc)
+		case !spec.hour.matches(h):
+			t = time.Date(y, mon, d, h+1, 0, 0, 0, loc)
+		case !spec.minute.matches(min):
+			t = time.Date(y, mon, d, h, min+1, 0, 0, loc)
+		case !spec.second.matches(sec):
+			t = t.Add(time.Second)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// String returns the normalized form of spec, e.g. "Mon..Fri *-*-* 09:00:00"
+func (spec *CalendarSpec) String() string {
+	var parts []string
+
+	if spec.weekdays != 0 {
+		parts = append(parts, formatWeekdays(spec.weekdays))
+	}
+	parts = append(parts,
+		spec.year.format(4)+"-"+spec.month.format(2)+"-"+spec.day.format(2),
+		spec.hour.format(2)+":"+spec.minute.format(2)+":"+spec.second.format(2),
+	)
+	if spec.location != nil {
+		parts = append(parts, spec.location.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// format formats c with values padded to width digits
+func (c calendarComponent) format(width int) string {
+	if c == nil {
+		return "*"
+	}
+
+	items := make([]string, len(c))
+	for i, r := range c {
+		item := fmt.Sprintf("%0*d", width, r.start)
+		if r.end > r.start {
+			item += fmt.Sprintf("..%0*d", width, r.end)
+		}
+		if r.step > 0 {
+			item += "/" + strconv.Itoa(r.step)
+		}
+		items[i] = item
+	}
+	return strings.Join(items, ",")
+}
+
+// formatWeekdays formats mask as a list of weekdays, consecutive ones as ranges, starting on Monday
+func formatWeekdays(mask uint8) string {
+	var items []string
+	for i := 0; i < 7; {
+		// Monday first, Sunday last
+		d := (i + 1) % 7
+		if mask&(1<<uint(d)) == 0 {
+			i++
+			continue
+		}
+
+		j := i
+		for j+1 < 7 && mask&(1<<uint((j+2)%7)) != 0 {
+			j++
+		}
+
+		switch {
+		case j == i:
+			items = append(items, weekdayNames[d].short)
+		case j == i+1:
+			items = append(items, weekdayNames[d].short, weekdayNames[(j+1)%7].short)
+		default:
+			items = append(items, weekdayNames[d].short+".."+weekdayNames[(j+1)%7].short)
+		}
+		i = j + 1
+	}
+	return strings.Join(items, ",")
+}
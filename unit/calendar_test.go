@@ -0,0 +1,68 @@
+package unit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"systemgo/unit"
+)
+
+func TestParseCalendar(t *testing.T) {
+	for s, expected := range map[string]string{
+		"daily":                      "*-*-* 00:00:00",
+		"weekly":                     "Mon *-*-* 00:00:00",
+		"hourly":                     "*-*-* *:00:00",
+		"quarterly":                  "*-01,04,07,10-01 00:00:00",
+		"Mon..Fri 09:00":             "Mon..Fri *-*-* 09:00:00",
+		"Sat,Sun *-*-* 10:30:15":     "Sat,Sun *-*-* 10:30:15",
+		"Fri..Mon":                   "Mon,Fri..Sun *-*-* 00:00:00",
+		"2026-10-16":                 "2026-10-16 00:00:00",
+		"12-24 18:00":                "*-12-24 18:00:00",
+		"*-*-1..7 *:0/15":            "*-*-01..07 *:00/15:00",
+		"*-*-* 06:00:00 UTC":         "*-*-* 06:00:00 UTC",
+		"wednesday *-*-* 12:00:00.5": "Wed *-*-* 12:00:00",
+	} {
+		spec, err := unit.ParseCalendar(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, spec.String(), s)
+		}
+	}
+
+	for _, s := range []string{"", "foo", "*-13-01", "25:00", "*-*-* 00:00:00 Mars/Olympus", "Mon..Foo", "*-*-5..1", "*:0/0"} {
+		_, err := unit.ParseCalendar(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCalendarNext(t *testing.T) {
+	after := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC) // Friday
+
+	for s, expected := range map[string]time.Time{
+		"daily":               time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		"hourly":              time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC),
+		"weekly":              time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		"monthly":             time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		"yearly":              time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		"Mon..Fri 12:30":      time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC),
+		"*-*-* *:0/20":        time.Date(2026, 10, 16, 12, 40, 0, 0, time.UTC),
+		"*-02-29 00:00:00":    time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		"*-*-31 06:00":        time.Date(2026, 10, 31, 6, 0, 0, 0, time.UTC),
+		"Sat *-*-* 08:00 UTC": time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+	} {
+		spec, err := unit.ParseCalendar(s)
+		if !assert.NoError(t, err, s) {
+			continue
+		}
+		next, ok := spec.Next(after)
+		if assert.True(t, ok, s) {
+			assert.True(t, expected.Equal(next), "%s: expected %s, got %s", s, expected, next)
+		}
+	}
+
+	spec, err := unit.ParseCalendar("2020-01-01")
+	if assert.NoError(t, err) {
+		_, ok := spec.Next(after)
+		assert.False(t, ok, "event in the past never elapses")
+	}
+}
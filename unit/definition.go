@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/coreos/go-systemd/unit"
 )
@@ -16,9 +17,15 @@ type Definition struct {
 		Description                               string
 		Documentation                             string
 		Wants, Requires, Conflicts, Before, After []string
+		IgnoreOnIsolate                           bool
+
+		FailureAction, SuccessAction, StartLimitAction string
+		StartLimitIntervalSec                          *time.Duration
+		StartLimitBurst                                *int
 	}
 	Install struct {
 		WantedBy, RequiredBy []string
+		Alias, Also          []string
 	}
 }
 
@@ -57,6 +64,42 @@ func (def Definition) Before() []string {
 	return def.Unit.Before
 }
 
+// IgnoreOnIsolate returns a bool as found in Definition
+func (def Definition) IgnoreOnIsolate() bool {
+	return def.Unit.IgnoreOnIsolate
+}
+
+// FailureAction returns a string as found in Definition
+func (def Definition) FailureAction() string {
+	return def.Unit.FailureAction
+}
+
+// SuccessAction returns a string as found in Definition
+func (def Definition) SuccessAction() string {
+	return def.Unit.SuccessAction
+}
+
+// StartLimitAction returns a string as found in Definition
+func (def Definition) StartLimitAction() string {
+	return def.Unit.StartLimitAction
+}
+
+// StartLimitIntervalSec returns a time.Duration as found in Definition and whether it is specified
+func (def Definition) StartLimitIntervalSec() (interval time.Duration, ok bool) {
+	if def.Unit.StartLimitIntervalSec == nil {
+		return 0, false
+	}
+	return *def.Unit.StartLimitIntervalSec, true
+}
+
+// StartLimitBurst returns an int as found in Definition and whether it is specified
+func (def Definition) StartLimitBurst() (burst int, ok bool) {
+	if def.Unit.StartLimitBurst == nil {
+		return 0, false
+	}
+	return *def.Unit.StartLimitBurst, true
+}
+
 // RequiredBy returns a slice of unit names as found in Definition
 func (def Definition) RequiredBy() []string {
 	return def.Install.RequiredBy
@@ -67,6 +110,16 @@ func (def Definition) WantedBy() []string {
 	return def.Install.WantedBy
 }
 
+// Alias returns a slice of unit names as found in Definition
+func (def Definition) Alias() []string {
+	return def.Install.Alias
+}
+
+// Also returns a slice of unit names as found in Definition
+func (def Definition) Also() []string {
+	return def.Install.Also
+}
+
 // ParseDefinition parses the data in Systemd unit-file format and stores the result in value pointed by Definition
 func ParseDefinition(r io.Reader, v interface{}) (err error) {
 	// Access the underlying value of the pointer
@@ -86,37 +139,8 @@ func ParseDefinition(r io.Reader, v interface{}) (err error) {
 	for _, opt := range opts {
 		if v := def.FieldByName(opt.Section); v.IsValid() && v.CanSet() {
 			if v := v.FieldByName(opt.Name); v.IsValid() && v.CanSet() {
-				// reflect.Kind of field in Definition
-				switch v.Kind() {
-
-				case reflect.String:
-					v.SetString(opt.Value)
-
-				case reflect.Bool:
-					if opt.Value == "yes" {
-						v.SetBool(true)
-					} else if opt.Value != "no" {
-						return ParseErr(opt.Name, errors.New(`Value should be "yes" or "no"`))
-					}
-
-				case reflect.Slice:
-					if _, ok := v.Interface().([]string); ok { // []string
-						v.Set(reflect.ValueOf(strings.Fields(opt.Value)))
-
-					} else if _, ok := v.Interface().([]int); ok { // []int
-						ints := []int{}
-						for _, val := range strings.Fields(opt.Value) {
-							if converted, err := strconv.Atoi(val); err == nil {
-								ints = append(ints, converted)
-							} else {
-								return ParseErr(opt.Name, err)
-							}
-						}
-						v.Set(reflect.ValueOf(ints))
-					}
-
-				default:
-					return ParseErr(opt.Name, ErrUnknownType)
+				if err = setValue(v, opt.Value); err != nil {
+					return ParseErr(opt.Name, err)
 				}
 			} else {
 				return ParseErr(opt.Name, ErrNotExist)
@@ -127,3 +151,156 @@ func ParseDefinition(r io.Reader, v interface{}) (err error) {
 	}
 	return
 }
+
+// Options returns the values of the options of the definition v points to(or embeds) by option name,
+// formatted the way they are parsed by ParseDefinition. Pointer options, which are not set, are omitted
+func Options(v interface{}) (opts map[string]string) {
+	opts = map[string]string{}
+	collectOptions(reflect.Indirect(reflect.ValueOf(v)), opts)
+	return
+}
+
+// collectOptions stores the options of sections found in struct v or structs embedded in it in opts
+func collectOptions(v reflect.Value, opts map[string]string) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+
+		if field.Anonymous {
+			collectOptions(v.Field(i), opts)
+			continue
+		}
+
+		section := v.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			if section.Type().Field(j).PkgPath != "" {
+				continue
+			}
+			if value, ok := formatValue(section.Field(j)); ok {
+				opts[section.Type().Field(j).Name] = value
+			}
+		}
+	}
+}
+
+// formatValue formats the value of v the way it is parsed by setValue.
+// ok is false for nil pointers and values of unsupported types
+func formatValue(v reflect.Value) (value string, ok bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "", false
+		}
+		return formatValue(v.Elem())
+
+	case reflect.String:
+		return v.String(), true
+
+	case reflect.Bool:
+		if v.Bool() {
+			return "yes", true
+		}
+		return "no", true
+
+	case reflect.Int, reflect.Int64:
+		if v.Type() == durationType {
+			return FormatTimespan(time.Duration(v.Int())), true
+		}
+		return strconv.FormatInt(v.Int(), 10), true
+
+	case reflect.Slice:
+		values := make([]string, v.Len())
+		for i := range values {
+			if values[i], ok = formatValue(v.Index(i)); !ok {
+				return "", false
+			}
+		}
+		return strings.Join(values, " "), true
+	}
+	return "", false
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	linesType    = reflect.TypeOf(Lines(nil))
+)
+
+// Lines is a list option, each assignment of which is a single value, which may contain spaces
+type Lines []string
+
+// setValue parses value according to the type of v and stores the result in v.
+// Pointer fields are left nil, unless the option is specified, which allows to tell unset options apart
+func setValue(v reflect.Value, value string) (err error) {
+	// reflect.Kind of field in Definition
+	switch v.Kind() {
+
+	case reflect.Ptr:
+		ptr := reflect.New(v.Type().Elem())
+		if err = setValue(ptr.Elem(), value); err != nil {
+			return
+		}
+		v.Set(ptr)
+
+	case reflect.String:
+		v.SetString(value)
+
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "yes", "y", "true", "t", "on", "1":
+			v.SetBool(true)
+		case "no", "n", "false", "f", "off", "0":
+			v.SetBool(false)
+		default:
+			return errors.New(`Value should be "yes" or "no"`)
+		}
+
+	case reflect.Int, reflect.Int64:
+		if v.Type() == durationType {
+			var d time.Duration
+			if d, err = ParseTimespan(value); err != nil {
+				return
+			}
+			v.SetInt(int64(d))
+			return
+		}
+
+		var i int64
+		if i, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return
+		}
+		v.SetInt(i)
+
+	case reflect.Slice:
+		// Values of list options accumulate, an empty assignment resets the list
+		if value == "" {
+			v.Set(reflect.Zero(v.Type()))
+		} else if v.Type() == linesType { // Lines
+			v.Set(reflect.Append(v, reflect.ValueOf(value)))
+
+		} else if _, ok := v.Interface().([]string); ok { // []string
+			v.Set(reflect.AppendSlice(v, reflect.ValueOf(strings.Fields(value))))
+
+		} else if _, ok := v.Interface().([]int); ok { // []int
+			ints := []int{}
+			for _, val := range strings.Fields(value) {
+				if converted, err := strconv.Atoi(val); err == nil {
+					ints = append(ints, converted)
+				} else {
+					return err
+				}
+			}
+			v.Set(reflect.AppendSlice(v, reflect.ValueOf(ints)))
+		}
+
+	default:
+		return ErrUnknownType
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"systemgo/unit"
@@ -22,10 +23,16 @@ Requires=Requires
 Conflicts=Conflicts
 Before=Before
 After=After
+IgnoreOnIsolate=yes
+FailureAction=FailureAction
+SuccessAction=SuccessAction
+StartLimitAction=StartLimitAction
 
 [Install]
 WantedBy=WantedBy
-RequiredBy=RequiredBy`
+RequiredBy=RequiredBy
+Alias=Alias
+Also=Also`
 
 func TestParseDefinition(t *testing.T) {
 	cases := []struct {
@@ -149,3 +156,72 @@ func interfaceOf(val reflect.Value) interface{} {
 func methodByName(val reflect.Value, name string) interface{} {
 	return interfaceOf(val.MethodByName(name))
 }
+
+func TestParsePointers(t *testing.T) {
+	def := unit.Definition{}
+	assert.NoError(t, unit.ParseDefinition(strings.NewReader(`[Unit]
+StartLimitBurst=3`), &def))
+
+	burst, ok := def.StartLimitBurst()
+	assert.True(t, ok)
+	assert.Equal(t, 3, burst)
+
+	_, ok = def.StartLimitIntervalSec()
+	assert.False(t, ok)
+
+	def = unit.Definition{}
+	assert.NoError(t, unit.ParseDefinition(strings.NewReader(`[Unit]
+StartLimitIntervalSec=1min 30s`), &def))
+
+	interval, ok := def.StartLimitIntervalSec()
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, interval)
+
+	assert.Error(t, unit.ParseDefinition(strings.NewReader(`[Unit]
+StartLimitBurst=many`), &unit.Definition{}))
+}
+
+func TestOptions(t *testing.T) {
+	def := struct {
+		unit.Definition
+		Service struct {
+			Type            string
+			RemainAfterExit bool
+			TimeoutStartSec *time.Duration
+			TimeoutStopSec  *time.Duration
+			Ints            []int
+		}
+	}{}
+
+	assert.NoError(t, unit.ParseDefinition(strings.NewReader(`[Unit]
+Description=Foo
+Wants=a.service b.service
+StartLimitBurst=3
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+TimeoutStartSec=90
+Ints=1 2`), &def))
+
+	opts := unit.Options(&def)
+	for name, expected := range map[string]string{
+		"Description":     "Foo",
+		"Wants":           "a.service b.service",
+		"Requires":        "",
+		"IgnoreOnIsolate": "no",
+		"StartLimitBurst": "3",
+		"Type":            "oneshot",
+		"RemainAfterExit": "yes",
+		"TimeoutStartSec": "1min 30s",
+		"Ints":            "1 2",
+		"WantedBy":        "",
+	} {
+		assert.Equal(t, expected, opts[name], name)
+	}
+
+	for _, name := range []string{"TimeoutStopSec", "StartLimitIntervalSec"} {
+		_, ok := opts[name]
+		assert.False(t, ok, name)
+	}
+}
@@ -30,6 +30,10 @@ func (err ParseError) Error() string {
 	return fmt.Sprintf("%s: %s", err.Source, err.Err)
 }
 
+func (err ParseError) Unwrap() error {
+	return err.Err
+}
+
 type MultiError []error
 
 func (m MultiError) Errors() (errs []string) {
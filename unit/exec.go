@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Executor spawns processes of units. Alternative executors may run them e.g. in containers,
+// on remote hosts or as goroutines in tests
+type Executor interface {
+	// Start starts the process described by Path, Args, Dir, Env, standard streams and ExtraFiles of cmd
+	Start(cmd *exec.Cmd) (Process, error)
+}
+
+// Process is a process spawned by an Executor
+type Process interface {
+	Pid() int
+	Signal(sig os.Signal) error
+
+	// Wait waits for the process to exit and returns a non-nil error, if it did not exit successfully.
+	// Wait must only be called once
+	Wait() error
+}
+
+// OSExecutor spawns processes of the operating system using os/exec, it is used, unless set otherwise
+var OSExecutor Executor = osExecutor{}
+
+type osExecutor struct{}
+
+func (osExecutor) Start(cmd *exec.Cmd) (Process, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &osProcess{Process: cmd.Process, cmd: cmd}, nil
+}
+
+// FindProcess returns the Process of the operating system with pid, which must be
+// a child of the calling process to be waited for
+func FindProcess(pid int) (Process, error) {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	return &osProcess{Process: p}, nil
+}
+
+type osProcess struct {
+	*os.Process
+
+	// Command p was started with, nil if p was found by pid
+	cmd *exec.Cmd
+}
+
+func (p *osProcess) Pid() int {
+	return p.Process.Pid
+}
+
+func (p *osProcess) Wait() (err error) {
+	if p.cmd != nil {
+		return p.cmd.Wait()
+	}
+
+	var ps *os.ProcessState
+	if ps, err = p.Process.Wait(); err == nil && !ps.Success() {
+		err = &exec.ExitError{ProcessState: ps}
+	}
+	return
+}
@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Defaults of the health check options
+const (
+	DEFAULT_HEALTH_CHECK_INTERVAL = 30 * time.Second
+	DEFAULT_HEALTH_CHECK_TIMEOUT  = 5 * time.Second
+	DEFAULT_HEALTH_CHECK_RETRIES  = 3
+)
+
+// HealthCheck is a probe checking the health of an active unit periodically
+type HealthCheck struct {
+	// Command run, address connected to over TCP or URL requested over HTTP, exactly one is set
+	Exec, TCP, HTTP string
+
+	// Time between two probes and the time a probe may take
+	Interval, Timeout time.Duration
+
+	// Number of consecutive failed probes, after which the unit is unhealthy
+	Retries int
+}
+
+// Probe probes the health once and returns an error, if the probe failed: the command exited with
+// a non-zero status, the connection was refused or the response status was not 2xx or 3xx.
+// A probe failing to finish within Timeout fails
+func (hc HealthCheck) Probe() (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hc.Timeout)
+	defer cancel()
+
+	switch {
+	case hc.Exec != "":
+		cmd := strings.Fields(hc.Exec)
+		return exec.CommandContext(ctx, cmd[0], cmd[1:]...).Run()
+
+	case hc.TCP != "":
+		var conn net.Conn
+		if conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", hc.TCP); err != nil {
+			return
+		}
+		return conn.Close()
+
+	case hc.HTTP != "":
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, hc.HTTP, nil); err != nil {
+			return
+		}
+
+		var resp *http.Response
+		if resp, err = http.DefaultClient.Do(req); err != nil {
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("HTTP status %s", resp.Status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package unit_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+
+	for _, c := range []struct {
+		hc unit.HealthCheck
+		ok bool
+	}{
+		{unit.HealthCheck{Exec: "/bin/true"}, true},
+		{unit.HealthCheck{Exec: "/bin/false"}, false},
+		{unit.HealthCheck{Exec: "/bin/sleep 1"}, false},
+		{unit.HealthCheck{TCP: addr}, true},
+		{unit.HealthCheck{HTTP: srv.URL + "/health"}, true},
+		{unit.HealthCheck{HTTP: srv.URL + "/missing"}, false},
+	} {
+		c.hc.Timeout = 100 * time.Millisecond
+		if c.ok {
+			assert.NoError(t, c.hc.Probe(), "%+v", c.hc)
+		} else {
+			assert.Error(t, c.hc.Probe(), "%+v", c.hc)
+		}
+	}
+
+	ln.Close()
+	assert.Error(t, unit.HealthCheck{TCP: addr, Timeout: time.Second}.Probe(), "connection is refused")
+}
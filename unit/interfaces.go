@@ -1,6 +1,10 @@
 package unit
 
-import "io"
+import (
+	"io"
+	"os"
+	"time"
+)
 
 type Interface interface {
 	Definer
@@ -38,11 +42,192 @@ type Stopper interface {
 	Stop() error
 }
 
+// Killer is implemented by any value running processes, which can be killed
+type Killer interface {
+	// Kill kills the processes still running and waits for them to exit
+	Kill() error
+}
+
 // Reloader is implemented by any value capable of reloading itself(or its definition)
 type Reloader interface {
 	Reload() error
 }
 
+// IsolateIgnorer is implemented by any value, which may be left running on isolation
+type IsolateIgnorer interface {
+	IgnoreOnIsolate() bool
+}
+
+// Installer is implemented by any value, which has aliases or units to be enabled along with it
+type Installer interface {
+	Alias() []string
+	Also() []string
+}
+
+// Serializer is implemented by any value, the runtime state of which can be saved
+// and restored, e.g. across re-executions of the daemon
+type Serializer interface {
+	Serialize() ([]byte, error)
+	Deserialize([]byte) error
+}
+
+// MainPIDer is implemented by any value running a main process
+type MainPIDer interface {
+	// MainPID returns the PID of the main process, 0 if it is not running
+	MainPID() int
+}
+
+// FailedResetter is implemented by any value, which can leave the failed state without being started again
+type FailedResetter interface {
+	// ResetFailed puts the value into the inactive state, if it has failed
+	ResetFailed()
+}
+
+// ResourceController is implemented by any value, the processes of which are subject to resource control
+type ResourceController interface {
+	// Resources returns the resource control properties specified, e.g. MemoryMax=, by name
+	Resources() map[string]string
+}
+
+// Accounter is implemented by any value, the resource usage of the processes of which may be accounted
+type Accounter interface {
+	// Accounting returns the resource accounting options specified, e.g. MemoryAccounting=, by name
+	Accounting() map[string]bool
+}
+
+// HealthChecker is implemented by any value, the health of which may be probed periodically, while it is active
+type HealthChecker interface {
+	// HealthCheck returns the health check specified and whether one is specified
+	HealthCheck() (hc HealthCheck, ok bool)
+}
+
+// Restarter is implemented by any value, which may be restarted automatically
+type Restarter interface {
+	// RestartPolicy returns the value of Restart=, empty means "no"
+	RestartPolicy() string
+}
+
+// Syslogger is implemented by any value specifying, how its log entries are forwarded to syslog.
+// Empty values are not specified
+type Syslogger interface {
+	SyslogIdentifier() string
+	SyslogFacility() string
+}
+
+// LogLeveler is implemented by any value specifying the priorities of its log entries kept,
+// as names or numbers of syslog priorities, e.g. "info" or "6". Empty values are not specified
+type LogLeveler interface {
+	// LogLevelMax returns the lowest priority of entries kept, entries of lower priorities are discarded
+	LogLevelMax() string
+
+	// LogLevel returns the lowest priority of messages logged by the manager about the value
+	LogLevel() string
+}
+
+// PropertySetter is implemented by any value, properties of which can be changed at runtime
+type PropertySetter interface {
+	// SetProperty sets the property key to value, as if it was found in the definition
+	SetProperty(key, value string) error
+}
+
+// EnvironmentSetter is implemented by any value spawning processes, the environment of which can be set
+type EnvironmentSetter interface {
+	SetEnvironment(env []string)
+}
+
+// Listen is a socket a Listener listens on
+type Listen struct {
+	// Type of the socket, the option it is specified by without the "Listen" prefix, e.g. "Stream"
+	Type string
+
+	// Address as specified, e.g. a path, a port or an IP address with a port
+	Address string
+}
+
+// Listener is implemented by any value holding listening sockets, incoming traffic on which activates a service
+type Listener interface {
+	// Files returns the files of the listening sockets, nil if not listening
+	Files() []*os.File
+
+	// Listens returns the sockets listened on in the order of Files
+	Listens() []Listen
+
+	// Service returns the name of the service activated, empty means the service named as the value
+	Service() string
+
+	// FileDescriptorName returns the name of the files passed to the service, empty means the name of the value
+	FileDescriptorName() string
+}
+
+// Acceptor is implemented by any Listener, which may spawn an instance of the service per connection accepted
+type Acceptor interface {
+	Listener
+
+	// Accept returns whether an instance of the service is spawned per connection accepted,
+	// the service named is the template of the instances then
+	Accept() bool
+
+	// MaxConnections returns the maximum number of connections accepted concurrently
+	MaxConnections() int
+}
+
+// IdleStopper is implemented by any Listener, which stops the service activated, once it is idle.
+// The service is activated again by the next incoming traffic
+type IdleStopper interface {
+	Listener
+
+	// TimeoutIdleSec returns the time the service is stopped after, once idle, 0 means never
+	TimeoutIdleSec() time.Duration
+}
+
+// ConnectionSetter is implemented by any value spawning processes, which can be passed
+// a connection accepted as standard input and output
+type ConnectionSetter interface {
+	// SetConnection sets the file of the connection passed to the next process spawned
+	SetConnection(f *os.File)
+}
+
+// ListenFilesSetter is implemented by any value spawning processes, which can be passed listening sockets
+// following the sd_listen_fds(3) protocol
+type ListenFilesSetter interface {
+	// SetListenFiles sets the files passed and their names, names[i] is the name of files[i]
+	SetListenFiles(files []*os.File, names []string)
+}
+
+// StateNotifier is implemented by any value, the activation state of which changes on its own,
+// e.g. when its main process exits
+type StateNotifier interface {
+	// SetStateNotify sets the function called with the reason, whenever the activation state changes on its own
+	SetStateNotify(fn func(reason string))
+}
+
+// ExecutorSetter is implemented by any value spawning processes, the Executor of which can be set
+type ExecutorSetter interface {
+	SetExecutor(e Executor)
+}
+
+// ActionSpecifier is implemented by any value specifying actions taken by the manager,
+// when it fails, succeeds or hits the start rate limit
+type ActionSpecifier interface {
+	FailureAction() string
+	SuccessAction() string
+	StartLimitAction() string
+}
+
+// StartLimiter is implemented by any value specifying a start rate limit.
+// ok is false for the values, which are not specified
+type StartLimiter interface {
+	StartLimitIntervalSec() (interval time.Duration, ok bool)
+	StartLimitBurst() (burst int, ok bool)
+}
+
+// Timeouter is implemented by any value specifying timeouts of its start and stop operations.
+// ok is false for the values, which are not specified
+type Timeouter interface {
+	TimeoutStartSec() (timeout time.Duration, ok bool)
+	TimeoutStopSec() (timeout time.Duration, ok bool)
+}
+
 type Dependency interface {
 	Wants() []string
 	Requires() []string
@@ -55,3 +240,42 @@ type Dependency interface {
 	After() []string
 	Before() []string
 }
+
+// TimerBase holds the points in time a Timer computes the next activation relative to
+type TimerBase struct {
+	// Activated is the time the timer was started at
+	Activated time.Time
+
+	// Boot is the time the system was booted at, Startup is the time the daemon was started at
+	Boot, Startup time.Time
+
+	// LastTrigger is the time the unit was last activated at since the timer was started, zero if never
+	LastTrigger time.Time
+
+	// Stamp is the time the unit was last activated at as stored on disk, zero if unknown
+	Stamp time.Time
+}
+
+// Timer is implemented by any value activating a unit at points in time
+type Timer interface {
+	// Unit returns the name of the unit activated, empty means the service named as the value
+	Unit() string
+
+	// Persistent returns whether the time of the last activation is stored on disk,
+	// so that activations missed while the system was down happen, once it is up
+	Persistent() bool
+
+	// NextElapse returns the next point in time the unit is activated at relative to base, which may
+	// be in the past, if an activation is due. ok is false, if the unit is never activated again
+	NextElapse(base TimerBase) (next time.Time, ok bool)
+
+	// SetElapsed sets whether the unit is never activated again
+	SetElapsed(elapsed bool)
+
+	// AccuracySec returns the time the activation may be delayed by to coalesce it with other timers
+	// and whether it is specified
+	AccuracySec() (accuracy time.Duration, ok bool)
+
+	// RandomizedDelaySec returns the maximum random time the activation is delayed by, 0 means none
+	RandomizedDelaySec() time.Duration
+}
@@ -0,0 +1,189 @@
+// Package mount defines a mount unit type
+package mount
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Commands used to mount and unmount file systems
+var (
+	MOUNT_CMD  = "mount"
+	UMOUNT_CMD = "umount"
+)
+
+// Path to the mount table of the calling process
+const MOUNTINFO = "/proc/self/mountinfo"
+
+// Mount unit
+type Unit struct {
+	Definition
+
+	sub   Sub
+	mutex sync.Mutex
+}
+
+// Mount unit definition
+type Definition struct {
+	unit.Definition
+	Mount struct {
+		What, Where, Type, Options string
+	}
+}
+
+// Define attempts to fill the m definition by parsing r
+func (m *Unit) Define(r io.Reader) (err error) {
+	log.WithField("r", r).Debugf("m.Define")
+
+	def := Definition{}
+	if err = unit.ParseDefinition(r, &def); err != nil {
+		return
+	}
+
+	merr := unit.MultiError{}
+	if def.Mount.What == "" {
+		merr = append(merr, unit.ParseErr("What", unit.ErrNotSet))
+	}
+	switch {
+	case def.Mount.Where == "":
+		merr = append(merr, unit.ParseErr("Where", unit.ErrNotSet))
+	case !filepath.IsAbs(def.Mount.Where):
+		merr = append(merr, unit.ParseErr("Where", unit.ParseErr(def.Mount.Where, unit.ErrNotSupported)))
+	}
+	if len(merr) > 0 {
+		return merr
+	}
+
+	m.Definition = def
+	return nil
+}
+
+// Args returns the arguments passed to MOUNT_CMD to mount the file system
+func (m *Unit) Args() (args []string) {
+	if m.Mount.Type != "" {
+		args = append(args, "-t", m.Mount.Type)
+	}
+	if m.Mount.Options != "" {
+		args = append(args, "-o", m.Mount.Options)
+	}
+	return append(args, m.Mount.What, m.Mount.Where)
+}
+
+// Start mounts the file system, unless it is mounted already
+func (m *Unit) Start() (err error) {
+	e := log.WithField("where", m.Mount.Where)
+	e.Debug("m.Start")
+
+	if IsMounted(m.Mount.Where) {
+		m.setSub(Mounted)
+		return nil
+	}
+
+	m.setSub(Mounting)
+	if out, err := exec.Command(MOUNT_CMD, m.Args()...).CombinedOutput(); err != nil {
+		e.WithField("out", string(out)).Debug("mount failed")
+		m.setSub(Failed)
+		return err
+	}
+	m.setSub(Mounted)
+	return nil
+}
+
+// Stop unmounts the file system. The root file system never gets unmounted
+func (m *Unit) Stop() (err error) {
+	log.WithField("where", m.Mount.Where).Debug("m.Stop")
+
+	if filepath.Clean(m.Mount.Where) == "/" || !IsMounted(m.Mount.Where) {
+		m.setSub(Dead)
+		return nil
+	}
+
+	m.setSub(Unmounting)
+	if err = exec.Command(UMOUNT_CMD, m.Mount.Where).Run(); err != nil {
+		m.setSub(Failed)
+		return
+	}
+	m.setSub(Dead)
+	return nil
+}
+
+func (m *Unit) setSub(sub Sub) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sub = sub
+}
+
+// ResetFailed puts a mount, which has failed, into the dead state
+func (m *Unit) ResetFailed() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.sub == Failed {
+		m.sub = Dead
+	}
+}
+
+// Sub reports the sub status of a mount
+func (m *Unit) Sub() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return strings.ToLower(m.sub.String())
+}
+
+// Active reports activation status of a mount
+func (m *Unit) Active() unit.Activation {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	switch m.sub {
+	case Dead:
+		return unit.Inactive
+	case Mounted:
+		return unit.Active
+	case Failed:
+		return unit.Failed
+	case Mounting, MountingDone:
+		return unit.Activating
+	case Remounting:
+		return unit.Reloading
+	default:
+		return unit.Deactivating
+	}
+}
+
+// IsMounted returns whether path is a mount point as found in MOUNTINFO
+func IsMounted(path string) bool {
+	f, err := os.Open(MOUNTINFO)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	path = filepath.Clean(path)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Mount point is the 5th field, octal-escaped
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 5 && Unescape(fields[4]) == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Unescape replaces octal escapes of whitespace and backslashes found in mount tables
+func Unescape(s string) string {
+	return strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(s)
+}
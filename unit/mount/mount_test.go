@@ -0,0 +1,41 @@
+package mount
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"systemgo/unit"
+)
+
+func TestDefine(t *testing.T) {
+	m := Unit{}
+	assert.NoError(t, m.Define(strings.NewReader(`[Mount]
+What=/dev/sda1
+Where=/data
+Type=ext4
+Options=noatime`)), "m.Define")
+	assert.Equal(t, []string{"-t", "ext4", "-o", "noatime", "/dev/sda1", "/data"}, m.Args())
+
+	var err error
+
+	m = Unit{}
+	if err = m.Define(strings.NewReader(`[Mount]
+What=/dev/sda1
+Where=data`)); assert.Error(t, err, "m.Define with relative Where") {
+		if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") {
+			if pe, ok := me[0].(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+				assert.Equal(t, "Where", pe.Source)
+			}
+		}
+	}
+}
+
+func TestIsMounted(t *testing.T) {
+	assert.True(t, IsMounted("/"), "/")
+	assert.False(t, IsMounted("/nonexistent/mount/point"))
+}
+
+func TestUnescape(t *testing.T) {
+	assert.Equal(t, "/mnt/with space", Unescape(`/mnt/with\040space`))
+}
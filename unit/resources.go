@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period of CPUQuota= in microseconds
+const CPU_PERIOD = 100000
+
+// Multipliers of the size suffixes recognized in MemoryMax=
+var byteSuffixes = map[byte]uint64{
+	'K': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+	'T': 1 << 40,
+	'P': 1 << 50,
+	'E': 1 << 60,
+}
+
+// AccountingControllers are the cgroup v2 controllers accounting the resource usage by resource accounting option
+var AccountingControllers = map[string]string{
+	"CPUAccounting":    "cpu",
+	"MemoryAccounting": "memory",
+	"IOAccounting":     "io",
+	"TasksAccounting":  "pids",
+}
+
+// ResourceUsage is the resource usage of the processes of a unit as accounted in its cgroup.
+// Usage, which is not accounted, is zero
+type ResourceUsage struct {
+	// CPU time consumed
+	CPU time.Duration `json:"CPU,omitempty"`
+
+	// Current and peak memory usage in bytes
+	Memory     uint64 `json:"Memory,omitempty"`
+	MemoryPeak uint64 `json:"MemoryPeak,omitempty"`
+
+	// Bytes read from and written to block devices
+	IORead  uint64 `json:"IORead,omitempty"`
+	IOWrite uint64 `json:"IOWrite,omitempty"`
+
+	// Number of tasks
+	Tasks uint64 `json:"Tasks,omitempty"`
+}
+
+// String returns the lines of the usage accounted, each preceded by a newline
+func (u ResourceUsage) String() (out string) {
+	if u.Tasks > 0 {
+		out += fmt.Sprintf("\nTasks: %d", u.Tasks)
+	}
+	if u.Memory > 0 {
+		out += "\nMemory: " + FormatBytes(u.Memory)
+		if u.MemoryPeak > 0 {
+			out += fmt.Sprintf(" (peak: %s)", FormatBytes(u.MemoryPeak))
+		}
+	}
+	if u.CPU > 0 {
+		out += "\nCPU: " + FormatTimespan(u.CPU.Truncate(time.Millisecond))
+	}
+	if u.IORead > 0 || u.IOWrite > 0 {
+		out += fmt.Sprintf("\nIO: %s read, %s written", FormatBytes(u.IORead), FormatBytes(u.IOWrite))
+	}
+	return
+}
+
+// CgroupAttribute returns the name of the cgroup v2 file and the contents it is written with to apply
+// the resource control property key set to value. Supported are MemoryMax=, CPUQuota= and TasksMax=,
+// an empty value or "infinity" removes the limit
+func CgroupAttribute(key, value string) (file, contents string, err error) {
+	value = strings.TrimSpace(value)
+	unlimited := value == "" || value == "infinity"
+
+	switch key {
+	case "MemoryMax":
+		file, contents = "memory.max", "max"
+		if !unlimited {
+			var n uint64
+			n, err = parseBytes(value)
+			contents = strconv.FormatUint(n, 10)
+		}
+
+	case "TasksMax":
+		file, contents = "pids.max", "max"
+		if !unlimited {
+			var n uint64
+			n, err = strconv.ParseUint(value, 10, 64)
+			contents = strconv.FormatUint(n, 10)
+		}
+
+	case "CPUQuota":
+		file, contents = "cpu.max", fmt.Sprintf("max %d", CPU_PERIOD)
+		if !unlimited {
+			var pct float64
+			if pct, err = parsePercentage(value); err == nil {
+				contents = fmt.Sprintf("%d %d", int64(pct*CPU_PERIOD/100), CPU_PERIOD)
+			}
+		}
+
+	default:
+		return "", "", ParseErr(key, ErrNotSupported)
+	}
+
+	if err != nil {
+		return "", "", ParseErr(key, ParseErr(value, ErrWrongVal))
+	}
+	return
+}
+
+// parseBytes parses a size in bytes with an optional suffix, e.g. "512M" or "1G"
+func parseBytes(s string) (n uint64, err error) {
+	mul := uint64(1)
+	if m, ok := byteSuffixes[s[len(s)-1]]; ok {
+		s, mul = s[:len(s)-1], m
+	}
+
+	if n, err = strconv.ParseUint(s, 10, 64); err != nil {
+		return 0, err
+	}
+	if n > (1<<64-1)/mul {
+		return 0, errors.New("size out of range")
+	}
+	return n * mul, nil
+}
+
+// FormatBytes formats n bytes with the largest size suffix recognized in MemoryMax=, which keeps the value
+// at least 1, with a single decimal, e.g. "1.5M"
+func FormatBytes(n uint64) string {
+	suffix, mul := byte(0), uint64(1)
+	for s, m := range byteSuffixes {
+		if n >= m && m > mul {
+			suffix, mul = s, m
+		}
+	}
+	if suffix == 0 {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(float64(n)/float64(mul), 'f', 1, 64), ".0") + string(suffix)
+}
+
+// parsePercentage parses a positive percentage, e.g. "20%" or "150%"
+func parsePercentage(s string) (pct float64, err error) {
+	if !strings.HasSuffix(s, "%") {
+		return 0, errors.New("not a percentage")
+	}
+
+	if pct, err = strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64); err == nil && pct <= 0 {
+		err = errors.New("percentage must be positive")
+	}
+	return
+}
@@ -0,0 +1,42 @@
+package unit_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"systemgo/unit"
+)
+
+func TestCgroupAttribute(t *testing.T) {
+	for _, c := range []struct {
+		key, value     string
+		file, contents string
+	}{
+		{"MemoryMax", "512M", "memory.max", "536870912"},
+		{"MemoryMax", "1024", "memory.max", "1024"},
+		{"MemoryMax", "infinity", "memory.max", "max"},
+		{"TasksMax", "64", "pids.max", "64"},
+		{"TasksMax", "", "pids.max", "max"},
+		{"CPUQuota", "20%", "cpu.max", "20000 100000"},
+		{"CPUQuota", "150%", "cpu.max", "150000 100000"},
+		{"CPUQuota", "", "cpu.max", "max 100000"},
+	} {
+		file, contents, err := unit.CgroupAttribute(c.key, c.value)
+		if assert.NoError(t, err, "%s=%s", c.key, c.value) {
+			assert.Equal(t, c.file, file, "%s=%s", c.key, c.value)
+			assert.Equal(t, c.contents, contents, "%s=%s", c.key, c.value)
+		}
+	}
+
+	for _, c := range [][2]string{
+		{"MemoryMax", "lots"},
+		{"MemoryMax", "16E"},
+		{"TasksMax", "-1"},
+		{"CPUQuota", "20"},
+		{"CPUQuota", "0%"},
+		{"CPUWeight", "100"},
+	} {
+		_, _, err := unit.CgroupAttribute(c[0], c[1])
+		assert.Error(t, err, "%s=%s", c[0], c[1])
+	}
+}
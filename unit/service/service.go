@@ -2,9 +2,17 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
+	"net"
+	"net/url"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"systemgo/unit"
 
@@ -13,6 +21,14 @@ import (
 
 const DEFAULT_TYPE = "simple"
 
+var ErrMultipleProbes = errors.New("Only one health check probe may be specified")
+
+// Shell setting LISTEN_PID to the PID of the main process, which is not known before it is spawned
+const LISTEN_PID_SHELL = "/bin/sh"
+
+// First file descriptor passed following the sd_listen_fds(3) protocol
+const LISTEN_FDS_START = 3
+
 const (
 	dead         = "dead"
 	startPre     = "startPre"
@@ -41,10 +57,79 @@ var supported = map[string]bool{
 	"idle":    false,
 }
 
+// Values of Restart=
+var restartPolicies = map[string]bool{
+	"":            true,
+	"no":          true,
+	"on-success":  true,
+	"on-failure":  true,
+	"on-abnormal": true,
+	"on-watchdog": true,
+	"on-abort":    true,
+	"always":      true,
+}
+
 // Service unit
 type Unit struct {
 	Definition
 	*exec.Cmd
+
+	// Executor spawning processes of the service, takes precedence over the one set by SetExecutor
+	Executor unit.Executor
+
+	// Executor set by SetExecutor
+	executor unit.Executor
+
+	// Listening sockets passed to the main process and their names, see SetListenFiles
+	listenFiles []*os.File
+	listenNames []string
+
+	// Connection passed to the main process as standard input and output, see SetConnection
+	conn *os.File
+
+	// Sub state restored by Deserialize, used until the service is started
+	restored string
+
+	// Main process, nil if the service has not been started
+	main *execution
+
+	// Process run by ExecStop=, nil if none is running
+	control *execution
+
+	// Called, when the main process exits, see SetStateNotify
+	notify func(reason string)
+
+	// Guards the runtime state of the service and the definition replaced by Define and SetProperty,
+	// processes are waited for without holding it
+	mutex sync.Mutex
+}
+
+// execution is a run of the main process of a service. Process is set, before the execution is published,
+// and never changes, so that it can be used from any goroutine
+type execution struct {
+	unit.Process
+
+	// Closed, when the process exits
+	exited chan struct{}
+
+	// Error returned by Wait, valid once exited is closed
+	err error
+}
+
+// wait waits for x to exit and returns the error returned by Wait
+func (x *execution) wait() error {
+	<-x.exited
+	return x.err
+}
+
+// hasExited returns whether Wait returned for x
+func (x *execution) hasExited() bool {
+	select {
+	case <-x.exited:
+		return true
+	default:
+		return false
+	}
 }
 
 // Service unit definition
@@ -53,14 +138,114 @@ type Definition struct {
 	Service struct {
 		Type                            string
 		ExecStart, ExecStop, ExecReload string
-		//Restart                         string
+		Restart                         string
 		//RestartSec                      int
 		RemainAfterExit  bool
 		WorkingDirectory string
+		PassEnvironment  []string
+
+		MemoryMax, CPUQuota, TasksMax string
+
+		CPUAccounting, MemoryAccounting, IOAccounting, TasksAccounting *bool
+
+		SyslogIdentifier, SyslogFacility string
+
+		LogLevelMax, LogLevel string
+
+		TimeoutStartSec, TimeoutStopSec *time.Duration
+
+		ExecHealthCheck, HealthCheckTCP, HealthCheckHTTP string
+		HealthCheckIntervalSec, HealthCheckTimeoutSec    time.Duration
+		HealthCheckRetries                               int
 		//PIDFile          string
 	}
 }
 
+// TimeoutStartSec returns a time.Duration as found in Definition and whether it is specified
+func (def Definition) TimeoutStartSec() (timeout time.Duration, ok bool) {
+	if def.Service.TimeoutStartSec == nil {
+		return 0, false
+	}
+	return *def.Service.TimeoutStartSec, true
+}
+
+// TimeoutStopSec returns a time.Duration as found in Definition and whether it is specified
+func (def Definition) TimeoutStopSec() (timeout time.Duration, ok bool) {
+	if def.Service.TimeoutStopSec == nil {
+		return 0, false
+	}
+	return *def.Service.TimeoutStopSec, true
+}
+
+// Resources returns the resource control properties as found in Definition, which are specified
+func (def Definition) Resources() map[string]string {
+	res := map[string]string{}
+	for key, value := range map[string]string{
+		"MemoryMax": def.Service.MemoryMax,
+		"CPUQuota":  def.Service.CPUQuota,
+		"TasksMax":  def.Service.TasksMax,
+	} {
+		if value != "" {
+			res[key] = value
+		}
+	}
+	return res
+}
+
+// Accounting returns the resource accounting options as found in Definition, which are specified
+func (def Definition) Accounting() map[string]bool {
+	acc := map[string]bool{}
+	for key, value := range map[string]*bool{
+		"CPUAccounting":    def.Service.CPUAccounting,
+		"MemoryAccounting": def.Service.MemoryAccounting,
+		"IOAccounting":     def.Service.IOAccounting,
+		"TasksAccounting":  def.Service.TasksAccounting,
+	} {
+		if value != nil {
+			acc[key] = *value
+		}
+	}
+	return acc
+}
+
+// HealthCheck returns the health check as found in Definition and whether a probe is specified
+func (def Definition) HealthCheck() (hc unit.HealthCheck, ok bool) {
+	hc = unit.HealthCheck{
+		Exec:     def.Service.ExecHealthCheck,
+		TCP:      def.Service.HealthCheckTCP,
+		HTTP:     def.Service.HealthCheckHTTP,
+		Interval: def.Service.HealthCheckIntervalSec,
+		Timeout:  def.Service.HealthCheckTimeoutSec,
+		Retries:  def.Service.HealthCheckRetries,
+	}
+	return hc, hc.Exec != "" || hc.TCP != "" || hc.HTTP != ""
+}
+
+// RestartPolicy returns the value of Restart= as found in Definition
+func (def Definition) RestartPolicy() string {
+	return def.Service.Restart
+}
+
+// SyslogIdentifier returns the identifier of log entries forwarded to syslog as found in Definition
+func (def Definition) SyslogIdentifier() string {
+	return def.Service.SyslogIdentifier
+}
+
+// SyslogFacility returns the facility of log entries forwarded to syslog as found in Definition
+func (def Definition) SyslogFacility() string {
+	return def.Service.SyslogFacility
+}
+
+// LogLevelMax returns the lowest priority of log entries kept as found in Definition
+func (def Definition) LogLevelMax() string {
+	return def.Service.LogLevelMax
+}
+
+// LogLevel returns the lowest priority of messages logged by the manager as found in Definition
+func (def Definition) LogLevel() string {
+	return def.Service.LogLevel
+}
+
 func Supported(typ string) (is bool) {
 	return supported[typ]
 }
@@ -75,6 +260,9 @@ func (sv *Unit) Define(r io.Reader /*, errch chan<- error*/) (err error) {
 
 	def := Definition{}
 	def.Service.Type = DEFAULT_TYPE
+	def.Service.HealthCheckIntervalSec = unit.DEFAULT_HEALTH_CHECK_INTERVAL
+	def.Service.HealthCheckTimeoutSec = unit.DEFAULT_HEALTH_CHECK_TIMEOUT
+	def.Service.HealthCheckRetries = unit.DEFAULT_HEALTH_CHECK_RETRIES
 
 	if err = unit.ParseDefinition(r, &def); err != nil {
 		return
@@ -91,34 +279,327 @@ func (sv *Unit) Define(r io.Reader /*, errch chan<- error*/) (err error) {
 		merr = append(merr, unit.ParseErr("Type", unit.ParseErr(def.Service.Type, unit.ErrNotSupported)))
 	}
 
+	if !restartPolicies[def.Service.Restart] {
+		merr = append(merr, unit.ParseErr("Restart", unit.ParseErr(def.Service.Restart, unit.ErrNotSupported)))
+	}
+
+	if hc, ok := def.HealthCheck(); ok {
+		merr = append(merr, checkHealthCheck(hc)...)
+	}
+
+	for key, value := range def.Resources() {
+		if _, _, err := unit.CgroupAttribute(key, value); err != nil {
+			merr = append(merr, err)
+		}
+	}
+
+	if facility := def.Service.SyslogFacility; facility != "" {
+		if _, err := unit.ParseSyslogFacility(facility); err != nil {
+			merr = append(merr, unit.ParseErr("SyslogFacility", err))
+		}
+	}
+
+	for key, value := range map[string]string{
+		"LogLevelMax": def.Service.LogLevelMax,
+		"LogLevel":    def.Service.LogLevel,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := unit.ParsePriority(value); err != nil {
+			merr = append(merr, unit.ParseErr(key, err))
+		}
+	}
+
 	if len(merr) > 0 {
 		return merr
 	}
 
-	sv.Definition = def
-
 	cmd := strings.Fields(def.Service.ExecStart)
+
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	sv.Definition = def
 	sv.Cmd = exec.Command(cmd[0], cmd[1:]...)
-	sv.Cmd.Dir = sv.Definition.Service.WorkingDirectory
+	sv.Cmd.Dir = def.Service.WorkingDirectory
+
+	return nil
+}
+
+// checkHealthCheck returns the errors found in the health check options
+func checkHealthCheck(hc unit.HealthCheck) (merr unit.MultiError) {
+	if hc.Exec != "" && hc.TCP != "" || hc.Exec != "" && hc.HTTP != "" || hc.TCP != "" && hc.HTTP != "" {
+		merr = append(merr, unit.ParseErr("ExecHealthCheck", ErrMultipleProbes))
+	}
+
+	if hc.TCP != "" {
+		if _, _, err := net.SplitHostPort(hc.TCP); err != nil {
+			merr = append(merr, unit.ParseErr("HealthCheckTCP", unit.ParseErr(hc.TCP, unit.ErrWrongVal)))
+		}
+	}
+	if hc.HTTP != "" {
+		if u, err := url.Parse(hc.HTTP); err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			merr = append(merr, unit.ParseErr("HealthCheckHTTP", unit.ParseErr(hc.HTTP, unit.ErrWrongVal)))
+		}
+	}
+
+	for _, opt := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"HealthCheckIntervalSec", hc.Interval},
+		{"HealthCheckTimeoutSec", hc.Timeout},
+	} {
+		if opt.d <= 0 {
+			merr = append(merr, unit.ParseErr(opt.key, unit.ErrWrongVal))
+		}
+	}
+	if hc.Retries < 1 {
+		merr = append(merr, unit.ParseErr("HealthCheckRetries", unit.ErrWrongVal))
+	}
+	return
+}
+
+// SetEnvironment sets the environment of the processes spawned by sv to env along with the
+// variables listed in PassEnvironment= taken from the environment of the calling process
+func (sv *Unit) SetEnvironment(env []string) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	for _, name := range sv.Definition.Service.PassEnvironment {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	if sv.Cmd != nil {
+		sv.Cmd.Env = env
+	}
+}
+
+// SetListenFiles sets the listening sockets passed to the main process of sv as file descriptors
+// starting at LISTEN_FDS_START along with LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES
+func (sv *Unit) SetListenFiles(files []*os.File, names []string) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	sv.listenFiles, sv.listenNames = files, names
+}
+
+// SetConnection sets the connection passed to the next main process of sv as standard input and output.
+// The file is closed, once the process is spawned
+func (sv *Unit) SetConnection(f *os.File) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	sv.conn = f
+}
+
+// listenCommand returns cmd passed the listening sockets of sv. LISTEN_PID is set by a shell,
+// which replaces itself by cmd, so that the PID of the shell is the PID of cmd.
+// The mutex of sv must be held
+func (sv *Unit) listenCommand(cmd *exec.Cmd) *exec.Cmd {
+	if len(sv.listenFiles) == 0 {
+		return cmd
+	}
+
+	wrapped := exec.Command(LISTEN_PID_SHELL, append([]string{
+		"-c", `LISTEN_PID=$$; export LISTEN_PID; exec "$0" "$@"`, cmd.Path,
+	}, cmd.Args[1:]...)...)
+	wrapped.Dir = cmd.Dir
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	wrapped.Env = append(append([]string{}, env...),
+		"LISTEN_FDS="+strconv.Itoa(len(sv.listenFiles)),
+		"LISTEN_FDNAMES="+strings.Join(sv.listenNames, ":"),
+	)
+	wrapped.ExtraFiles = sv.listenFiles
+	return wrapped
+}
+
+// SetProperty sets Restart=, LogLevelMax=, LogLevel= or one of the resource control properties of sv to value
+func (sv *Unit) SetProperty(key, value string) (err error) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	var field *string
+	switch key {
+	case "Restart":
+		if !restartPolicies[value] {
+			return unit.ParseErr(key, unit.ParseErr(value, unit.ErrNotSupported))
+		}
+		field = &sv.Definition.Service.Restart
+	case "LogLevelMax":
+		field = &sv.Definition.Service.LogLevelMax
+	case "LogLevel":
+		field = &sv.Definition.Service.LogLevel
+	case "MemoryMax":
+		field = &sv.Definition.Service.MemoryMax
+	case "CPUQuota":
+		field = &sv.Definition.Service.CPUQuota
+	case "TasksMax":
+		field = &sv.Definition.Service.TasksMax
+	default:
+		return unit.ParseErr(key, unit.ErrNotSupported)
+	}
+
+	switch key {
+	case "Restart":
+	case "LogLevelMax", "LogLevel":
+		if _, err = unit.ParsePriority(value); err != nil {
+			return unit.ParseErr(key, err)
+		}
+	default:
+		if _, _, err = unit.CgroupAttribute(key, value); err != nil {
+			return
+		}
+	}
 
+	*field = value
 	return nil
 }
 
+// SetExecutor sets the Executor spawning processes of sv, unless sv.Executor is set
+func (sv *Unit) SetExecutor(e unit.Executor) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	sv.executor = e
+}
+
+// SetStateNotify sets the function called, when the main processes started afterwards exit
+func (sv *Unit) SetStateNotify(fn func(reason string)) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	sv.notify = fn
+}
+
+// notifyExit waits for x to exit and calls the function set by SetStateNotify, if x is still the main process
+func (sv *Unit) notifyExit(x *execution) {
+	err := x.wait()
+
+	sv.mutex.Lock()
+	fn := sv.notify
+	if sv.main != x {
+		fn = nil
+	}
+	sv.mutex.Unlock()
+
+	if fn == nil {
+		return
+	}
+	if err != nil {
+		fn("main process exited: " + err.Error())
+	} else {
+		fn("main process exited")
+	}
+}
+
+// getExecutor returns the Executor spawning processes of sv
+func (sv *Unit) getExecutor() unit.Executor {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	switch {
+	case sv.Executor != nil:
+		return sv.Executor
+	case sv.executor != nil:
+		return sv.executor
+	default:
+		return unit.OSExecutor
+	}
+}
+
+// execute starts cmd using the Executor of sv and waits for it to exit in a separate goroutine
+func (sv *Unit) execute(cmd *exec.Cmd) (x *execution, err error) {
+	var p unit.Process
+	if p, err = sv.getExecutor().Start(cmd); err != nil {
+		return nil, err
+	}
+	return newExecution(p, nil), nil
+}
+
+// newExecution returns an execution of p, which is waited for in a separate goroutine.
+// onError is called with the error returned by Wait, if it is not nil and onError is set
+func newExecution(p unit.Process, onError func(error)) (x *execution) {
+	x = &execution{Process: p, exited: make(chan struct{})}
+
+	go func() {
+		err := p.Wait()
+		if err != nil && onError != nil {
+			onError(err)
+		}
+		x.err = err
+		close(x.exited)
+	}()
+	return x
+}
+
+// command returns the type of sv and the command of its next main process. The connection set by
+// SetConnection, if any, is passed to the command and returned, it is only passed once
+func (sv *Unit) command() (typ string, cmd *exec.Cmd, conn *os.File) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	typ = sv.Definition.Service.Type
+	if typ != "simple" && typ != "oneshot" {
+		panic("Unknown service type")
+	}
+
+	// A command can only be started once
+	cmd = exec.Command(sv.Cmd.Path, sv.Cmd.Args[1:]...)
+	cmd.Dir, cmd.Env = sv.Cmd.Dir, sv.Cmd.Env
+	cmd = sv.listenCommand(cmd)
+
+	if conn, sv.conn = sv.conn, nil; conn != nil {
+		cmd.Stdin, cmd.Stdout = conn, conn
+	}
+	return
+}
+
+// stopCommand returns the command specified by ExecStop=, nil if none is specified
+func (sv *Unit) stopCommand() (stop *exec.Cmd) {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if cmd := strings.Fields(sv.Definition.Service.ExecStop); len(cmd) > 0 {
+		stop = exec.Command(cmd[0], cmd[1:]...)
+		stop.Env = sv.Cmd.Env
+	}
+	return
+}
+
 // Start executes the command specified in service definition
 func (sv *Unit) Start() (err error) {
-	e := log.WithField("ExecStart", sv.Definition.Service.ExecStart)
+	typ, cmd, conn := sv.command()
+	if conn != nil {
+		// The connection is held open by the process
+		defer conn.Close()
+	}
 
+	e := log.WithField("cmd", cmd.Args)
 	e.Debug("sv.Start")
 
-	switch sv.Definition.Service.Type {
-	case "simple":
-		if err = sv.Cmd.Start(); err == nil {
-			go sv.Cmd.Wait()
+	var x *execution
+	if x, err = sv.execute(cmd); err == nil {
+		sv.mutex.Lock()
+		sv.main = x
+		sv.restored = ""
+		notify := sv.notify != nil
+		sv.mutex.Unlock()
+
+		if notify {
+			go sv.notifyExit(x)
+		}
+
+		if typ == "oneshot" {
+			err = x.wait()
 		}
-	case "oneshot":
-		err = sv.Cmd.Run()
-	default:
-		panic("Unknown service type")
 	}
 
 	e.WithField("err", err).Debug("started")
@@ -127,29 +608,87 @@ func (sv *Unit) Start() (err error) {
 
 // Stop stops execution of the command specified in service definition
 func (sv *Unit) Stop() (err error) {
-	if cmd := strings.Fields(sv.Definition.Service.ExecStop); len(cmd) > 0 {
-		return exec.Command(cmd[0], cmd[1:]...).Run()
+	if stop := sv.stopCommand(); stop != nil {
+		var x *execution
+		if x, err = sv.execute(stop); err != nil {
+			return
+		}
+
+		sv.mutex.Lock()
+		sv.control = x
+		sv.mutex.Unlock()
+
+		err = x.wait()
+
+		sv.mutex.Lock()
+		if sv.control == x {
+			sv.control = nil
+		}
+		sv.mutex.Unlock()
+		return err
+	}
+	if main := sv.running(); main != nil {
+		return main.Signal(os.Kill)
 	}
-	if sv.Cmd.Process != nil {
-		return sv.Cmd.Process.Kill()
+	return nil
+}
+
+// Kill kills the main process of a service and the one run by ExecStop=, if they are still running,
+// and waits for them to exit
+func (sv *Unit) Kill() (err error) {
+	sv.mutex.Lock()
+	xs := []*execution{sv.main, sv.control}
+	sv.mutex.Unlock()
+
+	for _, x := range xs {
+		if x == nil || x.hasExited() {
+			continue
+		}
+
+		if err = x.Signal(os.Kill); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return err
+		}
+		x.wait()
 	}
 	return nil
 }
 
+// running returns the main process of sv, if it is still running, nil otherwise
+func (sv *Unit) running() *execution {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if sv.main == nil || sv.main.hasExited() {
+		return nil
+	}
+	return sv.main
+}
+
 // Sub reports the sub status of a service
 func (sv *Unit) Sub() string {
 	log.WithField("sv", sv).Debugf("sv.Sub")
 
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	return sv.sub()
+}
+
+// sub reports the sub status of sv, the mutex of sv must be held
+func (sv *Unit) sub() string {
 	switch {
-	case sv.Cmd.Process == nil:
+	case sv.main == nil:
+		if sv.restored != "" {
+			return sv.restored
+		}
 		// Service has not been started yet
 		return dead
 
-	case sv.Cmd.ProcessState == nil:
+	case !sv.main.hasExited():
 		// Wait has not returned yet
 		return running
 
-	case sv.ProcessState.Exited(), sv.ProcessState.Success():
+	case sv.main.err == nil:
 		if sv.Definition.Service.RemainAfterExit {
 			return exited
 		}
@@ -183,3 +722,80 @@ func (sv *Unit) Active() unit.Activation {
 		panic("Unknown service sub state")
 	}
 }
+
+// ResetFailed forgets the main process of a service, which has failed, so that it is reported as dead
+func (sv *Unit) ResetFailed() {
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+
+	if sv.sub() == failed {
+		sv.main = nil
+		sv.restored = ""
+	}
+}
+
+// MainPID returns the PID of the main process of sv, 0 if it is not running
+func (sv *Unit) MainPID() int {
+	if main := sv.running(); main != nil {
+		return main.Pid()
+	}
+	return 0
+}
+
+// Serialized runtime state of a service
+type state struct {
+	PID int    `json:",omitempty"`
+	Sub string `json:",omitempty"`
+}
+
+// Serialize returns the runtime state of sv encoded as JSON
+func (sv *Unit) Serialize() ([]byte, error) {
+	sv.mutex.Lock()
+	st := state{Sub: sv.sub()}
+	if st.Sub == running {
+		st.PID = sv.main.Pid()
+	}
+	sv.mutex.Unlock()
+
+	return json.Marshal(st)
+}
+
+// Deserialize restores the runtime state of sv from b as returned by Serialize.
+// The running process is adopted, which only works if it is a child of the calling process
+// (e.g. after the daemon re-executed itself)
+func (sv *Unit) Deserialize(b []byte) (err error) {
+	var st state
+	if err = json.Unmarshal(b, &st); err != nil {
+		return
+	}
+
+	if st.PID == 0 {
+		if st.Sub != dead {
+			sv.mutex.Lock()
+			sv.restored = st.Sub
+			sv.mutex.Unlock()
+		}
+		return nil
+	}
+
+	var p unit.Process
+	if p, err = unit.FindProcess(st.PID); err != nil {
+		return
+	}
+
+	x := newExecution(p, func(err error) {
+		if _, ok := err.(*exec.ExitError); !ok {
+			log.WithField("pid", st.PID).Errorf("Error waiting for restored process: %s", err)
+		}
+	})
+
+	sv.mutex.Lock()
+	sv.main = x
+	notify := sv.notify != nil
+	sv.mutex.Unlock()
+
+	if notify {
+		go sv.notifyExit(x)
+	}
+	return nil
+}
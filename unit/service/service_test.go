@@ -2,9 +2,12 @@ package service
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"systemgo/unit"
@@ -39,8 +42,15 @@ func TestStartSimple(t *testing.T) {
 	sv.Cmd = exec.Command("sleep", "60")
 
 	assert.NoError(t, sv.Start(), "sv.Start")
-	assert.NotNil(t, sv.Cmd.Process)
-	assert.Nil(t, sv.Cmd.ProcessState)
+	if assert.NotNil(t, sv.main) {
+		assert.False(t, sv.main.hasExited())
+	}
+
+	assert.NoError(t, sv.Kill(), "sv.Kill")
+	if assert.True(t, sv.main.hasExited()) {
+		assert.Error(t, sv.main.err)
+	}
+	assert.NoError(t, sv.Kill(), "sv.Kill of exited process")
 }
 
 func TestStartOneshot(t *testing.T) {
@@ -52,9 +62,8 @@ func TestStartOneshot(t *testing.T) {
 	sv.Cmd = exec.Command("echo", "test")
 
 	assert.NoError(t, sv.Start(), "sv.Start")
-	assert.NotNil(t, sv.Cmd.Process)
-	if assert.NotNil(t, sv.Cmd.ProcessState) {
-		assert.True(t, sv.Cmd.ProcessState.Success())
+	if assert.NotNil(t, sv.main) && assert.True(t, sv.main.hasExited()) {
+		assert.NoError(t, sv.main.err)
 	}
 
 }
@@ -66,7 +75,7 @@ func TestActive(t *testing.T) {
 
 	sv.Definition.Service.Type = "oneshot"
 	sv.Definition.Service.RemainAfterExit = true
-	if assert.NoError(t, sv.Cmd.Run(), "oneshot Cmd.Run()") {
+	if assert.NoError(t, sv.Start(), "oneshot sv.Start()") {
 		assert.Equal(t, unit.Active, sv.Active(), fmt.Sprintf("oneshot service - %s", sv.Active()))
 	}
 
@@ -74,8 +83,9 @@ func TestActive(t *testing.T) {
 	sv = Unit{}
 	sv.Cmd = exec.Command("sleep", "60")
 	sv.Definition.Service.Type = "simple"
-	if assert.NoError(t, sv.Cmd.Start(), "simple Cmd.Run()") {
+	if assert.NoError(t, sv.Start(), "simple sv.Start()") {
 		assert.Equal(t, unit.Active, sv.Active(), fmt.Sprintf("simple service - %s", sv.Active()))
+		assert.NoError(t, sv.Kill())
 	}
 	// TODO
 	//assert.NoError(t, sv.Cmd.Process.Kill())
@@ -99,3 +109,279 @@ func TestSuported(t *testing.T) {
 
 	assert.False(t, Supported("not-a-service"))
 }
+
+func TestSerialize(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if !assert.NoError(t, cmd.Start()) {
+		return
+	}
+
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/sleep 60`)), "sv.Define")
+
+	assert.NoError(t, sv.Deserialize([]byte(fmt.Sprintf(`{"PID":%d,"Sub":"running"}`, cmd.Process.Pid))))
+	assert.Equal(t, running, sv.Sub())
+
+	b, err := sv.Serialize()
+	assert.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{"PID":%d,"Sub":"running"}`, cmd.Process.Pid), string(b))
+
+	assert.NoError(t, sv.Stop())
+	for i := 0; i < 50 && sv.Sub() == running; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, failed, sv.Sub())
+
+	sv = Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/true`)), "sv.Define")
+	assert.NoError(t, sv.Deserialize([]byte(`{"Sub":"exited"}`)))
+	assert.Equal(t, unit.Active, sv.Active())
+}
+
+func TestSetEnvironment(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+PassEnvironment=SYSTEMGO_TEST_PASS SYSTEMGO_TEST_UNSET`)), "sv.Define")
+
+	assert.NoError(t, os.Setenv("SYSTEMGO_TEST_PASS", "value"))
+	defer os.Unsetenv("SYSTEMGO_TEST_PASS")
+
+	sv.SetEnvironment([]string{"FOO=bar"})
+	assert.Equal(t, []string{"FOO=bar", "SYSTEMGO_TEST_PASS=value"}, sv.Cmd.Env)
+}
+
+func TestStartTwice(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+Type=oneshot
+ExecStart=/bin/echo test`)), "sv.Define")
+
+	assert.NoError(t, sv.Start(), "first sv.Start")
+	assert.NoError(t, sv.Start(), "second sv.Start")
+	assert.Equal(t, dead, sv.Sub())
+}
+
+// fakeExecutor runs processes as goroutines exiting, when signaled
+type fakeExecutor struct {
+	started []*exec.Cmd
+}
+
+func (e *fakeExecutor) Start(cmd *exec.Cmd) (unit.Process, error) {
+	e.started = append(e.started, cmd)
+	return &fakeProcess{pid: len(e.started), signals: make(chan os.Signal, 1)}, nil
+}
+
+type fakeProcess struct {
+	pid     int
+	signals chan os.Signal
+}
+
+func (p *fakeProcess) Pid() int {
+	return p.pid
+}
+
+func (p *fakeProcess) Signal(sig os.Signal) error {
+	p.signals <- sig
+	return nil
+}
+
+func (p *fakeProcess) Wait() error {
+	return fmt.Errorf("signal: %s", <-p.signals)
+}
+
+func TestExecutor(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/foo --bar`)), "sv.Define")
+
+	daemon, own := &fakeExecutor{}, &fakeExecutor{}
+
+	sv.SetExecutor(daemon)
+	assert.NoError(t, sv.Start(), "sv.Start")
+	if assert.Len(t, daemon.started, 1) {
+		assert.Equal(t, []string{"/bin/foo", "--bar"}, daemon.started[0].Args)
+	}
+	assert.Equal(t, running, sv.Sub())
+
+	b, err := sv.Serialize()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"PID":1,"Sub":"running"}`, string(b))
+
+	assert.NoError(t, sv.Kill(), "sv.Kill")
+	assert.Equal(t, failed, sv.Sub())
+
+	sv.Executor = own
+	assert.NoError(t, sv.Start(), "sv.Start with own Executor")
+	assert.Len(t, own.started, 1)
+	assert.Len(t, daemon.started, 1)
+	assert.NoError(t, sv.Kill(), "sv.Kill")
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/foo`)), "sv.Define")
+	sv.SetExecutor(&fakeExecutor{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			sv.Sub()
+			sv.Active()
+			sv.MainPID()
+			sv.Serialize()
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		sv.SetEnvironment([]string{"FOO=bar"})
+		assert.NoError(t, sv.SetProperty("Restart", "always"))
+		assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/foo`)), "sv.Define")
+		assert.NoError(t, sv.Start(), "sv.Start")
+		assert.NoError(t, sv.Kill(), "sv.Kill")
+		sv.ResetFailed()
+	}
+	<-done
+	assert.Equal(t, dead, sv.Sub())
+}
+
+func TestSetProperty(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+MemoryMax=1G`)), "sv.Define")
+	assert.Equal(t, map[string]string{"MemoryMax": "1G"}, sv.Resources())
+
+	assert.NoError(t, sv.SetProperty("Restart", "on-failure"))
+	assert.NoError(t, sv.SetProperty("CPUQuota", "50%"))
+	assert.Equal(t, "on-failure", sv.Definition.Service.Restart)
+	assert.Equal(t, map[string]string{"MemoryMax": "1G", "CPUQuota": "50%"}, sv.Resources())
+
+	assert.Error(t, sv.SetProperty("Restart", "sometimes"))
+	assert.Error(t, sv.SetProperty("TasksMax", "many"))
+	assert.Error(t, sv.SetProperty("ExecStart", "/bin/false"))
+	assert.Equal(t, "on-failure", sv.Definition.Service.Restart)
+
+	assert.Error(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+Restart=sometimes`)), "sv.Define with invalid Restart=")
+}
+
+func TestSyslog(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+SyslogIdentifier=echo
+SyslogFacility=local0`)), "sv.Define")
+	assert.Equal(t, "echo", sv.SyslogIdentifier())
+	assert.Equal(t, "local0", sv.SyslogFacility())
+
+	assert.Error(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+SyslogFacility=local8`)), "sv.Define with invalid SyslogFacility=")
+}
+
+func TestLogLevel(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+LogLevelMax=warning
+LogLevel=7`)), "sv.Define")
+	assert.Equal(t, "warning", sv.LogLevelMax())
+	assert.Equal(t, "7", sv.LogLevel())
+
+	assert.NoError(t, sv.SetProperty("LogLevelMax", "err"))
+	assert.Equal(t, "err", sv.LogLevelMax())
+	assert.Error(t, sv.SetProperty("LogLevel", "verbose"), "sv.SetProperty with invalid LogLevel=")
+
+	assert.Error(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+LogLevelMax=8`)), "sv.Define with invalid LogLevelMax=")
+}
+
+func TestHealthCheck(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test`)), "sv.Define")
+	_, ok := sv.HealthCheck()
+	assert.False(t, ok, "no probe specified")
+
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+HealthCheckHTTP=http://localhost:8080/health
+HealthCheckIntervalSec=10s`)), "sv.Define")
+	hc, ok := sv.HealthCheck()
+	assert.True(t, ok)
+	assert.Equal(t, unit.HealthCheck{
+		HTTP:     "http://localhost:8080/health",
+		Interval: 10 * time.Second,
+		Timeout:  unit.DEFAULT_HEALTH_CHECK_TIMEOUT,
+		Retries:  unit.DEFAULT_HEALTH_CHECK_RETRIES,
+	}, hc)
+
+	err := sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/echo test
+ExecHealthCheck=/bin/true
+HealthCheckTCP=8080
+HealthCheckTimeoutSec=0
+HealthCheckRetries=0`))
+	if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") && assert.Len(t, me, 4) {
+		assert.Equal(t, unit.ParseErr("ExecHealthCheck", ErrMultipleProbes), me[0])
+		assert.Equal(t, unit.ParseErr("HealthCheckTCP", unit.ParseErr("8080", unit.ErrWrongVal)), me[1])
+		assert.Equal(t, unit.ParseErr("HealthCheckTimeoutSec", unit.ErrWrongVal), me[2])
+		assert.Equal(t, unit.ParseErr("HealthCheckRetries", unit.ErrWrongVal), me[3])
+	}
+}
+
+func TestSetListenFiles(t *testing.T) {
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+ExecStart=/bin/foo --bar`)), "sv.Define")
+	sv.SetEnvironment([]string{"FOO=bar"})
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	e := &fakeExecutor{}
+	sv.SetExecutor(e)
+	sv.SetListenFiles([]*os.File{r, w}, []string{"foo.socket", "bar"})
+	assert.NoError(t, sv.Start(), "sv.Start")
+	defer sv.Kill()
+
+	if assert.Len(t, e.started, 1) {
+		cmd := e.started[0]
+		assert.Equal(t, LISTEN_PID_SHELL, cmd.Path)
+		assert.Equal(t, []string{"/bin/foo", "--bar"}, cmd.Args[len(cmd.Args)-2:])
+		assert.Equal(t, []*os.File{r, w}, cmd.ExtraFiles)
+		assert.Equal(t, []string{"FOO=bar", "LISTEN_FDS=2", "LISTEN_FDNAMES=foo.socket:bar"}, cmd.Env)
+	}
+}
+
+func TestListenPID(t *testing.T) {
+	script, err := ioutil.TempFile("", "listen-test")
+	assert.NoError(t, err)
+	defer os.Remove(script.Name())
+
+	fmt.Fprintln(script, `test "$LISTEN_PID" = $$ && test "$LISTEN_FDS" = 1 && test -e /dev/fd/3`)
+	script.Close()
+
+	sv := Unit{}
+	assert.NoError(t, sv.Define(strings.NewReader(`[Service]
+Type=oneshot
+ExecStart=/bin/sh `+script.Name())), "sv.Define")
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	sv.SetListenFiles([]*os.File{r}, []string{"foo.socket"})
+	assert.NoError(t, sv.Start(), "sv.Start")
+}
@@ -0,0 +1,545 @@
+// Package socket defines a socket unit type
+package socket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Maximum length of FileDescriptorName=
+const MAX_FD_NAME_LEN = 255
+
+// Number of connections accepted concurrently by default, if Accept= is set
+const DEFAULT_MAX_CONNECTIONS = 64
+
+// Access mode of unix sockets and FIFOs created by default
+const DEFAULT_SOCKET_MODE = 0666
+
+var (
+	ErrInvalidAddress = errors.New("Invalid address")
+	ErrAcceptService  = errors.New("Service can not be set, if Accept is set")
+	ErrAcceptDatagram = errors.New("Datagram sockets can not accept connections")
+	ErrAcceptFIFO     = errors.New("FIFOs can not accept connections")
+)
+
+// Socket unit
+type Unit struct {
+	Definition
+
+	// Files of the listening sockets in the order of the definition, nil if not listening
+	files []*os.File
+
+	// Paths of the unix sockets created, which are removed, when stopped
+	paths []string
+
+	sub   Sub
+	mutex sync.Mutex
+}
+
+// Socket unit definition
+type Definition struct {
+	unit.Definition
+	Socket struct {
+		ListenStream, ListenDatagram, ListenSequentialPacket, ListenFIFO []string
+
+		Service            string
+		FileDescriptorName string
+
+		Accept         bool
+		MaxConnections int
+
+		Backlog        int
+		ReusePort      bool
+		BindToDevice   string
+		DeferAcceptSec time.Duration
+
+		SocketUser, SocketGroup, SocketMode string
+
+		TimeoutIdleSec time.Duration
+	}
+}
+
+// listening is a socket specified by one of the Listen*= options
+type listening struct {
+	// Network and address as accepted by the net package
+	network, address string
+}
+
+// Service returns the name of the unit activated by incoming traffic as found in Definition,
+// empty means the service named as the socket
+func (def Definition) Service() string {
+	return def.Socket.Service
+}
+
+// FileDescriptorName returns the name of the file descriptors passed to the service as found in Definition,
+// empty means the name of the socket
+func (def Definition) FileDescriptorName() string {
+	return def.Socket.FileDescriptorName
+}
+
+// Accept returns whether an instance of the service is spawned per connection accepted as found in Definition
+func (def Definition) Accept() bool {
+	return def.Socket.Accept
+}
+
+// MaxConnections returns the maximum number of connections accepted concurrently, if Accept= is set
+func (def Definition) MaxConnections() int {
+	if def.Socket.MaxConnections == 0 {
+		return DEFAULT_MAX_CONNECTIONS
+	}
+	return def.Socket.MaxConnections
+}
+
+// TimeoutIdleSec returns the time the service activated is stopped after, once idle, as found in Definition,
+// 0 means never
+func (def Definition) TimeoutIdleSec() time.Duration {
+	return def.Socket.TimeoutIdleSec
+}
+
+// socketMode returns the access mode of the unix sockets and FIFOs created as found in Definition
+func (def Definition) socketMode() (mode os.FileMode, err error) {
+	if def.Socket.SocketMode == "" {
+		return DEFAULT_SOCKET_MODE, nil
+	}
+
+	var m uint64
+	if m, err = strconv.ParseUint(def.Socket.SocketMode, 8, 32); err != nil || m > 07777 {
+		return 0, unit.ErrWrongVal
+	}
+	return os.FileMode(m).Perm(), nil
+}
+
+// Listens returns the sockets specified in Definition in the order, in which they are passed to the service
+func (def Definition) Listens() (listens []unit.Listen) {
+	for _, spec := range def.listenSpecs() {
+		for _, addr := range spec.addresses {
+			listens = append(listens, unit.Listen{
+				Type:    strings.TrimPrefix(spec.key, "Listen"),
+				Address: addr,
+			})
+		}
+	}
+	return
+}
+
+// listenSpec are the addresses specified by a Listen*= option
+type listenSpec struct {
+	key       string
+	addresses []string
+}
+
+// listenSpecs returns the Listen*= options of Definition in the order, in which the sockets are passed to the service
+func (def Definition) listenSpecs() []listenSpec {
+	return []listenSpec{
+		{"ListenStream", def.Socket.ListenStream},
+		{"ListenDatagram", def.Socket.ListenDatagram},
+		{"ListenSequentialPacket", def.Socket.ListenSequentialPacket},
+		{"ListenFIFO", def.Socket.ListenFIFO},
+	}
+}
+
+// listenings returns the sockets specified in Definition in the order, in which they are passed to the service
+func (def Definition) listenings() (ls []listening, err error) {
+	for _, spec := range def.listenSpecs() {
+		for _, addr := range spec.addresses {
+			var l listening
+			if l, err = parseListening(spec.key, addr); err != nil {
+				return nil, unit.ParseErr(spec.key, unit.ParseErr(addr, err))
+			}
+			ls = append(ls, l)
+		}
+	}
+	return ls, nil
+}
+
+// parseListening parses addr specified by the option key: a path of a unix socket or a FIFO,
+// a name of an abstract unix socket prefixed by "@", a port or an IPv4 or IPv6 address with a port,
+// see systemd.socket(5)
+func parseListening(key, addr string) (l listening, err error) {
+	if key == "ListenFIFO" {
+		if !strings.HasPrefix(addr, "/") {
+			return l, ErrInvalidAddress
+		}
+		return listening{"fifo", addr}, nil
+	}
+
+	if strings.HasPrefix(addr, "/") || strings.HasPrefix(addr, "@") && len(addr) > 1 {
+		switch key {
+		case "ListenStream":
+			return listening{"unix", addr}, nil
+		case "ListenDatagram":
+			return listening{"unixgram", addr}, nil
+		default:
+			return listening{"unixpacket", addr}, nil
+		}
+	}
+
+	if key == "ListenSequentialPacket" {
+		return l, ErrInvalidAddress
+	}
+
+	network := "tcp"
+	if key == "ListenDatagram" {
+		network = "udp"
+	}
+
+	if port, err := strconv.ParseUint(addr, 10, 16); err == nil && port > 0 {
+		return listening{network, ":" + addr}, nil
+	}
+
+	var host, port string
+	if host, port, err = net.SplitHostPort(addr); err != nil {
+		return l, ErrInvalidAddress
+	}
+	if net.ParseIP(host) == nil {
+		return l, ErrInvalidAddress
+	}
+	if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+		return l, ErrInvalidAddress
+	}
+	return listening{network, addr}, nil
+}
+
+// Define attempts to fill the sock definition by parsing r
+func (sock *Unit) Define(r io.Reader) (err error) {
+	log.WithField("r", r).Debugf("sock.Define")
+
+	def := Definition{}
+	if err = unit.ParseDefinition(r, &def); err != nil {
+		return
+	}
+
+	merr := unit.MultiError{}
+
+	if ls, err := def.listenings(); err != nil {
+		merr = append(merr, err)
+	} else if len(ls) == 0 {
+		merr = append(merr, unit.ParseErr("ListenStream", unit.ErrNotSet))
+	}
+
+	if name := def.Socket.Service; name != "" && !strings.HasSuffix(name, ".service") {
+		merr = append(merr, unit.ParseErr("Service", unit.ParseErr(name, unit.ErrNotSupported)))
+	}
+
+	if def.Socket.Accept {
+		if def.Socket.Service != "" {
+			merr = append(merr, unit.ParseErr("Service", ErrAcceptService))
+		}
+		if len(def.Socket.ListenDatagram) > 0 {
+			merr = append(merr, unit.ParseErr("ListenDatagram", ErrAcceptDatagram))
+		}
+	}
+
+	if def.Socket.Accept && len(def.Socket.ListenFIFO) > 0 {
+		merr = append(merr, unit.ParseErr("ListenFIFO", ErrAcceptFIFO))
+	}
+
+	if def.Socket.TimeoutIdleSec < 0 {
+		merr = append(merr, unit.ParseErr("TimeoutIdleSec", unit.ErrWrongVal))
+	}
+
+	if def.Socket.Backlog < 0 {
+		merr = append(merr, unit.ParseErr("Backlog", unit.ErrWrongVal))
+	}
+
+	if _, err := def.socketMode(); err != nil {
+		merr = append(merr, unit.ParseErr("SocketMode", unit.ParseErr(def.Socket.SocketMode, err)))
+	}
+
+	if def.Socket.MaxConnections < 0 {
+		merr = append(merr, unit.ParseErr("MaxConnections", unit.ErrWrongVal))
+	}
+
+	if name := def.Socket.FileDescriptorName; len(name) > MAX_FD_NAME_LEN || strings.ContainsAny(name, ":\n") {
+		merr = append(merr, unit.ParseErr("FileDescriptorName", unit.ParseErr(name, unit.ErrWrongVal)))
+	}
+
+	if len(merr) > 0 {
+		return merr
+	}
+
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	sock.Definition = def
+	return nil
+}
+
+// Start creates the listening sockets specified
+func (sock *Unit) Start() (err error) {
+	log.WithField("sock", sock).Debug("sock.Start")
+
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	if sock.files != nil {
+		return nil
+	}
+
+	var ls []listening
+	if ls, err = sock.Definition.listenings(); err != nil {
+		sock.sub = Failed
+		return
+	}
+
+	sock.sub = StartPre
+	for _, l := range ls {
+		var f *os.File
+		if f, err = sock.Definition.listen(l); err != nil {
+			sock.closeFiles()
+			sock.sub = Failed
+			return
+		}
+		sock.files = append(sock.files, f)
+
+		if l.isPath() {
+			sock.paths = append(sock.paths, l.address)
+		}
+	}
+	sock.sub = Listening
+	return nil
+}
+
+// isPath reports whether l is a unix socket or a FIFO in the file system
+func (l listening) isPath() bool {
+	return strings.HasPrefix(l.address, "/") && (l.network == "fifo" || strings.HasPrefix(l.network, "unix"))
+}
+
+// listen creates the socket or the FIFO specified by l with the options of def and returns its file
+func (def Definition) listen(l listening) (f *os.File, err error) {
+	if l.isPath() {
+		// Remove the stale socket, if any, FIFOs are reused
+		if fi, err := os.Lstat(l.address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(l.address)
+		}
+	}
+
+	if l.network == "fifo" {
+		f, err = def.openFIFO(l.address)
+	} else {
+		f, err = def.listenSocket(l)
+	}
+	if err != nil || !l.isPath() {
+		return
+	}
+
+	if err = def.setOwnership(l.address); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// listenSocket creates the socket specified by l and returns its file
+func (def Definition) listenSocket(l listening) (f *os.File, err error) {
+	lc := net.ListenConfig{Control: def.control}
+
+	switch l.network {
+	case "udp", "unixgram":
+		var conn net.PacketConn
+		if conn, err = lc.ListenPacket(context.Background(), l.network, l.address); err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return conn.(interface{ File() (*os.File, error) }).File()
+	}
+
+	var ln net.Listener
+	if ln, err = lc.Listen(context.Background(), l.network, l.address); err != nil {
+		return nil, err
+	}
+	if ul, ok := ln.(*net.UnixListener); ok {
+		// The socket is kept by the file returned
+		ul.SetUnlinkOnClose(false)
+	}
+	defer ln.Close()
+
+	if f, err = ln.(interface{ File() (*os.File, error) }).File(); err != nil {
+		return nil, err
+	}
+
+	if def.Socket.Backlog > 0 {
+		// Listening again changes the backlog of the socket
+		if err = unix.Listen(int(f.Fd()), def.Socket.Backlog); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// control sets the options of def on the IP socket c created for network before it is bound
+func (def Definition) control(network, address string, c syscall.RawConn) (err error) {
+	if !strings.HasPrefix(network, "tcp") && !strings.HasPrefix(network, "udp") {
+		return nil
+	}
+
+	if cerr := c.Control(func(fd uintptr) {
+		if def.Socket.ReusePort {
+			if err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil {
+				return
+			}
+		}
+
+		if def.Socket.BindToDevice != "" {
+			if err = unix.BindToDevice(int(fd), def.Socket.BindToDevice); err != nil {
+				return
+			}
+		}
+
+		if d := def.Socket.DeferAcceptSec; d > 0 && strings.HasPrefix(network, "tcp") {
+			// Rounded up, so that sub-second values do not disable the option
+			secs := int((d + time.Second - 1) / time.Second)
+			err = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_DEFER_ACCEPT, secs)
+		}
+	}); cerr != nil {
+		return cerr
+	}
+	return
+}
+
+// openFIFO creates the FIFO at path, unless it exists, and opens it for reading and writing,
+// so that opening does not block and reads do not hit the end of file, when no writer is left
+func (def Definition) openFIFO(path string) (f *os.File, err error) {
+	mode, _ := def.socketMode()
+	if err = unix.Mkfifo(path, uint32(mode)); err != nil && err != unix.EEXIST {
+		return nil, &os.PathError{Op: "mkfifo", Path: path, Err: err}
+	}
+
+	var fi os.FileInfo
+	if fi, err = os.Lstat(path); err != nil {
+		return nil, err
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		return nil, &os.PathError{Op: "mkfifo", Path: path, Err: unix.EEXIST}
+	}
+
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+// setOwnership applies SocketMode=, SocketUser= and SocketGroup= of def to the file at path
+func (def Definition) setOwnership(path string) (err error) {
+	mode, _ := def.socketMode()
+	if err = os.Chmod(path, mode); err != nil {
+		return
+	}
+
+	uid, gid := -1, -1
+	if name := def.Socket.SocketUser; name != "" {
+		var u *user.User
+		if u, err = lookupUser(name); err != nil {
+			return
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+	}
+	if name := def.Socket.SocketGroup; name != "" {
+		var g *user.Group
+		if g, err = lookupGroup(name); err != nil {
+			return
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return os.Lchown(path, uid, gid)
+}
+
+// lookupUser looks up the user by name or numeric ID
+func lookupUser(name string) (*user.User, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return user.LookupId(name)
+	}
+	return user.Lookup(name)
+}
+
+// lookupGroup looks up the group by name or numeric ID
+func lookupGroup(name string) (*user.Group, error) {
+	if _, err := strconv.Atoi(name); err == nil {
+		return user.LookupGroupId(name)
+	}
+	return user.LookupGroup(name)
+}
+
+// Stop closes the listening sockets
+func (sock *Unit) Stop() (err error) {
+	log.WithField("sock", sock).Debug("sock.Stop")
+
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	sock.closeFiles()
+	sock.sub = Dead
+	return nil
+}
+
+// closeFiles closes the files of the listening sockets and removes the unix sockets created
+func (sock *Unit) closeFiles() {
+	for _, f := range sock.files {
+		f.Close()
+	}
+	for _, path := range sock.paths {
+		os.Remove(path)
+	}
+	sock.files, sock.paths = nil, nil
+}
+
+// Files returns the files of the listening sockets, nil if not listening
+func (sock *Unit) Files() []*os.File {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	return append([]*os.File(nil), sock.files...)
+}
+
+// ResetFailed puts a socket, which has failed, into the dead state
+func (sock *Unit) ResetFailed() {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	if sock.sub == Failed {
+		sock.sub = Dead
+	}
+}
+
+// Sub reports the sub status of a socket
+func (sock *Unit) Sub() string {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	return strings.ToLower(sock.sub.String())
+}
+
+// Active reports activation status of a socket
+func (sock *Unit) Active() unit.Activation {
+	sock.mutex.Lock()
+	defer sock.mutex.Unlock()
+
+	switch sock.sub {
+	case Dead:
+		return unit.Inactive
+	case Listening, Running:
+		return unit.Active
+	case Failed:
+		return unit.Failed
+	case StartPre, StartChown, StartPost:
+		return unit.Activating
+	default:
+		return unit.Deactivating
+	}
+}
@@ -0,0 +1,231 @@
+package socket
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+	"systemgo/unit"
+)
+
+func TestParseListening(t *testing.T) {
+	for _, c := range []struct {
+		key, addr string
+		l         listening
+	}{
+		{"ListenStream", "/run/foo.sock", listening{"unix", "/run/foo.sock"}},
+		{"ListenDatagram", "/run/foo.sock", listening{"unixgram", "/run/foo.sock"}},
+		{"ListenSequentialPacket", "/run/foo.sock", listening{"unixpacket", "/run/foo.sock"}},
+		{"ListenStream", "8080", listening{"tcp", ":8080"}},
+		{"ListenDatagram", "53", listening{"udp", ":53"}},
+		{"ListenStream", "127.0.0.1:8080", listening{"tcp", "127.0.0.1:8080"}},
+		{"ListenStream", "[::1]:8080", listening{"tcp", "[::1]:8080"}},
+		{"ListenStream", "@foo", listening{"unix", "@foo"}},
+		{"ListenFIFO", "/run/foo.fifo", listening{"fifo", "/run/foo.fifo"}},
+	} {
+		l, err := parseListening(c.key, c.addr)
+		if assert.NoError(t, err, c.addr) {
+			assert.Equal(t, c.l, l, c.addr)
+		}
+	}
+
+	for _, addr := range []string{"0", "65536", "localhost:8080", "foo"} {
+		_, err := parseListening("ListenStream", addr)
+		assert.Equal(t, ErrInvalidAddress, err, addr)
+	}
+	_, err := parseListening("ListenSequentialPacket", "8080")
+	assert.Equal(t, ErrInvalidAddress, err)
+	_, err = parseListening("ListenFIFO", "8080")
+	assert.Equal(t, ErrInvalidAddress, err)
+}
+
+func TestDefine(t *testing.T) {
+	sock := Unit{}
+	assert.NoError(t, sock.Define(strings.NewReader(`[Socket]
+ListenStream=/run/foo.sock
+ListenDatagram=5353
+Service=bar.service
+FileDescriptorName=foo
+TimeoutIdleSec=30s`)), "sock.Define")
+	assert.Equal(t, "bar.service", sock.Service())
+	assert.Equal(t, 30*time.Second, sock.TimeoutIdleSec())
+	assert.Equal(t, "foo", sock.FileDescriptorName())
+	assert.Equal(t, []unit.Listen{{Type: "Stream", Address: "/run/foo.sock"}, {Type: "Datagram", Address: "5353"}}, sock.Listens())
+
+	var err error
+
+	sock = Unit{}
+	if err = sock.Define(strings.NewReader(`[Socket]
+Service=bar.target`)); assert.Error(t, err, "sock.Define with wrong definition") {
+		if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") && assert.Len(t, me, 2) {
+			if pe, ok := me[0].(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+				assert.Equal(t, "ListenStream", pe.Source)
+				assert.Equal(t, unit.ErrNotSet, pe.Err)
+			}
+			if pe, ok := me[1].(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+				assert.Equal(t, "Service", pe.Source)
+			}
+		}
+	}
+}
+
+func TestDefineAccept(t *testing.T) {
+	sock := Unit{}
+	assert.NoError(t, sock.Define(strings.NewReader(`[Socket]
+ListenStream=8080
+Accept=yes`)), "sock.Define")
+	assert.True(t, sock.Accept())
+	assert.Equal(t, DEFAULT_MAX_CONNECTIONS, sock.MaxConnections())
+
+	err := sock.Define(strings.NewReader(`[Socket]
+ListenDatagram=5353
+Accept=yes
+Service=foo.service
+MaxConnections=-1`))
+	if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") && assert.Len(t, me, 3) {
+		assert.Equal(t, unit.ParseErr("Service", ErrAcceptService), me[0])
+		assert.Equal(t, unit.ParseErr("ListenDatagram", ErrAcceptDatagram), me[1])
+		assert.Equal(t, unit.ParseErr("MaxConnections", unit.ErrWrongVal), me[2])
+	}
+}
+
+func TestDefineOptions(t *testing.T) {
+	sock := Unit{}
+	err := sock.Define(strings.NewReader(`[Socket]
+ListenFIFO=/run/foo.fifo
+Accept=yes
+Backlog=-1
+SocketMode=0999`))
+	if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") && assert.Len(t, me, 3) {
+		assert.Equal(t, unit.ParseErr("ListenFIFO", ErrAcceptFIFO), me[0])
+		assert.Equal(t, unit.ParseErr("Backlog", unit.ErrWrongVal), me[1])
+		assert.Equal(t, unit.ParseErr("SocketMode", unit.ParseErr("0999", unit.ErrWrongVal)), me[2])
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo.sock")
+
+	sock := Unit{}
+	require.NoError(t, sock.Define(strings.NewReader(`[Socket]
+ListenStream=`+path+`
+ListenStream=127.0.0.1:0`)), "sock.Define")
+	assert.Equal(t, unit.Inactive, sock.Active())
+
+	require.NoError(t, sock.Start(), "sock.Start")
+	assert.Equal(t, unit.Active, sock.Active())
+	assert.Equal(t, "listening", sock.Sub())
+
+	files := sock.Files()
+	require.Len(t, files, 2)
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err, "socket is listening")
+	conn.Close()
+
+	ln, err := net.FileListener(files[1])
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	conn, err = net.Dial("tcp", addr)
+	require.NoError(t, err, "socket is listening")
+	conn.Close()
+
+	require.NoError(t, sock.Stop(), "sock.Stop")
+	assert.Equal(t, unit.Inactive, sock.Active())
+	assert.Nil(t, sock.Files())
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "socket is removed")
+
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err, "socket is closed")
+}
+
+func TestStartOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path, fifo := filepath.Join(dir, "foo.sock"), filepath.Join(dir, "foo.fifo")
+	abstract := fmt.Sprintf("@systemgo-test-%d", os.Getpid())
+
+	sock := Unit{}
+	require.NoError(t, sock.Define(strings.NewReader(`[Socket]
+ListenStream=`+path+`
+ListenStream=`+abstract+`
+ListenStream=127.0.0.1:0
+ListenFIFO=`+fifo+`
+SocketMode=0600
+SocketGroup=`+strconv.Itoa(os.Getgid())+`
+Backlog=8
+DeferAcceptSec=1.5s
+ReusePort=true`)), "sock.Define")
+
+	require.NoError(t, sock.Start(), "sock.Start")
+	defer sock.Stop()
+
+	files := sock.Files()
+	require.Len(t, files, 4)
+
+	for _, p := range []string{path, fifo} {
+		fi, err := os.Stat(p)
+		if assert.NoError(t, err) {
+			assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), p)
+		}
+	}
+
+	conn, err := net.Dial("unix", abstract)
+	require.NoError(t, err, "abstract socket is listening")
+	conn.Close()
+	_, err = os.Stat(abstract)
+	assert.True(t, os.IsNotExist(err), "abstract socket is not in the file system")
+
+	fd := int(files[2].Fd())
+	v, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_REUSEPORT)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v, "SO_REUSEPORT is set")
+	v, err = unix.GetsockoptInt(fd, unix.IPPROTO_TCP, unix.TCP_DEFER_ACCEPT)
+	require.NoError(t, err)
+	assert.NotZero(t, v, "TCP_DEFER_ACCEPT is set")
+
+	sa, err := unix.Getsockname(fd)
+	require.NoError(t, err)
+	port := sa.(*unix.SockaddrInet4).Port
+
+	other := Unit{}
+	require.NoError(t, other.Define(strings.NewReader(`[Socket]
+ListenStream=127.0.0.1:`+strconv.Itoa(port)+`
+ReusePort=yes`)), "other.Define")
+	assert.NoError(t, other.Start(), "port is reused")
+	other.Stop()
+
+	w, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("foo"))
+	w.Close()
+	require.NoError(t, err)
+
+	b := make([]byte, 3)
+	_, err = files[3].Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(b), "FIFO is readable")
+
+	require.NoError(t, sock.Stop())
+	_, err = os.Stat(fifo)
+	assert.True(t, os.IsNotExist(err), "FIFO is removed")
+}
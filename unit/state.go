@@ -32,10 +32,13 @@ const (
 // Enable status of a unit
 type Enable int
 
-//go:generate stringer -type=Enable state.go
+//go:generate stringer -type=Enable -linecomment state.go
 const (
 	Disabled Enable = iota
 	Static
 	Indirect
 	Enabled
+	EnabledRuntime
+	Linked
+	EnableMasked // Masked
 )
@@ -5,22 +5,36 @@ import "fmt"
 type Status struct {
 	Load       LoadStatus       `json:"Load"`
 	Activation ActivationStatus `json:"Activation"`
+	Usage      ResourceUsage    `json:"Usage"`
 
 	Log []byte `json:"Log,omitempty"`
 }
 type ActivationStatus struct {
 	State Activation `json:"State"`
 	Sub   string     `json:"Sub"`
+
+	// Outcome of the health checks, empty if the health is not probed
+	Health string `json:"Health,omitempty"`
 }
 type LoadStatus struct {
 	Path   string `json:"Path"`
 	Loaded Load   `json:"Loaded"`
 	State  Enable `json:"Enabled"`
 	Vendor Enable `json:"Vendor"`
+
+	// Whether the definition changed on disk since the unit was loaded
+	NeedsReload bool `json:"NeedsReload,omitempty"`
 }
 
 func (s Status) String() (out string) {
 	defer func() {
+		if s.Activation.Health != "" {
+			out += "\nHealth: " + s.Activation.Health
+		}
+		out += s.Usage.String()
+		if s.Load.NeedsReload {
+			out += "\nWarning: definition changed on disk, restart to apply"
+		}
 		if len(s.Log) > 0 {
 			out += fmt.Sprintf("\nLog:\n%s", s.Log)
 		}
@@ -3,6 +3,7 @@ package unit_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"systemgo/unit"
@@ -37,3 +38,22 @@ Log:
 
 	assert.Equal(t, st.String(), expected)
 }
+
+func TestStatusUsage(t *testing.T) {
+	st := unit.Status{
+		Load:       unit.LoadStatus{Path: "Path", Loaded: unit.Loaded, State: unit.Enabled, Vendor: unit.Enabled},
+		Activation: unit.ActivationStatus{State: unit.Active, Sub: "Sub"},
+		Usage: unit.ResourceUsage{
+			Tasks:      3,
+			Memory:     1536 << 10,
+			MemoryPeak: 2 << 20,
+			CPU:        1234567 * time.Microsecond,
+		},
+	}
+
+	assert.Equal(t, `Loaded: Loaded (Path; Enabled; vendor preset: Enabled)
+Active: Active (Sub)
+Tasks: 3
+Memory: 1.5M (peak: 2M)
+CPU: 1s 234ms`, st.String())
+}
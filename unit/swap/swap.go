@@ -0,0 +1,173 @@
+// Package swap defines a swap unit type
+package swap
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Commands used to enable and disable swap
+var (
+	SWAPON_CMD  = "swapon"
+	SWAPOFF_CMD = "swapoff"
+)
+
+// Path to the table of swap areas in use
+const SWAPS = "/proc/swaps"
+
+// Swap unit
+type Unit struct {
+	Definition
+
+	sub   Sub
+	mutex sync.Mutex
+}
+
+// Swap unit definition
+type Definition struct {
+	unit.Definition
+	Swap struct {
+		What, Priority, Options string
+	}
+}
+
+// Define attempts to fill the sw definition by parsing r
+func (sw *Unit) Define(r io.Reader) (err error) {
+	log.WithField("r", r).Debugf("sw.Define")
+
+	def := Definition{}
+	if err = unit.ParseDefinition(r, &def); err != nil {
+		return
+	}
+
+	if def.Swap.What == "" {
+		return unit.MultiError{unit.ParseErr("What", unit.ErrNotSet)}
+	}
+
+	sw.Definition = def
+	return nil
+}
+
+// Args returns the arguments passed to SWAPON_CMD to enable the swap
+func (sw *Unit) Args() (args []string) {
+	if sw.Swap.Priority != "" {
+		args = append(args, "-p", sw.Swap.Priority)
+	}
+	if sw.Swap.Options != "" {
+		args = append(args, "-o", sw.Swap.Options)
+	}
+	return append(args, sw.Swap.What)
+}
+
+// Start enables the swap, unless it is in use already
+func (sw *Unit) Start() (err error) {
+	e := log.WithField("what", sw.Swap.What)
+	e.Debug("sw.Start")
+
+	if IsActive(sw.Swap.What) {
+		sw.setSub(Active)
+		return nil
+	}
+
+	sw.setSub(Activating)
+	if out, err := exec.Command(SWAPON_CMD, sw.Args()...).CombinedOutput(); err != nil {
+		e.WithField("out", string(out)).Debug("swapon failed")
+		sw.setSub(Failed)
+		return err
+	}
+	sw.setSub(Active)
+	return nil
+}
+
+// Stop disables the swap
+func (sw *Unit) Stop() (err error) {
+	log.WithField("what", sw.Swap.What).Debug("sw.Stop")
+
+	if !IsActive(sw.Swap.What) {
+		sw.setSub(Dead)
+		return nil
+	}
+
+	sw.setSub(Deactivating)
+	if err = exec.Command(SWAPOFF_CMD, sw.Swap.What).Run(); err != nil {
+		sw.setSub(Failed)
+		return
+	}
+	sw.setSub(Dead)
+	return nil
+}
+
+func (sw *Unit) setSub(sub Sub) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	sw.sub = sub
+}
+
+// ResetFailed puts a swap, which has failed, into the dead state
+func (sw *Unit) ResetFailed() {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	if sw.sub == Failed {
+		sw.sub = Dead
+	}
+}
+
+// Sub reports the sub status of a swap
+func (sw *Unit) Sub() string {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	return strings.ToLower(sw.sub.String())
+}
+
+// Active reports activation status of a swap
+func (sw *Unit) Active() unit.Activation {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+
+	switch sw.sub {
+	case Dead:
+		return unit.Inactive
+	case Active:
+		return unit.Active
+	case Failed:
+		return unit.Failed
+	case Activating, ActivatingDone, ActivatingSigterm, ActivatingSigkill:
+		return unit.Activating
+	default:
+		return unit.Deactivating
+	}
+}
+
+// IsActive returns whether the swap area at path is in use as found in SWAPS
+func IsActive(path string) bool {
+	f, err := os.Open(SWAPS)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[0] == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package unit
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Default facility of log entries forwarded to syslog
+const DEFAULT_SYSLOG_FACILITY = "daemon"
+
+// Syslog facilities by name, see syslog(3)
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// ParseSyslogFacility returns the number of the syslog facility with name specified, e.g. 3 for "daemon"
+func ParseSyslogFacility(name string) (facility int, err error) {
+	facility, ok := syslogFacilities[name]
+	if !ok {
+		return 0, ParseErr(name, ErrWrongVal)
+	}
+	return facility, nil
+}
+
+// Names of syslog priorities indexed by priority, lower is more important, see syslog(3)
+var priorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// ParsePriority parses a syslog priority specified either by its name, e.g. "err", or number, e.g. "3"
+func ParsePriority(s string) (p int, err error) {
+	for i, name := range priorityNames {
+		if s == name {
+			return i, nil
+		}
+	}
+
+	if p, err = strconv.Atoi(s); err != nil || p < 0 || p >= len(priorityNames) {
+		return 0, fmt.Errorf("invalid priority: %q", s)
+	}
+	return p, nil
+}
+
+// PriorityName returns the name of syslog priority p, e.g. "err"
+func PriorityName(p int) string {
+	if p < 0 || p >= len(priorityNames) {
+		return strconv.Itoa(p)
+	}
+	return priorityNames[p]
+}
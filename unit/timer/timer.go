@@ -0,0 +1,247 @@
+// Package timer defines a timer unit type
+package timer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"systemgo/unit"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrNoTrigger = errors.New("No trigger specified")
+
+// Timer unit
+type Unit struct {
+	Definition
+
+	sub   Sub
+	mutex sync.Mutex
+}
+
+// Timer unit definition
+type Definition struct {
+	unit.Definition
+	Timer struct {
+		OnActiveSec, OnBootSec, OnStartupSec, OnUnitActiveSec time.Duration
+
+		OnCalendar unit.Lines
+
+		Unit       string
+		Persistent bool
+
+		AccuracySec        *time.Duration
+		RandomizedDelaySec time.Duration
+	}
+
+	// Parsed OnCalendar= expressions
+	calendars []*unit.CalendarSpec
+}
+
+// Unit returns the name of the unit activated as found in Definition,
+// empty means the service named as the timer
+func (def Definition) Unit() string {
+	return def.Timer.Unit
+}
+
+// Persistent returns whether the time of the last activation is stored on disk as found in Definition
+func (def Definition) Persistent() bool {
+	return def.Timer.Persistent
+}
+
+// AccuracySec returns the time the activation may be delayed by to coalesce it with other timers
+// as found in Definition and whether it is specified
+func (def Definition) AccuracySec() (accuracy time.Duration, ok bool) {
+	if def.Timer.AccuracySec == nil {
+		return 0, false
+	}
+	return *def.Timer.AccuracySec, true
+}
+
+// RandomizedDelaySec returns the maximum random time the activation is delayed by as found in Definition
+func (def Definition) RandomizedDelaySec() time.Duration {
+	return def.Timer.RandomizedDelaySec
+}
+
+// Calendars returns the calendar events the unit is activated at as found in Definition
+func (def Definition) Calendars() []*unit.CalendarSpec {
+	return def.calendars
+}
+
+// NextElapse returns the earliest point in time any of the triggers in Definition elapses at relative to base.
+// Monotonic triggers elapse once per activation of the timer, OnUnitActiveSec= elapses relative
+// to the last activation of the unit. Calendar events are matched after the last activation of the unit,
+// after the one stored on disk, if Persistent= is set, or after the activation of the timer otherwise
+func (def Definition) NextElapse(base unit.TimerBase) (next time.Time, ok bool) {
+	consider := func(t time.Time) {
+		if !ok || t.Before(next) {
+			next, ok = t, true
+		}
+	}
+
+	for _, trigger := range []struct {
+		base time.Time
+		d    time.Duration
+	}{
+		{base.Activated, def.Timer.OnActiveSec},
+		{base.Boot, def.Timer.OnBootSec},
+		{base.Startup, def.Timer.OnStartupSec},
+	} {
+		if trigger.d == 0 || trigger.base.IsZero() {
+			continue
+		}
+		if t := trigger.base.Add(trigger.d); t.After(base.LastTrigger) {
+			consider(t)
+		}
+	}
+
+	if def.Timer.OnUnitActiveSec > 0 && !base.LastTrigger.IsZero() {
+		consider(base.LastTrigger.Add(def.Timer.OnUnitActiveSec))
+	}
+
+	after := base.LastTrigger
+	if after.IsZero() && def.Timer.Persistent {
+		after = base.Stamp
+	}
+	if after.IsZero() {
+		after = base.Activated
+	}
+	for _, spec := range def.calendars {
+		if t, found := spec.Next(after); found {
+			consider(t)
+		}
+	}
+	return
+}
+
+// Define attempts to fill the tmr definition by parsing r
+func (tmr *Unit) Define(r io.Reader) (err error) {
+	log.WithField("r", r).Debugf("tmr.Define")
+
+	def := Definition{}
+	if err = unit.ParseDefinition(r, &def); err != nil {
+		return
+	}
+
+	merr := unit.MultiError{}
+
+	for _, s := range def.Timer.OnCalendar {
+		spec, err := unit.ParseCalendar(s)
+		if err != nil {
+			merr = append(merr, unit.ParseErr("OnCalendar", unit.ParseErr(s, err)))
+			continue
+		}
+		def.calendars = append(def.calendars, spec)
+	}
+
+	for _, opt := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"OnActiveSec", def.Timer.OnActiveSec},
+		{"OnBootSec", def.Timer.OnBootSec},
+		{"OnStartupSec", def.Timer.OnStartupSec},
+		{"OnUnitActiveSec", def.Timer.OnUnitActiveSec},
+		{"RandomizedDelaySec", def.Timer.RandomizedDelaySec},
+	} {
+		if opt.d < 0 {
+			merr = append(merr, unit.ParseErr(opt.key, unit.ErrWrongVal))
+		}
+	}
+
+	if accuracy, ok := def.AccuracySec(); ok && accuracy < 0 {
+		merr = append(merr, unit.ParseErr("AccuracySec", unit.ErrWrongVal))
+	}
+
+	if len(def.Timer.OnCalendar) == 0 && def.Timer.OnActiveSec == 0 && def.Timer.OnBootSec == 0 &&
+		def.Timer.OnStartupSec == 0 && def.Timer.OnUnitActiveSec == 0 {
+		merr = append(merr, unit.ParseErr("OnCalendar", ErrNoTrigger))
+	}
+
+	if name := def.Timer.Unit; name != "" && strings.HasSuffix(name, ".timer") {
+		merr = append(merr, unit.ParseErr("Unit", unit.ParseErr(name, unit.ErrNotSupported)))
+	}
+
+	if len(merr) > 0 {
+		return merr
+	}
+
+	tmr.Definition = def
+	return nil
+}
+
+// Start puts the timer into the waiting state
+func (tmr *Unit) Start() (err error) {
+	log.WithField("tmr", tmr).Debug("tmr.Start")
+
+	tmr.mutex.Lock()
+	defer tmr.mutex.Unlock()
+
+	if tmr.sub == Dead || tmr.sub == Failed {
+		tmr.sub = Waiting
+	}
+	return nil
+}
+
+// Stop puts the timer into the dead state
+func (tmr *Unit) Stop() (err error) {
+	log.WithField("tmr", tmr).Debug("tmr.Stop")
+
+	tmr.mutex.Lock()
+	defer tmr.mutex.Unlock()
+
+	tmr.sub = Dead
+	return nil
+}
+
+// SetElapsed puts a started timer into the elapsed state, if elapsed is true, or the waiting state otherwise
+func (tmr *Unit) SetElapsed(elapsed bool) {
+	tmr.mutex.Lock()
+	defer tmr.mutex.Unlock()
+
+	if tmr.sub != Waiting && tmr.sub != Elapsed {
+		return
+	}
+	if elapsed {
+		tmr.sub = Elapsed
+	} else {
+		tmr.sub = Waiting
+	}
+}
+
+// ResetFailed puts a timer, which has failed, into the dead state
+func (tmr *Unit) ResetFailed() {
+	tmr.mutex.Lock()
+	defer tmr.mutex.Unlock()
+
+	if tmr.sub == Failed {
+		tmr.sub = Dead
+	}
+}
+
+// Sub reports the sub status of a timer
+func (tmr *Unit) Sub() string {
+	tmr.mutex.Lock()
+	defer tmr.mutex.Unlock()
+
+	return strings.ToLower(tmr.sub.String())
+}
+
+// Active reports activation status of a timer
+func (tmr *Unit) Active() unit.Activation {
+	tmr.mutex.Lock()
+	defer tmr.mutex.Unlock()
+
+	switch tmr.sub {
+	case Dead:
+		return unit.Inactive
+	case Failed:
+		return unit.Failed
+	default:
+		return unit.Active
+	}
+}
@@ -0,0 +1,130 @@
+package timer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/unit"
+)
+
+func TestDefine(t *testing.T) {
+	tmr := Unit{}
+	assert.NoError(t, tmr.Define(strings.NewReader(`[Timer]
+OnCalendar=Mon..Fri *-*-* 09:00:00
+OnCalendar=daily
+OnBootSec=15min
+Unit=bar.service
+Persistent=yes
+RandomizedDelaySec=5min`)), "tmr.Define")
+	_, ok := tmr.AccuracySec()
+	assert.False(t, ok, "AccuracySec is not specified")
+	assert.Equal(t, 5*time.Minute, tmr.RandomizedDelaySec())
+	assert.Equal(t, "bar.service", tmr.Unit())
+	assert.True(t, tmr.Persistent())
+	if assert.Len(t, tmr.Calendars(), 2) {
+		assert.Equal(t, "Mon..Fri *-*-* 09:00:00", tmr.Calendars()[0].String())
+		assert.Equal(t, "*-*-* 00:00:00", tmr.Calendars()[1].String())
+	}
+
+	var err error
+
+	tmr = Unit{}
+	if err = tmr.Define(strings.NewReader(`[Timer]
+Unit=bar.timer`)); assert.Error(t, err, "tmr.Define with wrong definition") {
+		if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") && assert.Len(t, me, 2) {
+			assert.Equal(t, unit.ParseErr("OnCalendar", ErrNoTrigger), me[0])
+			if pe, ok := me[1].(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+				assert.Equal(t, "Unit", pe.Source)
+			}
+		}
+	}
+
+	err = tmr.Define(strings.NewReader(`[Timer]
+OnCalendar=foo`))
+	if me, ok := err.(unit.MultiError); assert.True(t, ok, "error is MultiError") && assert.Len(t, me, 1) {
+		if pe, ok := me[0].(unit.ParseError); assert.True(t, ok, "error is ParseError") {
+			assert.Equal(t, "OnCalendar", pe.Source)
+		}
+	}
+
+	require.NoError(t, tmr.Define(strings.NewReader(`[Timer]
+OnCalendar=daily
+AccuracySec=1s`)), "tmr.Define")
+	accuracy, ok := tmr.AccuracySec()
+	if assert.True(t, ok, "AccuracySec is specified") {
+		assert.Equal(t, time.Second, accuracy)
+	}
+}
+
+func TestNextElapse(t *testing.T) {
+	activated := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	base := unit.TimerBase{
+		Activated: activated,
+		Boot:      activated.Add(-time.Hour),
+		Startup:   activated.Add(-time.Minute),
+		Stamp:     activated.Add(-48 * time.Hour),
+	}
+
+	tmr := Unit{}
+	require.NoError(t, tmr.Define(strings.NewReader(`[Timer]
+OnCalendar=daily`)))
+
+	next, ok := tmr.NextElapse(base)
+	if assert.True(t, ok) {
+		assert.Equal(t, activated.Add(12*time.Hour), next, "calendar event after the activation")
+	}
+
+	require.NoError(t, tmr.Define(strings.NewReader(`[Timer]
+OnCalendar=daily
+Persistent=yes`)))
+
+	next, ok = tmr.NextElapse(base)
+	if assert.True(t, ok) {
+		assert.Equal(t, activated.Add(-36*time.Hour), next, "calendar event missed since the stamp")
+	}
+
+	base.LastTrigger = activated
+	next, ok = tmr.NextElapse(base)
+	if assert.True(t, ok) {
+		assert.Equal(t, activated.Add(12*time.Hour), next, "calendar event after the last trigger")
+	}
+
+	require.NoError(t, tmr.Define(strings.NewReader(`[Timer]
+OnActiveSec=10min
+OnBootSec=2h
+OnUnitActiveSec=1h`)))
+
+	base.LastTrigger = time.Time{}
+	next, ok = tmr.NextElapse(base)
+	if assert.True(t, ok) {
+		assert.Equal(t, activated.Add(10*time.Minute), next, "earliest monotonic trigger")
+	}
+
+	base.LastTrigger = activated.Add(10 * time.Minute)
+	next, ok = tmr.NextElapse(base)
+	if assert.True(t, ok) {
+		assert.Equal(t, activated.Add(time.Hour), next, "boot trigger is next")
+	}
+
+	base.LastTrigger = activated.Add(time.Hour)
+	next, ok = tmr.NextElapse(base)
+	if assert.True(t, ok) {
+		assert.Equal(t, activated.Add(2*time.Hour), next, "unit active trigger repeats")
+	}
+
+	require.NoError(t, tmr.Define(strings.NewReader(`[Timer]
+OnStartupSec=1s`)))
+	_, ok = tmr.NextElapse(base)
+	assert.False(t, ok, "monotonic triggers elapse once")
+
+	tmr.Start()
+	tmr.SetElapsed(true)
+	assert.Equal(t, "elapsed", tmr.Sub())
+	assert.Equal(t, unit.Active, tmr.Active())
+	tmr.Stop()
+	tmr.SetElapsed(false)
+	assert.Equal(t, unit.Inactive, tmr.Active())
+}
@@ -0,0 +1,117 @@
+package unit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Time units recognized in time spans
+var timespanUnits = map[string]time.Duration{
+	"us":      time.Microsecond,
+	"usec":    time.Microsecond,
+	"ms":      time.Millisecond,
+	"msec":    time.Millisecond,
+	"s":       time.Second,
+	"sec":     time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       24 * time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+	"w":       7 * 24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+}
+
+// ParseTimespan parses a time span in the format used by Systemd, e.g. "90", "1min 30s" or "2h30m".
+// Values without a unit are in seconds. "infinity" is parsed as the maximum duration
+func ParseTimespan(s string) (d time.Duration, err error) {
+	s = strings.TrimSpace(s)
+	if s == "infinity" {
+		return time.Duration(1<<63 - 1), nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("empty time span")
+	}
+
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+		if i == 0 {
+			return 0, fmt.Errorf("invalid time span: %q", s)
+		}
+
+		num := s
+		if i > 0 {
+			num, s = s[:i], strings.TrimLeft(s[i:], " ")
+		} else {
+			s = ""
+		}
+
+		var f float64
+		if f, err = strconv.ParseFloat(num, 64); err != nil {
+			return 0, err
+		}
+
+		i = strings.IndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) || r == ' ' })
+		name := s
+		if i >= 0 {
+			name, s = s[:i], strings.TrimLeft(s[i:], " ")
+		} else {
+			s = ""
+		}
+
+		unit := time.Second
+		if name != "" {
+			var ok bool
+			if unit, ok = timespanUnits[name]; !ok {
+				return 0, fmt.Errorf("unknown time unit: %q", name)
+			}
+		}
+		d += time.Duration(f * float64(unit))
+	}
+	return
+}
+
+// Time units used to format time spans, largest first
+var timespanFormatUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"min", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+}
+
+// FormatTimespan formats d in the format used by Systemd, e.g. "1min 30s", which ParseTimespan parses.
+// The maximum duration is formatted as "infinity"
+func FormatTimespan(d time.Duration) string {
+	if d == time.Duration(1<<63-1) {
+		return "infinity"
+	}
+	if d < time.Microsecond {
+		return "0"
+	}
+
+	parts := []string{}
+	for _, u := range timespanFormatUnits {
+		if n := d / u.d; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", n, u.name))
+			d -= n * u.d
+		}
+	}
+	return strings.Join(parts, " ")
+}
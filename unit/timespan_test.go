@@ -0,0 +1,49 @@
+package unit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"systemgo/unit"
+)
+
+func TestParseTimespan(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"90":         90 * time.Second,
+		"500ms":      500 * time.Millisecond,
+		"1min 30s":   90 * time.Second,
+		"2h30m":      150 * time.Minute,
+		"1.5s":       1500 * time.Millisecond,
+		"1w 1d":      8 * 24 * time.Hour,
+		"10 seconds": 10 * time.Second,
+	} {
+		d, err := unit.ParseTimespan(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, d, s)
+		}
+	}
+
+	for _, s := range []string{"", "foo", "10 parsecs", "s10"} {
+		_, err := unit.ParseTimespan(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFormatTimespan(t *testing.T) {
+	for d, expected := range map[time.Duration]string{
+		0:                                    "0",
+		90 * time.Second:                     "1min 30s",
+		500 * time.Millisecond:               "500ms",
+		26*time.Hour + 1500*time.Millisecond: "1d 2h 1s 500ms",
+		time.Duration(1<<63 - 1):             "infinity",
+	} {
+		s := unit.FormatTimespan(d)
+		assert.Equal(t, expected, s, d)
+
+		parsed, err := unit.ParseTimespan(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, d, parsed, s)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+// Package varlink serves a system.Daemon over the Varlink protocol: JSON objects terminated by NUL bytes
+// exchanged over a unix socket. Interfaces served are org.varlink.service for introspection and
+// io.systemgo.Manager for management
+package varlink
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"systemgo/system"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Name of the socket of the system manager, the user manager serves it in its runtime directory
+const SOCKET_PATH = "/run/systemgo/" + MANAGER_INTERFACE
+
+const (
+	SERVICE_INTERFACE = "org.varlink.service"
+	MANAGER_INTERFACE = "io.systemgo.Manager"
+)
+
+// Errors defined by org.varlink.service
+const (
+	ERR_INTERFACE_NOT_FOUND = SERVICE_INTERFACE + ".InterfaceNotFound"
+	ERR_METHOD_NOT_FOUND    = SERVICE_INTERFACE + ".MethodNotFound"
+	ERR_INVALID_PARAMETER   = SERVICE_INTERFACE + ".InvalidParameter"
+	ERR_EXPECTED_MORE       = SERVICE_INTERFACE + ".ExpectedMore"
+)
+
+// Errors defined by io.systemgo.Manager
+const (
+	ERR_NO_SUCH_UNIT = MANAGER_INTERFACE + ".NoSuchUnit"
+	ERR_JOB_FAILED   = MANAGER_INTERFACE + ".JobFailed"
+	ERR_FAILED       = MANAGER_INTERFACE + ".Failed"
+)
+
+// Description of io.systemgo.Manager in the Varlink interface definition language
+const managerDescription = `# Management of systemgo units
+interface io.systemgo.Manager
+
+type UnitStatus (
+  name: string,
+  path: string,
+  load_state: string,
+  active_state: string,
+  sub_state: string,
+  enable_state: string
+)
+
+type Event (
+  type: string,
+  unit: ?string,
+  job: ?string,
+  error: ?string,
+  from: ?string,
+  to: ?string,
+  state: ?string,
+  time: string
+)
+
+# Returns the status of the manager
+method Describe() -> (state: string, jobs: int, failed: int, since: string)
+
+# Returns the statuses of all loaded units
+method ListUnits() -> (units: []UnitStatus)
+
+# Returns the status of the unit, loading it, if necessary
+method GetUnit(name: string) -> (unit: UnitStatus)
+
+method StartUnit(name: string) -> ()
+method StopUnit(name: string) -> ()
+method RestartUnit(name: string) -> ()
+method ReloadUnit(name: string) -> ()
+
+# Streams events about the units, all units if none are specified, and manager state changes.
+# Must be called with "more" set
+method SubscribeEvents(units: ?[]string) -> (event: Event)
+
+error NoSuchUnit (name: string)
+error JobFailed (name: string, result: string, error: string)
+error Failed (message: string)
+`
+
+// Description of org.varlink.service in the Varlink interface definition language
+const serviceDescription = `# The Varlink Service Interface is provided by every varlink service
+interface org.varlink.service
+
+method GetInfo() -> (
+  vendor: string,
+  product: string,
+  version: string,
+  url: string,
+  interfaces: []string
+)
+
+method GetInterfaceDescription(interface: string) -> (description: string)
+
+error InterfaceNotFound (interface: string)
+error MethodNotFound (method: string)
+error MethodNotImplemented (method: string)
+error InvalidParameter (parameter: string)
+error PermissionDenied ()
+error ExpectedMore ()
+`
+
+var descriptions = map[string]string{
+	SERVICE_INTERFACE: serviceDescription,
+	MANAGER_INTERFACE: managerDescription,
+}
+
+// call is a method call sent by a client
+type call struct {
+	Method     string          `json:"method"`
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+	Oneway     bool            `json:"oneway,omitempty"`
+	More       bool            `json:"more,omitempty"`
+}
+
+// reply is a reply to a call
+type reply struct {
+	Parameters interface{} `json:"parameters,omitempty"`
+	Continues  bool        `json:"continues,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Error is a Varlink error, which gets sent to the client
+type Error struct {
+	Name       string
+	Parameters interface{}
+}
+
+func (err *Error) Error() string {
+	return err.Name
+}
+
+// unitStatus is the UnitStatus type of io.systemgo.Manager
+type unitStatus struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	LoadState   string `json:"load_state"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	EnableState string `json:"enable_state"`
+}
+
+// event is the Event type of io.systemgo.Manager
+type event struct {
+	Type  string `json:"type"`
+	Unit  string `json:"unit,omitempty"`
+	Job   string `json:"job,omitempty"`
+	Error string `json:"error,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+	State string `json:"state,omitempty"`
+	Time  string `json:"time"`
+}
+
+// Service serves the Varlink interfaces backed by a Daemon
+type Service struct {
+	sys *system.Daemon
+}
+
+// NewService returns a Service backed by sys
+func NewService(sys *system.Daemon) *Service {
+	return &Service{sys}
+}
+
+// Serve accepts connections on l and serves each of them in a new goroutine
+func (srv *Service) Serve(l net.Listener) (err error) {
+	for {
+		var conn net.Conn
+		if conn, err = l.Accept(); err != nil {
+			return
+		}
+		go srv.ServeConn(conn)
+	}
+}
+
+// ServeConn serves calls received on conn until it is closed
+func (srv *Service) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		b, err := r.ReadBytes(0)
+		if err != nil {
+			if err != io.EOF {
+				log.Errorf("Error reading varlink call: %s", err)
+			}
+			return
+		}
+
+		var c call
+		if err = json.Unmarshal(b[:len(b)-1], &c); err != nil {
+			log.Errorf("Malformed varlink call: %s", err)
+			return
+		}
+
+		log.WithField("method", c.Method).Debugf("varlink.ServeConn")
+
+		send := func(rep reply) error {
+			if c.Oneway {
+				return nil
+			}
+			return writeReply(conn, rep)
+		}
+		if err = srv.dispatch(c, r, send); err == errGone {
+			return
+		} else if err != nil {
+			if verr, ok := err.(*Error); ok {
+				err = send(reply{Error: verr.Name, Parameters: verr.Parameters})
+			}
+		}
+		if err != nil {
+			log.Errorf("Error serving varlink call: %s", err)
+			return
+		}
+	}
+}
+
+// Returned by streaming methods, once the client closes the connection
+var errGone = errors.New("Client closed the connection")
+
+// writeReply writes rep followed by a NUL byte to w
+func writeReply(w io.Writer, rep reply) (err error) {
+	if rep.Parameters == nil {
+		rep.Parameters = struct{}{}
+	}
+
+	var b []byte
+	if b, err = json.Marshal(rep); err != nil {
+		return
+	}
+	_, err = w.Write(append(b, 0))
+	return
+}
+
+// dispatch calls the method c refers to, sending the replies using send.
+// r is the rest of the connection, it is only read from by streaming methods to detect the client going away
+func (srv *Service) dispatch(c call, r io.Reader, send func(reply) error) (err error) {
+	i := strings.LastIndexByte(c.Method, '.')
+	if i < 0 {
+		return &Error{ERR_METHOD_NOT_FOUND, map[string]string{"method": c.Method}}
+	}
+
+	iface, method := c.Method[:i], c.Method[i+1:]
+	if _, ok := descriptions[iface]; !ok {
+		return &Error{ERR_INTERFACE_NOT_FOUND, map[string]string{"interface": iface}}
+	}
+
+	var params struct {
+		Interface string   `json:"interface"`
+		Name      string   `json:"name"`
+		Units     []string `json:"units"`
+	}
+	if len(c.Parameters) > 0 {
+		if err = json.Unmarshal(c.Parameters, &params); err != nil {
+			return &Error{ERR_INVALID_PARAMETER, map[string]string{"parameter": "parameters"}}
+		}
+	}
+
+	switch c.Method {
+	case SERVICE_INTERFACE + ".GetInfo":
+		return send(reply{Parameters: map[string]interface{}{
+			"vendor":     "systemgo",
+			"product":    "systemgo",
+			"version":    "0",
+			"url":        "https://github.com/arthurpro/systemgo",
+			"interfaces": []string{SERVICE_INTERFACE, MANAGER_INTERFACE},
+		}})
+
+	case SERVICE_INTERFACE + ".GetInterfaceDescription":
+		desc, ok := descriptions[params.Interface]
+		if !ok {
+			return &Error{ERR_INTERFACE_NOT_FOUND, map[string]string{"interface": params.Interface}}
+		}
+		return send(reply{Parameters: map[string]string{"description": desc}})
+
+	case MANAGER_INTERFACE + ".Describe":
+		var st system.Status
+		if st, err = srv.sys.Status(); err != nil {
+			return failed(err)
+		}
+		return send(reply{Parameters: map[string]interface{}{
+			"state":  fmt.Sprint(st.State),
+			"jobs":   st.Jobs,
+			"failed": st.Failed,
+			"since":  st.Since.Format(time.RFC3339Nano),
+		}})
+
+	case MANAGER_INTERFACE + ".ListUnits":
+		all := srv.sys.Units()
+		sort.Slice(all, func(i, j int) bool { return all[i].Name() < all[j].Name() })
+
+		units := []unitStatus{}
+		for _, u := range all {
+			units = append(units, newUnitStatus(u))
+		}
+		return send(reply{Parameters: map[string]interface{}{"units": units}})
+
+	case MANAGER_INTERFACE + ".GetUnit":
+		u, err := srv.sys.Get(params.Name)
+		if err != nil {
+			return unitError(params.Name, err)
+		}
+		return send(reply{Parameters: map[string]interface{}{"unit": newUnitStatus(u)}})
+
+	case MANAGER_INTERFACE + ".StartUnit":
+		return srv.job("start", params.Name, srv.sys.Start, send)
+	case MANAGER_INTERFACE + ".StopUnit":
+		return srv.job("stop", params.Name, srv.sys.Stop, send)
+	case MANAGER_INTERFACE + ".RestartUnit":
+		return srv.job("restart", params.Name, srv.sys.Restart, send)
+	case MANAGER_INTERFACE + ".ReloadUnit":
+		return srv.job("reload", params.Name, srv.sys.Reload, send)
+
+	case MANAGER_INTERFACE + ".SubscribeEvents":
+		if !c.More {
+			return &Error{ERR_EXPECTED_MORE, nil}
+		}
+		return srv.subscribe(params.Units, r, send)
+	}
+
+	return &Error{ERR_METHOD_NOT_FOUND, map[string]string{"method": method}}
+}
+
+// job calls fn performing the operation op with name, audits it and sends an empty reply, once it finishes
+func (srv *Service) job(op, name string, fn func(...string) error, send func(reply) error) error {
+	if name == "" {
+		return &Error{ERR_INVALID_PARAMETER, map[string]string{"parameter": "name"}}
+	}
+
+	err := fn(name)
+	srv.sys.Audit(system.AuditRecord{
+		Operation: op,
+		Units:     []string{name},
+		Via:       system.AUDIT_VIA_VARLINK,
+		UID:       -1,
+		PID:       -1,
+		Err:       err,
+	})
+	if err != nil {
+		return unitError(name, err)
+	}
+	return send(reply{})
+}
+
+// subscribe sends events about units and manager state changes, until the client goes away
+func (srv *Service) subscribe(units []string, r io.Reader, send func(reply) error) (err error) {
+	filter := map[string]bool{}
+	for _, name := range units {
+		filter[name] = true
+	}
+
+	events := srv.sys.Subscribe()
+	defer srv.sys.Unsubscribe(events)
+
+	// No more calls are expected on the connection, reading fails, when it is closed
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, r)
+		close(gone)
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return errGone
+		case e, ok := <-events:
+			if !ok {
+				return errGone
+			}
+			if len(filter) > 0 && e.Unit != "" && !filter[e.Unit] {
+				continue
+			}
+
+			if err = send(reply{
+				Parameters: map[string]interface{}{"event": newEvent(e)},
+				Continues:  true,
+			}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// unitError returns the Varlink error corresponding to err returned by an operation on the unit name
+func unitError(name string, err error) *Error {
+	if jerr, ok := err.(system.JobError); ok {
+		st := jerr.Results[name]
+		return &Error{ERR_JOB_FAILED, map[string]string{
+			"name":   name,
+			"result": fmt.Sprint(st.Result),
+			"error":  st.Err,
+		}}
+	}
+
+	if errors.Is(err, system.ErrNotFound) {
+		return &Error{ERR_NO_SUCH_UNIT, map[string]string{"name": name}}
+	}
+	return failed(err)
+}
+
+func failed(err error) *Error {
+	return &Error{ERR_FAILED, map[string]string{"message": err.Error()}}
+}
+
+func newUnitStatus(u *system.Unit) unitStatus {
+	return unitStatus{
+		Name:        u.Name(),
+		Path:        u.Path(),
+		LoadState:   strings.ToLower(fmt.Sprint(u.Loaded())),
+		ActiveState: strings.ToLower(fmt.Sprint(u.Active())),
+		SubState:    u.Sub(),
+		EnableState: strings.ToLower(fmt.Sprint(u.EnableState())),
+	}
+}
+
+func newEvent(e system.Event) (ev event) {
+	ev = event{
+		Type:  fmt.Sprint(e.Type),
+		Unit:  e.Unit,
+		Job:   e.Job,
+		Error: e.Err,
+		Time:  e.Time.Format(time.RFC3339Nano),
+	}
+
+	switch e.Type {
+	case system.UnitStateChanged:
+		ev.From = strings.ToLower(fmt.Sprint(e.From))
+		ev.To = strings.ToLower(fmt.Sprint(e.To))
+	case system.ManagerStateChanged:
+		ev.State = fmt.Sprint(e.State)
+	}
+	return
+}
@@ -0,0 +1,133 @@
+package varlink
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"systemgo/system"
+)
+
+type testReply struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Continues  bool                   `json:"continues"`
+	Error      string                 `json:"error"`
+}
+
+func TestService(t *testing.T) {
+	dir, err := ioutil.TempDir("", "varlink-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"foo.service": "[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/bin/true",
+		"bar.service": "[Service]\nType=oneshot\nExecStart=/bin/false",
+		"baz.service": "[Unit]\nStartLimitIntervalSec=0\n[Service]\nType=oneshot\nExecStart=/bin/true",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	sys := system.New()
+	sys.SetPaths(dir)
+
+	addr := filepath.Join(dir, "varlink")
+	l, err := net.Listen("unix", addr)
+	require.NoError(t, err)
+	defer l.Close()
+	go NewService(sys).Serve(l)
+
+	conn, err := net.Dial("unix", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	send := func(c map[string]interface{}) {
+		b, err := json.Marshal(c)
+		require.NoError(t, err)
+		_, err = conn.Write(append(b, 0))
+		require.NoError(t, err)
+	}
+	recv := func() (rep testReply) {
+		b, err := r.ReadBytes(0)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(b[:len(b)-1], &rep))
+		return
+	}
+	do := func(method string, params map[string]interface{}) testReply {
+		send(map[string]interface{}{"method": method, "parameters": params})
+		return recv()
+	}
+
+	rep := do("org.varlink.service.GetInfo", nil)
+	assert.Empty(t, rep.Error)
+	assert.Equal(t, []interface{}{SERVICE_INTERFACE, MANAGER_INTERFACE}, rep.Parameters["interfaces"])
+
+	rep = do("org.varlink.service.GetInterfaceDescription", map[string]interface{}{"interface": MANAGER_INTERFACE})
+	assert.Equal(t, managerDescription, rep.Parameters["description"])
+
+	rep = do("org.varlink.service.GetInterfaceDescription", map[string]interface{}{"interface": "com.example.Nonexistent"})
+	assert.Equal(t, ERR_INTERFACE_NOT_FOUND, rep.Error)
+
+	assert.Equal(t, ERR_INTERFACE_NOT_FOUND, do("com.example.Nonexistent.Method", nil).Error)
+	assert.Equal(t, ERR_METHOD_NOT_FOUND, do(MANAGER_INTERFACE+".Nonexistent", nil).Error)
+	assert.Equal(t, ERR_INVALID_PARAMETER, do(MANAGER_INTERFACE+".StartUnit", nil).Error)
+
+	// Oneway calls get no reply
+	send(map[string]interface{}{"method": MANAGER_INTERFACE + ".StartUnit", "parameters": map[string]string{"name": "foo.service"}, "oneway": true})
+
+	rep = do(MANAGER_INTERFACE+".StartUnit", map[string]interface{}{"name": "foo.service"})
+	assert.Empty(t, rep.Error)
+
+	rep = do(MANAGER_INTERFACE+".StartUnit", map[string]interface{}{"name": "bar.service"})
+	assert.Equal(t, ERR_JOB_FAILED, rep.Error)
+	assert.Equal(t, "bar.service", rep.Parameters["name"])
+
+	rep = do(MANAGER_INTERFACE+".StartUnit", map[string]interface{}{"name": "nonexistent.service"})
+	assert.Equal(t, ERR_NO_SUCH_UNIT, rep.Error)
+
+	rep = do(MANAGER_INTERFACE+".GetUnit", map[string]interface{}{"name": "foo.service"})
+	require.Empty(t, rep.Error)
+	assert.Equal(t, "active", rep.Parameters["unit"].(map[string]interface{})["active_state"])
+
+	rep = do(MANAGER_INTERFACE+".ListUnits", nil)
+	require.Empty(t, rep.Error)
+	units := rep.Parameters["units"].([]interface{})
+	require.Len(t, units, 2)
+	assert.Equal(t, "bar.service", units[0].(map[string]interface{})["name"])
+
+	rep = do(MANAGER_INTERFACE+".Describe", nil)
+	require.Empty(t, rep.Error)
+	assert.EqualValues(t, 1, rep.Parameters["failed"])
+
+	assert.Equal(t, ERR_EXPECTED_MORE, do(MANAGER_INTERFACE+".SubscribeEvents", nil).Error)
+
+	send(map[string]interface{}{"method": MANAGER_INTERFACE + ".SubscribeEvents", "more": true,
+		"parameters": map[string]interface{}{"units": []string{"baz.service"}}})
+
+	// The subscription is established asynchronously, start the unit until an event is received
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				sys.Start("baz.service")
+			}
+		}
+	}()
+
+	rep = recv()
+	assert.True(t, rep.Continues)
+	ev := rep.Parameters["event"].(map[string]interface{})
+	assert.Equal(t, "baz.service", ev["unit"])
+	assert.NotEmpty(t, ev["type"])
+	assert.NotEmpty(t, ev["time"])
+}